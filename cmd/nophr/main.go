@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -9,16 +10,23 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/sandwich/nophr/internal/aggregates"
-	"github.com/sandwich/nophr/internal/config"
-	"github.com/sandwich/nophr/internal/exporter"
-	"github.com/sandwich/nophr/internal/finger"
-	"github.com/sandwich/nophr/internal/gemini"
-	"github.com/sandwich/nophr/internal/gopher"
-	"github.com/sandwich/nophr/internal/ops"
-	"github.com/sandwich/nophr/internal/sections"
-	"github.com/sandwich/nophr/internal/storage"
-	"github.com/sandwich/nophr/internal/sync"
+	"github.com/sandwichfarm/nophr/internal/aggregates"
+	"github.com/sandwichfarm/nophr/internal/cache"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/exporter"
+	"github.com/sandwichfarm/nophr/internal/finger"
+	"github.com/sandwichfarm/nophr/internal/gemini"
+	"github.com/sandwichfarm/nophr/internal/gopher"
+	nostrclient "github.com/sandwichfarm/nophr/internal/nostr"
+	"github.com/sandwichfarm/nophr/internal/nostr/helpers"
+	"github.com/sandwichfarm/nophr/internal/ops"
+	"github.com/sandwichfarm/nophr/internal/outbox"
+	"github.com/sandwichfarm/nophr/internal/relay"
+	"github.com/sandwichfarm/nophr/internal/sections"
+	"github.com/sandwichfarm/nophr/internal/security"
+	"github.com/sandwichfarm/nophr/internal/storage"
+	"github.com/sandwichfarm/nophr/internal/sync"
+	"github.com/sandwichfarm/nophr/internal/web"
 )
 
 var (
@@ -29,15 +37,24 @@ var (
 )
 
 func main() {
-	// Define subcommands
-	if len(os.Args) > 1 && os.Args[1] == "init" {
-		handleInit()
-		return
+	// Operator subcommands
+	if len(os.Args) > 1 {
+		if cmd := findCommand(os.Args[1]); cmd != nil {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				if errors.Is(err, flag.ErrHelp) {
+					return
+				}
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
 	}
 
 	var (
 		showVersion = flag.Bool("version", false, "Show version information")
 		configPath  = flag.String("config", "", "Path to configuration file")
+		watch       = flag.Bool("watch", false, "Reload configuration when the file changes")
 	)
 	flag.Parse()
 
@@ -55,16 +72,17 @@ func main() {
 		fmt.Println("No configuration file specified. Use --config <path> to specify config.")
 		fmt.Println()
 		fmt.Println("Commands:")
-		fmt.Println("  nophr init              Generate example configuration")
-		fmt.Println("  nophr --version         Show version information")
-		fmt.Println("  nophr --config <path>   Start with configuration file")
+		fmt.Printf("  nophr %-46s %s\n", "--version", "Show version information")
+		fmt.Printf("  nophr %-46s %s\n", "--config <path> [--watch]", "Start with configuration file")
+		fmt.Printf("  %-52s %s\n", "", "(--watch reloads it when the file changes)")
+		printCommands()
 		os.Exit(1)
 	}
 
 	// Load and validate configuration
-	cfg, err := config.Load(*configPath)
+	cfg, err := loadConfig(*configPath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -75,18 +93,25 @@ func main() {
 	fmt.Println()
 
 	// Run the application
-	if err := run(cfg); err != nil {
+	if err := run(cfg, *configPath, *watch); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func run(cfg *config.Config) error {
+func run(cfg *config.Config, configPath string, watch bool) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Initialize storage
+	// Initialize storage, held for as long as the server runs so operator
+	// commands cannot change it underneath
 	fmt.Println("Initializing storage...")
+	release, err := storage.Lock(&cfg.Storage)
+	if err != nil {
+		return lockError(err)
+	}
+	defer release()
+
 	st, err := storage.New(ctx, &cfg.Storage)
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %w", err)
@@ -128,6 +153,15 @@ func run(cfg *config.Config) error {
 
 	defer retentionMgr.Stop()
 
+	// Scheduled online backups
+	if cfg.Backup.Enabled {
+		backupMgr := ops.NewBackupManager(&cfg.Storage, logger)
+		periodicBackup := ops.NewPeriodicBackup(backupMgr, &cfg.Backup, logger)
+		go periodicBackup.Start(ctx)
+		defer periodicBackup.Stop()
+		fmt.Printf("  Periodic backups enabled: every %d hours to %s\n", cfg.Backup.IntervalHours, cfg.Backup.Dir)
+	}
+
 	// Initialize static gopher exporter (optional)
 	var gopherExporter *exporter.GopherExporter
 	if cfg.Export.Gopher.Enabled {
@@ -148,6 +182,35 @@ func run(cfg *config.Config) error {
 		geminiExporter = exp
 	}
 
+	// Initialize Atom/RSS/JSON Feed exporter (optional)
+	var feedExporter *exporter.FeedExporter
+	if cfg.Export.Feeds.Enabled {
+		exp, err := exporter.NewFeedExporter(cfg, st)
+		if err != nil {
+			return fmt.Errorf("failed to initialize feed exporter: %w", err)
+		}
+		feedExporter = exp
+		defer feedExporter.Stop()
+	}
+
+	// Initialize response cache (optional)
+	var responseCache cache.Cache
+	if cfg.Caching.Enabled {
+		fmt.Println("Initializing response cache...")
+		c, err := cache.New(cache.FromConfig(&cfg.Caching))
+		if err != nil {
+			return fmt.Errorf("failed to initialize cache: %w", err)
+		}
+		defer c.Close()
+		responseCache = c
+		fmt.Printf("  Cache: %s ready\n", cfg.Caching.Engine)
+	}
+
+	var invalidator *cache.Invalidator
+	if responseCache != nil {
+		invalidator = cache.NewInvalidator(responseCache)
+	}
+
 	// Initialize sync engine if enabled
 	var syncEngine *sync.Engine
 	if cfg.Sync.Enabled {
@@ -160,14 +223,7 @@ func run(cfg *config.Config) error {
 			syncEngine.SetRetentionEvaluator(retentionMgr.EvaluateEvent)
 		}
 
-		if gopherExporter != nil {
-			fmt.Println("  Enabling static gopher export on owner publishes...")
-			syncEngine.AddEventHandler(gopherExporter.HandleEvent)
-		}
-		if geminiExporter != nil {
-			fmt.Println("  Enabling static gemini export on owner publishes...")
-			syncEngine.AddEventHandler(geminiExporter.HandleEvent)
-		}
+		addIngestHandlers(syncEngine, gopherExporter, geminiExporter, feedExporter, invalidator)
 
 		if err := syncEngine.Start(); err != nil {
 			return fmt.Errorf("failed to start sync engine: %w", err)
@@ -176,13 +232,48 @@ func run(cfg *config.Config) error {
 		fmt.Println("  Sync engine started")
 	}
 
+	// Diagnostics back the Gemini owner area and the metrics endpoint
+	diagnostics := ops.NewDiagnosticsCollector(version, commit, st, syncEngine)
+	diagnostics.SetRetentionManager(retentionMgr)
+
+	var metrics *ops.Metrics
+	if cfg.Metrics.Enabled {
+		metrics = ops.NewMetrics(diagnostics)
+	}
+
+	// One guard for every server, so max_connections caps them together
+	guard := security.NewGuard(&cfg.Security)
+	defer guard.Close()
+
 	// Initialize protocol servers
 	var servers []interface{ Stop() error }
+	reload := &reloader{
+		path:      configPath,
+		startup:   cfg,
+		current:   cfg,
+		cache:     responseCache,
+		retention: retentionMgr,
+		sync:      syncEngine,
+	}
+	if gopherExporter != nil {
+		reload.servers = append(reload.servers, gopherExporter)
+	}
+	if geminiExporter != nil {
+		reload.servers = append(reload.servers, geminiExporter)
+	}
+	if feedExporter != nil {
+		reload.servers = append(reload.servers, feedExporter)
+	}
 
 	// Gopher server
 	if cfg.Protocols.Gopher.Enabled {
 		fmt.Printf("Starting Gopher server on %s:%d...\n", cfg.Protocols.Gopher.Host, cfg.Protocols.Gopher.Port)
 		gopherServer := gopher.New(&cfg.Protocols.Gopher, cfg, st, cfg.Protocols.Gopher.Host, aggMgr)
+		if responseCache != nil {
+			gopherServer.SetCache(responseCache)
+		}
+		gopherServer.SetMetrics(metrics)
+		gopherServer.SetGuard(guard)
 
 		// Load sections from config
 		if len(cfg.Sections) > 0 {
@@ -196,6 +287,7 @@ func run(cfg *config.Config) error {
 			return fmt.Errorf("failed to start Gopher server: %w", err)
 		}
 		servers = append(servers, gopherServer)
+		reload.servers = append(reload.servers, gopherServer)
 		fmt.Println("  Gopher server ready")
 	}
 
@@ -206,6 +298,40 @@ func run(cfg *config.Config) error {
 		if err != nil {
 			return fmt.Errorf("failed to create Gemini server: %w", err)
 		}
+		if responseCache != nil {
+			geminiServer.SetCache(responseCache)
+		}
+		geminiServer.SetMetrics(metrics)
+		geminiServer.SetGuard(guard)
+
+		// Owner area: full diagnostics and retention controls
+		geminiServer.SetDiagnostics(diagnostics)
+		geminiServer.SetRetentionManager(retentionMgr)
+
+		// Compose flow for owner client certificates
+		if hasOwnerCert(cfg) {
+			client := nostrclient.New(ctx, &cfg.Relays)
+			defer client.Close()
+
+			ob, err := outbox.New(cfg, st, client)
+			if err != nil {
+				return fmt.Errorf("failed to initialize outbox: %w", err)
+			}
+			if gopherExporter != nil {
+				ob.AddEventHandler(gopherExporter.HandleEvent)
+			}
+			if geminiExporter != nil {
+				ob.AddEventHandler(geminiExporter.HandleEvent)
+			}
+			if feedExporter != nil {
+				ob.AddEventHandler(feedExporter.HandleEvent)
+			}
+			if invalidator != nil {
+				ob.AddEventHandler(invalidator.HandleEvent)
+			}
+			geminiServer.SetOutbox(ob)
+			fmt.Println("  Compose enabled for owner client certificates")
+		}
 
 		// Load sections from config
 		if len(cfg.Sections) > 0 {
@@ -218,6 +344,7 @@ func run(cfg *config.Config) error {
 			return fmt.Errorf("failed to start Gemini server: %w", err)
 		}
 		servers = append(servers, geminiServer)
+		reload.servers = append(reload.servers, geminiServer)
 		fmt.Println("  Gemini server ready")
 	}
 
@@ -225,26 +352,126 @@ func run(cfg *config.Config) error {
 	if cfg.Protocols.Finger.Enabled {
 		fmt.Printf("Starting Finger server on port %d...\n", cfg.Protocols.Finger.Port)
 		fingerServer := finger.New(&cfg.Protocols.Finger, cfg, st, aggMgr)
+		if responseCache != nil {
+			fingerServer.SetCache(responseCache)
+		}
+		fingerServer.SetMetrics(metrics)
+		fingerServer.SetGuard(guard)
 		if err := fingerServer.Start(); err != nil {
 			return fmt.Errorf("failed to start Finger server: %w", err)
 		}
 		servers = append(servers, fingerServer)
+		reload.servers = append(reload.servers, fingerServer)
 		fmt.Println("  Finger server ready")
 	}
 
+	// HTML gateway
+	if cfg.Protocols.HTTP.Enabled {
+		fmt.Printf("Starting HTTP server on %s:%d...\n", cfg.Protocols.HTTP.Host, cfg.Protocols.HTTP.Port)
+		httpServer := web.New(&cfg.Protocols.HTTP, cfg, st, aggMgr)
+		if responseCache != nil {
+			httpServer.SetCache(responseCache)
+		}
+		httpServer.SetMetrics(metrics)
+		httpServer.SetGuard(guard)
+
+		// Load sections from config
+		if len(cfg.Sections) > 0 {
+			if err := sections.LoadFromConfig(httpServer.GetSectionManager(), cfg.Sections); err != nil {
+				return fmt.Errorf("failed to load HTTP sections: %w", err)
+			}
+		}
+
+		if err := httpServer.Start(); err != nil {
+			return fmt.Errorf("failed to start HTTP server: %w", err)
+		}
+		servers = append(servers, httpServer)
+		reload.servers = append(reload.servers, httpServer)
+		fmt.Println("  HTTP server ready")
+	}
+
+	// Local Nostr relay
+	if cfg.Protocols.Relay.Enabled {
+		fmt.Printf("Starting relay server on port %d...\n", cfg.Protocols.Relay.Port)
+		relayServer, err := relay.New(&cfg.Protocols.Relay, cfg, st)
+		if err != nil {
+			return fmt.Errorf("failed to create relay server: %w", err)
+		}
+		relayServer.SetGuard(guard)
+		if cfg.Protocols.Relay.OwnerWrites {
+			// Owner events get the same bookkeeping as synced ones, so
+			// deletion requests are recorded and reach the exports
+			ingest := syncEngine
+			if ingest == nil {
+				ingest = sync.NewEngine(st, cfg)
+				defer ingest.Stop()
+				addIngestHandlers(ingest, gopherExporter, geminiExporter, feedExporter, invalidator)
+			}
+			relayServer.AddEventHandler(ingest.HandleStored)
+		}
+		if err := relayServer.Start(); err != nil {
+			return fmt.Errorf("failed to start relay server: %w", err)
+		}
+		servers = append(servers, relayServer)
+		if cfg.Protocols.Relay.OwnerWrites {
+			fmt.Println("  Relay server ready (owner writes enabled)")
+		} else {
+			fmt.Println("  Relay server ready (read-only)")
+		}
+	}
+
 	if len(servers) == 0 {
 		return fmt.Errorf("no protocol servers enabled")
 	}
 
+	// Prometheus metrics
+	if metrics != nil {
+		metricsServer := ops.NewMetricsServer(&cfg.Metrics, metrics)
+		if err := metricsServer.Start(); err != nil {
+			return fmt.Errorf("failed to start metrics server: %w", err)
+		}
+		servers = append(servers, metricsServer)
+	}
+
+	// Reload configuration on SIGHUP; with --watch, file changes are
+	// handled as if SIGHUP had been sent
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+
+	if watch {
+		err := config.Watch(ctx, configPath, func() {
+			select {
+			case hupChan <- syscall.SIGHUP:
+			default: // a reload is already pending
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to watch configuration: %w", err)
+		}
+	}
+
 	fmt.Println()
 	fmt.Println("✓ All services started successfully!")
 	fmt.Println()
-	fmt.Println("Press Ctrl+C to shutdown gracefully...")
+	if watch {
+		fmt.Printf("Watching %s for changes\n", configPath)
+	}
+	fmt.Println("Send SIGHUP to reload configuration, press Ctrl+C to shutdown gracefully...")
 
-	// Wait for interrupt signal
+	// Wait for interrupt signal, reloading configuration on request
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	<-sigChan
+
+	for waiting := true; waiting; {
+		select {
+		case <-sigChan:
+			waiting = false
+		case <-hupChan:
+			if err := reload.reload(); err != nil {
+				fmt.Fprintf(os.Stderr, "[CONFIG] ⚠ Reload failed, keeping current configuration: %v\n", err)
+			}
+		}
+	}
 
 	fmt.Println()
 	fmt.Println("Shutting down gracefully...")
@@ -270,3 +497,46 @@ func handleInit() {
 	// Write to stdout
 	fmt.Print(string(exampleConfig))
 }
+
+// hasOwnerCert reports whether any Gemini client certificate can act as the owner
+func hasOwnerCert(cfg *config.Config) bool {
+	if len(cfg.Outbox.CertFingerprints) > 0 {
+		return true
+	}
+	owner, _ := helpers.NormalizePubkey(cfg.Identity.Npub)
+	for _, cert := range cfg.Protocols.Gemini.ClientCerts {
+		if cert.Identity == "owner" {
+			return true
+		}
+		if pubkey, err := helpers.NormalizePubkey(cert.Identity); err == nil && pubkey == owner {
+			return true
+		}
+	}
+	return false
+}
+
+// addIngestHandlers notifies the static exporters and the response cache of
+// the events and deletions an engine ingests
+func addIngestHandlers(engine *sync.Engine, gopherExporter *exporter.GopherExporter, geminiExporter *exporter.GeminiExporter, feedExporter *exporter.FeedExporter, invalidator *cache.Invalidator) {
+	if gopherExporter != nil {
+		fmt.Println("  Enabling static gopher export on owner publishes...")
+		engine.AddEventHandler(gopherExporter.HandleEvent)
+		engine.AddDeletionHandler(gopherExporter.HandleDeletion)
+	}
+	if geminiExporter != nil {
+		fmt.Println("  Enabling static gemini export on owner publishes...")
+		engine.AddEventHandler(geminiExporter.HandleEvent)
+		engine.AddDeletionHandler(geminiExporter.HandleDeletion)
+	}
+	if feedExporter != nil {
+		fmt.Println("  Enabling feed export on new content...")
+		engine.AddEventHandler(feedExporter.HandleEvent)
+		engine.AddDeletionHandler(feedExporter.HandleDeletion)
+	}
+
+	if invalidator != nil {
+		fmt.Println("  Enabling cache invalidation on ingest...")
+		engine.AddEventHandler(invalidator.HandleEvent)
+		engine.AddDeletionHandler(invalidator.HandleDeletion)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sandwichfarm/nophr/internal/cache"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/ops"
+	"github.com/sandwichfarm/nophr/internal/sync"
+)
+
+// reloadable is a protocol server or static exporter that applies
+// configuration changes live. PrepareReload does everything that can fail
+// and returns a function that switches to the new settings and cannot.
+type reloadable interface {
+	PrepareReload(cfg *config.Config) (apply func(), err error)
+}
+
+// reloader re-reads the configuration file on SIGHUP or when it changes and
+// applies what it can to the running services
+type reloader struct {
+	path      string
+	startup   *config.Config // what listeners, storage and schedules were built from
+	current   *config.Config // what live settings were last applied from
+	servers   []reloadable   // protocol servers and exporters
+	cache     cache.Cache    // nil when caching is disabled
+	retention *ops.RetentionManager
+	sync      *sync.Engine // nil when sync is disabled
+}
+
+// reload loads and validates the configuration file, then applies its live
+// settings to every service. A file that fails to load or validate leaves
+// the running configuration untouched.
+func (r *reloader) reload() error {
+	next, err := config.Load(r.path)
+	if err != nil {
+		return err
+	}
+
+	plan := config.PlanReload(r.current, next)
+	pending := config.PlanReload(r.startup, next).Restart
+
+	// Prepare every service before switching any, so a failure leaves them
+	// all on the current configuration
+	applies := make([]func(), 0, len(r.servers))
+	for _, server := range r.servers {
+		apply, err := server.PrepareReload(next)
+		if err != nil {
+			return err
+		}
+		applies = append(applies, apply)
+	}
+
+	for _, apply := range applies {
+		apply()
+	}
+	r.retention.SetConfig(&next.Sync.Retention)
+	if r.sync != nil {
+		r.sync.SetScope(&next.Sync)
+	}
+	r.current = next
+
+	// Cached responses were rendered with the old settings. The new ones
+	// are already live, so a failed clear only delays them until the
+	// entries expire.
+	if r.cache != nil {
+		if err := r.cache.Clear(context.Background()); err != nil {
+			fmt.Printf("[CONFIG] ⚠ Failed to clear response cache: %v\n", err)
+		}
+	}
+
+	if plan.Empty() && len(pending) == 0 {
+		fmt.Println("[CONFIG] ✓ Reloaded, no changes")
+		return nil
+	}
+	fmt.Println("[CONFIG] ✓ Reloaded")
+	for _, key := range plan.Live {
+		fmt.Printf("[CONFIG]   applied: %s\n", key)
+	}
+	for _, key := range pending {
+		fmt.Printf("[CONFIG]   ⚠ needs a restart: %s\n", key)
+	}
+	return nil
+}
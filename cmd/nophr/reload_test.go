@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sandwichfarm/nophr/internal/cache"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/ops"
+)
+
+// fakeReloadable records the site title it was switched to
+type fakeReloadable struct {
+	title string
+	fail  bool
+}
+
+func (f *fakeReloadable) PrepareReload(cfg *config.Config) (func(), error) {
+	if f.fail {
+		return nil, fmt.Errorf("prepare failed")
+	}
+	return func() { f.title = cfg.Site.Title }, nil
+}
+
+func writeReloadConfig(t *testing.T, path, title string) {
+	t.Helper()
+	content := fmt.Sprintf(`
+site:
+  title: %q
+identity:
+  npub: "npub1nq3zgtqruwhnz0xx40gh4a4fkamlr2sc7ke5wqs2s3nyv2fpy9esg4hdwq"
+protocols:
+  gopher:
+    enabled: true
+    port: 70
+relays:
+  seeds:
+    - "wss://relay.test"
+sync:
+  scope:
+    mode: "self"
+storage:
+  driver: "sqlite"
+logging:
+  level: "info"
+`, title)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+}
+
+func TestReloadAppliesAllOrNothing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nophr.yaml")
+	writeReloadConfig(t, path, "Old")
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	c := cache.NewMemoryCache(cache.DefaultConfig())
+	defer c.Close()
+	ctx := context.Background()
+	if err := c.Set(ctx, "page", []byte("old"), 0); err != nil {
+		t.Fatalf("Failed to set cache entry: %v", err)
+	}
+
+	first, second := &fakeReloadable{title: "Old"}, &fakeReloadable{title: "Old", fail: true}
+	r := &reloader{
+		path:      path,
+		startup:   cfg,
+		current:   cfg,
+		servers:   []reloadable{first, second},
+		cache:     c,
+		retention: ops.NewRetentionManager(nil, &cfg.Sync.Retention, ops.NewLogger(&cfg.Logging), ""),
+	}
+
+	// A service that fails to prepare leaves every service, the current
+	// configuration and the cache as they were
+	writeReloadConfig(t, path, "New")
+	if err := r.reload(); err == nil {
+		t.Fatal("Expected the reload to fail")
+	}
+	if first.title != "Old" || r.current != cfg {
+		t.Errorf("A failed reload switched a service to %q", first.title)
+	}
+	if _, hit, _ := c.Get(ctx, "page"); !hit {
+		t.Error("A failed reload should keep the response cache")
+	}
+
+	second.fail = false
+	if err := r.reload(); err != nil {
+		t.Fatalf("reload() error = %v", err)
+	}
+	if first.title != "New" || second.title != "New" || r.current.Site.Title != "New" {
+		t.Errorf("Expected every service on the new config, got %q and %q", first.title, second.title)
+	}
+	if _, hit, _ := c.Get(ctx, "page"); hit {
+		t.Error("A reload should clear responses rendered with the old settings")
+	}
+}
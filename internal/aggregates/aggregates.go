@@ -110,6 +110,7 @@ type EventAggregates struct {
 	ReactionCounts  map[string]int
 	ZapSatsTotal    int64
 	LastInteraction int64
+	Zappers         []*Zapper // Top zappers, for detail views
 }
 
 // HasInteractions returns true if the event has any interactions
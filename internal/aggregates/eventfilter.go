@@ -0,0 +1,42 @@
+package aggregates
+
+import (
+	"sync"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/security"
+)
+
+// EventFilter hides events from denied pubkeys, with banned content or
+// muted by the owner. Each protocol server installs one on its query helper
+// and section manager so every list it renders is filtered the same way.
+type EventFilter struct {
+	mu    sync.RWMutex
+	guard *security.Guard
+	mutes *MuteFilter
+}
+
+// NewEventFilter creates a filter for a guard's security policy and the
+// owner's mute list
+func NewEventFilter(guard *security.Guard, mutes *MuteFilter) *EventFilter {
+	return &EventFilter{
+		guard: guard,
+		mutes: mutes,
+	}
+}
+
+// SetGuard switches the guard whose security policy is applied
+func (f *EventFilter) SetGuard(guard *security.Guard) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.guard = guard
+}
+
+// Filter drops events hidden by the security policy or the owner's mute list
+func (f *EventFilter) Filter(events []*nostr.Event) []*nostr.Event {
+	f.mu.RLock()
+	guard := f.guard
+	f.mu.RUnlock()
+
+	return f.mutes.FilterEvents(guard.FilterEvents(events))
+}
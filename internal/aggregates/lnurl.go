@@ -0,0 +1,243 @@
+package aggregates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/nbd-wtf/go-nostr"
+	nostrclient "github.com/sandwichfarm/nophr/internal/nostr"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+const (
+	// zapProviderTTL is how long a recipient's provider pubkey is cached
+	zapProviderTTL = 6 * time.Hour
+
+	// zapProviderRetry is how long a failed lookup is cached before retrying
+	zapProviderRetry = time.Minute
+
+	// lnurlTimeout bounds a single LNURL-pay endpoint request
+	lnurlTimeout = 10 * time.Second
+)
+
+// ErrNoLightningAddress means a zap recipient has no stored profile naming a
+// lightning address, so their provider cannot be resolved until one arrives
+var ErrNoLightningAddress = errors.New("no lightning address")
+
+// lnurlPayInfo is the part of an LNURL-pay response NIP-57 uses
+type lnurlPayInfo struct {
+	AllowsNostr bool   `json:"allowsNostr"`
+	NostrPubkey string `json:"nostrPubkey"`
+}
+
+// zapProvider is a cached provider lookup for one recipient
+type zapProvider struct {
+	address string // lud16 or lud06 the lookup was made for
+	pubkey  string
+	err     error
+	expires time.Time
+}
+
+// ZapProviders resolves and caches the nostrPubkey of each recipient's
+// LNURL provider, which must sign the recipient's zap receipts (NIP-57)
+type ZapProviders struct {
+	storage *storage.Storage
+	fetch   func(ctx context.Context, url string) (*lnurlPayInfo, error)
+
+	mu    sync.Mutex
+	cache map[string]*zapProvider // by recipient pubkey
+}
+
+// NewZapProviders creates a provider cache that reads recipients' lud16/lud06
+// from their stored profiles
+func NewZapProviders(st *storage.Storage) *ZapProviders {
+	return &ZapProviders{
+		storage: st,
+		fetch:   fetchLNURLPay,
+		cache:   make(map[string]*zapProvider),
+	}
+}
+
+// ProviderPubkey returns the pubkey allowed to sign zap receipts for a
+// recipient. A lookup is repeated when it expires or the recipient's
+// lightning address changes.
+func (zp *ZapProviders) ProviderPubkey(ctx context.Context, recipient string) (string, error) {
+	address, err := zp.lightningAddress(ctx, recipient)
+	if err != nil {
+		return "", err
+	}
+
+	zp.mu.Lock()
+	cached, ok := zp.cache[recipient]
+	zp.mu.Unlock()
+	if ok && cached.address == address && time.Now().Before(cached.expires) {
+		return cached.pubkey, cached.err
+	}
+
+	provider := &zapProvider{address: address, expires: time.Now().Add(zapProviderTTL)}
+	provider.pubkey, provider.err = zp.lookup(ctx, address)
+	if provider.err != nil {
+		provider.expires = time.Now().Add(zapProviderRetry)
+	}
+
+	zp.mu.Lock()
+	zp.cache[recipient] = provider
+	zp.mu.Unlock()
+
+	return provider.pubkey, provider.err
+}
+
+// lightningAddress returns the lud16 or lud06 of a recipient's profile
+func (zp *ZapProviders) lightningAddress(ctx context.Context, recipient string) (string, error) {
+	events, err := zp.storage.QueryEvents(ctx, nostr.Filter{
+		Kinds:   []int{0},
+		Authors: []string{recipient},
+		Limit:   1,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to query profile: %w", err)
+	}
+	if len(events) == 0 {
+		return "", fmt.Errorf("no profile for recipient %s: %w", recipient, ErrNoLightningAddress)
+	}
+
+	address := nostrclient.ParseProfile(events[0]).GetLightningAddress()
+	if address == "" {
+		return "", fmt.Errorf("recipient %s: %w", recipient, ErrNoLightningAddress)
+	}
+	return address, nil
+}
+
+// lookup fetches the provider pubkey for a lightning address
+func (zp *ZapProviders) lookup(ctx context.Context, address string) (string, error) {
+	url, err := lnurlPayURL(address)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := zp.fetch(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	if !info.AllowsNostr || !nostr.IsValidPublicKey(info.NostrPubkey) {
+		return "", fmt.Errorf("provider for %s does not support zaps", address)
+	}
+
+	return info.NostrPubkey, nil
+}
+
+// lnurlPayURL returns the LNURL-pay endpoint of a lud16 address
+// (name@domain, LUD-16) or a bech32 lud06 LNURL. Profiles come from anyone,
+// so the endpoint must be https on a domain name for lud16, and https for
+// lud06; lnurlClient checks the address it resolves to.
+func lnurlPayURL(address string) (string, error) {
+	if name, domain, ok := strings.Cut(address, "@"); ok {
+		if name == "" || !validLightningDomain(domain) {
+			return "", fmt.Errorf("invalid lightning address: %s", address)
+		}
+		return fmt.Sprintf("https://%s/.well-known/lnurlp/%s", domain, url.PathEscape(name)), nil
+	}
+
+	prefix, data, err := bech32.DecodeNoLimit(strings.ToLower(address))
+	if err != nil || prefix != "lnurl" {
+		return "", fmt.Errorf("invalid lnurl: %s", address)
+	}
+	decoded, err := bech32.ConvertBits(data, 5, 8, false)
+	if err != nil {
+		return "", fmt.Errorf("invalid lnurl: %s", address)
+	}
+	endpoint, err := url.Parse(string(decoded))
+	if err != nil || endpoint.Scheme != "https" || endpoint.Hostname() == "" || endpoint.User != nil {
+		return "", fmt.Errorf("lnurl is not an https endpoint: %s", address)
+	}
+	return endpoint.String(), nil
+}
+
+// validLightningDomain reports whether the domain of a lud16 address is a
+// plain host name: no port, path or IP literal
+func validLightningDomain(domain string) bool {
+	if domain == "" || strings.ContainsAny(domain, ":/?#[]@\\") {
+		return false
+	}
+	return net.ParseIP(domain) == nil
+}
+
+// lnurlClient fetches LNURL-pay endpoints. Any author can name one in their
+// profile, so connections, including redirects, are only made to public
+// addresses, and proxies from the environment are not used.
+var lnurlClient = &http.Client{
+	Timeout: lnurlTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: lnurlTimeout,
+			Control: dialPublicOnly,
+		}).DialContext,
+		TLSHandshakeTimeout: lnurlTimeout,
+		ForceAttemptHTTP2:   true,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return fmt.Errorf("lnurl redirect to %s is not https", req.URL)
+		}
+		if len(via) >= 5 {
+			return errors.New("too many lnurl redirects")
+		}
+		return nil
+	},
+}
+
+// dialPublicOnly refuses connections to loopback, private, link-local,
+// multicast and unspecified addresses. It runs after DNS resolution, so
+// host names pointing at internal addresses are refused too.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %s: %w", address, err)
+	}
+
+	ip := addrPort.Addr().Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%s is not a public address", ip)
+	}
+	return nil
+}
+
+// fetchLNURLPay requests an LNURL-pay endpoint
+func fetchLNURLPay(ctx context.Context, url string) (*lnurlPayInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, lnurlTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lnurl endpoint %s: %w", url, err)
+	}
+
+	resp, err := lnurlClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: status %d", url, resp.StatusCode)
+	}
+
+	var info lnurlPayInfo
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&info); err != nil {
+		return nil, fmt.Errorf("invalid lnurl response from %s: %w", url, err)
+	}
+	return &info, nil
+}
@@ -0,0 +1,114 @@
+package aggregates
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/config"
+	nostrclient "github.com/sandwichfarm/nophr/internal/nostr"
+	"github.com/sandwichfarm/nophr/internal/nostr/helpers"
+	"github.com/sandwichfarm/nophr/internal/security"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+// muteListTTL is how long the owner's mute list is used before storage is
+// checked for a newer one
+const muteListTTL = 30 * time.Second
+
+// MuteFilter hides events muted by the owner's NIP-51 mute list (kind 10000).
+// Private entries are decrypted when NOPHR_NSEC holds the owner's key.
+type MuteFilter struct {
+	storage *storage.Storage
+	owner   string
+
+	mu        sync.Mutex
+	secretKey *string // loaded on first use, "" when unavailable
+	listID    string  // ID of the event list was parsed from
+	list      *nostrclient.MuteList
+	checked   time.Time
+}
+
+// NewMuteFilter creates a filter for the configured owner's mute list
+func NewMuteFilter(st *storage.Storage, cfg *config.Config) *MuteFilter {
+	owner, _ := helpers.NormalizePubkey(cfg.Identity.Npub)
+	return &MuteFilter{
+		storage: st,
+		owner:   owner,
+	}
+}
+
+// MuteList returns the owner's current mute list, or nil if there is none
+func (mf *MuteFilter) MuteList(ctx context.Context) *nostrclient.MuteList {
+	mf.mu.Lock()
+	defer mf.mu.Unlock()
+
+	if time.Since(mf.checked) < muteListTTL {
+		return mf.list
+	}
+	mf.checked = time.Now()
+
+	if err := mf.load(ctx); err != nil {
+		fmt.Printf("[MUTES] ⚠ %v\n", err)
+	}
+	return mf.list
+}
+
+// load re-parses the owner's mute list if a newer one was stored
+func (mf *MuteFilter) load(ctx context.Context) error {
+	if mf.owner == "" {
+		return nil
+	}
+
+	events, err := mf.storage.QueryEvents(ctx, nostr.Filter{
+		Kinds:   []int{nostrclient.KindMuteList},
+		Authors: []string{mf.owner},
+		Limit:   1,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query mute list: %w", err)
+	}
+	if len(events) == 0 {
+		mf.list, mf.listID = nil, ""
+		return nil
+	}
+	if events[0].ID == mf.listID {
+		return nil
+	}
+
+	if mf.secretKey == nil {
+		sk, _ := security.OwnerSecretKey(mf.owner)
+		mf.secretKey = &sk
+	}
+
+	// A list whose private part fails to decrypt still mutes its public entries
+	list, err := nostrclient.ParseMuteList(events[0], *mf.secretKey)
+	if list != nil {
+		mf.list, mf.listID = list, events[0].ID
+	}
+	return err
+}
+
+// IsMuted reports whether the owner has muted a pubkey
+func (mf *MuteFilter) IsMuted(ctx context.Context, pubkey string) bool {
+	list := mf.MuteList(ctx)
+	return list != nil && list.Pubkeys[pubkey]
+}
+
+// FilterEvents removes muted events. The owner's own events are never muted.
+func (mf *MuteFilter) FilterEvents(events []*nostr.Event) []*nostr.Event {
+	list := mf.MuteList(context.Background())
+	if list == nil || list.IsEmpty() {
+		return events
+	}
+
+	filtered := make([]*nostr.Event, 0, len(events))
+	for _, event := range events {
+		if event.PubKey == mf.owner || !list.Mutes(event) {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,84 @@
+package aggregates
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/nbd-wtf/go-nostr/nip44"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+func TestMuteFilter(t *testing.T) {
+	ctx := context.Background()
+	st, err := storage.New(ctx, &config.Storage{Driver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	ownerKey := nostr.GeneratePrivateKey()
+	owner, _ := nostr.GetPublicKey(ownerKey)
+	npub, _ := nip19.EncodePublicKey(owner)
+	nsec, _ := nip19.EncodePrivateKey(ownerKey)
+	loud, quiet, friend := "loud", "quiet", "friend"
+
+	cfg := config.Default()
+	cfg.Identity.Npub = npub
+
+	// Without a mute list nothing is hidden
+	events := []*nostr.Event{
+		{ID: "1", PubKey: loud},
+		{ID: "2", PubKey: quiet},
+		{ID: "3", PubKey: friend, Content: "no spoilers here"},
+		{ID: "4", PubKey: owner, Content: "my own spoilers"},
+	}
+	if got := NewMuteFilter(st, cfg).FilterEvents(events); len(got) != 4 {
+		t.Fatalf("Expected no events muted without a mute list, got %d", len(got))
+	}
+
+	private, _ := json.Marshal(nostr.Tags{{"p", quiet}})
+	key, _ := nip44.GenerateConversationKey(owner, ownerKey)
+	content, _ := nip44.Encrypt(string(private), key)
+	list := &nostr.Event{
+		Kind:      10000,
+		CreatedAt: nostr.Now(),
+		Tags:      nostr.Tags{{"p", loud}, {"word", "spoilers"}},
+		Content:   content,
+	}
+	list.Sign(ownerKey)
+	if err := st.StoreEvent(ctx, list); err != nil {
+		t.Fatalf("failed to store mute list: %v", err)
+	}
+
+	ids := func(events []*nostr.Event) []string {
+		var ids []string
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		return ids
+	}
+
+	// Public entries apply without the owner's key; the owner's own events
+	// are never muted
+	t.Setenv("NOPHR_NSEC", "")
+	got := ids(NewMuteFilter(st, cfg).FilterEvents(events))
+	if len(got) != 2 || got[0] != "2" || got[1] != "4" {
+		t.Errorf("FilterEvents() without key = %v, want [2 4]", got)
+	}
+
+	// Private entries apply once NOPHR_NSEC is set
+	t.Setenv("NOPHR_NSEC", nsec)
+	mutes := NewMuteFilter(st, cfg)
+	got = ids(mutes.FilterEvents(events))
+	if len(got) != 1 || got[0] != "4" {
+		t.Errorf("FilterEvents() with key = %v, want [4]", got)
+	}
+	if !mutes.IsMuted(ctx, quiet) || mutes.IsMuted(ctx, friend) {
+		t.Error("IsMuted() should report the privately muted pubkey only")
+	}
+}
@@ -4,18 +4,22 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"sync"
 
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip19"
 	"github.com/sandwichfarm/nophr/internal/config"
+	nostrclient "github.com/sandwichfarm/nophr/internal/nostr"
 	"github.com/sandwichfarm/nophr/internal/storage"
 )
 
 // QueryHelper provides helper methods for inbox/outbox queries
 type QueryHelper struct {
-	storage *storage.Storage
-	config  *config.Config
-	manager *Manager
+	storage     *storage.Storage
+	mu          sync.RWMutex
+	config      *config.Config
+	manager     *Manager
+	eventFilter func([]*nostr.Event) []*nostr.Event
 }
 
 // NewQueryHelper creates a new query helper
@@ -27,15 +31,82 @@ func NewQueryHelper(st *storage.Storage, cfg *config.Config, mgr *Manager) *Quer
 	}
 }
 
+// SetConfig switches the display and behavior settings queries follow
+func (qh *QueryHelper) SetConfig(cfg *config.Config) {
+	qh.mu.Lock()
+	defer qh.mu.Unlock()
+	qh.config = cfg
+}
+
+// settings returns the configuration queries currently follow
+func (qh *QueryHelper) settings() *config.Config {
+	qh.mu.RLock()
+	defer qh.mu.RUnlock()
+	return qh.config
+}
+
+// SetEventFilter installs a filter applied to every event list the helper
+// reads from storage (e.g. the security deny list)
+func (qh *QueryHelper) SetEventFilter(filter func([]*nostr.Event) []*nostr.Event) {
+	qh.eventFilter = filter
+}
+
+// QueryEvents queries storage and applies the event filter, if any. Events
+// are filtered before the limit, so a filtered list still fills up.
+func (qh *QueryHelper) QueryEvents(ctx context.Context, filter nostr.Filter) ([]*nostr.Event, error) {
+	if qh.eventFilter == nil {
+		return qh.storage.QueryEvents(ctx, filter)
+	}
+	return qh.storage.QueryEventsFiltered(ctx, filter, qh.eventFilter)
+}
+
+// queryFeed queries events for a feed, dropping notes with a content warning
+// when display.feed.hide_sensitive is set
+func (qh *QueryHelper) queryFeed(ctx context.Context, filter nostr.Filter) ([]*nostr.Event, error) {
+	return qh.storage.QueryEventsFiltered(ctx, filter, qh.FilterFeedEvents)
+}
+
+// FilterFeedEvents applies the event filter and, when
+// display.feed.hide_sensitive is set, drops notes with a content warning.
+// Servers install it on their section manager.
+func (qh *QueryHelper) FilterFeedEvents(events []*nostr.Event) []*nostr.Event {
+	if qh.eventFilter != nil {
+		events = qh.eventFilter(events)
+	}
+	if qh.settings().Display.Feed.HideSensitive {
+		events = nostrclient.WithoutSensitive(events)
+	}
+	return events
+}
+
 // getOwnerHex decodes the owner's npub to hex pubkey
 func (qh *QueryHelper) getOwnerHex() (string, error) {
-	if _, hex, err := nip19.Decode(qh.config.Identity.Npub); err != nil {
+	if _, hex, err := nip19.Decode(qh.settings().Identity.Npub); err != nil {
 		return "", fmt.Errorf("failed to decode npub: %w", err)
 	} else {
 		return hex.(string), nil
 	}
 }
 
+// GetZapSummary totals the validated zaps of an event by sender
+func (qh *QueryHelper) GetZapSummary(ctx context.Context, eventID string) (*ZapSummary, error) {
+	zaps, err := qh.storage.GetZaps(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	return SummarizeZaps(zaps), nil
+}
+
+// GetProfileZapSummary totals the validated zaps of a pubkey's profile by
+// sender. Zaps of the pubkey's events are not included.
+func (qh *QueryHelper) GetProfileZapSummary(ctx context.Context, pubkey string) (*ZapSummary, error) {
+	zaps, err := qh.storage.GetProfileZaps(ctx, pubkey)
+	if err != nil {
+		return nil, err
+	}
+	return SummarizeZaps(zaps), nil
+}
+
 // GetOutboxNotes returns notes authored by the owner
 func (qh *QueryHelper) GetOutboxNotes(ctx context.Context, limit int) ([]*EnrichedEvent, error) {
 	ownerHex, err := qh.getOwnerHex()
@@ -49,7 +120,7 @@ func (qh *QueryHelper) GetOutboxNotes(ctx context.Context, limit int) ([]*Enrich
 		Limit:   limit,
 	}
 
-	events, err := qh.storage.QueryEvents(ctx, filter)
+	events, err := qh.queryFeed(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -73,7 +144,7 @@ func (qh *QueryHelper) GetInboxReplies(ctx context.Context, limit int) ([]*Enric
 		Limit: limit * 2, // Get more since we'll filter
 	}
 
-	events, err := qh.storage.QueryEvents(ctx, filter)
+	events, err := qh.queryFeed(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -121,7 +192,7 @@ func (qh *QueryHelper) GetInboxReactions(ctx context.Context, limit int) ([]*Enr
 		Limit: limit,
 	}
 
-	events, err := qh.storage.QueryEvents(ctx, filter)
+	events, err := qh.QueryEvents(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -138,7 +209,7 @@ func (qh *QueryHelper) GetThreadReplies(ctx context.Context, rootEventID string)
 		},
 	}
 
-	events, err := qh.storage.QueryEvents(ctx, filter)
+	events, err := qh.QueryEvents(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -182,7 +253,7 @@ func (qh *QueryHelper) GetThreadByEvent(ctx context.Context, eventID string) (*T
 		Limit: qh.threadQueryLimit(),
 	}
 
-	replyEvents, err := qh.storage.QueryEvents(ctx, filter)
+	replyEvents, err := qh.QueryEvents(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -249,7 +320,7 @@ func (qh *QueryHelper) enrichEvents(ctx context.Context, events []*nostr.Event)
 // filterAndSortEvents applies content filtering and sorting based on config
 func (qh *QueryHelper) filterAndSortEvents(enriched []*EnrichedEvent, sortMode string) []*EnrichedEvent {
 	// Apply content filtering if enabled
-	if qh.config.Behavior.ContentFiltering.Enabled {
+	if qh.settings().Behavior.ContentFiltering.Enabled {
 		filtered := make([]*EnrichedEvent, 0)
 		for _, e := range enriched {
 			if qh.passesContentFilter(e) {
@@ -285,7 +356,7 @@ func (qh *QueryHelper) filterAndSortEvents(enriched []*EnrichedEvent, sortMode s
 
 // passesContentFilter checks if an event passes content filtering rules
 func (qh *QueryHelper) passesContentFilter(e *EnrichedEvent) bool {
-	cfg := qh.config.Behavior.ContentFiltering
+	cfg := qh.settings().Behavior.ContentFiltering
 
 	// Check minimum reactions
 	if cfg.MinReactions > 0 && e.Aggregates.ReactionTotal < cfg.MinReactions {
@@ -334,7 +405,7 @@ func (qh *QueryHelper) GetPopularNotes(ctx context.Context, limit int) ([]*Enric
 		Limit: limit * 10, // Get more to sort
 	}
 
-	events, err := qh.storage.QueryEvents(ctx, filter)
+	events, err := qh.queryFeed(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -392,7 +463,7 @@ func sortThreadNodes(node *ThreadNode) {
 }
 
 func (qh *QueryHelper) fetchSingleEvent(ctx context.Context, eventID string) (*nostr.Event, error) {
-	events, err := qh.storage.QueryEvents(ctx, nostr.Filter{
+	events, err := qh.QueryEvents(ctx, nostr.Filter{
 		IDs:   []string{eventID},
 		Limit: 1,
 	})
@@ -407,7 +478,7 @@ func (qh *QueryHelper) fetchSingleEvent(ctx context.Context, eventID string) (*n
 
 // threadQueryLimit provides a conservative limit for thread queries so we can filter/indent
 func (qh *QueryHelper) threadQueryLimit() int {
-	limit := qh.config.Display.Limits.MaxRepliesInFeed * qh.config.Display.Limits.MaxThreadDepth * 4
+	limit := qh.settings().Display.Limits.MaxRepliesInFeed * qh.settings().Display.Limits.MaxThreadDepth * 4
 	if limit < 200 {
 		return 200
 	}
@@ -434,7 +505,7 @@ func (qh *QueryHelper) GetNotes(ctx context.Context, limit int) ([]*EnrichedEven
 		Limit:   limit * 2, // Get more since we'll filter out replies
 	}
 
-	events, err := qh.storage.QueryEvents(ctx, filter)
+	events, err := qh.queryFeed(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -457,7 +528,7 @@ func (qh *QueryHelper) GetNotes(ctx context.Context, limit int) ([]*EnrichedEven
 	}
 
 	// Apply filtering and sorting
-	enriched = qh.filterAndSortEvents(enriched, qh.config.Behavior.SortPreferences.Notes)
+	enriched = qh.filterAndSortEvents(enriched, qh.settings().Behavior.SortPreferences.Notes)
 
 	// Apply limit after filtering
 	if len(enriched) > limit {
@@ -480,7 +551,7 @@ func (qh *QueryHelper) GetArticles(ctx context.Context, limit int) ([]*EnrichedE
 		Limit:   limit,
 	}
 
-	events, err := qh.storage.QueryEvents(ctx, filter)
+	events, err := qh.queryFeed(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -491,7 +562,7 @@ func (qh *QueryHelper) GetArticles(ctx context.Context, limit int) ([]*EnrichedE
 	}
 
 	// Apply filtering and sorting
-	enriched = qh.filterAndSortEvents(enriched, qh.config.Behavior.SortPreferences.Articles)
+	enriched = qh.filterAndSortEvents(enriched, qh.settings().Behavior.SortPreferences.Articles)
 
 	// Apply limit after filtering
 	if len(enriched) > limit {
@@ -518,7 +589,7 @@ func (qh *QueryHelper) GetReplies(ctx context.Context, limit int) ([]*EnrichedEv
 		Limit: limit * 2, // Get more since we'll filter
 	}
 
-	events, err := qh.storage.QueryEvents(ctx, filter)
+	events, err := qh.queryFeed(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -542,7 +613,7 @@ func (qh *QueryHelper) GetReplies(ctx context.Context, limit int) ([]*EnrichedEv
 	}
 
 	// Apply filtering and sorting
-	enriched = qh.filterAndSortEvents(enriched, qh.config.Behavior.SortPreferences.Replies)
+	enriched = qh.filterAndSortEvents(enriched, qh.settings().Behavior.SortPreferences.Replies)
 
 	// Apply limit after filtering
 	if len(enriched) > limit {
@@ -568,7 +639,7 @@ func (qh *QueryHelper) GetMentions(ctx context.Context, limit int) ([]*EnrichedE
 		Limit: limit,
 	}
 
-	events, err := qh.storage.QueryEvents(ctx, filter)
+	events, err := qh.queryFeed(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -579,7 +650,7 @@ func (qh *QueryHelper) GetMentions(ctx context.Context, limit int) ([]*EnrichedE
 	}
 
 	// Apply filtering and sorting
-	enriched = qh.filterAndSortEvents(enriched, qh.config.Behavior.SortPreferences.Mentions)
+	enriched = qh.filterAndSortEvents(enriched, qh.settings().Behavior.SortPreferences.Mentions)
 
 	// Apply limit after filtering
 	if len(enriched) > limit {
@@ -1,10 +1,14 @@
 package aggregates
 
 import (
+	"context"
+	"path/filepath"
 	"testing"
 
 	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
 	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/storage"
 )
 
 func TestPassesContentFilter(t *testing.T) {
@@ -423,3 +427,45 @@ func TestHasInteractions(t *testing.T) {
 		})
 	}
 }
+
+func TestFeedsHideSensitive(t *testing.T) {
+	ctx := context.Background()
+	st, err := storage.New(ctx, &config.Storage{Driver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	ownerKey := nostr.GeneratePrivateKey()
+	owner, _ := nostr.GetPublicKey(ownerKey)
+	npub, _ := nip19.EncodePublicKey(owner)
+
+	for i, tags := range []nostr.Tags{{}, {{"content-warning", "spoilers"}}} {
+		event := &nostr.Event{Kind: 1, CreatedAt: nostr.Now() - nostr.Timestamp(i), Tags: tags, Content: "note"}
+		event.Sign(ownerKey)
+		if err := st.StoreEvent(ctx, event); err != nil {
+			t.Fatalf("failed to store event: %v", err)
+		}
+	}
+
+	cfg := config.Default()
+	cfg.Identity.Npub = npub
+	qh := NewQueryHelper(st, cfg, NewManager(st, cfg))
+
+	notes, err := qh.GetNotes(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetNotes failed: %v", err)
+	}
+	if len(notes) != 2 {
+		t.Errorf("Expected sensitive notes in feeds by default, got %d notes", len(notes))
+	}
+
+	cfg.Display.Feed.HideSensitive = true
+	notes, err = qh.GetNotes(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetNotes failed: %v", err)
+	}
+	if len(notes) != 1 || len(notes[0].Event.Tags) != 0 {
+		t.Errorf("Expected only the note without a content warning, got %d notes", len(notes))
+	}
+}
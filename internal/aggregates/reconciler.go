@@ -13,6 +13,7 @@ import (
 type Reconciler struct {
 	storage *storage.Storage
 	manager *Manager
+	zaps    *ZapProcessor
 }
 
 // NewReconciler creates a new reconciler
@@ -20,6 +21,7 @@ func NewReconciler(st *storage.Storage, mgr *Manager) *Reconciler {
 	return &Reconciler{
 		storage: st,
 		manager: mgr,
+		zaps:    NewZapProcessor(st, nil),
 	}
 }
 
@@ -103,11 +105,13 @@ func (r *Reconciler) ReconcileEvent(ctx context.Context, eventID string) error {
 	zapTotal := int64(0)
 	latestZap := int64(0)
 
-	zapProc := NewZapProcessor(r.storage, nil)
 	for _, zap := range zaps {
-		zapInfo, err := zapProc.parseZapEvent(zap)
+		zapInfo, err := r.zaps.ValidateZap(ctx, zap)
 		if err != nil {
-			continue
+			continue // Invalid receipts never count
+		}
+		if _, err := r.storage.SaveZap(ctx, zapRecord(zapInfo, zap)); err != nil {
+			return fmt.Errorf("failed to save zap: %w", err)
 		}
 
 		zapTotal += zapInfo.Amount
@@ -0,0 +1,198 @@
+package aggregates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+const (
+	// zapQueueSize is how many receipts can wait for the validation worker
+	zapQueueSize = 1000
+
+	// maxHeldZaps bounds the receipts held for recipients without a profile
+	maxHeldZaps = 10000
+)
+
+// ZapQueue validates zap receipts on a worker, so ingest never waits on a
+// recipient's LNURL provider. Receipts whose recipient has no profile with a
+// lightning address yet are held and retried when ProfileUpdated reports a
+// new profile for them.
+type ZapQueue struct {
+	processor *ZapProcessor
+	receipts  chan *nostr.Event
+	wake      chan struct{}
+	inflight  sync.WaitGroup // queued or retried receipts not processed yet
+
+	mu         sync.Mutex
+	held       map[string][]*nostr.Event // by recipient
+	heldCount  int
+	retry      []*nostr.Event // held receipts whose recipient has a new profile
+	profileGen int64          // bumped by ProfileUpdated
+}
+
+// NewZapQueue creates a queue that validates and records receipts with zp.
+// Run must be called for receipts to be processed.
+func NewZapQueue(zp *ZapProcessor) *ZapQueue {
+	return &ZapQueue{
+		processor: zp,
+		receipts:  make(chan *nostr.Event, zapQueueSize),
+		wake:      make(chan struct{}, 1),
+		held:      make(map[string][]*nostr.Event),
+	}
+}
+
+// Enqueue queues a receipt without blocking, reporting false when the queue
+// is full
+func (q *ZapQueue) Enqueue(receipt *nostr.Event) bool {
+	q.inflight.Add(1)
+	select {
+	case q.receipts <- receipt:
+		return true
+	default:
+		q.inflight.Done()
+		return false
+	}
+}
+
+// EnqueueWait queues a receipt, waiting for room until ctx is done
+func (q *ZapQueue) EnqueueWait(ctx context.Context, receipt *nostr.Event) error {
+	q.inflight.Add(1)
+	select {
+	case q.receipts <- receipt:
+		return nil
+	case <-ctx.Done():
+		q.inflight.Done()
+		return ctx.Err()
+	}
+}
+
+// ProfileUpdated retries the receipts held for a recipient. It is called
+// after their kind 0 is stored and does not block.
+func (q *ZapQueue) ProfileUpdated(pubkey string) {
+	q.mu.Lock()
+	q.profileGen++
+	receipts := q.held[pubkey]
+	delete(q.held, pubkey)
+	q.heldCount -= len(receipts)
+	q.retry = append(q.retry, receipts...)
+	q.inflight.Add(len(receipts))
+	q.mu.Unlock()
+
+	if len(receipts) > 0 {
+		q.signal()
+	}
+}
+
+// Held returns how many receipts are waiting for their recipient's profile
+func (q *ZapQueue) Held() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.heldCount
+}
+
+// Wait blocks until every queued receipt has been recorded, rejected or held
+func (q *ZapQueue) Wait() {
+	q.inflight.Wait()
+}
+
+// Run processes receipts until ctx is done. Receipts still queued then are
+// dropped; `nophr reconcile` recounts zaps from the stored receipts.
+func (q *ZapQueue) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			q.discard()
+			return
+
+		case receipt := <-q.receipts:
+			q.process(ctx, receipt)
+
+		case <-q.wake:
+			q.mu.Lock()
+			retry := q.retry
+			q.retry = nil
+			q.mu.Unlock()
+
+			for _, receipt := range retry {
+				q.process(ctx, receipt)
+			}
+		}
+	}
+}
+
+// process validates and records one receipt
+func (q *ZapQueue) process(ctx context.Context, receipt *nostr.Event) {
+	defer q.inflight.Done()
+
+	q.mu.Lock()
+	gen := q.profileGen
+	q.mu.Unlock()
+
+	err := q.processor.ProcessZap(ctx, receipt)
+	if err == nil || ctx.Err() != nil {
+		return
+	}
+	if errors.Is(err, ErrNoLightningAddress) && q.hold(receipt, gen) {
+		return
+	}
+	fmt.Printf("[ZAPS] ⚠ Ignoring zap receipt %s: %v\n", receipt.ID[:16]+"...", err)
+}
+
+// hold keeps a receipt until its recipient's profile arrives, reporting
+// false when too many receipts are held already. A profile stored while the
+// receipt was being validated sends it straight back for another try.
+func (q *ZapQueue) hold(receipt *nostr.Event, gen int64) bool {
+	recipient := tagValue(receipt.Tags, "p")
+	if recipient == "" {
+		return false
+	}
+
+	q.mu.Lock()
+	if q.profileGen != gen {
+		q.retry = append(q.retry, receipt)
+		q.inflight.Add(1)
+		q.mu.Unlock()
+		q.signal()
+		return true
+	}
+	if q.heldCount >= maxHeldZaps {
+		q.mu.Unlock()
+		return false
+	}
+	q.held[recipient] = append(q.held[recipient], receipt)
+	q.heldCount++
+	q.mu.Unlock()
+	return true
+}
+
+// signal wakes Run to process the retry list
+func (q *ZapQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// discard drops the receipts still queued or waiting for a retry
+func (q *ZapQueue) discard() {
+	q.mu.Lock()
+	dropped := len(q.retry)
+	q.retry = nil
+	q.mu.Unlock()
+
+	for {
+		select {
+		case <-q.receipts:
+			dropped++
+		default:
+			for i := 0; i < dropped; i++ {
+				q.inflight.Done()
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package aggregates
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+func TestZapQueueHoldsUntilProfile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	st, err := storage.New(ctx, &config.Storage{Driver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	recipientKey, senderKey, providerKey := nostr.GeneratePrivateKey(), nostr.GeneratePrivateKey(), nostr.GeneratePrivateKey()
+	recipient, _ := nostr.GetPublicKey(recipientKey)
+	provider, _ := nostr.GetPublicKey(providerKey)
+	noteID := strings.Repeat("a", 64)
+
+	zp := NewZapProcessor(st, &config.Inbox{})
+	zp.providers.fetch = func(ctx context.Context, url string) (*lnurlPayInfo, error) {
+		return &lnurlPayInfo{AllowsNostr: true, NostrPubkey: provider}, nil
+	}
+
+	request := &nostr.Event{Kind: 9734, CreatedAt: nostr.Now(), Tags: nostr.Tags{{"p", recipient}, {"e", noteID}, {"amount", "21000"}}}
+	request.Sign(senderKey)
+	description, _ := json.Marshal(request)
+	receipt := &nostr.Event{Kind: 9735, CreatedAt: nostr.Now(), Tags: nostr.Tags{
+		{"p", recipient}, {"e", noteID}, {"bolt11", "lnbc210n1pjexample"}, {"description", string(description)},
+	}}
+	receipt.Sign(providerKey)
+
+	q := NewZapQueue(zp)
+	go q.Run(ctx)
+
+	// The receipt arrives before the recipient's profile
+	if !q.Enqueue(receipt) {
+		t.Fatal("expected the receipt to be queued")
+	}
+	q.Wait()
+	if held := q.Held(); held != 1 {
+		t.Fatalf("expected the receipt to be held, got %d held", held)
+	}
+
+	profile := &nostr.Event{Kind: 0, CreatedAt: nostr.Now(), Content: `{"lud16":"alice@example.com"}`}
+	profile.Sign(recipientKey)
+	if err := st.StoreEvent(ctx, profile); err != nil {
+		t.Fatalf("failed to store profile: %v", err)
+	}
+	q.ProfileUpdated(recipient)
+	q.Wait()
+
+	if held := q.Held(); held != 0 {
+		t.Errorf("expected no held receipts, got %d", held)
+	}
+	agg, err := st.GetAggregate(ctx, noteID)
+	if err != nil || agg.ZapSatsTotal != 21 {
+		t.Errorf("expected 21 zapped sats once the profile arrived, got %+v (%v)", agg, err)
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/sandwichfarm/nophr/internal/config"
@@ -14,56 +17,141 @@ import (
 
 // ZapProcessor handles zap (kind 9735) event processing
 type ZapProcessor struct {
-	storage *storage.Storage
-	config  *config.Inbox
+	storage   *storage.Storage
+	config    *config.Inbox
+	providers *ZapProviders
 }
 
 // NewZapProcessor creates a new zap processor
 func NewZapProcessor(st *storage.Storage, cfg *config.Inbox) *ZapProcessor {
 	return &ZapProcessor{
-		storage: st,
-		config:  cfg,
+		storage:   st,
+		config:    cfg,
+		providers: NewZapProviders(st),
 	}
 }
 
 // ZapInfo contains parsed zap information
 type ZapInfo struct {
-	Amount       int64  // Amount in satoshis
-	TargetEventID string // Event being zapped
-	TargetPubkey string // Pubkey being zapped (if profile zap)
-	Sender       string // Pubkey of sender
-	Comment      string // Optional comment
+	ReceiptID     string // Zap receipt (kind 9735) ID
+	Amount        int64  // Amount in satoshis
+	TargetEventID string // Event being zapped ("" for a profile zap)
+	TargetPubkey  string // Pubkey being zapped
+	Sender        string // Pubkey of sender (the zap request's author)
+	Comment       string // Optional comment
 }
 
-// ProcessZap processes a kind 9735 zap receipt and updates aggregates
+// ProcessZap validates a kind 9735 zap receipt, records it per sender and
+// adds it to the zapped event's aggregate. Invalid receipts are rejected
+// with an error so they never count towards totals.
 func (zp *ZapProcessor) ProcessZap(ctx context.Context, event *nostr.Event) error {
-	if event.Kind != 9735 {
-		return fmt.Errorf("expected kind 9735, got %d", event.Kind)
-	}
-
-	// Parse zap info
-	info, err := zp.parseZapEvent(event)
+	info, err := zp.ValidateZap(ctx, event)
 	if err != nil {
-		return fmt.Errorf("failed to parse zap: %w", err)
+		return err
 	}
 
 	// Apply noise filter
-	if info.Amount < int64(zp.config.NoiseFilters.MinZapSats) {
+	if zp.config != nil && info.Amount < int64(zp.config.NoiseFilters.MinZapSats) {
 		return nil // Silently ignore small zaps
 	}
 
-	// Update aggregate if targeting an event
+	inserted, err := zp.storage.SaveZap(ctx, zapRecord(info, event))
+	if err != nil || !inserted {
+		return err
+	}
+
+	// Profile zaps are summed from the zaps table (see GetProfileZaps)
 	if info.TargetEventID != "" {
 		return zp.storage.AddZapAmount(ctx, info.TargetEventID, info.Amount, int64(event.CreatedAt))
 	}
-
-	// For profile zaps, we could track separately but for now just ignore
 	return nil
 }
 
+// zapRecord converts a validated zap receipt to its storage record
+func zapRecord(info *ZapInfo, receipt *nostr.Event) *storage.Zap {
+	return &storage.Zap{
+		ReceiptID:  info.ReceiptID,
+		EventID:    info.TargetEventID,
+		Recipient:  info.TargetPubkey,
+		Sender:     info.Sender,
+		AmountSats: info.Amount,
+		Comment:    info.Comment,
+		CreatedAt:  int64(receipt.CreatedAt),
+	}
+}
+
+// ValidateZap checks a zap receipt as NIP-57 (Appendix F) requires: it
+// must embed a signed kind 9734 zap request for the same recipient and
+// event, the request's amount must match the invoice, and the receipt must
+// be signed by the recipient's LNURL provider.
+func (zp *ZapProcessor) ValidateZap(ctx context.Context, event *nostr.Event) (*ZapInfo, error) {
+	if event.Kind != 9735 {
+		return nil, fmt.Errorf("expected kind 9735, got %d", event.Kind)
+	}
+
+	info, err := zp.parseZapEvent(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse zap: %w", err)
+	}
+
+	bolt11 := tagValue(event.Tags, "bolt11")
+	description := tagValue(event.Tags, "description")
+	if bolt11 == "" || description == "" {
+		return nil, fmt.Errorf("zap receipt missing bolt11 or description")
+	}
+	if info.TargetPubkey == "" {
+		return nil, fmt.Errorf("zap receipt missing p tag")
+	}
+
+	var request nostr.Event
+	if err := json.Unmarshal([]byte(description), &request); err != nil {
+		return nil, fmt.Errorf("invalid zap request: %w", err)
+	}
+	if request.Kind != 9734 {
+		return nil, fmt.Errorf("zap request has kind %d, expected 9734", request.Kind)
+	}
+	if ok, err := request.CheckSignature(); err != nil || !ok {
+		return nil, fmt.Errorf("zap request signature is invalid")
+	}
+
+	// The request names exactly one recipient, the one the receipt pays
+	recipients := request.Tags.GetAll([]string{"p"})
+	if len(recipients) != 1 || recipients[0][1] != info.TargetPubkey {
+		return nil, fmt.Errorf("zap request recipient does not match receipt")
+	}
+	if eventTags := request.Tags.GetAll([]string{"e"}); len(eventTags) > 1 ||
+		(len(eventTags) == 1 && eventTags[0][1] != info.TargetEventID) ||
+		(len(eventTags) == 0 && info.TargetEventID != "") {
+		return nil, fmt.Errorf("zap request event does not match receipt")
+	}
+
+	msats, err := parseInvoiceMsats(bolt11)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bolt11: %w", err)
+	}
+	if amount := tagValue(request.Tags, "amount"); amount != "" {
+		requested, err := strconv.ParseInt(amount, 10, 64)
+		if err != nil || requested != msats {
+			return nil, fmt.Errorf("zap request amount %s does not match invoice amount %d", amount, msats)
+		}
+	}
+
+	provider, err := zp.providers.ProviderPubkey(ctx, info.TargetPubkey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve zap provider: %w", err)
+	}
+	if event.PubKey != provider {
+		return nil, fmt.Errorf("zap receipt not signed by the recipient's provider")
+	}
+
+	info.ReceiptID = event.ID
+	info.Amount = msats / 1000
+	return info, nil
+}
+
 // parseZapEvent extracts zap information from a kind 9735 event
 func (zp *ZapProcessor) parseZapEvent(event *nostr.Event) (*ZapInfo, error) {
-	info := &ZapInfo{}
+	info := &ZapInfo{ReceiptID: event.ID}
 
 	// Extract target event and pubkey from tags
 	for _, tag := range event.Tags {
@@ -78,31 +166,19 @@ func (zp *ZapProcessor) parseZapEvent(event *nostr.Event) (*ZapInfo, error) {
 			info.TargetPubkey = tag[1]
 		case "description":
 			// The description tag contains the zap request (kind 9734)
-			if len(tag) >= 2 {
-				if err := zp.parseZapRequest(tag[1], info); err != nil {
-					// Log but don't fail
-					continue
-				}
+			if err := zp.parseZapRequest(tag[1], info); err != nil {
+				// Log but don't fail
+				continue
 			}
 		case "bolt11":
 			// Parse amount from bolt11 invoice
-			if len(tag) >= 2 {
-				amount, err := zp.parseInvoiceAmount(tag[1])
-				if err == nil {
-					info.Amount = amount
-				}
+			amount, err := zp.parseInvoiceAmount(tag[1])
+			if err == nil {
+				info.Amount = amount
 			}
 		}
 	}
 
-	// If we couldn't parse from bolt11, try from the event itself
-	if info.Amount == 0 {
-		amount, err := zp.parseInvoiceAmount(event.Content)
-		if err == nil {
-			info.Amount = amount
-		}
-	}
-
 	return info, nil
 }
 
@@ -123,45 +199,131 @@ func (zp *ZapProcessor) parseZapRequest(descJSON string, info *ZapInfo) error {
 	return nil
 }
 
+// tagValue returns the value of the first tag with the given name
+func tagValue(tags nostr.Tags, name string) string {
+	if tag := tags.GetFirst([]string{name, ""}); tag != nil && len(*tag) >= 2 {
+		return (*tag)[1]
+	}
+	return ""
+}
+
 // parseInvoiceAmount extracts the amount in satoshis from a bolt11 invoice
-// This is a simplified parser - a full implementation would use a proper bolt11 library
 func (zp *ZapProcessor) parseInvoiceAmount(invoice string) (int64, error) {
-	// Look for lnbc followed by amount and multiplier
-	// Format: lnbc{amount}{multiplier}...
-	// Multipliers: m (milli), u (micro), n (nano), p (pico)
+	msats, err := parseInvoiceMsats(invoice)
+	if err != nil {
+		return 0, err
+	}
+	return msats / 1000, nil
+}
 
-	re := regexp.MustCompile(`lnbc(\d+)([munp]?)`)
-	matches := re.FindStringSubmatch(invoice)
+// invoiceAmountPattern matches the network and amount of a bolt11 invoice:
+// ln{network}{amount}{multiplier}1, multipliers m (milli), u (micro),
+// n (nano), p (pico). Longer network prefixes come first so lnbcrt is not
+// read as lnbc.
+var invoiceAmountPattern = regexp.MustCompile(`^ln(bcrt|bc|tbs|tb)(\d+)([munp]?)1`)
+
+// invoiceNetworks names the networks other than mainnet. Zaps pay mainnet
+// invoices, so receipts for these are rejected rather than counted as sats.
+var invoiceNetworks = map[string]string{
+	"bcrt": "regtest",
+	"tb":   "testnet",
+	"tbs":  "signet",
+}
 
-	if len(matches) < 2 {
+// msatsPerUnit is the millisatoshis in one unit of each multiplier
+// (1 BTC = 10^11 msat)
+var msatsPerUnit = map[string]int64{
+	"":  100_000_000_000,
+	"m": 100_000_000,
+	"u": 100_000,
+	"n": 100,
+}
+
+// parseInvoiceMsats extracts the amount in millisatoshis from a mainnet
+// bolt11 invoice. This reads the human-readable part only; it does not decode
+// or verify the invoice's signed data.
+func parseInvoiceMsats(invoice string) (int64, error) {
+	matches := invoiceAmountPattern.FindStringSubmatch(strings.ToLower(invoice))
+	if matches == nil {
 		return 0, fmt.Errorf("could not parse invoice amount")
 	}
+	if network, ok := invoiceNetworks[matches[1]]; ok {
+		return 0, fmt.Errorf("invoice is for %s, not mainnet", network)
+	}
 
-	amount, err := strconv.ParseInt(matches[1], 10, 64)
+	amount, err := strconv.ParseInt(matches[2], 10, 64)
 	if err != nil {
 		return 0, err
 	}
 
-	// Apply multiplier to get satoshis
-	multiplier := ""
-	if len(matches) >= 3 {
-		multiplier = matches[2]
+	if matches[3] == "p" {
+		if amount%10 != 0 {
+			return 0, fmt.Errorf("invoice amount is not a whole millisatoshi")
+		}
+		return amount / 10, nil
 	}
 
-	switch multiplier {
-	case "m": // millibitcoin = 100,000 sats
-		amount = amount * 100000
-	case "u": // microbitcoin = 100 sats
-		amount = amount * 100
-	case "n": // nanobitcoin = 0.1 sats
-		amount = amount / 10
-	case "p": // picobitcoin = 0.0001 sats
-		amount = amount / 10000
-	default: // no multiplier = 1 bitcoin = 100,000,000 sats
-		amount = amount * 100000000
+	perUnit := msatsPerUnit[matches[3]]
+	if amount > math.MaxInt64/perUnit {
+		return 0, fmt.Errorf("invoice amount is too large")
 	}
+	return amount * perUnit, nil
+}
+
+// TopZappers is how many zappers note and profile pages list
+const TopZappers = 5
+
+// Zapper totals one sender's zaps of an event or profile
+type Zapper struct {
+	Pubkey  string
+	Sats    int64
+	Count   int
+	Comment string // newest non-empty comment
+}
+
+// ZapSummary totals the zaps of an event or profile by sender
+type ZapSummary struct {
+	TotalSats int64
+	Count     int
+	Zappers   []*Zapper // largest total first
+}
 
-	return amount, nil
+// SummarizeZaps totals zaps by sender. zaps are newest first, as storage
+// returns them.
+func SummarizeZaps(zaps []*storage.Zap) *ZapSummary {
+	summary := &ZapSummary{}
+	bySender := make(map[string]*Zapper)
+
+	for _, zap := range zaps {
+		summary.TotalSats += zap.AmountSats
+		summary.Count++
+
+		zapper, ok := bySender[zap.Sender]
+		if !ok {
+			zapper = &Zapper{Pubkey: zap.Sender}
+			bySender[zap.Sender] = zapper
+			summary.Zappers = append(summary.Zappers, zapper)
+		}
+		zapper.Sats += zap.AmountSats
+		zapper.Count++
+		if zapper.Comment == "" {
+			zapper.Comment = zap.Comment
+		}
+	}
+
+	sort.SliceStable(summary.Zappers, func(i, j int) bool {
+		return summary.Zappers[i].Sats > summary.Zappers[j].Sats
+	})
+
+	return summary
+}
+
+// Top returns the n largest zappers
+func (s *ZapSummary) Top(n int) []*Zapper {
+	if len(s.Zappers) > n {
+		return s.Zappers[:n]
+	}
+	return s.Zappers
 }
 
 // GetZapStats returns zap statistics for an event
@@ -1,7 +1,19 @@
 package aggregates
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/storage"
 )
 
 func TestParseInvoiceAmount(t *testing.T) {
@@ -34,9 +46,35 @@ func TestParseInvoiceAmount(t *testing.T) {
 		{
 			name:     "simple amount (full bitcoin)",
 			invoice:  "lnbc21001...",
-			expected: 2100100000000, // 21001 * 100,000,000 (no multiplier = full bitcoin)
+			expected: 210000000000, // 2100 * 100,000,000 (no multiplier = full bitcoin; the last 1 is the bech32 separator)
 			wantErr:  false,
 		},
+		{
+			name:     "picobitcoin",
+			invoice:  "lnbc10000p1...",
+			expected: 1, // 10000p = 1000 msat
+			wantErr:  false,
+		},
+		{
+			name:    "testnet",
+			invoice: "lntb100u1...",
+			wantErr: true,
+		},
+		{
+			name:    "signet",
+			invoice: "lntbs100u1...",
+			wantErr: true,
+		},
+		{
+			name:    "regtest",
+			invoice: "lnbcrt100u1...",
+			wantErr: true,
+		},
+		{
+			name:    "overflow",
+			invoice: "lnbc100000000001...", // 10^10 BTC, more msats than an int64 holds
+			wantErr: true,
+		},
 		{
 			name:     "invalid format",
 			invoice:  "invalid",
@@ -82,3 +120,200 @@ func TestFormatSats(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateZap(t *testing.T) {
+	ctx := context.Background()
+	st, err := storage.New(ctx, &config.Storage{Driver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	recipientKey, senderKey, providerKey := nostr.GeneratePrivateKey(), nostr.GeneratePrivateKey(), nostr.GeneratePrivateKey()
+	recipient, _ := nostr.GetPublicKey(recipientKey)
+	provider, _ := nostr.GetPublicKey(providerKey)
+	noteID := strings.Repeat("a", 64)
+
+	profile := &nostr.Event{Kind: 0, CreatedAt: nostr.Now(), Content: `{"lud16":"alice@example.com"}`}
+	profile.Sign(recipientKey)
+	if err := st.StoreEvent(ctx, profile); err != nil {
+		t.Fatalf("failed to store profile: %v", err)
+	}
+
+	zp := NewZapProcessor(st, &config.Inbox{})
+	lookups := 0
+	zp.providers.fetch = func(ctx context.Context, url string) (*lnurlPayInfo, error) {
+		lookups++
+		if url != "https://example.com/.well-known/lnurlp/alice" {
+			t.Errorf("unexpected lnurl endpoint: %s", url)
+		}
+		return &lnurlPayInfo{AllowsNostr: true, NostrPubkey: provider}, nil
+	}
+
+	// receipt builds a zap receipt for a request, letting each case break one rule
+	receipt := func(eventID, amount, bolt11, signer string, edit func(request, receipt *nostr.Event)) *nostr.Event {
+		request := &nostr.Event{Kind: 9734, CreatedAt: nostr.Now(), Content: "great post", Tags: nostr.Tags{{"p", recipient}, {"amount", amount}}}
+		if eventID != "" {
+			request.Tags = append(request.Tags, nostr.Tag{"e", eventID})
+		}
+		request.Sign(senderKey)
+
+		zap := &nostr.Event{Kind: 9735, CreatedAt: nostr.Now(), Tags: nostr.Tags{{"p", recipient}, {"bolt11", bolt11}}}
+		if eventID != "" {
+			zap.Tags = append(zap.Tags, nostr.Tag{"e", eventID})
+		}
+		if edit != nil {
+			edit(request, zap)
+		}
+		description, _ := json.Marshal(request)
+		zap.Tags = append(zap.Tags, nostr.Tag{"description", string(description)})
+		zap.Sign(signer)
+		return zap
+	}
+
+	valid := receipt(noteID, "21000", "lnbc210n1pjexample", providerKey, nil)
+	info, err := zp.ValidateZap(ctx, valid)
+	if err != nil {
+		t.Fatalf("expected valid zap, got %v", err)
+	}
+	if info.Amount != 21 || info.TargetEventID != noteID || info.Comment != "great post" {
+		t.Errorf("unexpected zap info: %+v", info)
+	}
+
+	invalid := map[string]*nostr.Event{
+		"not signed by provider": receipt(noteID, "21000", "lnbc210n1pjexample", senderKey, nil),
+		"amount mismatch":        receipt(noteID, "2100000", "lnbc210n1pjexample", providerKey, nil),
+		"recipient mismatch": receipt(noteID, "21000", "lnbc210n1pjexample", providerKey, func(_, zap *nostr.Event) {
+			zap.Tags[0][1] = strings.Repeat("b", 64)
+		}),
+		"event mismatch": receipt(noteID, "21000", "lnbc210n1pjexample", providerKey, func(_, zap *nostr.Event) {
+			zap.Tags[2][1] = strings.Repeat("c", 64)
+		}),
+		"tampered request": receipt(noteID, "21000", "lnbc210n1pjexample", providerKey, func(request, _ *nostr.Event) {
+			request.Content = "edited"
+		}),
+	}
+	for name, zap := range invalid {
+		if _, err := zp.ValidateZap(ctx, zap); err == nil {
+			t.Errorf("%s: expected zap to be rejected", name)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("expected the provider lookup to be cached, got %d lookups", lookups)
+	}
+
+	// Each receipt counts once; profile zaps are kept apart from note totals
+	for _, zap := range []*nostr.Event{valid, valid, receipt("", "1000000", "lnbc10u1pjexample", providerKey, nil)} {
+		if err := zp.ProcessZap(ctx, zap); err != nil {
+			t.Fatalf("ProcessZap failed: %v", err)
+		}
+	}
+	agg, err := st.GetAggregate(ctx, noteID)
+	if err != nil || agg.ZapSatsTotal != 21 {
+		t.Errorf("expected 21 zapped sats on the note, got %+v (%v)", agg, err)
+	}
+	profileZaps, err := st.GetProfileZaps(ctx, recipient)
+	if err != nil || len(profileZaps) != 1 || profileZaps[0].AmountSats != 1000 {
+		t.Errorf("expected one 1000 sat profile zap, got %+v (%v)", profileZaps, err)
+	}
+}
+
+func TestLNURLPayURL(t *testing.T) {
+	data, _ := bech32.ConvertBits([]byte("https://example.com/lnurlp/alice"), 8, 5, true)
+	lnurl, _ := bech32.Encode("lnurl", data)
+
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"alice@example.com", "https://example.com/.well-known/lnurlp/alice"},
+		{strings.ToUpper(lnurl), "https://example.com/lnurlp/alice"},
+	}
+	for _, tt := range tests {
+		got, err := lnurlPayURL(tt.address)
+		if err != nil || got != tt.want {
+			t.Errorf("lnurlPayURL(%s) = %s, %v; want %s", tt.address, got, err, tt.want)
+		}
+	}
+
+	if _, err := lnurlPayURL("not-an-address"); err == nil {
+		t.Error("expected invalid address to fail")
+	}
+
+	// Endpoints must be https on a host name; lud16 cannot pick a port or IP
+	encode := func(endpoint string) string {
+		data, _ := bech32.ConvertBits([]byte(endpoint), 8, 5, true)
+		lnurl, _ := bech32.Encode("lnurl", data)
+		return lnurl
+	}
+	for _, address := range []string{
+		"alice@127.0.0.1",
+		"alice@example.com:8080",
+		"alice@[::1]",
+		"alice@example.com/path",
+		encode("http://example.com/lnurlp/alice"),
+		encode("http://127.0.0.1:8080/lnurlp/alice"),
+		encode("https://user@example.com/lnurlp/alice"),
+	} {
+		if got, err := lnurlPayURL(address); err == nil {
+			t.Errorf("lnurlPayURL(%s) = %s, expected it to be refused", address, got)
+		}
+	}
+}
+
+func TestDialPublicOnly(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:443", false},
+		{"10.0.0.1:443", false},
+		{"192.168.1.1:443", false},
+		{"169.254.169.254:80", false},
+		{"0.0.0.0:443", false},
+		{"[::1]:443", false},
+		{"[fe80::1]:443", false},
+		{"[fd00::1]:443", false},
+		{"[::ffff:127.0.0.1]:443", false},
+	}
+	for _, tt := range tests {
+		if err := dialPublicOnly("tcp", tt.address, nil); (err == nil) != tt.allowed {
+			t.Errorf("dialPublicOnly(%s) = %v, allowed %v", tt.address, err, tt.allowed)
+		}
+	}
+}
+
+func TestFetchLNURLPayRefusesInternalAddresses(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte(`{"allowsNostr":true}`))
+	}))
+	defer server.Close()
+
+	// The test server listens on loopback, as an internal service would
+	if _, err := fetchLNURLPay(context.Background(), server.URL); err == nil || !strings.Contains(err.Error(), "not a public address") {
+		t.Errorf("expected the loopback endpoint to be refused, got %v", err)
+	}
+	if hits.Load() != 0 {
+		t.Error("expected no request to reach the loopback endpoint")
+	}
+}
+
+func TestSummarizeZaps(t *testing.T) {
+	summary := SummarizeZaps([]*storage.Zap{
+		{Sender: "bob", AmountSats: 100, Comment: "again"},
+		{Sender: "carol", AmountSats: 500},
+		{Sender: "bob", AmountSats: 1000, Comment: "first"},
+	})
+
+	if summary.TotalSats != 1600 || summary.Count != 3 || len(summary.Zappers) != 2 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	top := summary.Zappers[0]
+	if top.Pubkey != "bob" || top.Sats != 1100 || top.Count != 2 || top.Comment != "again" {
+		t.Errorf("unexpected top zapper: %+v", top)
+	}
+}
@@ -23,8 +23,9 @@ func NewInvalidator(cache Cache) *Invalidator {
 
 // InvalidateEvent invalidates cache entries related to an event
 func (inv *Invalidator) InvalidateEvent(ctx context.Context, event *nostr.Event) error {
-	// Get invalidation patterns for this event
+	// Get invalidation patterns for this event and the pages that render it
 	patterns := InvalidationPatterns(event.ID, event.Kind, event.PubKey)
+	patterns = append(patterns, PagePatterns(event)...)
 
 	// Invalidate each pattern
 	for _, pattern := range patterns {
@@ -171,6 +172,24 @@ func (inv *Invalidator) OnEventIngested(ctx context.Context, event *nostr.Event)
 	return inv.InvalidateEvent(ctx, event)
 }
 
+// HandleEvent is a sync engine event handler that invalidates cache entries
+// for each stored event
+func (inv *Invalidator) HandleEvent(ctx context.Context, event *nostr.Event) {
+	if err := inv.OnEventIngested(ctx, event); err != nil {
+		fmt.Printf("[CACHE] Failed to invalidate cache for event %s: %v\n", event.ID, err)
+	}
+}
+
+// HandleDeletion is a sync engine deletion handler that invalidates the pages
+// that rendered the deleted events
+func (inv *Invalidator) HandleDeletion(ctx context.Context, deletion *nostr.Event, targets []*nostr.Event) {
+	for _, target := range targets {
+		if err := inv.InvalidateEvent(ctx, target); err != nil {
+			fmt.Printf("[CACHE] Failed to invalidate cache for deleted event %s: %v\n", target.ID, err)
+		}
+	}
+}
+
 // Warmer handles cache warming (pre-populating cache)
 type Warmer struct {
 	cache Cache
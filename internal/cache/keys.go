@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
 )
 
 // KeyBuilder helps build cache keys
@@ -72,6 +74,19 @@ func GeminiKey(path string, query string) string {
 	return kb.BuildHashed()
 }
 
+// HTTPKey generates a cache key for HTTP responses
+func HTTPKey(path string, query string) string {
+	kb := NewKeyBuilder().
+		Add("http").
+		Add(path)
+
+	if query != "" {
+		kb.Add("q").Add(query)
+	}
+
+	return kb.BuildHashed()
+}
+
 // FingerKey generates a cache key for Finger responses
 func FingerKey(username string) string {
 	return NewKeyBuilder().
@@ -167,6 +182,11 @@ func GeminiPattern() string {
 	return "gemini:*"
 }
 
+// HTTPPattern returns a pattern for matching all HTTP keys
+func HTTPPattern() string {
+	return "http:*"
+}
+
 // FingerPattern returns a pattern for matching all Finger keys
 func FingerPattern() string {
 	return "finger:*"
@@ -221,3 +241,47 @@ func InvalidationPatterns(eventID string, kind int, pubkey string) []string {
 
 	return patterns
 }
+
+// PagePatterns returns the rendered Gopher, Gemini, HTTP and Finger page patterns
+// that may show a given event: its own note and thread pages, the pages of
+// any event it references (replies, reactions and zaps change their parent's
+// counts), and the list pages its kind appears in. Section-backed pages at
+// custom paths are left to expire by TTL.
+func PagePatterns(event *nostr.Event) []string {
+	var paths []string
+
+	ids := []string{event.ID}
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == "e" {
+			ids = append(ids, tag[1])
+		}
+	}
+	for _, id := range ids {
+		paths = append(paths, "/note/"+id, "/thread/"+id)
+	}
+
+	switch event.Kind {
+	case 0:
+		paths = append(paths, "/profile/"+event.PubKey)
+	case 1:
+		paths = append(paths, "/notes", "/outbox", "/replies", "/inbox", "/mentions", "/search")
+	case 30023:
+		paths = append(paths, "/articles", "/search")
+	}
+
+	patterns := make([]string, 0, len(paths)*3+1)
+	for _, path := range paths {
+		patterns = append(patterns,
+			GopherKey(path)+"*",
+			GeminiKey(path, "")+"*",
+			HTTPKey(path, "")+"*",
+		)
+	}
+
+	// Finger shows the owner's profile and recent notes
+	if event.Kind == 0 || event.Kind == 1 {
+		patterns = append(patterns, FingerPattern())
+	}
+
+	return patterns
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sandwichfarm/nophr/internal/config"
+)
+
+// FromConfig builds a cache configuration from the caching config block
+func FromConfig(cfg *config.Caching) *Config {
+	c := DefaultConfig()
+	c.Enabled = cfg.Enabled
+	if cfg.Engine != "" {
+		c.Engine = cfg.Engine
+	}
+	c.RedisURL = cfg.RedisURL
+	return c
+}
+
+// RenderTTLs picks the TTL for a rendered protocol response from the
+// caching.ttl config. Section pages use ttl.sections, detail pages use the
+// kind-specific ttl.render entries and everything else falls back to the
+// protocol's page TTL. A zero TTL means the response is not cached.
+type RenderTTLs struct {
+	sections map[string]int
+	render   map[string]int
+}
+
+// NewRenderTTLs creates TTL rules from the caching config
+func NewRenderTTLs(cfg *config.Caching) *RenderTTLs {
+	return &RenderTTLs{
+		sections: cfg.TTL.Sections,
+		render:   cfg.TTL.Render,
+	}
+}
+
+// Gopher returns the TTL for a Gopher selector
+func (r *RenderTTLs) Gopher(selector string) time.Duration {
+	return r.forPath(selector, "gopher_menu")
+}
+
+// Gemini returns the TTL for a Gemini path
+func (r *RenderTTLs) Gemini(path string) time.Duration {
+	return r.forPath(path, "gemini_page")
+}
+
+// HTTP returns the TTL for an HTTP path
+func (r *RenderTTLs) HTTP(path string) time.Duration {
+	return r.forPath(path, "http_page")
+}
+
+// Finger returns the TTL for Finger responses
+func (r *RenderTTLs) Finger() time.Duration {
+	return seconds(r.render["finger_response"])
+}
+
+// forPath maps the first path segment to a section or render TTL
+func (r *RenderTTLs) forPath(path string, pageKey string) time.Duration {
+	segment := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0]
+
+	var ttl int
+	var ok bool
+	switch segment {
+	case "notes", "outbox":
+		ttl, ok = r.sections["notes"]
+	case "articles":
+		ttl, ok = r.sections["articles"]
+	case "replies", "mentions", "inbox":
+		ttl, ok = r.sections["comments"]
+	case "note", "thread":
+		ttl, ok = r.render["kind_1"]
+	case "profile":
+		ttl, ok = r.render["kind_0"]
+	}
+
+	if !ok {
+		ttl = r.render[pageKey]
+	}
+	return seconds(ttl)
+}
+
+func seconds(n int) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
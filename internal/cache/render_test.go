@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/config"
+)
+
+func TestRenderTTLs(t *testing.T) {
+	ttls := NewRenderTTLs(&config.Caching{
+		TTL: config.CacheTTL{
+			Sections: map[string]int{"notes": 60, "comments": 30, "articles": 300},
+			Render:   map[string]int{"gopher_menu": 120, "gemini_page": 240, "http_page": 180, "finger_response": 45, "kind_1": 600, "kind_0": 900},
+		},
+	})
+
+	tests := []struct {
+		name string
+		got  time.Duration
+		want time.Duration
+	}{
+		{"gopher root", ttls.Gopher("/"), 120 * time.Second},
+		{"gopher notes page", ttls.Gopher("/notes/page/2"), 60 * time.Second},
+		{"gopher replies", ttls.Gopher("/replies"), 30 * time.Second},
+		{"gopher note", ttls.Gopher("/note/abc"), 600 * time.Second},
+		{"gemini articles", ttls.Gemini("/articles"), 300 * time.Second},
+		{"gemini profile", ttls.Gemini("/profile/abc"), 900 * time.Second},
+		{"gemini search", ttls.Gemini("/search"), 240 * time.Second},
+		{"http root", ttls.HTTP("/"), 180 * time.Second},
+		{"http thread", ttls.HTTP("/thread/abc"), 600 * time.Second},
+		{"finger", ttls.Finger(), 45 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if ttl := NewRenderTTLs(&config.Caching{}).Gopher("/notes"); ttl != 0 {
+		t.Errorf("expected no TTL without config, got %v", ttl)
+	}
+}
+
+func TestPagePatterns(t *testing.T) {
+	reply := &nostr.Event{
+		ID:     "reply1",
+		Kind:   1,
+		PubKey: "author",
+		Tags:   nostr.Tags{{"e", "root1"}, {"p", "owner"}},
+	}
+
+	patterns := PagePatterns(reply)
+	for _, expected := range []string{
+		"gopher:/note/reply1*",
+		"gopher:/thread/root1*",
+		"gemini:/note/root1*",
+		"gopher:/notes*",
+		"gemini:/search*",
+		"http:/note/root1*",
+		"finger:*",
+	} {
+		found := false
+		for _, pattern := range patterns {
+			if pattern == expected {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected pattern %s not found in %v", expected, patterns)
+		}
+	}
+}
+
+func TestInvalidatorPages(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(DefaultConfig())
+	defer c.Close()
+
+	keep := GopherKey("/note/other")
+	for _, key := range []string{GopherKey("/note/root1"), GopherKey("/notes/page/2"), GeminiKey("/search", "q=x"), keep} {
+		c.Set(ctx, key, []byte("page"), time.Minute)
+	}
+
+	reaction := &nostr.Event{ID: "r1", Kind: 7, Tags: nostr.Tags{{"e", "root1"}}}
+	NewInvalidator(c).HandleEvent(ctx, reaction)
+
+	if hit, _ := c.Has(ctx, GopherKey("/note/root1")); hit {
+		t.Error("reaction should invalidate the parent note page")
+	}
+	if hit, _ := c.Has(ctx, GopherKey("/notes/page/2")); !hit {
+		t.Error("reaction should not invalidate note lists")
+	}
+
+	note := &nostr.Event{ID: "n1", Kind: 1}
+	NewInvalidator(c).HandleEvent(ctx, note)
+
+	if hit, _ := c.Has(ctx, GopherKey("/notes/page/2")); hit {
+		t.Error("new note should invalidate note list pages")
+	}
+	if hit, _ := c.Has(ctx, GeminiKey("/search", "q=x")); hit {
+		t.Error("new note should invalidate search results")
+	}
+	if hit, _ := c.Has(ctx, keep); !hit {
+		t.Error("unrelated note page should stay cached")
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import "fmt"
+
+// Backup compression formats
+const (
+	BackupCompressionNone = "none"
+	BackupCompressionGzip = "gzip"
+	BackupCompressionZstd = "zstd"
+)
+
+// Backup configures scheduled online backups of the SQLite database
+type Backup struct {
+	Enabled       bool   `yaml:"enabled"`
+	Dir           string `yaml:"dir"`            // where nophr-backup-<timestamp>.db files are written
+	IntervalHours int    `yaml:"interval_hours"` // time between backups
+	Compression   string `yaml:"compression"`    // none|gzip|zstd
+	Keep          int    `yaml:"keep"`           // newest backups to keep, 0 = no limit
+	MaxAgeDays    int    `yaml:"max_age_days"`   // delete backups older than this, 0 = no limit
+}
+
+// Validate checks if backup config is valid
+func (b *Backup) Validate() error {
+	switch b.Compression {
+	case "", BackupCompressionNone, BackupCompressionGzip, BackupCompressionZstd:
+	default:
+		return fmt.Errorf("invalid backup.compression: %s (must be one of: none, gzip, zstd)", b.Compression)
+	}
+
+	if b.Keep < 0 {
+		return fmt.Errorf("backup.keep must be >= 0")
+	}
+	if b.MaxAgeDays < 0 {
+		return fmt.Errorf("backup.max_age_days must be >= 0")
+	}
+
+	if b.Enabled {
+		if b.Dir == "" {
+			return fmt.Errorf("backup.dir is required when backups are enabled")
+		}
+		if b.IntervalHours < 1 {
+			return fmt.Errorf("backup.interval_hours must be >= 1")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBackupValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		backup  Backup
+		wantErr string
+	}{
+		{"defaults", Default().Backup, ""},
+		{"disabled without dir", Backup{Compression: "none"}, ""},
+		{"enabled", Backup{Enabled: true, Dir: "/backups", IntervalHours: 6, Compression: "zstd"}, ""},
+		{"unknown compression", Backup{Compression: "bzip2"}, "invalid backup.compression"},
+		{"negative keep", Backup{Keep: -1}, "backup.keep"},
+		{"negative max age", Backup{MaxAgeDays: -1}, "backup.max_age_days"},
+		{"enabled without dir", Backup{Enabled: true, IntervalHours: 6}, "backup.dir"},
+		{"enabled without interval", Backup{Enabled: true, Dir: "/backups"}, "backup.interval_hours"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.backup.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackupRequiresSQLite(t *testing.T) {
+	cfg := Default()
+	cfg.Identity.Npub = "npub1test"
+	cfg.Backup.Enabled = true
+	cfg.Storage.Driver = "lmdb"
+
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "sqlite") {
+		t.Errorf("expected backups on lmdb to be rejected, got %v", err)
+	}
+}
@@ -2,10 +2,12 @@ package config
 
 import (
 	"embed"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"strings"
 
+	"github.com/sandwichfarm/nophr/internal/nostr/helpers"
 	"gopkg.in/yaml.v3"
 )
 
@@ -23,14 +25,17 @@ type Config struct {
 	Inbox        Inbox           `yaml:"inbox"`
 	Outbox       Outbox          `yaml:"outbox"`
 	Storage      Storage         `yaml:"storage"`
+	Backup       Backup          `yaml:"backup"`
 	Export       ExportConfig    `yaml:"export"`
 	Rendering    Rendering       `yaml:"rendering"`
 	Caching      Caching         `yaml:"caching"`
 	Logging      Logging         `yaml:"logging"`
+	Metrics      Metrics         `yaml:"metrics"`
 	Layout       Layout          `yaml:"layout"`
 	Display      Display         `yaml:"display"`
 	Presentation Presentation    `yaml:"presentation"`
 	Behavior     Behavior        `yaml:"behavior"`
+	Security     Security        `yaml:"security"`
 	Sections     []SectionConfig `yaml:"sections"`
 }
 
@@ -53,6 +58,8 @@ type Protocols struct {
 	Gopher GopherProtocol `yaml:"gopher"`
 	Gemini GeminiProtocol `yaml:"gemini"`
 	Finger FingerProtocol `yaml:"finger"`
+	Relay  RelayProtocol  `yaml:"relay"`
+	HTTP   HTTPProtocol   `yaml:"http"`
 }
 
 // GopherProtocol contains Gopher server settings
@@ -65,11 +72,35 @@ type GopherProtocol struct {
 
 // GeminiProtocol contains Gemini server settings
 type GeminiProtocol struct {
-	Enabled bool      `yaml:"enabled"`
-	Host    string    `yaml:"host"`
-	Port    int       `yaml:"port"`
-	Bind    string    `yaml:"bind"`
-	TLS     GeminiTLS `yaml:"tls"`
+	Enabled     bool               `yaml:"enabled"`
+	Host        string             `yaml:"host"`
+	Port        int                `yaml:"port"`
+	Bind        string             `yaml:"bind"`
+	TLS         GeminiTLS          `yaml:"tls"`
+	ClientCerts []GeminiClientCert `yaml:"client_certs"`
+}
+
+// GeminiClientCert maps a client certificate to a Nostr identity
+type GeminiClientCert struct {
+	Fingerprint string `yaml:"fingerprint"` // SHA-256 of the certificate, hex (colons optional)
+	Identity    string `yaml:"identity"`    // "owner", npub or hex pubkey
+}
+
+// Validate checks the client certificate mappings
+func (g *GeminiProtocol) Validate() error {
+	for i, cert := range g.ClientCerts {
+		fingerprint := strings.ReplaceAll(cert.Fingerprint, ":", "")
+		if _, err := hex.DecodeString(fingerprint); err != nil || len(fingerprint) != 64 {
+			return fmt.Errorf("protocols.gemini.client_certs[%d].fingerprint must be a SHA-256 hex digest", i)
+		}
+		if cert.Identity == "owner" {
+			continue
+		}
+		if _, err := helpers.NormalizePubkey(cert.Identity); err != nil {
+			return fmt.Errorf("protocols.gemini.client_certs[%d].identity: %w", i, err)
+		}
+	}
+	return nil
 }
 
 // GeminiTLS contains TLS configuration for Gemini
@@ -87,6 +118,24 @@ type FingerProtocol struct {
 	MaxUsers int    `yaml:"max_users"`
 }
 
+// RelayProtocol contains settings for the local NIP-01 websocket relay
+type RelayProtocol struct {
+	Enabled     bool   `yaml:"enabled"`
+	Port        int    `yaml:"port"`
+	Bind        string `yaml:"bind"`
+	URL         string `yaml:"url"`          // public ws(s):// URL, used for NIP-42 auth behind a proxy
+	OwnerWrites bool   `yaml:"owner_writes"` // accept events from the owner after NIP-42 auth
+}
+
+// HTTPProtocol contains settings for the HTML gateway
+type HTTPProtocol struct {
+	Enabled bool   `yaml:"enabled"`
+	Host    string `yaml:"host"`
+	Port    int    `yaml:"port"`
+	Bind    string `yaml:"bind"`
+	URL     string `yaml:"url"` // public http(s):// base URL, used for absolute links in Atom feeds
+}
+
 // Relays contains relay configuration
 type Relays struct {
 	Seeds  []string    `yaml:"seeds"`
@@ -116,6 +165,7 @@ type Sync struct {
 	Scope       SyncScope       `yaml:"scope"`
 	Retention   Retention       `yaml:"retention"`
 	Performance SyncPerformance `yaml:"performance"`
+	Ingest      Ingest          `yaml:"ingest"`
 }
 
 // SyncPerformance contains performance tuning options
@@ -134,6 +184,8 @@ type SyncKinds struct {
 	Zaps        bool  `yaml:"zaps"`         // kind 9735
 	Articles    bool  `yaml:"articles"`     // kind 30023
 	RelayList   bool  `yaml:"relay_list"`   // kind 10002
+	Deletions   bool  `yaml:"deletions"`    // kind 5 (NIP-09)
+	MuteList    bool  `yaml:"mute_list"`    // kind 10000 (NIP-51), the owner's only
 	Allowlist   []int `yaml:"allowlist"`    // Additional kinds to sync
 }
 
@@ -165,6 +217,12 @@ func (sk *SyncKinds) ToIntSlice() []int {
 	if sk.RelayList {
 		kinds = append(kinds, 10002)
 	}
+	if sk.Deletions {
+		kinds = append(kinds, 5)
+	}
+	if sk.MuteList {
+		kinds = append(kinds, 10000)
+	}
 
 	// Add allowlist kinds
 	kinds = append(kinds, sk.Allowlist...)
@@ -174,13 +232,15 @@ func (sk *SyncKinds) ToIntSlice() []int {
 
 // SyncScope defines synchronization scope
 type SyncScope struct {
-	Mode                  string   `yaml:"mode"` // self|following|mutual|foaf
-	Depth                 int      `yaml:"depth"`
-	IncludeDirectMentions bool     `yaml:"include_direct_mentions"`
-	IncludeThreadsOfMine  bool     `yaml:"include_threads_of_mine"`
-	MaxAuthors            int      `yaml:"max_authors"`
-	AllowlistPubkeys      []string `yaml:"allowlist_pubkeys"`
-	DenylistPubkeys       []string `yaml:"denylist_pubkeys"`
+	Mode                  string         `yaml:"mode"` // self|following|mutual|foaf
+	Depth                 int            `yaml:"depth"`
+	IncludeDirectMentions bool           `yaml:"include_direct_mentions"`
+	IncludeThreadsOfMine  bool           `yaml:"include_threads_of_mine"`
+	MaxAuthors            int            `yaml:"max_authors"`
+	AllowlistPubkeys      []string       `yaml:"allowlist_pubkeys"`
+	DenylistPubkeys       []string       `yaml:"denylist_pubkeys"`
+	ExcludeMuted          bool           `yaml:"exclude_muted"`     // stop syncing pubkeys on the owner's mute list
+	Ranking               *AuthorRanking `yaml:"ranking,omitempty"` // orders authors when there are more than max_authors
 }
 
 // Retention defines data retention policies
@@ -209,9 +269,10 @@ type NoiseFilters struct {
 
 // Outbox contains outbox/publishing settings
 type Outbox struct {
-	Publish  PublishSettings `yaml:"publish"`
-	DraftDir string          `yaml:"draft_dir"`
-	AutoSign bool            `yaml:"auto_sign"`
+	Publish          PublishSettings `yaml:"publish"`
+	DraftDir         string          `yaml:"draft_dir"`
+	AutoSign         bool            `yaml:"auto_sign"`
+	CertFingerprints []string        `yaml:"cert_fingerprints"` // SHA-256 of Gemini client certs allowed to compose
 }
 
 // PublishSettings defines what to publish
@@ -287,6 +348,14 @@ type Logging struct {
 	Format string `yaml:"format"` // text|json
 }
 
+// Metrics contains settings for the Prometheus metrics listener
+type Metrics struct {
+	Enabled bool   `yaml:"enabled"`
+	Port    int    `yaml:"port"`
+	Bind    string `yaml:"bind"`
+	Path    string `yaml:"path"`
+}
+
 // Layout contains layout and section definitions
 type Layout struct {
 	Sections map[string]interface{} `yaml:"sections,omitempty"`
@@ -306,6 +375,7 @@ type FeedDisplay struct {
 	ShowReactions    bool `yaml:"show_reactions"`
 	ShowZaps         bool `yaml:"show_zaps"`
 	ShowReplies      bool `yaml:"show_replies"`
+	HideSensitive    bool `yaml:"hide_sensitive"` // drop notes with a NIP-36 content warning instead of collapsing them
 }
 
 // DetailDisplay controls what appears in individual note/detail views
@@ -383,6 +453,7 @@ type Behavior struct {
 type ExportConfig struct {
 	Gopher GopherExportConfig `yaml:"gopher"`
 	Gemini GeminiExportConfig `yaml:"gemini"`
+	Feeds  FeedsExportConfig  `yaml:"feeds"`
 }
 
 // GopherExportConfig configures static gopher generation
@@ -403,6 +474,16 @@ type GeminiExportConfig struct {
 	MaxItems  int    `yaml:"max_items"`
 }
 
+// FeedsExportConfig configures Atom, RSS and JSON Feed generation for the
+// outbox, articles and sections
+type FeedsExportConfig struct {
+	Enabled   bool     `yaml:"enabled"`
+	OutputDir string   `yaml:"output_dir"`
+	Formats   []string `yaml:"formats"` // atom, rss, json
+	MaxItems  int      `yaml:"max_items"`
+	URL       string   `yaml:"url"` // public base URL the feed files are served from, for self links
+}
+
 // ContentFiltering defines content filtering rules
 type ContentFiltering struct {
 	Enabled             bool     `yaml:"enabled"`
@@ -523,10 +604,68 @@ func applyDefaults(cfg *Config) {
 		cfg.Export.Gemini.MaxItems = defaults.Export.Gemini.MaxItems
 	}
 
+	// Apply Export.Feeds defaults
+	if cfg.Export.Feeds.OutputDir == "" {
+		cfg.Export.Feeds.OutputDir = defaults.Export.Feeds.OutputDir
+	}
+	if len(cfg.Export.Feeds.Formats) == 0 {
+		cfg.Export.Feeds.Formats = defaults.Export.Feeds.Formats
+	}
+	if cfg.Export.Feeds.MaxItems == 0 {
+		cfg.Export.Feeds.MaxItems = defaults.Export.Feeds.MaxItems
+	}
+
+	// Apply Sync scope ranking defaults; a configured ranking keeps its weights
+	if cfg.Sync.Scope.Ranking == nil {
+		cfg.Sync.Scope.Ranking = defaults.Sync.Scope.Ranking
+	}
+	if cfg.Sync.Scope.Ranking.InteractionDays == 0 {
+		cfg.Sync.Scope.Ranking.InteractionDays = defaults.Sync.Scope.Ranking.InteractionDays
+	}
+
 	// Apply Sync performance defaults
 	if cfg.Sync.Performance.Workers == 0 {
 		cfg.Sync.Performance.Workers = defaults.Sync.Performance.Workers
 	}
+
+	// Apply Sync ingest defaults; an explicit empty policy list disables the checks
+	if cfg.Sync.Ingest.Policies == nil {
+		cfg.Sync.Ingest.Policies = defaults.Sync.Ingest.Policies
+	}
+	if cfg.Sync.Ingest.MaxContentBytes == 0 {
+		cfg.Sync.Ingest.MaxContentBytes = defaults.Sync.Ingest.MaxContentBytes
+	}
+	if cfg.Sync.Ingest.MaxTags == 0 {
+		cfg.Sync.Ingest.MaxTags = defaults.Sync.Ingest.MaxTags
+	}
+	if cfg.Sync.Ingest.MaxTagBytes == 0 {
+		cfg.Sync.Ingest.MaxTagBytes = defaults.Sync.Ingest.MaxTagBytes
+	}
+	if cfg.Sync.Ingest.MaxFutureSeconds == 0 {
+		cfg.Sync.Ingest.MaxFutureSeconds = defaults.Sync.Ingest.MaxFutureSeconds
+	}
+
+	// Apply Backup defaults; keep and max_age_days stay 0 (no limit) if unset
+	if cfg.Backup.Dir == "" {
+		cfg.Backup.Dir = defaults.Backup.Dir
+	}
+	if cfg.Backup.IntervalHours == 0 {
+		cfg.Backup.IntervalHours = defaults.Backup.IntervalHours
+	}
+	if cfg.Backup.Compression == "" {
+		cfg.Backup.Compression = defaults.Backup.Compression
+	}
+
+	// Apply Metrics listener defaults
+	if cfg.Metrics.Port == 0 {
+		cfg.Metrics.Port = defaults.Metrics.Port
+	}
+	if cfg.Metrics.Bind == "" {
+		cfg.Metrics.Bind = defaults.Metrics.Bind
+	}
+	if cfg.Metrics.Path == "" {
+		cfg.Metrics.Path = defaults.Metrics.Path
+	}
 }
 
 // Load reads and parses a configuration file
@@ -617,6 +756,17 @@ func Default() *Config {
 				Bind:     "0.0.0.0",
 				MaxUsers: 100,
 			},
+			Relay: RelayProtocol{
+				Enabled: false,
+				Port:    7777,
+				Bind:    "127.0.0.1",
+			},
+			HTTP: HTTPProtocol{
+				Enabled: false,
+				Host:    "localhost",
+				Port:    8080,
+				Bind:    "0.0.0.0",
+			},
 		},
 		Relays: Relays{
 			Seeds: []string{
@@ -647,6 +797,8 @@ func Default() *Config {
 				Zaps:        true,
 				Articles:    true,
 				RelayList:   true,
+				Deletions:   true,
+				MuteList:    true,
 				Allowlist:   []int{},
 			},
 			Scope: SyncScope{
@@ -657,6 +809,7 @@ func Default() *Config {
 				MaxAuthors:            5000,
 				AllowlistPubkeys:      []string{},
 				DenylistPubkeys:       []string{},
+				Ranking:               DefaultAuthorRanking(),
 			},
 			Retention: Retention{
 				KeepDays:     365,
@@ -666,6 +819,14 @@ func Default() *Config {
 				Workers:       4,    // Default: 4 parallel event processing workers
 				UseNegentropy: true, // Default: enable NIP-77 negentropy (always falls back to REQ if unsupported)
 			},
+			Ingest: Ingest{
+				Policies:         append([]string(nil), DefaultIngestPolicies...),
+				MaxContentBytes:  256 * 1024,
+				MaxTags:          10000,
+				MaxTagBytes:      1024 * 1024,
+				MaxFutureSeconds: 900,
+				MinPoWByKind:     map[int]int{},
+			},
 		},
 		Inbox: Inbox{
 			IncludeReplies:   true,
@@ -693,6 +854,14 @@ func Default() *Config {
 			LMDBPath:      "./data/nophr.lmdb",
 			LMDBMaxSizeMB: 10240,
 		},
+		Backup: Backup{
+			Enabled:       false,
+			Dir:           "./data/backups",
+			IntervalHours: 24,
+			Compression:   BackupCompressionGzip,
+			Keep:          7,
+			MaxAgeDays:    30,
+		},
 		Export: ExportConfig{
 			Gopher: GopherExportConfig{
 				Enabled:   false,
@@ -708,6 +877,12 @@ func Default() *Config {
 				Port:      1965,
 				MaxItems:  200,
 			},
+			Feeds: FeedsExportConfig{
+				Enabled:   false,
+				OutputDir: "./export/feeds",
+				Formats:   []string{"atom", "rss", "json"},
+				MaxItems:  50,
+			},
 		},
 		Rendering: Rendering{
 			Gopher: GopherRendering{
@@ -745,6 +920,7 @@ func Default() *Config {
 				Render: map[string]int{
 					"gopher_menu":     300,
 					"gemini_page":     300,
+					"http_page":       300,
 					"finger_response": 60,
 					"kind_1":          86400,
 					"kind_30023":      604800,
@@ -762,6 +938,12 @@ func Default() *Config {
 			Level:  "info",
 			Format: "text",
 		},
+		Metrics: Metrics{
+			Enabled: false,
+			Port:    9464,
+			Bind:    "127.0.0.1",
+			Path:    "/metrics",
+		},
 		Layout: Layout{
 			Sections: make(map[string]interface{}),
 			Pages:    make(map[string]interface{}),
@@ -839,6 +1021,19 @@ func Default() *Config {
 				MaxPages:     10,
 			},
 		},
+		Security: Security{
+			Enabled: true,
+			RateLimits: SecurityRateLimits{
+				Gopher: 60,
+				Gemini: 60,
+				Finger: 30,
+				HTTP:   60,
+			},
+			MaxConnectionsPerIP: 10,
+			MaxConnections:      500,
+			DenylistPubkeys:     []string{},
+			BannedWords:         []string{},
+		},
 	}
 }
 
@@ -881,7 +1076,7 @@ func Validate(cfg *Config) error {
 	}
 
 	// Validate at least one protocol is enabled
-	if !cfg.Protocols.Gopher.Enabled && !cfg.Protocols.Gemini.Enabled && !cfg.Protocols.Finger.Enabled {
+	if !cfg.Protocols.Gopher.Enabled && !cfg.Protocols.Gemini.Enabled && !cfg.Protocols.Finger.Enabled && !cfg.Protocols.Relay.Enabled && !cfg.Protocols.HTTP.Enabled {
 		return fmt.Errorf("at least one protocol must be enabled")
 	}
 
@@ -895,6 +1090,15 @@ func Validate(cfg *Config) error {
 	if cfg.Protocols.Finger.Enabled && (cfg.Protocols.Finger.Port < 1 || cfg.Protocols.Finger.Port > 65535) {
 		return fmt.Errorf("finger port must be between 1 and 65535")
 	}
+	if cfg.Protocols.Relay.Enabled && (cfg.Protocols.Relay.Port < 1 || cfg.Protocols.Relay.Port > 65535) {
+		return fmt.Errorf("relay port must be between 1 and 65535")
+	}
+	if cfg.Protocols.HTTP.Enabled && (cfg.Protocols.HTTP.Port < 1 || cfg.Protocols.HTTP.Port > 65535) {
+		return fmt.Errorf("http port must be between 1 and 65535")
+	}
+	if cfg.Metrics.Enabled && (cfg.Metrics.Port < 1 || cfg.Metrics.Port > 65535) {
+		return fmt.Errorf("metrics port must be between 1 and 65535")
+	}
 
 	// Validate relay seeds
 	if len(cfg.Relays.Seeds) == 0 {
@@ -916,6 +1120,14 @@ func Validate(cfg *Config) error {
 		return fmt.Errorf("invalid storage driver: %s (must be one of: sqlite, lmdb)", cfg.Storage.Driver)
 	}
 
+	// Validate backups; only SQLite databases can be backed up online
+	if err := cfg.Backup.Validate(); err != nil {
+		return err
+	}
+	if cfg.Backup.Enabled && cfg.Storage.Driver != "sqlite" {
+		return fmt.Errorf("backup.enabled requires the sqlite storage driver")
+	}
+
 	// Validate cache engine
 	if cfg.Caching.Enabled && !validCacheEngines[cfg.Caching.Engine] {
 		return fmt.Errorf("invalid cache engine: %s (must be one of: memory, redis)", cfg.Caching.Engine)
@@ -999,6 +1211,26 @@ func Validate(cfg *Config) error {
 		}
 	}
 
+	// Validate feed export
+	if cfg.Export.Feeds.Enabled {
+		if cfg.Export.Feeds.OutputDir == "" {
+			return fmt.Errorf("export.feeds.output_dir is required when export.feeds.enabled is true")
+		}
+		if len(cfg.Export.Feeds.Formats) == 0 {
+			return fmt.Errorf("export.feeds.formats must list at least one format")
+		}
+		for _, format := range cfg.Export.Feeds.Formats {
+			switch format {
+			case "atom", "rss", "json":
+			default:
+				return fmt.Errorf("export.feeds.formats: unknown format %q (use atom, rss or json)", format)
+			}
+		}
+		if cfg.Export.Feeds.MaxItems < 1 || cfg.Export.Feeds.MaxItems > 5000 {
+			return fmt.Errorf("export.feeds.max_items must be between 1 and 5000")
+		}
+	}
+
 	// Validate advanced retention (Phase 20)
 	if cfg.Sync.Retention.Advanced != nil {
 		if err := cfg.Sync.Retention.Advanced.Validate(); err != nil {
@@ -1006,6 +1238,25 @@ func Validate(cfg *Config) error {
 		}
 	}
 
+	if err := cfg.Sync.Ingest.Validate(); err != nil {
+		return err
+	}
+
+	if cfg.Sync.Scope.Ranking != nil {
+		if err := cfg.Sync.Scope.Ranking.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate security
+	if err := cfg.Security.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Protocols.Gemini.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -1024,18 +1275,20 @@ type SectionConfig struct {
 	GroupBy     string                 `yaml:"group_by"`
 	MoreLink    *SectionMoreLinkConfig `yaml:"more_link"`
 	Order       int                    `yaml:"order"`
+	Hidden      bool                   `yaml:"hidden"` // only served to the owner under /owner in Gemini
 }
 
 // SectionFilterConfig represents section filters in YAML
 type SectionFilterConfig struct {
-	Kinds   []int               `yaml:"kinds"`
-	Authors []string            `yaml:"authors"`
-	Tags    map[string][]string `yaml:"tags"`
-	Since   string              `yaml:"since"` // RFC3339 or duration like "-24h"
-	Until   string              `yaml:"until"` // RFC3339 or duration
-	Search  string              `yaml:"search"`
-	Scope   string              `yaml:"scope"`    // self, following, mutual, foaf, all
-	IsReply *bool               `yaml:"is_reply"` // true = only replies, false = only roots
+	Kinds     []int               `yaml:"kinds"`
+	Authors   []string            `yaml:"authors"`
+	Tags      map[string][]string `yaml:"tags"`
+	Since     string              `yaml:"since"` // RFC3339 or duration like "-24h"
+	Until     string              `yaml:"until"` // RFC3339 or duration
+	Search    string              `yaml:"search"`
+	Scope     string              `yaml:"scope"`     // self, following, mutual, foaf, all
+	IsReply   *bool               `yaml:"is_reply"`  // true = only replies, false = only roots
+	Sensitive *bool               `yaml:"sensitive"` // true = only content-warned notes, false = exclude them
 }
 
 // SectionMoreLinkConfig represents a "more" link configuration
@@ -0,0 +1,73 @@
+package config
+
+import "fmt"
+
+// Ingest policy names, in the order they run by default
+const (
+	IngestSignature  = "signature"  // event ID and signature must verify
+	IngestSize       = "size"       // content and tags within the size limits
+	IngestFuture     = "future"     // created_at no further ahead than max_future_seconds
+	IngestPoW        = "pow"        // NIP-13 minimum proof-of-work
+	IngestExpiration = "expiration" // NIP-40 expired events
+	IngestSecurity   = "security"   // security.denylist_pubkeys and banned_words
+)
+
+// DefaultIngestPolicies is the policy chain used when sync.ingest.policies is unset
+var DefaultIngestPolicies = []string{
+	IngestSignature,
+	IngestSize,
+	IngestFuture,
+	IngestPoW,
+	IngestExpiration,
+	IngestSecurity,
+}
+
+// Ingest contains the checks an event received from a relay must pass
+// before it is stored
+type Ingest struct {
+	Policies         []string    `yaml:"policies"`           // checks to run, in order; [] disables all
+	MaxContentBytes  int         `yaml:"max_content_bytes"`  // size: longest accepted content
+	MaxTags          int         `yaml:"max_tags"`           // size: most tags per event
+	MaxTagBytes      int         `yaml:"max_tag_bytes"`      // size: total bytes of all tag values
+	MaxFutureSeconds int         `yaml:"max_future_seconds"` // future: allowed clock skew
+	MinPoW           int         `yaml:"min_pow"`            // pow: difficulty required of every kind, 0 = none
+	MinPoWByKind     map[int]int `yaml:"min_pow_by_kind"`    // pow: overrides min_pow per kind
+}
+
+// RequiredPoW returns the NIP-13 difficulty required for a kind
+func (i *Ingest) RequiredPoW(kind int) int {
+	if bits, ok := i.MinPoWByKind[kind]; ok {
+		return bits
+	}
+	return i.MinPoW
+}
+
+// Validate checks if ingest config is valid
+func (i *Ingest) Validate() error {
+	known := make(map[string]bool, len(DefaultIngestPolicies))
+	for _, name := range DefaultIngestPolicies {
+		known[name] = true
+	}
+	for _, name := range i.Policies {
+		if !known[name] {
+			return fmt.Errorf("sync.ingest.policies: unknown policy %q", name)
+		}
+	}
+
+	if i.MaxContentBytes < 0 || i.MaxTags < 0 || i.MaxTagBytes < 0 {
+		return fmt.Errorf("sync.ingest size limits must be >= 0")
+	}
+	if i.MaxFutureSeconds < 0 {
+		return fmt.Errorf("sync.ingest.max_future_seconds must be >= 0")
+	}
+	if i.MinPoW < 0 || i.MinPoW > 256 {
+		return fmt.Errorf("sync.ingest.min_pow must be between 0 and 256")
+	}
+	for kind, bits := range i.MinPoWByKind {
+		if bits < 0 || bits > 256 {
+			return fmt.Errorf("sync.ingest.min_pow_by_kind[%d] must be between 0 and 256", kind)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import "fmt"
+
+// AuthorRanking weighs the signals that decide which authors are synced when
+// the scope holds more than max_authors. An author's score is the sum of each
+// signal times its weight, and ties go to the lower pubkey, so the same graph
+// always selects the same authors. Weights left out of a configured ranking
+// count as 0.
+type AuthorRanking struct {
+	Depth           float64 `yaml:"depth"`            // per hop closer to the owner than the furthest candidates
+	FollowedBy      float64 `yaml:"followed_by"`      // per follow from the owner's follows
+	Mutual          float64 `yaml:"mutual"`           // once if they follow the owner back
+	Interactions    float64 `yaml:"interactions"`     // per reply, reaction or zap to the owner
+	InteractionDays int     `yaml:"interaction_days"` // how far back interactions count
+	RelayHints      float64 `yaml:"relay_hints"`      // once if their relay list is known
+}
+
+// DefaultAuthorRanking favours close, well-connected authors the owner talks to
+func DefaultAuthorRanking() *AuthorRanking {
+	return &AuthorRanking{
+		Depth:           10,
+		FollowedBy:      1,
+		Mutual:          10,
+		Interactions:    2,
+		InteractionDays: 30,
+		RelayHints:      5,
+	}
+}
+
+// Validate checks if author ranking config is valid
+func (r *AuthorRanking) Validate() error {
+	for name, weight := range map[string]float64{
+		"depth":        r.Depth,
+		"followed_by":  r.FollowedBy,
+		"mutual":       r.Mutual,
+		"interactions": r.Interactions,
+		"relay_hints":  r.RelayHints,
+	} {
+		if weight < 0 {
+			return fmt.Errorf("sync.scope.ranking.%s must be >= 0", name)
+		}
+	}
+	if r.InteractionDays < 0 {
+		return fmt.Errorf("sync.scope.ranking.interaction_days must be >= 0")
+	}
+	return nil
+}
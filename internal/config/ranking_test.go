@@ -0,0 +1,80 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuthorRankingValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		ranking AuthorRanking
+		wantErr string
+	}{
+		{"defaults", *DefaultAuthorRanking(), ""},
+		{"all zero", AuthorRanking{}, ""},
+		{"negative weight", AuthorRanking{Mutual: -1}, "sync.scope.ranking.mutual"},
+		{"negative window", AuthorRanking{InteractionDays: -7}, "sync.scope.ranking.interaction_days"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.ranking.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthorRankingDefaults(t *testing.T) {
+	dir := t.TempDir()
+	write := func(scope string) *Config {
+		t.Helper()
+		path := filepath.Join(dir, "nophr.yaml")
+		content := `
+identity:
+  npub: npub1test
+protocols:
+  gopher:
+    enabled: true
+    port: 70
+relays:
+  seeds: ["wss://relay.example.com"]
+storage:
+  driver: sqlite
+logging:
+  level: info
+sync:
+  scope:
+    mode: foaf
+` + scope
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+		cfg, err := Load(path)
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		return cfg
+	}
+
+	if got := write("").Sync.Scope.Ranking; *got != *DefaultAuthorRanking() {
+		t.Errorf("expected default ranking without a ranking block, got %+v", got)
+	}
+
+	// Weights left out of a ranking block are off, not defaulted
+	got := write("    ranking:\n      mutual: 50\n").Sync.Scope.Ranking
+	want := AuthorRanking{Mutual: 50, InteractionDays: DefaultAuthorRanking().InteractionDays}
+	if *got != want {
+		t.Errorf("Ranking = %+v, want %+v", *got, want)
+	}
+}
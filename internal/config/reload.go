@@ -0,0 +1,156 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ReloadPlan lists the settings that differ between two configurations,
+// split by whether a running process can apply them
+type ReloadPlan struct {
+	Live    []string // applied without a restart
+	Restart []string // keep their startup values until the next restart
+}
+
+// Empty reports whether the configurations are equivalent
+func (p *ReloadPlan) Empty() bool {
+	return len(p.Live) == 0 && len(p.Restart) == 0
+}
+
+// reloadSetting is a config key compared on reload
+type reloadSetting struct {
+	key   string
+	live  bool
+	value func(*Config) interface{}
+}
+
+// reloadSettings covers every top-level key. Listeners, storage, relay
+// connections, caches and background schedules are set up once at startup;
+// sections, display, presentation, rendering, retention rules and the sync
+// scope are read on every request or sync iteration.
+var reloadSettings = []reloadSetting{
+	{"site", true, func(c *Config) interface{} { return c.Site }},
+	{"identity", false, func(c *Config) interface{} { return c.Identity }},
+	{"protocols.gopher", false, func(c *Config) interface{} { return c.Protocols.Gopher }},
+	{"protocols.gemini", false, func(c *Config) interface{} { return c.Protocols.Gemini }},
+	{"protocols.finger", false, func(c *Config) interface{} { return c.Protocols.Finger }},
+	{"protocols.relay", false, func(c *Config) interface{} { return c.Protocols.Relay }},
+	{"protocols.http", false, func(c *Config) interface{} { return c.Protocols.HTTP }},
+	{"relays", false, func(c *Config) interface{} { return c.Relays }},
+	{"discovery", false, func(c *Config) interface{} { return c.Discovery }},
+	{"sync.enabled", false, func(c *Config) interface{} { return c.Sync.Enabled }},
+	{"sync.kinds", true, func(c *Config) interface{} { return c.Sync.Kinds }},
+	{"sync.scope", true, func(c *Config) interface{} { return c.Sync.Scope }},
+	{"sync.retention.keep_days", true, func(c *Config) interface{} { return c.Sync.Retention.KeepDays }},
+	{"sync.retention.prune_on_start", false, func(c *Config) interface{} { return c.Sync.Retention.PruneOnStart }},
+	{"sync.retention.prune_interval_hours", false, func(c *Config) interface{} { return c.Sync.Retention.PruneIntervalHours }},
+	{"sync.retention.advanced.enabled", false, func(c *Config) interface{} { return advancedRetention(c).Enabled }},
+	{"sync.retention.advanced.evaluation", false, func(c *Config) interface{} { return advancedRetention(c).Evaluation }},
+	{"sync.retention.advanced", true, func(c *Config) interface{} {
+		advanced := advancedRetention(c)
+		advanced.Enabled = false
+		advanced.Evaluation = EvaluationConfig{}
+		return advanced
+	}},
+	{"sync.performance", false, func(c *Config) interface{} { return c.Sync.Performance }},
+	{"sync.ingest", false, func(c *Config) interface{} { return c.Sync.Ingest }},
+	{"inbox", false, func(c *Config) interface{} { return c.Inbox }},
+	{"outbox", false, func(c *Config) interface{} { return c.Outbox }},
+	{"storage", false, func(c *Config) interface{} { return c.Storage }},
+	{"backup", false, func(c *Config) interface{} { return c.Backup }},
+	{"export", false, func(c *Config) interface{} { return c.Export }},
+	{"rendering", true, func(c *Config) interface{} { return c.Rendering }},
+	{"caching", false, func(c *Config) interface{} { return c.Caching }},
+	{"logging", false, func(c *Config) interface{} { return c.Logging }},
+	{"metrics", false, func(c *Config) interface{} { return c.Metrics }},
+	{"layout", true, func(c *Config) interface{} { return c.Layout }},
+	{"display", true, func(c *Config) interface{} { return c.Display }},
+	{"presentation", true, func(c *Config) interface{} { return c.Presentation }},
+	{"behavior", true, func(c *Config) interface{} { return c.Behavior }},
+	{"security", false, func(c *Config) interface{} { return c.Security }},
+	{"sections", true, func(c *Config) interface{} { return c.Sections }},
+}
+
+// advancedRetention returns a copy of the advanced retention settings, zero
+// when they are not configured
+func advancedRetention(c *Config) AdvancedRetention {
+	if c.Sync.Retention.Advanced == nil {
+		return AdvancedRetention{}
+	}
+	return *c.Sync.Retention.Advanced
+}
+
+// PlanReload compares a running configuration with a reloaded one
+func PlanReload(current, next *Config) *ReloadPlan {
+	plan := &ReloadPlan{}
+	for _, setting := range reloadSettings {
+		if reflect.DeepEqual(setting.value(current), setting.value(next)) {
+			continue
+		}
+		if setting.live {
+			plan.Live = append(plan.Live, setting.key)
+		} else {
+			plan.Restart = append(plan.Restart, setting.key)
+		}
+	}
+	return plan
+}
+
+// watchDebounce is how long a config file must stay quiet before a change
+// is reported, so an editor saving in several steps triggers one reload
+const watchDebounce = 250 * time.Millisecond
+
+// Watch calls onChange after the config file at path is written or replaced,
+// until ctx is done. The file's directory is watched rather than the file, so
+// editors that save by renaming a temporary file over it are noticed too.
+func Watch(ctx context.Context, path string, onChange func()) error {
+	target, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config path: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(target)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch %s: %w", filepath.Dir(target), err)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		debounce := time.NewTimer(watchDebounce)
+		debounce.Stop()
+		defer debounce.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) == target && event.Has(fsnotify.Write|fsnotify.Create) {
+					debounce.Reset(watchDebounce)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				fmt.Printf("[CONFIG] ⚠ Watch error: %v\n", err)
+			case <-debounce.C:
+				onChange()
+			}
+		}
+	}()
+
+	return nil
+}
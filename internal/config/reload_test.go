@@ -0,0 +1,95 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlanReload(t *testing.T) {
+	current := Default()
+	current.Sync.Retention.Advanced = &AdvancedRetention{Enabled: true, Mode: "rules"}
+
+	if plan := PlanReload(current, Default()); !reflect.DeepEqual(plan.Restart, []string{"sync.retention.advanced.enabled"}) || len(plan.Live) != 1 {
+		t.Errorf("dropping advanced retention should only report its switch and rules, got %+v", plan)
+	}
+
+	next := Default()
+	next.Sync.Retention.Advanced = &AdvancedRetention{Enabled: true, Mode: "rules"}
+	if plan := PlanReload(current, next); !plan.Empty() {
+		t.Errorf("expected no changes between equal configs, got %+v", plan)
+	}
+
+	next.Site.Title = "Renamed"
+	next.Display.Limits.SummaryLength = 42
+	next.Sections = []SectionConfig{{Name: "diy", Path: "/diy"}}
+	next.Sync.Scope.Mode = "self"
+	next.Sync.Retention.KeepDays = 7
+	next.Sync.Retention.Advanced.Mode = "caps"
+	next.Protocols.Gopher.Port = 7070
+	next.Storage.Driver = "lmdb"
+	next.Sync.Retention.PruneIntervalHours = 12
+
+	plan := PlanReload(current, next)
+
+	wantLive := []string{"site", "sync.scope", "sync.retention.keep_days", "sync.retention.advanced", "display", "sections"}
+	if !reflect.DeepEqual(plan.Live, wantLive) {
+		t.Errorf("Live = %v, want %v", plan.Live, wantLive)
+	}
+	wantRestart := []string{"protocols.gopher", "sync.retention.prune_interval_hours", "storage"}
+	if !reflect.DeepEqual(plan.Restart, wantRestart) {
+		t.Errorf("Restart = %v, want %v", plan.Restart, wantRestart)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nophr.yaml")
+	if err := os.WriteFile(path, []byte("site:\n  title: one\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan struct{}, 10)
+	if err := Watch(ctx, path, func() { changes <- struct{}{} }); err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	// Other files in the directory are ignored
+	if err := os.WriteFile(filepath.Join(dir, "other.yaml"), []byte("x"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	select {
+	case <-changes:
+		t.Fatal("unexpected change for another file")
+	case <-time.After(2 * watchDebounce):
+	}
+
+	// A save through a temporary file is seen as one change
+	tmp := filepath.Join(dir, ".nophr.yaml.swp")
+	if err := os.WriteFile(tmp, []byte("site:\n  title: two\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("failed to replace config: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("site:\n  title: three\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a change after the config was replaced")
+	}
+	select {
+	case <-changes:
+		t.Error("expected writes in quick succession to be debounced")
+	case <-time.After(2 * watchDebounce):
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/sandwichfarm/nophr/internal/nostr/helpers"
+)
+
+// Security contains request throttling and content policy settings for the
+// protocol servers
+type Security struct {
+	Enabled             bool               `yaml:"enabled"`
+	RateLimits          SecurityRateLimits `yaml:"rate_limits"`
+	MaxConnectionsPerIP int                `yaml:"max_connections_per_ip"` // 0 = unlimited
+	MaxConnections      int                `yaml:"max_connections"`        // across every protocol server, 0 = unlimited
+	DenylistPubkeys     []string           `yaml:"denylist_pubkeys"`       // npub or hex, hidden from every rendered list
+	BannedWords         []string           `yaml:"banned_words"`           // events containing these are hidden
+}
+
+// SecurityRateLimits sets per-protocol request limits, in requests per minute
+// per client IP. 0 disables the limit for that protocol.
+type SecurityRateLimits struct {
+	Gopher int `yaml:"gopher"`
+	Gemini int `yaml:"gemini"`
+	Finger int `yaml:"finger"`
+	HTTP   int `yaml:"http"`
+}
+
+// Validate checks if security config is valid
+func (s *Security) Validate() error {
+	if s.RateLimits.Gopher < 0 || s.RateLimits.Gemini < 0 || s.RateLimits.Finger < 0 || s.RateLimits.HTTP < 0 {
+		return fmt.Errorf("security.rate_limits must be >= 0")
+	}
+	if s.MaxConnectionsPerIP < 0 {
+		return fmt.Errorf("security.max_connections_per_ip must be >= 0")
+	}
+	if s.MaxConnections < 0 {
+		return fmt.Errorf("security.max_connections must be >= 0")
+	}
+
+	for i, pubkey := range s.DenylistPubkeys {
+		if _, err := helpers.NormalizePubkey(pubkey); err != nil {
+			return fmt.Errorf("security.denylist_pubkeys[%d]: %w", i, err)
+		}
+	}
+
+	return nil
+}
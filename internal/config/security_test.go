@@ -0,0 +1,115 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSecurityValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		sec     Security
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "defaults",
+			sec:     Default().Security,
+			wantErr: false,
+		},
+		{
+			name:    "negative rate limit",
+			sec:     Security{Enabled: true, RateLimits: SecurityRateLimits{Gemini: -1}},
+			wantErr: true,
+			errMsg:  "rate_limits",
+		},
+		{
+			name:    "negative per-IP cap",
+			sec:     Security{Enabled: true, MaxConnectionsPerIP: -1},
+			wantErr: true,
+			errMsg:  "max_connections_per_ip",
+		},
+		{
+			name:    "negative global cap",
+			sec:     Security{Enabled: true, MaxConnections: -1},
+			wantErr: true,
+			errMsg:  "max_connections",
+		},
+		{
+			name: "npub and hex deny list",
+			sec: Security{DenylistPubkeys: []string{
+				"npub1nq3zgtqruwhnz0xx40gh4a4fkamlr2sc7ke5wqs2s3nyv2fpy9esg4hdwq",
+				strings.Repeat("ab", 32),
+			}},
+			wantErr: false,
+		},
+		{
+			name:    "invalid deny list entry",
+			sec:     Security{DenylistPubkeys: []string{"not-a-pubkey"}},
+			wantErr: true,
+			errMsg:  "denylist_pubkeys[0]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.sec.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestGeminiClientCertsValidate(t *testing.T) {
+	fingerprint := strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name    string
+		certs   []GeminiClientCert
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "owner and npub",
+			certs: []GeminiClientCert{
+				{Fingerprint: fingerprint, Identity: "owner"},
+				{Fingerprint: strings.ToUpper(fingerprint), Identity: "npub1nq3zgtqruwhnz0xx40gh4a4fkamlr2sc7ke5wqs2s3nyv2fpy9esg4hdwq"},
+			},
+		},
+		{
+			name:  "colon separated fingerprint",
+			certs: []GeminiClientCert{{Fingerprint: strings.Repeat("ab:", 31) + "ab", Identity: "owner"}},
+		},
+		{
+			name:    "short fingerprint",
+			certs:   []GeminiClientCert{{Fingerprint: "abcd", Identity: "owner"}},
+			wantErr: true,
+			errMsg:  "client_certs[0].fingerprint",
+		},
+		{
+			name:    "invalid identity",
+			certs:   []GeminiClientCert{{Fingerprint: fingerprint, Identity: "alice"}},
+			wantErr: true,
+			errMsg:  "client_certs[0].identity",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := GeminiProtocol{ClientCerts: tt.certs}
+			err := g.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
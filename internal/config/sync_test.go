@@ -260,3 +260,36 @@ func TestSyncKindsWithAllowlist(t *testing.T) {
 		t.Error("Expected allowlist kinds to be included")
 	}
 }
+
+func TestIngestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		ingest  Ingest
+		wantErr bool
+	}{
+		{"defaults", Default().Sync.Ingest, false},
+		{"no policies", Ingest{Policies: []string{}}, false},
+		{"unknown policy", Ingest{Policies: []string{"signature", "nip05"}}, true},
+		{"negative size limit", Ingest{MaxTags: -1}, true},
+		{"min pow out of range", Ingest{MinPoW: 257}, true},
+		{"negative kind pow", Ingest{MinPoWByKind: map[int]int{1: -1}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.ingest.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIngestRequiredPoW(t *testing.T) {
+	ingest := Ingest{MinPoW: 8, MinPoWByKind: map[int]int{1: 16, 7: 0}}
+
+	for kind, want := range map[int]int{1: 16, 7: 0, 30023: 8} {
+		if got := ingest.RequiredPoW(kind); got != want {
+			t.Errorf("RequiredPoW(%d) = %d, want %d", kind, got, want)
+		}
+	}
+}
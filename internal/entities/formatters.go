@@ -1,6 +1,9 @@
 package entities
 
-import "fmt"
+import (
+	"fmt"
+	"html"
+)
 
 // GopherFormatter formats an entity for Gopher protocol
 // Returns inline text representation (Gopher doesn't support inline links)
@@ -29,7 +32,8 @@ func MarkdownFormatter(entity *Entity) string {
 	return fmt.Sprintf("[%s](%s)", entity.DisplayName, entity.Link)
 }
 
-// HTMLFormatter formats an entity as HTML link
+// HTMLFormatter formats an entity as HTML link, escaping the link and the
+// display name (profile names are user-controlled)
 func HTMLFormatter(entity *Entity) string {
-	return fmt.Sprintf(`<a href="%s">%s</a>`, entity.Link, entity.DisplayName)
+	return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(entity.Link), html.EscapeString(entity.DisplayName))
 }
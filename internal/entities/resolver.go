@@ -91,6 +91,12 @@ func (r *Resolver) ResolveEntity(ctx context.Context, nip19Entity string) (*Enti
 	return entity, nil
 }
 
+// DisplayName returns the profile name of a pubkey, falling back to a
+// truncated pubkey when no profile is stored
+func (r *Resolver) DisplayName(ctx context.Context, pubkey string) string {
+	return r.resolvePubkeyName(ctx, pubkey)
+}
+
 // resolvePubkeyName fetches the display name for a pubkey
 func (r *Resolver) resolvePubkeyName(ctx context.Context, pubkey string) string {
 	// Try to get profile from storage
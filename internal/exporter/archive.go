@@ -0,0 +1,123 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/sections"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+// queryRootArchive returns every owner root event of a kind, newest first.
+// The section index only lists the newest max_items; the archive tree lists
+// the rest. Events with a content warning are left out when hideSensitive
+// is set.
+func queryRootArchive(ctx context.Context, st *storage.Storage, ownerPubkey string, kind int, hideSensitive bool, isRoot func(*nostr.Event) bool) ([]*nostr.Event, error) {
+	section := &sections.Section{
+		Filters: sections.FilterSet{
+			Kinds:   []int{kind},
+			Authors: []string{ownerPubkey},
+		},
+	}
+	if hideSensitive {
+		sensitive := false
+		section.Filters.Sensitive = &sensitive
+	}
+
+	events, err := sections.NewArchiveManager(st).SectionEvents(ctx, section)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events for export: %w", err)
+	}
+
+	var roots []*nostr.Event
+	for _, event := range events {
+		if isRoot(event) {
+			roots = append(roots, event)
+		}
+	}
+
+	return roots, nil
+}
+
+// newest returns at most n of the events, which are sorted newest first
+func newest(events []*nostr.Event, n int) []*nostr.Event {
+	if len(events) > n {
+		return events[:n]
+	}
+	return events
+}
+
+// archiveTree groups the exported events of a section into the same
+// year/month/day archives the protocol servers route
+type archiveTree struct {
+	section string
+	years   []*sections.Archive
+	events  map[string][]*nostr.Event // by archive selector
+}
+
+func newArchiveTree(section string, events []*nostr.Event) *archiveTree {
+	tree := &archiveTree{
+		section: section,
+		years:   sections.GroupArchives(events, sections.ArchiveByYear),
+		events:  make(map[string][]*nostr.Event),
+	}
+
+	periods := []sections.ArchivePeriod{sections.ArchiveByYear, sections.ArchiveByMonth, sections.ArchiveByDay}
+	for _, event := range events {
+		for _, period := range periods {
+			selector := tree.selector(sections.GroupArchives([]*nostr.Event{event}, period)[0])
+			tree.events[selector] = append(tree.events[selector], event)
+		}
+	}
+
+	return tree
+}
+
+// selector returns the selector of an archive page
+func (t *archiveTree) selector(archive *sections.Archive) string {
+	return archive.FormatArchiveSelector(t.section)
+}
+
+// all returns every year, month and day archive, parents before children
+func (t *archiveTree) all() []*sections.Archive {
+	var archives []*sections.Archive
+	var walk func([]*sections.Archive)
+	walk = func(level []*sections.Archive) {
+		for _, archive := range level {
+			archives = append(archives, archive)
+			walk(t.children(archive))
+		}
+	}
+	walk(t.years)
+	return archives
+}
+
+// children returns the months of a year or the days of a month
+func (t *archiveTree) children(archive *sections.Archive) []*sections.Archive {
+	switch archive.Period {
+	case sections.ArchiveByYear:
+		return sections.GroupArchives(t.events[t.selector(archive)], sections.ArchiveByMonth)
+	case sections.ArchiveByMonth:
+		return t.calendar(archive).Archives()
+	default:
+		return nil
+	}
+}
+
+// calendar returns the calendar of a month archive
+func (t *archiveTree) calendar(archive *sections.Archive) *sections.MonthlyArchiveCalendar {
+	return sections.NewMonthlyCalendar(archive.Year, archive.Month, t.events[t.selector(archive)])
+}
+
+// parent returns the selector one level up from an archive
+func (t *archiveTree) parent(archive *sections.Archive) string {
+	ap := &sections.ArchivePath{Section: t.section, Year: archive.Year, Month: archive.Month, Day: archive.Day}
+	switch archive.Period {
+	case sections.ArchiveByYear:
+		ap.Month, ap.Day = 0, 0
+	case sections.ArchiveByMonth:
+		ap.Day = 0
+	}
+	return ap.Parent()
+}
@@ -0,0 +1,559 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/sandwichfarm/nophr/internal/aggregates"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/entities"
+	"github.com/sandwichfarm/nophr/internal/feeds"
+	"github.com/sandwichfarm/nophr/internal/markdown"
+	nostrclient "github.com/sandwichfarm/nophr/internal/nostr"
+	"github.com/sandwichfarm/nophr/internal/sections"
+	"github.com/sandwichfarm/nophr/internal/security"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+// feedFormat is a syndication format the feed exporter writes
+type feedFormat struct {
+	ext    string
+	encode func(*feeds.Feed) ([]byte, error)
+}
+
+// feedFormats maps export.feeds.formats entries to their writers
+var feedFormats = map[string]feedFormat{
+	"atom": {ext: ".atom", encode: (*feeds.Feed).Atom},
+	"rss":  {ext: ".rss", encode: (*feeds.Feed).RSS},
+	"json": {ext: ".json", encode: (*feeds.Feed).JSON},
+}
+
+// sectionFeedInterval is the least time between section feed exports
+// triggered by other authors' events, so a sync burst does not rewrite the
+// feeds once per event. Events arriving sooner are picked up by one export
+// when the interval is up.
+var sectionFeedInterval = time.Minute
+
+// FeedExporter writes Atom, RSS and JSON Feed files for the owner's outbox,
+// articles and every configured section.
+type FeedExporter struct {
+	enabled       bool
+	outputDir     string
+	formats       []string
+	maxItems      int
+	baseURL       string
+	ownerPubkey   string
+	hideSensitive bool
+
+	config   *config.Config
+	sections *sections.Manager
+	resolver *entities.Resolver
+	parser   *markdown.Parser
+	storage  *storage.Storage
+	mu       sync.Mutex
+
+	lastSectionExport time.Time
+	sectionTimer      *time.Timer // pending export of throttled section events
+	stopped           bool
+}
+
+// NewFeedExporter builds an exporter when enabled in config.
+func NewFeedExporter(cfg *config.Config, st *storage.Storage) (*FeedExporter, error) {
+	if cfg == nil || st == nil {
+		return nil, fmt.Errorf("config and storage are required")
+	}
+
+	if !cfg.Export.Feeds.Enabled {
+		return nil, nil
+	}
+
+	ownerHex, err := decodeNpub(cfg.Identity.Npub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode identity.npub: %w", err)
+	}
+
+	outputDir := cfg.Export.Feeds.OutputDir
+	if outputDir == "" {
+		outputDir = "./export/feeds"
+	}
+
+	sectionManager := sections.NewManager(st, cfg.Identity.Npub)
+	if err := sections.LoadFromConfig(sectionManager, cfg.Sections); err != nil {
+		return nil, fmt.Errorf("failed to load sections: %w", err)
+	}
+
+	f := &FeedExporter{
+		enabled:       true,
+		outputDir:     outputDir,
+		formats:       cfg.Export.Feeds.Formats,
+		maxItems:      cfg.Export.Feeds.MaxItems,
+		baseURL:       strings.TrimSuffix(cfg.Export.Feeds.URL, "/"),
+		ownerPubkey:   ownerHex,
+		hideSensitive: cfg.Display.Feed.HideSensitive,
+		config:        cfg,
+		sections:      sectionManager,
+		resolver:      entities.NewResolver(st),
+		parser:        markdown.NewParser(),
+		storage:       st,
+	}
+
+	// Sections may show other authors, so apply the same deny list, banned
+	// words and mute list as the protocol servers. Pages are only built
+	// during an export, which holds mu.
+	enforcer := security.NewEnforcerFromConfig(&cfg.Security)
+	mutes := aggregates.NewMuteFilter(st, cfg)
+	sectionManager.SetEventFilter(func(events []*nostr.Event) []*nostr.Event {
+		events = mutes.FilterEvents(enforcer.EnforceEvents(context.Background(), events))
+		if f.hideSensitive {
+			events = nostrclient.WithoutSensitive(events)
+		}
+		return events
+	})
+
+	return f, nil
+}
+
+// PrepareReload converts the sections of a new configuration and returns a
+// function that applies them, with the site, display and rendering
+// settings, to the next export. The export.feeds settings keep their startup
+// values.
+func (f *FeedExporter) PrepareReload(cfg *config.Config) (func(), error) {
+	list, err := sections.FromConfig(cfg.Sections)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		f.sections.ReplaceSections(list)
+		f.hideSensitive = cfg.Display.Feed.HideSensitive
+		f.config = cfg
+	}, nil
+}
+
+// HandleDeletion re-exports when a deletion request removed content a feed
+// may list. Deletions are never throttled, so deleted events leave the
+// feeds right away.
+func (f *FeedExporter) HandleDeletion(ctx context.Context, deletion *nostr.Event, targets []*nostr.Event) {
+	if f == nil || !f.enabled {
+		return
+	}
+
+	owner, section := false, false
+	for _, target := range targets {
+		owner = owner || f.isOwnerRootEvent(target)
+		section = section || f.sectionMayShow(target)
+	}
+
+	switch {
+	case owner:
+		if err := f.Export(ctx); err != nil {
+			fmt.Printf("[EXPORT] ⚠ feed export failed: %v\n", err)
+		}
+	case section:
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if err := f.exportSections(ctx); err != nil {
+			fmt.Printf("[EXPORT] ⚠ section feed export failed: %v\n", err)
+		}
+	}
+}
+
+// HandleEvent regenerates every feed when a new owner root note/article
+// arrives, and the section feeds when another event may appear in one.
+func (f *FeedExporter) HandleEvent(ctx context.Context, event *nostr.Event) {
+	if f == nil || !f.enabled || event == nil {
+		return
+	}
+
+	if f.isOwnerRootEvent(event) {
+		if err := f.Export(ctx); err != nil {
+			fmt.Printf("[EXPORT] ⚠ feed export failed: %v\n", err)
+		}
+		return
+	}
+
+	if !f.sectionMayShow(event) {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if wait := sectionFeedInterval - time.Since(f.lastSectionExport); wait > 0 {
+		if f.sectionTimer == nil && !f.stopped {
+			f.sectionTimer = time.AfterFunc(wait, f.exportThrottledSections)
+		}
+		return
+	}
+	if err := f.exportSections(ctx); err != nil {
+		fmt.Printf("[EXPORT] ⚠ section feed export failed: %v\n", err)
+	}
+}
+
+// exportThrottledSections runs the section feed export that HandleEvent
+// deferred
+func (f *FeedExporter) exportThrottledSections() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sectionTimer = nil
+	if f.stopped {
+		return
+	}
+	if err := f.exportSections(context.Background()); err != nil {
+		fmt.Printf("[EXPORT] ⚠ section feed export failed: %v\n", err)
+	}
+}
+
+// Stop cancels a pending section feed export
+func (f *FeedExporter) Stop() {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopped = true
+	if f.sectionTimer != nil {
+		f.sectionTimer.Stop()
+		f.sectionTimer = nil
+	}
+}
+
+// Export regenerates every feed.
+func (f *FeedExporter) Export(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.MkdirAll(f.outputDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create export dir %s: %w", f.outputDir, err)
+	}
+
+	notes, err := queryRootArchive(ctx, f.storage, f.ownerPubkey, 1, f.hideSensitive, f.isRootEvent)
+	if err != nil {
+		return err
+	}
+
+	articles, err := queryRootArchive(ctx, f.storage, f.ownerPubkey, nostrclient.KindArticle, f.hideSensitive, f.isRootEvent)
+	if err != nil {
+		return err
+	}
+
+	// The outbox is everything the owner published, newest first
+	outbox := append(append([]*nostr.Event{}, notes...), articles...)
+	sort.SliceStable(outbox, func(i, j int) bool {
+		return outbox[i].CreatedAt > outbox[j].CreatedAt
+	})
+
+	if err := f.writeFeed(ctx, "outbox", f.siteTitle(), f.config.Site.Description, newest(outbox, f.maxItems)); err != nil {
+		return err
+	}
+	if err := f.writeFeed(ctx, "articles", f.siteTitle()+" - Articles", "", newest(articles, f.maxItems)); err != nil {
+		return err
+	}
+
+	return f.exportSections(ctx)
+}
+
+// exportSections rewrites the feed of every public section in place, then
+// removes the feeds of sections that are gone, so readers never find a
+// current section's feed missing. Callers hold mu.
+func (f *FeedExporter) exportSections(ctx context.Context) error {
+	keep := make(map[string]bool)
+	for _, section := range f.sections.PublicSections() {
+		page, err := f.sections.GetPage(ctx, section.Name, 1)
+		if err != nil {
+			return fmt.Errorf("failed to query section %s: %w", section.Name, err)
+		}
+
+		title := section.Title
+		if title == "" {
+			title = capitalize(section.Name)
+		}
+
+		name := path.Join("sections", url.PathEscape(section.Name))
+		if err := f.writeFeed(ctx, name, f.siteTitle()+" - "+title, section.Description, newest(page.Events, f.maxItems)); err != nil {
+			return err
+		}
+		for _, format := range f.formats {
+			if writer, ok := feedFormats[format]; ok {
+				keep[url.PathEscape(section.Name)+writer.ext] = true
+			}
+		}
+	}
+
+	if err := pruneFeeds(filepath.Join(f.outputDir, "sections"), keep); err != nil {
+		return err
+	}
+
+	// This export covers any events a pending one was waiting for
+	if f.sectionTimer != nil {
+		f.sectionTimer.Stop()
+		f.sectionTimer = nil
+	}
+	f.lastSectionExport = time.Now()
+	return nil
+}
+
+// writeFeed writes a feed in every configured format, as <name>.<format ext>
+// under the output directory
+func (f *FeedExporter) writeFeed(ctx context.Context, name, title, subtitle string, events []*nostr.Event) error {
+	feed := &feeds.Feed{
+		Title:    title,
+		Subtitle: subtitle,
+		Link:     f.ownerLink(),
+		Author:   f.config.Site.Operator,
+	}
+	if feed.Author == "" {
+		feed.Author = f.resolver.DisplayName(ctx, f.ownerPubkey)
+	}
+	for _, event := range events {
+		feed.Entries = append(feed.Entries, f.entry(ctx, event))
+	}
+
+	for _, format := range f.formats {
+		writer, ok := feedFormats[format]
+		if !ok {
+			continue
+		}
+
+		file := name + writer.ext
+		feed.ID = f.ownerLink() + "#" + file
+		feed.Self = ""
+		if f.baseURL != "" {
+			feed.Self = f.baseURL + "/" + file
+			feed.ID = feed.Self
+		}
+
+		data, err := writer.encode(feed)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", file, err)
+		}
+		if err := replaceFile(filepath.Join(f.outputDir, filepath.FromSlash(file)), data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replaceFile writes data next to path and renames it into place, so a
+// reader sees either the previous feed or the new one, never a partial file
+func replaceFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// pruneFeeds removes the files in dir that are not in keep, such as the
+// feeds of sections that were removed or hidden
+func pruneFeeds(dir string, keep map[string]bool) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read section feeds %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || keep[name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("failed to remove stale feed %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// entry builds the feed entry of an event. Sensitive entries carry their
+// content warning as the summary and no content.
+func (f *FeedExporter) entry(ctx context.Context, event *nostr.Event) *feeds.Entry {
+	entry := &feeds.Entry{
+		ID:        "nostr:" + event.ID,
+		Title:     summarizeEvent(event),
+		Author:    f.resolver.DisplayName(ctx, event.PubKey),
+		Published: event.CreatedAt.Time(),
+		Updated:   event.CreatedAt.Time(),
+	}
+	if note, err := nip19.EncodeNote(event.ID); err == nil {
+		entry.ID = "nostr:" + note
+	}
+
+	if links := f.portalLinks(event); len(links) > 0 {
+		entry.Link = links[0]
+		entry.Related = links[1:]
+	}
+
+	if label, ok := nostrclient.ContentWarningLabel(event); ok {
+		entry.Summary = label
+		return entry
+	}
+
+	if article := nostrclient.ParseArticle(event); article != nil {
+		if article.Title != "" {
+			entry.Title = article.Title
+		}
+		entry.Summary = article.Summary
+		entry.Published = article.PublishedAt
+	}
+	entry.Content = f.renderContent(ctx, event)
+
+	return entry
+}
+
+// renderContent renders an event's content as HTML, linking NIP-19
+// entities on the first portal. Notes keep their line breaks.
+func (f *FeedExporter) renderContent(ctx context.Context, event *nostr.Event) string {
+	portal := f.portalBases()[0]
+	content := f.resolver.ReplaceEntities(ctx, event.Content, func(entity *entities.Entity) string {
+		return fmt.Sprintf("[%s](%s/%s)", entity.DisplayName, portal, strings.TrimPrefix(entity.OriginalText, "nostr:"))
+	})
+
+	rendered, err := f.parser.RenderHTML([]byte(content), event.Kind != nostrclient.KindArticle)
+	if err != nil {
+		return ""
+	}
+	return rendered
+}
+
+// portalLinks links an event on every configured portal, which may be
+// https, gemini or gopher base URLs
+func (f *FeedExporter) portalLinks(event *nostr.Event) []string {
+	code, ok := f.nostrPointer(event)
+	if !ok {
+		return nil
+	}
+
+	portals := f.portalBases()
+	links := make([]string, 0, len(portals))
+	for _, base := range portals {
+		links = append(links, fmt.Sprintf("%s/%s", strings.TrimSuffix(base, "/"), code))
+	}
+	return links
+}
+
+// ownerLink links the owner's profile on the first portal
+func (f *FeedExporter) ownerLink() string {
+	npub, err := nip19.EncodePublicKey(f.ownerPubkey)
+	if err != nil {
+		npub = f.ownerPubkey
+	}
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(f.portalBases()[0], "/"), npub)
+}
+
+// nostrPointer encodes an event as an naddr (articles) or nevent
+func (f *FeedExporter) nostrPointer(event *nostr.Event) (string, bool) {
+	relays, _ := f.storage.GetReadRelays(context.Background(), event.PubKey)
+
+	if article := nostrclient.ParseArticle(event); article != nil && article.Identifier != "" {
+		if code, err := nip19.EncodeEntity(event.PubKey, event.Kind, article.Identifier, relays); err == nil {
+			return code, true
+		}
+	}
+
+	if code, err := nip19.EncodeEvent(event.ID, relays, event.PubKey); err == nil {
+		return code, true
+	}
+
+	return "", false
+}
+
+func (f *FeedExporter) portalBases() []string {
+	if len(f.config.Rendering.Portals) > 0 {
+		return f.config.Rendering.Portals
+	}
+	return []string{"https://njump.me", "https://nostr.at", "https://nostr.eu"}
+}
+
+// sectionMayShow reports whether an event's kind is listed by a public
+// section
+func (f *FeedExporter) sectionMayShow(event *nostr.Event) bool {
+	if event == nil {
+		return false
+	}
+
+	for _, section := range f.sections.PublicSections() {
+		if len(section.Filters.Kinds) == 0 {
+			return true
+		}
+		for _, kind := range section.Filters.Kinds {
+			if kind == event.Kind {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f *FeedExporter) siteTitle() string {
+	if f.config.Site.Title != "" {
+		return f.config.Site.Title
+	}
+	return "nophr"
+}
+
+func (f *FeedExporter) isOwnerRootEvent(event *nostr.Event) bool {
+	if event == nil {
+		return false
+	}
+
+	if event.PubKey != f.ownerPubkey {
+		return false
+	}
+
+	if event.Kind != 1 && event.Kind != nostrclient.KindArticle {
+		return false
+	}
+
+	return f.isRootEvent(event)
+}
+
+func (f *FeedExporter) isRootEvent(event *nostr.Event) bool {
+	if event.Kind == 1 {
+		for _, tag := range event.Tags {
+			if len(tag) >= 1 && tag[0] == "e" {
+				return false
+			}
+		}
+	}
+	return event.Kind == 1 || event.Kind == nostrclient.KindArticle
+}
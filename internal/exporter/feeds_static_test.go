@@ -0,0 +1,294 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+func TestFeedExporterWritesFeeds(t *testing.T) {
+	priv := nostr.GeneratePrivateKey()
+	pub, err := nostr.GetPublicKey(priv)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	npub, _ := nip19.EncodePublicKey(pub)
+
+	tmp := t.TempDir()
+	cfg := config.Default()
+	cfg.Identity.Npub = npub
+	cfg.Site.Title = "Test Site"
+	cfg.Export.Feeds.Enabled = true
+	cfg.Export.Feeds.OutputDir = tmp
+	cfg.Export.Feeds.URL = "https://example.com/feeds/"
+	cfg.Rendering.Portals = []string{"https://njump.me", "gemini://example.com"}
+	cfg.Sections = []config.SectionConfig{
+		{Name: "journal", Title: "Journal", Filters: config.SectionFilterConfig{Kinds: []int{1}}},
+		{Name: "private", Hidden: true},
+	}
+	cfg.Storage = config.Storage{
+		Driver:     "sqlite",
+		SQLitePath: ":memory:",
+	}
+
+	ctx := context.Background()
+	st, err := storage.New(ctx, &cfg.Storage)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	exporter, err := NewFeedExporter(cfg, st)
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+
+	store := func(kind int, offset time.Duration, tags nostr.Tags, content string) *nostr.Event {
+		event := &nostr.Event{
+			Kind:      kind,
+			CreatedAt: nostr.Timestamp(time.Now().Add(offset).Unix()),
+			PubKey:    pub,
+			Tags:      tags,
+			Content:   content,
+		}
+		if err := event.Sign(priv); err != nil {
+			t.Fatalf("failed to sign event: %v", err)
+		}
+		if err := st.StoreEvent(ctx, event); err != nil {
+			t.Fatalf("failed to store event: %v", err)
+		}
+		return event
+	}
+
+	note := store(1, 0, nostr.Tags{}, "Hello **feeds**")
+	sensitive := store(1, time.Second, nostr.Tags{{"content-warning", "spoilers"}}, "the butler did it")
+	article := store(30023, 2*time.Second, nostr.Tags{{"d", "first"}, {"title", "First Article"}, {"summary", "An introduction"}}, "## Chapter one")
+
+	// A feed left by a section that has since been removed
+	stale := filepath.Join(tmp, "sections", "removed.atom")
+	if err := os.MkdirAll(filepath.Dir(stale), 0o755); err != nil {
+		t.Fatalf("failed to create sections dir: %v", err)
+	}
+	if err := os.WriteFile(stale, []byte("<feed/>"), 0o644); err != nil {
+		t.Fatalf("failed to write stale feed: %v", err)
+	}
+
+	exporter.HandleEvent(ctx, article)
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("expected the stale section feed to be removed, got %v", err)
+	}
+	for _, name := range []string{"outbox", "articles", "sections/journal"} {
+		for _, ext := range []string{".atom", ".rss", ".json"} {
+			if _, err := os.Stat(filepath.Join(tmp, filepath.FromSlash(name+ext))); err != nil {
+				t.Errorf("expected %s%s to be written: %v", name, ext, err)
+			}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "sections", "private.atom")); err == nil {
+		t.Error("hidden sections should not get a feed")
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmp, "outbox.atom"))
+	if err != nil {
+		t.Fatalf("failed to read outbox feed: %v", err)
+	}
+	var atom struct {
+		Title string `xml:"title"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Entries []struct {
+			Title   string `xml:"title"`
+			Summary string `xml:"summary"`
+			Content string `xml:"content"`
+			Links   []struct {
+				Href string `xml:"href,attr"`
+				Rel  string `xml:"rel,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(data, &atom); err != nil {
+		t.Fatalf("outbox feed is not valid Atom: %v", err)
+	}
+	if atom.Title != "Test Site" || len(atom.Entries) != 3 {
+		t.Fatalf("unexpected outbox feed %q with %d entries", atom.Title, len(atom.Entries))
+	}
+	if !strings.Contains(string(data), `href="https://example.com/feeds/outbox.atom" rel="self"`) {
+		t.Error("feed should link itself under export.feeds.url")
+	}
+
+	first := atom.Entries[0]
+	if first.Title != "First Article" || first.Summary != "An introduction" || !strings.Contains(first.Content, "<h2") {
+		t.Errorf("unexpected article entry: %+v", first)
+	}
+	if len(first.Links) != 2 || !strings.HasPrefix(first.Links[0].Href, "https://njump.me/naddr1") ||
+		first.Links[1].Rel != "related" || !strings.HasPrefix(first.Links[1].Href, "gemini://example.com/naddr1") {
+		t.Errorf("entry links should come from the portals: %+v", first.Links)
+	}
+	if warned := atom.Entries[1]; warned.Summary != "CW: spoilers" || warned.Content != "" {
+		t.Errorf("sensitive entry should only carry its warning: %+v", warned)
+	}
+	if !strings.Contains(atom.Entries[2].Content, "<strong>feeds</strong>") {
+		t.Errorf("note content should be rendered as HTML: %q", atom.Entries[2].Content)
+	}
+
+	data, err = os.ReadFile(filepath.Join(tmp, "sections", "journal.json"))
+	if err != nil {
+		t.Fatalf("failed to read section feed: %v", err)
+	}
+	var jsonFeed struct {
+		Title string `json:"title"`
+		Items []struct {
+			ID  string `json:"id"`
+			URL string `json:"url"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &jsonFeed); err != nil {
+		t.Fatalf("section feed is not valid JSON: %v", err)
+	}
+	if jsonFeed.Title != "Test Site - Journal" || len(jsonFeed.Items) != 2 {
+		t.Fatalf("unexpected section feed %q with %d items", jsonFeed.Title, len(jsonFeed.Items))
+	}
+	for _, item := range jsonFeed.Items {
+		if item.ID != "nostr:"+mustEncodeNote(t, sensitive.ID) && item.ID != "nostr:"+mustEncodeNote(t, note.ID) {
+			t.Errorf("unexpected section item %q", item.ID)
+		}
+		if !strings.HasPrefix(item.URL, "https://njump.me/nevent1") {
+			t.Errorf("section item should link a portal, got %q", item.URL)
+		}
+	}
+
+	// A reload renames the site and replaces the sections on the next export
+	next := *cfg
+	next.Site.Title = "Renamed Site"
+	next.Sections = []config.SectionConfig{
+		{Name: "diary", Title: "Diary", Filters: config.SectionFilterConfig{Kinds: []int{1}}},
+	}
+	apply, err := exporter.PrepareReload(&next)
+	if err != nil {
+		t.Fatalf("PrepareReload() error = %v", err)
+	}
+	apply()
+	if err := exporter.Export(ctx); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	data, err = os.ReadFile(filepath.Join(tmp, "sections", "diary.json"))
+	if err != nil {
+		t.Fatalf("failed to read reloaded section feed: %v", err)
+	}
+	if err := json.Unmarshal(data, &jsonFeed); err != nil || jsonFeed.Title != "Renamed Site - Diary" {
+		t.Errorf("unexpected reloaded section feed %q (%v)", jsonFeed.Title, err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "sections", "journal.json")); !os.IsNotExist(err) {
+		t.Errorf("expected the feed of the removed section to be gone, got %v", err)
+	}
+}
+
+func TestFeedExporterDisabled(t *testing.T) {
+	cfg := config.Default()
+	cfg.Storage = config.Storage{Driver: "sqlite", SQLitePath: ":memory:"}
+
+	st, err := storage.New(context.Background(), &cfg.Storage)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	exporter, err := NewFeedExporter(cfg, st)
+	if err != nil || exporter != nil {
+		t.Fatalf("expected no exporter when disabled, got %v, %v", exporter, err)
+	}
+
+	// A nil exporter ignores events
+	exporter.HandleEvent(context.Background(), &nostr.Event{Kind: 1})
+}
+
+func mustEncodeNote(t *testing.T, id string) string {
+	t.Helper()
+	note, err := nip19.EncodeNote(id)
+	if err != nil {
+		t.Fatalf("failed to encode note: %v", err)
+	}
+	return note
+}
+
+func TestFeedExporterThrottlesSectionFeeds(t *testing.T) {
+	priv := nostr.GeneratePrivateKey()
+	pub, _ := nostr.GetPublicKey(priv)
+	npub, _ := nip19.EncodePublicKey(pub)
+
+	defer func(interval time.Duration) { sectionFeedInterval = interval }(sectionFeedInterval)
+	sectionFeedInterval = 50 * time.Millisecond
+
+	cfg := config.Default()
+	cfg.Identity.Npub = npub
+	cfg.Export.Feeds.Enabled = true
+	cfg.Export.Feeds.OutputDir = t.TempDir()
+	cfg.Sections = []config.SectionConfig{
+		{Name: "journal", Filters: config.SectionFilterConfig{Kinds: []int{1}}},
+	}
+	cfg.Storage = config.Storage{Driver: "sqlite", SQLitePath: ":memory:"}
+
+	ctx := context.Background()
+	st, err := storage.New(ctx, &cfg.Storage)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	exporter, err := NewFeedExporter(cfg, st)
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	defer exporter.Stop()
+
+	lastExport := func() time.Time {
+		exporter.mu.Lock()
+		defer exporter.mu.Unlock()
+		return exporter.lastSectionExport
+	}
+
+	note := &nostr.Event{Kind: 1, CreatedAt: nostr.Now(), Tags: nostr.Tags{}, Content: "root"}
+	note.Sign(priv)
+	reply := &nostr.Event{Kind: 1, CreatedAt: nostr.Now(), Tags: nostr.Tags{{"e", note.ID}}, Content: "reply"}
+	reply.Sign(priv)
+
+	exporter.HandleEvent(ctx, note)
+	first := lastExport()
+	if first.IsZero() {
+		t.Fatal("expected an owner note to export the section feeds")
+	}
+
+	// A reply within the interval is exported once the interval is up
+	exporter.HandleEvent(ctx, reply)
+	if !lastExport().Equal(first) {
+		t.Fatal("expected the reply to be throttled")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for lastExport().Equal(first) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	second := lastExport()
+	if second.Equal(first) {
+		t.Fatal("expected a trailing export after the interval")
+	}
+
+	// Deletions are never throttled
+	exporter.HandleDeletion(ctx, &nostr.Event{Kind: 5}, []*nostr.Event{reply})
+	if lastExport().Equal(second) {
+		t.Error("expected a deletion to export the section feeds right away")
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -13,17 +12,20 @@ import (
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/sandwichfarm/nophr/internal/config"
 	"github.com/sandwichfarm/nophr/internal/gemini"
+	"github.com/sandwichfarm/nophr/internal/sections"
 	"github.com/sandwichfarm/nophr/internal/storage"
 )
 
 // GeminiExporter writes static gemtext for owner content.
 type GeminiExporter struct {
-	enabled     bool
-	outputDir   string
-	host        string
-	port        int
-	maxItems    int
-	ownerPubkey string
+	enabled       bool
+	outputDir     string
+	host          string
+	port          int
+	maxItems      int
+	ownerPubkey   string
+	hideSensitive bool
+	siteTitle     string
 
 	renderer *gemini.Renderer
 	storage  *storage.Storage
@@ -51,17 +53,47 @@ func NewGeminiExporter(cfg *config.Config, st *storage.Storage) (*GeminiExporter
 	}
 
 	return &GeminiExporter{
-		enabled:     true,
-		outputDir:   outputDir,
-		host:        cfg.Export.Gemini.Host,
-		port:        cfg.Export.Gemini.Port,
-		maxItems:    cfg.Export.Gemini.MaxItems,
-		ownerPubkey: ownerHex,
-		renderer:    gemini.NewRenderer(cfg, st),
-		storage:     st,
+		enabled:       true,
+		outputDir:     outputDir,
+		host:          cfg.Export.Gemini.Host,
+		port:          cfg.Export.Gemini.Port,
+		maxItems:      cfg.Export.Gemini.MaxItems,
+		ownerPubkey:   ownerHex,
+		hideSensitive: cfg.Display.Feed.HideSensitive,
+		siteTitle:     cfg.Site.Title,
+		renderer:      gemini.NewRenderer(cfg, st),
+		storage:       st,
 	}, nil
 }
 
+// PrepareReload returns a function that applies the site, display and
+// rendering settings of a new configuration to the next export. The
+// export.gemini settings keep their startup values.
+func (g *GeminiExporter) PrepareReload(cfg *config.Config) (func(), error) {
+	return func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		g.hideSensitive = cfg.Display.Feed.HideSensitive
+		g.siteTitle = cfg.Site.Title
+		g.renderer.SetConfig(cfg)
+	}, nil
+}
+
+// HandleDeletion re-exports when a deletion request removed owner content.
+func (g *GeminiExporter) HandleDeletion(ctx context.Context, deletion *nostr.Event, targets []*nostr.Event) {
+	if g == nil || !g.enabled {
+		return
+	}
+
+	for _, target := range targets {
+		if g.isOwnerRootEvent(target) {
+			g.HandleEvent(ctx, target)
+			return
+		}
+	}
+}
+
 // HandleEvent triggers an export when a new owner root note/article arrives.
 func (g *GeminiExporter) HandleEvent(ctx context.Context, event *nostr.Event) {
 	if g == nil || !g.enabled {
@@ -102,11 +134,25 @@ func (g *GeminiExporter) Export(ctx context.Context) error {
 		return err
 	}
 
-	if err := g.writeSection("notes", notes); err != nil {
+	if err := g.writeSection("notes", newest(notes, g.maxItems)); err != nil {
+		return err
+	}
+
+	if err := g.writeSection("articles", newest(articles, g.maxItems)); err != nil {
+		return err
+	}
+
+	if err := g.writeAtom(ctx, "notes", newest(notes, g.maxItems)); err != nil {
+		return err
+	}
+	if err := g.writeAtom(ctx, "articles", newest(articles, g.maxItems)); err != nil {
 		return err
 	}
 
-	if err := g.writeSection("articles", articles); err != nil {
+	if err := g.writeArchive("notes", notes); err != nil {
+		return err
+	}
+	if err := g.writeArchive("articles", articles); err != nil {
 		return err
 	}
 
@@ -147,12 +193,24 @@ func (g *GeminiExporter) writeRootIndex(notes, articles []*nostr.Event, generate
 		sb.WriteString(fmt.Sprintf("=> %s Articles\n", g.relativeLink("/articles/index.gmi")))
 	}
 
+	if len(notes) > 0 || len(articles) > 0 {
+		sb.WriteString("\n## Feeds\n\n")
+	}
+	if len(notes) > 0 {
+		sb.WriteString(fmt.Sprintf("=> %s Notes (Atom)\n", g.relativeLink(gemini.AtomPath("/notes"))))
+	}
+	if len(articles) > 0 {
+		sb.WriteString(fmt.Sprintf("=> %s Articles (Atom)\n", g.relativeLink(gemini.AtomPath("/articles"))))
+	}
+
 	sb.WriteString("\n")
 	sb.WriteString(fmt.Sprintf("Generated: %s\n", generatedAt.Format(time.RFC3339)))
 
 	return writeFile(filepath.Join(g.outputDir, "index.gmi"), []byte(sb.String()))
 }
 
+// writeSection writes a section index as a gemsub feed: a level-1 heading,
+// then one dated link per event
 func (g *GeminiExporter) writeSection(section string, events []*nostr.Event) error {
 	var sb strings.Builder
 
@@ -165,56 +223,108 @@ func (g *GeminiExporter) writeSection(section string, events []*nostr.Event) err
 	}
 
 	for _, event := range events {
-		display := summarizeContent(event.Content)
-		sb.WriteString(fmt.Sprintf("=> %s %s\n", g.relativeLink(fmt.Sprintf("/%s/%s.gmi", section, event.ID)), display))
+		display := summarizeEvent(event)
+		sb.WriteString(gemini.GemsubLink(g.relativeLink(fmt.Sprintf("/%s/%s.gmi", section, event.ID)), event.CreatedAt.Time(), display))
 	}
 
+	sb.WriteString(fmt.Sprintf("\n=> %s Archive\n", g.relativeLink(sections.ArchiveSelector(section)+"/index.gmi")))
+	sb.WriteString(fmt.Sprintf("=> %s Atom feed\n", g.relativeLink(gemini.AtomPath("/"+section))))
+
 	return writeFile(filepath.Join(g.outputDir, section, "index.gmi"), []byte(sb.String()))
 }
 
-func (g *GeminiExporter) writeEvents(section string, events []*nostr.Event) error {
-	for _, event := range events {
-		homeURL := "/index.gmi"
-		threadURL := fmt.Sprintf("/%s/index.gmi", section)
-		content := g.renderer.RenderNote(event, nil, threadURL, homeURL)
-		targetPath := filepath.Join(g.outputDir, section, fmt.Sprintf("%s.gmi", event.ID))
-		if err := writeFile(targetPath, []byte(content)); err != nil {
-			return err
-		}
+// writeAtom writes the Atom variant of a section index as <section>/atom.xml
+func (g *GeminiExporter) writeAtom(ctx context.Context, section string, events []*nostr.Event) error {
+	title := capitalize(section)
+	if g.siteTitle != "" {
+		title = g.siteTitle + " - " + title
 	}
 
-	return nil
+	data, err := g.renderer.AtomFeed(ctx, title,
+		g.relativeLink(fmt.Sprintf("/%s/index.gmi", section)),
+		g.relativeLink(gemini.AtomPath("/"+section)),
+		events,
+		func(event *nostr.Event) string {
+			return g.relativeLink(fmt.Sprintf("/%s/%s.gmi", section, event.ID))
+		})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s feed: %w", section, err)
+	}
+
+	return writeFile(filepath.Join(g.outputDir, section, gemini.AtomFeedName), data)
 }
 
-func (g *GeminiExporter) queryOwnerRoots(ctx context.Context, kind int) ([]*nostr.Event, error) {
-	filter := nostr.Filter{
-		Kinds:   []int{kind},
-		Authors: []string{g.ownerPubkey},
-		Limit:   g.maxItems * 2,
+// writeArchive writes the year, month and day archive pages of a section,
+// linking every event rather than only the newest max_items
+func (g *GeminiExporter) writeArchive(section string, events []*nostr.Event) error {
+	archiveDir := filepath.Join(g.outputDir, section, "archive")
+	if err := os.RemoveAll(archiveDir); err != nil {
+		return fmt.Errorf("failed to clear archive export %s: %w", archiveDir, err)
 	}
 
-	events, err := g.storage.QueryEvents(ctx, filter)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query events for export: %w", err)
+	tree := newArchiveTree(section, events)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# %s archive\n\n", capitalize(section)))
+	g.writeArchiveLinks(&sb, tree, tree.years)
+	sb.WriteString(fmt.Sprintf("\n=> %s Up\n", g.relativeLink(fmt.Sprintf("/%s/index.gmi", section))))
+	if err := writeFile(filepath.Join(archiveDir, "index.gmi"), []byte(sb.String())); err != nil {
+		return err
 	}
 
-	var roots []*nostr.Event
-	for _, event := range events {
-		if !g.isRootEvent(event) {
-			continue
+	for _, archive := range tree.all() {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("# %s - %s\n\n", capitalize(section), archive.FormatTitle()))
+
+		switch archive.Period {
+		case sections.ArchiveByDay:
+			for _, event := range tree.events[tree.selector(archive)] {
+				display := summarizeEvent(event)
+				sb.WriteString(fmt.Sprintf("=> %s %s\n", g.relativeLink(fmt.Sprintf("/%s/%s.gmi", section, event.ID)), display))
+			}
+		case sections.ArchiveByMonth:
+			sb.WriteString(fmt.Sprintf("```Calendar of %s, days with posts are marked *\n", archive.FormatTitle()))
+			sb.WriteString(strings.Join(tree.calendar(archive).Grid(), "\n"))
+			sb.WriteString("\n```\n\n")
+			g.writeArchiveLinks(&sb, tree, tree.children(archive))
+		default:
+			g.writeArchiveLinks(&sb, tree, tree.children(archive))
+		}
+
+		sb.WriteString(fmt.Sprintf("\n=> %s Up\n", g.relativeLink(tree.parent(archive)+"/index.gmi")))
+
+		selector := tree.selector(archive)
+		if err := writeFile(filepath.Join(g.outputDir, filepath.FromSlash(selector), "index.gmi"), []byte(sb.String())); err != nil {
+			return err
 		}
-		roots = append(roots, event)
 	}
 
-	sort.SliceStable(roots, func(i, j int) bool {
-		return roots[i].CreatedAt > roots[j].CreatedAt
-	})
+	return nil
+}
 
-	if len(roots) > g.maxItems {
-		roots = roots[:g.maxItems]
+func (g *GeminiExporter) writeArchiveLinks(sb *strings.Builder, tree *archiveTree, archives []*sections.Archive) {
+	for _, archive := range archives {
+		sb.WriteString(fmt.Sprintf("=> %s %s (%d)\n", g.relativeLink(tree.selector(archive)+"/index.gmi"), archive.FormatTitle(), archive.EventCount))
 	}
+}
 
-	return roots, nil
+func (g *GeminiExporter) writeEvents(section string, events []*nostr.Event) error {
+	for _, event := range events {
+		homeURL := "/index.gmi"
+		threadURL := fmt.Sprintf("/%s/index.gmi", section)
+		content := g.renderer.RenderNote(event, nil, threadURL, homeURL)
+		targetPath := filepath.Join(g.outputDir, section, fmt.Sprintf("%s.gmi", event.ID))
+		if err := writeFile(targetPath, []byte(content)); err != nil {
+			return err
+		}
+	}
+
+	return pruneEventFiles(filepath.Join(g.outputDir, section), ".gmi", events)
+}
+
+// queryOwnerRoots returns every owner root event of a kind, newest first
+func (g *GeminiExporter) queryOwnerRoots(ctx context.Context, kind int) ([]*nostr.Event, error) {
+	return queryRootArchive(ctx, g.storage, g.ownerPubkey, kind, g.hideSensitive, g.isRootEvent)
 }
 
 func (g *GeminiExporter) isOwnerRootEvent(event *nostr.Event) bool {
@@ -98,6 +98,24 @@ func TestGeminiExporterGeneratesOnOwnerRoot(t *testing.T) {
 	if !strings.Contains(string(content), "Hello static gemini") {
 		t.Fatalf("note file should contain content, got: %s", string(content))
 	}
+
+	// The notes index is a gemsub feed with an Atom variant
+	index, err := os.ReadFile(notesIndex)
+	if err != nil {
+		t.Fatalf("failed to read notes index: %v", err)
+	}
+	dated := "=> gemini://example.com/notes/" + note.ID + ".gmi " + note.CreatedAt.Time().UTC().Format("2006-01-02") + " - Hello static gemini"
+	if !strings.Contains(string(index), dated) {
+		t.Errorf("notes index should link notes with their date, got: %s", index)
+	}
+
+	feed, err := os.ReadFile(filepath.Join(tmp, "notes", "atom.xml"))
+	if err != nil {
+		t.Fatalf("expected notes Atom feed to be written: %v", err)
+	}
+	if !strings.Contains(string(feed), `<link href="gemini://example.com/notes/`+note.ID+`.gmi" rel="alternate">`) {
+		t.Errorf("Atom entries should link the exported notes, got: %s", feed)
+	}
 }
 
 func TestGeminiExporterIgnoresReply(t *testing.T) {
@@ -152,3 +170,83 @@ func TestGeminiExporterIgnoresReply(t *testing.T) {
 		t.Fatalf("expected no export for reply, but index.gmi exists")
 	}
 }
+
+func TestGeminiExporterArchivesPastMaxItems(t *testing.T) {
+	priv := nostr.GeneratePrivateKey()
+	pub, err := nostr.GetPublicKey(priv)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	npub, _ := nip19.EncodePublicKey(pub)
+
+	tmp := t.TempDir()
+	cfg := config.Default()
+	cfg.Identity.Npub = npub
+	cfg.Export.Gemini.Enabled = true
+	cfg.Export.Gemini.OutputDir = tmp
+	cfg.Export.Gemini.Host = "example.com"
+	cfg.Export.Gemini.Port = 1965
+	cfg.Export.Gemini.MaxItems = 1
+	cfg.Storage = config.Storage{
+		Driver:     "sqlite",
+		SQLitePath: ":memory:",
+	}
+
+	ctx := context.Background()
+	st, err := storage.New(ctx, &cfg.Storage)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	exporter, err := NewGeminiExporter(cfg, st)
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+
+	var old nostr.Event
+	for i, createdAt := range []time.Time{
+		time.Date(2023, time.May, 4, 10, 0, 0, 0, time.UTC),
+		time.Date(2025, time.October, 24, 10, 0, 0, 0, time.UTC),
+	} {
+		note := nostr.Event{
+			Kind:      1,
+			CreatedAt: nostr.Timestamp(createdAt.Unix()),
+			PubKey:    pub,
+			Content:   "Note from " + createdAt.Format("2006"),
+		}
+		if err := note.Sign(priv); err != nil {
+			t.Fatalf("failed to sign note: %v", err)
+		}
+		if err := st.StoreEvent(ctx, &note); err != nil {
+			t.Fatalf("failed to store note: %v", err)
+		}
+		if i == 0 {
+			old = note
+		}
+	}
+
+	if err := exporter.Export(ctx); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmp, "notes", old.ID+".gmi")); err != nil {
+		t.Fatalf("expected archived note file to be written: %v", err)
+	}
+
+	day, err := os.ReadFile(filepath.Join(tmp, "notes", "archive", "2023", "05", "04", "index.gmi"))
+	if err != nil {
+		t.Fatalf("expected day archive to be written: %v", err)
+	}
+	if !strings.Contains(string(day), "gemini://example.com/notes/"+old.ID+".gmi") {
+		t.Errorf("expected day archive to link the old note, got:\n%s", day)
+	}
+
+	month, err := os.ReadFile(filepath.Join(tmp, "notes", "archive", "2023", "05", "index.gmi"))
+	if err != nil {
+		t.Fatalf("expected month archive to be written: %v", err)
+	}
+	if !strings.Contains(string(month), "```") || !strings.Contains(string(month), "=> gemini://example.com/notes/archive/2023/05/04/index.gmi May 4, 2023 (1)") {
+		t.Errorf("unexpected month archive:\n%s", month)
+	}
+}
@@ -2,10 +2,10 @@ package exporter
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -14,17 +14,20 @@ import (
 	"github.com/nbd-wtf/go-nostr/nip19"
 	"github.com/sandwichfarm/nophr/internal/config"
 	"github.com/sandwichfarm/nophr/internal/gopher"
+	nostrclient "github.com/sandwichfarm/nophr/internal/nostr"
+	"github.com/sandwichfarm/nophr/internal/sections"
 	"github.com/sandwichfarm/nophr/internal/storage"
 )
 
 // GopherExporter writes static gopher holes for owner content.
 type GopherExporter struct {
-	enabled     bool
-	outputDir   string
-	host        string
-	port        int
-	maxItems    int
-	ownerPubkey string
+	enabled       bool
+	outputDir     string
+	host          string
+	port          int
+	maxItems      int
+	ownerPubkey   string
+	hideSensitive bool
 
 	renderer *gopher.Renderer
 	storage  *storage.Storage
@@ -52,17 +55,45 @@ func NewGopherExporter(cfg *config.Config, st *storage.Storage) (*GopherExporter
 	}
 
 	return &GopherExporter{
-		enabled:     true,
-		outputDir:   outputDir,
-		host:        cfg.Export.Gopher.Host,
-		port:        cfg.Export.Gopher.Port,
-		maxItems:    cfg.Export.Gopher.MaxItems,
-		ownerPubkey: ownerHex,
-		renderer:    gopher.NewRenderer(cfg, st),
-		storage:     st,
+		enabled:       true,
+		outputDir:     outputDir,
+		host:          cfg.Export.Gopher.Host,
+		port:          cfg.Export.Gopher.Port,
+		maxItems:      cfg.Export.Gopher.MaxItems,
+		ownerPubkey:   ownerHex,
+		hideSensitive: cfg.Display.Feed.HideSensitive,
+		renderer:      gopher.NewRenderer(cfg, st),
+		storage:       st,
 	}, nil
 }
 
+// PrepareReload returns a function that applies the display and rendering
+// settings of a new configuration to the next export. The export.gopher
+// settings keep their startup values.
+func (g *GopherExporter) PrepareReload(cfg *config.Config) (func(), error) {
+	return func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		g.hideSensitive = cfg.Display.Feed.HideSensitive
+		g.renderer.SetConfig(cfg)
+	}, nil
+}
+
+// HandleDeletion re-exports when a deletion request removed owner content.
+func (g *GopherExporter) HandleDeletion(ctx context.Context, deletion *nostr.Event, targets []*nostr.Event) {
+	if g == nil || !g.enabled {
+		return
+	}
+
+	for _, target := range targets {
+		if g.isOwnerRootEvent(target) {
+			g.HandleEvent(ctx, target)
+			return
+		}
+	}
+}
+
 // HandleEvent triggers an export when a new owner root note/article arrives.
 func (g *GopherExporter) HandleEvent(ctx context.Context, event *nostr.Event) {
 	if g == nil || !g.enabled {
@@ -103,11 +134,18 @@ func (g *GopherExporter) Export(ctx context.Context) error {
 		return err
 	}
 
-	if err := g.writeSection("notes", notes); err != nil {
+	if err := g.writeSection("notes", newest(notes, g.maxItems)); err != nil {
 		return err
 	}
 
-	if err := g.writeSection("articles", articles); err != nil {
+	if err := g.writeSection("articles", newest(articles, g.maxItems)); err != nil {
+		return err
+	}
+
+	if err := g.writeArchive("notes", notes); err != nil {
+		return err
+	}
+	if err := g.writeArchive("articles", articles); err != nil {
 		return err
 	}
 
@@ -162,19 +200,61 @@ func (g *GopherExporter) writeSection(section string, events []*nostr.Event) err
 	gmap.AddWelcome(title, "")
 
 	for _, event := range events {
-		display := summarizeContent(event.Content)
+		display := summarizeEvent(event)
 		selector := fmt.Sprintf("/%s/%s.txt", section, event.ID)
 		gmap.AddTextFile(display, selector)
 	}
 
+	if len(events) > 0 {
+		gmap.AddSpacer()
+		gmap.AddDirectory("Archive", sections.ArchiveSelector(section))
+	}
+
 	return writeFile(sectionPath, gmap.Bytes())
 }
 
-func (g *GopherExporter) writeEvents(section string, events []*nostr.Event) error {
-	for _, event := range events {
-		content := g.renderer.RenderNote(event, nil)
-		targetPath := filepath.Join(g.outputDir, section, fmt.Sprintf("%s.txt", event.ID))
-		if err := writeFile(targetPath, []byte(content)); err != nil {
+// writeArchive writes the year, month and day archive menus of a section,
+// linking every event rather than only the newest max_items
+func (g *GopherExporter) writeArchive(section string, events []*nostr.Event) error {
+	archiveDir := filepath.Join(g.outputDir, section, "archive")
+	if err := os.RemoveAll(archiveDir); err != nil {
+		return fmt.Errorf("failed to clear archive export %s: %w", archiveDir, err)
+	}
+
+	tree := newArchiveTree(section, events)
+
+	gmap := gopher.NewGophermap(g.host, g.port)
+	gmap.AddWelcome(capitalize(section)+" archive", "")
+	g.addArchiveLinks(gmap, tree, tree.years)
+	gmap.AddSpacer()
+	gmap.AddDirectory("Up", "/"+section)
+	if err := writeFile(filepath.Join(archiveDir, "gophermap"), gmap.Bytes()); err != nil {
+		return err
+	}
+
+	for _, archive := range tree.all() {
+		gmap := gopher.NewGophermap(g.host, g.port)
+		gmap.AddWelcome(fmt.Sprintf("%s - %s", capitalize(section), archive.FormatTitle()), "")
+
+		switch archive.Period {
+		case sections.ArchiveByDay:
+			for _, event := range tree.events[tree.selector(archive)] {
+				display := summarizeEvent(event)
+				gmap.AddTextFile(display, fmt.Sprintf("/%s/%s.txt", section, event.ID))
+			}
+		case sections.ArchiveByMonth:
+			gmap.AddInfoBlock(tree.calendar(archive).Grid())
+			gmap.AddSpacer()
+			g.addArchiveLinks(gmap, tree, tree.children(archive))
+		default:
+			g.addArchiveLinks(gmap, tree, tree.children(archive))
+		}
+
+		gmap.AddSpacer()
+		gmap.AddDirectory("Up", tree.parent(archive))
+
+		selector := tree.selector(archive)
+		if err := writeFile(filepath.Join(g.outputDir, filepath.FromSlash(selector), "gophermap"), gmap.Bytes()); err != nil {
 			return err
 		}
 	}
@@ -182,35 +262,27 @@ func (g *GopherExporter) writeEvents(section string, events []*nostr.Event) erro
 	return nil
 }
 
-func (g *GopherExporter) queryOwnerRoots(ctx context.Context, kind int) ([]*nostr.Event, error) {
-	filter := nostr.Filter{
-		Kinds:   []int{kind},
-		Authors: []string{g.ownerPubkey},
-		Limit:   g.maxItems * 2, // grab extras; we'll trim after filtering replies
-	}
-
-	events, err := g.storage.QueryEvents(ctx, filter)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query events for export: %w", err)
+func (g *GopherExporter) addArchiveLinks(gmap *gopher.Gophermap, tree *archiveTree, archives []*sections.Archive) {
+	for _, archive := range archives {
+		gmap.AddDirectory(fmt.Sprintf("%s (%d)", archive.FormatTitle(), archive.EventCount), tree.selector(archive))
 	}
+}
 
-	var roots []*nostr.Event
+func (g *GopherExporter) writeEvents(section string, events []*nostr.Event) error {
 	for _, event := range events {
-		if !g.isRootEvent(event) {
-			continue
+		content := g.renderer.RenderNote(event, nil)
+		targetPath := filepath.Join(g.outputDir, section, fmt.Sprintf("%s.txt", event.ID))
+		if err := writeFile(targetPath, []byte(content)); err != nil {
+			return err
 		}
-		roots = append(roots, event)
 	}
 
-	sort.SliceStable(roots, func(i, j int) bool {
-		return roots[i].CreatedAt > roots[j].CreatedAt
-	})
-
-	if len(roots) > g.maxItems {
-		roots = roots[:g.maxItems]
-	}
+	return pruneEventFiles(filepath.Join(g.outputDir, section), ".txt", events)
+}
 
-	return roots, nil
+// queryOwnerRoots returns every owner root event of a kind, newest first
+func (g *GopherExporter) queryOwnerRoots(ctx context.Context, kind int) ([]*nostr.Event, error) {
+	return queryRootArchive(ctx, g.storage, g.ownerPubkey, kind, g.hideSensitive, g.isRootEvent)
 }
 
 func (g *GopherExporter) isOwnerRootEvent(event *nostr.Event) bool {
@@ -240,7 +312,14 @@ func (g *GopherExporter) isRootEvent(event *nostr.Event) bool {
 	return event.Kind == 1 || event.Kind == 30023
 }
 
-func summarizeContent(content string) string {
+// summarizeEvent returns the link text of an exported event: its content
+// warning or the first line of its content
+func summarizeEvent(event *nostr.Event) string {
+	if label, ok := nostrclient.ContentWarningLabel(event); ok {
+		return label
+	}
+
+	content := event.Content
 	if content == "" {
 		return "Untitled"
 	}
@@ -262,6 +341,41 @@ func writeFile(path string, data []byte) error {
 	return nil
 }
 
+// pruneEventFiles removes exported event files (named <event id><ext>) that
+// are no longer in events, e.g. after the owner deleted a note
+func pruneEventFiles(dir, ext string, events []*nostr.Event) error {
+	keep := make(map[string]bool, len(events))
+	for _, event := range events {
+		keep[event.ID+ext] = true
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read export dir %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || keep[name] || !isEventFile(name, ext) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("failed to remove stale export %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func isEventFile(name, ext string) bool {
+	id := strings.TrimSuffix(name, ext)
+	if id == name || len(id) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
 func decodeNpub(npub string) (string, error) {
 	if npub == "" {
 		return "", fmt.Errorf("npub is empty")
@@ -152,3 +152,97 @@ func TestGopherExporterIgnoresReply(t *testing.T) {
 		t.Fatalf("expected no export for reply, but gophermap exists")
 	}
 }
+
+func TestGopherExporterArchivesPastMaxItems(t *testing.T) {
+	priv := nostr.GeneratePrivateKey()
+	pub, err := nostr.GetPublicKey(priv)
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	npub, _ := nip19.EncodePublicKey(pub)
+
+	tmp := t.TempDir()
+	cfg := config.Default()
+	cfg.Identity.Npub = npub
+	cfg.Export.Gopher.Enabled = true
+	cfg.Export.Gopher.OutputDir = tmp
+	cfg.Export.Gopher.Host = "example.com"
+	cfg.Export.Gopher.Port = 70
+	cfg.Export.Gopher.MaxItems = 1
+	cfg.Storage = config.Storage{
+		Driver:     "sqlite",
+		SQLitePath: ":memory:",
+	}
+
+	ctx := context.Background()
+	st, err := storage.New(ctx, &cfg.Storage)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	exporter, err := NewGopherExporter(cfg, st)
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+
+	var notes []nostr.Event
+	for _, createdAt := range []time.Time{
+		time.Date(2023, time.May, 4, 10, 0, 0, 0, time.UTC),
+		time.Date(2025, time.October, 24, 10, 0, 0, 0, time.UTC),
+	} {
+		note := nostr.Event{
+			Kind:      1,
+			CreatedAt: nostr.Timestamp(createdAt.Unix()),
+			PubKey:    pub,
+			Content:   "Note from " + createdAt.Format("2006"),
+		}
+		if err := note.Sign(priv); err != nil {
+			t.Fatalf("failed to sign note: %v", err)
+		}
+		if err := st.StoreEvent(ctx, &note); err != nil {
+			t.Fatalf("failed to store note: %v", err)
+		}
+		notes = append(notes, note)
+	}
+	old := notes[0]
+
+	if err := exporter.Export(ctx); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	index, err := os.ReadFile(filepath.Join(tmp, "notes", "gophermap"))
+	if err != nil {
+		t.Fatalf("expected notes gophermap to be written: %v", err)
+	}
+	if strings.Contains(string(index), old.ID) {
+		t.Errorf("expected notes index to be limited to max_items")
+	}
+
+	if _, err := os.Stat(filepath.Join(tmp, "notes", old.ID+".txt")); err != nil {
+		t.Fatalf("expected archived note file to be written: %v", err)
+	}
+
+	expected := map[string]string{
+		"notes/archive/gophermap":            "/notes/archive/2023",
+		"notes/archive/2023/gophermap":       "/notes/archive/2023/05",
+		"notes/archive/2023/05/gophermap":    "/notes/archive/2023/05/04",
+		"notes/archive/2023/05/04/gophermap": "/notes/" + old.ID + ".txt",
+		"notes/archive/2025/10/24/gophermap": "/notes/" + notes[1].ID + ".txt",
+		"articles/archive/gophermap":         "Articles archive",
+	}
+	for file, want := range expected {
+		content, err := os.ReadFile(filepath.Join(tmp, filepath.FromSlash(file)))
+		if err != nil {
+			t.Fatalf("expected %s to be written: %v", file, err)
+		}
+		if !strings.Contains(string(content), want) {
+			t.Errorf("expected %s to contain %q, got:\n%s", file, want, content)
+		}
+	}
+
+	calendar, _ := os.ReadFile(filepath.Join(tmp, "notes", "archive", "2023", "05", "gophermap"))
+	if !strings.Contains(string(calendar), " 4*") {
+		t.Errorf("expected month calendar to mark the 4th, got:\n%s", calendar)
+	}
+}
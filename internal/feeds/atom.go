@@ -0,0 +1,155 @@
+package feeds
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// AtomContentType is the media type of Atom documents
+const AtomContentType = "application/atom+xml"
+
+// Feed is a syndication feed of rendered events
+type Feed struct {
+	ID       string // stable IRI identifying the feed
+	Title    string
+	Subtitle string
+	Link     string // page the feed mirrors
+	Self     string // URL the feed is served from
+	Author   string
+	Updated  time.Time // defaults to the newest entry's update time
+	Entries  []*Entry
+}
+
+// Entry is one item of a feed
+type Entry struct {
+	ID        string // stable IRI, e.g. a nostr: URI
+	Title     string
+	Link      string
+	Related   []string // other places the entry can be read, e.g. more portals
+	Author    string
+	Published time.Time
+	Updated   time.Time // defaults to Published
+	Summary   string    // plain text
+	Content   string    // HTML
+}
+
+type atomFeed struct {
+	XMLName  xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string       `xml:"id"`
+	Title    string       `xml:"title"`
+	Subtitle string       `xml:"subtitle,omitempty"`
+	Updated  string       `xml:"updated"`
+	Links    []atomLink   `xml:"link"`
+	Author   *atomAuthor  `xml:"author,omitempty"`
+	Entries  []*atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Links     []atomLink  `xml:"link"`
+	Author    *atomAuthor `xml:"author,omitempty"`
+	Published string      `xml:"published,omitempty"`
+	Updated   string      `xml:"updated"`
+	Summary   *atomText   `xml:"summary,omitempty"`
+	Content   *atomText   `xml:"content,omitempty"`
+}
+
+// Atom encodes the feed as an Atom 1.0 document
+func (f *Feed) Atom() ([]byte, error) {
+	feed := &atomFeed{
+		ID:       f.ID,
+		Title:    f.Title,
+		Subtitle: f.Subtitle,
+		Updated:  atomTime(f.updated()),
+	}
+	if f.Link != "" {
+		feed.Links = append(feed.Links, atomLink{Href: f.Link, Rel: "alternate"})
+	}
+	if f.Self != "" {
+		feed.Links = append(feed.Links, atomLink{Href: f.Self, Rel: "self", Type: AtomContentType})
+	}
+	if f.Author != "" {
+		feed.Author = &atomAuthor{Name: f.Author}
+	}
+
+	for _, entry := range f.Entries {
+		e := &atomEntry{
+			ID:      entry.ID,
+			Title:   entry.Title,
+			Updated: atomTime(entry.updated()),
+		}
+		if entry.Link != "" {
+			e.Links = append(e.Links, atomLink{Href: entry.Link, Rel: "alternate"})
+		}
+		for _, related := range entry.Related {
+			e.Links = append(e.Links, atomLink{Href: related, Rel: "related"})
+		}
+		if entry.Author != "" {
+			e.Author = &atomAuthor{Name: entry.Author}
+		}
+		if !entry.Published.IsZero() {
+			e.Published = atomTime(entry.Published)
+		}
+		if entry.Summary != "" {
+			e.Summary = &atomText{Type: "text", Body: entry.Summary}
+		}
+		if entry.Content != "" {
+			e.Content = &atomText{Type: "html", Body: entry.Content}
+		}
+		feed.Entries = append(feed.Entries, e)
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// updated returns the feed's update time: the configured one, or that of its
+// newest entry
+func (f *Feed) updated() time.Time {
+	if !f.Updated.IsZero() {
+		return f.Updated
+	}
+
+	var latest time.Time
+	for _, entry := range f.Entries {
+		if updated := entry.updated(); updated.After(latest) {
+			latest = updated
+		}
+	}
+	if latest.IsZero() {
+		return time.Unix(0, 0)
+	}
+	return latest
+}
+
+// updated returns the entry's update time, defaulting to its publication
+func (e *Entry) updated() time.Time {
+	if e.Updated.IsZero() {
+		return e.Published
+	}
+	return e.Updated
+}
+
+// atomTime formats a time as an RFC 3339 date in UTC
+func atomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
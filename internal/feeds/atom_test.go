@@ -0,0 +1,63 @@
+package feeds
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeedAtom(t *testing.T) {
+	feed := &Feed{
+		ID:    "https://example.com/feeds/notes.atom",
+		Title: "Notes & more",
+		Link:  "https://example.com/notes",
+		Self:  "https://example.com/feeds/notes.atom",
+		Entries: []*Entry{
+			{
+				ID:        "nostr:note1abc",
+				Title:     "First <note>",
+				Link:      "https://example.com/note/abc",
+				Related:   []string{"https://njump.me/nevent1abc"},
+				Author:    "alice",
+				Published: time.Unix(1000, 0),
+				Content:   "<p>Hello</p>",
+			},
+			{
+				ID:        "nostr:note1def",
+				Title:     "Second",
+				Published: time.Unix(2000, 0),
+			},
+		},
+	}
+
+	data, err := feed.Atom()
+	if err != nil {
+		t.Fatalf("Atom() error = %v", err)
+	}
+	output := string(data)
+
+	for _, expected := range []string{
+		`<feed xmlns="http://www.w3.org/2005/Atom">`,
+		"<title>Notes &amp; more</title>",
+		"<updated>1970-01-01T00:33:20Z</updated>",
+		`<link href="https://example.com/feeds/notes.atom" rel="self" type="application/atom+xml"></link>`,
+		"<title>First &lt;note&gt;</title>",
+		`<link href="https://njump.me/nevent1abc" rel="related"></link>`,
+		`<content type="html">&lt;p&gt;Hello&lt;/p&gt;</content>`,
+		"<published>1970-01-01T00:16:40Z</published>",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Atom output missing %s:\n%s", expected, output)
+		}
+	}
+
+	// The document must round-trip as XML
+	var parsed atomFeed
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("Atom output is not valid XML: %v", err)
+	}
+	if len(parsed.Entries) != 2 || parsed.Entries[1].Updated != "1970-01-01T00:33:20Z" {
+		t.Errorf("unexpected entries after round-trip: %+v", parsed.Entries)
+	}
+}
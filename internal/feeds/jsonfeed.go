@@ -0,0 +1,86 @@
+package feeds
+
+import (
+	"encoding/json"
+)
+
+// JSONFeedContentType is the media type of JSON Feed documents
+const JSONFeedContentType = "application/feed+json"
+
+// jsonFeedVersion identifies the JSON Feed version written
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+type jsonFeed struct {
+	Version     string          `json:"version"`
+	Title       string          `json:"title"`
+	HomePageURL string          `json:"home_page_url,omitempty"`
+	FeedURL     string          `json:"feed_url,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Authors     []jsonFeedActor `json:"authors,omitempty"`
+	Items       []*jsonFeedItem `json:"items"`
+}
+
+type jsonFeedActor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string          `json:"id"`
+	URL           string          `json:"url,omitempty"`
+	ExternalURL   string          `json:"external_url,omitempty"`
+	Title         string          `json:"title,omitempty"`
+	ContentHTML   string          `json:"content_html,omitempty"`
+	ContentText   string          `json:"content_text,omitempty"`
+	Summary       string          `json:"summary,omitempty"`
+	DatePublished string          `json:"date_published,omitempty"`
+	DateModified  string          `json:"date_modified,omitempty"`
+	Authors       []jsonFeedActor `json:"authors,omitempty"`
+}
+
+// JSON encodes the feed as a JSON Feed 1.1 document. Every item needs
+// content, so entries without HTML content carry their summary as text.
+func (f *Feed) JSON() ([]byte, error) {
+	feed := &jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		FeedURL:     f.Self,
+		Description: f.Subtitle,
+		Items:       make([]*jsonFeedItem, 0, len(f.Entries)),
+	}
+	if f.Author != "" {
+		feed.Authors = []jsonFeedActor{{Name: f.Author}}
+	}
+
+	for _, entry := range f.Entries {
+		item := &jsonFeedItem{
+			ID:          entry.ID,
+			URL:         entry.Link,
+			Title:       entry.Title,
+			ContentHTML: entry.Content,
+			Summary:     entry.Summary,
+		}
+		if len(entry.Related) > 0 {
+			item.ExternalURL = entry.Related[0]
+		}
+		if item.ContentHTML == "" {
+			item.ContentText = entry.Summary
+		}
+		if !entry.Published.IsZero() {
+			item.DatePublished = atomTime(entry.Published)
+		}
+		if !entry.Updated.IsZero() {
+			item.DateModified = atomTime(entry.Updated)
+		}
+		if entry.Author != "" {
+			item.Authors = []jsonFeedActor{{Name: entry.Author}}
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	data, err := json.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
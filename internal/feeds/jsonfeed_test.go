@@ -0,0 +1,60 @@
+package feeds
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestFeedJSON(t *testing.T) {
+	feed := &Feed{
+		Title:  "Notes",
+		Link:   "https://example.com/notes",
+		Self:   "https://example.com/feeds/notes.json",
+		Author: "alice",
+		Entries: []*Entry{
+			{
+				ID:        "nostr:note1abc",
+				Title:     "First",
+				Link:      "https://njump.me/nevent1abc",
+				Related:   []string{"gemini://example.com/nevent1abc"},
+				Published: time.Unix(1000, 0),
+				Content:   "<p>Hello</p>",
+			},
+			{
+				ID:        "nostr:note1def",
+				Title:     "CW: spoilers",
+				Published: time.Unix(2000, 0),
+				Summary:   "CW: spoilers",
+			},
+		},
+	}
+
+	data, err := feed.JSON()
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+
+	var parsed jsonFeed
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("JSON output is not valid JSON: %v", err)
+	}
+
+	if parsed.Version != "https://jsonfeed.org/version/1.1" || parsed.FeedURL != feed.Self {
+		t.Errorf("unexpected feed header: %+v", parsed)
+	}
+	if len(parsed.Authors) != 1 || parsed.Authors[0].Name != "alice" {
+		t.Errorf("unexpected authors: %+v", parsed.Authors)
+	}
+	if len(parsed.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(parsed.Items))
+	}
+
+	first, second := parsed.Items[0], parsed.Items[1]
+	if first.ContentHTML != "<p>Hello</p>" || first.ExternalURL != "gemini://example.com/nevent1abc" || first.DatePublished != "1970-01-01T00:16:40Z" {
+		t.Errorf("unexpected first item: %+v", first)
+	}
+	if second.ContentHTML != "" || second.ContentText != "CW: spoilers" {
+		t.Errorf("items without content should carry their summary as text: %+v", second)
+	}
+}
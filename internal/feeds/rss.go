@@ -0,0 +1,93 @@
+package feeds
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// RSSContentType is the media type of RSS documents
+const RSSContentType = "application/rss+xml"
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	LastBuildDate string     `xml:"lastBuildDate"`
+	Self          *atomLink  `xml:"atom:link,omitempty"`
+	Items         []*rssItem `xml:"item"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+	Creator     string  `xml:"dc:creator,omitempty"`
+	Description string  `xml:"description,omitempty"`
+}
+
+// RSS encodes the feed as an RSS 2.0 document. RSS has no separate summary,
+// so an entry's description is its HTML content, or its summary without one.
+// Authors are written as dc:creator since RSS expects an email address.
+func (f *Feed) RSS() ([]byte, error) {
+	description := f.Subtitle
+	if description == "" {
+		description = f.Title
+	}
+
+	doc := &rssDocument{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		DCNS:    "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.Link,
+			Description:   description,
+			LastBuildDate: rssTime(f.updated()),
+		},
+	}
+	if f.Self != "" {
+		doc.Channel.Self = &atomLink{Href: f.Self, Rel: "self", Type: RSSContentType}
+	}
+
+	for _, entry := range f.Entries {
+		item := &rssItem{
+			Title:       entry.Title,
+			Link:        entry.Link,
+			GUID:        rssGUID{IsPermaLink: "false", Value: entry.ID},
+			Creator:     entry.Author,
+			Description: entry.Content,
+		}
+		if !entry.Published.IsZero() {
+			item.PubDate = rssTime(entry.Published)
+		}
+		if item.Description == "" {
+			item.Description = entry.Summary
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// rssTime formats a time as an RFC 822 date in UTC
+func rssTime(t time.Time) string {
+	return t.UTC().Format(time.RFC1123Z)
+}
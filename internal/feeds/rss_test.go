@@ -0,0 +1,66 @@
+package feeds
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeedRSS(t *testing.T) {
+	feed := &Feed{
+		Title: "Notes & more",
+		Link:  "https://example.com/notes",
+		Self:  "https://example.com/feeds/notes.rss",
+		Entries: []*Entry{
+			{
+				ID:        "nostr:note1abc",
+				Title:     "First <note>",
+				Link:      "https://njump.me/nevent1abc",
+				Author:    "alice",
+				Published: time.Unix(1000, 0),
+				Content:   "<p>Hello</p>",
+			},
+			{
+				ID:        "nostr:note1def",
+				Title:     "CW: spoilers",
+				Published: time.Unix(2000, 0),
+				Summary:   "CW: spoilers",
+			},
+		},
+	}
+
+	data, err := feed.RSS()
+	if err != nil {
+		t.Fatalf("RSS() error = %v", err)
+	}
+	output := string(data)
+
+	for _, expected := range []string{
+		`<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/elements/1.1/">`,
+		"<description>Notes &amp; more</description>",
+		"<lastBuildDate>Thu, 01 Jan 1970 00:33:20 +0000</lastBuildDate>",
+		`<atom:link href="https://example.com/feeds/notes.rss" rel="self" type="application/rss+xml"></atom:link>`,
+		`<guid isPermaLink="false">nostr:note1abc</guid>`,
+		"<dc:creator>alice</dc:creator>",
+		"<description>&lt;p&gt;Hello&lt;/p&gt;</description>",
+		"<description>CW: spoilers</description>",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("RSS output missing %s:\n%s", expected, output)
+		}
+	}
+
+	var parsed struct {
+		Items []struct {
+			Link    string `xml:"link"`
+			PubDate string `xml:"pubDate"`
+		} `xml:"channel>item"`
+	}
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("RSS output is not valid XML: %v", err)
+	}
+	if len(parsed.Items) != 2 || parsed.Items[0].Link != "https://njump.me/nevent1abc" {
+		t.Errorf("unexpected items after round-trip: %+v", parsed.Items)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/sandwichfarm/nophr/internal/config"
+	nostrclient "github.com/sandwichfarm/nophr/internal/nostr"
 )
 
 // Handler handles Finger protocol queries
@@ -107,7 +108,7 @@ func (h *Handler) renderOwnerInfo(ctx context.Context, verbose bool) string {
 	ownerPubkey := h.server.GetOwnerPubkey()
 
 	// Get owner's profile
-	profile, err := h.server.GetStorage().QueryEvents(ctx, nostr.Filter{
+	profile, err := h.server.queryHelper.QueryEvents(ctx, nostr.Filter{
 		Kinds:   []int{0},
 		Authors: []string{ownerPubkey},
 		Limit:   1,
@@ -131,7 +132,7 @@ func (h *Handler) renderOwnerInfo(ctx context.Context, verbose bool) string {
 // renderUserInfo renders information about a followed user
 func (h *Handler) renderUserInfo(ctx context.Context, pubkey string, verbose bool) string {
 	// Query profile
-	profile, err := h.server.GetStorage().QueryEvents(ctx, nostr.Filter{
+	profile, err := h.server.queryHelper.QueryEvents(ctx, nostr.Filter{
 		Kinds:   []int{0},
 		Authors: []string{pubkey},
 		Limit:   1,
@@ -144,12 +145,16 @@ func (h *Handler) renderUserInfo(ctx context.Context, pubkey string, verbose boo
 	profileEvent := profile[0]
 
 	// Get recent notes
-	notes, err := h.server.GetStorage().QueryEvents(ctx, nostr.Filter{
+	notes, err := h.server.queryHelper.QueryEvents(ctx, nostr.Filter{
 		Kinds:   []int{1},
 		Authors: []string{pubkey},
 		Limit:   5,
 	})
 
+	if err == nil && h.config.Display.Feed.HideSensitive {
+		notes = nostrclient.WithoutSensitive(notes)
+	}
+
 	var enrichedNotes []*enrichedNote
 	if err == nil {
 		for _, note := range notes {
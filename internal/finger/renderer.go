@@ -115,6 +115,12 @@ func (r *Renderer) renderNoteCompact(event *nostr.Event) string {
 	// Timestamp
 	sb.WriteString(fmt.Sprintf("[%s] ", formatTimestamp(event.CreatedAt)))
 
+	// Sensitive content is collapsed to its warning
+	if label, ok := nostrclient.ContentWarningLabel(event); ok {
+		sb.WriteString(label)
+		return sb.String()
+	}
+
 	// Content (first line, max 60 chars)
 	content := event.Content
 	if len(content) > 60 {
@@ -10,17 +10,27 @@ import (
 	"time"
 
 	"github.com/sandwichfarm/nophr/internal/aggregates"
+	"github.com/sandwichfarm/nophr/internal/cache"
 	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/ops"
+	"github.com/sandwichfarm/nophr/internal/security"
 	"github.com/sandwichfarm/nophr/internal/storage"
 )
 
 // Server implements a Finger protocol server (RFC 1288)
 type Server struct {
 	config      *config.FingerProtocol
+	reloadMu    sync.RWMutex // held for reading while a query is answered
+	fullConfig  *config.Config
 	storage     *storage.Storage
 	handler     *Handler
 	queryHelper *aggregates.QueryHelper
 	ownerPubkey string
+	guard       *security.Guard
+	events      *aggregates.EventFilter
+	cache       cache.Cache // nil = responses are not cached
+	cacheTTL    time.Duration
+	metrics     *ops.Metrics // nil = requests are not counted
 
 	listener net.Listener
 	wg       sync.WaitGroup
@@ -34,13 +44,20 @@ func New(cfg *config.FingerProtocol, fullCfg *config.Config, st *storage.Storage
 
 	s := &Server{
 		config:      cfg,
+		fullConfig:  fullCfg,
 		storage:     st,
 		ownerPubkey: fullCfg.Identity.Npub,
 		ctx:         ctx,
 		cancel:      cancel,
 		queryHelper: aggregates.NewQueryHelper(st, fullCfg, aggMgr),
+		guard:       security.NewGuard(&fullCfg.Security),
 	}
 
+	// Hide events from denied pubkeys, with banned content or muted by the
+	// owner everywhere
+	s.events = aggregates.NewEventFilter(s.guard, aggregates.NewMuteFilter(st, fullCfg))
+	s.queryHelper.SetEventFilter(s.events.Filter)
+
 	// Initialize handler
 	s.handler = NewHandler(s, fullCfg)
 
@@ -75,6 +92,7 @@ func (s *Server) Stop() error {
 	}
 
 	s.wg.Wait()
+	s.guard.Close()
 	return nil
 }
 
@@ -105,6 +123,14 @@ func (s *Server) handleConnection(conn net.Conn) {
 	defer s.wg.Done()
 	defer conn.Close()
 
+	clientIP := security.ClientIP(conn.RemoteAddr())
+	if err := s.guard.AcquireConnection(clientIP); err != nil {
+		fmt.Printf("Finger connection refused for %s: %v\n", clientIP, err)
+		s.sendResponse(conn, fmt.Sprintf("Connection refused: %v\n", err))
+		return
+	}
+	defer s.guard.ReleaseConnection(clientIP)
+
 	// Set read timeout
 	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
 
@@ -120,16 +146,49 @@ func (s *Server) handleConnection(conn net.Conn) {
 	query := strings.TrimSpace(line)
 
 	// Log request
+	start := time.Now()
 	fmt.Printf("Finger request: %q from %s\n", query, conn.RemoteAddr())
 
+	if ok, retryAfter := s.guard.AllowRequest(security.ProtocolFinger, clientIP); !ok {
+		fmt.Printf("Finger request rate limited for %s\n", clientIP)
+		s.sendResponse(conn, fmt.Sprintf("Rate limit exceeded, retry in %d seconds\n", security.RetrySeconds(retryAfter)))
+		s.metrics.ObserveRequest(security.ProtocolFinger, "rate_limited", time.Since(start))
+		return
+	}
+
 	// Handle query
-	response := s.handler.Handle(query)
+	response := s.handle(query)
+	s.metrics.ObserveRequest(security.ProtocolFinger, "ok", time.Since(start))
 
 	// Write response
 	conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
 	s.sendResponse(conn, response)
 }
 
+// handle answers a query, serving and storing it in the response cache when
+// one is configured
+func (s *Server) handle(query string) string {
+	s.reloadMu.RLock()
+	defer s.reloadMu.RUnlock()
+
+	if s.cache == nil || s.cacheTTL <= 0 {
+		return s.handler.Handle(query)
+	}
+
+	key := cache.FingerKey(query)
+
+	if data, hit, err := s.cache.Get(s.ctx, key); err == nil && hit {
+		return string(data)
+	}
+
+	response := s.handler.Handle(query)
+	if err := s.cache.Set(s.ctx, key, []byte(response), s.cacheTTL); err != nil {
+		fmt.Printf("Cache set error: %v\n", err)
+	}
+
+	return response
+}
+
 // sendResponse sends a response and ensures proper formatting
 func (s *Server) sendResponse(conn net.Conn, response string) {
 	// Ensure CRLF line endings per RFC 1288
@@ -152,6 +211,46 @@ func (s *Server) GetQueryHelper() *aggregates.QueryHelper {
 	return s.queryHelper
 }
 
+// SetCache enables response caching with the finger_response TTL
+func (s *Server) SetCache(c cache.Cache) {
+	s.cache = c
+	s.cacheTTL = cache.NewRenderTTLs(&s.fullConfig.Caching).Finger()
+}
+
+// SetMetrics enables request counters and latency histograms
+func (s *Server) SetMetrics(m *ops.Metrics) {
+	s.metrics = m
+}
+
+// PrepareReload returns a function that switches the server to the display
+// and behavior settings of a new configuration. Listener and security
+// settings keep their startup values. The switch waits for queries in
+// flight, so every query is answered entirely with the old settings or the
+// new ones.
+func (s *Server) PrepareReload(cfg *config.Config) (func(), error) {
+	return func() {
+		s.reloadMu.Lock()
+		defer s.reloadMu.Unlock()
+
+		s.handler.config = cfg
+		s.queryHelper.SetConfig(cfg)
+		s.fullConfig = cfg
+	}, nil
+}
+
+// SetGuard replaces the server's own guard with one shared by every
+// server, so connection caps count connections to all of them together
+func (s *Server) SetGuard(g *security.Guard) {
+	s.guard.Close()
+	s.guard = g
+	s.events.SetGuard(g)
+}
+
+// GetGuard returns the security guard
+func (s *Server) GetGuard() *security.Guard {
+	return s.guard
+}
+
 // GetOwnerPubkey returns the owner's pubkey
 func (s *Server) GetOwnerPubkey() string {
 	return s.ownerPubkey
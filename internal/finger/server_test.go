@@ -9,9 +9,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/nbd-wtf/go-nostr"
 	"github.com/sandwichfarm/nophr/internal/aggregates"
 	"github.com/sandwichfarm/nophr/internal/config"
 	"github.com/sandwichfarm/nophr/internal/storage"
+	"github.com/sandwichfarm/nophr/internal/storage/storagetest"
 )
 
 func TestFingerProtocol(t *testing.T) {
@@ -111,6 +113,54 @@ func TestFingerProtocol(t *testing.T) {
 	})
 }
 
+func TestFingerRateLimit(t *testing.T) {
+	cfg := &config.Config{
+		Identity: config.Identity{
+			Npub: "test-pubkey-1234567890abcdef",
+		},
+		Storage: config.Storage{
+			Driver:     "sqlite",
+			SQLitePath: ":memory:",
+		},
+		Security: config.Security{
+			Enabled:    true,
+			RateLimits: config.SecurityRateLimits{Finger: 1},
+		},
+	}
+
+	fingerCfg := &config.FingerProtocol{
+		Enabled:  true,
+		Port:     17080,
+		Bind:     "localhost",
+		MaxUsers: 10,
+	}
+
+	ctx := context.Background()
+	st, err := storage.New(ctx, &cfg.Storage)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	server := New(fingerCfg, cfg, st, aggregates.NewManager(st, cfg))
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	response := sendFingerRequest(t, fingerCfg.Port, "owner")
+	if strings.Contains(response, "Rate limit exceeded") {
+		t.Fatalf("First request should be served, got: %s", response)
+	}
+
+	response = sendFingerRequest(t, fingerCfg.Port, "owner")
+	if !strings.HasPrefix(response, "Rate limit exceeded, retry in ") {
+		t.Errorf("Second request should be rate limited, got: %s", response)
+	}
+}
+
 func TestQueryParsing(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -215,3 +265,39 @@ func sendFingerRequest(t *testing.T, port int, query string) string {
 
 	return response.String()
 }
+
+func TestFingerReload(t *testing.T) {
+	cfg := &config.Config{
+		Identity: config.Identity{Npub: "test-pubkey-1234567890abcdef"},
+		Storage:  config.Storage{Driver: "sqlite", SQLitePath: ":memory:"},
+	}
+
+	ctx := context.Background()
+	st, err := storage.New(ctx, &cfg.Storage)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	priv := strings.Repeat("1", 64)
+	now := time.Now()
+	pubkey := storagetest.StoreSigned(t, st, priv, 0, now, nostr.Tags{}, `{"name":"alice"}`)
+	storagetest.StoreSigned(t, st, priv, 1, now, nostr.Tags{{"content-warning", "spoilers"}}, "the butler did it")
+
+	server := New(&config.FingerProtocol{Enabled: true, MaxUsers: 10}, cfg, st, aggregates.NewManager(st, cfg))
+	if response := server.handle("/W " + pubkey); !strings.Contains(response, "CW: spoilers") {
+		t.Fatalf("Expected the sensitive note behind its warning before the reload, got: %s", response)
+	}
+
+	next := *cfg
+	next.Display.Feed.HideSensitive = true
+	apply, err := server.PrepareReload(&next)
+	if err != nil {
+		t.Fatalf("PrepareReload() error = %v", err)
+	}
+	apply()
+
+	if response := server.handle("/W " + pubkey); strings.Contains(response, "CW: spoilers") {
+		t.Errorf("Expected hide_sensitive to apply after the reload, got: %s", response)
+	}
+}
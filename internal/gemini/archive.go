@@ -0,0 +1,146 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sandwichfarm/nophr/internal/sections"
+)
+
+// handleArchive renders a section's date-based archive:
+// /<section>/archive, /<section>/archive/YYYY, .../YYYY/MM and .../YYYY/MM/DD
+func (r *Router) handleArchive(ctx context.Context, path string) []byte {
+	ap, err := sections.ParseArchivePath(path)
+	if err != nil {
+		return FormatErrorResponse(StatusNotFound, fmt.Sprintf("Invalid archive: %v", err))
+	}
+
+	section, err := r.server.GetSectionManager().GetSection(ap.Section)
+	if err != nil {
+		return FormatErrorResponse(StatusNotFound, fmt.Sprintf("Unknown section: %s", ap.Section))
+	}
+
+	var gemtext strings.Builder
+	archives := r.server.GetSectionManager().Archives()
+	switch {
+	case ap.Day > 0:
+		err = r.renderArchiveDay(ctx, &gemtext, archives, section, ap)
+	case ap.Month > 0:
+		err = r.renderArchiveMonth(ctx, &gemtext, archives, section, ap)
+	case ap.Year > 0:
+		err = r.renderArchiveYear(ctx, &gemtext, archives, section, ap)
+	default:
+		err = r.renderArchiveIndex(ctx, &gemtext, archives, section)
+	}
+	if err != nil {
+		return FormatErrorResponse(StatusTemporaryFailure, fmt.Sprintf("Error loading archive: %v", err))
+	}
+
+	gemtext.WriteString("\n")
+	if ap.Year > 0 {
+		gemtext.WriteString(fmt.Sprintf("=> %s ↑ Up\n", r.geminiURL(ap.Parent())))
+	} else if section.Path != "" {
+		gemtext.WriteString(fmt.Sprintf("=> %s ← Back to %s\n", r.geminiURL(section.Path), sectionLabel(section)))
+	}
+	gemtext.WriteString(fmt.Sprintf("=> %s ⌂ Home\n", r.geminiURL("/")))
+
+	return FormatSuccessResponse(r.renderer.applyHeadersFooters(gemtext.String(), section.Name))
+}
+
+// renderArchiveIndex lists the years that have events
+func (r *Router) renderArchiveIndex(ctx context.Context, gemtext *strings.Builder, archives *sections.ArchiveManager, section *sections.Section) error {
+	years, err := archives.ListArchives(ctx, section, sections.ArchiveByYear)
+	if err != nil {
+		return err
+	}
+
+	gemtext.WriteString(fmt.Sprintf("# %s - Archive\n\n", sectionLabel(section)))
+	r.writeArchiveLinks(gemtext, section, years)
+	return nil
+}
+
+// renderArchiveYear lists the months of a year that have events
+func (r *Router) renderArchiveYear(ctx context.Context, gemtext *strings.Builder, archives *sections.ArchiveManager, section *sections.Section, ap *sections.ArchivePath) error {
+	months, err := archives.PeriodArchives(ctx, section, ap.Year, 0, sections.ArchiveByMonth)
+	if err != nil {
+		return err
+	}
+
+	gemtext.WriteString(fmt.Sprintf("# %s - %s\n\n", sectionLabel(section), ap.Archive().FormatTitle()))
+	r.writeArchiveLinks(gemtext, section, months)
+	return nil
+}
+
+// renderArchiveMonth shows a calendar of the month and lists its days
+func (r *Router) renderArchiveMonth(ctx context.Context, gemtext *strings.Builder, archives *sections.ArchiveManager, section *sections.Section, ap *sections.ArchivePath) error {
+	calendar, err := archives.GenerateMonthlyCalendar(ctx, section, ap.Year, ap.Month)
+	if err != nil {
+		return err
+	}
+
+	title := ap.Archive().FormatTitle()
+	gemtext.WriteString(fmt.Sprintf("# %s - %s\n\n", sectionLabel(section), title))
+
+	gemtext.WriteString(fmt.Sprintf("```Calendar of %s, days with posts are marked *\n", title))
+	gemtext.WriteString(strings.Join(calendar.Grid(), "\n"))
+	gemtext.WriteString("\n```\n\n")
+
+	r.writeArchiveLinks(gemtext, section, calendar.Archives())
+	return nil
+}
+
+// renderArchiveDay lists a page of the events of a day
+func (r *Router) renderArchiveDay(ctx context.Context, gemtext *strings.Builder, archives *sections.ArchiveManager, section *sections.Section, ap *sections.ArchivePath) error {
+	page, err := archives.GetArchivePage(ctx, section, ap.Year, ap.Month, ap.Day, ap.Page)
+	if err != nil {
+		return err
+	}
+
+	gemtext.WriteString(fmt.Sprintf("# %s - %s\n\n", sectionLabel(section), ap.Archive().FormatTitle()))
+
+	if len(page.Events) == 0 {
+		gemtext.WriteString("No content for this day.\n")
+	}
+	for _, event := range page.Events {
+		if section.ShowAuthors {
+			gemtext.WriteString(fmt.Sprintf("%s - %s\n", truncatePubkey(event.PubKey), formatTimestamp(event.CreatedAt)))
+		} else {
+			gemtext.WriteString(fmt.Sprintf("%s\n", formatTimestamp(event.CreatedAt)))
+		}
+		gemtext.WriteString(fmt.Sprintf("=> %s %s\n\n", r.geminiURL("/note/"+event.ID), r.renderer.titleForEvent(event)))
+	}
+
+	if page.HasPrev {
+		gemtext.WriteString(fmt.Sprintf("=> %s ← Previous Page\n", r.geminiURL(fmt.Sprintf("%s/page/%d", ap.Selector(), page.PageNumber-1))))
+	}
+	if page.HasNext {
+		gemtext.WriteString(fmt.Sprintf("=> %s → Next Page\n", r.geminiURL(fmt.Sprintf("%s/page/%d", ap.Selector(), page.PageNumber+1))))
+	}
+	if page.TotalPages > 1 {
+		gemtext.WriteString(fmt.Sprintf("Page %d of %d\n", page.PageNumber, page.TotalPages))
+	}
+
+	return nil
+}
+
+// writeArchiveLinks adds a link with the event count for each archive
+func (r *Router) writeArchiveLinks(gemtext *strings.Builder, section *sections.Section, archives []*sections.Archive) {
+	if len(archives) == 0 {
+		gemtext.WriteString("No content yet.\n")
+		return
+	}
+
+	for _, archive := range archives {
+		gemtext.WriteString(fmt.Sprintf("=> %s %s (%d)\n",
+			r.geminiURL(archive.FormatArchiveSelector(section.Name)), archive.FormatTitle(), archive.EventCount))
+	}
+}
+
+// sectionLabel returns a section's title, falling back to its name
+func sectionLabel(section *sections.Section) string {
+	if section.Title != "" {
+		return section.Title
+	}
+	return section.Name
+}
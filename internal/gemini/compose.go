@@ -0,0 +1,208 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// handleCompose handles the owner's compose flow. Every /compose route needs
+// an owner client certificate, the same as the /owner area:
+//
+//	/compose                  prompt for a new note
+//	/compose/reply/<id>       prompt for a reply to a stored event
+//	/compose/react/<id>       prompt for a reaction to a stored event
+//	/compose/drafts           list drafts
+//	/compose/draft/<name>     review a draft
+//	/compose/publish/<name>   sign and publish a draft
+//	/compose/discard/<name>   delete a draft
+func (r *Router) handleCompose(ctx context.Context, parts []string, rawQuery string, client Client) []byte {
+	ob := r.server.GetOutbox()
+	if ob == nil {
+		return FormatErrorResponse(StatusNotFound, "Composing is not enabled")
+	}
+	if denied := requireOwner(client); denied != nil {
+		return denied
+	}
+
+	input, err := url.QueryUnescape(rawQuery)
+	if err != nil {
+		return FormatErrorResponse(StatusBadRequest, "Invalid input encoding")
+	}
+
+	action := ""
+	if len(parts) > 0 {
+		action = parts[0]
+	}
+	arg := ""
+	if len(parts) > 1 {
+		arg = parts[1]
+	}
+
+	switch action {
+	case "":
+		if !ob.Allowed(1) {
+			return FormatErrorResponse(StatusPermanentFailure, "Publishing notes is disabled")
+		}
+		if input == "" {
+			return FormatInputResponse("New note", false)
+		}
+		return r.composeDraft(ctx, ob.NewNote(input))
+
+	case "reply":
+		if !ob.Allowed(1) {
+			return FormatErrorResponse(StatusPermanentFailure, "Publishing notes is disabled")
+		}
+		if input == "" {
+			return FormatInputResponse(fmt.Sprintf("Reply to %s", r.composeTarget(ctx, arg)), false)
+		}
+		event, err := ob.NewReply(ctx, arg, input)
+		if err != nil {
+			return FormatErrorResponse(StatusNotFound, err.Error())
+		}
+		return r.composeDraft(ctx, event)
+
+	case "react":
+		if !ob.Allowed(7) {
+			return FormatErrorResponse(StatusPermanentFailure, "Publishing reactions is disabled")
+		}
+		if input == "" {
+			return FormatInputResponse("Reaction (+, - or an emoji)", false)
+		}
+		event, err := ob.NewReaction(ctx, arg, input)
+		if err != nil {
+			return FormatErrorResponse(StatusNotFound, err.Error())
+		}
+		return r.composeDraft(ctx, event)
+
+	case "drafts":
+		return r.handleDrafts()
+
+	case "draft":
+		return r.handleDraft(arg)
+
+	case "publish":
+		return r.publishDraft(ctx, arg)
+
+	case "discard":
+		if err := ob.DeleteDraft(arg); err != nil {
+			return FormatErrorResponse(StatusNotFound, err.Error())
+		}
+		return FormatRedirectResponse(r.geminiURL("/compose/drafts"), false)
+
+	default:
+		return FormatErrorResponse(StatusNotFound, fmt.Sprintf("Unknown compose action: %s", action))
+	}
+}
+
+// composeDraft saves a composed event as a draft, publishing it right away
+// when auto_sign is enabled
+func (r *Router) composeDraft(ctx context.Context, event *nostr.Event) []byte {
+	ob := r.server.GetOutbox()
+
+	name, err := ob.SaveDraft(event)
+	if err != nil {
+		return FormatErrorResponse(StatusTemporaryFailure, fmt.Sprintf("Error saving draft: %v", err))
+	}
+
+	if !ob.AutoSign() {
+		return FormatRedirectResponse(r.geminiURL("/compose/draft/"+name), false)
+	}
+
+	return r.publishDraft(ctx, name)
+}
+
+// publishDraft signs and publishes a draft, then redirects to the published
+// note (or, for reactions, to the note reacted to)
+func (r *Router) publishDraft(ctx context.Context, name string) []byte {
+	event, err := r.server.GetOutbox().PublishDraft(ctx, name)
+	if err != nil {
+		return FormatErrorResponse(StatusTemporaryFailure, fmt.Sprintf("Publish failed, draft %s kept: %v", name, err))
+	}
+
+	target := event.ID
+	if event.Kind == 7 {
+		if tag := event.Tags.GetFirst([]string{"e", ""}); tag != nil {
+			target = (*tag)[1]
+		}
+	}
+
+	return FormatRedirectResponse(r.geminiURL("/note/"+target), false)
+}
+
+// handleDrafts lists the drafts waiting to be published
+func (r *Router) handleDrafts() []byte {
+	drafts, err := r.server.GetOutbox().ListDrafts()
+	if err != nil {
+		return FormatErrorResponse(StatusTemporaryFailure, fmt.Sprintf("Error loading drafts: %v", err))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Drafts\n\n")
+
+	if len(drafts) == 0 {
+		sb.WriteString("No drafts.\n\n")
+	}
+	for _, draft := range drafts {
+		sb.WriteString(fmt.Sprintf("=> %s [%s] %s\n",
+			r.geminiURL("/compose/draft/"+draft.Name),
+			draftKind(draft.Event.Kind),
+			r.renderer.GetSummary(draft.Event.Content, 60)))
+	}
+
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("=> %s New Note\n", r.geminiURL("/compose")))
+	sb.WriteString(fmt.Sprintf("=> %s Back to Home\n", r.geminiURL("/")))
+
+	return FormatSuccessResponse(sb.String())
+}
+
+// handleDraft shows a draft for review before signing
+func (r *Router) handleDraft(name string) []byte {
+	event, err := r.server.GetOutbox().LoadDraft(name)
+	if err != nil {
+		return FormatErrorResponse(StatusNotFound, fmt.Sprintf("Draft not found: %s", name))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Draft %s\n\n", draftKind(event.Kind)))
+
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == "e" {
+			sb.WriteString(fmt.Sprintf("=> %s In response to %s\n", r.geminiURL("/note/"+tag[1]), truncatePubkey(tag[1])))
+		}
+	}
+
+	sb.WriteString("\n```\n")
+	sb.WriteString(event.Content)
+	sb.WriteString("\n```\n\n")
+
+	sb.WriteString(fmt.Sprintf("=> %s Sign and Publish\n", r.geminiURL("/compose/publish/"+name)))
+	sb.WriteString(fmt.Sprintf("=> %s Discard\n", r.geminiURL("/compose/discard/"+name)))
+	sb.WriteString(fmt.Sprintf("=> %s All Drafts\n", r.geminiURL("/compose/drafts")))
+
+	return FormatSuccessResponse(sb.String())
+}
+
+// composeTarget describes a stored event for a reply prompt
+func (r *Router) composeTarget(ctx context.Context, id string) string {
+	events, err := r.server.GetStorage().QueryEvents(ctx, nostr.Filter{IDs: []string{id}, Limit: 1})
+	if err != nil || len(events) == 0 {
+		return truncatePubkey(id)
+	}
+	return fmt.Sprintf("%q", r.renderer.GetSummary(events[0].Content, 60))
+}
+
+// draftKind names a draft's event kind
+func draftKind(kind int) string {
+	switch kind {
+	case 1:
+		return "Note"
+	case 7:
+		return "Reaction"
+	}
+	return fmt.Sprintf("Kind %d", kind)
+}
@@ -0,0 +1,146 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/sandwichfarm/nophr/internal/feeds"
+	nostrclient "github.com/sandwichfarm/nophr/internal/nostr"
+	"github.com/sandwichfarm/nophr/internal/sections"
+)
+
+// AtomFeedName is the file name of a page's Atom variant: /notes/atom.xml
+// mirrors /notes
+const AtomFeedName = "atom.xml"
+
+// gemsubDateFormat is the date that starts the label of every entry link
+// in a gemsub feed
+const gemsubDateFormat = "2006-01-02"
+
+// GemsubLink formats a link line of a gemsub feed: the entry's UTC date,
+// then its title
+func GemsubLink(target string, t time.Time, title string) string {
+	return fmt.Sprintf("=> %s %s - %s\n", target, t.UTC().Format(gemsubDateFormat), title)
+}
+
+// AtomPath returns the path of the Atom variant of a page path
+func AtomPath(path string) string {
+	return strings.TrimSuffix(path, "/") + "/" + AtomFeedName
+}
+
+// AtomFeed encodes events as an Atom feed. link is the gemtext page the feed
+// mirrors, self the feed's own URL and noteURL the page of an event. Entries
+// carry a plain-text summary; sensitive ones only their content warning.
+func (r *Renderer) AtomFeed(ctx context.Context, title, link, self string, events []*nostr.Event, noteURL func(*nostr.Event) string) ([]byte, error) {
+	feed := &feeds.Feed{
+		ID:     self,
+		Title:  title,
+		Link:   link,
+		Self:   self,
+		Author: r.settings().Site.Operator,
+	}
+
+	for _, event := range events {
+		entry := &feeds.Entry{
+			ID:        "nostr:" + event.ID,
+			Title:     r.titleForEvent(event),
+			Link:      noteURL(event),
+			Author:    r.resolver.DisplayName(ctx, event.PubKey),
+			Published: event.CreatedAt.Time(),
+			Updated:   event.CreatedAt.Time(),
+			Summary:   r.EventSummary(event, 280),
+		}
+		if note, err := nip19.EncodeNote(event.ID); err == nil {
+			entry.ID = "nostr:" + note
+		}
+		if article := nostrclient.ParseArticle(event); article != nil && !nostrclient.IsSensitive(event) {
+			entry.Published = article.PublishedAt
+			if article.Summary != "" {
+				entry.Summary = article.Summary
+			}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	return feed.Atom()
+}
+
+// FormatAtomResponse creates a successful response with an Atom document
+func FormatAtomResponse(body []byte) []byte {
+	return FormatResponse(StatusSuccess, feeds.AtomContentType, string(body))
+}
+
+// handleAtomFeed serves the Atom variant of /notes, /articles or a section
+// path, requested as <path>/atom.xml. Sections take precedence, as they do
+// for the gemtext pages.
+func (r *Router) handleAtomFeed(ctx context.Context, path string) []byte {
+	siteTitle := r.server.settings().Site.Title
+	if siteTitle == "" {
+		siteTitle = "nophr"
+	}
+
+	var title string
+	var events []*nostr.Event
+
+	var sectionsList []*sections.Section
+	if r.server.GetSectionManager() != nil {
+		sectionsList = r.server.GetSectionManager().GetSectionsByPath(path)
+	}
+
+	switch {
+	case len(sectionsList) > 0:
+		labels := make([]string, 0, len(sectionsList))
+		for _, section := range sectionsList {
+			page, err := r.server.GetSectionManager().GetPage(ctx, section.Name, 1)
+			if err != nil {
+				return FormatErrorResponse(StatusTemporaryFailure, fmt.Sprintf("Error loading section: %v", err))
+			}
+			labels = append(labels, sectionLabel(section))
+			events = append(events, page.Events...)
+		}
+		// Sections sharing a path are merged newest first
+		if len(sectionsList) > 1 {
+			sort.SliceStable(events, func(i, j int) bool {
+				return events[i].CreatedAt > events[j].CreatedAt
+			})
+		}
+		title = strings.Join(labels, ", ")
+
+	case path == "/notes":
+		notes, err := r.server.GetQueryHelper().GetNotes(ctx, 50)
+		if err != nil {
+			return FormatErrorResponse(StatusTemporaryFailure, fmt.Sprintf("Error loading notes: %v", err))
+		}
+		for _, note := range notes {
+			events = append(events, note.Event)
+		}
+		title = "Notes"
+
+	case path == "/articles":
+		articles, err := r.server.GetQueryHelper().GetArticles(ctx, 50)
+		if err != nil {
+			return FormatErrorResponse(StatusTemporaryFailure, fmt.Sprintf("Error loading articles: %v", err))
+		}
+		for _, article := range articles {
+			events = append(events, article.Event)
+		}
+		title = "Articles"
+
+	default:
+		return FormatErrorResponse(StatusNotFound, fmt.Sprintf("No feed for path: %s", path))
+	}
+
+	data, err := r.renderer.AtomFeed(ctx, fmt.Sprintf("%s - %s", siteTitle, title), r.geminiURL(path), r.geminiURL(AtomPath(path)), events,
+		func(event *nostr.Event) string {
+			return r.geminiURL("/note/" + event.ID)
+		})
+	if err != nil {
+		return FormatErrorResponse(StatusTemporaryFailure, fmt.Sprintf("Error encoding feed: %v", err))
+	}
+	return FormatAtomResponse(data)
+}
@@ -0,0 +1,83 @@
+package gemini
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"net"
+	"time"
+
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/nostr/helpers"
+	"github.com/sandwichfarm/nophr/internal/outbox"
+)
+
+// Client identifies the sender of a request by its TLS client certificate.
+// The zero value is an anonymous client.
+type Client struct {
+	Fingerprint string // hex SHA-256 of the certificate, "" if none was presented
+	Pubkey      string // hex pubkey the certificate maps to, "" if unmapped
+	Owner       bool   // the certificate maps to the configured identity
+	Expired     bool   // the certificate is outside its validity period
+}
+
+// Anonymous reports whether no client certificate was presented
+func (c Client) Anonymous() bool {
+	return c.Fingerprint == ""
+}
+
+// loadClientCerts builds the fingerprint to pubkey map from config. Entries
+// with identity "owner" and fingerprints pinned in outbox.cert_fingerprints
+// map to the owner's pubkey.
+func loadClientCerts(certs []config.GeminiClientCert, pinned []string, owner string) map[string]string {
+	identities := make(map[string]string, len(certs)+len(pinned))
+	if owner != "" {
+		for _, fingerprint := range pinned {
+			identities[outbox.NormalizeFingerprint(fingerprint)] = owner
+		}
+	}
+	for _, cert := range certs {
+		pubkey := owner
+		if cert.Identity != "owner" {
+			hexKey, err := helpers.NormalizePubkey(cert.Identity)
+			if err != nil {
+				continue
+			}
+			pubkey = hexKey
+		}
+		if pubkey != "" {
+			identities[outbox.NormalizeFingerprint(cert.Fingerprint)] = pubkey
+		}
+	}
+	return identities
+}
+
+// identify maps the client certificate on a connection to an identity
+func (s *Server) identify(conn net.Conn) Client {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return Client{}
+	}
+
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return Client{}
+	}
+
+	return s.identifyCert(certs[0], time.Now())
+}
+
+// identifyCert maps a client certificate to an identity
+func (s *Server) identifyCert(cert *x509.Certificate, now time.Time) Client {
+	sum := sha256.Sum256(cert.Raw)
+	client := Client{
+		Fingerprint: hex.EncodeToString(sum[:]),
+		Expired:     now.Before(cert.NotBefore) || now.After(cert.NotAfter),
+	}
+
+	client.Pubkey = s.clientCerts[client.Fingerprint]
+	client.Owner = client.Pubkey != "" && client.Pubkey == s.ownerPubkey
+
+	return client
+}
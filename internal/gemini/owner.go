@@ -0,0 +1,181 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sandwichfarm/nophr/internal/cache"
+	"github.com/sandwichfarm/nophr/internal/ops"
+	"github.com/sandwichfarm/nophr/internal/sections"
+)
+
+// requireOwner returns a 6x response unless the client certificate maps to
+// the owner, or nil if the request may proceed
+func requireOwner(client Client) []byte {
+	switch {
+	case client.Anonymous():
+		return FormatErrorResponse(StatusClientCertRequired, "Client certificate required")
+	case client.Expired:
+		return FormatErrorResponse(StatusCertNotValid, "Certificate is expired or not yet valid")
+	case !client.Owner:
+		return FormatErrorResponse(StatusCertNotAuthorized, "Certificate not authorized")
+	}
+	return nil
+}
+
+// handleOwner handles the owner-only area:
+//
+//	/owner                    dashboard
+//	/owner/diagnostics        full diagnostics
+//	/owner/retention          retention statistics
+//	/owner/retention/prune    prune events now
+//	/owner/section/<name>     hidden section
+func (r *Router) handleOwner(ctx context.Context, parts []string, client Client) []byte {
+	if denied := requireOwner(client); denied != nil {
+		return denied
+	}
+
+	action := ""
+	if len(parts) > 0 {
+		action = parts[0]
+	}
+
+	switch action {
+	case "":
+		return r.handleOwnerHome(client)
+
+	case "diagnostics":
+		return r.handleOwnerDiagnostics(ctx)
+
+	case "retention":
+		if len(parts) > 1 && parts[1] == "prune" {
+			return r.handleOwnerPrune(ctx)
+		}
+		return r.handleOwnerRetention(ctx)
+
+	case "section":
+		if len(parts) < 2 || parts[1] == "" {
+			return FormatErrorResponse(StatusNotFound, "Missing section name")
+		}
+		section, err := r.server.GetSectionManager().GetHiddenSection(parts[1])
+		if err != nil {
+			return FormatErrorResponse(StatusNotFound, err.Error())
+		}
+		_, page := splitPagePath("/owner/" + strings.Join(parts, "/"))
+		return r.handleSections(ctx, []*sections.Section{section}, "/owner/section/"+section.Name, page)
+
+	default:
+		return FormatErrorResponse(StatusNotFound, fmt.Sprintf("Unknown owner path: %s", action))
+	}
+}
+
+// handleOwnerHome renders the owner dashboard
+func (r *Router) handleOwnerHome(client Client) []byte {
+	var sb strings.Builder
+
+	sb.WriteString("# Owner\n\n")
+	sb.WriteString(fmt.Sprintf("Certificate: %s\n\n", truncatePubkey(client.Fingerprint)))
+
+	sb.WriteString("## Manage\n\n")
+	sb.WriteString(fmt.Sprintf("=> %s Full Diagnostics\n", r.geminiURL("/owner/diagnostics")))
+	sb.WriteString(fmt.Sprintf("=> %s Retention\n", r.geminiURL("/owner/retention")))
+	if r.server.GetOutbox() != nil {
+		sb.WriteString(fmt.Sprintf("=> %s New Note\n", r.geminiURL("/compose")))
+		sb.WriteString(fmt.Sprintf("=> %s Drafts\n", r.geminiURL("/compose/drafts")))
+	}
+	sb.WriteString("\n")
+
+	if hidden := r.server.GetSectionManager().HiddenSections(); len(hidden) > 0 {
+		sb.WriteString("## Hidden Sections\n\n")
+		for _, section := range hidden {
+			label := section.Title
+			if label == "" {
+				label = section.Name
+			}
+			sb.WriteString(fmt.Sprintf("=> %s %s\n", r.geminiURL("/owner/section/"+section.Name), label))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("=> %s Back to Home\n", r.geminiURL("/")))
+
+	return FormatSuccessResponse(sb.String())
+}
+
+// handleOwnerDiagnostics renders full system, storage, sync and retention
+// diagnostics
+func (r *Router) handleOwnerDiagnostics(ctx context.Context) []byte {
+	collector := r.server.diagnostics
+	if collector == nil {
+		collector = ops.NewDiagnosticsCollector("", "", r.server.GetStorage(), nil)
+	}
+
+	diag, err := collector.CollectAll(ctx)
+	if err != nil {
+		return FormatErrorResponse(StatusTemporaryFailure, fmt.Sprintf("Error collecting diagnostics: %v", err))
+	}
+
+	gemtext := diag.FormatAsGemtext()
+	gemtext += "\n"
+	gemtext += fmt.Sprintf("=> %s Back to Owner\n", r.geminiURL("/owner"))
+
+	return FormatSuccessResponse(gemtext)
+}
+
+// handleOwnerRetention renders retention statistics with a prune control
+func (r *Router) handleOwnerRetention(ctx context.Context) []byte {
+	rm := r.server.retention
+	if rm == nil {
+		return FormatErrorResponse(StatusNotFound, "Retention controls are not available")
+	}
+
+	stats, err := rm.GetRetentionStats(ctx)
+	if err != nil {
+		return FormatErrorResponse(StatusTemporaryFailure, fmt.Sprintf("Error loading retention stats: %v", err))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Retention\n\n")
+	sb.WriteString(fmt.Sprintf("* Keep Days: %d\n", stats.KeepDays))
+	sb.WriteString(fmt.Sprintf("* Prune on Start: %v\n", stats.PruneOnStart))
+	sb.WriteString(fmt.Sprintf("* Total Events: %d\n", stats.TotalEvents))
+	if !stats.OldestEvent.IsZero() {
+		sb.WriteString(fmt.Sprintf("* Oldest Event: %s\n", stats.OldestEvent.Format(time.RFC3339)))
+		sb.WriteString(fmt.Sprintf("* Newest Event: %s\n", stats.NewestEvent.Format(time.RFC3339)))
+	}
+	sb.WriteString(fmt.Sprintf("* Cutoff: %s\n", stats.Cutoff.Format(time.RFC3339)))
+	sb.WriteString("\n")
+
+	sb.WriteString(fmt.Sprintf("=> %s Prune Now\n", r.geminiURL("/owner/retention/prune")))
+	sb.WriteString(fmt.Sprintf("=> %s Back to Owner\n", r.geminiURL("/owner")))
+
+	return FormatSuccessResponse(sb.String())
+}
+
+// handleOwnerPrune prunes events past retention and clears the response cache
+func (r *Router) handleOwnerPrune(ctx context.Context) []byte {
+	rm := r.server.retention
+	if rm == nil {
+		return FormatErrorResponse(StatusNotFound, "Retention controls are not available")
+	}
+
+	deleted, err := rm.PruneOldEvents(ctx)
+	if err != nil {
+		return FormatErrorResponse(StatusTemporaryFailure, fmt.Sprintf("Pruning failed: %v", err))
+	}
+
+	if c := r.server.GetCache(); c != nil && deleted > 0 {
+		if err := cache.NewInvalidator(c).InvalidateAll(ctx); err != nil {
+			fmt.Printf("Cache invalidation error: %v\n", err)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Pruning Complete\n\n")
+	sb.WriteString(fmt.Sprintf("Deleted %d events.\n\n", deleted))
+	sb.WriteString(fmt.Sprintf("=> %s Back to Retention\n", r.geminiURL("/owner/retention")))
+
+	return FormatSuccessResponse(sb.String())
+}
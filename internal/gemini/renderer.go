@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nbd-wtf/go-nostr"
@@ -20,6 +21,7 @@ import (
 // Renderer renders Nostr events as Gemtext
 type Renderer struct {
 	parser   *markdown.Parser
+	mu       sync.RWMutex
 	config   *config.Config
 	loader   *presentation.Loader
 	resolver *entities.Resolver
@@ -37,6 +39,22 @@ func NewRenderer(cfg *config.Config, st *storage.Storage) *Renderer {
 	}
 }
 
+// SetConfig switches rendering to a new configuration, including the headers
+// and footers it defines
+func (r *Renderer) SetConfig(cfg *config.Config) {
+	r.mu.Lock()
+	r.config = cfg
+	r.mu.Unlock()
+	r.loader.SetConfig(cfg)
+}
+
+// settings returns the configuration pages are currently rendered with
+func (r *Renderer) settings() *config.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config
+}
+
 // RenderHome renders the home page
 func (r *Renderer) RenderHome() string {
 	var sb strings.Builder
@@ -51,6 +69,10 @@ func (r *Renderer) RenderHome() string {
 	sb.WriteString("=> /search Search\n")
 	sb.WriteString("=> /diagnostics Diagnostics\n")
 	sb.WriteString("\n")
+	sb.WriteString("## Feeds\n\n")
+	sb.WriteString(fmt.Sprintf("=> %s Notes (Atom)\n", AtomPath("/notes")))
+	sb.WriteString(fmt.Sprintf("=> %s Articles (Atom)\n", AtomPath("/articles")))
+	sb.WriteString("\n")
 	sb.WriteString("Powered by nophr\n")
 
 	return r.applyHeadersFooters(sb.String(), "home")
@@ -64,12 +86,16 @@ func (r *Renderer) RenderNote(event *nostr.Event, agg *aggregates.EventAggregate
 	sb.WriteString(fmt.Sprintf("# Note by %s\n", truncatePubkey(event.PubKey)))
 	sb.WriteString(fmt.Sprintf("Posted: %s\n\n", formatTimestamp(event.CreatedAt)))
 
+	if warning := nostrclient.RevealedWarning(event); warning != "" {
+		sb.WriteString("> " + warning + "\n\n")
+	}
+
 	// Content (resolve NIP-19 entities, then render markdown as gemtext)
 	content := event.Content
 	content = r.resolver.ReplaceEntities(context.Background(), content, entities.PlainTextFormatter)
 
 	rendered, _ := r.parser.RenderGemini([]byte(content), r.geminiRenderOptions())
-	rendered = clampWidth(rendered, r.config.Rendering.Gemini.MaxLineLength)
+	rendered = clampWidth(rendered, r.settings().Rendering.Gemini.MaxLineLength)
 	sb.WriteString(rendered)
 	sb.WriteString("\n")
 
@@ -78,6 +104,9 @@ func (r *Renderer) RenderNote(event *nostr.Event, agg *aggregates.EventAggregate
 		sb.WriteString("## Interactions\n\n")
 		sb.WriteString(r.renderAggregates(agg))
 		sb.WriteString("\n")
+		if r.settings().Display.Detail.ShowZaps && len(agg.Zappers) > 0 {
+			sb.WriteString(r.renderZappers(agg.Zappers))
+		}
 	}
 
 	// Navigation
@@ -92,7 +121,7 @@ func (r *Renderer) RenderNote(event *nostr.Event, agg *aggregates.EventAggregate
 func (r *Renderer) RenderNoteWithThread(event *nostr.Event, agg *aggregates.EventAggregates, thread *aggregates.ThreadView, threadURL, homeURL string) string {
 	base := r.RenderNote(event, agg, threadURL, homeURL)
 
-	if thread == nil || !r.config.Display.Detail.ShowThread {
+	if thread == nil || !r.settings().Display.Detail.ShowThread {
 		return base
 	}
 
@@ -104,8 +133,39 @@ func (r *Renderer) RenderNoteWithThread(event *nostr.Event, agg *aggregates.Even
 	return sb.String()
 }
 
-// RenderProfile renders a profile event
-func (r *Renderer) RenderProfile(profileEvent *nostr.Event, homeURL string) string {
+// RenderProfileZaps renders the zaps of a profile (not of its notes)
+func (r *Renderer) RenderProfileZaps(summary *aggregates.ZapSummary) string {
+	if summary == nil || summary.Count == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Zaps\n\n")
+	sb.WriteString(fmt.Sprintf("%s from %d zaps\n\n", aggregates.FormatSats(summary.TotalSats), summary.Count))
+	sb.WriteString(r.renderZappers(summary.Top(aggregates.TopZappers)))
+	return sb.String()
+}
+
+// renderZappers renders zappers with their totals and latest comments
+func (r *Renderer) renderZappers(zappers []*aggregates.Zapper) string {
+	var sb strings.Builder
+	sb.WriteString("### Top zappers\n\n")
+	for _, zapper := range zappers {
+		sb.WriteString(fmt.Sprintf("* %s - %s", r.resolver.DisplayName(context.Background(), zapper.Pubkey), aggregates.FormatSats(zapper.Sats)))
+		if zapper.Count > 1 {
+			sb.WriteString(fmt.Sprintf(" (%d zaps)", zapper.Count))
+		}
+		sb.WriteString("\n")
+		if zapper.Comment != "" {
+			sb.WriteString(fmt.Sprintf("> %s\n", r.GetSummary(zapper.Comment, 80)))
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// RenderProfile renders a profile event with the profile's zaps, if any
+func (r *Renderer) RenderProfile(profileEvent *nostr.Event, zaps *aggregates.ZapSummary, homeURL string) string {
 	var sb strings.Builder
 
 	// Parse profile metadata
@@ -177,6 +237,8 @@ func (r *Renderer) RenderProfile(profileEvent *nostr.Event, homeURL string) stri
 		sb.WriteString("\n")
 	}
 
+	sb.WriteString(r.RenderProfileZaps(zaps))
+
 	// Navigation
 	sb.WriteString(fmt.Sprintf("=> %s Back to Home\n", homeURL))
 
@@ -193,7 +255,7 @@ func (r *Renderer) RenderThread(thread *aggregates.ThreadView, homeURL string) s
 
 	sb.WriteString(fmt.Sprintf("=> /note/%s Back to note\n\n", thread.FocusID))
 
-	maxDepth := r.config.Display.Limits.MaxThreadDepth
+	maxDepth := r.settings().Display.Limits.MaxThreadDepth
 	if maxDepth <= 0 {
 		maxDepth = 10
 	}
@@ -206,7 +268,8 @@ func (r *Renderer) RenderThread(thread *aggregates.ThreadView, homeURL string) s
 	return sb.String()
 }
 
-// RenderNoteList renders a list of notes with summaries
+// RenderNoteList renders a list of notes as a gemsub feed: a level-1
+// heading, then one dated link per note
 func (r *Renderer) RenderNoteList(notes []*aggregates.EnrichedEvent, title, homeURL string) string {
 	var sb strings.Builder
 
@@ -230,11 +293,8 @@ func (r *Renderer) RenderNoteList(notes []*aggregates.EnrichedEvent, title, home
 		return r.applyHeadersFooters(sb.String(), pageName)
 	}
 
-	for i, note := range notes {
-		entryTitle := r.titleForEvent(note.Event)
-		ts := formatTimestamp(note.Event.CreatedAt)
-		linkText := fmt.Sprintf("%d. %s — %s", i+1, entryTitle, ts)
-		sb.WriteString(fmt.Sprintf("=> /note/%s %s\n", note.Event.ID, linkText))
+	for _, note := range notes {
+		sb.WriteString(GemsubLink("/note/"+note.Event.ID, note.Event.CreatedAt.Time(), r.titleForEvent(note.Event)))
 
 		if note.Aggregates != nil && note.Aggregates.HasInteractions() {
 			if agg := strings.TrimSpace(r.renderAggregates(note.Aggregates)); agg != "" {
@@ -251,15 +311,15 @@ func (r *Renderer) RenderNoteList(notes []*aggregates.EnrichedEvent, title, home
 
 // renderAggregates renders interaction stats (for feed view)
 func (r *Renderer) renderAggregates(agg *aggregates.EventAggregates) string {
-	if !r.config.Display.Feed.ShowInteractions {
+	if !r.settings().Display.Feed.ShowInteractions {
 		return ""
 	}
-	return r.buildAggregatesString(agg, r.config.Display.Feed.ShowReplies, r.config.Display.Feed.ShowReactions, r.config.Display.Feed.ShowZaps)
+	return r.buildAggregatesString(agg, r.settings().Display.Feed.ShowReplies, r.settings().Display.Feed.ShowReactions, r.settings().Display.Feed.ShowZaps)
 }
 
 // renderAggregatesForDetail renders interaction stats for detail view
 func (r *Renderer) renderAggregatesForDetail(agg *aggregates.EventAggregates) string {
-	return r.buildAggregatesString(agg, r.config.Display.Detail.ShowReplies, r.config.Display.Detail.ShowReactions, r.config.Display.Detail.ShowZaps)
+	return r.buildAggregatesString(agg, r.settings().Display.Detail.ShowReplies, r.settings().Display.Detail.ShowReactions, r.settings().Display.Detail.ShowZaps)
 }
 
 // buildAggregatesString builds the aggregates string based on what should be shown
@@ -368,6 +428,15 @@ func (r *Renderer) applyHeadersFooters(content, page string) string {
 	return sb.String()
 }
 
+// EventSummary returns GetSummary of an event's content, or its content
+// warning in place of sensitive content
+func (r *Renderer) EventSummary(event *nostr.Event, maxLen int) string {
+	if label, ok := nostrclient.ContentWarningLabel(event); ok {
+		return label
+	}
+	return r.GetSummary(event.Content, maxLen)
+}
+
 // GetSummary creates a summary of content for display
 func (r *Renderer) GetSummary(content string, maxLen int) string {
 	// Remove newlines
@@ -387,8 +456,8 @@ func (r *Renderer) GetSummary(content string, maxLen int) string {
 
 func (r *Renderer) geminiRenderOptions() *markdown.RenderOptions {
 	opts := markdown.DefaultGeminiOptions()
-	if r.config.Rendering.Gemini.MaxLineLength > 0 {
-		opts.Width = r.config.Rendering.Gemini.MaxLineLength
+	if r.settings().Rendering.Gemini.MaxLineLength > 0 {
+		opts.Width = r.settings().Rendering.Gemini.MaxLineLength
 	}
 	return opts
 }
@@ -498,6 +567,11 @@ func wrapLineToWidth(text string, width int) []string {
 }
 
 func (r *Renderer) titleForEvent(event *nostr.Event) string {
+	// Collapse sensitive content to its warning; the note page reveals it
+	if label, ok := nostrclient.ContentWarningLabel(event); ok {
+		return label
+	}
+
 	// Prefer explicit title tag for long-form
 	if event.Kind == 30023 {
 		if title := titleFromTags(event); title != "" {
@@ -549,7 +623,7 @@ func (r *Renderer) renderThreadNode(sb *strings.Builder, node *aggregates.Thread
 		markers = append(markers, "you are here")
 	}
 
-	summary := r.threadSummary(node.Event.Content)
+	summary := r.threadSummary(node.Event)
 	line := fmt.Sprintf("%s* %s (%s)", prefix, summary, formatTimestamp(node.Event.CreatedAt))
 	if len(markers) > 0 {
 		line = fmt.Sprintf("%s [%s]", line, strings.Join(markers, ", "))
@@ -564,26 +638,32 @@ func (r *Renderer) renderThreadNode(sb *strings.Builder, node *aggregates.Thread
 }
 
 func (r *Renderer) threadIndent() string {
-	indent := r.config.Rendering.Gopher.ThreadIndent
+	indent := r.settings().Rendering.Gopher.ThreadIndent
 	if indent == "" {
 		return "  "
 	}
 	return indent
 }
 
-func (r *Renderer) threadSummary(content string) string {
-	limit := r.config.Display.Limits.SummaryLength
+// threadSummary returns one line of an event's content for thread trees, or
+// its content warning in place of sensitive content
+func (r *Renderer) threadSummary(event *nostr.Event) string {
+	if label, ok := nostrclient.ContentWarningLabel(event); ok {
+		return label
+	}
+
+	limit := r.settings().Display.Limits.SummaryLength
 	if limit <= 0 {
 		limit = 100
 	}
 
-	plain := strings.ReplaceAll(content, "\n", " ")
+	plain := strings.ReplaceAll(event.Content, "\n", " ")
 	plain = strings.TrimSpace(plain)
 	if len(plain) <= limit {
 		return plain
 	}
 
-	indicator := r.config.Display.Limits.TruncateIndicator
+	indicator := r.settings().Display.Limits.TruncateIndicator
 	if indicator == "" {
 		indicator = "..."
 	}
@@ -646,8 +726,8 @@ func displayNameWithAt(entity *entities.Entity) string {
 }
 
 func (r *Renderer) portalBases() []string {
-	if len(r.config.Rendering.Portals) > 0 {
-		return r.config.Rendering.Portals
+	if len(r.settings().Rendering.Portals) > 0 {
+		return r.settings().Rendering.Portals
 	}
 	return []string{"https://njump.me", "https://nostr.at", "https://nostr.eu"}
 }
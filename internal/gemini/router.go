@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/sandwichfarm/nophr/internal/aggregates"
+	nostrclient "github.com/sandwichfarm/nophr/internal/nostr"
 	"github.com/sandwichfarm/nophr/internal/sections"
 )
 
@@ -29,8 +31,9 @@ func NewRouter(server *Server, host string, port int) *Router {
 	}
 }
 
-// Route routes a URL to the appropriate handler
-func (r *Router) Route(u *url.URL) []byte {
+// Route routes a URL to the appropriate handler. client identifies the
+// request's client certificate; the zero value is anonymous.
+func (r *Router) Route(u *url.URL, client Client) []byte {
 	ctx := context.Background()
 
 	// Extract path
@@ -39,14 +42,29 @@ func (r *Router) Route(u *url.URL) []byte {
 		path = "/"
 	}
 
+	// Atom variants of list pages: /notes/atom.xml, <section path>/atom.xml
+	if strings.HasSuffix(path, "/"+AtomFeedName) {
+		base := strings.TrimSuffix(path, "/"+AtomFeedName)
+		if base == "" {
+			base = "/"
+		}
+		return r.handleAtomFeed(ctx, base)
+	}
+
 	// Check if sections are registered for this path (sections override defaults)
 	if r.server.GetSectionManager() != nil {
-		sectionsList := r.server.GetSectionManager().GetSectionsByPath(path)
-		if len(sectionsList) > 0 {
-			return r.handleSections(ctx, sectionsList, path, u.Query())
+		base, page := splitPagePath(path)
+		sectionsList := r.server.GetSectionManager().GetSectionsByPath(base)
+		if len(sectionsList) == 1 || (len(sectionsList) > 1 && page == 1) {
+			return r.handleSections(ctx, sectionsList, base, page)
 		}
 	}
 
+	// Section archives: /<section>/archive/...
+	if r.server.GetSectionManager() != nil && sections.IsArchivePath(path) {
+		return r.handleArchive(ctx, path)
+	}
+
 	// Parse path
 	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
 	if len(parts) == 0 || parts[0] == "" {
@@ -89,6 +107,12 @@ func (r *Router) Route(u *url.URL) []byte {
 	case "search":
 		return r.handleSearch(ctx, u.Query())
 
+	case "compose":
+		return r.handleCompose(ctx, parts[1:], u.RawQuery, client)
+
+	case "owner":
+		return r.handleOwner(ctx, parts[1:], client)
+
 	case "diagnostics":
 		return r.handleDiagnostics(ctx)
 
@@ -198,7 +222,7 @@ func (r *Router) handleMentions(ctx context.Context, parts []string, query url.V
 // handleNote handles displaying a single note
 func (r *Router) handleNote(ctx context.Context, noteID string) []byte {
 	// Query the note
-	events, err := r.server.GetStorage().QueryEvents(ctx, nostr.Filter{
+	events, err := r.server.queryHelper.QueryEvents(ctx, nostr.Filter{
 		IDs: []string{noteID},
 	})
 	if err != nil || len(events) == 0 {
@@ -219,6 +243,11 @@ func (r *Router) handleNote(ctx context.Context, noteID string) []byte {
 			ZapSatsTotal:    aggData.ZapSatsTotal,
 			LastInteraction: aggData.LastInteractionAt,
 		}
+
+		// Top zappers with their comments
+		if summary, err := r.server.GetQueryHelper().GetZapSummary(ctx, noteID); err == nil {
+			agg.Zappers = summary.Top(aggregates.TopZappers)
+		}
 	}
 
 	// Build thread view (includes replies and navigation)
@@ -229,6 +258,18 @@ func (r *Router) handleNote(ctx context.Context, noteID string) []byte {
 
 	// Render the note
 	gemtext := r.renderer.RenderNoteWithThread(note, agg, threadView, r.geminiURL("/thread/"+noteID), r.geminiURL("/"))
+
+	// Compose links (the compose routes check the client certificate)
+	if ob := r.server.GetOutbox(); ob != nil && (ob.Allowed(1) || ob.Allowed(7)) {
+		gemtext += "\n## Respond\n\n"
+		if ob.Allowed(1) {
+			gemtext += fmt.Sprintf("=> %s Reply\n", r.geminiURL("/compose/reply/"+noteID))
+		}
+		if ob.Allowed(7) {
+			gemtext += fmt.Sprintf("=> %s React\n", r.geminiURL("/compose/react/"+noteID))
+		}
+	}
+
 	return FormatSuccessResponse(gemtext)
 }
 
@@ -250,7 +291,7 @@ func (r *Router) handleThread(ctx context.Context, rootID string) []byte {
 // handleProfile handles displaying a profile
 func (r *Router) handleProfile(ctx context.Context, pubkey string) []byte {
 	// Query profile metadata (kind 0)
-	events, err := r.server.GetStorage().QueryEvents(ctx, nostr.Filter{
+	events, err := r.server.queryHelper.QueryEvents(ctx, nostr.Filter{
 		Kinds:   []int{0},
 		Authors: []string{pubkey},
 		Limit:   1,
@@ -262,7 +303,10 @@ func (r *Router) handleProfile(ctx context.Context, pubkey string) []byte {
 	profile := events[0]
 
 	// Render the profile
-	gemtext := r.renderer.RenderProfile(profile, r.geminiURL("/"))
+	// Profile zaps (errors only hide the zaps section)
+	zaps, _ := r.server.GetQueryHelper().GetProfileZapSummary(ctx, pubkey)
+
+	gemtext := r.renderer.RenderProfile(profile, zaps, r.geminiURL("/"))
 	return FormatSuccessResponse(gemtext)
 }
 
@@ -281,6 +325,7 @@ func (r *Router) handleSearch(ctx context.Context, query url.Values) []byte {
 		Kinds:  []int{0, 1, 30023}, // Profiles, notes, articles
 		Limit:  50,
 	})
+	events = r.server.events.Filter(events)
 
 	gemtext := "# Search Results\n\n"
 	gemtext += fmt.Sprintf("Query: \"%s\"\n\n", searchQuery)
@@ -309,13 +354,13 @@ func (r *Router) handleSearch(ctx context.Context, query url.Values) []byte {
 				truncatePubkey(event.PubKey))
 
 		case 1: // Note
-			summary := r.renderer.GetSummary(event.Content, 100)
+			summary := r.renderer.EventSummary(event, 100)
 			gemtext += fmt.Sprintf("=> %s [Note] %s\n",
 				r.geminiURL(fmt.Sprintf("/note/%s", event.ID)),
 				summary)
 
 		case 30023: // Article
-			summary := r.renderer.GetSummary(event.Content, 100)
+			summary := r.renderer.EventSummary(event, 100)
 			gemtext += fmt.Sprintf("=> %s [Article] %s\n",
 				r.geminiURL(fmt.Sprintf("/note/%s", event.ID)),
 				summary)
@@ -338,6 +383,19 @@ func (r *Router) handleDiagnostics(ctx context.Context) []byte {
 	gemtext += fmt.Sprintf("* Port: %d\n", r.port)
 	gemtext += "\n## Storage\n\n"
 	gemtext += "* Status: Connected\n"
+	gemtext += "\n## Cache\n\n"
+	if c := r.server.GetCache(); c != nil {
+		if stats, err := c.Stats(ctx); err == nil {
+			gemtext += fmt.Sprintf("* Hits: %d\n", stats.Hits)
+			gemtext += fmt.Sprintf("* Misses: %d\n", stats.Misses)
+			gemtext += fmt.Sprintf("* Hit Rate: %.1f%%\n", stats.HitRate*100)
+			gemtext += fmt.Sprintf("* Keys: %d\n", stats.Keys)
+		} else {
+			gemtext += fmt.Sprintf("* Status: error (%v)\n", err)
+		}
+	} else {
+		gemtext += "* Status: Disabled\n"
+	}
 	gemtext += "\n"
 	gemtext += fmt.Sprintf("=> %s Back to Home\n", r.geminiURL("/"))
 
@@ -352,58 +410,56 @@ func (r *Router) geminiURL(path string) string {
 	return fmt.Sprintf("gemini://%s:%d%s", r.host, r.port, path)
 }
 
-// handleSections renders multiple sections on a single page (e.g., homepage with multiple filtered views)
-func (r *Router) handleSections(ctx context.Context, sectionsList []*sections.Section, path string, query url.Values) []byte {
+// handleSections renders the sections registered on a path (e.g., a homepage
+// with multiple filtered views). A path with a single section is paginated
+// with /page/N.
+func (r *Router) handleSections(ctx context.Context, sectionsList []*sections.Section, path string, page int) []byte {
 	var gemtext strings.Builder
 
 	// Render each section in order
 	for i, section := range sectionsList {
-		// Get section page (always page 1 for multi-section views)
-		sectionPage, err := r.server.GetSectionManager().GetPage(ctx, section.Name, 1)
+		sectionPage, err := r.server.GetSectionManager().GetPage(ctx, section.Name, page)
 		if err != nil {
 			gemtext.WriteString(fmt.Sprintf("# Error loading section %s\n\n", section.Name))
 			gemtext.WriteString(fmt.Sprintf("Error: %v\n\n", err))
 			continue
 		}
 
-		// Section title and description
+		// Section title and description. A lone section's title is the
+		// page's level-1 heading, which names it as a gemsub feed.
 		if section.Title != "" {
-			gemtext.WriteString(fmt.Sprintf("## %s\n\n", section.Title))
+			heading := "##"
+			if len(sectionsList) == 1 {
+				heading = "#"
+			}
+			gemtext.WriteString(fmt.Sprintf("%s %s\n\n", heading, section.Title))
 		}
 		if section.Description != "" {
 			gemtext.WriteString(fmt.Sprintf("%s\n\n", section.Description))
 		}
 
-		// Render events from section
-		if len(sectionPage.Events) > 0 {
-			for _, event := range sectionPage.Events {
-				// Extract first line for display
-				content := event.Content
-				if len(content) > 80 {
-					content = content[:77] + "..."
-				}
-				firstLine := strings.Split(content, "\n")[0]
-
-				linkText := firstLine
-
-				// Add author and timestamp if configured
-				if section.ShowAuthors && section.ShowDates {
-					gemtext.WriteString(fmt.Sprintf("%s - %s\n",
-						truncatePubkey(event.PubKey),
-						formatTimestamp(event.CreatedAt)))
-				} else if section.ShowAuthors {
-					gemtext.WriteString(fmt.Sprintf("%s\n", truncatePubkey(event.PubKey)))
-				} else if section.ShowDates {
-					gemtext.WriteString(fmt.Sprintf("%s\n", formatTimestamp(event.CreatedAt)))
-				}
-
-				// Add the clickable link
-				gemtext.WriteString(fmt.Sprintf("=> %s %s\n\n", r.geminiURL(fmt.Sprintf("/note/%s", event.ID)), linkText))
+		// Render events from section, under group headings if configured
+		if len(sectionPage.Groups) > 0 {
+			for _, group := range sectionPage.Groups {
+				gemtext.WriteString(fmt.Sprintf("### %s\n\n", r.groupTitle(ctx, section, group)))
+				r.writeSectionEvents(&gemtext, section, group.Events)
 			}
+		} else if len(sectionPage.Events) > 0 {
+			r.writeSectionEvents(&gemtext, section, sectionPage.Events)
 		} else {
 			gemtext.WriteString("No content yet.\n\n")
 		}
 
+		if len(sectionsList) == 1 && (sectionPage.HasPrev || sectionPage.HasNext) {
+			if sectionPage.HasPrev {
+				gemtext.WriteString(fmt.Sprintf("=> %s ← Previous Page\n", r.geminiURL(sectionPagePath(path, sectionPage.PageNumber-1))))
+			}
+			if sectionPage.HasNext {
+				gemtext.WriteString(fmt.Sprintf("=> %s → Next Page\n", r.geminiURL(sectionPagePath(path, sectionPage.PageNumber+1))))
+			}
+			gemtext.WriteString(fmt.Sprintf("Page %d\n\n", sectionPage.PageNumber))
+		}
+
 		// Add "more" link if configured
 		if section.MoreLink != nil {
 			targetSection, err := r.server.GetSectionManager().GetSection(section.MoreLink.SectionRef)
@@ -412,14 +468,87 @@ func (r *Router) handleSections(ctx context.Context, sectionsList []*sections.Se
 			}
 		}
 
+		gemtext.WriteString(fmt.Sprintf("=> %s %s archive\n\n", r.geminiURL(sections.ArchiveSelector(section.Name)), sectionLabel(section)))
+
 		// Add separator between sections (except after last)
 		if i < len(sectionsList)-1 {
 			gemtext.WriteString("---\n\n")
 		}
 	}
 
-	// Add home link at bottom
-	gemtext.WriteString("\n=> / ⌂ Home\n")
+	// Atom variant and home link at bottom
+	gemtext.WriteString(fmt.Sprintf("\n=> %s Atom feed\n", r.geminiURL(AtomPath(path))))
+	gemtext.WriteString("=> / ⌂ Home\n")
 
 	return FormatSuccessResponse(gemtext.String())
 }
+
+// writeSectionEvents writes a section's events as dated gemsub links, with
+// the author and date lines the section is configured to show
+func (r *Router) writeSectionEvents(gemtext *strings.Builder, section *sections.Section, events []*nostr.Event) {
+	for _, event := range events {
+		// Extract first line for display
+		content := event.Content
+		if len(content) > 80 {
+			content = content[:77] + "..."
+		}
+		linkText := strings.Split(content, "\n")[0]
+		if label, ok := nostrclient.ContentWarningLabel(event); ok {
+			linkText = label
+		}
+
+		if section.ShowAuthors && section.ShowDates {
+			gemtext.WriteString(fmt.Sprintf("%s - %s\n",
+				truncatePubkey(event.PubKey),
+				formatTimestamp(event.CreatedAt)))
+		} else if section.ShowAuthors {
+			gemtext.WriteString(fmt.Sprintf("%s\n", truncatePubkey(event.PubKey)))
+		} else if section.ShowDates {
+			gemtext.WriteString(fmt.Sprintf("%s\n", formatTimestamp(event.CreatedAt)))
+		}
+
+		gemtext.WriteString(GemsubLink(r.geminiURL(fmt.Sprintf("/note/%s", event.ID)), event.CreatedAt.Time(), linkText))
+		gemtext.WriteString("\n")
+	}
+}
+
+// groupTitle returns the heading of an event group: the group's date or
+// kind name, or the author's profile name
+func (r *Router) groupTitle(ctx context.Context, section *sections.Section, group *sections.EventGroup) string {
+	title := group.Title
+	if section.GroupBy == sections.GroupByAuthor {
+		title = r.renderer.resolver.DisplayName(ctx, group.Key)
+	}
+	if group.Continued {
+		title += " (continued)"
+	}
+	return title
+}
+
+// splitPagePath splits a trailing /page/N off a path, returning the base
+// path and page number (1 when there is none)
+func splitPagePath(path string) (string, int) {
+	idx := strings.LastIndex(path, "/page/")
+	if idx < 0 {
+		return path, 1
+	}
+
+	page, err := strconv.Atoi(path[idx+len("/page/"):])
+	if err != nil || page < 1 {
+		return path, 1
+	}
+
+	base := path[:idx]
+	if base == "" {
+		base = "/"
+	}
+	return base, page
+}
+
+// sectionPagePath returns the path of a page of a section path
+func sectionPagePath(path string, page int) string {
+	if page <= 1 {
+		return path
+	}
+	return fmt.Sprintf("%s/page/%d", strings.TrimSuffix(path, "/"), page)
+}
@@ -12,14 +12,21 @@ import (
 	"time"
 
 	"github.com/sandwichfarm/nophr/internal/aggregates"
+	"github.com/sandwichfarm/nophr/internal/cache"
 	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/nostr/helpers"
+	"github.com/sandwichfarm/nophr/internal/ops"
+	"github.com/sandwichfarm/nophr/internal/outbox"
 	"github.com/sandwichfarm/nophr/internal/sections"
+	"github.com/sandwichfarm/nophr/internal/security"
 	"github.com/sandwichfarm/nophr/internal/storage"
 )
 
 // Server implements a Gemini protocol server
 type Server struct {
 	config         *config.GeminiProtocol
+	reloadMu       sync.RWMutex // held for reading while a request is served
+	mu             sync.RWMutex
 	fullConfig     *config.Config
 	storage        *storage.Storage
 	router         *Router
@@ -27,6 +34,16 @@ type Server struct {
 	queryHelper    *aggregates.QueryHelper
 	sectionManager *sections.Manager
 	tlsConfig      *tls.Config
+	guard          *security.Guard
+	events         *aggregates.EventFilter
+	cache          cache.Cache // nil = rendered responses are not cached
+	cacheTTLs      *cache.RenderTTLs
+	metrics        *ops.Metrics   // nil = requests are not counted
+	outbox         *outbox.Outbox // nil = composing is disabled
+	diagnostics    *ops.DiagnosticsCollector
+	retention      *ops.RetentionManager
+	ownerPubkey    string            // hex
+	clientCerts    map[string]string // certificate fingerprint -> hex pubkey
 
 	listener net.Listener
 	wg       sync.WaitGroup
@@ -46,14 +63,28 @@ func New(cfg *config.GeminiProtocol, fullCfg *config.Config, st *storage.Storage
 		ctx:         ctx,
 		cancel:      cancel,
 		queryHelper: aggregates.NewQueryHelper(st, fullCfg, aggMgr),
+		guard:       security.NewGuard(&fullCfg.Security),
 	}
 
+	// Hide events from denied pubkeys, with banned content or muted by the
+	// owner everywhere
+	s.events = aggregates.NewEventFilter(s.guard, aggregates.NewMuteFilter(st, fullCfg))
+	s.queryHelper.SetEventFilter(s.events.Filter)
+
+	// Map pinned client certificates to identities
+	if owner, err := helpers.NormalizePubkey(fullCfg.Identity.Npub); err == nil {
+		s.ownerPubkey = owner
+	}
+	s.clientCerts = loadClientCerts(cfg.ClientCerts, fullCfg.Outbox.CertFingerprints, s.ownerPubkey)
+
 	// Initialize sections manager (opt-in for custom filtered views)
 	s.sectionManager = sections.NewManager(st, fullCfg.Identity.Npub)
+	s.sectionManager.SetEventFilter(s.queryHelper.FilterFeedEvents)
 
 	// Initialize TLS configuration
 	if err := s.initTLS(); err != nil {
 		cancel()
+		s.guard.Close()
 		return nil, fmt.Errorf("failed to initialize TLS: %w", err)
 	}
 
@@ -96,6 +127,7 @@ func (s *Server) Stop() error {
 	}
 
 	s.wg.Wait()
+	s.guard.Close()
 	return nil
 }
 
@@ -126,6 +158,18 @@ func (s *Server) handleConnection(conn net.Conn) {
 	defer s.wg.Done()
 	defer conn.Close()
 
+	clientIP := security.ClientIP(conn.RemoteAddr())
+	if err := s.guard.AcquireConnection(clientIP); err != nil {
+		fmt.Printf("Gemini connection refused for %s: %v\n", clientIP, err)
+		if err == security.ErrTooManyConnections {
+			s.sendResponse(conn, StatusServerUnavailable, "Server busy, try again later", "")
+		} else {
+			s.sendResponse(conn, StatusSlowDown, fmt.Sprintf("%d", connectionRetrySeconds), "")
+		}
+		return
+	}
+	defer s.guard.ReleaseConnection(clientIP)
+
 	// Set read timeout
 	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
 
@@ -160,10 +204,19 @@ func (s *Server) handleConnection(conn net.Conn) {
 	}
 
 	// Log request
+	start := time.Now()
 	fmt.Printf("Gemini request: %s from %s\n", request, conn.RemoteAddr())
 
+	if ok, retryAfter := s.guard.AllowRequest(security.ProtocolGemini, clientIP); !ok {
+		fmt.Printf("Gemini request rate limited for %s\n", clientIP)
+		s.sendResponse(conn, StatusSlowDown, fmt.Sprintf("%d", security.RetrySeconds(retryAfter)), "")
+		s.metrics.ObserveRequest(security.ProtocolGemini, fmt.Sprintf("%d", StatusSlowDown), time.Since(start))
+		return
+	}
+
 	// Route request
-	response := s.router.Route(parsedURL)
+	response := s.route(parsedURL, s.identify(conn))
+	s.metrics.ObserveRequest(security.ProtocolGemini, responseStatus(response), time.Since(start))
 
 	// Write response
 	conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
@@ -173,12 +226,67 @@ func (s *Server) handleConnection(conn net.Conn) {
 	}
 }
 
+// route renders a request URL, serving and storing it in the response cache
+// when one is configured
+func (s *Server) route(u *url.URL, client Client) []byte {
+	s.reloadMu.RLock()
+	defer s.reloadMu.RUnlock()
+
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	if s.cache == nil || !cacheable(path) {
+		return s.router.Route(u, client)
+	}
+
+	key := cache.GeminiKey(path, u.RawQuery)
+
+	if data, hit, err := s.cache.Get(s.ctx, key); err == nil && hit {
+		return data
+	}
+
+	response := s.router.Route(u, client)
+
+	// Only success and input responses are cached; failures may be transient
+	if ttl := s.cacheTTLs.Gemini(path); ttl > 0 && len(response) > 0 && (response[0] == '1' || response[0] == '2') {
+		if err := s.cache.Set(s.ctx, key, response, ttl); err != nil {
+			fmt.Printf("Cache set error: %v\n", err)
+		}
+	}
+
+	return response
+}
+
+// cacheable reports whether responses for path are the same for every client
+func cacheable(path string) bool {
+	for _, prefix := range []string{"/diagnostics", "/compose", "/owner"} {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// connectionRetrySeconds is the SLOW DOWN delay sent when a client IP holds
+// too many concurrent connections
+const connectionRetrySeconds = 5
+
 // sendResponse sends a Gemini response
 func (s *Server) sendResponse(conn net.Conn, status Status, meta string, body string) {
 	response := FormatResponse(status, meta, body)
 	conn.Write(response)
 }
 
+// responseStatus returns the two-digit status code of a response
+func responseStatus(response []byte) string {
+	if len(response) < 2 {
+		return "unknown"
+	}
+	return string(response[:2])
+}
+
 // GetStorage returns the storage instance
 func (s *Server) GetStorage() *storage.Storage {
 	return s.storage
@@ -199,7 +307,88 @@ func (s *Server) GetQueryHelper() *aggregates.QueryHelper {
 	return s.queryHelper
 }
 
+// SetCache enables response caching with TTLs from the caching config
+func (s *Server) SetCache(c cache.Cache) {
+	s.cache = c
+	s.cacheTTLs = cache.NewRenderTTLs(&s.settings().Caching)
+}
+
+// SetMetrics enables request counters and latency histograms
+func (s *Server) SetMetrics(m *ops.Metrics) {
+	s.metrics = m
+}
+
+// GetCache returns the response cache, or nil if caching is disabled
+func (s *Server) GetCache() cache.Cache {
+	return s.cache
+}
+
+// SetGuard replaces the server's own guard with one shared by every
+// server, so connection caps count connections to all of them together
+func (s *Server) SetGuard(g *security.Guard) {
+	s.guard.Close()
+	s.guard = g
+	s.events.SetGuard(g)
+}
+
+// GetGuard returns the security guard
+func (s *Server) GetGuard() *security.Guard {
+	return s.guard
+}
+
+// SetOutbox enables the /compose routes for pinned client certificates
+func (s *Server) SetOutbox(o *outbox.Outbox) {
+	s.outbox = o
+}
+
+// GetOutbox returns the outbox, or nil if composing is disabled
+func (s *Server) GetOutbox() *outbox.Outbox {
+	return s.outbox
+}
+
+// SetDiagnostics enables full diagnostics in the owner area
+func (s *Server) SetDiagnostics(d *ops.DiagnosticsCollector) {
+	s.diagnostics = d
+}
+
+// SetRetentionManager enables retention controls in the owner area
+func (s *Server) SetRetentionManager(rm *ops.RetentionManager) {
+	s.retention = rm
+}
+
 // GetSectionManager returns the section manager instance
 func (s *Server) GetSectionManager() *sections.Manager {
 	return s.sectionManager
 }
+
+// PrepareReload converts the sections of a new configuration and returns a
+// function that switches the server to it: sections, display, presentation
+// and rendering settings. Listener and security settings keep their startup
+// values. The switch cannot fail and waits for requests in flight, so every
+// request is served entirely with the old settings or the new ones.
+func (s *Server) PrepareReload(cfg *config.Config) (func(), error) {
+	list, err := sections.FromConfig(cfg.Sections)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		s.reloadMu.Lock()
+		defer s.reloadMu.Unlock()
+
+		s.sectionManager.ReplaceSections(list)
+		s.router.renderer.SetConfig(cfg)
+		s.queryHelper.SetConfig(cfg)
+
+		s.mu.Lock()
+		s.fullConfig = cfg
+		s.mu.Unlock()
+	}, nil
+}
+
+// settings returns the configuration the server currently follows
+func (s *Server) settings() *config.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.fullConfig
+}
@@ -3,18 +3,34 @@ package gemini
 import (
 	"bufio"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"flag"
 	"fmt"
+	"math/big"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
 	"github.com/sandwichfarm/nophr/internal/aggregates"
 	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/ops"
+	"github.com/sandwichfarm/nophr/internal/outbox"
+	"github.com/sandwichfarm/nophr/internal/sections"
 	"github.com/sandwichfarm/nophr/internal/storage"
+	"github.com/sandwichfarm/nophr/internal/storage/storagetest"
 )
 
 func TestGeminiProtocol(t *testing.T) {
@@ -265,6 +281,29 @@ func TestRendererOutput(t *testing.T) {
 			t.Errorf("Empty note list should say 'No notes yet'")
 		}
 	})
+	// Sensitive notes collapse to their warning in lists; the note page reveals them
+	t.Run("ContentWarning", func(t *testing.T) {
+		note := &nostr.Event{
+			ID:        "cw1",
+			Kind:      1,
+			CreatedAt: nostr.Now(),
+			Tags:      nostr.Tags{{"content-warning", "spoilers"}},
+			Content:   "the butler did it",
+		}
+
+		gemtext := renderer.RenderNoteList([]*aggregates.EnrichedEvent{{Event: note}}, "Notes", "/")
+		if !strings.Contains(gemtext, "=> /note/cw1 "+note.CreatedAt.Time().UTC().Format("2006-01-02")+" - CW: spoilers") {
+			t.Errorf("Note list should link the warning, got:\n%s", gemtext)
+		}
+		if strings.Contains(gemtext, "butler") {
+			t.Errorf("Note list should hide sensitive content")
+		}
+
+		page := renderer.RenderNote(note, nil, "/thread/cw1", "/")
+		if !strings.Contains(page, "> ⚠ CW: spoilers") || !strings.Contains(page, "the butler did it") {
+			t.Errorf("Note page should show the warning and the content, got:\n%s", page)
+		}
+	})
 }
 
 func TestGenerateSelfSignedCertFallsBackOnPersistError(t *testing.T) {
@@ -298,12 +337,364 @@ func TestGenerateSelfSignedCertFallsBackOnPersistError(t *testing.T) {
 	}
 }
 
+func TestGeminiRateLimit(t *testing.T) {
+	cfg := &config.Config{
+		Identity: config.Identity{
+			Npub: "npub1nq3zgtqruwhnz0xx40gh4a4fkamlr2sc7ke5wqs2s3nyv2fpy9esg4hdwq",
+		},
+		Storage: config.Storage{
+			Driver:     "sqlite",
+			SQLitePath: ":memory:",
+		},
+		Security: config.Security{
+			Enabled:    true,
+			RateLimits: config.SecurityRateLimits{Gemini: 1},
+		},
+	}
+
+	geminiCfg := &config.GeminiProtocol{
+		Enabled: true,
+		Host:    "localhost",
+		Port:    11966,
+		TLS: config.GeminiTLS{
+			AutoGenerate: true,
+		},
+	}
+
+	ctx := context.Background()
+	st, err := storage.New(ctx, &cfg.Storage)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	server, err := New(geminiCfg, cfg, st, "localhost", aggregates.NewManager(st, cfg))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(200 * time.Millisecond)
+
+	response := sendGeminiRequest(t, geminiCfg.Port, "gemini://localhost/")
+	if !strings.HasPrefix(response, "20 ") {
+		t.Fatalf("First request should succeed, got: %q", response)
+	}
+
+	// One request per minute: the next token is ~60 seconds away
+	response = sendGeminiRequest(t, geminiCfg.Port, "gemini://localhost/")
+	var seconds int
+	if _, err := fmt.Sscanf(response, "44 %d\r\n", &seconds); err != nil {
+		t.Fatalf("Second request should get 44 SLOW DOWN, got: %q", response)
+	}
+	if seconds < 1 || seconds > 60 {
+		t.Errorf("Expected retry between 1 and 60 seconds, got %d", seconds)
+	}
+}
+
+func TestGeminiCompose(t *testing.T) {
+	sk := nostr.GeneratePrivateKey()
+	pk, _ := nostr.GetPublicKey(sk)
+	npub, _ := nip19.EncodePublicKey(pk)
+	nsec, _ := nip19.EncodePrivateKey(sk)
+	t.Setenv("NOPHR_NSEC", nsec)
+
+	clientCert := generateClientCert(t)
+	sum := sha256.Sum256(clientCert.Certificate[0])
+	fingerprint := hex.EncodeToString(sum[:])
+
+	cfg := config.Default()
+	cfg.Identity.Npub = npub
+	cfg.Storage = config.Storage{Driver: "sqlite", SQLitePath: ":memory:"}
+	cfg.Relays.Seeds = []string{"wss://seed.example.com"}
+	cfg.Outbox.DraftDir = t.TempDir()
+	cfg.Outbox.Publish.Reactions = true
+	cfg.Outbox.CertFingerprints = []string{strings.ToUpper(fingerprint)}
+
+	geminiCfg := &config.GeminiProtocol{
+		Enabled: true,
+		Host:    "localhost",
+		Port:    11967,
+		TLS: config.GeminiTLS{
+			AutoGenerate: true,
+		},
+	}
+
+	ctx := context.Background()
+	st, err := storage.New(ctx, &cfg.Storage)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	server, err := New(geminiCfg, cfg, st, "localhost", aggregates.NewManager(st, cfg))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	pub := &recordingPublisher{}
+	ob, err := outbox.New(cfg, st, pub)
+	if err != nil {
+		t.Fatalf("Failed to create outbox: %v", err)
+	}
+	server.SetOutbox(ob)
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(200 * time.Millisecond)
+
+	// Fingerprints pinned in outbox.cert_fingerprints map to the owner
+	parsed, _ := x509.ParseCertificate(clientCert.Certificate[0])
+	client := server.identifyCert(parsed, time.Now())
+	if !client.Owner {
+		t.Fatalf("Expected pinned certificate to identify the owner, got %+v", client)
+	}
+
+	route := func(raw string) string {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %v", err)
+		}
+		return string(server.router.Route(u, client))
+	}
+
+	// Certificate checks over TLS
+	if response := sendGeminiRequest(t, geminiCfg.Port, "gemini://localhost/compose"); !strings.HasPrefix(response, "60 ") {
+		t.Errorf("Expected 60 without a client certificate, got: %q", response)
+	}
+	if response := sendGeminiRequestWithCert(t, geminiCfg.Port, "gemini://localhost/compose", &clientCert); !strings.HasPrefix(response, "10 ") {
+		t.Errorf("Expected 10 input prompt with the pinned certificate, got: %q", response)
+	}
+	if response := string(server.router.Route(&url.URL{Path: "/compose"}, Client{Fingerprint: "0000"})); !strings.HasPrefix(response, "61 ") {
+		t.Errorf("Expected 61 for an unpinned certificate, got: %q", response)
+	}
+	if response := sendGeminiRequestWithCert(t, geminiCfg.Port, "gemini://localhost/owner", &clientCert); !strings.HasPrefix(response, "20 ") {
+		t.Errorf("Expected the pinned certificate to open the owner area, got: %q", response)
+	}
+
+	// Without auto_sign a composed note becomes a draft for review
+	response := route("gemini://localhost/compose?hello%20gemini")
+	var draftURL string
+	if _, err := fmt.Sscanf(response, "30 %s\r\n", &draftURL); err != nil || !strings.Contains(draftURL, "/compose/draft/") {
+		t.Fatalf("Expected redirect to draft, got: %q", response)
+	}
+	name := draftURL[strings.LastIndex(draftURL, "/")+1:]
+
+	if response := route("gemini://localhost/compose/draft/" + name); !strings.Contains(response, "hello gemini") {
+		t.Errorf("Expected draft page to show content, got: %q", response)
+	}
+
+	response = route("gemini://localhost/compose/publish/" + name)
+	if !strings.HasPrefix(response, "30 ") || len(pub.events) != 1 {
+		t.Fatalf("Expected draft to be published, got: %q", response)
+	}
+	note := pub.events[0]
+	if note.Content != "hello gemini" || note.PubKey != pk {
+		t.Errorf("Unexpected published note: %+v", note)
+	}
+	if !strings.Contains(response, "/note/"+note.ID) {
+		t.Errorf("Expected redirect to the published note, got: %q", response)
+	}
+
+	// Note pages link to reply and react
+	page := route("gemini://localhost/note/" + note.ID)
+	if !strings.Contains(page, "/compose/reply/"+note.ID) || !strings.Contains(page, "/compose/react/"+note.ID) {
+		t.Errorf("Expected reply and react links on note page")
+	}
+
+	// auto_sign publishes replies and reactions straight away
+	cfg.Outbox.AutoSign = true
+	if response := route("gemini://localhost/compose/reply/" + note.ID); !strings.HasPrefix(response, "10 ") {
+		t.Errorf("Expected reply prompt, got: %q", response)
+	}
+	route("gemini://localhost/compose/reply/" + note.ID + "?a%20reply")
+	route("gemini://localhost/compose/react/" + note.ID + "?%F0%9F%A4%99")
+	if len(pub.events) != 3 {
+		t.Fatalf("Expected 3 published events, got %d", len(pub.events))
+	}
+	if reply := pub.events[1]; reply.Kind != 1 || reply.Tags.GetFirst([]string{"e", note.ID}) == nil {
+		t.Errorf("Expected reply tagging the note, got %+v", reply)
+	}
+	if reaction := pub.events[2]; reaction.Kind != 7 || reaction.Content != "🤙" {
+		t.Errorf("Expected reaction, got %+v", reaction)
+	}
+}
+
+func TestGeminiOwnerArea(t *testing.T) {
+	ownerCert := generateClientCert(t)
+	friendCert := generateClientCert(t)
+	fingerprintOf := func(cert tls.Certificate) string {
+		sum := sha256.Sum256(cert.Certificate[0])
+		return hex.EncodeToString(sum[:])
+	}
+
+	friend, _ := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+
+	cfg := config.Default()
+	cfg.Identity.Npub = "npub1nq3zgtqruwhnz0xx40gh4a4fkamlr2sc7ke5wqs2s3nyv2fpy9esg4hdwq"
+	cfg.Storage = config.Storage{Driver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "test.db")}
+
+	geminiCfg := &config.GeminiProtocol{
+		Enabled: true,
+		Host:    "localhost",
+		Port:    11968,
+		TLS: config.GeminiTLS{
+			AutoGenerate: true,
+		},
+		ClientCerts: []config.GeminiClientCert{
+			{Fingerprint: fingerprintOf(ownerCert), Identity: "owner"},
+			{Fingerprint: fingerprintOf(friendCert), Identity: friend},
+		},
+	}
+
+	ctx := context.Background()
+	st, err := storage.New(ctx, &cfg.Storage)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	server, err := New(geminiCfg, cfg, st, "localhost", aggregates.NewManager(st, cfg))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	if err := server.GetSectionManager().RegisterSection(&sections.Section{
+		Name:   "drafts-feed",
+		Path:   "/secret",
+		Title:  "Secret Feed",
+		Hidden: true,
+	}); err != nil {
+		t.Fatalf("Failed to register section: %v", err)
+	}
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(200 * time.Millisecond)
+
+	// Certificates map to identities
+	parsed, _ := x509.ParseCertificate(ownerCert.Certificate[0])
+	owner := server.identifyCert(parsed, time.Now())
+	if !owner.Owner || owner.Expired {
+		t.Errorf("Expected valid owner identity, got %+v", owner)
+	}
+	expired := server.identifyCert(parsed, time.Now().Add(48*time.Hour))
+	if !expired.Expired {
+		t.Errorf("Expected certificate to be expired, got %+v", expired)
+	}
+	parsed, _ = x509.ParseCertificate(friendCert.Certificate[0])
+	visitor := server.identifyCert(parsed, time.Now())
+	if visitor.Owner || visitor.Pubkey != friend {
+		t.Errorf("Expected friend identity, got %+v", visitor)
+	}
+
+	route := func(path string, client Client) string {
+		return string(server.router.Route(&url.URL{Path: path}, client))
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		client Client
+		want   string
+	}{
+		{"anonymous", "/owner", Client{}, "60 "},
+		{"unmapped", "/owner", Client{Fingerprint: "0000"}, "61 "},
+		{"other pubkey", "/owner", visitor, "61 "},
+		{"expired", "/owner", expired, "62 "},
+		{"owner", "/owner", owner, "20 "},
+		{"diagnostics", "/owner/diagnostics", owner, "20 "},
+		{"hidden section", "/owner/section/drafts-feed", owner, "20 "},
+		{"unknown section", "/owner/section/missing", owner, "51 "},
+		{"retention unavailable", "/owner/retention", owner, "51 "},
+		{"hidden section path is not public", "/secret", owner, "51 "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if response := route(tt.path, tt.client); !strings.HasPrefix(response, tt.want) {
+				t.Errorf("Route(%s) = %q, want status %q", tt.path, response, tt.want)
+			}
+		})
+	}
+
+	if page := route("/owner", owner); !strings.Contains(page, "Secret Feed") {
+		t.Errorf("Expected owner page to list hidden sections, got %q", page)
+	}
+
+	server.SetRetentionManager(ops.NewRetentionManager(st, &config.Retention{KeepDays: 30}, ops.NewLogger(&cfg.Logging), cfg.Identity.Npub))
+	if response := route("/owner/retention", owner); !strings.Contains(response, "Keep Days: 30") {
+		t.Errorf("Expected retention stats, got %q", response)
+	}
+	if response := route("/owner/retention/prune", owner); !strings.Contains(response, "Deleted 0 events") {
+		t.Errorf("Expected prune result, got %q", response)
+	}
+
+	// End to end over TLS
+	if response := sendGeminiRequestWithCert(t, geminiCfg.Port, "gemini://localhost/owner", &ownerCert); !strings.HasPrefix(response, "20 ") {
+		t.Errorf("Expected 20 for the owner certificate over TLS, got %q", response)
+	}
+	if response := sendGeminiRequestWithCert(t, geminiCfg.Port, "gemini://localhost/owner", &friendCert); !strings.HasPrefix(response, "61 ") {
+		t.Errorf("Expected 61 for a non-owner certificate over TLS, got %q", response)
+	}
+}
+
+type recordingPublisher struct {
+	events []*nostr.Event
+}
+
+func (p *recordingPublisher) PublishEvent(_ context.Context, _ []string, event *nostr.Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+// generateClientCert creates a self-signed client certificate
+func generateClientCert(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "owner"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
 // Helper function to send a Gemini request
 func sendGeminiRequest(t *testing.T, port int, url string) string {
+	return sendGeminiRequestWithCert(t, port, url, nil)
+}
+
+// sendGeminiRequestWithCert sends a Gemini request presenting a client certificate
+func sendGeminiRequestWithCert(t *testing.T, port int, url string, cert *tls.Certificate) string {
 	// Create TLS config that accepts self-signed certs
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: true,
 	}
+	if cert != nil {
+		tlsConfig.Certificates = []tls.Certificate{*cert}
+	}
 
 	// Connect to server
 	conn, err := tls.DialWithDialer(
@@ -339,3 +730,160 @@ func sendGeminiRequest(t *testing.T, port int, url string) string {
 
 	return response.String()
 }
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func TestGeminiSectionGroups(t *testing.T) {
+	cfg := config.Default()
+	cfg.Identity.Npub = "npub1nq3zgtqruwhnz0xx40gh4a4fkamlr2sc7ke5wqs2s3nyv2fpy9esg4hdwq"
+	cfg.Storage = config.Storage{Driver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "test.db")}
+
+	ctx := context.Background()
+	st, err := storage.New(ctx, &cfg.Storage)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	alicePub, bobPub := storagetest.SeedSectionGroups(t, st)
+
+	geminiCfg := &config.GeminiProtocol{Enabled: true, Host: "localhost", Port: 11970, TLS: config.GeminiTLS{AutoGenerate: true}}
+	server, err := New(geminiCfg, cfg, st, "localhost", aggregates.NewManager(st, cfg))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	for _, section := range []*sections.Section{
+		{Name: "weekly", Path: "/weekly", Title: "Weekly", Limit: 3, GroupBy: sections.GroupByWeek},
+		{Name: "people", Path: "/people", Title: "People", GroupBy: sections.GroupByAuthor},
+		{Name: "kinds", Path: "/kinds", Title: "Kinds", GroupBy: sections.GroupByKind},
+	} {
+		section.Filters = sections.FilterSet{Kinds: []int{1, 30023}, Authors: []string{alicePub, bobPub}}
+		section.SortBy, section.SortOrder = sections.SortByCreatedAt, sections.SortDesc
+		if err := server.GetSectionManager().RegisterSection(section); err != nil {
+			t.Fatalf("Failed to register section: %v", err)
+		}
+	}
+
+	tests := []struct {
+		path   string
+		golden string
+	}{
+		{"/weekly", "section_weekly.gmi"},
+		{"/weekly/page/2", "section_weekly_page2.gmi"},
+		{"/people", "section_people.gmi"},
+		{"/kinds", "section_kinds.gmi"},
+	}
+
+	for _, tt := range tests {
+		response := server.router.Route(&url.URL{Path: tt.path}, Client{})
+		golden := filepath.Join("testdata", tt.golden)
+		if *update {
+			if err := os.WriteFile(golden, response, 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", golden, err)
+			}
+			continue
+		}
+
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", golden, err)
+		}
+		if string(response) != string(want) {
+			t.Errorf("Route(%s) does not match %s:\n%s", tt.path, golden, response)
+		}
+	}
+
+	// Every list page has an Atom variant
+	feed := string(server.router.Route(&url.URL{Path: "/weekly/atom.xml"}, Client{}))
+	if !strings.HasPrefix(feed, "20 application/atom+xml\r\n") {
+		t.Fatalf("unexpected Atom response header: %q", strings.SplitN(feed, "\n", 2)[0])
+	}
+	for _, expected := range []string{
+		"<title>My Nostr Site - Weekly</title>",
+		`<link href="gemini://localhost:11970/weekly/atom.xml" rel="self" type="application/atom+xml"></link>`,
+		"<title>first light</title>",
+		"<published>2025-10-24T09:00:00Z</published>",
+	} {
+		if !strings.Contains(feed, expected) {
+			t.Errorf("Atom feed missing %s:\n%s", expected, feed)
+		}
+	}
+	if entries := strings.Count(feed, "<entry>"); entries != 3 {
+		t.Errorf("Atom feed should mirror the first page, got %d entries", entries)
+	}
+
+	if notes := string(server.router.Route(&url.URL{Path: "/notes/atom.xml"}, Client{})); !strings.HasPrefix(notes, "20 application/atom+xml") {
+		t.Errorf("/notes/atom.xml: unexpected response %q", notes)
+	}
+	if missing := string(server.router.Route(&url.URL{Path: "/nowhere/atom.xml"}, Client{})); !strings.HasPrefix(missing, "51 ") {
+		t.Errorf("/nowhere/atom.xml: expected not found, got %q", missing)
+	}
+}
+
+func TestGeminiSectionArchive(t *testing.T) {
+	cfg := config.Default()
+	cfg.Identity.Npub = "npub1nq3zgtqruwhnz0xx40gh4a4fkamlr2sc7ke5wqs2s3nyv2fpy9esg4hdwq"
+	cfg.Storage = config.Storage{Driver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "test.db")}
+
+	ctx := context.Background()
+	st, err := storage.New(ctx, &cfg.Storage)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	priv := nostr.GeneratePrivateKey()
+	pub, _ := nostr.GetPublicKey(priv)
+
+	for i := 0; i < 3; i++ {
+		event := nostr.Event{
+			Kind:      1,
+			PubKey:    pub,
+			CreatedAt: nostr.Timestamp(time.Date(2025, time.October, 24, 9, i, 0, 0, time.UTC).Unix()),
+			Content:   fmt.Sprintf("note %d", i),
+		}
+		if err := event.Sign(priv); err != nil {
+			t.Fatalf("Failed to sign event: %v", err)
+		}
+		if err := st.StoreEvent(ctx, &event); err != nil {
+			t.Fatalf("Failed to store event: %v", err)
+		}
+	}
+
+	geminiCfg := &config.GeminiProtocol{Enabled: true, Host: "localhost", Port: 11969, TLS: config.GeminiTLS{AutoGenerate: true}}
+	server, err := New(geminiCfg, cfg, st, "localhost", aggregates.NewManager(st, cfg))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	if err := server.GetSectionManager().RegisterSection(&sections.Section{
+		Name:    "diary",
+		Path:    "/diary",
+		Title:   "Diary",
+		Limit:   2,
+		Filters: sections.FilterSet{Kinds: []int{1}, Authors: []string{pub}},
+	}); err != nil {
+		t.Fatalf("Failed to register section: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		contains []string
+	}{
+		{"/diary", []string{"=> gemini://localhost:11969/diary/archive Diary archive"}},
+		{"/diary/archive", []string{"# Diary - Archive", "=> gemini://localhost:11969/diary/archive/2025 2025 (3)"}},
+		{"/diary/archive/2025", []string{"=> gemini://localhost:11969/diary/archive/2025/10 October 2025 (3)"}},
+		{"/diary/archive/2025/10", []string{"```Calendar of October 2025", "19  20  21  22  23  24* 25", "=> gemini://localhost:11969/diary/archive/2025/10/24 October 24, 2025 (3)"}},
+		{"/diary/archive/2025/10/24", []string{"note 2", "note 1", "=> gemini://localhost:11969/diary/archive/2025/10/24/page/2 → Next Page", "Page 1 of 2"}},
+		{"/diary/archive/2025/10/24/page/2", []string{"note 0", "← Previous Page"}},
+		{"/diary/archive/2025/13", []string{"51 Invalid archive"}},
+	}
+
+	for _, tt := range tests {
+		response := string(server.router.Route(&url.URL{Path: tt.path}, Client{}))
+		for _, want := range tt.contains {
+			if !strings.Contains(response, want) {
+				t.Errorf("Route(%s): expected %q in response, got: %s", tt.path, want, response)
+			}
+		}
+	}
+}
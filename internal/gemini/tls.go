@@ -37,6 +37,9 @@ func (s *Server) initTLS() error {
 	s.tlsConfig = &tls.Config{
 		Certificates: []tls.Certificate{cert},
 		MinVersion:   tls.VersionTLS12,
+		// Client certificates are optional and self-signed; they identify
+		// visitors by fingerprint rather than by a CA chain
+		ClientAuth: tls.RequestClientCert,
 		CipherSuites: []uint16{
 			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
 			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
@@ -94,6 +97,9 @@ func (s *Server) generateSelfSignedCert() error {
 	s.tlsConfig = &tls.Config{
 		Certificates: []tls.Certificate{cert},
 		MinVersion:   tls.VersionTLS12,
+		// Client certificates are optional and self-signed; they identify
+		// visitors by fingerprint rather than by a CA chain
+		ClientAuth: tls.RequestClientCert,
 		CipherSuites: []uint16{
 			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
 			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
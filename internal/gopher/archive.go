@@ -0,0 +1,151 @@
+package gopher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sandwichfarm/nophr/internal/sections"
+)
+
+// handleArchive renders a section's date-based archive:
+// /<section>/archive, /<section>/archive/YYYY, .../YYYY/MM and .../YYYY/MM/DD
+func (r *Router) handleArchive(ctx context.Context, path string) []byte {
+	ap, err := sections.ParseArchivePath(path)
+	if err != nil {
+		return r.errorResponse(fmt.Sprintf("Invalid archive: %v", err))
+	}
+
+	section, err := r.server.GetSectionManager().GetSection(ap.Section)
+	if err != nil {
+		return r.errorResponse(fmt.Sprintf("Unknown section: %s", ap.Section))
+	}
+
+	gmap := NewGophermap(r.host, r.port)
+	r.addHeaderToGophermap(gmap, section.Name)
+
+	archives := r.server.GetSectionManager().Archives()
+	switch {
+	case ap.Day > 0:
+		err = r.renderArchiveDay(ctx, gmap, archives, section, ap)
+	case ap.Month > 0:
+		err = r.renderArchiveMonth(ctx, gmap, archives, section, ap)
+	case ap.Year > 0:
+		err = r.renderArchiveYear(ctx, gmap, archives, section, ap)
+	default:
+		err = r.renderArchiveIndex(ctx, gmap, archives, section)
+	}
+	if err != nil {
+		return r.errorResponse(fmt.Sprintf("Error loading archive: %v", err))
+	}
+
+	gmap.AddSpacer()
+	if ap.Year > 0 {
+		gmap.AddDirectory("↑ Up", ap.Parent())
+	} else if section.Path != "" {
+		gmap.AddDirectory(fmt.Sprintf("← Back to %s", sectionLabel(section)), section.Path)
+	}
+	gmap.AddDirectory("⌂ Home", "/")
+
+	r.addFooterToGophermap(gmap, section.Name)
+
+	return gmap.Bytes()
+}
+
+// renderArchiveIndex lists the years that have events
+func (r *Router) renderArchiveIndex(ctx context.Context, gmap *Gophermap, archives *sections.ArchiveManager, section *sections.Section) error {
+	years, err := archives.ListArchives(ctx, section, sections.ArchiveByYear)
+	if err != nil {
+		return err
+	}
+
+	gmap.AddInfo(fmt.Sprintf("%s - Archive", sectionLabel(section)))
+	gmap.AddSpacer()
+
+	r.addArchiveLinks(gmap, section, years)
+	return nil
+}
+
+// renderArchiveYear lists the months of a year that have events
+func (r *Router) renderArchiveYear(ctx context.Context, gmap *Gophermap, archives *sections.ArchiveManager, section *sections.Section, ap *sections.ArchivePath) error {
+	months, err := archives.PeriodArchives(ctx, section, ap.Year, 0, sections.ArchiveByMonth)
+	if err != nil {
+		return err
+	}
+
+	gmap.AddInfo(fmt.Sprintf("%s - %s", sectionLabel(section), ap.Archive().FormatTitle()))
+	gmap.AddSpacer()
+
+	r.addArchiveLinks(gmap, section, months)
+	return nil
+}
+
+// renderArchiveMonth shows a calendar of the month and lists its days
+func (r *Router) renderArchiveMonth(ctx context.Context, gmap *Gophermap, archives *sections.ArchiveManager, section *sections.Section, ap *sections.ArchivePath) error {
+	calendar, err := archives.GenerateMonthlyCalendar(ctx, section, ap.Year, ap.Month)
+	if err != nil {
+		return err
+	}
+
+	gmap.AddInfo(fmt.Sprintf("%s - %s", sectionLabel(section), ap.Archive().FormatTitle()))
+	gmap.AddSpacer()
+
+	gmap.AddInfoBlock(calendar.Grid())
+	gmap.AddSpacer()
+
+	r.addArchiveLinks(gmap, section, calendar.Archives())
+	return nil
+}
+
+// renderArchiveDay lists a page of the events of a day
+func (r *Router) renderArchiveDay(ctx context.Context, gmap *Gophermap, archives *sections.ArchiveManager, section *sections.Section, ap *sections.ArchivePath) error {
+	page, err := archives.GetArchivePage(ctx, section, ap.Year, ap.Month, ap.Day, ap.Page)
+	if err != nil {
+		return err
+	}
+
+	gmap.AddInfo(fmt.Sprintf("%s - %s", sectionLabel(section), ap.Archive().FormatTitle()))
+	gmap.AddSpacer()
+
+	if len(page.Events) == 0 {
+		gmap.AddInfo("No content for this day.")
+	}
+	for _, event := range page.Events {
+		if section.ShowAuthors {
+			gmap.AddInfo(fmt.Sprintf("   By %s - %s", truncatePubkey(event.PubKey), formatTimestamp(event.CreatedAt)))
+		} else {
+			gmap.AddInfo(fmt.Sprintf("   %s", formatTimestamp(event.CreatedAt)))
+		}
+		gmap.AddTextFile(eventTitle(event), fmt.Sprintf("/note/%s", event.ID))
+		gmap.AddSpacer()
+	}
+
+	if page.HasPrev {
+		gmap.AddDirectory("← Previous Page", fmt.Sprintf("%s/page/%d", ap.Selector(), page.PageNumber-1))
+	}
+	if page.HasNext {
+		gmap.AddDirectory("→ Next Page", fmt.Sprintf("%s/page/%d", ap.Selector(), page.PageNumber+1))
+	}
+	if page.TotalPages > 1 {
+		gmap.AddInfo(fmt.Sprintf("Page %d of %d", page.PageNumber, page.TotalPages))
+	}
+
+	return nil
+}
+
+// addArchiveLinks adds a menu item with the event count for each archive
+func (r *Router) addArchiveLinks(gmap *Gophermap, section *sections.Section, archives []*sections.Archive) {
+	if len(archives) == 0 {
+		gmap.AddInfo("No content yet.")
+		return
+	}
+
+	for _, archive := range archives {
+		gmap.AddDirectory(fmt.Sprintf("%s (%d)", archive.FormatTitle(), archive.EventCount),
+			archive.FormatArchiveSelector(section.Name))
+	}
+}
+
+// archiveSelector returns the selector of a section's archive index
+func archiveSelector(section *sections.Section) string {
+	return sections.ArchiveSelector(section.Name)
+}
@@ -92,6 +92,12 @@ func (g *Gophermap) AddTextFile(display, selector string) {
 	g.AddItem(ItemTypeTextFile, display, selector)
 }
 
+// AddSearch adds a full-text search item (type 7). Clients prompt for a
+// query and send it after the selector, separated by a TAB.
+func (g *Gophermap) AddSearch(display, selector string) {
+	g.AddItem(ItemTypeSearch, display, selector)
+}
+
 // AddError adds an error item
 func (g *Gophermap) AddError(message string) {
 	g.AddItem(ItemTypeError, message, "error")
@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nbd-wtf/go-nostr"
@@ -22,6 +23,7 @@ import (
 // Renderer renders Nostr events as Gopher text
 type Renderer struct {
 	parser   *markdown.Parser
+	mu       sync.RWMutex
 	config   *config.Config
 	loader   *presentation.Loader
 	resolver *entities.Resolver
@@ -39,6 +41,22 @@ func NewRenderer(cfg *config.Config, st *storage.Storage) *Renderer {
 	}
 }
 
+// SetConfig switches rendering to a new configuration, including the headers
+// and footers it defines
+func (r *Renderer) SetConfig(cfg *config.Config) {
+	r.mu.Lock()
+	r.config = cfg
+	r.mu.Unlock()
+	r.loader.SetConfig(cfg)
+}
+
+// settings returns the configuration pages are currently rendered with
+func (r *Renderer) settings() *config.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config
+}
+
 // RenderNote renders a note event as plain text
 func (r *Renderer) RenderNote(event *nostr.Event, agg *aggregates.EventAggregates) string {
 	var sb strings.Builder
@@ -49,6 +67,10 @@ func (r *Renderer) RenderNote(event *nostr.Event, agg *aggregates.EventAggregate
 	sb.WriteString(strings.Repeat("=", 70))
 	sb.WriteString("\n\n")
 
+	if warning := nostrclient.RevealedWarning(event); warning != "" {
+		sb.WriteString(warning + "\n\n")
+	}
+
 	// Content (resolve NIP-19 entities, then render markdown)
 	content := event.Content
 
@@ -56,20 +78,23 @@ func (r *Renderer) RenderNote(event *nostr.Event, agg *aggregates.EventAggregate
 	content = r.resolver.ReplaceEntities(context.Background(), content, entities.GopherFormatter)
 
 	// Apply max content length if configured
-	if r.config.Display.Limits.MaxContentLength > 0 && len(content) > r.config.Display.Limits.MaxContentLength {
-		content = content[:r.config.Display.Limits.MaxContentLength] + r.config.Display.Limits.TruncateIndicator
+	if r.settings().Display.Limits.MaxContentLength > 0 && len(content) > r.settings().Display.Limits.MaxContentLength {
+		content = content[:r.settings().Display.Limits.MaxContentLength] + r.settings().Display.Limits.TruncateIndicator
 	}
 
 	rendered, _ := r.parser.RenderGopher([]byte(content), r.gopherRenderOptions())
-	rendered = clampWidth(rendered, r.config.Rendering.Gopher.MaxLineLength)
+	rendered = clampWidth(rendered, r.settings().Rendering.Gopher.MaxLineLength)
 	sb.WriteString(rendered)
 
 	// Aggregates footer - only show if configured for detail view
-	if r.config.Display.Detail.ShowInteractions && agg != nil && agg.HasInteractions() {
+	if r.settings().Display.Detail.ShowInteractions && agg != nil && agg.HasInteractions() {
 		sb.WriteString("\n")
 		sb.WriteString(r.applyConfigSeparator("section"))
 		sb.WriteString("\n")
 		sb.WriteString(r.renderAggregatesForDetail(agg))
+		if r.settings().Display.Detail.ShowZaps && len(agg.Zappers) > 0 {
+			sb.WriteString(r.renderZappers(agg.Zappers))
+		}
 	}
 
 	return sb.String()
@@ -143,11 +168,40 @@ func (r *Renderer) RenderProfile(profileEvent *nostr.Event) string {
 	return sb.String()
 }
 
+// RenderProfileZaps renders the zaps of a profile (not of its notes)
+func (r *Renderer) RenderProfileZaps(summary *aggregates.ZapSummary) string {
+	if summary == nil || summary.Count == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Profile zaps: %s from %d zaps\n", aggregates.FormatSats(summary.TotalSats), summary.Count))
+	sb.WriteString(r.renderZappers(summary.Top(aggregates.TopZappers)))
+	return sb.String()
+}
+
+// renderZappers renders zappers with their totals and latest comments
+func (r *Renderer) renderZappers(zappers []*aggregates.Zapper) string {
+	var sb strings.Builder
+	sb.WriteString("Top zappers:\n")
+	for _, zapper := range zappers {
+		sb.WriteString(fmt.Sprintf("  %s - %s", r.resolver.DisplayName(context.Background(), zapper.Pubkey), aggregates.FormatSats(zapper.Sats)))
+		if zapper.Count > 1 {
+			sb.WriteString(fmt.Sprintf(" (%d zaps)", zapper.Count))
+		}
+		if zapper.Comment != "" {
+			sb.WriteString(fmt.Sprintf(": \"%s\"", getSummary(zapper.Comment, 80)))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
 // RenderNoteWithThread renders a note and optionally appends a threaded view
 func (r *Renderer) RenderNoteWithThread(event *nostr.Event, agg *aggregates.EventAggregates, thread *aggregates.ThreadView) string {
 	base := r.RenderNote(event, agg)
 
-	if thread == nil || !r.config.Display.Detail.ShowThread {
+	if thread == nil || !r.settings().Display.Detail.ShowThread {
 		return base
 	}
 
@@ -177,7 +231,7 @@ func (r *Renderer) RenderThread(thread *aggregates.ThreadView) string {
 	sb.WriteString(fmt.Sprintf("Back to note: /note/%s\n", thread.FocusID))
 	sb.WriteString("Home: /\n\n")
 
-	maxDepth := r.config.Display.Limits.MaxThreadDepth
+	maxDepth := r.settings().Display.Limits.MaxThreadDepth
 	if maxDepth <= 0 {
 		maxDepth = 10
 	}
@@ -189,15 +243,15 @@ func (r *Renderer) RenderThread(thread *aggregates.ThreadView) string {
 
 // renderAggregates renders interaction stats (for feed view - respects feed config)
 func (r *Renderer) renderAggregates(agg *aggregates.EventAggregates) string {
-	if !r.config.Display.Feed.ShowInteractions {
+	if !r.settings().Display.Feed.ShowInteractions {
 		return ""
 	}
-	return r.buildAggregatesString(agg, r.config.Display.Feed.ShowReplies, r.config.Display.Feed.ShowReactions, r.config.Display.Feed.ShowZaps)
+	return r.buildAggregatesString(agg, r.settings().Display.Feed.ShowReplies, r.settings().Display.Feed.ShowReactions, r.settings().Display.Feed.ShowZaps)
 }
 
 // renderAggregatesForDetail renders interaction stats for detail view
 func (r *Renderer) renderAggregatesForDetail(agg *aggregates.EventAggregates) string {
-	return r.buildAggregatesString(agg, r.config.Display.Detail.ShowReplies, r.config.Display.Detail.ShowReactions, r.config.Display.Detail.ShowZaps)
+	return r.buildAggregatesString(agg, r.settings().Display.Detail.ShowReplies, r.settings().Display.Detail.ShowReactions, r.settings().Display.Detail.ShowZaps)
 }
 
 // buildAggregatesString builds the aggregates string based on what should be shown
@@ -237,9 +291,9 @@ func (r *Renderer) applyConfigSeparator(separatorType string) string {
 	var sep string
 	switch separatorType {
 	case "item":
-		sep = r.config.Presentation.Separators.Item.Gopher
+		sep = r.settings().Presentation.Separators.Item.Gopher
 	case "section":
-		sep = r.config.Presentation.Separators.Section.Gopher
+		sep = r.settings().Presentation.Separators.Section.Gopher
 	default:
 		sep = "---"
 	}
@@ -353,7 +407,7 @@ func (r *Renderer) RenderNoteList(notes []*aggregates.EnrichedEvent, title strin
 		return lines
 	}
 
-	summaryLength := r.config.Display.Limits.SummaryLength
+	summaryLength := r.settings().Display.Limits.SummaryLength
 	if summaryLength <= 0 {
 		summaryLength = 70 // Default fallback
 	}
@@ -362,9 +416,12 @@ func (r *Renderer) RenderNoteList(notes []*aggregates.EnrichedEvent, title strin
 		// Extract first line of content as summary
 		content := note.Event.Content
 		if len(content) > summaryLength {
-			content = content[:summaryLength-len(r.config.Display.Limits.TruncateIndicator)] + r.config.Display.Limits.TruncateIndicator
+			content = content[:summaryLength-len(r.settings().Display.Limits.TruncateIndicator)] + r.settings().Display.Limits.TruncateIndicator
 		}
 		firstLine := strings.Split(content, "\n")[0]
+		if label, ok := nostrclient.ContentWarningLabel(note.Event); ok {
+			firstLine = label
+		}
 
 		lines = append(lines, fmt.Sprintf("%d. %s", i+1, firstLine))
 		lines = append(lines, fmt.Sprintf("   by %s - %s",
@@ -372,7 +429,7 @@ func (r *Renderer) RenderNoteList(notes []*aggregates.EnrichedEvent, title strin
 			formatTimestamp(note.Event.CreatedAt)))
 
 		// Only show aggregates if configured for feed view
-		if r.config.Display.Feed.ShowInteractions && note.Aggregates != nil && note.Aggregates.HasInteractions() {
+		if r.settings().Display.Feed.ShowInteractions && note.Aggregates != nil && note.Aggregates.HasInteractions() {
 			aggStr := r.renderAggregates(note.Aggregates)
 			if aggStr != "" {
 				lines = append(lines, fmt.Sprintf("   %s", aggStr))
@@ -393,8 +450,8 @@ func (r *Renderer) RenderNoteList(notes []*aggregates.EnrichedEvent, title strin
 
 func (r *Renderer) gopherRenderOptions() *markdown.RenderOptions {
 	opts := markdown.DefaultGopherOptions()
-	if r.config.Rendering.Gopher.MaxLineLength > 0 {
-		opts.Width = r.config.Rendering.Gopher.MaxLineLength
+	if r.settings().Rendering.Gopher.MaxLineLength > 0 {
+		opts.Width = r.settings().Rendering.Gopher.MaxLineLength
 	}
 	return opts
 }
@@ -494,7 +551,7 @@ func (r *Renderer) renderThreadNode(sb *strings.Builder, node *aggregates.Thread
 		markers = append(markers, "you are here")
 	}
 
-	summary := r.threadSummary(node.Event.Content)
+	summary := r.threadSummary(node.Event)
 	line := fmt.Sprintf("%s- %s (%s)", prefix, summary, formatTimestamp(node.Event.CreatedAt))
 	if len(markers) > 0 {
 		line = fmt.Sprintf("%s [%s]", line, strings.Join(markers, ", "))
@@ -510,26 +567,32 @@ func (r *Renderer) renderThreadNode(sb *strings.Builder, node *aggregates.Thread
 }
 
 func (r *Renderer) threadIndent() string {
-	indent := r.config.Rendering.Gopher.ThreadIndent
+	indent := r.settings().Rendering.Gopher.ThreadIndent
 	if indent == "" {
 		return "  "
 	}
 	return indent
 }
 
-func (r *Renderer) threadSummary(content string) string {
-	limit := r.config.Display.Limits.SummaryLength
+// threadSummary returns one line of an event's content for thread trees, or
+// its content warning in place of sensitive content
+func (r *Renderer) threadSummary(event *nostr.Event) string {
+	if label, ok := nostrclient.ContentWarningLabel(event); ok {
+		return label
+	}
+
+	limit := r.settings().Display.Limits.SummaryLength
 	if limit <= 0 {
 		limit = 100
 	}
 
-	plain := strings.ReplaceAll(content, "\n", " ")
+	plain := strings.ReplaceAll(event.Content, "\n", " ")
 	plain = strings.TrimSpace(plain)
 	if len(plain) <= limit {
 		return plain
 	}
 
-	indicator := r.config.Display.Limits.TruncateIndicator
+	indicator := r.settings().Display.Limits.TruncateIndicator
 	if indicator == "" {
 		indicator = "..."
 	}
@@ -592,7 +655,7 @@ func (r *Renderer) RenderThreadGophermap(gmap *Gophermap, thread *aggregates.Thr
 	gmap.AddDirectory("⌂ Home", "/")
 	gmap.AddSpacer()
 
-	maxDepth := r.config.Display.Limits.MaxThreadDepth
+	maxDepth := r.settings().Display.Limits.MaxThreadDepth
 	if maxDepth <= 0 {
 		maxDepth = 10
 	}
@@ -620,7 +683,7 @@ func (r *Renderer) renderThreadNodeMap(gmap *Gophermap, node *aggregates.ThreadN
 		markers = append(markers, "you are here")
 	}
 
-	summary := r.threadSummary(node.Event.Content)
+	summary := r.threadSummary(node.Event)
 	line := fmt.Sprintf("%s- %s (%s)", prefix, summary, formatTimestamp(node.Event.CreatedAt))
 	if len(markers) > 0 {
 		line = fmt.Sprintf("%s [%s]", line, strings.Join(markers, ", "))
@@ -702,8 +765,8 @@ func displayNameWithAt(entity *entities.Entity) string {
 }
 
 func (r *Renderer) portalBases() []string {
-	if len(r.config.Rendering.Portals) > 0 {
-		return r.config.Rendering.Portals
+	if len(r.settings().Rendering.Portals) > 0 {
+		return r.settings().Rendering.Portals
 	}
 	return []string{"https://njump.me", "https://nostr.at", "https://nostr.eu"}
 }
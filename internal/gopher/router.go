@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/sandwichfarm/nophr/internal/aggregates"
+	nostrclient "github.com/sandwichfarm/nophr/internal/nostr"
 	"github.com/sandwichfarm/nophr/internal/sections"
 )
 
@@ -52,6 +54,26 @@ func parsePageFromParts(parts []string) (int, []string) {
 	return page, remaining
 }
 
+// splitPagePath splits a trailing /page/N off a path, returning the base
+// path and page number (1 when there is none)
+func splitPagePath(path string) (string, int) {
+	idx := strings.LastIndex(path, "/page/")
+	if idx < 0 {
+		return path, 1
+	}
+
+	page, err := strconv.Atoi(path[idx+len("/page/"):])
+	if err != nil || page < 1 {
+		return path, 1
+	}
+
+	base := path[:idx]
+	if base == "" {
+		base = "/"
+	}
+	return base, page
+}
+
 // addPaginationLinks adds Next/Previous/Home navigation to gophermap
 func (r *Router) addPaginationLinks(gmap *Gophermap, basePath string, page, totalItems int) {
 	totalPages := (totalItems + itemsPerPage - 1) / itemsPerPage
@@ -95,8 +117,9 @@ func paginateItems[T any](items []T, page int) []T {
 	return items[start:end]
 }
 
-// Route routes a selector to the appropriate handler
-func (r *Router) Route(selector string) []byte {
+// Route routes a selector to the appropriate handler. query is the search
+// string a type-7 client sent after the selector, if any.
+func (r *Router) Route(selector, query string) []byte {
 	ctx := context.Background()
 
 	// Normalize path
@@ -107,9 +130,10 @@ func (r *Router) Route(selector string) []byte {
 
 	// Check if sections are registered for this path (sections override defaults)
 	if r.server.GetSectionManager() != nil {
-		sections := r.server.GetSectionManager().GetSectionsByPath(path)
-		if len(sections) > 0 {
-			return r.handleSections(ctx, sections, path)
+		base, page := splitPagePath(path)
+		sections := r.server.GetSectionManager().GetSectionsByPath(base)
+		if len(sections) == 1 || (len(sections) > 1 && page == 1) {
+			return r.handleSections(ctx, sections, base, page)
 		}
 	}
 
@@ -118,6 +142,11 @@ func (r *Router) Route(selector string) []byte {
 		return r.handleRoot(ctx)
 	}
 
+	// Section archives: /<section>/archive/...
+	if r.server.GetSectionManager() != nil && sections.IsArchivePath(path) {
+		return r.handleArchive(ctx, path)
+	}
+
 	// Parse selector path
 	parts := strings.Split(strings.TrimPrefix(selector, "/"), "/")
 	if len(parts) == 0 {
@@ -161,7 +190,7 @@ func (r *Router) Route(selector string) []byte {
 		return r.handleDiagnostics(ctx)
 
 	case "search":
-		return r.handleSearch(ctx, parts[1:])
+		return r.handleSearch(ctx, parts[1:], query)
 
 	// Legacy support - redirect to new endpoints
 	case "outbox":
@@ -189,7 +218,7 @@ func (r *Router) handleRoot(ctx context.Context) []byte {
 	gmap.AddDirectory("Replies", "/replies")
 	gmap.AddDirectory("Mentions", "/mentions")
 	gmap.AddSpacer()
-	gmap.AddDirectory("Search", "/search")
+	gmap.AddSearch("Search", "/search")
 	gmap.AddDirectory("Diagnostics", "/diagnostics")
 	gmap.AddSpacer()
 	gmap.AddInfo("Powered by nophr")
@@ -348,6 +377,7 @@ func (r *Router) handleArticles(ctx context.Context, parts []string) []byte {
 	}
 
 	gmap.AddInfo("Articles")
+	gmap.AddSearch("Search articles", "/search/articles")
 	gmap.AddSpacer()
 
 	// Paginate articles
@@ -516,7 +546,7 @@ func (r *Router) handleMentions(ctx context.Context, parts []string) []byte {
 // handleNote handles displaying a single note
 func (r *Router) handleNote(ctx context.Context, noteID string) []byte {
 	// Query the note
-	events, err := r.server.GetStorage().QueryEvents(ctx, nostr.Filter{
+	events, err := r.server.queryHelper.QueryEvents(ctx, nostr.Filter{
 		IDs: []string{noteID},
 	})
 	if err != nil || len(events) == 0 {
@@ -541,6 +571,11 @@ func (r *Router) handleNote(ctx context.Context, noteID string) []byte {
 			ZapSatsTotal:    aggData.ZapSatsTotal,
 			LastInteraction: aggData.LastInteractionAt,
 		}
+
+		// Top zappers with their comments
+		if summary, err := r.server.GetQueryHelper().GetZapSummary(ctx, noteID); err == nil {
+			agg.Zappers = summary.Top(aggregates.TopZappers)
+		}
 	}
 
 	// Build thread view (includes replies and navigation)
@@ -579,7 +614,7 @@ func (r *Router) handleThread(ctx context.Context, rootID string) []byte {
 // handleProfile handles displaying a profile
 func (r *Router) handleProfile(ctx context.Context, pubkey string) []byte {
 	// Query profile metadata (kind 0)
-	events, err := r.server.GetStorage().QueryEvents(ctx, nostr.Filter{
+	events, err := r.server.queryHelper.QueryEvents(ctx, nostr.Filter{
 		Kinds:   []int{0},
 		Authors: []string{pubkey},
 		Limit:   1,
@@ -594,11 +629,29 @@ func (r *Router) handleProfile(ctx context.Context, pubkey string) []byte {
 
 	profile := events[0]
 
+	gmap := NewGophermap(r.host, r.port)
+
 	// Render the profile
 	text := r.renderer.RenderProfile(profile)
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		// A TAB would end the display field of the info line
+		gmap.AddInfo(strings.ReplaceAll(line, "\t", "    "))
+	}
+	gmap.AddSpacer()
 
-	// Return as plain text with gopher terminator
-	return append([]byte(text), []byte(".\r\n")...)
+	// Profile zaps
+	if summary, err := r.server.GetQueryHelper().GetProfileZapSummary(ctx, pubkey); err == nil && summary.Count > 0 {
+		for _, line := range strings.Split(strings.TrimRight(r.renderer.RenderProfileZaps(summary), "\n"), "\n") {
+			gmap.AddInfo(strings.ReplaceAll(line, "\t", "    "))
+		}
+		gmap.AddSpacer()
+	}
+
+	gmap.AddSearch("Search notes by this author", "/search/profile/"+pubkey)
+	gmap.AddSpacer()
+	gmap.AddDirectory("← Back to Home", "/")
+
+	return gmap.Bytes()
 }
 
 // handleDiagnostics handles the diagnostics page
@@ -618,56 +671,138 @@ func (r *Router) handleDiagnostics(ctx context.Context) []byte {
 	gmap.AddInfo("Storage: Connected")
 	gmap.AddSpacer()
 
+	if c := r.server.GetCache(); c != nil {
+		if stats, err := c.Stats(ctx); err == nil {
+			gmap.AddInfo("Cache:")
+			gmap.AddInfo(fmt.Sprintf("  Hits: %d", stats.Hits))
+			gmap.AddInfo(fmt.Sprintf("  Misses: %d", stats.Misses))
+			gmap.AddInfo(fmt.Sprintf("  Hit Rate: %.1f%%", stats.HitRate*100))
+			gmap.AddInfo(fmt.Sprintf("  Keys: %d", stats.Keys))
+		} else {
+			gmap.AddInfo(fmt.Sprintf("Cache: error (%v)", err))
+		}
+	} else {
+		gmap.AddInfo("Cache: Disabled")
+	}
+	gmap.AddSpacer()
+
 	gmap.AddDirectory("← Back to Home", "/")
 
 	return gmap.Bytes()
 }
 
-// handleSearch handles search requests
-func (r *Router) handleSearch(ctx context.Context, params []string) []byte {
+// searchScope restricts a search to a subset of events
+type searchScope struct {
+	title    string   // shown in the results header
+	selector string   // type-7 selector for this scope
+	kinds    []int    // event kinds to search
+	authors  []string // optional author restriction
+	section  string   // optional section name (uses the section's filters)
+}
+
+// parseSearchScope resolves the scope from the selector parts after /search.
+// It returns the remaining parts, which may hold a legacy path-style query.
+func (r *Router) parseSearchScope(params []string) (*searchScope, []string, error) {
+	if len(params) > 0 {
+		switch params[0] {
+		case "articles":
+			return &searchScope{
+				title:    "Articles",
+				selector: "/search/articles",
+				kinds:    []int{30023},
+			}, params[1:], nil
+
+		case "profile":
+			if len(params) < 2 || params[1] == "" {
+				return nil, nil, fmt.Errorf("missing pubkey")
+			}
+			pubkey := params[1]
+			return &searchScope{
+				title:    "Notes by " + truncatePubkey(pubkey),
+				selector: "/search/profile/" + pubkey,
+				kinds:    []int{1, 30023},
+				authors:  []string{pubkey},
+			}, params[2:], nil
+
+		case "section":
+			if len(params) < 2 || params[1] == "" {
+				return nil, nil, fmt.Errorf("missing section name")
+			}
+			section, err := r.server.GetSectionManager().GetSection(params[1])
+			if err != nil {
+				return nil, nil, err
+			}
+			return &searchScope{
+				title:    sectionLabel(section),
+				selector: "/search/section/" + section.Name,
+				section:  section.Name,
+			}, params[2:], nil
+		}
+	}
+
+	return &searchScope{
+		title:    "All content",
+		selector: "/search",
+		kinds:    []int{0, 1, 30023}, // Profiles, notes, articles
+	}, params, nil
+}
+
+// handleSearch handles search requests. Type-7 clients send the query after
+// a TAB; the older /search/your+search+terms form is still accepted.
+func (r *Router) handleSearch(ctx context.Context, params []string, query string) []byte {
 	gmap := NewGophermap(r.host, r.port)
 
+	scope, rest, err := r.parseSearchScope(params)
+	if err != nil {
+		return r.errorResponse(fmt.Sprintf("Invalid search: %v", err))
+	}
+
+	if query == "" && len(rest) > 0 {
+		// Decode path query (URL encoded, replace + with space)
+		query = strings.TrimSpace(strings.ReplaceAll(strings.Join(rest, "/"), "+", " "))
+	}
+
 	// If no search query, show search page
-	if len(params) == 0 || params[0] == "" {
-		gmap.AddInfo("Search Nostr Content")
+	if query == "" {
+		gmap.AddInfo("Search: " + scope.title)
 		gmap.AddInfo(strings.Repeat("=", 70))
 		gmap.AddSpacer()
-		gmap.AddInfo("Note: Gopher protocol requires entering full path with query")
-		gmap.AddInfo("Format: /search/your+search+terms")
-		gmap.AddSpacer()
-		gmap.AddInfo("Examples:")
-		gmap.AddInfo("  /search/nostr+protocol")
-		gmap.AddInfo("  /search/bitcoin")
+		gmap.AddSearch("Enter search terms", scope.selector)
 		gmap.AddSpacer()
 		gmap.AddDirectory("← Back to Home", "/")
 		return gmap.Bytes()
 	}
 
-	// Decode search query (URL encoded, replace + with space)
-	query := strings.ReplaceAll(params[0], "+", " ")
-
-	gmap.AddInfo(fmt.Sprintf("Search Results: \"%s\"", query))
+	gmap.AddInfo(fmt.Sprintf("Search Results: \"%s\" in %s", query, scope.title))
 	gmap.AddInfo(strings.Repeat("=", 70))
 	gmap.AddSpacer()
 
 	// Perform search using NIP-50
-	events, err := r.server.storage.QueryEventsWithSearch(ctx, nostr.Filter{
-		Search: query,
-		Kinds:  []int{0, 1, 30023}, // Profiles, notes, articles
-		Limit:  20,
-	})
+	var events []*nostr.Event
+	if scope.section != "" {
+		events, err = r.server.GetSectionManager().Search(ctx, scope.section, query, 20)
+	} else {
+		events, err = r.server.storage.QueryEventsWithSearch(ctx, nostr.Filter{
+			Search:  query,
+			Kinds:   scope.kinds,
+			Authors: scope.authors,
+			Limit:   20,
+		})
+		events = r.server.events.Filter(events)
+	}
 
 	if err != nil {
 		gmap.AddError(fmt.Sprintf("Search failed: %v", err))
 		gmap.AddSpacer()
-		gmap.AddDirectory("← Back to Search", "/search")
+		gmap.AddSearch("← Search again", scope.selector)
 		return gmap.Bytes()
 	}
 
 	if len(events) == 0 {
 		gmap.AddInfo("No results found")
 		gmap.AddSpacer()
-		gmap.AddDirectory("← Back to Search", "/search")
+		gmap.AddSearch("← Search again", scope.selector)
+		gmap.AddDirectory("← Back to Home", "/")
 		return gmap.Bytes()
 	}
 
@@ -677,23 +812,25 @@ func (r *Router) handleSearch(ctx context.Context, params []string) []byte {
 	for _, event := range events {
 		switch event.Kind {
 		case 0: // Profile
-			gmap.AddTextFile(fmt.Sprintf("[Profile] %s", truncatePubkey(event.PubKey)),
+			gmap.AddDirectory(fmt.Sprintf("[Profile] %s", truncatePubkey(event.PubKey)),
 				fmt.Sprintf("/profile/%s", event.PubKey))
 
-		case 1: // Note
-			summary := getSummary(event.Content, 80)
-			gmap.AddTextFile(fmt.Sprintf("[Note] %s", summary),
+		case 30023: // Article
+			gmap.AddTextFile(fmt.Sprintf("[Article] %s", eventTitle(event)),
 				fmt.Sprintf("/note/%s", event.ID))
 
-		case 30023: // Article
-			summary := getSummary(event.Content, 80)
-			gmap.AddTextFile(fmt.Sprintf("[Article] %s", summary),
+		default: // Notes and other section content
+			summary, ok := nostrclient.ContentWarningLabel(event)
+			if !ok {
+				summary = getSummary(event.Content, 80)
+			}
+			gmap.AddTextFile(fmt.Sprintf("[Note] %s", summary),
 				fmt.Sprintf("/note/%s", event.ID))
 		}
 	}
 
 	gmap.AddSpacer()
-	gmap.AddDirectory("← Back to Search", "/search")
+	gmap.AddSearch("← Search again", scope.selector)
 	gmap.AddDirectory("← Back to Home", "/")
 
 	return gmap.Bytes()
@@ -755,7 +892,13 @@ func getSummary(content string, maxLen int) string {
 	return summary
 }
 
+// eventTitle returns the link text of an event in lists: its content warning,
+// article title or first line
 func eventTitle(event *nostr.Event) string {
+	if label, ok := nostrclient.ContentWarningLabel(event); ok {
+		return label
+	}
+
 	if event.Kind == 30023 {
 		if title := titleFromTags(event); title != "" {
 			return title
@@ -786,84 +929,10 @@ func titleFromTags(event *nostr.Event) string {
 	return ""
 }
 
-// handleSection renders a custom section
-func (r *Router) handleSection(ctx context.Context, section *sections.Section, path string) []byte {
-	gmap := NewGophermap(r.host, r.port)
-
-	// Parse page number from path
-	page := 1
-	// TODO: Extract page from path if needed
-
-	// Add header if configured
-	r.addHeaderToGophermap(gmap, section.Name)
-
-	// Get section page
-	sectionPage, err := r.server.GetSectionManager().GetPage(ctx, section.Name, page)
-	if err != nil {
-		gmap.AddError(fmt.Sprintf("Error loading section: %v", err))
-		gmap.AddSpacer()
-		gmap.AddDirectory("⌂ Home", "/")
-		return gmap.Bytes()
-	}
-
-	// Title
-	gmap.AddInfo(section.Title)
-	if section.Description != "" {
-		gmap.AddInfo(section.Description)
-	}
-	gmap.AddSpacer()
-
-	// Render events
-	if len(sectionPage.Events) > 0 {
-		for _, event := range sectionPage.Events {
-			linkText := eventTitle(event)
-
-			// Add author and timestamp if configured
-			if section.ShowAuthors && section.ShowDates {
-				gmap.AddInfo(fmt.Sprintf("   By %s - %s",
-					truncatePubkey(event.PubKey),
-					formatTimestamp(event.CreatedAt)))
-			} else if section.ShowAuthors {
-				gmap.AddInfo(fmt.Sprintf("   By %s", truncatePubkey(event.PubKey)))
-			} else if section.ShowDates {
-				gmap.AddInfo(fmt.Sprintf("   %s", formatTimestamp(event.CreatedAt)))
-			}
-
-			// Add the clickable link
-			gmap.AddTextFile(linkText, fmt.Sprintf("/note/%s", event.ID))
-			gmap.AddSpacer()
-		}
-	} else {
-		gmap.AddInfo("No content yet.")
-		gmap.AddSpacer()
-	}
-
-	// Add "more" link if configured
-	if section.MoreLink != nil {
-		// Get the target section to determine its path
-		targetSection, err := r.server.GetSectionManager().GetSection(section.MoreLink.SectionRef)
-		if err == nil && targetSection.Path != "" {
-			gmap.AddSpacer()
-			gmap.AddDirectory(fmt.Sprintf("→ %s", section.MoreLink.Text), targetSection.Path)
-		}
-	}
-
-	// Add pagination links
-	if sectionPage.TotalPages > 1 {
-		r.addPaginationLinks(gmap, path, page, int(sectionPage.TotalItems))
-	} else {
-		gmap.AddSpacer()
-		gmap.AddDirectory("⌂ Home", "/")
-	}
-
-	// Add footer if configured
-	r.addFooterToGophermap(gmap, section.Name)
-
-	return gmap.Bytes()
-}
-
-// handleSections renders multiple sections on a single page (e.g., homepage with multiple filtered views)
-func (r *Router) handleSections(ctx context.Context, sections []*sections.Section, path string) []byte {
+// handleSections renders the sections registered on a path (e.g., a homepage
+// with multiple filtered views). A path with a single section is paginated
+// with /page/N.
+func (r *Router) handleSections(ctx context.Context, sections []*sections.Section, path string, page int) []byte {
 	gmap := NewGophermap(r.host, r.port)
 
 	// Add header if first section has one configured
@@ -873,8 +942,7 @@ func (r *Router) handleSections(ctx context.Context, sections []*sections.Sectio
 
 	// Render each section in order
 	for i, section := range sections {
-		// Get section page (always page 1 for multi-section views)
-		sectionPage, err := r.server.GetSectionManager().GetPage(ctx, section.Name, 1)
+		sectionPage, err := r.server.GetSectionManager().GetPage(ctx, section.Name, page)
 		if err != nil {
 			gmap.AddError(fmt.Sprintf("Error loading section %s: %v", section.Name, err))
 			gmap.AddSpacer()
@@ -890,31 +958,32 @@ func (r *Router) handleSections(ctx context.Context, sections []*sections.Sectio
 		}
 		gmap.AddSpacer()
 
-		// Render events from section
-		if len(sectionPage.Events) > 0 {
-			for _, event := range sectionPage.Events {
-				linkText := eventTitle(event)
-
-				// Add author and timestamp if configured
-				if section.ShowAuthors && section.ShowDates {
-					gmap.AddInfo(fmt.Sprintf("   By %s - %s",
-						truncatePubkey(event.PubKey),
-						formatTimestamp(event.CreatedAt)))
-				} else if section.ShowAuthors {
-					gmap.AddInfo(fmt.Sprintf("   By %s", truncatePubkey(event.PubKey)))
-				} else if section.ShowDates {
-					gmap.AddInfo(fmt.Sprintf("   %s", formatTimestamp(event.CreatedAt)))
-				}
-
-				// Add the clickable link
-				gmap.AddTextFile(linkText, fmt.Sprintf("/note/%s", event.ID))
-				gmap.AddSpacer()
+		// Render events from section, under group headings if configured
+		if len(sectionPage.Groups) > 0 {
+			for _, group := range sectionPage.Groups {
+				title := r.groupTitle(ctx, section, group)
+				gmap.AddInfo(title)
+				gmap.AddInfo(strings.Repeat("─", utf8.RuneCountInString(title)))
+				r.addSectionEvents(gmap, section, group.Events)
 			}
+		} else if len(sectionPage.Events) > 0 {
+			r.addSectionEvents(gmap, section, sectionPage.Events)
 		} else {
 			gmap.AddInfo("No content yet.")
 			gmap.AddSpacer()
 		}
 
+		if len(sections) == 1 && (sectionPage.HasPrev || sectionPage.HasNext) {
+			if sectionPage.HasPrev {
+				gmap.AddDirectory("← Previous Page", sectionPageSelector(path, sectionPage.PageNumber-1))
+			}
+			if sectionPage.HasNext {
+				gmap.AddDirectory("→ Next Page", sectionPageSelector(path, sectionPage.PageNumber+1))
+			}
+			gmap.AddInfo(fmt.Sprintf("Page %d", sectionPage.PageNumber))
+			gmap.AddSpacer()
+		}
+
 		// Add "more" link if configured
 		if section.MoreLink != nil {
 			targetSection, err := r.server.GetSectionManager().GetSection(section.MoreLink.SectionRef)
@@ -924,6 +993,10 @@ func (r *Router) handleSections(ctx context.Context, sections []*sections.Sectio
 			}
 		}
 
+		gmap.AddSearch(fmt.Sprintf("Search %s", sectionLabel(section)), "/search/section/"+section.Name)
+		gmap.AddDirectory(fmt.Sprintf("%s archive", sectionLabel(section)), archiveSelector(section))
+		gmap.AddSpacer()
+
 		// Add separator between sections (except after last)
 		if i < len(sections)-1 {
 			gmap.AddInfo("─────────────────────────────────────────")
@@ -942,3 +1015,51 @@ func (r *Router) handleSections(ctx context.Context, sections []*sections.Sectio
 
 	return gmap.Bytes()
 }
+
+// addSectionEvents adds a section's events as menu items, with the author
+// and date lines the section is configured to show
+func (r *Router) addSectionEvents(gmap *Gophermap, section *sections.Section, events []*nostr.Event) {
+	for _, event := range events {
+		if section.ShowAuthors && section.ShowDates {
+			gmap.AddInfo(fmt.Sprintf("   By %s - %s",
+				truncatePubkey(event.PubKey),
+				formatTimestamp(event.CreatedAt)))
+		} else if section.ShowAuthors {
+			gmap.AddInfo(fmt.Sprintf("   By %s", truncatePubkey(event.PubKey)))
+		} else if section.ShowDates {
+			gmap.AddInfo(fmt.Sprintf("   %s", formatTimestamp(event.CreatedAt)))
+		}
+
+		gmap.AddTextFile(eventTitle(event), fmt.Sprintf("/note/%s", event.ID))
+		gmap.AddSpacer()
+	}
+}
+
+// groupTitle returns the heading of an event group: the group's date or
+// kind name, or the author's profile name
+func (r *Router) groupTitle(ctx context.Context, section *sections.Section, group *sections.EventGroup) string {
+	title := group.Title
+	if section.GroupBy == sections.GroupByAuthor {
+		title = r.renderer.resolver.DisplayName(ctx, group.Key)
+	}
+	if group.Continued {
+		title += " (continued)"
+	}
+	return title
+}
+
+// sectionPageSelector returns the selector of a page of a section path
+func sectionPageSelector(path string, page int) string {
+	if page <= 1 {
+		return path
+	}
+	return fmt.Sprintf("%s/page/%d", strings.TrimSuffix(path, "/"), page)
+}
+
+// sectionLabel returns a section's title, falling back to its name
+func sectionLabel(section *sections.Section) string {
+	if section.Title != "" {
+		return section.Title
+	}
+	return section.Name
+}
@@ -10,20 +10,30 @@ import (
 	"time"
 
 	"github.com/sandwichfarm/nophr/internal/aggregates"
+	"github.com/sandwichfarm/nophr/internal/cache"
 	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/ops"
 	"github.com/sandwichfarm/nophr/internal/sections"
+	"github.com/sandwichfarm/nophr/internal/security"
 	"github.com/sandwichfarm/nophr/internal/storage"
 )
 
 // Server implements a Gopher protocol server (RFC 1436)
 type Server struct {
 	config         *config.GopherProtocol
+	reloadMu       sync.RWMutex // held for reading while a request is served
+	mu             sync.RWMutex
 	fullConfig     *config.Config
 	storage        *storage.Storage
 	router         *Router
 	host           string
 	queryHelper    *aggregates.QueryHelper
 	sectionManager *sections.Manager
+	guard          *security.Guard
+	events         *aggregates.EventFilter
+	cache          cache.Cache // nil = rendered responses are not cached
+	cacheTTLs      *cache.RenderTTLs
+	metrics        *ops.Metrics // nil = requests are not counted
 
 	listener net.Listener
 	wg       sync.WaitGroup
@@ -43,12 +53,19 @@ func New(cfg *config.GopherProtocol, fullCfg *config.Config, st *storage.Storage
 		ctx:         ctx,
 		cancel:      cancel,
 		queryHelper: aggregates.NewQueryHelper(st, fullCfg, aggMgr),
+		guard:       security.NewGuard(&fullCfg.Security),
 	}
 
+	// Hide events from denied pubkeys, with banned content or muted by the
+	// owner everywhere
+	s.events = aggregates.NewEventFilter(s.guard, aggregates.NewMuteFilter(st, fullCfg))
+	s.queryHelper.SetEventFilter(s.events.Filter)
+
 	// Initialize sections manager (opt-in for custom filtered views)
 	// Sections are available but not auto-registered
 	// Users can configure custom sections via config for filtered views
 	s.sectionManager = sections.NewManager(st, fullCfg.Identity.Npub)
+	s.sectionManager.SetEventFilter(s.queryHelper.FilterFeedEvents)
 
 	// Initialize router
 	s.router = NewRouter(s, host, cfg.Port)
@@ -89,6 +106,7 @@ func (s *Server) Stop() error {
 	}
 
 	s.wg.Wait()
+	s.guard.Close()
 	return nil
 }
 
@@ -119,6 +137,14 @@ func (s *Server) handleConnection(conn net.Conn) {
 	defer s.wg.Done()
 	defer conn.Close()
 
+	clientIP := security.ClientIP(conn.RemoteAddr())
+	if err := s.guard.AcquireConnection(clientIP); err != nil {
+		fmt.Printf("Gopher connection refused for %s: %v\n", clientIP, err)
+		s.refuse(conn, err.Error())
+		return
+	}
+	defer s.guard.ReleaseConnection(clientIP)
+
 	// Set read timeout
 	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
 
@@ -130,14 +156,31 @@ func (s *Server) handleConnection(conn net.Conn) {
 		return
 	}
 
-	// Clean selector (remove CRLF and trim)
-	selector := strings.TrimSpace(line)
+	start := time.Now()
+	selector, query := parseRequest(line)
 
 	// Log request
-	fmt.Printf("Gopher request: %q from %s\n", selector, conn.RemoteAddr())
+	if query != "" {
+		fmt.Printf("Gopher request: %q query %q from %s\n", selector, query, conn.RemoteAddr())
+	} else {
+		fmt.Printf("Gopher request: %q from %s\n", selector, conn.RemoteAddr())
+	}
+
+	if ok, retryAfter := s.guard.AllowRequest(security.ProtocolGopher, clientIP); !ok {
+		fmt.Printf("Gopher request rate limited for %s\n", clientIP)
+		s.refuse(conn, fmt.Sprintf("Rate limit exceeded, retry in %d seconds", security.RetrySeconds(retryAfter)))
+		s.metrics.ObserveRequest(security.ProtocolGopher, "rate_limited", time.Since(start))
+		return
+	}
 
 	// Route request
-	response := s.router.Route(selector)
+	response := s.route(selector, query)
+
+	status := "ok"
+	if len(response) > 0 && response[0] == byte(ItemTypeError) {
+		status = "error"
+	}
+	s.metrics.ObserveRequest(security.ProtocolGopher, status, time.Since(start))
 
 	// Write response
 	conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
@@ -147,6 +190,69 @@ func (s *Server) handleConnection(conn net.Conn) {
 	}
 }
 
+// route renders a selector and search query, serving and storing it in the
+// response cache when one is configured
+func (s *Server) route(selector, query string) []byte {
+	s.reloadMu.RLock()
+	defer s.reloadMu.RUnlock()
+
+	if s.cache == nil || !cacheableSelector(selector) {
+		return s.router.Route(selector, query)
+	}
+
+	if selector == "" {
+		selector = "/"
+	}
+	key := cache.GopherKey(selector)
+	if query != "" {
+		key = cache.GopherKey(selector + "\t" + query)
+	}
+
+	if data, hit, err := s.cache.Get(s.ctx, key); err == nil && hit {
+		return data
+	}
+
+	response := s.router.Route(selector, query)
+
+	// Error menus (e.g. note not found) are not cached
+	if ttl := s.cacheTTLs.Gopher(selector); ttl > 0 && len(response) > 0 && response[0] != byte(ItemTypeError) {
+		if err := s.cache.Set(s.ctx, key, response, ttl); err != nil {
+			fmt.Printf("Cache set error: %v\n", err)
+		}
+	}
+
+	return response
+}
+
+// parseRequest splits a request line into selector and search query. Per
+// RFC 1436 a type-7 client sends the query after the selector, separated by
+// a TAB; any further fields (such as a Gopher+ "+") are ignored.
+func parseRequest(line string) (selector, query string) {
+	line = strings.TrimRight(line, "\r\n")
+
+	selector, rest, found := strings.Cut(line, "\t")
+	if found {
+		query, _, _ = strings.Cut(rest, "\t")
+	}
+
+	return strings.TrimSpace(selector), strings.TrimSpace(query)
+}
+
+// cacheableSelector reports whether a selector's response may be cached.
+// Diagnostics shows live stats and is always rendered fresh.
+func cacheableSelector(selector string) bool {
+	return !strings.HasPrefix(selector, "/diagnostics")
+}
+
+// refuse writes a type-3 error menu and leaves the connection to be closed
+func (s *Server) refuse(conn net.Conn, message string) {
+	gmap := NewGophermap(s.host, s.config.Port)
+	gmap.AddError(message)
+
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	conn.Write(gmap.Bytes())
+}
+
 // GetStorage returns the storage instance
 func (s *Server) GetStorage() *storage.Storage {
 	return s.storage
@@ -167,7 +273,68 @@ func (s *Server) GetQueryHelper() *aggregates.QueryHelper {
 	return s.queryHelper
 }
 
+// SetCache enables response caching with TTLs from the caching config
+func (s *Server) SetCache(c cache.Cache) {
+	s.cache = c
+	s.cacheTTLs = cache.NewRenderTTLs(&s.settings().Caching)
+}
+
+// SetMetrics enables request counters and latency histograms
+func (s *Server) SetMetrics(m *ops.Metrics) {
+	s.metrics = m
+}
+
+// GetCache returns the response cache, or nil if caching is disabled
+func (s *Server) GetCache() cache.Cache {
+	return s.cache
+}
+
+// SetGuard replaces the server's own guard with one shared by every
+// server, so connection caps count connections to all of them together
+func (s *Server) SetGuard(g *security.Guard) {
+	s.guard.Close()
+	s.guard = g
+	s.events.SetGuard(g)
+}
+
+// GetGuard returns the security guard
+func (s *Server) GetGuard() *security.Guard {
+	return s.guard
+}
+
 // GetSectionManager returns the section manager instance
 func (s *Server) GetSectionManager() *sections.Manager {
 	return s.sectionManager
 }
+
+// PrepareReload converts the sections of a new configuration and returns a
+// function that switches the server to it: sections, display, presentation
+// and rendering settings. Listener and security settings keep their startup
+// values. The switch cannot fail and waits for requests in flight, so every
+// request is served entirely with the old settings or the new ones.
+func (s *Server) PrepareReload(cfg *config.Config) (func(), error) {
+	list, err := sections.FromConfig(cfg.Sections)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		s.reloadMu.Lock()
+		defer s.reloadMu.Unlock()
+
+		s.sectionManager.ReplaceSections(list)
+		s.router.renderer.SetConfig(cfg)
+		s.queryHelper.SetConfig(cfg)
+
+		s.mu.Lock()
+		s.fullConfig = cfg
+		s.mu.Unlock()
+	}, nil
+}
+
+// settings returns the configuration the server currently follows
+func (s *Server) settings() *config.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.fullConfig
+}
@@ -3,17 +3,26 @@ package gopher
 import (
 	"bufio"
 	"context"
+	"flag"
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/nbd-wtf/go-nostr"
 	"github.com/sandwichfarm/nophr/internal/aggregates"
+	"github.com/sandwichfarm/nophr/internal/cache"
 	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/sections"
 	"github.com/sandwichfarm/nophr/internal/storage"
+	"github.com/sandwichfarm/nophr/internal/storage/storagetest"
 )
 
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
 func TestGopherProtocol(t *testing.T) {
 	// Create test config
 	cfg := &config.Config{
@@ -192,6 +201,409 @@ func TestRendererOutput(t *testing.T) {
 	if lines[0] != "Test List" {
 		t.Errorf("First line should be title, got: %s", lines[0])
 	}
+	// Sensitive notes collapse to their warning in lists; the note page reveals them
+	note := &nostr.Event{
+		ID:        "cw1",
+		Kind:      1,
+		CreatedAt: nostr.Now(),
+		Tags:      nostr.Tags{{"content-warning", "spoilers"}},
+		Content:   "the butler did it",
+	}
+	lines = renderer.RenderNoteList([]*aggregates.EnrichedEvent{{Event: note}}, "Notes")
+	if lines[3] != "1. CW: spoilers" {
+		t.Errorf("Sensitive note should be listed by its warning, got: %s", lines[3])
+	}
+	if title := eventTitle(note); title != "CW: spoilers" {
+		t.Errorf("eventTitle() = %q, want the warning", title)
+	}
+
+	text := renderer.RenderNote(note, nil)
+	if !strings.Contains(text, "⚠ CW: spoilers") || !strings.Contains(text, "the butler did it") {
+		t.Errorf("Note page should show the warning and the content, got:\n%s", text)
+	}
+}
+
+func TestGopherRateLimit(t *testing.T) {
+	cfg := &config.Config{
+		Identity: config.Identity{
+			Npub: "npub1nq3zgtqruwhnz0xx40gh4a4fkamlr2sc7ke5wqs2s3nyv2fpy9esg4hdwq",
+		},
+		Storage: config.Storage{
+			Driver:     "sqlite",
+			SQLitePath: ":memory:",
+		},
+		Security: config.Security{
+			Enabled:    true,
+			RateLimits: config.SecurityRateLimits{Gopher: 1},
+		},
+	}
+
+	gopherCfg := &config.GopherProtocol{
+		Enabled: true,
+		Host:    "localhost",
+		Port:    17071,
+	}
+
+	ctx := context.Background()
+	st, err := storage.New(ctx, &cfg.Storage)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	server := New(gopherCfg, cfg, st, "localhost", aggregates.NewManager(st, cfg))
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	response := sendGopherRequest(t, gopherCfg.Port, "")
+	if !strings.Contains(response, "nophr") {
+		t.Fatalf("First request should be served, got: %s", response)
+	}
+
+	response = sendGopherRequest(t, gopherCfg.Port, "")
+	if !strings.HasPrefix(response, "3Rate limit exceeded") {
+		t.Errorf("Second request should get a type-3 rate limit error, got: %s", response)
+	}
+	if !strings.HasSuffix(response, ".\r\n") {
+		t.Errorf("Refusal should end with gopher terminator '.\\r\\n'")
+	}
+}
+
+func TestGopherResponseCache(t *testing.T) {
+	cfg := &config.Config{
+		Identity: config.Identity{
+			Npub: "npub1nq3zgtqruwhnz0xx40gh4a4fkamlr2sc7ke5wqs2s3nyv2fpy9esg4hdwq",
+		},
+		Storage: config.Storage{
+			Driver:     "sqlite",
+			SQLitePath: ":memory:",
+		},
+		Caching: config.Caching{
+			Enabled: true,
+			TTL: config.CacheTTL{
+				Sections: map[string]int{"notes": 60},
+				Render:   map[string]int{"gopher_menu": 300},
+			},
+		},
+	}
+
+	gopherCfg := &config.GopherProtocol{
+		Enabled: true,
+		Host:    "localhost",
+		Port:    17072,
+	}
+
+	ctx := context.Background()
+	st, err := storage.New(ctx, &cfg.Storage)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	c := cache.NewMemoryCache(cache.DefaultConfig())
+	defer c.Close()
+
+	server := New(gopherCfg, cfg, st, "localhost", aggregates.NewManager(st, cfg))
+	server.SetCache(c)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	first := sendGopherRequest(t, gopherCfg.Port, "/notes")
+	second := sendGopherRequest(t, gopherCfg.Port, "/notes")
+	if first != second {
+		t.Errorf("Cached response should match the rendered one")
+	}
+
+	if hit, _ := c.Has(ctx, cache.GopherKey("/notes")); !hit {
+		t.Fatal("Expected /notes to be cached")
+	}
+
+	stats, _ := c.Stats(ctx)
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Expected 1 hit and 1 miss, got %d/%d", stats.Hits, stats.Misses)
+	}
+
+	// Diagnostics is never cached and reports the stats
+	response := sendGopherRequest(t, gopherCfg.Port, "/diagnostics")
+	if !strings.Contains(response, "Hits: 1") || !strings.Contains(response, "Misses: 1") {
+		t.Errorf("Diagnostics should show cache stats, got: %s", response)
+	}
+	if hit, _ := c.Has(ctx, cache.GopherKey("/diagnostics")); hit {
+		t.Error("Diagnostics should not be cached")
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		line     string
+		selector string
+		query    string
+	}{
+		{"\r\n", "", ""},
+		{"/notes\r\n", "/notes", ""},
+		{"/search\tbitcoin\r\n", "/search", "bitcoin"},
+		{"/search\tnostr protocol\r\n", "/search", "nostr protocol"},
+		{"/search/articles\tgopher\t+\r\n", "/search/articles", "gopher"},
+		{"/search\t\r\n", "/search", ""},
+	}
+
+	for _, tt := range tests {
+		selector, query := parseRequest(tt.line)
+		if selector != tt.selector || query != tt.query {
+			t.Errorf("parseRequest(%q) = %q, %q; want %q, %q", tt.line, selector, query, tt.selector, tt.query)
+		}
+	}
+}
+
+func TestGopherSearch(t *testing.T) {
+	cfg := &config.Config{
+		Identity: config.Identity{
+			Npub: "npub1nq3zgtqruwhnz0xx40gh4a4fkamlr2sc7ke5wqs2s3nyv2fpy9esg4hdwq",
+		},
+		Storage: config.Storage{
+			Driver:     "sqlite",
+			SQLitePath: ":memory:",
+		},
+	}
+
+	gopherCfg := &config.GopherProtocol{
+		Enabled: true,
+		Host:    "localhost",
+		Port:    17073,
+	}
+
+	ctx := context.Background()
+	st, err := storage.New(ctx, &cfg.Storage)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	priv := nostr.GeneratePrivateKey()
+	pub, _ := nostr.GetPublicKey(priv)
+
+	events := []nostr.Event{
+		{Kind: 1, Content: "bitcoin fixes this"},
+		{Kind: 30023, Content: "a long read about bitcoin", Tags: nostr.Tags{{"title", "Sound Money"}}},
+	}
+	for i := range events {
+		events[i].PubKey = pub
+		events[i].CreatedAt = nostr.Timestamp(time.Now().Unix() + int64(i))
+		if err := events[i].Sign(priv); err != nil {
+			t.Fatalf("Failed to sign event: %v", err)
+		}
+		if err := st.StoreEvent(ctx, &events[i]); err != nil {
+			t.Fatalf("Failed to store event: %v", err)
+		}
+	}
+
+	server := New(gopherCfg, cfg, st, "localhost", aggregates.NewManager(st, cfg))
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+
+	// Home advertises a real type-7 search item
+	home := sendGopherRequest(t, gopherCfg.Port, "")
+	if !strings.Contains(home, "7Search\t/search\t") {
+		t.Errorf("Home should contain a type-7 search item, got: %s", home)
+	}
+
+	tests := []struct {
+		name     string
+		request  string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "global",
+			request:  "/search\tbitcoin",
+			contains: []string{"Found 2 results", "bitcoin fixes this", "Sound Money"},
+		},
+		{
+			name:     "legacy path query",
+			request:  "/search/bitcoin+fixes",
+			contains: []string{"Found 1 results", "bitcoin fixes this"},
+		},
+		{
+			name:     "articles",
+			request:  "/search/articles\tbitcoin",
+			contains: []string{"Found 1 results", "[Article] Sound Money"},
+			excludes: []string{"bitcoin fixes this"},
+		},
+		{
+			name:     "profile",
+			request:  "/search/profile/" + pub + "\tfixes",
+			contains: []string{"Found 1 results", "bitcoin fixes this"},
+		},
+		{
+			name:     "no query prompts",
+			request:  "/search/articles",
+			contains: []string{"7Enter search terms\t/search/articles\t"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := sendGopherRequest(t, gopherCfg.Port, tt.request)
+			for _, want := range tt.contains {
+				if !strings.Contains(response, want) {
+					t.Errorf("Expected %q in response, got: %s", want, response)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(response, unwanted) {
+					t.Errorf("Did not expect %q in response, got: %s", unwanted, response)
+				}
+			}
+		})
+	}
+
+	// The articles page offers a scoped search item
+	articles := sendGopherRequest(t, gopherCfg.Port, "/articles")
+	if !strings.Contains(articles, "7Search articles\t/search/articles\t") {
+		t.Errorf("Articles page should offer a scoped search, got: %s", articles)
+	}
+}
+
+func TestGopherSectionArchive(t *testing.T) {
+	cfg := &config.Config{
+		Identity: config.Identity{
+			Npub: "npub1nq3zgtqruwhnz0xx40gh4a4fkamlr2sc7ke5wqs2s3nyv2fpy9esg4hdwq",
+		},
+		Storage: config.Storage{
+			Driver:     "sqlite",
+			SQLitePath: ":memory:",
+		},
+	}
+
+	ctx := context.Background()
+	st, err := storage.New(ctx, &cfg.Storage)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	priv := nostr.GeneratePrivateKey()
+	pub, _ := nostr.GetPublicKey(priv)
+
+	for _, createdAt := range []time.Time{
+		time.Date(2024, time.February, 29, 9, 0, 0, 0, time.UTC),
+		time.Date(2025, time.October, 24, 9, 0, 0, 0, time.UTC),
+	} {
+		event := nostr.Event{Kind: 1, PubKey: pub, CreatedAt: nostr.Timestamp(createdAt.Unix()), Content: "leap day note"}
+		if err := event.Sign(priv); err != nil {
+			t.Fatalf("Failed to sign event: %v", err)
+		}
+		if err := st.StoreEvent(ctx, &event); err != nil {
+			t.Fatalf("Failed to store event: %v", err)
+		}
+	}
+
+	server := New(&config.GopherProtocol{Enabled: true, Host: "localhost", Port: 17074}, cfg, st, "localhost", aggregates.NewManager(st, cfg))
+	if err := server.GetSectionManager().RegisterSection(&sections.Section{
+		Name:    "diary",
+		Path:    "/diary",
+		Title:   "Diary",
+		Filters: sections.FilterSet{Kinds: []int{1}, Authors: []string{pub}},
+	}); err != nil {
+		t.Fatalf("Failed to register section: %v", err)
+	}
+
+	tests := []struct {
+		selector string
+		contains []string
+	}{
+		{"/diary", []string{"1Diary archive\t/diary/archive\t"}},
+		{"/diary/archive", []string{"Diary - Archive", "12025 (1)\t/diary/archive/2025\t", "12024 (1)\t/diary/archive/2024\t"}},
+		{"/diary/archive/2024", []string{"1February 2024 (1)\t/diary/archive/2024/02\t", "1↑ Up\t/diary/archive\t"}},
+		{"/diary/archive/2024/02", []string{"Su  Mo  Tu  We  Th  Fr  Sa", "25  26  27  28  29*", "1February 29, 2024 (1)\t/diary/archive/2024/02/29\t"}},
+		{"/diary/archive/2024/02/29", []string{"0leap day note\t/note/"}},
+		{"/diary/archive/2024/02/30", []string{"3Invalid archive: invalid day: 30"}},
+		{"/missing/archive", []string{"3Unknown section: missing"}},
+	}
+
+	for _, tt := range tests {
+		response := string(server.router.Route(tt.selector, ""))
+		for _, want := range tt.contains {
+			if !strings.Contains(response, want) {
+				t.Errorf("Route(%s): expected %q in response, got: %s", tt.selector, want, response)
+			}
+		}
+	}
+}
+
+func TestGopherSectionGroups(t *testing.T) {
+	cfg := &config.Config{
+		Identity: config.Identity{
+			Npub: "npub1nq3zgtqruwhnz0xx40gh4a4fkamlr2sc7ke5wqs2s3nyv2fpy9esg4hdwq",
+		},
+		Storage: config.Storage{
+			Driver:     "sqlite",
+			SQLitePath: ":memory:",
+		},
+	}
+
+	ctx := context.Background()
+	st, err := storage.New(ctx, &cfg.Storage)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	alicePub, bobPub := storagetest.SeedSectionGroups(t, st)
+
+	server := New(&config.GopherProtocol{Enabled: true, Host: "localhost", Port: 17075}, cfg, st, "localhost", aggregates.NewManager(st, cfg))
+	for _, section := range []*sections.Section{
+		{Name: "weekly", Path: "/weekly", Title: "Weekly", Limit: 3, GroupBy: sections.GroupByWeek},
+		{Name: "people", Path: "/people", Title: "People", GroupBy: sections.GroupByAuthor},
+		{Name: "kinds", Path: "/kinds", Title: "Kinds", GroupBy: sections.GroupByKind},
+	} {
+		section.Filters = sections.FilterSet{Kinds: []int{1, 30023}, Authors: []string{alicePub, bobPub}}
+		section.SortBy, section.SortOrder = sections.SortByCreatedAt, sections.SortDesc
+		if err := server.GetSectionManager().RegisterSection(section); err != nil {
+			t.Fatalf("Failed to register section: %v", err)
+		}
+	}
+
+	tests := []struct {
+		selector string
+		golden   string
+	}{
+		{"/weekly", "section_weekly.gophermap"},
+		{"/weekly/page/2", "section_weekly_page2.gophermap"},
+		{"/people", "section_people.gophermap"},
+		{"/kinds", "section_kinds.gophermap"},
+	}
+
+	for _, tt := range tests {
+		response := server.router.Route(tt.selector, "")
+		golden := filepath.Join("testdata", tt.golden)
+		if *update {
+			if err := os.WriteFile(golden, response, 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", golden, err)
+			}
+			continue
+		}
+
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", golden, err)
+		}
+		if string(response) != string(want) {
+			t.Errorf("Route(%s) does not match %s:\n%s", tt.selector, golden, response)
+		}
+	}
 }
 
 // Helper function to send a Gopher request
@@ -193,6 +193,32 @@ func TestRenderFingerWithWidthLimit(t *testing.T) {
 	}
 }
 
+func TestRenderHTML(t *testing.T) {
+	p := NewParser()
+	output, err := p.RenderHTML([]byte(sampleMarkdown), false)
+	if err != nil {
+		t.Fatalf("RenderHTML() error = %v", err)
+	}
+
+	for _, expected := range []string{`<h1 id="main-heading">Main Heading</h1>`, "<strong>bold</strong>", `<a href="https://example.com">link</a>`, "<blockquote>"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Output missing %s", expected)
+		}
+	}
+
+	unsafe := "line one\nline two <script>alert(1)</script> [x](javascript:alert(1))"
+	output, err = p.RenderHTML([]byte(unsafe), true)
+	if err != nil {
+		t.Fatalf("RenderHTML() error = %v", err)
+	}
+	if strings.Contains(output, "<script>") || strings.Contains(output, "javascript:") {
+		t.Errorf("Raw HTML and dangerous links should be dropped: %s", output)
+	}
+	if !strings.Contains(output, "line one<br>") {
+		t.Errorf("Hard wraps should become line breaks: %s", output)
+	}
+}
+
 func TestExtractText(t *testing.T) {
 	p := NewParser()
 	source := []byte("# Heading\n\nParagraph with **bold**.")
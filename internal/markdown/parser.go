@@ -7,6 +7,8 @@ import (
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
 	"github.com/yuin/goldmark/text"
 )
 
@@ -71,6 +73,28 @@ func (p *Parser) RenderFinger(source []byte, opts *RenderOptions) (string, error
 	return renderer.Render(doc, source), nil
 }
 
+// RenderHTML renders markdown as an HTML fragment. Raw HTML in the source is
+// omitted and dangerous link schemes are dropped. hardWraps turns single
+// newlines into line breaks, which suits notes written as plain text.
+func (p *Parser) RenderHTML(source []byte, hardWraps bool) (string, error) {
+	var rendererOpts []renderer.Option
+	if hardWraps {
+		rendererOpts = append(rendererOpts, html.WithHardWraps())
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		goldmark.WithRendererOptions(rendererOpts...),
+	)
+
+	var buf bytes.Buffer
+	if err := md.Convert(source, &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 // RenderOptions contains configuration for rendering
 type RenderOptions struct {
 	// Width is the maximum line width (0 = no wrapping)
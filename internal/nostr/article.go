@@ -0,0 +1,56 @@
+package nostr
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// KindArticle is the NIP-23 long-form content kind
+const KindArticle = 30023
+
+// ArticleMetadata holds the NIP-23 tags of a long-form article
+type ArticleMetadata struct {
+	Identifier  string    // d tag
+	Title       string    // title tag
+	Summary     string    // summary tag
+	Image       string    // image tag (URL)
+	PublishedAt time.Time // published_at tag, falling back to created_at
+	Hashtags    []string  // t tags
+}
+
+// ParseArticle extracts the metadata of a kind 30023 event
+// Returns nil if the event is not an article
+func ParseArticle(event *nostr.Event) *ArticleMetadata {
+	if event == nil || event.Kind != KindArticle {
+		return nil
+	}
+
+	article := &ArticleMetadata{
+		PublishedAt: event.CreatedAt.Time(),
+	}
+	for _, tag := range event.Tags {
+		if len(tag) < 2 || tag[1] == "" {
+			continue
+		}
+		switch tag[0] {
+		case "d":
+			article.Identifier = tag[1]
+		case "title":
+			article.Title = tag[1]
+		case "summary":
+			article.Summary = tag[1]
+		case "image":
+			article.Image = tag[1]
+		case "published_at":
+			if ts, err := strconv.ParseInt(tag[1], 10, 64); err == nil && ts > 0 {
+				article.PublishedAt = time.Unix(ts, 0)
+			}
+		case "t":
+			article.Hashtags = append(article.Hashtags, tag[1])
+		}
+	}
+
+	return article
+}
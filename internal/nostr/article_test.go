@@ -0,0 +1,50 @@
+package nostr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestParseArticle(t *testing.T) {
+	event := &nostr.Event{
+		Kind:      KindArticle,
+		CreatedAt: 2000,
+		Tags: nostr.Tags{
+			{"d", "hello-world"},
+			{"title", "Hello World"},
+			{"summary", "A first post"},
+			{"image", "https://example.com/cover.png"},
+			{"published_at", "1000"},
+			{"t", "intro"},
+		},
+	}
+
+	article := ParseArticle(event)
+	if article == nil {
+		t.Fatal("ParseArticle() returned nil")
+	}
+	if article.Identifier != "hello-world" || article.Title != "Hello World" || article.Summary != "A first post" {
+		t.Errorf("unexpected metadata: %+v", article)
+	}
+	if article.Image != "https://example.com/cover.png" {
+		t.Errorf("Image = %q", article.Image)
+	}
+	if !article.PublishedAt.Equal(time.Unix(1000, 0)) {
+		t.Errorf("PublishedAt = %v, want published_at tag", article.PublishedAt)
+	}
+	if len(article.Hashtags) != 1 || article.Hashtags[0] != "intro" {
+		t.Errorf("Hashtags = %v", article.Hashtags)
+	}
+
+	// Without published_at the creation time is used
+	article = ParseArticle(&nostr.Event{Kind: KindArticle, CreatedAt: 2000})
+	if !article.PublishedAt.Equal(time.Unix(2000, 0)) {
+		t.Errorf("PublishedAt = %v, want created_at", article.PublishedAt)
+	}
+
+	if ParseArticle(&nostr.Event{Kind: 1}) != nil {
+		t.Error("ParseArticle() should return nil for non-article kinds")
+	}
+}
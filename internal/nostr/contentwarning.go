@@ -0,0 +1,60 @@
+package nostr
+
+import "github.com/nbd-wtf/go-nostr"
+
+// ContentWarning returns the reason of an event's NIP-36 content-warning tag.
+// ok is false when the event has no such tag; the reason is optional.
+func ContentWarning(event *nostr.Event) (reason string, ok bool) {
+	for _, tag := range event.Tags {
+		if len(tag) == 0 || tag[0] != "content-warning" {
+			continue
+		}
+		if len(tag) > 1 {
+			reason = tag[1]
+		}
+		return reason, true
+	}
+	return "", false
+}
+
+// IsSensitive reports whether an event carries a NIP-36 content warning
+func IsSensitive(event *nostr.Event) bool {
+	_, ok := ContentWarning(event)
+	return ok
+}
+
+// ContentWarningLabel returns the "CW: reason" line shown in place of a
+// sensitive event's content in lists. ok is false when there is no warning.
+func ContentWarningLabel(event *nostr.Event) (label string, ok bool) {
+	reason, ok := ContentWarning(event)
+	if !ok {
+		return "", false
+	}
+	if reason == "" {
+		reason = "sensitive content"
+	}
+	return "CW: " + reason, true
+}
+
+// RevealedWarning returns the "⚠ CW: reason" line shown above a sensitive
+// event's content on its own page. Lists collapse sensitive events to their
+// warning, so the page that reveals the content repeats it. It returns ""
+// when the event has no warning.
+func RevealedWarning(event *nostr.Event) string {
+	label, ok := ContentWarningLabel(event)
+	if !ok {
+		return ""
+	}
+	return "⚠ " + label
+}
+
+// WithoutSensitive removes events carrying a content warning
+func WithoutSensitive(events []*nostr.Event) []*nostr.Event {
+	filtered := make([]*nostr.Event, 0, len(events))
+	for _, event := range events {
+		if !IsSensitive(event) {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
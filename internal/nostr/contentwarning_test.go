@@ -0,0 +1,54 @@
+package nostr
+
+import (
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestContentWarningLabel(t *testing.T) {
+	tests := []struct {
+		name  string
+		tags  nostr.Tags
+		label string
+		hasCW bool
+	}{
+		{"no warning", nostr.Tags{{"t", "nostr"}}, "", false},
+		{"with reason", nostr.Tags{{"content-warning", "spoilers"}}, "CW: spoilers", true},
+		{"without reason", nostr.Tags{{"content-warning"}}, "CW: sensitive content", true},
+		{"empty reason", nostr.Tags{{"content-warning", ""}}, "CW: sensitive content", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &nostr.Event{Kind: 1, Tags: tt.tags}
+			label, ok := ContentWarningLabel(event)
+			if label != tt.label || ok != tt.hasCW {
+				t.Errorf("ContentWarningLabel() = %q, %v, want %q, %v", label, ok, tt.label, tt.hasCW)
+			}
+			if IsSensitive(event) != tt.hasCW {
+				t.Errorf("IsSensitive() = %v, want %v", IsSensitive(event), tt.hasCW)
+			}
+		})
+	}
+}
+
+func TestRevealedWarning(t *testing.T) {
+	if got := RevealedWarning(&nostr.Event{Kind: 1}); got != "" {
+		t.Errorf("RevealedWarning() = %q for a note without a warning", got)
+	}
+	sensitive := &nostr.Event{Kind: 1, Tags: nostr.Tags{{"content-warning", "spoilers"}}}
+	if got := RevealedWarning(sensitive); got != "⚠ CW: spoilers" {
+		t.Errorf("RevealedWarning() = %q, want %q", got, "⚠ CW: spoilers")
+	}
+}
+
+func TestWithoutSensitive(t *testing.T) {
+	plain := &nostr.Event{ID: "plain"}
+	sensitive := &nostr.Event{ID: "sensitive", Tags: nostr.Tags{{"content-warning", "nsfw"}}}
+
+	filtered := WithoutSensitive([]*nostr.Event{plain, sensitive})
+	if len(filtered) != 1 || filtered[0] != plain {
+		t.Errorf("WithoutSensitive() kept %d events, want only the plain one", len(filtered))
+	}
+}
@@ -0,0 +1,128 @@
+package nostr
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip04"
+	"github.com/nbd-wtf/go-nostr/nip44"
+)
+
+// KindMuteList is the NIP-51 mute list kind
+const KindMuteList = 10000
+
+// MuteList is a parsed NIP-51 mute list. Hashtags and words are lowercase.
+type MuteList struct {
+	Pubkeys  map[string]bool
+	Hashtags map[string]bool
+	Words    []string
+	Threads  map[string]bool // muted thread (root event) IDs
+}
+
+// ParseMuteList parses a kind 10000 event. The encrypted private entries in
+// its content are included when the author's secret key is given; otherwise
+// only the public tags are read.
+func ParseMuteList(event *nostr.Event, secretKey string) (*MuteList, error) {
+	if event.Kind != KindMuteList {
+		return nil, fmt.Errorf("expected kind %d, got %d", KindMuteList, event.Kind)
+	}
+
+	list := &MuteList{
+		Pubkeys:  make(map[string]bool),
+		Hashtags: make(map[string]bool),
+		Threads:  make(map[string]bool),
+	}
+	list.add(event.Tags)
+
+	if event.Content == "" || secretKey == "" {
+		return list, nil
+	}
+
+	plaintext, err := decryptToSelf(event.Content, event.PubKey, secretKey)
+	if err != nil {
+		return list, fmt.Errorf("failed to decrypt private mutes: %w", err)
+	}
+
+	var private nostr.Tags
+	if err := json.Unmarshal([]byte(plaintext), &private); err != nil {
+		return list, fmt.Errorf("invalid private mutes: %w", err)
+	}
+	list.add(private)
+
+	return list, nil
+}
+
+// add records the mute entries of a set of tags
+func (m *MuteList) add(tags nostr.Tags) {
+	for _, tag := range tags {
+		if len(tag) < 2 || tag[1] == "" {
+			continue
+		}
+		switch tag[0] {
+		case "p":
+			m.Pubkeys[tag[1]] = true
+		case "t":
+			m.Hashtags[strings.ToLower(tag[1])] = true
+		case "word":
+			m.Words = append(m.Words, strings.ToLower(tag[1]))
+		case "e":
+			m.Threads[tag[1]] = true
+		}
+	}
+}
+
+// Mutes reports whether an event is hidden by the list: its author is muted,
+// it carries a muted hashtag or word, or it belongs to a muted thread
+func (m *MuteList) Mutes(event *nostr.Event) bool {
+	if m.Pubkeys[event.PubKey] || m.Threads[event.ID] {
+		return true
+	}
+
+	for _, tag := range event.Tags {
+		if len(tag) < 2 {
+			continue
+		}
+		if tag[0] == "t" && m.Hashtags[strings.ToLower(tag[1])] {
+			return true
+		}
+		if tag[0] == "e" && m.Threads[tag[1]] {
+			return true
+		}
+	}
+
+	if len(m.Words) > 0 {
+		content := strings.ToLower(event.Content)
+		for _, word := range m.Words {
+			if strings.Contains(content, word) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// IsEmpty reports whether the list mutes nothing
+func (m *MuteList) IsEmpty() bool {
+	return len(m.Pubkeys) == 0 && len(m.Hashtags) == 0 && len(m.Words) == 0 && len(m.Threads) == 0
+}
+
+// decryptToSelf decrypts content a pubkey encrypted to itself, with NIP-44
+// or, for older lists, NIP-04
+func decryptToSelf(content, pubkey, secretKey string) (string, error) {
+	if strings.Contains(content, "?iv=") {
+		shared, err := nip04.ComputeSharedSecret(pubkey, secretKey)
+		if err != nil {
+			return "", err
+		}
+		return nip04.Decrypt(content, shared)
+	}
+
+	key, err := nip44.GenerateConversationKey(pubkey, secretKey)
+	if err != nil {
+		return "", err
+	}
+	return nip44.Decrypt(content, key)
+}
@@ -0,0 +1,93 @@
+package nostr
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip04"
+	"github.com/nbd-wtf/go-nostr/nip44"
+)
+
+func TestParseMuteList(t *testing.T) {
+	sk := nostr.GeneratePrivateKey()
+	pk, _ := nostr.GetPublicKey(sk)
+
+	private, _ := json.Marshal(nostr.Tags{{"p", "secret-pubkey"}, {"word", "Spoilers"}})
+	key, _ := nip44.GenerateConversationKey(pk, sk)
+	nip44Content, err := nip44.Encrypt(string(private), key)
+	if err != nil {
+		t.Fatalf("nip44.Encrypt() error = %v", err)
+	}
+	shared, _ := nip04.ComputeSharedSecret(pk, sk)
+	nip04Content, err := nip04.Encrypt(string(private), shared)
+	if err != nil {
+		t.Fatalf("nip04.Encrypt() error = %v", err)
+	}
+
+	public := nostr.Tags{{"p", "loud-pubkey"}, {"t", "Crypto"}, {"e", "thread-root"}}
+
+	for _, tt := range []struct {
+		name        string
+		content     string
+		secretKey   string
+		wantPrivate bool
+	}{
+		{"public only", "", sk, false},
+		{"nip44 without key", nip44Content, "", false},
+		{"nip44", nip44Content, sk, true},
+		{"nip04", nip04Content, sk, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &nostr.Event{Kind: KindMuteList, PubKey: pk, Tags: public, Content: tt.content}
+			list, err := ParseMuteList(event, tt.secretKey)
+			if err != nil {
+				t.Fatalf("ParseMuteList() error = %v", err)
+			}
+
+			if !list.Pubkeys["loud-pubkey"] || !list.Hashtags["crypto"] || !list.Threads["thread-root"] {
+				t.Errorf("Missing public entries: %+v", list)
+			}
+			if got := list.Pubkeys["secret-pubkey"]; got != tt.wantPrivate {
+				t.Errorf("private pubkey muted = %v, want %v", got, tt.wantPrivate)
+			}
+			if got := len(list.Words) == 1 && list.Words[0] == "spoilers"; got != tt.wantPrivate {
+				t.Errorf("private word muted = %v, want %v (words %v)", got, tt.wantPrivate, list.Words)
+			}
+		})
+	}
+
+	if _, err := ParseMuteList(&nostr.Event{Kind: 3}, ""); err == nil {
+		t.Error("Expected an error for a non-mute-list kind")
+	}
+}
+
+func TestMuteListMutes(t *testing.T) {
+	list := &MuteList{
+		Pubkeys:  map[string]bool{"muted": true},
+		Hashtags: map[string]bool{"crypto": true},
+		Words:    []string{"spoiler"},
+		Threads:  map[string]bool{"root": true},
+	}
+
+	tests := []struct {
+		name  string
+		event *nostr.Event
+		want  bool
+	}{
+		{"muted author", &nostr.Event{PubKey: "muted"}, true},
+		{"muted hashtag", &nostr.Event{PubKey: "a", Tags: nostr.Tags{{"t", "CRYPTO"}}}, true},
+		{"muted word", &nostr.Event{PubKey: "a", Content: "Big SPOILER ahead"}, true},
+		{"muted thread root", &nostr.Event{ID: "root", PubKey: "a"}, true},
+		{"reply in muted thread", &nostr.Event{PubKey: "a", Tags: nostr.Tags{{"e", "root", "", "root"}}}, true},
+		{"unrelated", &nostr.Event{PubKey: "a", Content: "hello", Tags: nostr.Tags{{"t", "nostr"}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := list.Mutes(tt.event); got != tt.want {
+				t.Errorf("Mutes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package ops
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+// archiveStateVersion is the version of the state sidecar format
+const archiveStateVersion = 1
+
+// ArchiveState is the nophr state exported next to an event archive: what
+// relays have been synced up to, where authors publish, the social graph and
+// the retention decisions made so far. None of it can be derived from the
+// events alone.
+type ArchiveState struct {
+	Version       int                          `json:"version"`
+	ExportedAt    time.Time                    `json:"exported_at"`
+	Driver        string                       `json:"driver"`
+	SchemaVersion int                          `json:"schema_version"`
+	Cursors       []*storage.SyncState         `json:"cursors"`
+	RelayHints    []*storage.RelayHint         `json:"relay_hints"`
+	Graph         []*storage.GraphNode         `json:"graph"`
+	Retention     []*storage.RetentionMetadata `json:"retention"`
+}
+
+// StatePath returns where the state sidecar of an event archive is written:
+// events.jsonl goes with events.state.json
+func StatePath(eventsPath string) string {
+	return strings.TrimSuffix(eventsPath, ".jsonl") + ".state.json"
+}
+
+// ExportEvents writes the events matching filter to w as JSON lines, one
+// NIP-01 event per line, newest first. Only the filter's kinds, authors,
+// since and until are used.
+func ExportEvents(ctx context.Context, st *storage.Storage, filter nostr.Filter, w io.Writer) (int64, error) {
+	buf := bufio.NewWriter(w)
+	var count int64
+
+	err := st.ScanEvents(ctx, filter, func(event *nostr.Event) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode event %s: %w", event.ID, err)
+		}
+		data = append(data, '\n')
+		if _, err := buf.Write(data); err != nil {
+			return fmt.Errorf("failed to write event: %w", err)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	if err := buf.Flush(); err != nil {
+		return count, fmt.Errorf("failed to write events: %w", err)
+	}
+	return count, nil
+}
+
+// ExportState collects the nophr state of st. It is not narrowed by an
+// event filter: cursors and the graph describe the whole archive.
+func ExportState(ctx context.Context, st *storage.Storage) (*ArchiveState, error) {
+	state := &ArchiveState{
+		Version:       archiveStateVersion,
+		ExportedAt:    time.Now().UTC(),
+		Driver:        st.Driver(),
+		SchemaVersion: storage.SchemaVersion(),
+	}
+
+	var err error
+	if state.Cursors, err = st.GetAllSyncStates(ctx); err != nil {
+		return nil, err
+	}
+	if state.RelayHints, err = st.GetAllRelayHints(ctx); err != nil {
+		return nil, err
+	}
+	if state.Graph, err = st.GetAllGraphNodes(ctx); err != nil {
+		return nil, err
+	}
+	if state.Retention, err = st.GetAllRetentionMetadata(ctx); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// WriteState writes a state sidecar to path
+func WriteState(path string, state *ArchiveState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
+
+// ReadState reads a state sidecar written by WriteState
+func ReadState(path string) (*ArchiveState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	var state ArchiveState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state %s: %w", path, err)
+	}
+	if state.Version > archiveStateVersion {
+		return nil, fmt.Errorf("state %s has version %d, this build reads up to %d", path, state.Version, archiveStateVersion)
+	}
+
+	return &state, nil
+}
+
+// ImportState restores a state sidecar into st. Run it after the events are
+// imported: it overwrites the graph and retention records those events
+// produced with the exported ones. Relay hints only replace fresher ones.
+func ImportState(ctx context.Context, st *storage.Storage, state *ArchiveState) error {
+	for _, cursor := range state.Cursors {
+		if err := st.SaveSyncState(ctx, cursor); err != nil {
+			return err
+		}
+	}
+	for _, hint := range state.RelayHints {
+		if err := st.SaveRelayHint(ctx, hint); err != nil {
+			return err
+		}
+	}
+	for _, node := range state.Graph {
+		if err := st.SaveGraphNode(ctx, node); err != nil {
+			return err
+		}
+	}
+	for _, meta := range state.Retention {
+		if err := st.StoreRetentionMetadata(ctx, meta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportStats counts what an import did with each line of its input
+type ImportStats struct {
+	Lines   int64 `json:"lines"`
+	Stored  int64 `json:"stored"`
+	Skipped int64 `json:"skipped"` // already stored, or turned away by ingest policies or deletions
+	Failed  int64 `json:"failed"`  // malformed, badly signed, or failed to store
+}
+
+// importCheckpoint records how far into its input an import got
+type importCheckpoint struct {
+	Size   int64       `json:"size"`
+	Offset int64       `json:"offset"`
+	Stats  ImportStats `json:"stats"`
+}
+
+// importCheckpointEvery is how many lines pass between checkpoints
+const importCheckpointEvery = 1000
+
+// Importer reads JSON lines event archives into storage through an ingest
+// function, normally sync.Engine.ImportEvent, so the graph, relay hints,
+// aggregates and retention are built as they would be for synced events
+type Importer struct {
+	ingest func(*nostr.Event) (bool, error)
+	logger *Logger
+
+	// Progress, when set, is called after every checkpoint and at the end
+	Progress func(ImportStats)
+}
+
+// NewImporter creates an importer that stores events with ingest
+func NewImporter(ingest func(*nostr.Event) (bool, error), logger *Logger) *Importer {
+	return &Importer{
+		ingest: ingest,
+		logger: logger.WithComponent("import"),
+	}
+}
+
+// CheckpointPath returns the file an import of path keeps its progress in
+func CheckpointPath(path string) string {
+	return path + ".progress"
+}
+
+// ImportFile imports the archive at path. Progress is saved next to it every
+// thousand lines and when ctx is cancelled, so an interrupted import of the
+// same file picks up where it stopped; re-read events are skipped as
+// duplicates. The checkpoint is removed once the whole file has been read.
+func (im *Importer) ImportFile(ctx context.Context, path string) (ImportStats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ImportStats{}, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return ImportStats{}, fmt.Errorf("failed to stat archive: %w", err)
+	}
+
+	checkpointPath := CheckpointPath(path)
+	checkpoint := importCheckpoint{Size: info.Size()}
+	if data, err := os.ReadFile(checkpointPath); err == nil {
+		var saved importCheckpoint
+		switch {
+		case json.Unmarshal(data, &saved) != nil:
+			im.logger.Warn("ignoring unreadable import checkpoint", "path", checkpointPath)
+		case saved.Size != info.Size():
+			im.logger.Warn("archive changed since the last import attempt, starting over", "path", path)
+		default:
+			checkpoint = saved
+			im.logger.Info("resuming import", "path", path, "offset", saved.Offset, "lines", saved.Stats.Lines)
+		}
+	}
+
+	if _, err := f.Seek(checkpoint.Offset, io.SeekStart); err != nil {
+		return checkpoint.Stats, fmt.Errorf("failed to seek archive: %w", err)
+	}
+
+	save := func(offset int64, stats ImportStats) error {
+		data, err := json.Marshal(importCheckpoint{Size: info.Size(), Offset: offset, Stats: stats})
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(checkpointPath, data, 0644); err != nil {
+			return fmt.Errorf("failed to save import checkpoint: %w", err)
+		}
+		return nil
+	}
+
+	stats, err := im.importLines(ctx, f, checkpoint.Offset, checkpoint.Stats, save)
+	if err != nil {
+		return stats, err
+	}
+
+	if err := os.Remove(checkpointPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return stats, fmt.Errorf("failed to remove import checkpoint: %w", err)
+	}
+	return stats, nil
+}
+
+// Import imports an archive from r, such as stdin, without checkpoints
+func (im *Importer) Import(ctx context.Context, r io.Reader) (ImportStats, error) {
+	return im.importLines(ctx, r, 0, ImportStats{}, nil)
+}
+
+// importLines ingests the lines of r, which starts offset bytes into the
+// archive. save, when set, is called with the offset of the next unread line
+// every importCheckpointEvery lines and before returning on cancellation.
+func (im *Importer) importLines(ctx context.Context, r io.Reader, offset int64, stats ImportStats, save func(int64, ImportStats) error) (ImportStats, error) {
+	reader := bufio.NewReader(r)
+	start := time.Now()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			if save != nil {
+				if saveErr := save(offset, stats); saveErr != nil {
+					return stats, saveErr
+				}
+			}
+			return stats, err
+		}
+
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return stats, fmt.Errorf("failed to read archive: %w", readErr)
+		}
+		offset += int64(len(line))
+
+		if text := strings.TrimSpace(string(line)); text != "" {
+			stats.Lines++
+			im.importLine(text, stats.Lines, &stats)
+
+			if stats.Lines%importCheckpointEvery == 0 {
+				if save != nil {
+					if err := save(offset, stats); err != nil {
+						return stats, err
+					}
+				}
+				if im.Progress != nil {
+					im.Progress(stats)
+				}
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if im.Progress != nil {
+		im.Progress(stats)
+	}
+	im.logger.Info("import completed",
+		"lines", stats.Lines,
+		"stored", stats.Stored,
+		"skipped", stats.Skipped,
+		"failed", stats.Failed,
+		"duration_ms", time.Since(start).Milliseconds())
+
+	return stats, nil
+}
+
+// importLine decodes, checks and ingests one event, counting the outcome
+func (im *Importer) importLine(text string, lineNo int64, stats *ImportStats) {
+	var event nostr.Event
+	if err := json.Unmarshal([]byte(text), &event); err != nil {
+		stats.Failed++
+		im.logger.Warn("skipping malformed line", "line", lineNo, "error", err)
+		return
+	}
+
+	// Archives are plain files, so check what relays would have checked
+	if !event.CheckID() {
+		stats.Failed++
+		im.logger.Warn("skipping event with wrong id", "line", lineNo, "id", event.ID)
+		return
+	}
+	if ok, err := event.CheckSignature(); !ok {
+		stats.Failed++
+		im.logger.Warn("skipping event with invalid signature", "line", lineNo, "id", event.ID, "error", err)
+		return
+	}
+
+	stored, err := im.ingest(&event)
+	switch {
+	case err != nil && !stored:
+		stats.Failed++
+		im.logger.Warn("failed to import event", "line", lineNo, "id", event.ID, "error", err)
+	case err != nil:
+		// Stored, but a follow-up such as the graph update failed
+		stats.Stored++
+		im.logger.Warn("event stored with errors", "line", lineNo, "id", event.ID, "error", err)
+	case stored:
+		stats.Stored++
+	default:
+		stats.Skipped++
+	}
+}
@@ -0,0 +1,190 @@
+package ops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+func newArchiveTestStorage(t *testing.T) *storage.Storage {
+	t.Helper()
+
+	st, err := storage.New(context.Background(), &config.Storage{
+		Driver:     "sqlite",
+		SQLitePath: filepath.Join(t.TempDir(), "nophr.db"),
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+func TestExportAndImport(t *testing.T) {
+	ctx := context.Background()
+	logger := NewLogger(&config.Logging{Level: "error"})
+	src := newArchiveTestStorage(t)
+
+	sk := nostr.GeneratePrivateKey()
+	pubkey, _ := nostr.GetPublicKey(sk)
+	var events []*nostr.Event
+	for i, kind := range []int{1, 7, 1} {
+		event := &nostr.Event{Kind: kind, CreatedAt: nostr.Timestamp(100 + i), Tags: nostr.Tags{}, Content: "note"}
+		if err := event.Sign(sk); err != nil {
+			t.Fatalf("failed to sign event: %v", err)
+		}
+		if err := src.StoreEvent(ctx, event); err != nil {
+			t.Fatalf("failed to store event: %v", err)
+		}
+		events = append(events, event)
+	}
+	if err := src.UpdateSyncCursor(ctx, "wss://relay.example.com", 1, 102); err != nil {
+		t.Fatalf("failed to save cursor: %v", err)
+	}
+	if err := src.SaveGraphNode(ctx, &storage.GraphNode{RootPubkey: pubkey, Pubkey: strings.Repeat("b", 64), Depth: 1}); err != nil {
+		t.Fatalf("failed to save graph node: %v", err)
+	}
+
+	dir := t.TempDir()
+	eventsPath := filepath.Join(dir, "archive.jsonl")
+	var buf bytes.Buffer
+	count, err := ExportEvents(ctx, src, nostr.Filter{Kinds: []int{1}}, &buf)
+	if err != nil {
+		t.Fatalf("ExportEvents() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("ExportEvents() = %d, want the 2 kind 1 events", count)
+	}
+
+	// A malformed line and a tampered event are counted, not fatal
+	tampered := *events[1]
+	tampered.Content = "edited"
+	data, _ := json.Marshal(&tampered)
+	buf.WriteString("not json\n")
+	buf.Write(append(data, '\n'))
+	if err := os.WriteFile(eventsPath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	state, err := ExportState(ctx, src)
+	if err != nil {
+		t.Fatalf("ExportState() error = %v", err)
+	}
+	if err := WriteState(StatePath(eventsPath), state); err != nil {
+		t.Fatalf("WriteState() error = %v", err)
+	}
+
+	dest := newArchiveTestStorage(t)
+	ingest := func(event *nostr.Event) (bool, error) {
+		if exists, err := dest.EventExists(ctx, event.ID); err != nil || exists {
+			return false, err
+		}
+		return true, dest.StoreEvent(ctx, event)
+	}
+
+	stats, err := NewImporter(ingest, logger).ImportFile(ctx, eventsPath)
+	if err != nil {
+		t.Fatalf("ImportFile() error = %v", err)
+	}
+	if want := (ImportStats{Lines: 4, Stored: 2, Failed: 2}); stats != want {
+		t.Errorf("ImportFile() = %+v, want %+v", stats, want)
+	}
+	if _, err := os.Stat(CheckpointPath(eventsPath)); !os.IsNotExist(err) {
+		t.Error("expected the checkpoint to be removed after a complete import")
+	}
+
+	read, err := ReadState(StatePath(eventsPath))
+	if err != nil {
+		t.Fatalf("ReadState() error = %v", err)
+	}
+	if err := ImportState(ctx, dest, read); err != nil {
+		t.Fatalf("ImportState() error = %v", err)
+	}
+
+	cursor, err := dest.GetSyncState(ctx, "wss://relay.example.com", 1)
+	if err != nil || cursor.Since != 102 {
+		t.Errorf("expected the cursor to be restored, got %+v (%v)", cursor, err)
+	}
+	nodes, err := dest.GetGraphNodes(ctx, pubkey, 1)
+	if err != nil || len(nodes) != 1 {
+		t.Errorf("expected the graph to be restored, got %d nodes (%v)", len(nodes), err)
+	}
+
+	// Running the import again skips what is already stored
+	stats, err = NewImporter(ingest, logger).ImportFile(ctx, eventsPath)
+	if err != nil {
+		t.Fatalf("ImportFile() error = %v", err)
+	}
+	if stats.Stored != 0 || stats.Skipped != 2 {
+		t.Errorf("second ImportFile() = %+v, want both events skipped", stats)
+	}
+}
+
+func TestImportResume(t *testing.T) {
+	ctx := context.Background()
+	logger := NewLogger(&config.Logging{Level: "error"})
+
+	sk := nostr.GeneratePrivateKey()
+	var lines []string
+	for i := 0; i < 3; i++ {
+		event := &nostr.Event{Kind: 1, CreatedAt: nostr.Timestamp(100 + i), Tags: nostr.Tags{}, Content: "note"}
+		if err := event.Sign(sk); err != nil {
+			t.Fatalf("failed to sign event: %v", err)
+		}
+		data, _ := json.Marshal(event)
+		lines = append(lines, string(data)+"\n")
+	}
+	content := strings.Join(lines, "")
+
+	path := filepath.Join(t.TempDir(), "archive.jsonl")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	// An earlier attempt got through the first line
+	checkpoint, _ := json.Marshal(importCheckpoint{
+		Size:   int64(len(content)),
+		Offset: int64(len(lines[0])),
+		Stats:  ImportStats{Lines: 1, Stored: 1},
+	})
+	if err := os.WriteFile(CheckpointPath(path), checkpoint, 0644); err != nil {
+		t.Fatalf("failed to write checkpoint: %v", err)
+	}
+
+	var seen []int64
+	importer := NewImporter(func(event *nostr.Event) (bool, error) {
+		seen = append(seen, int64(event.CreatedAt))
+		return true, nil
+	}, logger)
+
+	stats, err := importer.ImportFile(ctx, path)
+	if err != nil {
+		t.Fatalf("ImportFile() error = %v", err)
+	}
+	if len(seen) != 2 || seen[0] != 101 {
+		t.Errorf("expected the import to resume at the second line, ingested %v", seen)
+	}
+	if stats.Lines != 3 || stats.Stored != 3 {
+		t.Errorf("ImportFile() = %+v, want the earlier line counted", stats)
+	}
+
+	// A checkpoint for a different file is ignored
+	if err := os.WriteFile(CheckpointPath(path), []byte(`{"size":1,"offset":1}`), 0644); err != nil {
+		t.Fatalf("failed to write checkpoint: %v", err)
+	}
+	seen = nil
+	if _, err := importer.ImportFile(ctx, path); err != nil {
+		t.Fatalf("ImportFile() error = %v", err)
+	}
+	if len(seen) != 3 {
+		t.Errorf("expected a stale checkpoint to restart the import, ingested %v", seen)
+	}
+}
@@ -1,46 +1,80 @@
 package ops
 
 import (
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
+	"github.com/sandwichfarm/nophr/internal/config"
 	"github.com/sandwichfarm/nophr/internal/storage"
 )
 
+// backupPrefix starts the name of every backup file
+const backupPrefix = "nophr-backup-"
+
+// backupExtensions maps backup file extensions to their compression
+var backupExtensions = map[string]string{
+	".db":     config.BackupCompressionNone,
+	".db.gz":  config.BackupCompressionGzip,
+	".db.zst": config.BackupCompressionZstd,
+}
+
+// BackupFileName returns the name of a backup taken at t:
+// nophr-backup-20060102-150405.db, with .gz or .zst appended when compressed
+func BackupFileName(t time.Time, compression string) string {
+	ext := ".db"
+	for e, c := range backupExtensions {
+		if c == compression {
+			ext = e
+		}
+	}
+	return backupPrefix + t.Format("20060102-150405") + ext
+}
+
+// backupCompression returns the compression a backup path's extension implies
+func backupCompression(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		return config.BackupCompressionGzip
+	case strings.HasSuffix(path, ".zst"):
+		return config.BackupCompressionZstd
+	default:
+		return config.BackupCompressionNone
+	}
+}
+
 // BackupManager handles database backup operations
 type BackupManager struct {
-	storage *storage.Storage
-	logger  *Logger
-	dbPath  string
+	config *config.Storage
+	logger *Logger
 }
 
 // NewBackupManager creates a new backup manager
-func NewBackupManager(st *storage.Storage, logger *Logger, dbPath string) *BackupManager {
+func NewBackupManager(cfg *config.Storage, logger *Logger) *BackupManager {
 	return &BackupManager{
-		storage: st,
-		logger:  logger.WithComponent("backup"),
-		dbPath:  dbPath,
+		config: cfg,
+		logger: logger.WithComponent("backup"),
 	}
 }
 
-// Backup creates a backup of the database
+// Backup writes a consistent copy of the database to destPath and checks its
+// integrity. It is safe while nophr is running. The copy is compressed if
+// destPath ends in .gz or .zst.
 func (b *BackupManager) Backup(ctx context.Context, destPath string) error {
 	start := time.Now()
 
 	b.logger.Info("starting database backup", "destination", destPath)
 
-	// Get the database file path
-	driver := b.storage.Driver()
-	var sourcePath string
-
-	switch driver {
+	switch b.config.Driver {
 	case "sqlite":
-		sourcePath = b.dbPath
-		if sourcePath == "" {
+		if b.config.SQLitePath == "" {
 			b.logger.Error("database path not configured")
 			return fmt.Errorf("database path not set")
 		}
@@ -48,7 +82,7 @@ func (b *BackupManager) Backup(ctx context.Context, destPath string) error {
 		b.logger.Error("backup not yet implemented for LMDB")
 		return fmt.Errorf("LMDB backup not implemented")
 	default:
-		return fmt.Errorf("unsupported driver: %s", driver)
+		return fmt.Errorf("unsupported driver: %s", b.config.Driver)
 	}
 
 	// Create destination directory if it doesn't exist
@@ -58,105 +92,212 @@ func (b *BackupManager) Backup(ctx context.Context, destPath string) error {
 		return fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
-	// Copy the database file
-	size, err := b.copyFile(sourcePath, destPath)
+	// Snapshot next to the destination so the final rename stays on one
+	// filesystem; VACUUM INTO accepts the empty file
+	snapshot, err := tempFile(destDir, ".nophr-snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(snapshot)
+
+	if err := storage.SnapshotSQLite(ctx, b.config.SQLitePath, snapshot); err != nil {
+		b.logger.LogBackupOperation("backup", destPath, 0, err)
+		return err
+	}
+
+	version, err := storage.VerifySQLite(ctx, snapshot)
+	if err != nil {
+		b.logger.LogBackupOperation("verify", destPath, 0, err)
+		return fmt.Errorf("backup failed verification: %w", err)
+	}
+
+	size, err := b.writeCompressed(snapshot, destPath, backupCompression(destPath))
 	if err != nil {
 		b.logger.LogBackupOperation("backup", destPath, size, err)
-		return fmt.Errorf("failed to copy database: %w", err)
+		return err
 	}
 
 	b.logger.LogBackupOperation("backup", destPath, size, nil)
 	b.logger.Info("database backup completed",
 		"destination", destPath,
+		"schema_version", version,
 		"size_mb", float64(size)/1024/1024,
 		"duration_ms", time.Since(start).Milliseconds())
 
 	return nil
 }
 
-// BackupWithConfig creates a backup using config paths
-func (b *BackupManager) BackupWithConfig(ctx context.Context, sourcePath, destPath string) error {
-	start := time.Now()
-
-	b.logger.Info("starting database backup",
-		"source", sourcePath,
-		"destination", destPath)
+// writeCompressed moves the snapshot to destPath, compressing it on the way
+// if asked to, and returns the size written
+func (b *BackupManager) writeCompressed(snapshot, destPath, compression string) (int64, error) {
+	if compression == config.BackupCompressionNone {
+		if err := os.Rename(snapshot, destPath); err != nil {
+			return 0, fmt.Errorf("failed to move backup into place: %w", err)
+		}
+		info, err := os.Stat(destPath)
+		if err != nil {
+			return 0, fmt.Errorf("failed to stat backup: %w", err)
+		}
+		return info.Size(), nil
+	}
 
-	// Create destination directory if it doesn't exist
-	destDir := filepath.Dir(destPath)
-	if err := os.MkdirAll(destDir, 0755); err != nil {
-		b.logger.LogBackupOperation("create directory", destPath, 0, err)
-		return fmt.Errorf("failed to create backup directory: %w", err)
+	src, err := os.Open(snapshot)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open snapshot: %w", err)
 	}
+	defer src.Close()
 
-	// Copy the database file
-	size, err := b.copyFile(sourcePath, destPath)
+	partial := destPath + ".partial"
+	dst, err := os.Create(partial)
 	if err != nil {
-		b.logger.LogBackupOperation("backup", destPath, size, err)
-		return fmt.Errorf("failed to copy database: %w", err)
+		return 0, fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer os.Remove(partial)
+	defer dst.Close()
+
+	var w io.WriteCloser
+	switch compression {
+	case config.BackupCompressionGzip:
+		w = gzip.NewWriter(dst)
+	case config.BackupCompressionZstd:
+		zw, err := zstd.NewWriter(dst)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create zstd writer: %w", err)
+		}
+		w = zw
+	default:
+		return 0, fmt.Errorf("unsupported compression: %s", compression)
 	}
 
-	b.logger.LogBackupOperation("backup", destPath, size, nil)
-	b.logger.Info("database backup completed",
-		"source", sourcePath,
-		"destination", destPath,
-		"size_mb", float64(size)/1024/1024,
-		"duration_ms", time.Since(start).Milliseconds())
+	if _, err := io.Copy(w, src); err != nil {
+		w.Close()
+		return 0, fmt.Errorf("failed to compress backup: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return 0, fmt.Errorf("failed to compress backup: %w", err)
+	}
 
-	return nil
+	// Sync to ensure data is written to disk
+	if err := dst.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to sync file: %w", err)
+	}
+	info, err := dst.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat backup: %w", err)
+	}
+	if err := os.Rename(partial, destPath); err != nil {
+		return 0, fmt.Errorf("failed to move backup into place: %w", err)
+	}
+
+	return info.Size(), nil
 }
 
-// Restore restores a database from backup
-func (b *BackupManager) Restore(ctx context.Context, backupPath, destPath string) error {
+// Restore replaces the database at destPath with a backup, compressed or not.
+// The backup must pass an integrity check and must not come from a newer
+// nophr whose schema this build cannot migrate. It returns the backup's
+// schema version; older schemas are upgraded when the database is next opened.
+func (b *BackupManager) Restore(ctx context.Context, backupPath, destPath string) (int, error) {
 	start := time.Now()
 
 	b.logger.Info("starting database restore",
 		"backup", backupPath,
 		"destination", destPath)
 
-	// Verify backup file exists
-	if _, err := os.Stat(backupPath); os.IsNotExist(err) {
-		return fmt.Errorf("backup file not found: %s", backupPath)
+	src, err := os.Open(backupPath)
+	if err != nil {
+		return 0, fmt.Errorf("backup file not found: %s", backupPath)
+	}
+	defer src.Close()
+
+	var r io.Reader = src
+	switch backupCompression(backupPath) {
+	case config.BackupCompressionGzip:
+		gr, err := gzip.NewReader(src)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read gzip backup: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	case config.BackupCompressionZstd:
+		zr, err := zstd.NewReader(src)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read zstd backup: %w", err)
+		}
+		defer zr.Close()
+		r = zr
 	}
 
 	// Create destination directory if it doesn't exist
 	destDir := filepath.Dir(destPath)
 	if err := os.MkdirAll(destDir, 0755); err != nil {
-		return fmt.Errorf("failed to create destination directory: %w", err)
+		return 0, fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	// Unpack next to the destination and check it before replacing anything
+	restored, err := tempFile(destDir, ".nophr-restore-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(restored)
+
+	size, err := copyToFile(r, restored)
+	if err != nil {
+		b.logger.LogBackupOperation("restore", destPath, size, err)
+		return 0, fmt.Errorf("failed to restore database: %w", err)
 	}
 
-	// Copy the backup file to destination
-	size, err := b.copyFile(backupPath, destPath)
+	version, err := storage.VerifySQLite(ctx, restored)
 	if err != nil {
+		b.logger.LogBackupOperation("verify", backupPath, size, err)
+		return 0, fmt.Errorf("backup failed verification: %w", err)
+	}
+	if version > storage.SchemaVersion() {
+		return 0, fmt.Errorf("backup has schema version %d, newer than this build supports (%d)", version, storage.SchemaVersion())
+	}
+
+	if err := os.Rename(restored, destPath); err != nil {
 		b.logger.LogBackupOperation("restore", destPath, size, err)
-		return fmt.Errorf("failed to restore database: %w", err)
+		return 0, fmt.Errorf("failed to restore database: %w", err)
+	}
+
+	// A write-ahead log left by the replaced database would be replayed
+	// over the restored one
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(destPath + suffix); err != nil && !os.IsNotExist(err) {
+			return 0, fmt.Errorf("failed to remove stale %s file: %w", suffix, err)
+		}
 	}
 
 	b.logger.LogBackupOperation("restore", destPath, size, nil)
 	b.logger.Info("database restore completed",
 		"backup", backupPath,
 		"destination", destPath,
+		"schema_version", version,
 		"size_mb", float64(size)/1024/1024,
 		"duration_ms", time.Since(start).Milliseconds())
 
-	return nil
+	return version, nil
 }
 
-// copyFile copies a file from src to dst
-func (b *BackupManager) copyFile(src, dst string) (int64, error) {
-	sourceFile, err := os.Open(src)
+// tempFile creates an empty file in dir and returns its path
+func tempFile(dir, pattern string) (string, error) {
+	f, err := os.CreateTemp(dir, pattern)
 	if err != nil {
-		return 0, fmt.Errorf("failed to open source file: %w", err)
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
 	}
-	defer sourceFile.Close()
+	f.Close()
+	return f.Name(), nil
+}
 
-	destFile, err := os.Create(dst)
+// copyToFile writes everything read from r to the file at path
+func copyToFile(r io.Reader, path string) (int64, error) {
+	destFile, err := os.Create(path)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create destination file: %w", err)
 	}
 	defer destFile.Close()
 
-	size, err := io.Copy(destFile, sourceFile)
+	size, err := io.Copy(destFile, r)
 	if err != nil {
 		return size, fmt.Errorf("failed to copy file: %w", err)
 	}
@@ -171,35 +312,43 @@ func (b *BackupManager) copyFile(src, dst string) (int64, error) {
 
 // PeriodicBackup runs periodic backups
 type PeriodicBackup struct {
-	manager    *BackupManager
-	sourcePath string
-	destDir    string
-	interval   time.Duration
-	logger     *Logger
-	stopChan   chan struct{}
+	manager  *BackupManager
+	config   *config.Backup
+	logger   *Logger
+	stopChan chan struct{}
 }
 
 // NewPeriodicBackup creates a new periodic backup handler
-func NewPeriodicBackup(manager *BackupManager, sourcePath, destDir string, interval time.Duration, logger *Logger) *PeriodicBackup {
+func NewPeriodicBackup(manager *BackupManager, cfg *config.Backup, logger *Logger) *PeriodicBackup {
 	return &PeriodicBackup{
-		manager:    manager,
-		sourcePath: sourcePath,
-		destDir:    destDir,
-		interval:   interval,
-		logger:     logger.WithComponent("periodic-backup"),
-		stopChan:   make(chan struct{}),
+		manager:  manager,
+		config:   cfg,
+		logger:   logger.WithComponent("periodic-backup"),
+		stopChan: make(chan struct{}),
 	}
 }
 
-// Start begins periodic backups
+// Start begins periodic backups. The first one is due an interval after the
+// newest backup in the directory, so restarts do not postpone backups
+// indefinitely.
 func (p *PeriodicBackup) Start(ctx context.Context) {
-	ticker := time.NewTicker(p.interval)
-	defer ticker.Stop()
+	interval := time.Duration(p.config.IntervalHours) * time.Hour
+
+	var wait time.Duration
+	if newest, ok := newestBackup(p.config.Dir); ok {
+		wait = interval - time.Since(newest)
+		if wait < 0 {
+			wait = 0
+		}
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
 
 	p.logger.Info("periodic backup started",
-		"source", p.sourcePath,
-		"destination", p.destDir,
-		"interval", p.interval)
+		"destination", p.config.Dir,
+		"interval", interval,
+		"next", time.Now().Add(wait).Format(time.RFC3339))
 
 	for {
 		select {
@@ -209,73 +358,126 @@ func (p *PeriodicBackup) Start(ctx context.Context) {
 		case <-p.stopChan:
 			p.logger.Info("periodic backup stopped")
 			return
-		case <-ticker.C:
+		case <-timer.C:
 			p.logger.Debug("running periodic backup")
 
-			// Generate timestamped backup filename
-			timestamp := time.Now().Format("20060102-150405")
-			backupPath := filepath.Join(p.destDir, fmt.Sprintf("nophr-backup-%s.db", timestamp))
-
-			err := p.manager.BackupWithConfig(ctx, p.sourcePath, backupPath)
-			if err != nil {
+			if path, err := p.RunOnce(ctx); err != nil {
 				p.logger.Error("periodic backup failed", "error", err)
 			} else {
-				p.logger.Info("periodic backup completed", "path", backupPath)
+				p.logger.Info("periodic backup completed", "path", path)
 			}
+			timer.Reset(interval)
 		}
 	}
 }
 
+// RunOnce writes a new backup into the backup directory, then removes the
+// backups that fall outside the retention limits. It returns the new
+// backup's path.
+func (p *PeriodicBackup) RunOnce(ctx context.Context) (string, error) {
+	backupPath := filepath.Join(p.config.Dir, BackupFileName(time.Now(), p.config.Compression))
+	if err := p.manager.Backup(ctx, backupPath); err != nil {
+		return "", err
+	}
+
+	maxAge := time.Duration(p.config.MaxAgeDays) * 24 * time.Hour
+	if _, err := CleanOldBackups(p.config.Dir, p.config.Keep, maxAge, p.logger); err != nil {
+		p.logger.Warn("failed to clean old backups", "error", err)
+	}
+
+	return backupPath, nil
+}
+
 // Stop stops the periodic backup
 func (p *PeriodicBackup) Stop() {
 	close(p.stopChan)
 }
 
-// CleanOldBackups removes backups older than the specified age
-func CleanOldBackups(backupDir string, maxAge time.Duration, logger *Logger) error {
-	logger.Info("cleaning old backups", "directory", backupDir, "max_age", maxAge)
+// backupFile is a backup found in the backup directory
+type backupFile struct {
+	path    string
+	modTime time.Time
+}
 
+// listBackups returns the backups in backupDir, newest first
+func listBackups(backupDir string) ([]backupFile, error) {
 	entries, err := os.ReadDir(backupDir)
 	if err != nil {
-		return fmt.Errorf("failed to read backup directory: %w", err)
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
 	}
 
-	cutoff := time.Now().Add(-maxAge)
-	var deleted int
-
+	var backups []backupFile
 	for _, entry := range entries {
-		if entry.IsDir() {
+		if entry.IsDir() || !isBackupFile(entry.Name()) {
 			continue
 		}
-
-		// Check if it's a backup file
-		if !isBackupFile(entry.Name()) {
+		info, err := entry.Info()
+		if err != nil {
 			continue
 		}
+		backups = append(backups, backupFile{
+			path:    filepath.Join(backupDir, entry.Name()),
+			modTime: info.ModTime(),
+		})
+	}
 
-		info, err := entry.Info()
-		if err != nil {
-			logger.Warn("failed to get file info", "file", entry.Name(), "error", err)
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].modTime.After(backups[j].modTime)
+	})
+	return backups, nil
+}
+
+// newestBackup returns when the newest backup in backupDir was written
+func newestBackup(backupDir string) (time.Time, bool) {
+	backups, err := listBackups(backupDir)
+	if err != nil || len(backups) == 0 {
+		return time.Time{}, false
+	}
+	return backups[0].modTime, true
+}
+
+// CleanOldBackups keeps the newest keep backups in backupDir and removes the
+// rest, along with any older than maxAge. A zero keep or maxAge is no limit.
+// It returns the number of backups deleted.
+func CleanOldBackups(backupDir string, keep int, maxAge time.Duration, logger *Logger) (int, error) {
+	logger.Info("cleaning old backups", "directory", backupDir, "keep", keep, "max_age", maxAge)
+
+	backups, err := listBackups(backupDir)
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	var deleted int
+
+	for i, backup := range backups {
+		tooMany := keep > 0 && i >= keep
+		tooOld := maxAge > 0 && backup.modTime.Before(cutoff)
+		if !tooMany && !tooOld {
 			continue
 		}
 
-		if info.ModTime().Before(cutoff) {
-			path := filepath.Join(backupDir, entry.Name())
-			if err := os.Remove(path); err != nil {
-				logger.Warn("failed to delete old backup", "file", path, "error", err)
-			} else {
-				logger.Info("deleted old backup", "file", path, "age", time.Since(info.ModTime()))
-				deleted++
-			}
+		if err := os.Remove(backup.path); err != nil {
+			logger.Warn("failed to delete old backup", "file", backup.path, "error", err)
+		} else {
+			logger.Info("deleted old backup", "file", backup.path, "age", time.Since(backup.modTime))
+			deleted++
 		}
 	}
 
 	logger.Info("old backup cleanup completed", "deleted", deleted)
-	return nil
+	return deleted, nil
 }
 
 // isBackupFile checks if a filename is a backup file
 func isBackupFile(name string) bool {
-	return filepath.Ext(name) == ".db" &&
-		(len(name) > 14 && name[:14] == "nophr-backup-")
+	if !strings.HasPrefix(name, backupPrefix) {
+		return false
+	}
+	for ext := range backupExtensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
 }
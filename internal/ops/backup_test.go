@@ -0,0 +1,141 @@
+package ops
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	cfg := &config.Storage{Driver: "sqlite", SQLitePath: filepath.Join(dir, "nophr.db")}
+
+	st, err := storage.New(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	event := &nostr.Event{
+		ID:        strings.Repeat("a", 64),
+		PubKey:    strings.Repeat("f", 64),
+		CreatedAt: nostr.Timestamp(1000),
+		Kind:      1,
+		Tags:      nostr.Tags{},
+		Sig:       strings.Repeat("c", 128),
+	}
+	if err := st.StoreEvent(ctx, event); err != nil {
+		t.Fatalf("failed to store event: %v", err)
+	}
+
+	bm := NewBackupManager(cfg, NewLogger(&config.Logging{Level: "error"}))
+
+	for _, compression := range []string{config.BackupCompressionNone, config.BackupCompressionGzip, config.BackupCompressionZstd} {
+		t.Run(compression, func(t *testing.T) {
+			backupPath := filepath.Join(dir, "backups", BackupFileName(time.Now(), compression))
+
+			// The database is still open, as it would be in a running server
+			if err := bm.Backup(ctx, backupPath); err != nil {
+				t.Fatalf("Backup() error = %v", err)
+			}
+
+			restored := &config.Storage{Driver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "restored.db")}
+			version, err := NewBackupManager(restored, bm.logger).Restore(ctx, backupPath, restored.SQLitePath)
+			if err != nil {
+				t.Fatalf("Restore() error = %v", err)
+			}
+			if version != storage.SchemaVersion() {
+				t.Errorf("expected schema version %d, got %d", storage.SchemaVersion(), version)
+			}
+
+			rst, err := storage.New(ctx, restored)
+			if err != nil {
+				t.Fatalf("failed to open restored database: %v", err)
+			}
+			defer rst.Close()
+			if count, _ := rst.CountEvents(ctx); count != 1 {
+				t.Errorf("expected 1 restored event, got %d", count)
+			}
+		})
+	}
+
+	t.Run("newer schema", func(t *testing.T) {
+		if _, err := st.DB().ExecContext(ctx, "PRAGMA user_version = 9999"); err != nil {
+			t.Fatalf("failed to set schema version: %v", err)
+		}
+		defer st.DB().ExecContext(ctx, "PRAGMA user_version = 0")
+
+		backupPath := filepath.Join(dir, "newer.db")
+		if err := bm.Backup(ctx, backupPath); err != nil {
+			t.Fatalf("Backup() error = %v", err)
+		}
+
+		dest := filepath.Join(t.TempDir(), "nophr.db")
+		if _, err := bm.Restore(ctx, backupPath, dest); err == nil || !strings.Contains(err.Error(), "newer") {
+			t.Errorf("expected restore to refuse a newer schema, got %v", err)
+		}
+		if _, err := os.Stat(dest); !os.IsNotExist(err) {
+			t.Error("a refused restore should leave the destination untouched")
+		}
+	})
+
+	st.Close()
+}
+
+func TestCleanOldBackups(t *testing.T) {
+	dir := t.TempDir()
+	logger := NewLogger(&config.Logging{Level: "error"})
+
+	now := time.Now()
+	names := []string{
+		BackupFileName(now.Add(-1*time.Hour), config.BackupCompressionNone),
+		BackupFileName(now.Add(-2*time.Hour), config.BackupCompressionGzip),
+		BackupFileName(now.Add(-3*time.Hour), config.BackupCompressionZstd),
+		BackupFileName(now.Add(-50*24*time.Hour), config.BackupCompressionGzip),
+		"unrelated.db",
+	}
+	for i, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		modTime := now.Add(-time.Duration(i+1) * time.Hour)
+		if i == 3 {
+			modTime = now.Add(-50 * 24 * time.Hour)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("failed to set time on %s: %v", name, err)
+		}
+	}
+
+	// max_age removes the 50-day-old backup
+	deleted, err := CleanOldBackups(dir, 0, 30*24*time.Hour, logger)
+	if err != nil {
+		t.Fatalf("CleanOldBackups() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 backup past max age, deleted %d", deleted)
+	}
+
+	// keep removes all but the newest two
+	deleted, err = CleanOldBackups(dir, 2, 0, logger)
+	if err != nil {
+		t.Fatalf("CleanOldBackups() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 backup over the keep limit, deleted %d", deleted)
+	}
+
+	for i, name := range names {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists := err == nil; exists != (i < 2 || i == 4) {
+			t.Errorf("%s: exists = %v", name, exists)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"sort"
 	"time"
 
 	"github.com/sandwichfarm/nophr/internal/storage"
@@ -44,6 +45,10 @@ type SyncStats struct {
 	TotalSynced     int64
 	LastSyncTime    *time.Time
 	Cursors         []CursorInfo
+	EventsReceived  map[string]int64 // events received per relay since start
+	SyncMethods     map[string]int64 // relay syncs per method since start (see sync.SyncMethodNegentropy)
+	Rejected        map[string]int64 // events dropped by ingest policies per reason since start
+	Authors         *sync.AuthorSelection // how the last sync chose its authors; nil before the first
 }
 
 // CursorInfo contains cursor information for a relay/kind pair
@@ -199,6 +204,15 @@ func (d *DiagnosticsCollector) CollectSyncStats(ctx context.Context) (*SyncStats
 		stats.LastSyncTime = lastSync
 	}
 
+	// Get ingest counters
+	ingest := d.syncEngine.IngestStats()
+	stats.EventsReceived = ingest.EventsByRelay
+	stats.SyncMethods = ingest.Syncs
+	stats.Rejected = ingest.Rejected
+
+	// Get the author selection
+	stats.Authors = d.syncEngine.AuthorSelection()
+
 	// Get cursor information
 	cursors, err := d.storage.GetAllCursors(ctx)
 	if err == nil {
@@ -306,7 +320,7 @@ func (d *DiagnosticsCollector) CollectRetentionStats(ctx context.Context) (*Rete
 	}
 
 	// Check if advanced retention is enabled
-	if d.retentionMgr.config.Advanced != nil && d.retentionMgr.config.Advanced.Enabled {
+	if cfg, _ := d.retentionMgr.settings(); cfg.Advanced != nil && cfg.Advanced.Enabled {
 		stats.AdvancedEnabled = true
 
 		// Get protected event count
@@ -425,6 +439,16 @@ func (d *Diagnostics) FormatAsText() string {
 		if d.Sync.LastSyncTime != nil {
 			out += fmt.Sprintf("Last Sync: %s\n", d.Sync.LastSyncTime.Format(time.RFC3339))
 		}
+		out += fmt.Sprintf("Rejected: %d events\n", sumCounts(d.Sync.Rejected))
+		for _, reason := range sortedKeys(d.Sync.Rejected) {
+			out += fmt.Sprintf("  %s: %d\n", reason, d.Sync.Rejected[reason])
+		}
+		if a := d.Sync.Authors; a != nil {
+			out += fmt.Sprintf("Authors: %s\n", formatAuthorSummary(a))
+			for _, score := range a.Ranked {
+				out += fmt.Sprintf("  %s: %s\n", score.Pubkey, formatAuthorScore(score))
+			}
+		}
 	}
 	out += "\n"
 
@@ -529,9 +553,24 @@ func (d *Diagnostics) FormatAsGemtext() string {
 	if d.Sync.Enabled {
 		out += fmt.Sprintf("* Relays: %d total, %d connected\n", d.Sync.RelayCount, d.Sync.ConnectedRelays)
 		out += fmt.Sprintf("* Total Synced: %d events\n", d.Sync.TotalSynced)
+		out += fmt.Sprintf("* Rejected: %d events\n", sumCounts(d.Sync.Rejected))
+		for _, reason := range sortedKeys(d.Sync.Rejected) {
+			out += fmt.Sprintf("* Rejected (%s): %d\n", reason, d.Sync.Rejected[reason])
+		}
+		if a := d.Sync.Authors; a != nil {
+			out += fmt.Sprintf("* Authors: %s\n", formatAuthorSummary(a))
+		}
 	}
 	out += "\n"
 
+	if a := d.Sync.Authors; a != nil && len(a.Ranked) > 0 {
+		out += "## Selected Authors\n\n"
+		for _, score := range a.Ranked {
+			out += fmt.Sprintf("* %s: %s\n", score.Pubkey, formatAuthorScore(score))
+		}
+		out += "\n"
+	}
+
 	// Phase 20: Retention
 	out += "## Retention\n\n"
 	if d.Retention != nil {
@@ -550,3 +589,43 @@ func (d *Diagnostics) FormatAsGemtext() string {
 
 	return out
 }
+
+// formatAuthorSummary describes how many authors a sync selected
+func formatAuthorSummary(a *sync.AuthorSelection) string {
+	if len(a.Ranked) == 0 {
+		return fmt.Sprintf("%d in scope (computed %s)", a.Candidates, a.At.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("%d of %d ranked by score, max_authors %d (computed %s)",
+		a.MaxAuthors, a.Candidates, a.MaxAuthors, a.At.Format(time.RFC3339))
+}
+
+// formatAuthorScore describes a selected author's score and its signals
+func formatAuthorScore(s sync.AuthorScore) string {
+	out := fmt.Sprintf("score %.1f, depth %d, followed by %d, %d interactions", s.Score, s.Depth, s.FollowedBy, s.Interactions)
+	if s.Mutual {
+		out += ", mutual"
+	}
+	if s.RelayHints {
+		out += ", relay hints"
+	}
+	return out
+}
+
+// sumCounts adds up a set of counters
+func sumCounts(counts map[string]int64) int64 {
+	var total int64
+	for _, n := range counts {
+		total += n
+	}
+	return total
+}
+
+// sortedKeys returns the keys of a set of counters in order
+func sortedKeys(counts map[string]int64) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -4,6 +4,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/sandwichfarm/nophr/internal/sync"
 )
 
 func TestSystemStats(t *testing.T) {
@@ -119,6 +121,12 @@ func TestDiagnosticsFormatAsGemtext(t *testing.T) {
 			RelayCount:      3,
 			ConnectedRelays: 2,
 			TotalSynced:     1000,
+			Authors: &sync.AuthorSelection{
+				At:         time.Now(),
+				Candidates: 120,
+				MaxAuthors: 2,
+				Ranked:     []sync.AuthorScore{{Pubkey: "abcd", Score: 31, Depth: 1, Mutual: true}},
+			},
 		},
 		Aggregates: &AggregateStats{
 			TotalAggregates: 800,
@@ -133,6 +141,9 @@ func TestDiagnosticsFormatAsGemtext(t *testing.T) {
 		"## System",
 		"## Storage",
 		"## Sync",
+		"* Authors: 2 of 120 ranked by score",
+		"## Selected Authors",
+		"* abcd: score 31.0, depth 1, followed by 0, 0 interactions, mutual",
 	}
 
 	for _, expected := range expectedHeadings {
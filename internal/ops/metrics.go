@@ -0,0 +1,342 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sandwichfarm/nophr/internal/config"
+)
+
+// latencyBuckets are the request duration histogram bounds, in seconds
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	protocol string
+	status   string
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// Metrics counts protocol requests and renders them, together with the
+// DiagnosticsCollector stats, in the Prometheus text exposition format
+type Metrics struct {
+	collector *DiagnosticsCollector
+
+	mu       sync.Mutex
+	requests map[requestKey]uint64
+	latency  map[string]*histogram
+}
+
+// NewMetrics creates a metrics registry backed by a diagnostics collector
+func NewMetrics(collector *DiagnosticsCollector) *Metrics {
+	return &Metrics{
+		collector: collector,
+		requests:  make(map[requestKey]uint64),
+		latency:   make(map[string]*histogram),
+	}
+}
+
+// ObserveRequest records a protocol request with its status (a protocol
+// status code, or "ok"/"error") and how long it took to answer. It is a no-op
+// on a nil Metrics, so servers can call it unconditionally.
+func (m *Metrics) ObserveRequest(protocol, status string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{protocol, status}]++
+
+	h, ok := m.latency[protocol]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		m.latency[protocol] = h
+	}
+	seconds := duration.Seconds()
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// Render writes all metrics in the Prometheus text exposition format
+func (m *Metrics) Render(ctx context.Context, w io.Writer) error {
+	diag, err := m.collector.CollectAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	p := &promWriter{w: w}
+
+	sys := diag.System
+	p.metric("nophr_build_info", "gauge", "Build information", sample{
+		labels: labels("version", sys.Version, "commit", sys.Commit, "go_version", sys.GoVersion), value: 1,
+	})
+	p.metric("nophr_uptime_seconds", "gauge", "Seconds since nophr started", sample{value: sys.Uptime.Seconds()})
+	p.metric("nophr_goroutines", "gauge", "Number of goroutines", sample{value: float64(sys.NumGoroutines)})
+	p.metric("nophr_memory_alloc_bytes", "gauge", "Bytes of allocated heap objects", sample{value: sys.MemAllocMB * 1024 * 1024})
+	p.metric("nophr_memory_sys_bytes", "gauge", "Bytes of memory obtained from the OS", sample{value: sys.MemSysMB * 1024 * 1024})
+	p.metric("nophr_gc_cycles_total", "counter", "Completed GC cycles", sample{value: float64(sys.NumGC)})
+
+	st := diag.Storage
+	p.metric("nophr_events", "gauge", "Events in storage", sample{value: float64(st.TotalEvents)})
+	p.metric("nophr_events_by_kind", "gauge", "Events in storage by kind", kindSamples(st.EventsByKind)...)
+	p.metric("nophr_database_size_bytes", "gauge", "Size of the database", sample{value: st.DatabaseSizeMB * 1024 * 1024})
+	if st.NewestEventTime != nil {
+		p.metric("nophr_newest_event_timestamp_seconds", "gauge", "created_at of the newest stored event", sample{value: float64(st.NewestEventTime.Unix())})
+	}
+
+	sy := diag.Sync
+	p.metric("nophr_sync_enabled", "gauge", "Whether the sync engine is running", sample{value: boolValue(sy.Enabled)})
+	if sy.Enabled {
+		p.metric("nophr_sync_relays", "gauge", "Relays known to the sync engine", sample{value: float64(sy.RelayCount)})
+		p.metric("nophr_sync_relays_connected", "gauge", "Relays currently connected", sample{value: float64(sy.ConnectedRelays)})
+		p.metric("nophr_sync_events_synced", "gauge", "Events synced according to relay cursors", sample{value: float64(sy.TotalSynced)})
+		if sy.LastSyncTime != nil {
+			p.metric("nophr_sync_last_sync_timestamp_seconds", "gauge", "Time of the last cursor update", sample{value: float64(sy.LastSyncTime.Unix())})
+		}
+		p.metric("nophr_sync_events_received_total", "counter", "Events received from each relay", stringSamples("relay", sy.EventsReceived)...)
+		p.metric("nophr_sync_runs_total", "counter", "Relay syncs by method (negentropy, req_fallback, req)", stringSamples("method", sy.SyncMethods)...)
+		p.metric("nophr_sync_events_rejected_total", "counter", "Events dropped by ingest policies by reason", stringSamples("reason", sy.Rejected)...)
+	}
+
+	if len(diag.Relays) > 0 {
+		connected := make([]sample, 0, len(diag.Relays))
+		stored := make([]sample, 0, len(diag.Relays))
+		for _, relay := range diag.Relays {
+			connected = append(connected, sample{labels: labels("relay", relay.URL), value: boolValue(relay.Connected)})
+			stored = append(stored, sample{labels: labels("relay", relay.URL), value: float64(relay.EventsSynced)})
+		}
+		p.metric("nophr_relay_connected", "gauge", "Whether the relay is connected", connected...)
+		p.metric("nophr_relay_events_stored", "gauge", "Stored events synced from the relay", stored...)
+	}
+
+	agg := diag.Aggregates
+	p.metric("nophr_aggregates", "gauge", "Events with computed aggregates", sample{value: float64(agg.TotalAggregates)})
+	p.metric("nophr_aggregates_by_kind", "gauge", "Events with computed aggregates by kind", kindSamples(agg.ByKind)...)
+
+	ret := diag.Retention
+	p.metric("nophr_retention_enabled", "gauge", "Whether retention is configured", sample{value: boolValue(ret.Enabled)})
+	if ret.Enabled {
+		p.metric("nophr_retention_keep_days", "gauge", "Days events are kept", sample{value: float64(ret.KeepDays)})
+		p.metric("nophr_retention_prunable_events", "gauge", "Events past the retention cutoff", sample{value: float64(ret.EstimatedPrunable)})
+		if ret.AdvancedEnabled {
+			p.metric("nophr_retention_protected_events", "gauge", "Events protected by advanced retention rules", sample{value: float64(ret.TotalProtected)})
+		}
+	}
+
+	m.writeRequests(p)
+
+	return p.err
+}
+
+// writeRequests renders the request counters and latency histograms
+func (m *Metrics) writeRequests(p *promWriter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]requestKey, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].protocol != keys[j].protocol {
+			return keys[i].protocol < keys[j].protocol
+		}
+		return keys[i].status < keys[j].status
+	})
+
+	requests := make([]sample, 0, len(keys))
+	for _, key := range keys {
+		requests = append(requests, sample{labels: labels("protocol", key.protocol, "status", key.status), value: float64(m.requests[key])})
+	}
+	p.metric("nophr_requests_total", "counter", "Protocol requests by status", requests...)
+
+	protocols := make([]string, 0, len(m.latency))
+	for protocol := range m.latency {
+		protocols = append(protocols, protocol)
+	}
+	sort.Strings(protocols)
+
+	var buckets []sample
+	for _, protocol := range protocols {
+		h := m.latency[protocol]
+		var cumulative uint64
+		for i, bound := range latencyBuckets {
+			cumulative += h.counts[i]
+			buckets = append(buckets, sample{
+				suffix: "_bucket",
+				labels: labels("protocol", protocol, "le", formatFloat(bound)),
+				value:  float64(cumulative),
+			})
+		}
+		buckets = append(buckets,
+			sample{suffix: "_bucket", labels: labels("protocol", protocol, "le", "+Inf"), value: float64(h.count)},
+			sample{suffix: "_sum", labels: labels("protocol", protocol), value: h.sum},
+			sample{suffix: "_count", labels: labels("protocol", protocol), value: float64(h.count)},
+		)
+	}
+	p.metric("nophr_request_duration_seconds", "histogram", "Time to answer protocol requests", buckets...)
+}
+
+// Handler returns an HTTP handler serving the metrics
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var sb strings.Builder
+		if err := m.Render(r.Context(), &sb); err != nil {
+			http.Error(w, fmt.Sprintf("failed to collect metrics: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		io.WriteString(w, sb.String())
+	})
+}
+
+// MetricsServer serves metrics over HTTP for Prometheus to scrape
+type MetricsServer struct {
+	config     *config.Metrics
+	httpServer *http.Server
+	wg         sync.WaitGroup
+}
+
+// NewMetricsServer creates an HTTP listener for metrics
+func NewMetricsServer(cfg *config.Metrics, metrics *Metrics) *MetricsServer {
+	mux := http.NewServeMux()
+	mux.Handle(cfg.Path, metrics.Handler())
+
+	return &MetricsServer{
+		config: cfg,
+		httpServer: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+// Start starts the metrics listener
+func (s *MetricsServer) Start() error {
+	addr := fmt.Sprintf("%s:%d", s.config.Bind, s.config.Port)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to start metrics server: %w", err)
+	}
+	fmt.Printf("Metrics server listening on %s%s\n", addr, s.config.Path)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("Metrics server error: %v\n", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the metrics listener
+func (s *MetricsServer) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.httpServer.Shutdown(ctx)
+	s.wg.Wait()
+	return err
+}
+
+// sample is one line of a metric family
+type sample struct {
+	suffix string // e.g. "_bucket" for histograms
+	labels string // rendered label set, "" for none
+	value  float64
+}
+
+// promWriter writes metric families, keeping the first write error
+type promWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *promWriter) metric(name, kind, help string, samples ...sample) {
+	if p.err != nil {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# HELP %s %s\n", name, help))
+	sb.WriteString(fmt.Sprintf("# TYPE %s %s\n", name, kind))
+	for _, s := range samples {
+		sb.WriteString(name)
+		sb.WriteString(s.suffix)
+		if s.labels != "" {
+			sb.WriteString("{" + s.labels + "}")
+		}
+		sb.WriteString(" " + formatFloat(s.value) + "\n")
+	}
+
+	_, p.err = io.WriteString(p.w, sb.String())
+}
+
+// labelEscaper escapes label values per the exposition format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labels renders name/value pairs as a Prometheus label set
+func labels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+`="`+labelEscaper.Replace(pairs[i+1])+`"`)
+	}
+	return strings.Join(parts, ",")
+}
+
+func kindSamples(counts map[int]int64) []sample {
+	kinds := make([]int, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Ints(kinds)
+
+	samples := make([]sample, 0, len(kinds))
+	for _, kind := range kinds {
+		samples = append(samples, sample{labels: labels("kind", fmt.Sprintf("%d", kind)), value: float64(counts[kind])})
+	}
+	return samples
+}
+
+func stringSamples(label string, counts map[string]int64) []sample {
+	keys := sortedKeys(counts)
+	samples := make([]sample, 0, len(keys))
+	for _, key := range keys {
+		samples = append(samples, sample{labels: labels(label, key), value: float64(counts[key])})
+	}
+	return samples
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func formatFloat(f float64) string {
+	return fmt.Sprintf("%g", f)
+}
@@ -0,0 +1,86 @@
+package ops
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+func TestMetricsRender(t *testing.T) {
+	ctx := context.Background()
+	st, err := storage.New(ctx, &config.Storage{
+		Driver:     "sqlite",
+		SQLitePath: filepath.Join(t.TempDir(), "test.db"),
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	sk := nostr.GeneratePrivateKey()
+	for i, kind := range []int{1, 1, 7} {
+		event := &nostr.Event{Kind: kind, CreatedAt: nostr.Timestamp(1000 + i), Tags: nostr.Tags{}, Content: "+"}
+		event.Sign(sk)
+		if err := st.StoreEvent(ctx, event); err != nil {
+			t.Fatalf("failed to store event: %v", err)
+		}
+	}
+
+	metrics := NewMetrics(NewDiagnosticsCollector("v1.0.0", "abc123", st, nil))
+	metrics.ObserveRequest("gopher", "ok", 3*time.Millisecond)
+	metrics.ObserveRequest("gopher", "ok", 300*time.Millisecond)
+	metrics.ObserveRequest("gemini", "51", 20*time.Millisecond)
+	metrics.ObserveRequest("finger", "ok", time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if rec.Code != 200 {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+
+	body := rec.Body.String()
+	expected := []string{
+		`nophr_build_info{version="v1.0.0",commit="abc123",go_version="`,
+		"# TYPE nophr_events gauge\nnophr_events 3\n",
+		`nophr_events_by_kind{kind="1"} 2`,
+		`nophr_events_by_kind{kind="7"} 1`,
+		"nophr_sync_enabled 0",
+		`nophr_requests_total{protocol="gemini",status="51"} 1`,
+		`nophr_requests_total{protocol="gopher",status="ok"} 2`,
+		`nophr_requests_total{protocol="finger",status="ok"} 1`,
+		"# TYPE nophr_request_duration_seconds histogram",
+		`nophr_request_duration_seconds_bucket{protocol="gopher",le="0.005"} 1`,
+		`nophr_request_duration_seconds_bucket{protocol="gopher",le="0.25"} 1`,
+		`nophr_request_duration_seconds_bucket{protocol="gopher",le="0.5"} 2`,
+		`nophr_request_duration_seconds_bucket{protocol="gopher",le="+Inf"} 2`,
+		`nophr_request_duration_seconds_count{protocol="gopher"} 2`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %q\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsLabels(t *testing.T) {
+	got := labels("relay", `wss://a"b\c`+"\n")
+	want := `relay="wss://a\"b\\c\n"`
+	if got != want {
+		t.Errorf("labels() = %s, want %s", got, want)
+	}
+
+	// ObserveRequest on a nil registry is a no-op
+	var m *Metrics
+	m.ObserveRequest("gopher", "ok", time.Second)
+}
@@ -3,6 +3,7 @@ package ops
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/nbd-wtf/go-nostr"
@@ -14,6 +15,7 @@ import (
 // RetentionManager handles data retention and pruning
 type RetentionManager struct {
 	storage         *storage.Storage
+	mu              sync.RWMutex // guards config and retentionEngine
 	config          *config.Retention
 	logger          *Logger
 	retentionEngine *retention.Engine // Phase 20: Advanced retention
@@ -56,11 +58,51 @@ func NewRetentionManager(st *storage.Storage, cfg *config.Retention, logger *Log
 	return rm
 }
 
+// SetConfig applies new retention rules. Whether advanced retention runs at
+// all, prune_interval_hours and the re-evaluation schedule are fixed when the
+// background workers start, so those keep their current values.
+func (r *RetentionManager) SetConfig(cfg *config.Retention) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := *cfg
+	next.PruneIntervalHours = r.config.PruneIntervalHours
+
+	if r.retentionEngine == nil || next.Advanced == nil || !next.Advanced.Enabled {
+		next.Advanced = r.config.Advanced
+		r.config = &next
+		return
+	}
+
+	advanced := *next.Advanced
+	advanced.Evaluation = r.config.Advanced.Evaluation
+	next.Advanced = &advanced
+	r.config = &next
+	r.retentionEngine = retention.NewEngine(
+		&advanced,
+		&storageAdapter{storage: r.storage},
+		&graphAdapter{storage: r.storage},
+		r.ownerPubkey,
+	)
+
+	r.logger.Info("retention rules reloaded",
+		"mode", advanced.Mode,
+		"rules", len(advanced.Rules))
+}
+
+// settings returns the current retention config and advanced engine
+func (r *RetentionManager) settings() (*config.Retention, *retention.Engine) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config, r.retentionEngine
+}
+
 // PruneOldEvents deletes events based on retention rules
 // Routes to advanced or simple pruning based on configuration
 func (r *RetentionManager) PruneOldEvents(ctx context.Context) (int64, error) {
 	// Check if advanced retention is enabled
-	if r.config.Advanced != nil && r.config.Advanced.Enabled && r.retentionEngine != nil {
+	cfg, engine := r.settings()
+	if cfg.Advanced != nil && cfg.Advanced.Enabled && engine != nil {
 		return r.PruneAdvanced(ctx)
 	}
 
@@ -68,16 +110,46 @@ func (r *RetentionManager) PruneOldEvents(ctx context.Context) (int64, error) {
 	return r.pruneSimple(ctx)
 }
 
+// CountPrunable returns how many events PruneOldEvents would delete now,
+// without deleting anything
+func (r *RetentionManager) CountPrunable(ctx context.Context) (int64, error) {
+	cfg, engine := r.settings()
+	if cfg.Advanced == nil || !cfg.Advanced.Enabled || engine == nil {
+		cutoff := time.Now().AddDate(0, 0, -cfg.KeepDays)
+		return r.storage.CountEventsBefore(ctx, cutoff)
+	}
+
+	// Expired events go first, then the lowest-scored ones over the cap
+	expired, err := r.storage.GetExpiredEvents(ctx, 1000)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expired events: %w", err)
+	}
+	count := int64(len(expired))
+
+	if max := int64(cfg.Advanced.GlobalCaps.MaxTotalEvents); max > 0 {
+		total, err := r.storage.CountEvents(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count events: %w", err)
+		}
+		if remaining := total - count; remaining > max {
+			count += remaining - max
+		}
+	}
+
+	return count, nil
+}
+
 // pruneSimple performs simple time-based pruning (original implementation)
 func (r *RetentionManager) pruneSimple(ctx context.Context) (int64, error) {
 	start := time.Now()
+	cfg, _ := r.settings()
 
 	// Calculate cutoff time
-	cutoff := time.Now().AddDate(0, 0, -r.config.KeepDays)
+	cutoff := time.Now().AddDate(0, 0, -cfg.KeepDays)
 
 	r.logger.Info("starting simple retention pruning",
 		"cutoff", cutoff.Format(time.RFC3339),
-		"keep_days", r.config.KeepDays)
+		"keep_days", cfg.KeepDays)
 
 	// Delete events before cutoff
 	deleted, err := r.storage.DeleteEventsBefore(ctx, cutoff)
@@ -112,14 +184,16 @@ func (r *RetentionManager) PruneByKind(ctx context.Context, kind int) (int64, er
 
 // ShouldPruneOnStart returns true if pruning should run on startup
 func (r *RetentionManager) ShouldPruneOnStart() bool {
-	return r.config.PruneOnStart
+	cfg, _ := r.settings()
+	return cfg.PruneOnStart
 }
 
 // GetRetentionStats returns statistics about retention
 func (r *RetentionManager) GetRetentionStats(ctx context.Context) (*RetentionStats, error) {
+	cfg, _ := r.settings()
 	stats := &RetentionStats{
-		KeepDays:     r.config.KeepDays,
-		PruneOnStart: r.config.PruneOnStart,
+		KeepDays:     cfg.KeepDays,
+		PruneOnStart: cfg.PruneOnStart,
 	}
 
 	// Get total events
@@ -143,7 +217,7 @@ func (r *RetentionManager) GetRetentionStats(ctx context.Context) (*RetentionSta
 	}
 
 	// Calculate events eligible for pruning
-	cutoff := time.Now().AddDate(0, 0, -r.config.KeepDays)
+	cutoff := time.Now().AddDate(0, 0, -cfg.KeepDays)
 	stats.Cutoff = cutoff
 
 	// Estimate prunable events (this is approximate)
@@ -222,7 +296,8 @@ func (p *PeriodicPruner) Stop() {
 
 // PruneAdvanced performs advanced retention pruning using rules and caps
 func (r *RetentionManager) PruneAdvanced(ctx context.Context) (int64, error) {
-	if r.retentionEngine == nil {
+	cfg, engine := r.settings()
+	if engine == nil {
 		return 0, fmt.Errorf("advanced retention engine not initialized")
 	}
 
@@ -241,8 +316,8 @@ func (r *RetentionManager) PruneAdvanced(ctx context.Context) (int64, error) {
 	}
 
 	// Step 2: Enforce global caps
-	if r.config.Advanced.GlobalCaps.MaxTotalEvents > 0 || r.config.Advanced.GlobalCaps.MaxStorageMB > 0 {
-		capped, err := r.enforceGlobalCaps(ctx)
+	if cfg.Advanced.GlobalCaps.MaxTotalEvents > 0 || cfg.Advanced.GlobalCaps.MaxStorageMB > 0 {
+		capped, err := r.enforceGlobalCaps(ctx, cfg.Advanced.GlobalCaps)
 		if err != nil {
 			r.logger.Error("failed to enforce global caps", "error", err)
 		} else {
@@ -285,9 +360,7 @@ func (r *RetentionManager) pruneExpiredEvents(ctx context.Context) (int64, error
 }
 
 // enforceGlobalCaps enforces storage caps by deleting lowest-priority events
-func (r *RetentionManager) enforceGlobalCaps(ctx context.Context) (int64, error) {
-	caps := r.config.Advanced.GlobalCaps
-
+func (r *RetentionManager) enforceGlobalCaps(ctx context.Context, caps config.GlobalCaps) (int64, error) {
 	// Check if we're over the total events cap
 	totalEvents, err := r.storage.CountEvents(ctx)
 	if err != nil {
@@ -328,11 +401,12 @@ func (r *RetentionManager) enforceGlobalCaps(ctx context.Context) (int64, error)
 
 // EvaluateEvent evaluates retention for a single event
 func (r *RetentionManager) EvaluateEvent(ctx context.Context, event *nostr.Event) error {
-	if r.retentionEngine == nil {
+	_, engine := r.settings()
+	if engine == nil {
 		return nil // Advanced retention not enabled, skip
 	}
 
-	decision, err := r.retentionEngine.EvaluateEvent(ctx, event)
+	decision, err := engine.EvaluateEvent(ctx, event)
 	if err != nil {
 		return fmt.Errorf("failed to evaluate event: %w", err)
 	}
@@ -426,18 +500,19 @@ func (a *graphAdapter) IsMutual(ownerPubkey, targetPubkey string) bool {
 // StartReEvaluationWorker starts the background re-evaluation worker
 func (r *RetentionManager) StartReEvaluationWorker(ctx context.Context) {
 	// Only start if advanced retention is enabled with re-evaluation configured
-	if r.config.Advanced == nil || !r.config.Advanced.Enabled {
+	cfg, _ := r.settings()
+	if cfg.Advanced == nil || !cfg.Advanced.Enabled {
 		return
 	}
 
-	if r.config.Advanced.Evaluation.ReEvalIntervalHrs <= 0 {
+	if cfg.Advanced.Evaluation.ReEvalIntervalHrs <= 0 {
 		r.logger.Info("re-evaluation worker not started (interval not configured)")
 		return
 	}
 
-	interval := time.Duration(r.config.Advanced.Evaluation.ReEvalIntervalHrs) * time.Hour
+	interval := time.Duration(cfg.Advanced.Evaluation.ReEvalIntervalHrs) * time.Hour
 	r.logger.Info("starting re-evaluation worker",
-		"interval_hours", r.config.Advanced.Evaluation.ReEvalIntervalHrs)
+		"interval_hours", cfg.Advanced.Evaluation.ReEvalIntervalHrs)
 
 	go r.reEvaluationLoop(ctx, interval)
 }
@@ -470,13 +545,14 @@ func (r *RetentionManager) reEvaluationLoop(ctx context.Context, interval time.D
 func (r *RetentionManager) reEvaluateEvents(ctx context.Context) error {
 	start := time.Now()
 
-	if r.retentionEngine == nil {
+	cfg, engine := r.settings()
+	if engine == nil {
 		return fmt.Errorf("retention engine not initialized")
 	}
 
 	// Get events that need re-evaluation
-	cutoff := time.Now().Add(-time.Duration(r.config.Advanced.Evaluation.ReEvalIntervalHrs) * time.Hour)
-	batchSize := r.config.Advanced.Evaluation.BatchSize
+	cutoff := time.Now().Add(-time.Duration(cfg.Advanced.Evaluation.ReEvalIntervalHrs) * time.Hour)
+	batchSize := cfg.Advanced.Evaluation.BatchSize
 	if batchSize == 0 {
 		batchSize = 1000
 	}
@@ -0,0 +1,108 @@
+package ops
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+func TestRetentionManagerSetConfig(t *testing.T) {
+	logger := NewLogger(&config.Logging{Level: "error"})
+
+	t.Run("simple retention", func(t *testing.T) {
+		rm := NewRetentionManager(nil, &config.Retention{KeepDays: 30, PruneIntervalHours: 6}, logger, "")
+
+		rm.SetConfig(&config.Retention{KeepDays: 7, PruneIntervalHours: 1})
+
+		cfg, engine := rm.settings()
+		if cfg.KeepDays != 7 {
+			t.Errorf("expected keep_days 7 after reload, got %d", cfg.KeepDays)
+		}
+		if cfg.PruneIntervalHours != 6 {
+			t.Errorf("prune interval should keep its startup value, got %d", cfg.PruneIntervalHours)
+		}
+		if engine != nil {
+			t.Error("advanced retention cannot be switched on by a reload")
+		}
+	})
+
+	t.Run("advanced retention", func(t *testing.T) {
+		rm := NewRetentionManager(nil, &config.Retention{
+			KeepDays: 30,
+			Advanced: &config.AdvancedRetention{
+				Enabled:    true,
+				Mode:       "rules",
+				Rules:      []config.RetentionRule{{Name: "everything", Conditions: config.RuleConditions{All: true}}},
+				Evaluation: config.EvaluationConfig{ReEvalIntervalHrs: 24},
+			},
+		}, logger, "")
+		_, before := rm.settings()
+
+		rm.SetConfig(&config.Retention{
+			KeepDays: 30,
+			Advanced: &config.AdvancedRetention{
+				Enabled:    true,
+				Mode:       "caps",
+				Evaluation: config.EvaluationConfig{ReEvalIntervalHrs: 1},
+			},
+		})
+
+		cfg, after := rm.settings()
+		if cfg.Advanced.Mode != "caps" || len(cfg.Advanced.Rules) != 0 {
+			t.Errorf("expected the new rules to apply, got %+v", cfg.Advanced)
+		}
+		if cfg.Advanced.Evaluation.ReEvalIntervalHrs != 24 {
+			t.Errorf("re-evaluation interval should keep its startup value, got %d", cfg.Advanced.Evaluation.ReEvalIntervalHrs)
+		}
+		if after == nil || after == before {
+			t.Error("expected a new retention engine for the new rules")
+		}
+	})
+}
+
+func TestRetentionManagerCountPrunable(t *testing.T) {
+	ctx := context.Background()
+	st, err := storage.New(ctx, &config.Storage{
+		Driver:     "sqlite",
+		SQLitePath: filepath.Join(t.TempDir(), "nophr.db"),
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	for i, age := range []int{1, 10, 20} {
+		event := &nostr.Event{
+			ID:        strings.Repeat(string(rune('a'+i)), 64),
+			PubKey:    strings.Repeat("f", 64),
+			CreatedAt: nostr.Timestamp(time.Now().AddDate(0, 0, -age).Unix()),
+			Kind:      1,
+			Tags:      nostr.Tags{},
+			Sig:       strings.Repeat("c", 128),
+		}
+		if err := st.StoreEvent(ctx, event); err != nil {
+			t.Fatalf("failed to store event: %v", err)
+		}
+	}
+
+	rm := NewRetentionManager(st, &config.Retention{KeepDays: 7}, NewLogger(&config.Logging{Level: "error"}), "")
+
+	count, err := rm.CountPrunable(ctx)
+	if err != nil {
+		t.Fatalf("CountPrunable() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 events older than 7 days, got %d", count)
+	}
+
+	total, _ := st.CountEvents(ctx)
+	if total != 3 {
+		t.Errorf("CountPrunable should not delete anything, %d events left", total)
+	}
+}
@@ -0,0 +1,356 @@
+package outbox
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/aggregates"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/nostr/helpers"
+	"github.com/sandwichfarm/nophr/internal/security"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+// Publisher sends signed events to relays
+type Publisher interface {
+	PublishEvent(ctx context.Context, relays []string, event *nostr.Event) error
+}
+
+// EventHandler is called after an event has been published and stored
+type EventHandler func(ctx context.Context, event *nostr.Event)
+
+// Draft is an unsigned event waiting in the draft directory
+type Draft struct {
+	Name  string
+	Event *nostr.Event
+}
+
+// Outbox composes owner events, keeps them as drafts, and signs and publishes
+// them with the NOPHR_NSEC key
+type Outbox struct {
+	config    *config.Outbox
+	relays    *config.Relays
+	storage   *storage.Storage
+	publisher Publisher
+	owner     string // hex pubkey
+	handlers  []EventHandler
+}
+
+// New creates an outbox for the configured identity
+func New(cfg *config.Config, st *storage.Storage, publisher Publisher) (*Outbox, error) {
+	owner, err := helpers.NormalizePubkey(cfg.Identity.Npub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode identity npub: %w", err)
+	}
+
+	return &Outbox{
+		config:    &cfg.Outbox,
+		relays:    &cfg.Relays,
+		storage:   st,
+		publisher: publisher,
+		owner:     owner,
+	}, nil
+}
+
+// AddEventHandler registers a handler for published events
+func (o *Outbox) AddEventHandler(handler EventHandler) {
+	if handler == nil {
+		return
+	}
+	o.handlers = append(o.handlers, handler)
+}
+
+// AutoSign reports whether composed events are published without review
+func (o *Outbox) AutoSign() bool {
+	return o.config.AutoSign
+}
+
+// Allowed reports whether publishing events of kind is enabled. Zap requests
+// go to the recipient's LNURL endpoint rather than relays and are not handled.
+func (o *Outbox) Allowed(kind int) bool {
+	switch kind {
+	case 1:
+		return o.config.Publish.Notes
+	case 7:
+		return o.config.Publish.Reactions
+	}
+	return false
+}
+
+// NormalizeFingerprint lowercases a hex fingerprint and strips colons
+func NormalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fingerprint), ":", ""))
+}
+
+// NewNote builds an unsigned kind 1 note
+func (o *Outbox) NewNote(content string) *nostr.Event {
+	return o.newEvent(1, content, nostr.Tags{})
+}
+
+// NewReply builds an unsigned kind 1 reply to a stored event, with NIP-10
+// marked e tags and p tags for everyone in the parent's thread
+func (o *Outbox) NewReply(ctx context.Context, parentID, content string) (*nostr.Event, error) {
+	parent, err := o.getEvent(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	rootID := parent.ID
+	if info, err := aggregates.ParseThreadInfo(parent); err == nil && info.RootEventID != "" {
+		rootID = info.RootEventID
+	}
+
+	tags := nostr.Tags{}
+	if rootID != parent.ID {
+		tags = append(tags, nostr.Tag{"e", rootID, "", "root"})
+		tags = append(tags, nostr.Tag{"e", parent.ID, "", "reply"})
+	} else {
+		tags = append(tags, nostr.Tag{"e", parent.ID, "", "root"})
+	}
+	tags = append(tags, threadPTags(parent, o.owner)...)
+
+	return o.newEvent(1, content, tags), nil
+}
+
+// NewReaction builds an unsigned kind 7 reaction to a stored event
+func (o *Outbox) NewReaction(ctx context.Context, targetID, content string) (*nostr.Event, error) {
+	target, err := o.getEvent(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if content == "" {
+		content = "+"
+	}
+
+	tags := nostr.Tags{
+		{"e", target.ID},
+		{"p", target.PubKey},
+		{"k", fmt.Sprintf("%d", target.Kind)},
+	}
+
+	return o.newEvent(7, content, tags), nil
+}
+
+// SaveDraft writes an unsigned event to the draft directory and returns its name
+func (o *Outbox) SaveDraft(event *nostr.Event) (string, error) {
+	if err := os.MkdirAll(o.config.DraftDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create draft directory: %w", err)
+	}
+
+	name := event.GetID()[:16]
+	data, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode draft: %w", err)
+	}
+
+	if err := os.WriteFile(o.draftPath(name), data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write draft: %w", err)
+	}
+
+	return name, nil
+}
+
+// LoadDraft reads a draft by name
+func (o *Outbox) LoadDraft(name string) (*nostr.Event, error) {
+	if !IsDraftName(name) {
+		return nil, fmt.Errorf("invalid draft name: %s", name)
+	}
+
+	data, err := os.ReadFile(o.draftPath(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read draft: %w", err)
+	}
+
+	var event nostr.Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode draft: %w", err)
+	}
+
+	return &event, nil
+}
+
+// ListDrafts returns all drafts, newest first
+func (o *Outbox) ListDrafts() ([]Draft, error) {
+	entries, err := os.ReadDir(o.config.DraftDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read draft directory: %w", err)
+	}
+
+	var drafts []Draft
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !IsDraftName(name) {
+			continue
+		}
+		event, err := o.LoadDraft(name)
+		if err != nil {
+			continue
+		}
+		drafts = append(drafts, Draft{Name: name, Event: event})
+	}
+
+	sort.Slice(drafts, func(i, j int) bool {
+		return drafts[i].Event.CreatedAt > drafts[j].Event.CreatedAt
+	})
+
+	return drafts, nil
+}
+
+// DeleteDraft removes a draft by name
+func (o *Outbox) DeleteDraft(name string) error {
+	if !IsDraftName(name) {
+		return fmt.Errorf("invalid draft name: %s", name)
+	}
+
+	if err := os.Remove(o.draftPath(name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete draft: %w", err)
+	}
+
+	return nil
+}
+
+// PublishDraft signs and publishes a draft, removing it once published
+func (o *Outbox) PublishDraft(ctx context.Context, name string) (*nostr.Event, error) {
+	event, err := o.LoadDraft(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.Publish(ctx, event); err != nil {
+		return nil, err
+	}
+
+	if err := o.DeleteDraft(name); err != nil {
+		fmt.Printf("[OUTBOX] ⚠ %v\n", err)
+	}
+
+	return event, nil
+}
+
+// Publish signs an event with NOPHR_NSEC, publishes it to the owner's write
+// relays and stores it locally
+func (o *Outbox) Publish(ctx context.Context, event *nostr.Event) error {
+	if !o.Allowed(event.Kind) {
+		return fmt.Errorf("publishing kind %d is disabled", event.Kind)
+	}
+
+	sk, err := o.secretKey()
+	if err != nil {
+		return err
+	}
+
+	event.CreatedAt = nostr.Now()
+	if err := event.Sign(sk); err != nil {
+		return fmt.Errorf("failed to sign event: %w", err)
+	}
+
+	relays, err := o.writeRelays(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := o.publisher.PublishEvent(ctx, relays, event); err != nil {
+		return err
+	}
+
+	if err := o.storage.StoreEvent(ctx, event); err != nil {
+		return err
+	}
+
+	fmt.Printf("[OUTBOX] ✓ Published kind %d event %s to %d relay(s)\n", event.Kind, event.ID[:16]+"...", len(relays))
+
+	for _, handler := range o.handlers {
+		handler(ctx, event)
+	}
+
+	return nil
+}
+
+// IsDraftName reports whether name is a valid draft name (16 hex characters)
+func IsDraftName(name string) bool {
+	if len(name) != 16 {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
+
+func (o *Outbox) newEvent(kind int, content string, tags nostr.Tags) *nostr.Event {
+	return &nostr.Event{
+		PubKey:    o.owner,
+		CreatedAt: nostr.Now(),
+		Kind:      kind,
+		Tags:      tags,
+		Content:   content,
+	}
+}
+
+func (o *Outbox) draftPath(name string) string {
+	return filepath.Join(o.config.DraftDir, name+".json")
+}
+
+func (o *Outbox) getEvent(ctx context.Context, id string) (*nostr.Event, error) {
+	events, err := o.storage.QueryEvents(ctx, nostr.Filter{IDs: []string{id}, Limit: 1})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query event: %w", err)
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("event not found: %s", id)
+	}
+	return events[0], nil
+}
+
+// secretKey loads NOPHR_NSEC and checks it belongs to the configured identity
+func (o *Outbox) secretKey() (string, error) {
+	return security.OwnerSecretKey(o.owner)
+}
+
+// writeRelays returns the owner's NIP-65 write relays, falling back to the
+// configured seed relays
+func (o *Outbox) writeRelays(ctx context.Context) ([]string, error) {
+	relays, err := o.storage.GetWriteRelays(ctx, o.owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get write relays: %w", err)
+	}
+	if len(relays) == 0 {
+		relays = o.relays.Seeds
+	}
+	if len(relays) == 0 {
+		return nil, fmt.Errorf("no write relays known for owner")
+	}
+	return relays, nil
+}
+
+// threadPTags returns p tags for the parent's author and everyone it tagged,
+// excluding the owner
+func threadPTags(parent *nostr.Event, owner string) nostr.Tags {
+	seen := map[string]bool{owner: true}
+	var tags nostr.Tags
+
+	add := func(pubkey string) {
+		if !seen[pubkey] {
+			seen[pubkey] = true
+			tags = append(tags, nostr.Tag{"p", pubkey})
+		}
+	}
+
+	add(parent.PubKey)
+	for _, tag := range parent.Tags {
+		if len(tag) >= 2 && tag[0] == "p" {
+			add(tag[1])
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,175 @@
+package outbox
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+type fakePublisher struct {
+	relays []string
+	events []*nostr.Event
+}
+
+func (f *fakePublisher) PublishEvent(_ context.Context, relays []string, event *nostr.Event) error {
+	f.relays = relays
+	f.events = append(f.events, event)
+	return nil
+}
+
+func setupTestOutbox(t *testing.T) (*Outbox, *storage.Storage, *fakePublisher, string) {
+	t.Helper()
+
+	sk := nostr.GeneratePrivateKey()
+	pk, _ := nostr.GetPublicKey(sk)
+	npub, _ := nip19.EncodePublicKey(pk)
+	nsec, _ := nip19.EncodePrivateKey(sk)
+	t.Setenv("NOPHR_NSEC", nsec)
+
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.Identity.Npub = npub
+	cfg.Relays.Seeds = []string{"wss://seed.example.com"}
+	cfg.Outbox.DraftDir = filepath.Join(dir, "drafts")
+	cfg.Outbox.Publish.Reactions = true
+
+	st, err := storage.New(context.Background(), &config.Storage{
+		Driver:     "sqlite",
+		SQLitePath: filepath.Join(dir, "test.db"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	pub := &fakePublisher{}
+	ob, err := New(cfg, st, pub)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	return ob, st, pub, pk
+}
+
+func TestNewReply(t *testing.T) {
+	ob, st, _, owner := setupTestOutbox(t)
+	ctx := context.Background()
+
+	alice := nostr.GeneratePrivateKey()
+	root := &nostr.Event{Kind: 1, CreatedAt: 100, Tags: nostr.Tags{}, Content: "root"}
+	root.Sign(alice)
+	parent := &nostr.Event{Kind: 1, CreatedAt: 110, Tags: nostr.Tags{
+		{"e", root.ID, "", "root"},
+		{"p", owner},
+		{"p", "bob"},
+	}, Content: "parent"}
+	parent.Sign(alice)
+	for _, event := range []*nostr.Event{root, parent} {
+		if err := st.StoreEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to store event: %v", err)
+		}
+	}
+
+	reply, err := ob.NewReply(ctx, parent.ID, "hi")
+	if err != nil {
+		t.Fatalf("NewReply() error = %v", err)
+	}
+
+	want := nostr.Tags{
+		{"e", root.ID, "", "root"},
+		{"e", parent.ID, "", "reply"},
+		{"p", parent.PubKey},
+		{"p", "bob"},
+	}
+	if !reflect.DeepEqual(reply.Tags, want) {
+		t.Errorf("Expected tags %v, got %v", want, reply.Tags)
+	}
+
+	// Replying to a thread root marks it as root only
+	reply, err = ob.NewReply(ctx, root.ID, "hi")
+	if err != nil {
+		t.Fatalf("NewReply() error = %v", err)
+	}
+	if len(reply.Tags) != 2 || !reflect.DeepEqual(reply.Tags[0], nostr.Tag{"e", root.ID, "", "root"}) {
+		t.Errorf("Unexpected tags for reply to root: %v", reply.Tags)
+	}
+
+	if _, err := ob.NewReply(ctx, "missing", "hi"); err == nil {
+		t.Error("Expected error replying to unknown event")
+	}
+}
+
+func TestPublishDraft(t *testing.T) {
+	ob, st, pub, owner := setupTestOutbox(t)
+	ctx := context.Background()
+
+	var handled []*nostr.Event
+	ob.AddEventHandler(func(_ context.Context, event *nostr.Event) {
+		handled = append(handled, event)
+	})
+
+	name, err := ob.SaveDraft(ob.NewNote("hello from gemini"))
+	if err != nil {
+		t.Fatalf("SaveDraft() error = %v", err)
+	}
+
+	drafts, err := ob.ListDrafts()
+	if err != nil || len(drafts) != 1 || drafts[0].Name != name {
+		t.Fatalf("ListDrafts() = %v, %v; want draft %s", drafts, err, name)
+	}
+
+	event, err := ob.PublishDraft(ctx, name)
+	if err != nil {
+		t.Fatalf("PublishDraft() error = %v", err)
+	}
+
+	if event.PubKey != owner {
+		t.Errorf("Expected owner pubkey, got %s", event.PubKey)
+	}
+	if ok, _ := event.CheckSignature(); !ok {
+		t.Error("Expected a valid signature")
+	}
+	if len(pub.events) != 1 || len(pub.relays) != 1 || pub.relays[0] != "wss://seed.example.com" {
+		t.Errorf("Expected publish to seed relay, got %v to %v", pub.events, pub.relays)
+	}
+	if ok, _ := st.EventExists(ctx, event.ID); !ok {
+		t.Error("Expected published event to be stored")
+	}
+	if len(handled) != 1 {
+		t.Errorf("Expected event handler to be called once, got %d", len(handled))
+	}
+	if drafts, _ := ob.ListDrafts(); len(drafts) != 0 {
+		t.Errorf("Expected draft to be removed, got %v", drafts)
+	}
+}
+
+func TestPublishRejected(t *testing.T) {
+	ob, _, pub, _ := setupTestOutbox(t)
+	ctx := context.Background()
+
+	ob.config.Publish.Notes = false
+	if err := ob.Publish(ctx, ob.NewNote("disabled")); err == nil {
+		t.Error("Expected error publishing a disabled kind")
+	}
+	ob.config.Publish.Notes = true
+
+	other, _ := nip19.EncodePrivateKey(nostr.GeneratePrivateKey())
+	t.Setenv("NOPHR_NSEC", other)
+	if err := ob.Publish(ctx, ob.NewNote("wrong key")); err == nil {
+		t.Error("Expected error signing with a key that is not the identity")
+	}
+
+	if len(pub.events) != 0 {
+		t.Errorf("Expected nothing published, got %d events", len(pub.events))
+	}
+
+	if _, err := ob.LoadDraft("../../etc/passwd"); err == nil {
+		t.Error("Expected invalid draft name to be rejected")
+	}
+}
@@ -159,6 +159,15 @@ func (l *Loader) applyTemplateVariables(content string) string {
 	return result
 }
 
+// SetConfig switches the loader to a new configuration and drops content
+// cached from the previous one
+func (l *Loader) SetConfig(cfg *config.Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.config = cfg
+	l.cache = make(map[string]*cachedContent)
+}
+
 // ClearCache clears the content cache
 func (l *Loader) ClearCache() {
 	l.mu.Lock()
@@ -309,6 +309,29 @@ func TestClearCache(t *testing.T) {
 	}
 }
 
+func TestSetConfig(t *testing.T) {
+	header := func(content string) *config.Config {
+		return &config.Config{
+			Presentation: config.Presentation{
+				Headers: config.Headers{
+					Global: config.HeaderConfig{Enabled: true, Content: content},
+				},
+			},
+		}
+	}
+
+	loader := NewLoader(header("Old Header"))
+	if got, _ := loader.GetHeader(""); got != "Old Header" {
+		t.Fatalf("Expected 'Old Header', got %q", got)
+	}
+
+	// The cached header must not outlive the config it came from
+	loader.SetConfig(header("New Header"))
+	if got, _ := loader.GetHeader(""); got != "New Header" {
+		t.Errorf("Expected 'New Header' after SetConfig, got %q", got)
+	}
+}
+
 func TestTemplateVariables(t *testing.T) {
 	now := time.Now()
 	cfg := &config.Config{
@@ -0,0 +1,200 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fiatjaf/khatru"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip11"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/nostr/helpers"
+	"github.com/sandwichfarm/nophr/internal/security"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+// maxQueryLimit caps the events returned per REQ filter
+const maxQueryLimit = 500
+
+// EventHandler is called after an event published to the relay has been stored
+type EventHandler func(ctx context.Context, event *nostr.Event)
+
+// Server serves the storage's khatru relay as a NIP-01 websocket relay. It is
+// read-only unless owner writes are enabled, in which case only the owner may
+// publish, after NIP-42 auth.
+type Server struct {
+	config      *config.RelayProtocol
+	storage     *storage.Storage
+	relay       *khatru.Relay
+	ownerPubkey string
+	guard       *security.Guard
+	handlers    []EventHandler
+
+	httpServer *http.Server
+	listener   net.Listener
+	wg         sync.WaitGroup
+}
+
+// New creates a relay server for the storage's khatru relay
+func New(cfg *config.RelayProtocol, fullCfg *config.Config, st *storage.Storage) (*Server, error) {
+	owner, err := helpers.NormalizePubkey(fullCfg.Identity.Npub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode identity npub: %w", err)
+	}
+
+	s := &Server{
+		config:      cfg,
+		storage:     st,
+		relay:       st.Relay(),
+		ownerPubkey: owner,
+		guard:       security.NewGuard(&fullCfg.Security),
+	}
+
+	rl := s.relay
+	rl.ServiceURL = cfg.URL
+
+	// NIP-11 information from the site config
+	rl.Info.Name = fullCfg.Site.Title
+	rl.Info.Description = fullCfg.Site.Description
+	rl.Info.Contact = fullCfg.Site.Operator
+	rl.Info.PubKey = owner
+	rl.Info.Software = "https://github.com/sandwichfarm/nophr"
+	rl.Info.SupportedNIPs = []any{1, 11, 50}
+	if cfg.OwnerWrites {
+		rl.Info.AddSupportedNIP(42)
+	}
+	rl.Info.Limitation = &nip11.RelayLimitationDocument{
+		MaxLimit:         maxQueryLimit,
+		RestrictedWrites: true,
+	}
+
+	// Storage.QueryEvents reads the eventstore directly, so replacing the
+	// relay's query hooks only changes what websocket clients see
+	rl.QueryEvents = []func(ctx context.Context, filter nostr.Filter) (chan *nostr.Event, error){s.queryEvents}
+	rl.RejectEvent = append(rl.RejectEvent, s.rejectEvent)
+	rl.OnEventSaved = append(rl.OnEventSaved, s.onEventSaved)
+
+	return s, nil
+}
+
+// AddEventHandler registers a handler for events published by the owner
+func (s *Server) AddEventHandler(handler EventHandler) {
+	if handler == nil {
+		return
+	}
+	s.handlers = append(s.handlers, handler)
+}
+
+// SetGuard replaces the server's own guard with one shared by every
+// server, so connection caps count connections to all of them together
+func (s *Server) SetGuard(g *security.Guard) {
+	s.guard.Close()
+	s.guard = g
+}
+
+// Start starts the relay server
+func (s *Server) Start() error {
+	addr := fmt.Sprintf("%s:%d", s.config.Bind, s.config.Port)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to start relay server: %w", err)
+	}
+
+	s.listener = listener
+	s.httpServer = &http.Server{
+		Handler:           s.relay,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Printf("Relay server listening on %s\n", addr)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("Relay server error: %v\n", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the relay server
+func (s *Server) Stop() error {
+	if s.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.httpServer.Shutdown(ctx); err != nil {
+			s.httpServer.Close()
+		}
+	}
+
+	s.wg.Wait()
+	s.guard.Close()
+	return nil
+}
+
+// queryEvents answers REQ filters, using the storage search for NIP-50 and
+// hiding events the security config denies
+func (s *Server) queryEvents(ctx context.Context, filter nostr.Filter) (chan *nostr.Event, error) {
+	// khatru looks up the targets of a client's deletion request to remove
+	// them before storing it. Report none: the handlers apply the request
+	// after it is stored, as for synced ones, so it is recorded against
+	// late copies and the exporters see what it removed.
+	if khatru.IsInternalCall(ctx) && khatru.GetConnection(ctx) != nil {
+		ch := make(chan *nostr.Event)
+		close(ch)
+		return ch, nil
+	}
+
+	if filter.Limit <= 0 || filter.Limit > maxQueryLimit {
+		filter.Limit = maxQueryLimit
+	}
+
+	events, err := s.storage.QueryEventsWithSearch(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	events = s.guard.FilterEvents(events)
+
+	ch := make(chan *nostr.Event, len(events))
+	for _, event := range events {
+		ch <- event
+	}
+	close(ch)
+
+	return ch, nil
+}
+
+// rejectEvent refuses every event unless owner writes are enabled and the
+// connection has authenticated as the owner
+func (s *Server) rejectEvent(ctx context.Context, event *nostr.Event) (bool, string) {
+	if !s.config.OwnerWrites {
+		return true, "blocked: this relay is read-only"
+	}
+
+	authed := khatru.GetAuthed(ctx)
+	if authed == "" {
+		return true, "auth-required: only the owner may publish"
+	}
+	if authed != s.ownerPubkey || event.PubKey != s.ownerPubkey {
+		return true, "restricted: only the owner may publish"
+	}
+
+	return false, ""
+}
+
+// onEventSaved notifies handlers of events the owner published. Deletion
+// requests are stored like other events and applied by the handlers.
+func (s *Server) onEventSaved(ctx context.Context, event *nostr.Event) {
+	fmt.Printf("[RELAY] ✓ Stored kind %d event %s from owner\n", event.Kind, event.ID[:16]+"...")
+
+	for _, handler := range s.handlers {
+		handler(ctx, event)
+	}
+}
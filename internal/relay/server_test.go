@@ -0,0 +1,238 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip11"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/storage"
+	"github.com/sandwichfarm/nophr/internal/sync"
+)
+
+func setupTestRelay(t *testing.T, port int, ownerWrites bool) (*Server, *storage.Storage, string) {
+	t.Helper()
+
+	sk := nostr.GeneratePrivateKey()
+	pk, _ := nostr.GetPublicKey(sk)
+	npub, _ := nip19.EncodePublicKey(pk)
+
+	cfg := config.Default()
+	cfg.Identity.Npub = npub
+	cfg.Site.Title = "Test Archive"
+	cfg.Protocols.Relay = config.RelayProtocol{
+		Enabled:     true,
+		Port:        port,
+		Bind:        "127.0.0.1",
+		OwnerWrites: ownerWrites,
+	}
+
+	st, err := storage.New(context.Background(), &config.Storage{
+		Driver:     "sqlite",
+		SQLitePath: filepath.Join(t.TempDir(), "test.db"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	server, err := New(&cfg.Protocols.Relay, cfg, st)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	t.Cleanup(func() { server.Stop() })
+
+	return server, st, sk
+}
+
+func TestRelayReadOnly(t *testing.T) {
+	_, st, sk := setupTestRelay(t, 17777, false)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	notes := []string{"hello gopherspace", "hello geminispace", "unrelated"}
+	for i, content := range notes {
+		event := &nostr.Event{Kind: 1, CreatedAt: nostr.Timestamp(1000 + i), Tags: nostr.Tags{}, Content: content}
+		event.Sign(sk)
+		if err := st.StoreEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to store event: %v", err)
+		}
+	}
+
+	t.Run("NIP-11", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "http://127.0.0.1:17777", nil)
+		req.Header.Set("Accept", "application/nostr+json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("NIP-11 request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var info nip11.RelayInformationDocument
+		if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+			t.Fatalf("Failed to decode NIP-11 document: %v", err)
+		}
+		pk, _ := nostr.GetPublicKey(sk)
+		if info.Name != "Test Archive" || info.PubKey != pk {
+			t.Errorf("Unexpected NIP-11 document: %+v", info)
+		}
+	})
+
+	r, err := nostr.RelayConnect(ctx, "ws://127.0.0.1:17777")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer r.Close()
+
+	t.Run("REQ", func(t *testing.T) {
+		events, err := r.QuerySync(ctx, nostr.Filter{Kinds: []int{1}})
+		if err != nil {
+			t.Fatalf("QuerySync() error = %v", err)
+		}
+		if len(events) != len(notes) {
+			t.Errorf("Expected %d events, got %d", len(notes), len(events))
+		}
+	})
+
+	t.Run("NIP-50", func(t *testing.T) {
+		events, err := r.QuerySync(ctx, nostr.Filter{Kinds: []int{1}, Search: "hello"})
+		if err != nil {
+			t.Fatalf("QuerySync() error = %v", err)
+		}
+		if len(events) != 2 {
+			t.Errorf("Expected 2 search results, got %d", len(events))
+		}
+	})
+
+	t.Run("EVENT rejected", func(t *testing.T) {
+		event := nostr.Event{Kind: 1, CreatedAt: nostr.Now(), Tags: nostr.Tags{}, Content: "write"}
+		event.Sign(sk)
+		err := r.Publish(ctx, event)
+		if err == nil || !strings.Contains(err.Error(), "read-only") {
+			t.Errorf("Expected read-only rejection, got %v", err)
+		}
+		if ok, _ := st.EventExists(ctx, event.ID); ok {
+			t.Error("Expected event not to be stored")
+		}
+	})
+}
+
+func TestRelayOwnerWrites(t *testing.T) {
+	server, st, sk := setupTestRelay(t, 17778, true)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var handled []*nostr.Event
+	server.AddEventHandler(func(_ context.Context, event *nostr.Event) {
+		handled = append(handled, event)
+	})
+
+	// Others may not publish, even after authenticating
+	other := nostr.GeneratePrivateKey()
+	r, err := nostr.RelayConnect(ctx, "ws://127.0.0.1:17778")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	event := nostr.Event{Kind: 1, CreatedAt: nostr.Now(), Tags: nostr.Tags{}, Content: "spam"}
+	event.Sign(other)
+	if err := r.Publish(ctx, event); err == nil || !strings.Contains(err.Error(), "auth-required") {
+		t.Fatalf("Expected auth-required, got %v", err)
+	}
+	if err := r.Auth(ctx, func(e *nostr.Event) error { return e.Sign(other) }); err != nil {
+		t.Fatalf("Auth() error = %v", err)
+	}
+	if err := r.Publish(ctx, event); err == nil || !strings.Contains(err.Error(), "restricted") {
+		t.Errorf("Expected restricted, got %v", err)
+	}
+	r.Close()
+
+	// The owner may publish after authenticating
+	r, err = nostr.RelayConnect(ctx, "ws://127.0.0.1:17778")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer r.Close()
+
+	event = nostr.Event{Kind: 1, CreatedAt: nostr.Now(), Tags: nostr.Tags{}, Content: "from my client"}
+	event.Sign(sk)
+	if err := r.Publish(ctx, event); err == nil {
+		t.Fatal("Expected auth-required before authenticating")
+	}
+	if err := r.Auth(ctx, func(e *nostr.Event) error { return e.Sign(sk) }); err != nil {
+		t.Fatalf("Auth() error = %v", err)
+	}
+	if err := r.Publish(ctx, event); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	if ok, _ := st.EventExists(ctx, event.ID); !ok {
+		t.Error("Expected owner event to be stored")
+	}
+	if len(handled) != 1 || handled[0].ID != event.ID {
+		t.Errorf("Expected event handler to be called with the owner event, got %v", handled)
+	}
+}
+
+func TestRelayOwnerDeletion(t *testing.T) {
+	server, st, sk := setupTestRelay(t, 17779, true)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Deletion requests go through the sync engine's bookkeeping, as in main
+	pk, _ := nostr.GetPublicKey(sk)
+	npub, _ := nip19.EncodePublicKey(pk)
+	cfg := config.Default()
+	cfg.Identity.Npub = npub
+	engine := sync.NewEngine(st, cfg)
+	defer engine.Stop()
+	var removed []*nostr.Event
+	engine.AddDeletionHandler(func(_ context.Context, _ *nostr.Event, targets []*nostr.Event) {
+		removed = append(removed, targets...)
+	})
+	server.AddEventHandler(engine.HandleStored)
+
+	r, err := nostr.RelayConnect(ctx, "ws://127.0.0.1:17779")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer r.Close()
+
+	note := nostr.Event{Kind: 1, CreatedAt: nostr.Now(), Tags: nostr.Tags{}, Content: "regrettable"}
+	note.Sign(sk)
+	if err := r.Publish(ctx, note); err == nil {
+		t.Fatal("Expected auth-required before authenticating")
+	}
+	if err := r.Auth(ctx, func(e *nostr.Event) error { return e.Sign(sk) }); err != nil {
+		t.Fatalf("Auth() error = %v", err)
+	}
+	if err := r.Publish(ctx, note); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	deletion := nostr.Event{Kind: 5, CreatedAt: nostr.Now(), Tags: nostr.Tags{{"e", note.ID}}}
+	deletion.Sign(sk)
+	if err := r.Publish(ctx, deletion); err != nil {
+		t.Fatalf("Publish() deletion error = %v", err)
+	}
+
+	if ok, _ := st.EventExists(ctx, note.ID); ok {
+		t.Error("Expected the deleted note to be removed")
+	}
+	if len(removed) != 1 || removed[0].ID != note.ID {
+		t.Errorf("Expected deletion handlers to see the removed note, got %v", removed)
+	}
+	// The deletion is recorded, so a later sync cannot store the note again
+	if deleted, err := st.IsDeleted(ctx, &note); err != nil || !deleted {
+		t.Errorf("Expected the note to be recorded as deleted, got %v, %v", deleted, err)
+	}
+}
@@ -3,7 +3,6 @@ package search
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/nbd-wtf/go-nostr"
 )
@@ -93,36 +92,22 @@ func WithUntil(until nostr.Timestamp) SearchOption {
 }
 
 // ParseSearchQuery provides helper functionality for common search patterns
-// Returns appropriate search options based on query analysis
+// Returns appropriate search options based on query analysis (see ParseQuery)
 func ParseSearchQuery(query string) (searchText string, opts []SearchOption) {
-	query = strings.TrimSpace(query)
-
-	// Check for kind-specific search (e.g., "kind:1 bitcoin")
-	if strings.Contains(query, "kind:") {
-		parts := strings.Fields(query)
-		var kinds []int
-		var textParts []string
-
-		for _, part := range parts {
-			if strings.HasPrefix(part, "kind:") {
-				kindStr := strings.TrimPrefix(part, "kind:")
-				var kind int
-				fmt.Sscanf(kindStr, "%d", &kind)
-				if kind > 0 {
-					kinds = append(kinds, kind)
-				}
-			} else {
-				textParts = append(textParts, part)
-			}
-		}
-
-		if len(kinds) > 0 {
-			opts = append(opts, WithKinds(kinds...))
-		}
-		searchText = strings.Join(textParts, " ")
-	} else {
-		searchText = query
+	q := ParseQuery(query)
+
+	if len(q.Kinds) > 0 {
+		opts = append(opts, WithKinds(q.Kinds...))
+	}
+	if len(q.Authors) > 0 {
+		opts = append(opts, WithAuthors(q.Authors...))
+	}
+	if q.Since != nil {
+		opts = append(opts, WithSince(*q.Since))
+	}
+	if q.Until != nil {
+		opts = append(opts, WithUntil(*q.Until))
 	}
 
-	return searchText, opts
+	return q.Text(), opts
 }
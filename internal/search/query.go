@@ -0,0 +1,205 @@
+package search
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/nostr/helpers"
+)
+
+// Query is a parsed NIP-50 search string. Free text is split into terms and
+// "quoted phrases"; kind:, author:, since: and until: extensions become
+// filter restrictions.
+type Query struct {
+	Terms   []string
+	Phrases []string
+	Kinds   []int
+	Authors []string // hex pubkeys
+	Since   *nostr.Timestamp
+	Until   *nostr.Timestamp
+}
+
+// ignoredExtensions are NIP-50 extensions we do not support. Relays should
+// ignore unsupported extensions rather than search for them as text.
+var ignoredExtensions = map[string]bool{
+	"include":   true,
+	"domain":    true,
+	"language":  true,
+	"sentiment": true,
+	"nsfw":      true,
+}
+
+// ParseQuery parses a NIP-50 search string. since: and until: accept a unix
+// timestamp or a YYYY-MM-DD date; author: accepts npub or hex.
+func ParseQuery(raw string) Query {
+	var q Query
+
+	for _, token := range tokenize(raw) {
+		if token.phrase {
+			q.Phrases = append(q.Phrases, token.text)
+			continue
+		}
+
+		key, value, found := strings.Cut(token.text, ":")
+		if !found || value == "" {
+			q.Terms = append(q.Terms, token.text)
+			continue
+		}
+
+		switch strings.ToLower(key) {
+		case "kind":
+			if kind, err := strconv.Atoi(value); err == nil && kind >= 0 {
+				q.Kinds = append(q.Kinds, kind)
+				continue
+			}
+		case "author":
+			if pubkey, err := helpers.NormalizePubkey(value); err == nil {
+				q.Authors = append(q.Authors, pubkey)
+				continue
+			}
+		case "since":
+			if ts, ok := parseTimestamp(value); ok {
+				q.Since = &ts
+				continue
+			}
+		case "until":
+			if ts, ok := parseTimestamp(value); ok {
+				q.Until = &ts
+				continue
+			}
+		default:
+			if ignoredExtensions[strings.ToLower(key)] {
+				continue
+			}
+		}
+
+		// Not a valid extension, search for it as text
+		q.Terms = append(q.Terms, token.text)
+	}
+
+	return q
+}
+
+// HasText reports whether the query contains any terms or phrases
+func (q Query) HasText() bool {
+	return len(q.Terms) > 0 || len(q.Phrases) > 0
+}
+
+// Text returns the free-text part of the query with phrases requoted
+func (q Query) Text() string {
+	parts := make([]string, 0, len(q.Terms)+len(q.Phrases))
+	parts = append(parts, q.Terms...)
+	for _, phrase := range q.Phrases {
+		parts = append(parts, `"`+phrase+`"`)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Restrict narrows a filter by the query's extensions. Kinds and authors are
+// intersected with any the filter already has; since/until keep the tighter
+// bound. It returns false when no event can match both.
+func (q Query) Restrict(filter nostr.Filter) (nostr.Filter, bool) {
+	if len(q.Kinds) > 0 {
+		if len(filter.Kinds) > 0 {
+			filter.Kinds = intersect(filter.Kinds, q.Kinds)
+			if len(filter.Kinds) == 0 {
+				return filter, false
+			}
+		} else {
+			filter.Kinds = q.Kinds
+		}
+	}
+
+	if len(q.Authors) > 0 {
+		if len(filter.Authors) > 0 {
+			filter.Authors = intersect(filter.Authors, q.Authors)
+			if len(filter.Authors) == 0 {
+				return filter, false
+			}
+		} else {
+			filter.Authors = q.Authors
+		}
+	}
+
+	if q.Since != nil && (filter.Since == nil || *q.Since > *filter.Since) {
+		since := *q.Since
+		filter.Since = &since
+	}
+	if q.Until != nil && (filter.Until == nil || *q.Until < *filter.Until) {
+		until := *q.Until
+		filter.Until = &until
+	}
+
+	if filter.Since != nil && filter.Until != nil && *filter.Since > *filter.Until {
+		return filter, false
+	}
+
+	return filter, true
+}
+
+type token struct {
+	text   string
+	phrase bool
+}
+
+// tokenize splits on whitespace, keeping "double quoted" phrases together.
+// An unterminated quote runs to the end of the string.
+func tokenize(raw string) []token {
+	var tokens []token
+	var current strings.Builder
+	inQuote := false
+
+	flush := func(phrase bool) {
+		text := strings.TrimSpace(current.String())
+		current.Reset()
+		if text == "" {
+			return
+		}
+		if phrase && !strings.ContainsAny(text, " \t") {
+			// A quoted single word is just a term
+			phrase = false
+		}
+		tokens = append(tokens, token{text: text, phrase: phrase})
+	}
+
+	for _, r := range raw {
+		switch {
+		case r == '"':
+			flush(inQuote)
+			inQuote = !inQuote
+		case !inQuote && (r == ' ' || r == '\t' || r == '\n' || r == '\r'):
+			flush(false)
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush(inQuote)
+
+	return tokens
+}
+
+// parseTimestamp parses a unix timestamp or a YYYY-MM-DD date (UTC)
+func parseTimestamp(value string) (nostr.Timestamp, bool) {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil && n >= 0 {
+		return nostr.Timestamp(n), true
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return nostr.Timestamp(t.Unix()), true
+	}
+	return 0, false
+}
+
+func intersect[T comparable](a, b []T) []T {
+	var out []T
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				out = append(out, x)
+				break
+			}
+		}
+	}
+	return out
+}
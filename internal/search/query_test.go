@@ -0,0 +1,70 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+)
+
+func TestParseQuery(t *testing.T) {
+	hex := strings.Repeat("a", 64)
+	npub, _ := nip19.EncodePublicKey(hex)
+	ts := func(n int64) *nostr.Timestamp {
+		t := nostr.Timestamp(n)
+		return &t
+	}
+
+	tests := []struct {
+		name string
+		raw  string
+		want Query
+	}{
+		{"terms", "nostr  protocol", Query{Terms: []string{"nostr", "protocol"}}},
+		{"phrase", `bitcoin "sound money"`, Query{Terms: []string{"bitcoin"}, Phrases: []string{"sound money"}}},
+		{"quoted word is a term", `"bitcoin"`, Query{Terms: []string{"bitcoin"}}},
+		{"unterminated phrase", `"sound money`, Query{Phrases: []string{"sound money"}}},
+		{"kinds", "kind:1 kind:30023 gm", Query{Terms: []string{"gm"}, Kinds: []int{1, 30023}}},
+		{"author npub", "author:" + npub, Query{Authors: []string{hex}}},
+		{"author hex", "author:" + hex, Query{Authors: []string{hex}}},
+		{"since and until", "since:100 until:2024-01-02", Query{Since: ts(100), Until: ts(1704153600)}},
+		{"invalid extension is text", "kind:abc", Query{Terms: []string{"kind:abc"}}},
+		{"unsupported extension ignored", "gm include:spam language:en", Query{Terms: []string{"gm"}}},
+		{"other colons are text", "https://example.com", Query{Terms: []string{"https://example.com"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseQuery(tt.raw)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseQuery(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryRestrict(t *testing.T) {
+	q := ParseQuery("kind:1 kind:30023 since:200")
+
+	filter, ok := q.Restrict(nostr.Filter{Kinds: []int{30023, 0}})
+	if !ok {
+		t.Fatal("Expected overlapping kinds to match")
+	}
+	if !reflect.DeepEqual(filter.Kinds, []int{30023}) {
+		t.Errorf("Expected kinds [30023], got %v", filter.Kinds)
+	}
+	if filter.Since == nil || *filter.Since != 200 {
+		t.Errorf("Expected since 200, got %v", filter.Since)
+	}
+
+	if _, ok := q.Restrict(nostr.Filter{Kinds: []int{0}}); ok {
+		t.Error("Expected disjoint kinds to match nothing")
+	}
+
+	until := nostr.Timestamp(100)
+	if _, ok := q.Restrict(nostr.Filter{Until: &until}); ok {
+		t.Error("Expected since after until to match nothing")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/nbd-wtf/go-nostr"
@@ -30,9 +32,14 @@ const (
 	ArchiveByYear  ArchivePeriod = "year"
 )
 
-// ArchiveManager manages time-based archives
+// archiveBatchSize is how many events are fetched per query while walking a
+// section's history. Storage backends cap each query (SQLite at 100).
+const archiveBatchSize = 100
+
+// ArchiveManager manages time-based archives. Archive dates are in UTC.
 type ArchiveManager struct {
 	storage *storage.Storage
+	manager *Manager // resolves section authors and filters events, may be nil
 }
 
 // NewArchiveManager creates a new archive manager
@@ -42,55 +49,95 @@ func NewArchiveManager(st *storage.Storage) *ArchiveManager {
 	}
 }
 
-// ListArchives returns available archives for a section
-func (am *ArchiveManager) ListArchives(ctx context.Context, section *Section, period ArchivePeriod) ([]*Archive, error) {
-	// Get all events for the section
-	filter := nostr.Filter{}
-	if len(section.Filters.Kinds) > 0 {
-		filter.Kinds = section.Filters.Kinds
-	}
-	if len(section.Filters.Authors) > 0 {
-		filter.Authors = section.Filters.Authors
+// Archives returns an archive manager that resolves authors and filters
+// events the same way as section pages
+func (m *Manager) Archives() *ArchiveManager {
+	return &ArchiveManager{
+		storage: m.storage,
+		manager: m,
 	}
+}
+
+// ListArchives returns available archives for a section. Events are counted
+// while the history is walked, so only the per-period counters are kept.
+func (am *ArchiveManager) ListArchives(ctx context.Context, section *Section, period ArchivePeriod) ([]*Archive, error) {
+	return am.countArchives(ctx, section, time.Time{}, time.Time{}, period)
+}
 
-	events, err := am.storage.QueryEvents(ctx, filter)
+// PeriodArchives returns the archives of a year or month (day = 0) grouped
+// by a shorter period, such as the months of a year
+func (am *ArchiveManager) PeriodArchives(ctx context.Context, section *Section, year int, month time.Month, period ArchivePeriod) ([]*Archive, error) {
+	start, end := periodRange(year, month, 0)
+	return am.countArchives(ctx, section, start, end, period)
+}
+
+// countArchives counts the section's events in [start, end) per period
+func (am *ArchiveManager) countArchives(ctx context.Context, section *Section, start, end time.Time, period ArchivePeriod) ([]*Archive, error) {
+	counter := newArchiveCounter(period)
+	err := am.walkEvents(ctx, section, start, end, func(event *nostr.Event) {
+		counter.add(event)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to query events: %w", err)
+		return nil, err
 	}
+	return counter.archives(), nil
+}
 
-	// Group events by time period
-	archiveMap := make(map[string]*Archive)
-
+// GroupArchives groups events by time period, newest period first
+func GroupArchives(events []*nostr.Event, period ArchivePeriod) []*Archive {
+	counter := newArchiveCounter(period)
 	for _, event := range events {
-		eventTime := time.Unix(int64(event.CreatedAt), 0)
-		key := am.getPeriodKey(eventTime, period)
-
-		archive, exists := archiveMap[key]
-		if !exists {
-			archive = &Archive{
-				Period:     period,
-				Year:       eventTime.Year(),
-				Month:      eventTime.Month(),
-				Day:        eventTime.Day(),
-				EventCount: 0,
-				FirstEvent: eventTime,
-				LastEvent:  eventTime,
-			}
-			archiveMap[key] = archive
-		}
+		counter.add(event)
+	}
+	return counter.archives()
+}
 
-		archive.EventCount++
-		if eventTime.Before(archive.FirstEvent) {
-			archive.FirstEvent = eventTime
-		}
-		if eventTime.After(archive.LastEvent) {
-			archive.LastEvent = eventTime
+// archiveCounter counts events per period without keeping the events
+type archiveCounter struct {
+	period  ArchivePeriod
+	periods map[string]*Archive
+}
+
+func newArchiveCounter(period ArchivePeriod) *archiveCounter {
+	return &archiveCounter{
+		period:  period,
+		periods: make(map[string]*Archive),
+	}
+}
+
+// add counts an event in its period
+func (c *archiveCounter) add(event *nostr.Event) {
+	eventTime := time.Unix(int64(event.CreatedAt), 0).UTC()
+	key := getPeriodKey(eventTime, c.period)
+
+	archive, exists := c.periods[key]
+	if !exists {
+		archive = &Archive{
+			Period:     c.period,
+			Year:       eventTime.Year(),
+			Month:      eventTime.Month(),
+			Day:        eventTime.Day(),
+			EventCount: 0,
+			FirstEvent: eventTime,
+			LastEvent:  eventTime,
 		}
+		c.periods[key] = archive
 	}
 
+	archive.EventCount++
+	if eventTime.Before(archive.FirstEvent) {
+		archive.FirstEvent = eventTime
+	}
+	if eventTime.After(archive.LastEvent) {
+		archive.LastEvent = eventTime
+	}
+}
+
+// archives returns the counted periods, newest first
+func (c *archiveCounter) archives() []*Archive {
 	// Convert map to sorted slice
-	archives := make([]*Archive, 0, len(archiveMap))
-	for _, archive := range archiveMap {
+	archives := make([]*Archive, 0, len(c.periods))
+	for _, archive := range c.periods {
 		archives = append(archives, archive)
 	}
 
@@ -99,53 +146,25 @@ func (am *ArchiveManager) ListArchives(ctx context.Context, section *Section, pe
 		return archives[i].LastEvent.After(archives[j].LastEvent)
 	})
 
-	return archives, nil
+	return archives
 }
 
-// GetArchivePage returns events for a specific archive period
-func (am *ArchiveManager) GetArchivePage(ctx context.Context, section *Section, year int, month time.Month, day int, pageNum int) (*Page, error) {
-	// Build time range based on archive period
-	var start, end time.Time
-
-	if day > 0 {
-		// Day archive
-		start = time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
-		end = start.AddDate(0, 0, 1)
-	} else if month > 0 {
-		// Month archive
-		start = time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
-		end = start.AddDate(0, 1, 0)
-	} else {
-		// Year archive
-		start = time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
-		end = start.AddDate(1, 0, 0)
-	}
-
-	// Build filter with time range
-	filter := nostr.Filter{}
-	if len(section.Filters.Kinds) > 0 {
-		filter.Kinds = section.Filters.Kinds
-	}
-	if len(section.Filters.Authors) > 0 {
-		filter.Authors = section.Filters.Authors
-	}
-
-	since := nostr.Timestamp(start.Unix())
-	until := nostr.Timestamp(end.Unix())
-	filter.Since = &since
-	filter.Until = &until
-
-	// Query events
-	events, err := am.storage.QueryEvents(ctx, filter)
+// SectionEvents returns every event of a section, newest first. Exporters
+// use it to write every event; archive listings count with ListArchives.
+func (am *ArchiveManager) SectionEvents(ctx context.Context, section *Section) ([]*nostr.Event, error) {
+	var events []*nostr.Event
+	err := am.walkEvents(ctx, section, time.Time{}, time.Time{}, func(event *nostr.Event) {
+		events = append(events, event)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to query archive events: %w", err)
+		return nil, err
 	}
+	return events, nil
+}
 
-	// Sort events
-	sort.Slice(events, func(i, j int) bool {
-		return events[i].CreatedAt > events[j].CreatedAt
-	})
-
+// GetArchivePage returns events for a specific archive period. Only the
+// events of the requested page are kept; the rest are just counted.
+func (am *ArchiveManager) GetArchivePage(ctx context.Context, section *Section, year int, month time.Month, day int, pageNum int) (*Page, error) {
 	// Paginate
 	if pageNum < 1 {
 		pageNum = 1
@@ -157,18 +176,22 @@ func (am *ArchiveManager) GetArchivePage(ctx context.Context, section *Section,
 	}
 
 	offset := (pageNum - 1) * limit
-	totalItems := int64(len(events))
-	totalPages := int((totalItems + int64(limit) - 1) / int64(limit))
-
+	var totalItems int64
 	var pageEvents []*nostr.Event
-	if offset < len(events) {
-		endIdx := offset + limit
-		if endIdx > len(events) {
-			endIdx = len(events)
+
+	start, end := periodRange(year, month, day)
+	err := am.walkEvents(ctx, section, start, end, func(event *nostr.Event) {
+		if totalItems >= int64(offset) && len(pageEvents) < limit {
+			pageEvents = append(pageEvents, event)
 		}
-		pageEvents = events[offset:endIdx]
+		totalItems++
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	totalPages := int((totalItems + int64(limit) - 1) / int64(limit))
+
 	return &Page{
 		Section:    section,
 		Events:     pageEvents,
@@ -180,8 +203,138 @@ func (am *ArchiveManager) GetArchivePage(ctx context.Context, section *Section,
 	}, nil
 }
 
+// periodRange returns the [start, end) range of a year, month or day
+func periodRange(year int, month time.Month, day int) (time.Time, time.Time) {
+	if day > 0 {
+		start := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 0, 1)
+	}
+	if month > 0 {
+		start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(1, 0, 0)
+}
+
+// walkEvents calls fn for each of the section's events in [start, end),
+// newest first. A zero start or end leaves that side open. Storage caps every
+// query, so the history is walked backwards in batches and only one batch is
+// held at a time.
+func (am *ArchiveManager) walkEvents(ctx context.Context, section *Section, start, end time.Time, fn func(*nostr.Event)) error {
+	filter := am.buildFilter(section)
+	filter.Limit = archiveBatchSize
+
+	if !start.IsZero() {
+		since := nostr.Timestamp(start.Unix())
+		if filter.Since == nil || since > *filter.Since {
+			filter.Since = &since
+		}
+	}
+	if !end.IsZero() {
+		until := nostr.Timestamp(end.Unix() - 1) // until is inclusive
+		if filter.Until == nil || until < *filter.Until {
+			filter.Until = &until
+		}
+	}
+
+	// Until is inclusive, so consecutive batches overlap on one second;
+	// only the events of that second are remembered
+	seen := make(map[string]nostr.Timestamp)
+	for {
+		batch, err := am.storage.QueryEvents(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("failed to query archive events: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		oldest := batch[0].CreatedAt
+		var fresh []*nostr.Event
+		added := 0
+		for _, event := range batch {
+			if event.CreatedAt < oldest {
+				oldest = event.CreatedAt
+			}
+			if _, ok := seen[event.ID]; ok {
+				continue
+			}
+			seen[event.ID] = event.CreatedAt
+			fresh = append(fresh, event)
+			added++
+		}
+		for id, createdAt := range seen {
+			if createdAt > oldest {
+				delete(seen, id)
+			}
+		}
+
+		if am.manager != nil && am.manager.eventFilter != nil {
+			fresh = am.manager.eventFilter(fresh)
+		}
+		fresh = applyIsReplyFilter(fresh, section.Filters.IsReply)
+		fresh = applySensitiveFilter(fresh, section.Filters.Sensitive)
+
+		sort.SliceStable(fresh, func(i, j int) bool {
+			return fresh[i].CreatedAt > fresh[j].CreatedAt
+		})
+		for _, event := range fresh {
+			fn(event)
+		}
+
+		if len(batch) < archiveBatchSize {
+			break
+		}
+		if added == 0 {
+			// The whole batch shares one second; move past it
+			oldest--
+		}
+		if filter.Since != nil && oldest < *filter.Since {
+			break
+		}
+		filter.Until = &oldest
+	}
+
+	return nil
+}
+
+// buildFilter converts section filters to a Nostr filter without a limit
+func (am *ArchiveManager) buildFilter(section *Section) nostr.Filter {
+	filter := nostr.Filter{}
+	if len(section.Filters.Kinds) > 0 {
+		filter.Kinds = section.Filters.Kinds
+	}
+
+	authors := section.Filters.Authors
+	if am.manager != nil {
+		authors = am.manager.resolveAuthors(authors, section.Filters.Scope)
+	}
+	if len(authors) > 0 {
+		filter.Authors = authors
+	}
+
+	if section.Filters.Since != nil {
+		since := nostr.Timestamp(section.Filters.Since.Unix())
+		filter.Since = &since
+	}
+	if section.Filters.Until != nil {
+		until := nostr.Timestamp(section.Filters.Until.Unix())
+		filter.Until = &until
+	}
+
+	if len(section.Filters.Tags) > 0 {
+		filter.Tags = make(nostr.TagMap)
+		for key, values := range section.Filters.Tags {
+			filter.Tags[key] = values
+		}
+	}
+
+	return filter
+}
+
 // getPeriodKey generates a unique key for a time period
-func (am *ArchiveManager) getPeriodKey(t time.Time, period ArchivePeriod) string {
+func getPeriodKey(t time.Time, period ArchivePeriod) string {
 	switch period {
 	case ArchiveByDay:
 		return fmt.Sprintf("%04d-%02d-%02d", t.Year(), t.Month(), t.Day())
@@ -210,23 +363,129 @@ func (a *Archive) FormatTitle() string {
 
 // FormatArchiveSelector formats an archive selector for navigation
 func (a *Archive) FormatArchiveSelector(sectionName string) string {
+	base := ArchiveSelector(sectionName)
 	switch a.Period {
 	case ArchiveByDay:
-		return fmt.Sprintf("/archive/%s/%04d/%02d/%02d", sectionName, a.Year, a.Month, a.Day)
+		return fmt.Sprintf("%s/%04d/%02d/%02d", base, a.Year, a.Month, a.Day)
 	case ArchiveByMonth:
-		return fmt.Sprintf("/archive/%s/%04d/%02d", sectionName, a.Year, a.Month)
+		return fmt.Sprintf("%s/%04d/%02d", base, a.Year, a.Month)
 	case ArchiveByYear:
-		return fmt.Sprintf("/archive/%s/%04d", sectionName, a.Year)
+		return fmt.Sprintf("%s/%04d", base, a.Year)
+	default:
+		return fmt.Sprintf("%s/%04d/%02d/%02d", base, a.Year, a.Month, a.Day)
+	}
+}
+
+// ArchiveSelector returns the selector of a section's archive index
+func ArchiveSelector(sectionName string) string {
+	return "/" + sectionName + "/archive"
+}
+
+// ArchivePath is a parsed /<section>/archive[/YYYY[/MM[/DD]]][/page/N] route
+type ArchivePath struct {
+	Section string
+	Year    int        // 0 = archive index
+	Month   time.Month // 0 = whole year
+	Day     int        // 0 = whole month
+	Page    int
+}
+
+// IsArchivePath reports whether a path is a section archive route
+func IsArchivePath(path string) bool {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	return len(parts) >= 2 && parts[0] != "" && parts[1] == "archive"
+}
+
+// ParseArchivePath parses a section archive route
+func ParseArchivePath(path string) (*ArchivePath, error) {
+	if !IsArchivePath(path) {
+		return nil, fmt.Errorf("not an archive path: %s", path)
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	ap := &ArchivePath{Section: parts[0], Page: 1}
+	parts = parts[2:]
+
+	if n := len(parts); n >= 2 && parts[n-2] == "page" {
+		page, err := strconv.Atoi(parts[n-1])
+		if err != nil || page < 1 {
+			return nil, fmt.Errorf("invalid page: %s", parts[n-1])
+		}
+		ap.Page = page
+		parts = parts[:n-2]
+	}
+
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid archive path: %s", path)
+	}
+
+	if len(parts) >= 1 {
+		year, err := strconv.Atoi(parts[0])
+		if err != nil || year < 1 || year > 9999 {
+			return nil, fmt.Errorf("invalid year: %s", parts[0])
+		}
+		ap.Year = year
+	}
+	if len(parts) >= 2 {
+		month, err := strconv.Atoi(parts[1])
+		if err != nil || month < 1 || month > 12 {
+			return nil, fmt.Errorf("invalid month: %s", parts[1])
+		}
+		ap.Month = time.Month(month)
+	}
+	if len(parts) == 3 {
+		day, err := strconv.Atoi(parts[2])
+		_, end := periodRange(ap.Year, ap.Month, 0)
+		if err != nil || day < 1 || day > end.AddDate(0, 0, -1).Day() {
+			return nil, fmt.Errorf("invalid day: %s", parts[2])
+		}
+		ap.Day = day
+	}
+
+	return ap, nil
+}
+
+// Archive returns the archive the path points to, or nil for the index
+func (ap *ArchivePath) Archive() *Archive {
+	switch {
+	case ap.Day > 0:
+		return &Archive{Period: ArchiveByDay, Year: ap.Year, Month: ap.Month, Day: ap.Day}
+	case ap.Month > 0:
+		return &Archive{Period: ArchiveByMonth, Year: ap.Year, Month: ap.Month}
+	case ap.Year > 0:
+		return &Archive{Period: ArchiveByYear, Year: ap.Year}
 	default:
-		return fmt.Sprintf("/archive/%s/%04d/%02d/%02d", sectionName, a.Year, a.Month, a.Day)
+		return nil
+	}
+}
+
+// Selector returns the path's selector without the page
+func (ap *ArchivePath) Selector() string {
+	if archive := ap.Archive(); archive != nil {
+		return archive.FormatArchiveSelector(ap.Section)
 	}
+	return ArchiveSelector(ap.Section)
+}
+
+// Parent returns the selector one level up (day → month → year → index)
+func (ap *ArchivePath) Parent() string {
+	parent := *ap
+	switch {
+	case ap.Day > 0:
+		parent.Day = 0
+	case ap.Month > 0:
+		parent.Month = 0
+	default:
+		parent.Year = 0
+	}
+	return parent.Selector()
 }
 
 // MonthlyArchiveCalendar generates a calendar view of monthly archives
 type MonthlyArchiveCalendar struct {
-	Year   int
-	Month  time.Month
-	Days   []*DayArchive
+	Year  int
+	Month time.Month
+	Days  []*DayArchive
 }
 
 // DayArchive represents a single day in the calendar
@@ -238,38 +497,37 @@ type DayArchive struct {
 
 // GenerateMonthlyCalendar generates a monthly archive calendar
 func (am *ArchiveManager) GenerateMonthlyCalendar(ctx context.Context, section *Section, year int, month time.Month) (*MonthlyArchiveCalendar, error) {
-	// Get month range
-	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
-	end := start.AddDate(0, 1, 0)
-
-	// Build filter
-	filter := nostr.Filter{}
-	if len(section.Filters.Kinds) > 0 {
-		filter.Kinds = section.Filters.Kinds
-	}
-	if len(section.Filters.Authors) > 0 {
-		filter.Authors = section.Filters.Authors
-	}
-
-	since := nostr.Timestamp(start.Unix())
-	until := nostr.Timestamp(end.Unix())
-	filter.Since = &since
-	filter.Until = &until
-
-	// Query events
-	events, err := am.storage.QueryEvents(ctx, filter)
+	dayCounts := make(map[int]int64)
+	start, end := periodRange(year, month, 0)
+	err := am.walkEvents(ctx, section, start, end, func(event *nostr.Event) {
+		dayCounts[time.Unix(int64(event.CreatedAt), 0).UTC().Day()]++
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to query events for calendar: %w", err)
 	}
 
+	return newMonthlyCalendar(year, month, dayCounts), nil
+}
+
+// NewMonthlyCalendar counts events per day of a month. Events outside the
+// month are ignored.
+func NewMonthlyCalendar(year int, month time.Month, events []*nostr.Event) *MonthlyArchiveCalendar {
 	// Count events by day
 	dayCounts := make(map[int]int64)
 	for _, event := range events {
-		eventTime := time.Unix(int64(event.CreatedAt), 0)
-		dayCounts[eventTime.Day()]++
+		eventTime := time.Unix(int64(event.CreatedAt), 0).UTC()
+		if eventTime.Year() == year && eventTime.Month() == month {
+			dayCounts[eventTime.Day()]++
+		}
 	}
 
+	return newMonthlyCalendar(year, month, dayCounts)
+}
+
+// newMonthlyCalendar builds a calendar from per-day event counts
+func newMonthlyCalendar(year int, month time.Month, dayCounts map[int]int64) *MonthlyArchiveCalendar {
 	// Generate day list
+	_, end := periodRange(year, month, 0)
 	daysInMonth := end.AddDate(0, 0, -1).Day()
 	days := make([]*DayArchive, daysInMonth)
 
@@ -286,5 +544,58 @@ func (am *ArchiveManager) GenerateMonthlyCalendar(ctx context.Context, section *
 		Year:  year,
 		Month: month,
 		Days:  days,
-	}, nil
+	}
+}
+
+// Archives returns the days that have events as day archives, newest first
+func (c *MonthlyArchiveCalendar) Archives() []*Archive {
+	var archives []*Archive
+	for i := len(c.Days) - 1; i >= 0; i-- {
+		day := c.Days[i]
+		if !day.HasEvents {
+			continue
+		}
+		archives = append(archives, &Archive{
+			Period:     ArchiveByDay,
+			Year:       c.Year,
+			Month:      c.Month,
+			Day:        day.Day,
+			EventCount: day.EventCount,
+		})
+	}
+	return archives
+}
+
+// Grid renders the calendar as fixed-width text lines, weeks starting on
+// Sunday. Days with events are marked with an asterisk.
+func (c *MonthlyArchiveCalendar) Grid() []string {
+	const width = 26 // 7 columns of 4 characters, less the trailing spaces
+
+	title := fmt.Sprintf("%s %d", c.Month, c.Year)
+	lines := []string{
+		strings.Repeat(" ", (width-len(title))/2) + title,
+		"Su  Mo  Tu  We  Th  Fr  Sa",
+	}
+
+	offset := int(time.Date(c.Year, c.Month, 1, 0, 0, 0, 0, time.UTC).Weekday())
+
+	var week strings.Builder
+	week.WriteString(strings.Repeat("    ", offset))
+	for i, day := range c.Days {
+		marker := " "
+		if day.HasEvents {
+			marker = "*"
+		}
+		week.WriteString(fmt.Sprintf("%2d%s ", day.Day, marker))
+
+		if (offset+i+1)%7 == 0 {
+			lines = append(lines, strings.TrimRight(week.String(), " "))
+			week.Reset()
+		}
+	}
+	if week.Len() > 0 {
+		lines = append(lines, strings.TrimRight(week.String(), " "))
+	}
+
+	return lines
 }
@@ -0,0 +1,167 @@
+package sections
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+func TestArchiveManagerWalksFullHistory(t *testing.T) {
+	ctx := context.Background()
+	st, err := storage.New(ctx, &config.Storage{
+		Driver:     "sqlite",
+		SQLitePath: filepath.Join(t.TempDir(), "test.db"),
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer st.Close()
+
+	sk := nostr.GeneratePrivateKey()
+	pk, _ := nostr.GetPublicKey(sk)
+
+	// 150 notes in March 2024 (more than one storage query returns),
+	// 3 in January 2023 and one reply that the section excludes
+	store := func(createdAt time.Time, tags nostr.Tags) {
+		event := &nostr.Event{Kind: 1, CreatedAt: nostr.Timestamp(createdAt.Unix()), Tags: tags, Content: "note"}
+		if err := event.Sign(sk); err != nil {
+			t.Fatalf("failed to sign event: %v", err)
+		}
+		if err := st.StoreEvent(ctx, event); err != nil {
+			t.Fatalf("failed to store event: %v", err)
+		}
+	}
+	march := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 150; i++ {
+		store(march.Add(time.Duration(i)*time.Hour), nostr.Tags{})
+	}
+	for i := 0; i < 3; i++ {
+		store(time.Date(2023, time.January, 31, 23, 59, 57+i, 0, time.UTC), nostr.Tags{})
+	}
+	store(march, nostr.Tags{{"e", strings.Repeat("a", 64), "", "reply"}})
+
+	isReply := false
+	section := &Section{
+		Name:    "notes",
+		Limit:   20,
+		Filters: FilterSet{Kinds: []int{1}, Authors: []string{pk}, IsReply: &isReply},
+	}
+	am := NewArchiveManager(st)
+
+	years, err := am.ListArchives(ctx, section, ArchiveByYear)
+	if err != nil {
+		t.Fatalf("ListArchives failed: %v", err)
+	}
+	if len(years) != 2 || years[0].Year != 2024 || years[0].EventCount != 150 || years[1].Year != 2023 || years[1].EventCount != 3 {
+		t.Fatalf("unexpected year archives: %+v %+v", years[0], years[len(years)-1])
+	}
+
+	months, err := am.PeriodArchives(ctx, section, 2024, 0, ArchiveByMonth)
+	if err != nil {
+		t.Fatalf("PeriodArchives failed: %v", err)
+	}
+	if len(months) != 1 || months[0].Month != time.March || months[0].EventCount != 150 {
+		t.Fatalf("unexpected month archives for 2024: %+v", months)
+	}
+
+	// The last second of January belongs to January, not February
+	page, err := am.GetArchivePage(ctx, section, 2023, time.January, 31, 1)
+	if err != nil {
+		t.Fatalf("GetArchivePage failed: %v", err)
+	}
+	if page.TotalItems != 3 {
+		t.Errorf("expected 3 events on 2023-01-31, got %d", page.TotalItems)
+	}
+
+	page, err = am.GetArchivePage(ctx, section, 2024, time.March, 0, 8)
+	if err != nil {
+		t.Fatalf("GetArchivePage failed: %v", err)
+	}
+	if page.TotalItems != 150 || page.TotalPages != 8 || len(page.Events) != 10 || page.HasNext {
+		t.Errorf("unexpected last page: items=%d pages=%d events=%d", page.TotalItems, page.TotalPages, len(page.Events))
+	}
+	if len(page.Events) > 0 && !page.Events[len(page.Events)-1].CreatedAt.Time().Equal(march) {
+		t.Errorf("expected the last page to end with the oldest note, got %v", page.Events[len(page.Events)-1].CreatedAt.Time())
+	}
+
+	calendar, err := am.GenerateMonthlyCalendar(ctx, section, 2024, time.March)
+	if err != nil {
+		t.Fatalf("GenerateMonthlyCalendar failed: %v", err)
+	}
+	if len(calendar.Days) != 31 || calendar.Days[0].EventCount != 12 || calendar.Days[7].HasEvents {
+		t.Errorf("unexpected calendar days: first=%d eighth=%v", calendar.Days[0].EventCount, calendar.Days[7].HasEvents)
+	}
+	days := calendar.Archives()
+	if len(days) != 7 || days[0].Day != 7 || days[0].FormatArchiveSelector("notes") != "/notes/archive/2024/03/07" {
+		t.Errorf("unexpected day archives: %d", len(days))
+	}
+}
+
+func TestParseArchivePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		want     ArchivePath
+		selector string
+		parent   string
+	}{
+		{"/notes/archive", ArchivePath{Section: "notes", Page: 1}, "/notes/archive", "/notes/archive"},
+		{"/notes/archive/2025", ArchivePath{Section: "notes", Year: 2025, Page: 1}, "/notes/archive/2025", "/notes/archive"},
+		{"/notes/archive/2025/10", ArchivePath{Section: "notes", Year: 2025, Month: 10, Page: 1}, "/notes/archive/2025/10", "/notes/archive/2025"},
+		{"/notes/archive/2025/10/24/page/3", ArchivePath{Section: "notes", Year: 2025, Month: 10, Day: 24, Page: 3}, "/notes/archive/2025/10/24", "/notes/archive/2025/10"},
+	}
+
+	for _, tt := range tests {
+		ap, err := ParseArchivePath(tt.path)
+		if err != nil {
+			t.Errorf("ParseArchivePath(%q) failed: %v", tt.path, err)
+			continue
+		}
+		if *ap != tt.want {
+			t.Errorf("ParseArchivePath(%q) = %+v, want %+v", tt.path, *ap, tt.want)
+		}
+		if got := ap.Selector(); got != tt.selector {
+			t.Errorf("Selector() = %s, want %s", got, tt.selector)
+		}
+		if got := ap.Parent(); got != tt.parent {
+			t.Errorf("Parent() = %s, want %s", got, tt.parent)
+		}
+	}
+
+	for _, path := range []string{"/notes/archive/20x5", "/notes/archive/2025/13", "/notes/archive/2025/02/30", "/notes/archive/2025/10/24/1", "/notes/archive/page/0"} {
+		if _, err := ParseArchivePath(path); err == nil {
+			t.Errorf("expected ParseArchivePath(%q) to fail", path)
+		}
+	}
+
+	if IsArchivePath("/notes") || IsArchivePath("/archive") || !IsArchivePath("/notes/archive/") {
+		t.Error("unexpected IsArchivePath result")
+	}
+}
+
+func TestMonthlyCalendarGrid(t *testing.T) {
+	events := []*nostr.Event{
+		{CreatedAt: nostr.Timestamp(time.Date(2025, time.October, 1, 8, 0, 0, 0, time.UTC).Unix())},
+		{CreatedAt: nostr.Timestamp(time.Date(2025, time.October, 24, 8, 0, 0, 0, time.UTC).Unix())},
+		{CreatedAt: nostr.Timestamp(time.Date(2025, time.November, 1, 8, 0, 0, 0, time.UTC).Unix())},
+	}
+
+	got := strings.Join(NewMonthlyCalendar(2025, time.October, events).Grid(), "\n")
+	want := strings.Join([]string{
+		"       October 2025",
+		"Su  Mo  Tu  We  Th  Fr  Sa",
+		"             1*  2   3   4",
+		" 5   6   7   8   9  10  11",
+		"12  13  14  15  16  17  18",
+		"19  20  21  22  23  24* 25",
+		"26  27  28  29  30  31",
+	}, "\n")
+	if got != want {
+		t.Errorf("unexpected grid:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package sections
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// EventGroup is a heading and the events of a page that fall under it
+type EventGroup struct {
+	Key       string // day/week/month/year key, author pubkey or kind number
+	Title     string // heading; author groups are titled by the renderer
+	Events    []*nostr.Event
+	Continued bool // the group already started on an earlier page
+}
+
+// GroupEvents groups events by a section's group_by field. Groups are
+// ordered by their first event and keep the events' sort order, so time
+// groups follow the section's sort. Dates are in UTC, like the archives.
+func GroupEvents(events []*nostr.Event, field GroupField) []*EventGroup {
+	if field == GroupNone {
+		return nil
+	}
+
+	var groups []*EventGroup
+	byKey := make(map[string]*EventGroup)
+	for _, event := range events {
+		key := GroupKey(event, field)
+		group, ok := byKey[key]
+		if !ok {
+			group = &EventGroup{Key: key, Title: groupTitle(event, field)}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		group.Events = append(group.Events, event)
+	}
+
+	return groups
+}
+
+// GroupKey returns the key of the group an event belongs to
+func GroupKey(event *nostr.Event, field GroupField) string {
+	t := event.CreatedAt.Time().UTC()
+	switch field {
+	case GroupByDay:
+		return t.Format("2006-01-02")
+	case GroupByWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	case GroupByMonth:
+		return t.Format("2006-01")
+	case GroupByYear:
+		return t.Format("2006")
+	case GroupByAuthor:
+		return event.PubKey
+	case GroupByKind:
+		return strconv.Itoa(event.Kind)
+	default:
+		return ""
+	}
+}
+
+// groupTitle returns the heading of an event's group
+func groupTitle(event *nostr.Event, field GroupField) string {
+	t := event.CreatedAt.Time().UTC()
+	switch field {
+	case GroupByDay:
+		return newArchive(t, ArchiveByDay).FormatTitle()
+	case GroupByWeek:
+		monday := t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+		return "Week of " + newArchive(monday, ArchiveByDay).FormatTitle()
+	case GroupByMonth:
+		return newArchive(t, ArchiveByMonth).FormatTitle()
+	case GroupByYear:
+		return newArchive(t, ArchiveByYear).FormatTitle()
+	case GroupByKind:
+		return KindName(event.Kind)
+	default:
+		return ""
+	}
+}
+
+// newArchive returns the archive of a period containing t
+func newArchive(t time.Time, period ArchivePeriod) *Archive {
+	return &Archive{Period: period, Year: t.Year(), Month: t.Month(), Day: t.Day()}
+}
+
+// KindName returns a human name for an event kind
+func KindName(kind int) string {
+	switch kind {
+	case 0:
+		return "Profiles"
+	case 1:
+		return "Notes"
+	case 3:
+		return "Contacts"
+	case 6, 16:
+		return "Reposts"
+	case 7:
+		return "Reactions"
+	case 1063:
+		return "Files"
+	case 9735:
+		return "Zaps"
+	case 10002:
+		return "Relay lists"
+	case 30023:
+		return "Articles"
+	case 30024:
+		return "Draft articles"
+	default:
+		return fmt.Sprintf("Kind %d", kind)
+	}
+}
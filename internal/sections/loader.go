@@ -24,8 +24,26 @@ func LoadFromConfig(manager *Manager, sectionConfigs []config.SectionConfig) err
 	return nil
 }
 
+// FromConfig converts config.SectionConfig entries to Section instances
+// without registering them
+func FromConfig(sectionConfigs []config.SectionConfig) ([]*Section, error) {
+	list := make([]*Section, 0, len(sectionConfigs))
+	for _, cfg := range sectionConfigs {
+		section, err := convertConfigToSection(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert section %s: %w", cfg.Name, err)
+		}
+		list = append(list, section)
+	}
+	return list, nil
+}
+
 // convertConfigToSection converts a config.SectionConfig to a Section
 func convertConfigToSection(cfg config.SectionConfig) (*Section, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("section name is required")
+	}
+
 	section := &Section{
 		Name:        cfg.Name,
 		Path:        cfg.Path,
@@ -34,6 +52,7 @@ func convertConfigToSection(cfg config.SectionConfig) (*Section, error) {
 		ShowDates:   cfg.ShowDates,
 		ShowAuthors: cfg.ShowAuthors,
 		Order:       cfg.Order,
+		Hidden:      cfg.Hidden,
 	}
 
 	// Set limit (default to 20 if not specified)
@@ -99,6 +118,11 @@ func convertFilterConfig(cfg config.SectionFilterConfig) (FilterSet, error) {
 		filterSet.IsReply = cfg.IsReply
 	}
 
+	// Convert content warning flag
+	if cfg.Sensitive != nil {
+		filterSet.Sensitive = cfg.Sensitive
+	}
+
 	// Parse time ranges
 	if cfg.Since != "" {
 		sinceTime, err := parseTimeOrDuration(cfg.Since)
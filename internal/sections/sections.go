@@ -3,12 +3,15 @@ package sections
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip19"
 	"github.com/sandwichfarm/nophr/internal/aggregates"
+	nostrclient "github.com/sandwichfarm/nophr/internal/nostr"
 	"github.com/sandwichfarm/nophr/internal/storage"
 )
 
@@ -27,6 +30,7 @@ type Section struct {
 	GroupBy     GroupField
 	MoreLink    *MoreLink // Optional link to full paginated view
 	Order       int       // Display order when multiple sections share a path (lower numbers first)
+	Hidden      bool      // Only served to the owner; skipped by public lookups
 }
 
 // MoreLink defines a "more" link to a full paginated section view
@@ -37,14 +41,15 @@ type MoreLink struct {
 
 // FilterSet contains multiple filter criteria
 type FilterSet struct {
-	Kinds   []int
-	Authors []string
-	Tags    map[string][]string
-	Since   *time.Time
-	Until   *time.Time
-	Search  string
-	Scope   Scope
-	IsReply *bool
+	Kinds     []int
+	Authors   []string
+	Tags      map[string][]string
+	Since     *time.Time
+	Until     *time.Time
+	Search    string
+	Scope     Scope
+	IsReply   *bool
+	Sensitive *bool // true = only events with a NIP-36 content warning, false = none
 }
 
 // SortField defines how to sort events
@@ -99,13 +104,16 @@ type Page struct {
 	TotalItems int64
 	HasNext    bool
 	HasPrev    bool
+	Groups     []*EventGroup // Events grouped by the section's GroupBy, if set
 }
 
 // Manager manages sections and their content
 type Manager struct {
 	storage     *storage.Storage
+	mu          sync.RWMutex
 	sections    map[string]*Section
 	ownerPubkey string // canonical hex pubkey for owner (default author scope)
+	eventFilter func([]*nostr.Event) []*nostr.Event
 }
 
 // NewManager creates a new section manager
@@ -117,6 +125,12 @@ func NewManager(st *storage.Storage, ownerPubkey string) *Manager {
 	}
 }
 
+// SetEventFilter installs a filter applied to every page's events before
+// rendering (e.g. the security deny list)
+func (m *Manager) SetEventFilter(filter func([]*nostr.Event) []*nostr.Event) {
+	m.eventFilter = filter
+}
+
 // RegisterSection registers a section definition
 func (m *Manager) RegisterSection(section *Section) error {
 	if section.Name == "" {
@@ -127,14 +141,49 @@ func (m *Manager) RegisterSection(section *Section) error {
 		section.Limit = 20 // Default limit
 	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.sections[section.Name] = section
 	return nil
 }
 
-// GetSection retrieves a section by name
+// ReplaceSections swaps every registered section for the given definitions
+// in one step, so concurrent requests see either the old set or the new one.
+// The list is expected to come from FromConfig, which rejects unnamed
+// sections, so the swap itself cannot fail.
+func (m *Manager) ReplaceSections(list []*Section) {
+	replacement := make(map[string]*Section, len(list))
+	for _, section := range list {
+		if section.Limit == 0 {
+			section.Limit = 20 // Default limit
+		}
+		replacement[section.Name] = section
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sections = replacement
+}
+
+// GetSection retrieves a public section by name
 func (m *Manager) GetSection(name string) (*Section, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	section, exists := m.sections[name]
-	if !exists {
+	if !exists || section.Hidden {
+		return nil, fmt.Errorf("section not found: %s", name)
+	}
+	return section, nil
+}
+
+// GetHiddenSection retrieves a hidden (owner-only) section by name
+func (m *Manager) GetHiddenSection(name string) (*Section, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	section, exists := m.sections[name]
+	if !exists || !section.Hidden {
 		return nil, fmt.Errorf("section not found: %s", name)
 	}
 	return section, nil
@@ -142,22 +191,27 @@ func (m *Manager) GetSection(name string) (*Section, error) {
 
 // GetSectionByPath retrieves a section by its URL path (deprecated - use GetSectionsByPath for multiple sections)
 func (m *Manager) GetSectionByPath(path string) (*Section, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	for _, section := range m.sections {
-		if section.Path == path {
+		if section.Path == path && !section.Hidden {
 			return section, nil
 		}
 	}
 	return nil, fmt.Errorf("no section registered for path: %s", path)
 }
 
-// GetSectionsByPath retrieves all sections for a given path, sorted by Order field
+// GetSectionsByPath retrieves all public sections for a given path, sorted by Order field
 func (m *Manager) GetSectionsByPath(path string) []*Section {
 	var matched []*Section
+	m.mu.RLock()
 	for _, section := range m.sections {
-		if section.Path == path {
+		if section.Path == path && !section.Hidden {
 			matched = append(matched, section)
 		}
 	}
+	m.mu.RUnlock()
 
 	// Sort by Order field (lower numbers first)
 	for i := 0; i < len(matched)-1; i++ {
@@ -173,6 +227,9 @@ func (m *Manager) GetSectionsByPath(path string) []*Section {
 
 // ListSections returns all registered sections
 func (m *Manager) ListSections() []*Section {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	sections := make([]*Section, 0, len(m.sections))
 	for _, section := range m.sections {
 		sections = append(sections, section)
@@ -180,11 +237,46 @@ func (m *Manager) ListSections() []*Section {
 	return sections
 }
 
-// GetPage retrieves a page of events for a section
+// PublicSections returns the sections that are not hidden, sorted by Order,
+// then name
+func (m *Manager) PublicSections() []*Section {
+	return m.sectionsByHidden(false)
+}
+
+// HiddenSections returns the hidden sections sorted by Order, then name
+func (m *Manager) HiddenSections() []*Section {
+	return m.sectionsByHidden(true)
+}
+
+func (m *Manager) sectionsByHidden(hidden bool) []*Section {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var matched []*Section
+	for _, section := range m.sections {
+		if section.Hidden == hidden {
+			matched = append(matched, section)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].Order != matched[j].Order {
+			return matched[i].Order < matched[j].Order
+		}
+		return matched[i].Name < matched[j].Name
+	})
+
+	return matched
+}
+
+// GetPage retrieves a page of events for a section. Callers resolve the
+// section first, so hidden sections are served only where they were looked up.
 func (m *Manager) GetPage(ctx context.Context, sectionName string, pageNum int) (*Page, error) {
-	section, err := m.GetSection(sectionName)
-	if err != nil {
-		return nil, err
+	m.mu.RLock()
+	section, exists := m.sections[sectionName]
+	m.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("section not found: %s", sectionName)
 	}
 
 	if pageNum < 1 {
@@ -194,15 +286,14 @@ func (m *Manager) GetPage(ctx context.Context, sectionName string, pageNum int)
 	// Build Nostr filter from section filters
 	filter := m.buildFilter(section, pageNum)
 
-	// Query events
-	events, err := m.storage.QueryEvents(ctx, filter)
+	// Query events, filtering before the limit so pages are not cut short
+	events, err := m.storage.QueryEventsFiltered(ctx, filter, func(events []*nostr.Event) []*nostr.Event {
+		return m.filterEvents(section, events)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to query events: %w", err)
 	}
 
-	// Apply reply/root filtering if configured
-	events = m.applyIsReplyFilter(events, section.Filters.IsReply)
-
 	// Sort events
 	m.sortEvents(events, section.SortBy, section.SortOrder)
 
@@ -229,16 +320,62 @@ func (m *Manager) GetPage(ctx context.Context, sectionName string, pageNum int)
 		TotalItems: totalItems,
 		HasNext:    pageNum < totalPages,
 		HasPrev:    pageNum > 1,
+		Groups:     groupPage(events, offset, pageEvents, section.GroupBy),
 	}, nil
 }
 
+// groupPage groups a page's events, marking groups that began on an
+// earlier page so renderers can show them as continued
+func groupPage(events []*nostr.Event, offset int, pageEvents []*nostr.Event, field GroupField) []*EventGroup {
+	groups := GroupEvents(pageEvents, field)
+	if len(groups) == 0 || offset == 0 {
+		return groups
+	}
+
+	earlier := make(map[string]bool)
+	for _, event := range events[:min(offset, len(events))] {
+		earlier[GroupKey(event, field)] = true
+	}
+	for _, group := range groups {
+		group.Continued = earlier[group.Key]
+	}
+
+	return groups
+}
+
+// Search runs a full-text search restricted to a section's filters
+func (m *Manager) Search(ctx context.Context, sectionName, query string, limit int) ([]*nostr.Event, error) {
+	section, err := m.GetSection(sectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := m.buildFilter(section, 1)
+	filter.Search = query
+	filter.Limit = limit
+
+	events, err := m.storage.QueryEventsWithSearch(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search events: %w", err)
+	}
+
+	return m.filterEvents(section, events), nil
+}
+
+// filterEvents applies the event filter and the section's reply/root and
+// content warning filters
+func (m *Manager) filterEvents(section *Section, events []*nostr.Event) []*nostr.Event {
+	if m.eventFilter != nil {
+		events = m.eventFilter(events)
+	}
+
+	events = applyIsReplyFilter(events, section.Filters.IsReply)
+	return applySensitiveFilter(events, section.Filters.Sensitive)
+}
+
 // buildFilter converts section filters to Nostr filter
 func (m *Manager) buildFilter(section *Section, pageNum int) nostr.Filter {
-	limit := section.Limit * pageNum // Get all up to this page
-	// When post-filtering for replies/roots, fetch extra to avoid empty pages
-	if section.Filters.IsReply != nil {
-		limit = section.Limit * (pageNum + 2)
-	}
+	limit := section.Limit * (pageNum + 1) // Everything up to this page, plus one page to detect a next one
 
 	filter := nostr.Filter{
 		Limit: limit,
@@ -401,7 +538,7 @@ func (m *Manager) resolveAuthors(authors []string, scope Scope) []string {
 	return nil
 }
 
-func (m *Manager) applyIsReplyFilter(events []*nostr.Event, isReply *bool) []*nostr.Event {
+func applyIsReplyFilter(events []*nostr.Event, isReply *bool) []*nostr.Event {
 	if isReply == nil {
 		return events
 	}
@@ -425,6 +562,21 @@ func (m *Manager) applyIsReplyFilter(events []*nostr.Event, isReply *bool) []*no
 	return filtered
 }
 
+func applySensitiveFilter(events []*nostr.Event, sensitive *bool) []*nostr.Event {
+	if sensitive == nil {
+		return events
+	}
+
+	filtered := make([]*nostr.Event, 0, len(events))
+	for _, event := range events {
+		if nostrclient.IsSensitive(event) == *sensitive {
+			filtered = append(filtered, event)
+		}
+	}
+
+	return filtered
+}
+
 // normalizeAuthorValue converts npub → hex and owner/self aliases to the owner pubkey
 func normalizeAuthorValue(author string, ownerPubkey string) string {
 	if author == "" {
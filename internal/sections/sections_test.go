@@ -4,7 +4,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/sandwichfarm/nophr/internal/config"
 )
 
 func TestDefaultSections(t *testing.T) {
@@ -87,6 +89,31 @@ func TestSectionManager(t *testing.T) {
 		}
 	})
 
+	t.Run("Hidden sections", func(t *testing.T) {
+		manager.RegisterSection(&Section{Name: "private", Path: "/private", Hidden: true})
+
+		if _, err := manager.GetSection("private"); err == nil {
+			t.Error("expected hidden section to be skipped by GetSection")
+		}
+		if sections := manager.GetSectionsByPath("/private"); len(sections) != 0 {
+			t.Errorf("expected no public sections at hidden path, got %d", len(sections))
+		}
+		if _, err := manager.GetHiddenSection("private"); err != nil {
+			t.Errorf("failed to get hidden section: %v", err)
+		}
+		if _, err := manager.GetHiddenSection("test-section"); err == nil {
+			t.Error("expected public section to be skipped by GetHiddenSection")
+		}
+		if hidden := manager.HiddenSections(); len(hidden) != 1 || hidden[0].Name != "private" {
+			t.Errorf("expected one hidden section, got %v", hidden)
+		}
+		for _, section := range manager.PublicSections() {
+			if section.Hidden {
+				t.Errorf("expected PublicSections to skip hidden section %s", section.Name)
+			}
+		}
+	})
+
 	t.Run("Default limit", func(t *testing.T) {
 		section := &Section{
 			Name:  "no-limit",
@@ -102,6 +129,44 @@ func TestSectionManager(t *testing.T) {
 	})
 }
 
+func TestReplaceSections(t *testing.T) {
+	manager := NewManager(nil, "ownerhex")
+	if err := LoadFromConfig(manager, []config.SectionConfig{
+		{Name: "old", Path: "/old"},
+		{Name: "kept", Path: "/kept", Limit: 5},
+	}); err != nil {
+		t.Fatalf("failed to load sections: %v", err)
+	}
+
+	if _, err := FromConfig([]config.SectionConfig{
+		{Name: "kept", Path: "/kept", Limit: 10},
+		{Name: "broken", Filters: config.SectionFilterConfig{Since: "yesterday"}},
+	}); err == nil {
+		t.Fatal("expected an invalid section to fail conversion")
+	}
+	if _, err := FromConfig([]config.SectionConfig{{Path: "/unnamed"}}); err == nil {
+		t.Fatal("expected an unnamed section to fail conversion")
+	}
+
+	list, err := FromConfig([]config.SectionConfig{
+		{Name: "kept", Path: "/kept", Limit: 10},
+		{Name: "new", Path: "/new"},
+	})
+	if err != nil {
+		t.Fatalf("failed to convert sections: %v", err)
+	}
+	manager.ReplaceSections(list)
+	if _, err := manager.GetSection("old"); err == nil {
+		t.Error("sections missing from the new config should be dropped")
+	}
+	if kept, err := manager.GetSection("kept"); err != nil || kept.Limit != 10 {
+		t.Errorf("expected the kept section to be updated, got %+v, %v", kept, err)
+	}
+	if sections := manager.GetSectionsByPath("/new"); len(sections) != 1 {
+		t.Errorf("expected the new section at /new, got %d", len(sections))
+	}
+}
+
 func TestArchiveFormatting(t *testing.T) {
 	t.Run("Day archive", func(t *testing.T) {
 		archive := &Archive{
@@ -117,7 +182,7 @@ func TestArchiveFormatting(t *testing.T) {
 		}
 
 		selector := archive.FormatArchiveSelector("notes")
-		expected := "/archive/notes/2025/10/24"
+		expected := "/notes/archive/2025/10/24"
 		if selector != expected {
 			t.Errorf("expected selector %s, got %s", expected, selector)
 		}
@@ -136,7 +201,7 @@ func TestArchiveFormatting(t *testing.T) {
 		}
 
 		selector := archive.FormatArchiveSelector("notes")
-		expected := "/archive/notes/2025/10"
+		expected := "/notes/archive/2025/10"
 		if selector != expected {
 			t.Errorf("expected selector %s, got %s", expected, selector)
 		}
@@ -154,7 +219,7 @@ func TestArchiveFormatting(t *testing.T) {
 		}
 
 		selector := archive.FormatArchiveSelector("notes")
-		expected := "/archive/notes/2025"
+		expected := "/notes/archive/2025"
 		if selector != expected {
 			t.Errorf("expected selector %s, got %s", expected, selector)
 		}
@@ -197,3 +262,23 @@ func TestResolveAuthorsDefaultsAndNpub(t *testing.T) {
 		}
 	})
 }
+
+func TestApplySensitiveFilter(t *testing.T) {
+	plain := &nostr.Event{ID: "plain"}
+	warned := &nostr.Event{ID: "warned", Tags: nostr.Tags{{"content-warning", "spoilers"}}}
+	events := []*nostr.Event{plain, warned}
+
+	if got := applySensitiveFilter(events, nil); len(got) != 2 {
+		t.Errorf("expected both events without a filter, got %d", len(got))
+	}
+
+	exclude := false
+	if got := applySensitiveFilter(events, &exclude); len(got) != 1 || got[0] != plain {
+		t.Errorf("expected only the plain event when excluding sensitive, got %v", got)
+	}
+
+	only := true
+	if got := applySensitiveFilter(events, &only); len(got) != 1 || got[0] != warned {
+		t.Errorf("expected only the warned event when including sensitive, got %v", got)
+	}
+}
@@ -0,0 +1,90 @@
+package security
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+var (
+	// ErrTooManyConnectionsPerIP is returned when a client IP already holds
+	// its maximum number of concurrent connections
+	ErrTooManyConnectionsPerIP = errors.New("too many connections from this address")
+
+	// ErrTooManyConnections is returned when the server-wide connection cap
+	// has been reached
+	ErrTooManyConnections = errors.New("server connection limit reached")
+)
+
+// ConnectionLimiter caps concurrent connections per client IP and in total
+type ConnectionLimiter struct {
+	maxPerIP int // 0 = unlimited
+	maxTotal int // 0 = unlimited
+	perIP    map[string]int
+	total    int
+	mu       sync.Mutex
+}
+
+// NewConnectionLimiter creates a new connection limiter
+func NewConnectionLimiter(maxPerIP, maxTotal int) *ConnectionLimiter {
+	return &ConnectionLimiter{
+		maxPerIP: maxPerIP,
+		maxTotal: maxTotal,
+		perIP:    make(map[string]int),
+	}
+}
+
+// Acquire reserves a connection slot for ip. Every successful Acquire must be
+// paired with a Release.
+func (cl *ConnectionLimiter) Acquire(ip string) error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cl.maxTotal > 0 && cl.total >= cl.maxTotal {
+		return ErrTooManyConnections
+	}
+	if cl.maxPerIP > 0 && cl.perIP[ip] >= cl.maxPerIP {
+		return ErrTooManyConnectionsPerIP
+	}
+
+	cl.perIP[ip]++
+	cl.total++
+	return nil
+}
+
+// Release frees a connection slot previously reserved for ip
+func (cl *ConnectionLimiter) Release(ip string) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cl.perIP[ip] <= 1 {
+		delete(cl.perIP, ip)
+	} else {
+		cl.perIP[ip]--
+	}
+	if cl.total > 0 {
+		cl.total--
+	}
+}
+
+// Active returns the number of open connections for ip and in total
+func (cl *ConnectionLimiter) Active(ip string) (perIP, total int) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	return cl.perIP[ip], cl.total
+}
+
+// ClientIP extracts the IP address from a remote address, falling back to
+// the full address string if it has no port
+func ClientIP(addr net.Addr) string {
+	if addr == nil {
+		return "unknown"
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
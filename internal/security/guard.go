@@ -0,0 +1,147 @@
+package security
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/nostr/helpers"
+)
+
+// Protocol names used as rate limiter keys
+const (
+	ProtocolGopher = "gopher"
+	ProtocolGemini = "gemini"
+	ProtocolFinger = "finger"
+	ProtocolHTTP   = "http"
+)
+
+// Guard applies the security config to the protocol servers: connection
+// caps, per-protocol rate limits and the event deny list / banned words. One
+// guard is shared by every server, so max_connections caps them together.
+type Guard struct {
+	enabled     bool
+	limiters    *MultiRateLimiter
+	connections *ConnectionLimiter
+	enforcer    *Enforcer
+	closeOnce   sync.Once
+}
+
+// NewGuard creates a guard from the security config. A disabled config (or a
+// nil one) yields a guard that allows everything.
+func NewGuard(cfg *config.Security) *Guard {
+	g := &Guard{
+		limiters:    NewMultiRateLimiter(),
+		connections: NewConnectionLimiter(0, 0),
+		enforcer:    NewEnforcer(&SecurityPolicy{}),
+	}
+
+	if cfg == nil || !cfg.Enabled {
+		return g
+	}
+	g.enabled = true
+
+	limits := map[string]int{
+		ProtocolGopher: cfg.RateLimits.Gopher,
+		ProtocolGemini: cfg.RateLimits.Gemini,
+		ProtocolFinger: cfg.RateLimits.Finger,
+		ProtocolHTTP:   cfg.RateLimits.HTTP,
+	}
+	for protocol, rate := range limits {
+		if rate > 0 {
+			g.limiters.AddLimiter(protocol, NewRateLimiter(rate, time.Minute))
+		}
+	}
+
+	g.connections = NewConnectionLimiter(cfg.MaxConnectionsPerIP, cfg.MaxConnections)
+	g.enforcer = NewEnforcerFromConfig(cfg)
+
+	return g
+}
+
+// NewEnforcerFromConfig creates an enforcer for the security config's deny
+// list and banned words. A disabled config (or a nil one) allows every event.
+func NewEnforcerFromConfig(cfg *config.Security) *Enforcer {
+	if cfg == nil || !cfg.Enabled {
+		return NewEnforcer(&SecurityPolicy{})
+	}
+
+	// Events carry hex pubkeys, so normalize npubs up front
+	pubkeys := make([]string, 0, len(cfg.DenylistPubkeys))
+	for _, pubkey := range cfg.DenylistPubkeys {
+		if hex, err := helpers.NormalizePubkey(pubkey); err == nil {
+			pubkeys = append(pubkeys, hex)
+		}
+	}
+
+	// An empty word would match every event
+	words := make([]string, 0, len(cfg.BannedWords))
+	for _, word := range cfg.BannedWords {
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+
+	return NewEnforcer(&SecurityPolicy{
+		DenyListPubkeys: pubkeys,
+		BannedWords:     words,
+	})
+}
+
+// Enabled reports whether the security config is active
+func (g *Guard) Enabled() bool {
+	return g.enabled
+}
+
+// AcquireConnection reserves a connection slot for the client IP. It returns
+// ErrTooManyConnectionsPerIP or ErrTooManyConnections when a cap is reached.
+func (g *Guard) AcquireConnection(ip string) error {
+	return g.connections.Acquire(ip)
+}
+
+// ReleaseConnection frees a slot reserved by AcquireConnection
+func (g *Guard) ReleaseConnection(ip string) {
+	g.connections.Release(ip)
+}
+
+// AllowRequest checks the protocol's rate limit for the client IP. When the
+// request is refused it also returns how long the client should wait.
+func (g *Guard) AllowRequest(protocol, ip string) (bool, time.Duration) {
+	if g.limiters.Allow(protocol, ip) {
+		return true, 0
+	}
+
+	return false, g.limiters.RetryAfter(protocol, ip)
+}
+
+// RetrySeconds rounds a retry delay up to whole seconds, with a minimum of 1,
+// for protocols that report the delay to clients
+func RetrySeconds(d time.Duration) int {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// AllowEvent reports whether an event may be rendered
+func (g *Guard) AllowEvent(event *nostr.Event) bool {
+	return g.enforcer.EnforceEvent(context.Background(), event) == nil
+}
+
+// FilterEvents removes events from denied pubkeys or with banned content
+func (g *Guard) FilterEvents(events []*nostr.Event) []*nostr.Event {
+	if !g.enabled {
+		return events
+	}
+
+	return g.enforcer.EnforceEvents(context.Background(), events)
+}
+
+// Close stops the rate limiters' cleanup goroutines. It is safe to call
+// more than once, as each server sharing the guard does when it stops.
+func (g *Guard) Close() {
+	g.closeOnce.Do(g.limiters.Close)
+}
@@ -0,0 +1,165 @@
+package security
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/nostr/helpers"
+)
+
+func TestConnectionLimiter(t *testing.T) {
+	t.Run("Per-IP cap", func(t *testing.T) {
+		cl := NewConnectionLimiter(2, 0)
+
+		for i := 0; i < 2; i++ {
+			if err := cl.Acquire("1.2.3.4"); err != nil {
+				t.Fatalf("connection %d should be allowed: %v", i+1, err)
+			}
+		}
+		if err := cl.Acquire("1.2.3.4"); err != ErrTooManyConnectionsPerIP {
+			t.Errorf("expected ErrTooManyConnectionsPerIP, got %v", err)
+		}
+		if err := cl.Acquire("5.6.7.8"); err != nil {
+			t.Errorf("other IP should be allowed: %v", err)
+		}
+
+		cl.Release("1.2.3.4")
+		if err := cl.Acquire("1.2.3.4"); err != nil {
+			t.Errorf("slot should be free after release: %v", err)
+		}
+	})
+
+	t.Run("Global cap", func(t *testing.T) {
+		cl := NewConnectionLimiter(0, 2)
+
+		cl.Acquire("a")
+		cl.Acquire("b")
+		if err := cl.Acquire("c"); err != ErrTooManyConnections {
+			t.Errorf("expected ErrTooManyConnections, got %v", err)
+		}
+
+		cl.Release("a")
+		perIP, total := cl.Active("a")
+		if perIP != 0 || total != 1 {
+			t.Errorf("expected 0/1 active connections, got %d/%d", perIP, total)
+		}
+	})
+}
+
+func TestRateLimiterRetryAfter(t *testing.T) {
+	rl := NewRateLimiter(2, time.Minute)
+	defer rl.Close()
+
+	if d := rl.RetryAfter("client"); d != 0 {
+		t.Errorf("unknown client should not wait, got %v", d)
+	}
+
+	rl.Allow("client")
+	rl.Allow("client")
+
+	// One token refills every 30 seconds
+	d := rl.RetryAfter("client")
+	if d <= 0 || d > 30*time.Second {
+		t.Errorf("expected wait in (0, 30s], got %v", d)
+	}
+	if s := RetrySeconds(d); s < 1 || s > 30 {
+		t.Errorf("expected 1-30 retry seconds, got %d", s)
+	}
+}
+
+func TestGuard(t *testing.T) {
+	denied := strings.Repeat("ab", 32)
+
+	t.Run("Disabled allows everything", func(t *testing.T) {
+		g := NewGuard(&config.Security{
+			Enabled:         false,
+			RateLimits:      config.SecurityRateLimits{Gopher: 1},
+			DenylistPubkeys: []string{denied},
+		})
+		defer g.Close()
+
+		for i := 0; i < 3; i++ {
+			if ok, _ := g.AllowRequest(ProtocolGopher, "1.2.3.4"); !ok {
+				t.Fatalf("request %d should be allowed", i+1)
+			}
+		}
+		events := []*nostr.Event{{PubKey: denied}}
+		if len(g.FilterEvents(events)) != 1 {
+			t.Error("disabled guard should not filter events")
+		}
+	})
+
+	t.Run("Rate limits are per protocol", func(t *testing.T) {
+		g := NewGuard(&config.Security{
+			Enabled:    true,
+			RateLimits: config.SecurityRateLimits{Gopher: 1},
+		})
+		defer g.Close()
+
+		if ok, _ := g.AllowRequest(ProtocolGopher, "1.2.3.4"); !ok {
+			t.Fatal("first request should be allowed")
+		}
+		ok, retry := g.AllowRequest(ProtocolGopher, "1.2.3.4")
+		if ok {
+			t.Fatal("second request should be limited")
+		}
+		if retry <= 0 {
+			t.Errorf("expected a retry delay, got %v", retry)
+		}
+		if ok, _ := g.AllowRequest(ProtocolGemini, "1.2.3.4"); !ok {
+			t.Error("gemini has no limit configured and should be allowed")
+		}
+	})
+
+	t.Run("Shared guard caps servers together", func(t *testing.T) {
+		g := NewGuard(&config.Security{Enabled: true, MaxConnections: 2})
+
+		// Connections to different servers count against one cap
+		if err := g.AcquireConnection("1.1.1.1"); err != nil {
+			t.Fatalf("first connection should be allowed: %v", err)
+		}
+		if err := g.AcquireConnection("2.2.2.2"); err != nil {
+			t.Fatalf("second connection should be allowed: %v", err)
+		}
+		if err := g.AcquireConnection("3.3.3.3"); !errors.Is(err, ErrTooManyConnections) {
+			t.Errorf("expected the cap to refuse a third connection, got %v", err)
+		}
+
+		// Every server sharing the guard closes it when it stops
+		g.Close()
+		g.Close()
+	})
+
+	t.Run("Deny list accepts npub and banned words", func(t *testing.T) {
+		npub := "npub1nq3zgtqruwhnz0xx40gh4a4fkamlr2sc7ke5wqs2s3nyv2fpy9esg4hdwq"
+		g := NewGuard(&config.Security{
+			Enabled:         true,
+			DenylistPubkeys: []string{npub, denied},
+			BannedWords:     []string{"", "spam"},
+		})
+		defer g.Close()
+
+		npubHex, err := helpers.NormalizePubkey(npub)
+		if err != nil {
+			t.Fatalf("failed to decode npub: %v", err)
+		}
+		events := []*nostr.Event{
+			{PubKey: npubHex, Content: "hello"},
+			{PubKey: denied, Content: "hello"},
+			{PubKey: "cd", Content: "buy spam now"},
+			{PubKey: "cd", Content: "hello"},
+		}
+
+		filtered := g.FilterEvents(events)
+		if len(filtered) != 1 || filtered[0] != events[3] {
+			t.Errorf("expected only the clean event to remain, got %d events", len(filtered))
+		}
+		if g.AllowEvent(events[0]) {
+			t.Error("event from npub-denied author should not be allowed")
+		}
+	})
+}
@@ -114,6 +114,32 @@ func (rl *RateLimiter) GetLimit(clientID string) (remaining int, resetTime time.
 	return b.tokens, b.lastRefill.Add(rl.window)
 }
 
+// RetryAfter returns how long a client must wait before its next token is
+// available. It returns 0 if the client can make a request now.
+func (rl *RateLimiter) RetryAfter(clientID string) time.Duration {
+	rl.mu.RLock()
+	b, exists := rl.buckets[clientID]
+	rl.mu.RUnlock()
+
+	if !exists || rl.rate <= 0 {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tokens > 0 {
+		return 0
+	}
+
+	// One token is refilled every window/rate
+	wait := time.Until(b.lastRefill.Add(rl.window / time.Duration(rl.rate)))
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
 // cleanupLoop periodically removes old buckets
 func (rl *RateLimiter) cleanupLoop() {
 	ticker := time.NewTicker(rl.cleanupInterval)
@@ -185,6 +211,20 @@ func (mrl *MultiRateLimiter) Allow(limiterName, clientID string) bool {
 	return limiter.Allow(clientID)
 }
 
+// RetryAfter returns how long a client must wait before a specific limiter
+// allows its next request
+func (mrl *MultiRateLimiter) RetryAfter(limiterName, clientID string) time.Duration {
+	mrl.mu.RLock()
+	limiter, exists := mrl.limiters[limiterName]
+	mrl.mu.RUnlock()
+
+	if !exists {
+		return 0
+	}
+
+	return limiter.RetryAfter(clientID)
+}
+
 // Close closes all rate limiters
 func (mrl *MultiRateLimiter) Close() {
 	mrl.mu.Lock()
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
 )
 
 // SecretManager handles secure secret management
@@ -90,6 +93,31 @@ func (sm *SecretManager) LoadNsecFromEnv() (string, error) {
 	return nsec, nil
 }
 
+// OwnerSecretKey loads NOPHR_NSEC as a hex secret key and checks it belongs
+// to the owner's hex pubkey
+func OwnerSecretKey(ownerPubkey string) (string, error) {
+	nsec, err := NewSecretManager().LoadNsecFromEnv()
+	if err != nil {
+		return "", err
+	}
+
+	prefix, value, err := nip19.Decode(nsec)
+	if err != nil || prefix != "nsec" {
+		return "", fmt.Errorf("invalid NOPHR_NSEC")
+	}
+	sk := value.(string)
+
+	pubkey, err := nostr.GetPublicKey(sk)
+	if err != nil {
+		return "", fmt.Errorf("invalid NOPHR_NSEC: %w", err)
+	}
+	if pubkey != ownerPubkey {
+		return "", fmt.Errorf("NOPHR_NSEC does not match identity.npub")
+	}
+
+	return sk, nil
+}
+
 // RedactedConfig returns a config with secrets redacted
 type RedactedConfig struct {
 	config map[string]interface{}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 )
@@ -18,6 +19,10 @@ type Aggregate struct {
 
 // SaveAggregate stores or updates an aggregate
 func (s *Storage) SaveAggregate(ctx context.Context, agg *Aggregate) error {
+	if s.kv != nil {
+		return s.kvSaveAggregate(agg)
+	}
+
 	reactionCountsJSON, err := json.Marshal(agg.ReactionCounts)
 	if err != nil {
 		return fmt.Errorf("failed to marshal reaction counts: %w", err)
@@ -49,6 +54,10 @@ func (s *Storage) SaveAggregate(ctx context.Context, agg *Aggregate) error {
 
 // GetAggregate retrieves an aggregate for a given event ID
 func (s *Storage) GetAggregate(ctx context.Context, eventID string) (*Aggregate, error) {
+	if s.kv != nil {
+		return s.kvGetAggregate(eventID)
+	}
+
 	query := `
 		SELECT event_id, reply_count, reaction_total, reaction_counts_json,
 		       zap_sats_total, last_interaction_at
@@ -57,7 +66,7 @@ func (s *Storage) GetAggregate(ctx context.Context, eventID string) (*Aggregate,
 	`
 
 	var agg Aggregate
-	var reactionCountsJSON string
+	var reactionCountsJSON sql.NullString // NULL for rows created by reply or zap updates
 
 	err := s.db.QueryRowContext(ctx, query, eventID).Scan(
 		&agg.EventID, &agg.ReplyCount, &agg.ReactionTotal, &reactionCountsJSON,
@@ -67,8 +76,8 @@ func (s *Storage) GetAggregate(ctx context.Context, eventID string) (*Aggregate,
 		return nil, fmt.Errorf("failed to get aggregate: %w", err)
 	}
 
-	if reactionCountsJSON != "" {
-		if err := json.Unmarshal([]byte(reactionCountsJSON), &agg.ReactionCounts); err != nil {
+	if reactionCountsJSON.String != "" {
+		if err := json.Unmarshal([]byte(reactionCountsJSON.String), &agg.ReactionCounts); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal reaction counts: %w", err)
 		}
 	} else {
@@ -80,6 +89,10 @@ func (s *Storage) GetAggregate(ctx context.Context, eventID string) (*Aggregate,
 
 // GetAggregates retrieves aggregates for multiple event IDs
 func (s *Storage) GetAggregates(ctx context.Context, eventIDs []string) (map[string]*Aggregate, error) {
+	if s.kv != nil {
+		return s.kvGetAggregates(eventIDs)
+	}
+
 	if len(eventIDs) == 0 {
 		return make(map[string]*Aggregate), nil
 	}
@@ -111,7 +124,7 @@ func (s *Storage) GetAggregates(ctx context.Context, eventIDs []string) (map[str
 	aggregates := make(map[string]*Aggregate)
 	for rows.Next() {
 		var agg Aggregate
-		var reactionCountsJSON string
+		var reactionCountsJSON sql.NullString
 
 		if err := rows.Scan(
 			&agg.EventID, &agg.ReplyCount, &agg.ReactionTotal, &reactionCountsJSON,
@@ -120,8 +133,8 @@ func (s *Storage) GetAggregates(ctx context.Context, eventIDs []string) (map[str
 			return nil, fmt.Errorf("failed to scan aggregate: %w", err)
 		}
 
-		if reactionCountsJSON != "" {
-			if err := json.Unmarshal([]byte(reactionCountsJSON), &agg.ReactionCounts); err != nil {
+		if reactionCountsJSON.String != "" {
+			if err := json.Unmarshal([]byte(reactionCountsJSON.String), &agg.ReactionCounts); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal reaction counts: %w", err)
 			}
 		} else {
@@ -140,6 +153,10 @@ func (s *Storage) GetAggregates(ctx context.Context, eventIDs []string) (map[str
 
 // IncrementReplyCount increments the reply count for an event
 func (s *Storage) IncrementReplyCount(ctx context.Context, eventID string, interactionAt int64) error {
+	if s.kv != nil {
+		return s.kvIncrementReplies(map[string]int64{eventID: interactionAt})
+	}
+
 	query := `
 		INSERT INTO aggregates (event_id, reply_count, reaction_total, zap_sats_total, last_interaction_at)
 		VALUES (?, 1, 0, 0, ?)
@@ -158,6 +175,10 @@ func (s *Storage) IncrementReplyCount(ctx context.Context, eventID string, inter
 
 // IncrementReaction increments the reaction count for an event
 func (s *Storage) IncrementReaction(ctx context.Context, eventID string, reaction string, interactionAt int64) error {
+	if s.kv != nil {
+		return s.kvIncrementReactions(map[string]map[string]int64{eventID: {reaction: interactionAt}})
+	}
+
 	// Get current aggregate
 	agg, err := s.GetAggregate(ctx, eventID)
 	if err != nil {
@@ -181,6 +202,10 @@ func (s *Storage) IncrementReaction(ctx context.Context, eventID string, reactio
 
 // AddZapAmount adds zap sats to an event's aggregate
 func (s *Storage) AddZapAmount(ctx context.Context, eventID string, sats int64, interactionAt int64) error {
+	if s.kv != nil {
+		return s.kvAddZapAmount(eventID, sats, interactionAt)
+	}
+
 	query := `
 		INSERT INTO aggregates (event_id, reply_count, reaction_total, zap_sats_total, last_interaction_at)
 		VALUES (?, 0, 0, ?, ?)
@@ -199,6 +224,10 @@ func (s *Storage) AddZapAmount(ctx context.Context, eventID string, sats int64,
 
 // DeleteAggregate removes an aggregate
 func (s *Storage) DeleteAggregate(ctx context.Context, eventID string) error {
+	if s.kv != nil {
+		return s.kvDeleteAggregate(eventID)
+	}
+
 	query := `DELETE FROM aggregates WHERE event_id = ?`
 	_, err := s.db.ExecContext(ctx, query, eventID)
 	if err != nil {
@@ -209,6 +238,10 @@ func (s *Storage) DeleteAggregate(ctx context.Context, eventID string) error {
 
 // BatchIncrementReplies increments reply counts for multiple events (Performance optimization)
 func (s *Storage) BatchIncrementReplies(ctx context.Context, updates map[string]int64) error {
+	if s.kv != nil {
+		return s.kvIncrementReplies(updates)
+	}
+
 	if len(updates) == 0 {
 		return nil
 	}
@@ -240,44 +273,12 @@ func (s *Storage) BatchIncrementReplies(ctx context.Context, updates map[string]
 	return tx.Commit()
 }
 
-// BatchAddZaps adds zap amounts for multiple events (Performance optimization)
-func (s *Storage) BatchAddZaps(ctx context.Context, updates map[string]struct {
-	Sats          int64
-	InteractionAt int64
-}) error {
-	if len(updates) == 0 {
-		return nil
-	}
-
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
-	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO aggregates (event_id, reply_count, reaction_total, zap_sats_total, last_interaction_at)
-		VALUES (?, 0, 0, ?, ?)
-		ON CONFLICT(event_id) DO UPDATE SET
-			zap_sats_total = zap_sats_total + excluded.zap_sats_total,
-			last_interaction_at = MAX(last_interaction_at, excluded.last_interaction_at)
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to prepare statement: %w", err)
-	}
-	defer stmt.Close()
-
-	for eventID, update := range updates {
-		if _, err := stmt.ExecContext(ctx, eventID, update.Sats, update.InteractionAt); err != nil {
-			return fmt.Errorf("failed to add zap for %s: %w", eventID, err)
-		}
-	}
-
-	return tx.Commit()
-}
-
 // BatchIncrementReactions increments reaction counts for multiple events (Performance optimization)
 func (s *Storage) BatchIncrementReactions(ctx context.Context, updates map[string]map[string]int64) error {
+	if s.kv != nil {
+		return s.kvIncrementReactions(updates)
+	}
+
 	if len(updates) == 0 {
 		return nil
 	}
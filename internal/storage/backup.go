@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// openReadOnly opens the SQLite database at path without write access
+func openReadOnly(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro&_busy_timeout=5000", path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return db, nil
+}
+
+// SnapshotSQLite writes a consistent copy of the SQLite database at src to
+// dest with VACUUM INTO. It reads through its own read-only connection, so it
+// is safe while nophr is writing to the database. dest must not exist or be
+// an empty file.
+func SnapshotSQLite(ctx context.Context, src, dest string) error {
+	db, err := openReadOnly(src)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", dest); err != nil {
+		return fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	return nil
+}
+
+// VerifySQLite checks the integrity of the nophr SQLite database at path and
+// returns its schema version (see SchemaVersion)
+func VerifySQLite(ctx context.Context, path string) (int, error) {
+	db, err := openReadOnly(path)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&result); err != nil {
+		return 0, fmt.Errorf("failed to check integrity: %w", err)
+	}
+	if result != "ok" {
+		return 0, fmt.Errorf("integrity check failed: %s", result)
+	}
+
+	var tables int
+	if err := db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('event', 'sync_state')",
+	).Scan(&tables); err != nil {
+		return 0, fmt.Errorf("failed to read schema: %w", err)
+	}
+	if tables != 2 {
+		return 0, fmt.Errorf("not a nophr database")
+	}
+
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/config"
+)
+
+func TestSnapshotSQLite(t *testing.T) {
+	s, cleanup := setupTestStorage(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	event := &nostr.Event{
+		ID:        strings.Repeat("a", 64),
+		PubKey:    strings.Repeat("f", 64),
+		CreatedAt: nostr.Timestamp(1000),
+		Kind:      1,
+		Tags:      nostr.Tags{},
+		Sig:       strings.Repeat("c", 128),
+	}
+	if err := s.StoreEvent(ctx, event); err != nil {
+		t.Fatalf("Failed to store event: %v", err)
+	}
+
+	// The snapshot is taken while s still has the database open
+	dest := filepath.Join(t.TempDir(), "snapshot.db")
+	if err := SnapshotSQLite(ctx, s.config.SQLitePath, dest); err != nil {
+		t.Fatalf("SnapshotSQLite() error = %v", err)
+	}
+
+	version, err := VerifySQLite(ctx, dest)
+	if err != nil {
+		t.Fatalf("VerifySQLite() error = %v", err)
+	}
+	if version != SchemaVersion() {
+		t.Errorf("expected schema version %d, got %d", SchemaVersion(), version)
+	}
+
+	copied, err := New(ctx, &config.Storage{Driver: "sqlite", SQLitePath: dest})
+	if err != nil {
+		t.Fatalf("Failed to open snapshot: %v", err)
+	}
+	defer copied.Close()
+	if count, _ := copied.CountEvents(ctx); count != 1 {
+		t.Errorf("expected 1 event in the snapshot, got %d", count)
+	}
+
+	junk := filepath.Join(t.TempDir(), "junk.db")
+	if err := os.WriteFile(junk, []byte(strings.Repeat("not a database", 100)), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := VerifySQLite(ctx, junk); err == nil {
+		t.Error("expected VerifySQLite to reject a file that is not a database")
+	}
+}
+
+func TestNewerSchemaVersion(t *testing.T) {
+	s, cleanup := setupTestStorage(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := s.DB().ExecContext(ctx, "PRAGMA user_version = 9999"); err != nil {
+		t.Fatalf("Failed to set schema version: %v", err)
+	}
+
+	if err := s.runMigrations(ctx); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("expected migrations to refuse a newer schema, got %v", err)
+	}
+}
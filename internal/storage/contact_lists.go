@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// ContactList records the kind 3 event an author's stored follows come from
+type ContactList struct {
+	Pubkey    string
+	EventID   string
+	CreatedAt int64
+}
+
+// GetContactList retrieves the contact list recorded for an author
+func (s *Storage) GetContactList(ctx context.Context, pubkey string) (*ContactList, error) {
+	if s.kv != nil {
+		return s.kvGetContactList(pubkey)
+	}
+
+	query := `
+		SELECT pubkey, event_id, created_at
+		FROM contact_lists
+		WHERE pubkey = ?
+	`
+
+	var list ContactList
+	err := s.db.QueryRowContext(ctx, query, pubkey).Scan(&list.Pubkey, &list.EventID, &list.CreatedAt)
+	if err != nil {
+		return nil, err // Returns sql.ErrNoRows if not found
+	}
+
+	return &list, nil
+}
+
+// GetAllContactLists retrieves the contact list recorded for every author
+func (s *Storage) GetAllContactLists(ctx context.Context) ([]*ContactList, error) {
+	if s.kv != nil {
+		return s.kvAllContactLists()
+	}
+
+	query := `
+		SELECT pubkey, event_id, created_at
+		FROM contact_lists
+		ORDER BY pubkey
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query contact lists: %w", err)
+	}
+	defer rows.Close()
+
+	var lists []*ContactList
+	for rows.Next() {
+		var list ContactList
+		if err := rows.Scan(&list.Pubkey, &list.EventID, &list.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan contact list: %w", err)
+		}
+		lists = append(lists, &list)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return lists, nil
+}
+
+// SaveContactList records an author's contact list along with the follows it
+// added and removed relative to the previous one, in a single transaction
+func (s *Storage) SaveContactList(ctx context.Context, list *ContactList, added, removed []string) error {
+	if s.kv != nil {
+		return s.kvSaveContactList(list, added, removed)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO contact_lists (pubkey, event_id, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(pubkey) DO UPDATE SET
+			event_id = excluded.event_id,
+			created_at = excluded.created_at
+	`, list.Pubkey, list.EventID, list.CreatedAt); err != nil {
+		return fmt.Errorf("failed to save contact list: %w", err)
+	}
+
+	if len(added) > 0 {
+		stmt, err := tx.PrepareContext(ctx, `INSERT OR IGNORE INTO follows (follower, followee) VALUES (?, ?)`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, followee := range added {
+			if _, err := stmt.ExecContext(ctx, list.Pubkey, followee); err != nil {
+				return fmt.Errorf("failed to add follow: %w", err)
+			}
+		}
+	}
+
+	if len(removed) > 0 {
+		stmt, err := tx.PrepareContext(ctx, `DELETE FROM follows WHERE follower = ? AND followee = ?`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, followee := range removed {
+			if _, err := stmt.ExecContext(ctx, list.Pubkey, followee); err != nil {
+				return fmt.Errorf("failed to remove follow: %w", err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CountContactLists returns how many authors have a contact list recorded
+func (s *Storage) CountContactLists(ctx context.Context) (int64, error) {
+	if s.kv != nil {
+		lists, err := s.kvAllContactLists()
+		return int64(len(lists)), err
+	}
+
+	var count int64
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM contact_lists`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count contact lists: %w", err)
+	}
+	return count, nil
+}
+
+// GetFollows returns the pubkeys an author follows, per their latest contact list
+func (s *Storage) GetFollows(ctx context.Context, follower string) ([]string, error) {
+	if s.kv != nil {
+		return s.kvScanFollows(kvTableFollows, follower)
+	}
+
+	return s.queryPubkeys(ctx,
+		`SELECT followee FROM follows WHERE follower = ? ORDER BY followee`, follower)
+}
+
+// GetFollowers returns the pubkeys whose latest contact list follows pubkey
+func (s *Storage) GetFollowers(ctx context.Context, followee string) ([]string, error) {
+	if s.kv != nil {
+		return s.kvScanFollows(kvTableFollowers, followee)
+	}
+
+	return s.queryPubkeys(ctx,
+		`SELECT follower FROM follows WHERE followee = ? ORDER BY follower`, followee)
+}
+
+// IsFollowing reports whether follower's latest contact list follows followee
+func (s *Storage) IsFollowing(ctx context.Context, follower, followee string) (bool, error) {
+	if s.kv != nil {
+		return s.kvIsFollowing(follower, followee)
+	}
+
+	var exists int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM follows WHERE follower = ? AND followee = ?)`,
+		follower, followee).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check follow: %w", err)
+	}
+	return exists == 1, nil
+}
+
+// GetAllFollows returns every stored follow edge, keyed by follower
+func (s *Storage) GetAllFollows(ctx context.Context) (map[string][]string, error) {
+	if s.kv != nil {
+		return s.kvAllFollows()
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT follower, followee FROM follows ORDER BY follower, followee`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query follows: %w", err)
+	}
+	defer rows.Close()
+
+	follows := make(map[string][]string)
+	for rows.Next() {
+		var follower, followee string
+		if err := rows.Scan(&follower, &followee); err != nil {
+			return nil, fmt.Errorf("failed to scan follow: %w", err)
+		}
+		follows[follower] = append(follows[follower], followee)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return follows, nil
+}
+
+// queryPubkeys runs a query returning a single pubkey column
+func (s *Storage) queryPubkeys(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query follows: %w", err)
+	}
+	defer rows.Close()
+
+	var pubkeys []string
+	for rows.Next() {
+		var pubkey string
+		if err := rows.Scan(&pubkey); err != nil {
+			return nil, fmt.Errorf("failed to scan pubkey: %w", err)
+		}
+		pubkeys = append(pubkeys, pubkey)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return pubkeys, nil
+}
@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// Deletion is a NIP-09 deletion request for one target. Targets are event IDs
+// (from "e" tags) or "kind:pubkey:d" addresses (from "a" tags). Deletions are
+// kept after the target is removed so late copies are rejected at ingest.
+type Deletion struct {
+	Target     string
+	Pubkey     string // author of the deletion request
+	DeletionID string
+	CreatedAt  int64 // created_at of the deletion request
+}
+
+// SaveDeletion records a deletion request. For a target already deleted by
+// the same pubkey, the newest request wins.
+func (s *Storage) SaveDeletion(ctx context.Context, d *Deletion) error {
+	if s.kv != nil {
+		if err := s.kvSaveDeletion(d); err != nil {
+			return err
+		}
+		s.addDeletionAuthor(d.Pubkey)
+		return nil
+	}
+
+	query := `
+		INSERT INTO deletions (target, pubkey, deletion_id, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(target, pubkey) DO UPDATE SET
+			deletion_id = excluded.deletion_id,
+			created_at = excluded.created_at
+		WHERE excluded.created_at > deletions.created_at
+	`
+
+	_, err := s.db.ExecContext(ctx, query, d.Target, d.Pubkey, d.DeletionID, d.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save deletion: %w", err)
+	}
+
+	s.addDeletionAuthor(d.Pubkey)
+	return nil
+}
+
+// GetDeletion returns the deletion of target by pubkey, or nil if there is none
+func (s *Storage) GetDeletion(ctx context.Context, target, pubkey string) (*Deletion, error) {
+	if s.kv != nil {
+		return s.kvGetDeletion(target, pubkey)
+	}
+
+	query := `
+		SELECT target, pubkey, deletion_id, created_at
+		FROM deletions
+		WHERE target = ? AND pubkey = ?
+	`
+
+	var d Deletion
+	err := s.db.QueryRowContext(ctx, query, target, pubkey).Scan(
+		&d.Target, &d.Pubkey, &d.DeletionID, &d.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deletion: %w", err)
+	}
+
+	return &d, nil
+}
+
+// IsDeleted reports whether an event's author has requested its deletion,
+// either by ID or, for replaceable and addressable events, by address for
+// versions created up to the deletion request. Authors who never requested a
+// deletion are answered from memory, without a lookup.
+func (s *Storage) IsDeleted(ctx context.Context, event *nostr.Event) (bool, error) {
+	if has, err := s.hasDeletions(ctx, event.PubKey); err != nil || !has {
+		return false, err
+	}
+
+	d, err := s.GetDeletion(ctx, event.ID, event.PubKey)
+	if err != nil {
+		return false, err
+	}
+	if d != nil {
+		return true, nil
+	}
+
+	address := DeletionAddress(event)
+	if address == "" {
+		return false, nil
+	}
+
+	d, err = s.GetDeletion(ctx, address, event.PubKey)
+	if err != nil {
+		return false, err
+	}
+	return d != nil && int64(event.CreatedAt) <= d.CreatedAt, nil
+}
+
+// hasDeletions reports whether pubkey has requested any deletion. The set of
+// authors with deletions is read once, then kept current by SaveDeletion.
+func (s *Storage) hasDeletions(ctx context.Context, pubkey string) (bool, error) {
+	s.deletionsMu.Lock()
+	defer s.deletionsMu.Unlock()
+
+	if s.deletionAuthors == nil {
+		authors, err := s.deletionAuthorList(ctx)
+		if err != nil {
+			return false, err
+		}
+		s.deletionAuthors = make(map[string]bool, len(authors))
+		for _, author := range authors {
+			s.deletionAuthors[author] = true
+		}
+	}
+
+	return s.deletionAuthors[pubkey], nil
+}
+
+// addDeletionAuthor marks pubkey as having requested a deletion
+func (s *Storage) addDeletionAuthor(pubkey string) {
+	s.deletionsMu.Lock()
+	defer s.deletionsMu.Unlock()
+
+	if s.deletionAuthors != nil {
+		s.deletionAuthors[pubkey] = true
+	}
+}
+
+// deletionAuthorList returns every pubkey with a recorded deletion request
+func (s *Storage) deletionAuthorList(ctx context.Context) ([]string, error) {
+	if s.kv != nil {
+		return s.kvDeletionAuthors()
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT pubkey FROM deletions`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deletion authors: %w", err)
+	}
+	defer rows.Close()
+
+	var authors []string
+	for rows.Next() {
+		var pubkey string
+		if err := rows.Scan(&pubkey); err != nil {
+			return nil, fmt.Errorf("failed to scan deletion author: %w", err)
+		}
+		authors = append(authors, pubkey)
+	}
+	return authors, rows.Err()
+}
+
+// DeletionAddress returns the "kind:pubkey:d" address an "a" tag uses to
+// delete a replaceable or addressable event, or "" for regular events
+func DeletionAddress(event *nostr.Event) string {
+	switch {
+	case nostr.IsAddressableKind(event.Kind):
+		return fmt.Sprintf("%d:%s:%s", event.Kind, event.PubKey, event.Tags.GetD())
+	case nostr.IsReplaceableKind(event.Kind):
+		return fmt.Sprintf("%d:%s:", event.Kind, event.PubKey)
+	}
+	return ""
+}
@@ -16,6 +16,10 @@ type GraphNode struct {
 
 // SaveGraphNode stores or updates a graph node
 func (s *Storage) SaveGraphNode(ctx context.Context, node *GraphNode) error {
+	if s.kv != nil {
+		return s.kvSaveGraphNode(node)
+	}
+
 	query := `
 		INSERT INTO graph_nodes (root_pubkey, pubkey, depth, mutual, last_seen)
 		VALUES (?, ?, ?, ?, ?)
@@ -39,8 +43,71 @@ func (s *Storage) SaveGraphNode(ctx context.Context, node *GraphNode) error {
 	return nil
 }
 
+// UpdateGraphNodes saves and removes graph nodes under a root pubkey in a
+// single transaction
+func (s *Storage) UpdateGraphNodes(ctx context.Context, rootPubkey string, save []*GraphNode, remove []string) error {
+	if s.kv != nil {
+		return s.kvUpdateGraphNodes(rootPubkey, save, remove)
+	}
+
+	if len(save) == 0 && len(remove) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if len(save) > 0 {
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO graph_nodes (root_pubkey, pubkey, depth, mutual, last_seen)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(root_pubkey, pubkey) DO UPDATE SET
+				depth = excluded.depth,
+				mutual = excluded.mutual,
+				last_seen = excluded.last_seen
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, node := range save {
+			mutual := 0
+			if node.Mutual {
+				mutual = 1
+			}
+			if _, err := stmt.ExecContext(ctx, rootPubkey, node.Pubkey, node.Depth, mutual, node.LastSeen); err != nil {
+				return fmt.Errorf("failed to save graph node: %w", err)
+			}
+		}
+	}
+
+	if len(remove) > 0 {
+		stmt, err := tx.PrepareContext(ctx, `DELETE FROM graph_nodes WHERE root_pubkey = ? AND pubkey = ?`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, pubkey := range remove {
+			if _, err := stmt.ExecContext(ctx, rootPubkey, pubkey); err != nil {
+				return fmt.Errorf("failed to remove graph node: %w", err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
 // GetGraphNode retrieves a single graph node for a specific root-target pair
 func (s *Storage) GetGraphNode(ctx context.Context, rootPubkey, targetPubkey string) (*GraphNode, error) {
+	if s.kv != nil {
+		return s.kvGetGraphNode(rootPubkey, targetPubkey)
+	}
+
 	query := `
 		SELECT root_pubkey, pubkey, depth, mutual, last_seen
 		FROM graph_nodes
@@ -63,6 +130,12 @@ func (s *Storage) GetGraphNode(ctx context.Context, rootPubkey, targetPubkey str
 
 // GetGraphNodes retrieves graph nodes for a given root pubkey
 func (s *Storage) GetGraphNodes(ctx context.Context, rootPubkey string, maxDepth int) ([]*GraphNode, error) {
+	if s.kv != nil {
+		return s.kvScanGraphNodes(rootPubkey, func(n *GraphNode) bool {
+			return n.Depth <= maxDepth
+		})
+	}
+
 	query := `
 		SELECT root_pubkey, pubkey, depth, mutual, last_seen
 		FROM graph_nodes
@@ -98,8 +171,55 @@ func (s *Storage) GetGraphNodes(ctx context.Context, rootPubkey string, maxDepth
 	return nodes, nil
 }
 
+// GetAllGraphNodes retrieves the graph nodes under every root pubkey
+func (s *Storage) GetAllGraphNodes(ctx context.Context) ([]*GraphNode, error) {
+	if s.kv != nil {
+		return s.kvAllGraphNodes()
+	}
+
+	query := `
+		SELECT root_pubkey, pubkey, depth, mutual, last_seen
+		FROM graph_nodes
+		ORDER BY root_pubkey, pubkey
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query graph nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []*GraphNode
+	for rows.Next() {
+		var node GraphNode
+		var mutual int
+
+		if err := rows.Scan(
+			&node.RootPubkey, &node.Pubkey, &node.Depth, &mutual, &node.LastSeen,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan graph node: %w", err)
+		}
+
+		node.Mutual = mutual == 1
+		nodes = append(nodes, &node)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return nodes, nil
+}
+
 // GetFollowingPubkeys returns the pubkeys being followed by the root
 func (s *Storage) GetFollowingPubkeys(ctx context.Context, rootPubkey string) ([]string, error) {
+	if s.kv != nil {
+		nodes, err := s.kvScanGraphNodes(rootPubkey, func(n *GraphNode) bool {
+			return n.Depth == 1
+		})
+		return graphNodePubkeys(nodes), err
+	}
+
 	query := `
 		SELECT pubkey
 		FROM graph_nodes
@@ -131,6 +251,13 @@ func (s *Storage) GetFollowingPubkeys(ctx context.Context, rootPubkey string) ([
 
 // GetMutualPubkeys returns the pubkeys with mutual follows
 func (s *Storage) GetMutualPubkeys(ctx context.Context, rootPubkey string) ([]string, error) {
+	if s.kv != nil {
+		nodes, err := s.kvScanGraphNodes(rootPubkey, func(n *GraphNode) bool {
+			return n.Depth == 1 && n.Mutual
+		})
+		return graphNodePubkeys(nodes), err
+	}
+
 	query := `
 		SELECT pubkey
 		FROM graph_nodes
@@ -162,6 +289,10 @@ func (s *Storage) GetMutualPubkeys(ctx context.Context, rootPubkey string) ([]st
 
 // DeleteGraphNodes removes all graph nodes for a given root pubkey
 func (s *Storage) DeleteGraphNodes(ctx context.Context, rootPubkey string) error {
+	if s.kv != nil {
+		return s.kvDeleteGraphNodes(rootPubkey)
+	}
+
 	query := `DELETE FROM graph_nodes WHERE root_pubkey = ?`
 	_, err := s.db.ExecContext(ctx, query, rootPubkey)
 	if err != nil {
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// CountInteractionsWith counts, per author, the replies and reactions that
+// tag pubkey and the zaps sent to it since the given unix time. The pubkey's
+// own events are not counted.
+func (s *Storage) CountInteractionsWith(ctx context.Context, pubkey string, since int64) (map[string]int64, error) {
+	if s.kv != nil {
+		return s.kvCountInteractionsWith(ctx, pubkey, since)
+	}
+
+	counts := make(map[string]int64)
+
+	// The eventstore keeps tags as JSON text in a blob column; the LIKE
+	// narrows the rows before their tags are parsed
+	eventQuery := `
+		SELECT e.pubkey, COUNT(DISTINCT e.id)
+		FROM event e, json_each(CAST(e.tags AS TEXT)) t
+		WHERE e.kind IN (1, 7)
+		  AND e.created_at >= ?
+		  AND e.pubkey != ?
+		  AND e.tags LIKE ?
+		  AND json_extract(t.value, '$[0]') = 'p'
+		  AND json_extract(t.value, '$[1]') = ?
+		GROUP BY e.pubkey
+	`
+	if err := s.addCounts(ctx, counts, eventQuery, since, pubkey, "%"+pubkey+"%", pubkey); err != nil {
+		return nil, fmt.Errorf("failed to count interactions: %w", err)
+	}
+
+	zapQuery := `
+		SELECT sender, COUNT(*)
+		FROM zaps
+		WHERE recipient = ? AND created_at >= ? AND sender != ?
+		GROUP BY sender
+	`
+	if err := s.addCounts(ctx, counts, zapQuery, pubkey, since, pubkey); err != nil {
+		return nil, fmt.Errorf("failed to count zaps: %w", err)
+	}
+
+	return counts, nil
+}
+
+// addCounts adds the (key, count) rows of a query to counts
+func (s *Storage) addCounts(ctx context.Context, counts map[string]int64, query string, args ...interface{}) error {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var count int64
+		if err := rows.Scan(&key, &count); err != nil {
+			return err
+		}
+		counts[key] += count
+	}
+	return rows.Err()
+}
+
+func (s *Storage) kvCountInteractionsWith(ctx context.Context, pubkey string, since int64) (map[string]int64, error) {
+	sinceTs := nostr.Timestamp(since)
+	counts := make(map[string]int64)
+	err := s.kvEachEvent(ctx, nostr.Filter{
+		Kinds: []int{1, 7},
+		Tags:  nostr.TagMap{"p": []string{pubkey}},
+		Since: &sinceTs,
+	}, func(event *nostr.Event) error {
+		if event.PubKey != pubkey {
+			counts[event.PubKey]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	zaps, err := s.kvGetZaps("")
+	if err != nil {
+		return nil, err
+	}
+	for _, z := range zaps {
+		if z.Recipient == pubkey && z.CreatedAt >= since && z.Sender != pubkey {
+			counts[z.Sender]++
+		}
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Table names used by key-value backends (LMDB) for nophr's custom tables.
+// They mirror the SQLite tables created in runMigrations.
+const (
+	kvTableAggregates        = "aggregates"
+	kvTableSyncState         = "sync_state"
+	kvTableRelayHints        = "relay_hints"
+	kvTableGraphNodes        = "graph_nodes"
+	kvTableRetentionMetadata = "retention_metadata"
+	kvTableRelayCapabilities = "relay_capabilities"
+	kvTableDeletions         = "deletions"
+	kvTableZaps              = "zaps"
+	kvTableContactLists      = "contact_lists"
+	kvTableFollows           = "follows"   // follower + followee
+	kvTableFollowers         = "followers" // followee + follower, for reverse lookups
+)
+
+// kvTables lists every table a key-value backend must provide
+var kvTables = []string{
+	kvTableAggregates,
+	kvTableSyncState,
+	kvTableRelayHints,
+	kvTableGraphNodes,
+	kvTableRetentionMetadata,
+	kvTableRelayCapabilities,
+	kvTableDeletions,
+	kvTableZaps,
+	kvTableContactLists,
+	kvTableFollows,
+	kvTableFollowers,
+}
+
+// kvMaxQueryLimit caps what one eventstore query may return on key-value
+// drivers. The LMDB adapter preallocates for MaxLimit/4 results whenever a
+// filter has no limit, so it has to stay bounded.
+const kvMaxQueryLimit = 100000
+
+// kvEventPage is how many events kvEachEvent asks the eventstore for at a time
+var kvEventPage = 5000
+
+// kvSep separates the parts of composite keys (e.g. relay + kind)
+const kvSep = "\x00"
+
+// errKVNotFound is returned by kvTxn.Get when a key does not exist. It aliases
+// sql.ErrNoRows so callers see the same sentinel regardless of driver.
+var errKVNotFound = sql.ErrNoRows
+
+// kvStore is the minimal ordered key-value interface nophr needs to keep its
+// custom tables outside of SQL. The LMDB driver implements it on top of named
+// databases; tests use an in-memory implementation.
+type kvStore interface {
+	// View runs fn in a read-only transaction
+	View(fn func(txn kvTxn) error) error
+
+	// Update runs fn in a read-write transaction, committing if fn returns nil
+	Update(fn func(txn kvTxn) error) error
+
+	// Close releases the underlying environment
+	Close() error
+}
+
+// kvTxn is a transaction over the named tables of a kvStore
+type kvTxn interface {
+	// Get returns the value for key, or errKVNotFound
+	Get(table, key string) ([]byte, error)
+
+	// Put stores value under key, replacing any existing value
+	Put(table, key string, value []byte) error
+
+	// Delete removes key; deleting a missing key is not an error
+	Delete(table, key string) error
+
+	// Scan calls fn for every key starting with prefix, in ascending key order.
+	// Returning errKVStop from fn ends the scan without error.
+	Scan(table, prefix string, fn func(key string, value []byte) error) error
+}
+
+// errKVStop can be returned from a Scan callback to stop iteration early
+var errKVStop = errors.New("stop scan")
+
+// kvKey joins key parts with kvSep
+func kvKey(parts ...string) string {
+	return strings.Join(parts, kvSep)
+}
+
+// kvIntKey encodes an integer so that lexical order matches numeric order
+func kvIntKey(n int) string {
+	return fmt.Sprintf("%010d", n)
+}
+
+// kvGetJSON loads and decodes a JSON record, returning errKVNotFound if absent
+func kvGetJSON(txn kvTxn, table, key string, v interface{}) error {
+	data, err := txn.Get(table, key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// kvPutJSON encodes and stores a JSON record
+func kvPutJSON(txn kvTxn, table, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s record: %w", table, err)
+	}
+	return txn.Put(table, key, data)
+}
+
+// kvDeletePrefix removes every key in table starting with prefix
+func kvDeletePrefix(txn kvTxn, table, prefix string) error {
+	var keys []string
+	if err := txn.Scan(table, prefix, func(key string, _ []byte) error {
+		keys = append(keys, key)
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := txn.Delete(table, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kvCount returns the number of keys in table starting with prefix
+func kvCount(txn kvTxn, table, prefix string) (int64, error) {
+	var count int64
+	err := txn.Scan(table, prefix, func(string, []byte) error {
+		count++
+		return nil
+	})
+	return count, err
+}
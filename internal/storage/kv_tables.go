@@ -0,0 +1,935 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// This file holds the key-value equivalents of the SQL in aggregates.go,
+// sync_state.go, relay_hints.go, graph_nodes.go, contact_lists.go,
+// retention_metadata.go, relay_capabilities.go and stats.go. Each Storage method dispatches here
+// when the active driver keeps its custom tables in a kvStore (LMDB).
+
+// Aggregates
+
+func (s *Storage) kvSaveAggregate(agg *Aggregate) error {
+	return s.kv.Update(func(txn kvTxn) error {
+		return kvPutJSON(txn, kvTableAggregates, agg.EventID, agg)
+	})
+}
+
+func (s *Storage) kvGetAggregate(eventID string) (*Aggregate, error) {
+	var agg Aggregate
+	if err := s.kv.View(func(txn kvTxn) error {
+		return kvGetJSON(txn, kvTableAggregates, eventID, &agg)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get aggregate: %w", err)
+	}
+	if agg.ReactionCounts == nil {
+		agg.ReactionCounts = make(map[string]int)
+	}
+	return &agg, nil
+}
+
+func (s *Storage) kvGetAggregates(eventIDs []string) (map[string]*Aggregate, error) {
+	aggregates := make(map[string]*Aggregate)
+	err := s.kv.View(func(txn kvTxn) error {
+		for _, id := range eventIDs {
+			var agg Aggregate
+			err := kvGetJSON(txn, kvTableAggregates, id, &agg)
+			if err == errKVNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if agg.ReactionCounts == nil {
+				agg.ReactionCounts = make(map[string]int)
+			}
+			aggregates[agg.EventID] = &agg
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query aggregates: %w", err)
+	}
+	return aggregates, nil
+}
+
+// kvUpdateAggregate applies fn to the aggregate for eventID (creating it if
+// missing) inside a single write transaction
+func kvUpdateAggregate(txn kvTxn, eventID string, fn func(agg *Aggregate)) error {
+	var agg Aggregate
+	err := kvGetJSON(txn, kvTableAggregates, eventID, &agg)
+	if err != nil && err != errKVNotFound {
+		return err
+	}
+	agg.EventID = eventID
+	if agg.ReactionCounts == nil {
+		agg.ReactionCounts = make(map[string]int)
+	}
+	fn(&agg)
+	return kvPutJSON(txn, kvTableAggregates, eventID, &agg)
+}
+
+func (s *Storage) kvIncrementReplies(updates map[string]int64) error {
+	return s.kv.Update(func(txn kvTxn) error {
+		for eventID, interactionAt := range updates {
+			if err := kvUpdateAggregate(txn, eventID, func(agg *Aggregate) {
+				agg.ReplyCount++
+				if interactionAt > agg.LastInteractionAt {
+					agg.LastInteractionAt = interactionAt
+				}
+			}); err != nil {
+				return fmt.Errorf("failed to increment reply for %s: %w", eventID, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Storage) kvIncrementReactions(updates map[string]map[string]int64) error {
+	return s.kv.Update(func(txn kvTxn) error {
+		for eventID, reactions := range updates {
+			if err := kvUpdateAggregate(txn, eventID, func(agg *Aggregate) {
+				for reaction, interactionAt := range reactions {
+					agg.ReactionCounts[reaction]++
+					agg.ReactionTotal++
+					if interactionAt > agg.LastInteractionAt {
+						agg.LastInteractionAt = interactionAt
+					}
+				}
+			}); err != nil {
+				return fmt.Errorf("failed to save aggregate for %s: %w", eventID, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Storage) kvAddZapAmount(eventID string, sats, interactionAt int64) error {
+	return s.kv.Update(func(txn kvTxn) error {
+		if err := kvUpdateAggregate(txn, eventID, func(agg *Aggregate) {
+			agg.ZapSatsTotal += sats
+			if interactionAt > agg.LastInteractionAt {
+				agg.LastInteractionAt = interactionAt
+			}
+		}); err != nil {
+			return fmt.Errorf("failed to add zap for %s: %w", eventID, err)
+		}
+		return nil
+	})
+}
+
+func (s *Storage) kvDeleteAggregate(eventID string) error {
+	return s.kv.Update(func(txn kvTxn) error {
+		return txn.Delete(kvTableAggregates, eventID)
+	})
+}
+
+// Sync state
+
+func syncStateKey(relay string, kind int) string {
+	return kvKey(relay, kvIntKey(kind))
+}
+
+func (s *Storage) kvSaveSyncState(state *SyncState) error {
+	return s.kv.Update(func(txn kvTxn) error {
+		return kvPutJSON(txn, kvTableSyncState, syncStateKey(state.Relay, state.Kind), state)
+	})
+}
+
+func (s *Storage) kvGetSyncState(relay string, kind int) (*SyncState, error) {
+	var state SyncState
+	if err := s.kv.View(func(txn kvTxn) error {
+		return kvGetJSON(txn, kvTableSyncState, syncStateKey(relay, kind), &state)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get sync state: %w", err)
+	}
+	return &state, nil
+}
+
+func (s *Storage) kvGetAllSyncStates() ([]*SyncState, error) {
+	var states []*SyncState
+	err := s.kv.View(func(txn kvTxn) error {
+		return txn.Scan(kvTableSyncState, "", func(_ string, value []byte) error {
+			var state SyncState
+			if err := unmarshalRecord(value, &state); err != nil {
+				return err
+			}
+			states = append(states, &state)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sync states: %w", err)
+	}
+	return states, nil
+}
+
+func (s *Storage) kvDeleteSyncState(relay string, kind int) error {
+	return s.kv.Update(func(txn kvTxn) error {
+		return txn.Delete(kvTableSyncState, syncStateKey(relay, kind))
+	})
+}
+
+// Relay hints
+
+func (s *Storage) kvSaveRelayHint(hint *RelayHint) error {
+	key := kvKey(hint.Pubkey, hint.Relay)
+	return s.kv.Update(func(txn kvTxn) error {
+		// Only replace an existing hint with a fresher one
+		var existing RelayHint
+		err := kvGetJSON(txn, kvTableRelayHints, key, &existing)
+		if err == nil && hint.Freshness <= existing.Freshness {
+			return nil
+		}
+		if err != nil && err != errKVNotFound {
+			return err
+		}
+		return kvPutJSON(txn, kvTableRelayHints, key, hint)
+	})
+}
+
+func (s *Storage) kvGetRelayHints(pubkey string) ([]*RelayHint, error) {
+	hints, err := s.kvScanRelayHints(pubkey + kvSep)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(hints, func(i, j int) bool {
+		return hints[i].Freshness > hints[j].Freshness
+	})
+	return hints, nil
+}
+
+func (s *Storage) kvAllRelayHints() ([]*RelayHint, error) {
+	return s.kvScanRelayHints("")
+}
+
+// kvScanRelayHints returns the hints whose key starts with prefix, in key
+// order, which for the empty prefix matches ORDER BY pubkey, relay
+func (s *Storage) kvScanRelayHints(prefix string) ([]*RelayHint, error) {
+	var hints []*RelayHint
+	err := s.kv.View(func(txn kvTxn) error {
+		return txn.Scan(kvTableRelayHints, prefix, func(_ string, value []byte) error {
+			var hint RelayHint
+			if err := unmarshalRecord(value, &hint); err != nil {
+				return err
+			}
+			hints = append(hints, &hint)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query relay hints: %w", err)
+	}
+	return hints, nil
+}
+
+func (s *Storage) kvGetRelays(pubkey string, write bool) ([]string, error) {
+	hints, err := s.kvGetRelayHints(pubkey)
+	if err != nil {
+		return nil, err
+	}
+
+	var relays []string
+	for _, hint := range hints {
+		if (write && hint.CanWrite) || (!write && hint.CanRead) {
+			relays = append(relays, hint.Relay)
+		}
+	}
+	return relays, nil
+}
+
+func (s *Storage) kvDeleteRelayHints(pubkey string) error {
+	return s.kv.Update(func(txn kvTxn) error {
+		return kvDeletePrefix(txn, kvTableRelayHints, pubkey+kvSep)
+	})
+}
+
+// Graph nodes
+
+func (s *Storage) kvSaveGraphNode(node *GraphNode) error {
+	return s.kv.Update(func(txn kvTxn) error {
+		return kvPutJSON(txn, kvTableGraphNodes, kvKey(node.RootPubkey, node.Pubkey), node)
+	})
+}
+
+func (s *Storage) kvUpdateGraphNodes(rootPubkey string, save []*GraphNode, remove []string) error {
+	return s.kv.Update(func(txn kvTxn) error {
+		for _, node := range save {
+			if err := kvPutJSON(txn, kvTableGraphNodes, kvKey(rootPubkey, node.Pubkey), node); err != nil {
+				return err
+			}
+		}
+		for _, pubkey := range remove {
+			if err := txn.Delete(kvTableGraphNodes, kvKey(rootPubkey, pubkey)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Storage) kvGetGraphNode(rootPubkey, targetPubkey string) (*GraphNode, error) {
+	var node GraphNode
+	if err := s.kv.View(func(txn kvTxn) error {
+		return kvGetJSON(txn, kvTableGraphNodes, kvKey(rootPubkey, targetPubkey), &node)
+	}); err != nil {
+		return nil, err // errKVNotFound is sql.ErrNoRows, matching the SQLite driver
+	}
+	return &node, nil
+}
+
+// kvScanGraphNodes returns the nodes under rootPubkey accepted by keep,
+// ordered by depth and then pubkey like the SQL queries
+func (s *Storage) kvScanGraphNodes(rootPubkey string, keep func(*GraphNode) bool) ([]*GraphNode, error) {
+	var nodes []*GraphNode
+	err := s.kv.View(func(txn kvTxn) error {
+		return txn.Scan(kvTableGraphNodes, rootPubkey+kvSep, func(_ string, value []byte) error {
+			var node GraphNode
+			if err := unmarshalRecord(value, &node); err != nil {
+				return err
+			}
+			if keep(&node) {
+				nodes = append(nodes, &node)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query graph nodes: %w", err)
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Depth != nodes[j].Depth {
+			return nodes[i].Depth < nodes[j].Depth
+		}
+		return nodes[i].Pubkey < nodes[j].Pubkey
+	})
+	return nodes, nil
+}
+
+// kvAllGraphNodes returns the nodes under every root, in key order, which
+// matches ORDER BY root_pubkey, pubkey
+func (s *Storage) kvAllGraphNodes() ([]*GraphNode, error) {
+	var nodes []*GraphNode
+	err := s.kv.View(func(txn kvTxn) error {
+		return txn.Scan(kvTableGraphNodes, "", func(_ string, value []byte) error {
+			var node GraphNode
+			if err := unmarshalRecord(value, &node); err != nil {
+				return err
+			}
+			nodes = append(nodes, &node)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query graph nodes: %w", err)
+	}
+	return nodes, nil
+}
+
+func graphNodePubkeys(nodes []*GraphNode) []string {
+	var pubkeys []string
+	for _, node := range nodes {
+		pubkeys = append(pubkeys, node.Pubkey)
+	}
+	return pubkeys
+}
+
+func (s *Storage) kvDeleteGraphNodes(rootPubkey string) error {
+	return s.kv.Update(func(txn kvTxn) error {
+		return kvDeletePrefix(txn, kvTableGraphNodes, rootPubkey+kvSep)
+	})
+}
+
+// Retention metadata
+
+func (s *Storage) kvStoreRetentionMetadata(meta *RetentionMetadata) error {
+	return s.kv.Update(func(txn kvTxn) error {
+		return kvPutJSON(txn, kvTableRetentionMetadata, meta.EventID, meta)
+	})
+}
+
+func (s *Storage) kvGetRetentionMetadata(eventID string) (*RetentionMetadata, error) {
+	var meta RetentionMetadata
+	err := s.kv.View(func(txn kvTxn) error {
+		return kvGetJSON(txn, kvTableRetentionMetadata, eventID, &meta)
+	})
+	if err == errKVNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retention metadata: %w", err)
+	}
+	return &meta, nil
+}
+
+// kvAllRetentionMetadata loads every retention record. LMDB reads are
+// memory-mapped, so a full scan is the equivalent of an unindexed SQL query.
+func (s *Storage) kvAllRetentionMetadata() ([]*RetentionMetadata, error) {
+	var metas []*RetentionMetadata
+	err := s.kv.View(func(txn kvTxn) error {
+		return txn.Scan(kvTableRetentionMetadata, "", func(_ string, value []byte) error {
+			var meta RetentionMetadata
+			if err := unmarshalRecord(value, &meta); err != nil {
+				return err
+			}
+			metas = append(metas, &meta)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan retention metadata: %w", err)
+	}
+	return metas, nil
+}
+
+func (s *Storage) kvGetExpiredEvents(limit int) ([]string, error) {
+	metas, err := s.kvAllRetentionMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var expired []*RetentionMetadata
+	for _, meta := range metas {
+		if meta.RetainUntil != nil && meta.RetainUntil.Before(now) && !meta.Protected {
+			expired = append(expired, meta)
+		}
+	}
+	sort.SliceStable(expired, func(i, j int) bool {
+		return expired[i].RetainUntil.Before(*expired[j].RetainUntil)
+	})
+
+	return retentionEventIDs(expired, limit), nil
+}
+
+func (s *Storage) kvGetEventsByScore(limit int) ([]*RetentionMetadata, error) {
+	metas, err := s.kvAllRetentionMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*RetentionMetadata
+	for _, meta := range metas {
+		if !meta.Protected {
+			results = append(results, meta)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score < results[j].Score
+	})
+
+	if limit >= 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (s *Storage) kvGetEventsNeedingEvaluation(ctx context.Context, limit int) ([]string, error) {
+	// Walk events newest first and keep those without metadata
+	events, err := s.QueryEvents(ctx, nostr.Filter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events needing evaluation: %w", err)
+	}
+
+	var eventIDs []string
+	err = s.kv.View(func(txn kvTxn) error {
+		for _, event := range events {
+			if limit >= 0 && len(eventIDs) >= limit {
+				return nil
+			}
+			_, err := txn.Get(kvTableRetentionMetadata, event.ID)
+			if err == errKVNotFound {
+				eventIDs = append(eventIDs, event.ID)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events needing evaluation: %w", err)
+	}
+	return eventIDs, nil
+}
+
+func (s *Storage) kvGetEventsForReEvaluation(olderThan time.Time, limit int) ([]string, error) {
+	metas, err := s.kvAllRetentionMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := olderThan.Unix()
+	var stale []*RetentionMetadata
+	for _, meta := range metas {
+		if meta.LastEvaluatedAt.Unix() < cutoff {
+			stale = append(stale, meta)
+		}
+	}
+	sort.SliceStable(stale, func(i, j int) bool {
+		return stale[i].LastEvaluatedAt.Before(stale[j].LastEvaluatedAt)
+	})
+
+	return retentionEventIDs(stale, limit), nil
+}
+
+func (s *Storage) kvCountRetentionStats() (map[string]interface{}, error) {
+	metas, err := s.kvAllRetentionMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	sevenDaysFromNow := time.Now().Add(7 * 24 * time.Hour)
+	protected := 0
+	expiringWithin7d := 0
+	byRule := make(map[string]int)
+	for _, meta := range metas {
+		if meta.Protected {
+			protected++
+		}
+		if meta.RetainUntil != nil && meta.RetainUntil.Before(sevenDaysFromNow) {
+			expiringWithin7d++
+		}
+		byRule[meta.RuleName]++
+	}
+
+	return map[string]interface{}{
+		"total_events":       len(metas),
+		"protected_events":   protected,
+		"expiring_within_7d": expiringWithin7d,
+		"by_rule":            byRule,
+	}, nil
+}
+
+func (s *Storage) kvDeleteRetentionMetadata(eventID string) error {
+	return s.kv.Update(func(txn kvTxn) error {
+		return txn.Delete(kvTableRetentionMetadata, eventID)
+	})
+}
+
+func retentionEventIDs(metas []*RetentionMetadata, limit int) []string {
+	var eventIDs []string
+	for _, meta := range metas {
+		if limit >= 0 && len(eventIDs) >= limit {
+			break
+		}
+		eventIDs = append(eventIDs, meta.EventID)
+	}
+	return eventIDs
+}
+
+// Relay capabilities
+
+func (s *Storage) kvGetRelayCapabilities(url string) (*RelayCapabilities, error) {
+	var caps RelayCapabilities
+	err := s.kv.View(func(txn kvTxn) error {
+		return kvGetJSON(txn, kvTableRelayCapabilities, url, &caps)
+	})
+	if err == errKVNotFound {
+		return nil, nil // No cached data
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query relay capabilities: %w", err)
+	}
+	return &caps, nil
+}
+
+func (s *Storage) kvSaveRelayCapabilities(caps *RelayCapabilities) error {
+	return s.kv.Update(func(txn kvTxn) error {
+		return kvPutJSON(txn, kvTableRelayCapabilities, caps.URL, caps)
+	})
+}
+
+// Deletions
+
+func (s *Storage) kvSaveDeletion(d *Deletion) error {
+	return s.kv.Update(func(txn kvTxn) error {
+		key := kvKey(d.Target, d.Pubkey)
+
+		var existing Deletion
+		err := kvGetJSON(txn, kvTableDeletions, key, &existing)
+		if err == nil && existing.CreatedAt >= d.CreatedAt {
+			return nil // keep the newest request
+		}
+		if err != nil && err != errKVNotFound {
+			return err
+		}
+
+		return kvPutJSON(txn, kvTableDeletions, key, d)
+	})
+}
+
+func (s *Storage) kvGetDeletion(target, pubkey string) (*Deletion, error) {
+	var d Deletion
+	err := s.kv.View(func(txn kvTxn) error {
+		return kvGetJSON(txn, kvTableDeletions, kvKey(target, pubkey), &d)
+	})
+	if err == errKVNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deletion: %w", err)
+	}
+	return &d, nil
+}
+
+func (s *Storage) kvDeletionAuthors() ([]string, error) {
+	seen := make(map[string]bool)
+	var authors []string
+	err := s.kv.View(func(txn kvTxn) error {
+		return txn.Scan(kvTableDeletions, "", func(key string, _ []byte) error {
+			pubkey := key[strings.LastIndex(key, kvSep)+len(kvSep):]
+			if !seen[pubkey] {
+				seen[pubkey] = true
+				authors = append(authors, pubkey)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deletion authors: %w", err)
+	}
+	return authors, nil
+}
+
+// Zaps
+
+func (s *Storage) kvSaveZap(z *Zap) (bool, error) {
+	key := zapProfilePrefix(z.Recipient) + z.ReceiptID
+	if z.EventID != "" {
+		key = zapEventPrefix(z.EventID) + z.ReceiptID
+	}
+
+	inserted := false
+	err := s.kv.Update(func(txn kvTxn) error {
+		_, err := txn.Get(kvTableZaps, key)
+		if err == nil {
+			return nil // already recorded
+		}
+		if err != errKVNotFound {
+			return err
+		}
+
+		inserted = true
+		return kvPutJSON(txn, kvTableZaps, key, z)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to save zap: %w", err)
+	}
+	return inserted, nil
+}
+
+func (s *Storage) kvGetZaps(prefix string) ([]*Zap, error) {
+	var zaps []*Zap
+	err := s.kv.View(func(txn kvTxn) error {
+		return txn.Scan(kvTableZaps, prefix, func(_ string, value []byte) error {
+			var z Zap
+			if err := unmarshalRecord(value, &z); err != nil {
+				return err
+			}
+			zaps = append(zaps, &z)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query zaps: %w", err)
+	}
+
+	sort.SliceStable(zaps, func(i, j int) bool {
+		return zaps[i].CreatedAt > zaps[j].CreatedAt
+	})
+	return zaps, nil
+}
+
+// Contact lists and follows
+
+// followEdge is the record stored under both directions of a follow
+type followEdge struct {
+	Follower string
+	Followee string
+}
+
+func (s *Storage) kvGetContactList(pubkey string) (*ContactList, error) {
+	var list ContactList
+	if err := s.kv.View(func(txn kvTxn) error {
+		return kvGetJSON(txn, kvTableContactLists, pubkey, &list)
+	}); err != nil {
+		return nil, err // errKVNotFound is sql.ErrNoRows, matching the SQLite driver
+	}
+	return &list, nil
+}
+
+func (s *Storage) kvAllContactLists() ([]*ContactList, error) {
+	var lists []*ContactList
+	err := s.kv.View(func(txn kvTxn) error {
+		return txn.Scan(kvTableContactLists, "", func(_ string, value []byte) error {
+			var list ContactList
+			if err := unmarshalRecord(value, &list); err != nil {
+				return err
+			}
+			lists = append(lists, &list)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query contact lists: %w", err)
+	}
+	return lists, nil
+}
+
+func (s *Storage) kvSaveContactList(list *ContactList, added, removed []string) error {
+	err := s.kv.Update(func(txn kvTxn) error {
+		if err := kvPutJSON(txn, kvTableContactLists, list.Pubkey, list); err != nil {
+			return err
+		}
+		for _, followee := range added {
+			edge := &followEdge{Follower: list.Pubkey, Followee: followee}
+			if err := kvPutJSON(txn, kvTableFollows, kvKey(list.Pubkey, followee), edge); err != nil {
+				return err
+			}
+			if err := kvPutJSON(txn, kvTableFollowers, kvKey(followee, list.Pubkey), edge); err != nil {
+				return err
+			}
+		}
+		for _, followee := range removed {
+			if err := txn.Delete(kvTableFollows, kvKey(list.Pubkey, followee)); err != nil {
+				return err
+			}
+			if err := txn.Delete(kvTableFollowers, kvKey(followee, list.Pubkey)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save contact list: %w", err)
+	}
+	return nil
+}
+
+// kvScanFollows returns the other end of every edge keyed under pubkey in
+// table (follows or followers), in key order
+func (s *Storage) kvScanFollows(table, pubkey string) ([]string, error) {
+	var pubkeys []string
+	prefix := pubkey + kvSep
+	err := s.kv.View(func(txn kvTxn) error {
+		return txn.Scan(table, prefix, func(key string, _ []byte) error {
+			pubkeys = append(pubkeys, key[len(prefix):])
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query follows: %w", err)
+	}
+	return pubkeys, nil
+}
+
+func (s *Storage) kvIsFollowing(follower, followee string) (bool, error) {
+	found := false
+	err := s.kv.View(func(txn kvTxn) error {
+		_, err := txn.Get(kvTableFollows, kvKey(follower, followee))
+		if err == errKVNotFound {
+			return nil
+		}
+		found = err == nil
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check follow: %w", err)
+	}
+	return found, nil
+}
+
+func (s *Storage) kvAllFollows() (map[string][]string, error) {
+	follows := make(map[string][]string)
+	err := s.kv.View(func(txn kvTxn) error {
+		return txn.Scan(kvTableFollows, "", func(_ string, value []byte) error {
+			var edge followEdge
+			if err := unmarshalRecord(value, &edge); err != nil {
+				return err
+			}
+			follows[edge.Follower] = append(follows[edge.Follower], edge.Followee)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query follows: %w", err)
+	}
+	return follows, nil
+}
+
+// Event statistics (the LMDB eventstore has no SQL, so these walk QueryEvents)
+
+// kvEachEvent calls fn for every event matching filter, newest first. It pages
+// through the eventstore with a created_at cursor, so at most one page of
+// events is held at a time. filter.Limit is ignored.
+func (s *Storage) kvEachEvent(ctx context.Context, filter nostr.Filter, fn func(*nostr.Event) error) error {
+	page := filter
+	pageSize := kvEventPage
+	seen := make(map[string]bool) // events already passed at the cursor's second
+
+	for {
+		page.Limit = pageSize
+		events, err := s.QueryEvents(ctx, page)
+		if err != nil {
+			return fmt.Errorf("failed to scan events: %w", err)
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		cursor := events[0].CreatedAt
+		fresh := 0
+		for _, event := range events {
+			if event.CreatedAt < cursor {
+				cursor = event.CreatedAt
+			}
+			if seen[event.ID] {
+				continue
+			}
+			fresh++
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+
+		if len(events) < pageSize {
+			return nil
+		}
+
+		// The next page starts at the oldest second reached, which may hold
+		// events this page did not get to
+		if page.Until == nil || *page.Until != cursor {
+			seen = make(map[string]bool)
+		}
+		for _, event := range events {
+			if event.CreatedAt == cursor {
+				seen[event.ID] = true
+			}
+		}
+		page.Until = &cursor
+
+		// A single second holding more than a page needs bigger pages
+		if fresh == 0 {
+			if pageSize >= kvMaxQueryLimit {
+				return fmt.Errorf("failed to scan events: more than %d events at %d", kvMaxQueryLimit, cursor)
+			}
+			pageSize = min(pageSize*2, kvMaxQueryLimit)
+		}
+	}
+}
+
+// kvScanEvents pages through the matching events with kvEachEvent, newest
+// first like ScanEvents on SQLite
+func (s *Storage) kvScanEvents(ctx context.Context, filter nostr.Filter, fn func(*nostr.Event) error) error {
+	return s.kvEachEvent(ctx, nostr.Filter{
+		Kinds:   filter.Kinds,
+		Authors: filter.Authors,
+		Since:   filter.Since,
+		Until:   filter.Until,
+	}, fn)
+}
+
+func (s *Storage) kvCountEventsByKind(ctx context.Context) (map[int]int64, error) {
+	counts := make(map[int]int64)
+	err := s.kvEachEvent(ctx, nostr.Filter{}, func(event *nostr.Event) error {
+		counts[event.Kind]++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// kvCountEvents counts the events matching filter
+func (s *Storage) kvCountEvents(ctx context.Context, filter nostr.Filter) (int64, error) {
+	var count int64
+	err := s.kvEachEvent(ctx, filter, func(*nostr.Event) error {
+		count++
+		return nil
+	})
+	return count, err
+}
+
+func (s *Storage) kvEventTimeRange(ctx context.Context) (*time.Time, *time.Time, error) {
+	var oldestUnix, newestUnix nostr.Timestamp
+	found := false
+	err := s.kvEachEvent(ctx, nostr.Filter{}, func(event *nostr.Event) error {
+		if !found || event.CreatedAt < oldestUnix {
+			oldestUnix = event.CreatedAt
+		}
+		if !found || event.CreatedAt > newestUnix {
+			newestUnix = event.CreatedAt
+		}
+		found = true
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found {
+		return nil, nil, nil
+	}
+
+	oldest, newest := oldestUnix.Time(), newestUnix.Time()
+	return &oldest, &newest, nil
+}
+
+// kvDeleteEvents deletes every event matched by filter through the relay's
+// DeleteEvent handlers and returns how many were removed
+func (s *Storage) kvDeleteEvents(ctx context.Context, filter nostr.Filter) (int64, error) {
+	var deleted int64
+	err := s.kvEachEvent(ctx, filter, func(event *nostr.Event) error {
+		for _, handler := range s.relay.DeleteEvent {
+			if err := handler(ctx, event); err != nil {
+				return fmt.Errorf("failed to delete events: %w", err)
+			}
+		}
+		deleted++
+		return nil
+	})
+	return deleted, err
+}
+
+// unmarshalRecord decodes a JSON record read during a scan
+func unmarshalRecord(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode record: %w", err)
+	}
+	return nil
+}
+
+// kvDirSize sums the size of the files under a directory-based store
+func kvDirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
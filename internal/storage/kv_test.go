@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/fiatjaf/eventstore/slicestore"
+	"github.com/fiatjaf/khatru"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/config"
+)
+
+// memoryKV is an in-memory kvStore used to exercise the key-value code paths
+// of the LMDB driver without cgo
+type memoryKV struct {
+	tables map[string]map[string][]byte
+}
+
+func newMemoryKV() *memoryKV {
+	m := &memoryKV{tables: make(map[string]map[string][]byte)}
+	for _, table := range kvTables {
+		m.tables[table] = make(map[string][]byte)
+	}
+	return m
+}
+
+func (m *memoryKV) View(fn func(txn kvTxn) error) error {
+	return fn(&memoryTxn{tables: m.tables})
+}
+
+func (m *memoryKV) Update(fn func(txn kvTxn) error) error {
+	// Writes go to a copy so a failing fn leaves the store untouched
+	staged := make(map[string]map[string][]byte, len(m.tables))
+	for name, table := range m.tables {
+		copied := make(map[string][]byte, len(table))
+		for k, v := range table {
+			copied[k] = v
+		}
+		staged[name] = copied
+	}
+
+	if err := fn(&memoryTxn{tables: staged}); err != nil {
+		return err
+	}
+
+	m.tables = staged
+	return nil
+}
+
+func (m *memoryKV) Close() error {
+	return nil
+}
+
+type memoryTxn struct {
+	tables map[string]map[string][]byte
+}
+
+func (t *memoryTxn) table(name string) (map[string][]byte, error) {
+	table, ok := t.tables[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown table: %s", name)
+	}
+	return table, nil
+}
+
+func (t *memoryTxn) Get(table, key string) ([]byte, error) {
+	tbl, err := t.table(table)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := tbl[key]
+	if !ok {
+		return nil, errKVNotFound
+	}
+	return value, nil
+}
+
+func (t *memoryTxn) Put(table, key string, value []byte) error {
+	tbl, err := t.table(table)
+	if err != nil {
+		return err
+	}
+	tbl[key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (t *memoryTxn) Delete(table, key string) error {
+	tbl, err := t.table(table)
+	if err != nil {
+		return err
+	}
+	delete(tbl, key)
+	return nil
+}
+
+func (t *memoryTxn) Scan(table, prefix string, fn func(key string, value []byte) error) error {
+	tbl, err := t.table(table)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(tbl))
+	for key := range tbl {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := fn(key, tbl[key]); err != nil {
+			if err == errKVStop {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// setupMemoryKVStorage builds a Storage that uses the key-value code paths
+// with an in-memory event store and in-memory custom tables
+func setupMemoryKVStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	db := &slicestore.SliceStore{MaxLimit: kvMaxQueryLimit}
+	if err := db.Init(); err != nil {
+		t.Fatalf("Failed to init slicestore: %v", err)
+	}
+
+	relay := khatru.NewRelay()
+	relay.StoreEvent = append(relay.StoreEvent, db.SaveEvent)
+	relay.QueryEvents = append(relay.QueryEvents, db.QueryEvents)
+	relay.DeleteEvent = append(relay.DeleteEvent, db.DeleteEvent)
+
+	return &Storage{
+		relay: relay,
+		query: db.QueryEvents,
+		kv:    newMemoryKV(),
+		config: &config.Storage{
+			Driver:   "lmdb",
+			LMDBPath: t.TempDir(),
+		},
+	}
+}
+
+func TestKVScanPrefixOrder(t *testing.T) {
+	kv := newMemoryKV()
+
+	err := kv.Update(func(txn kvTxn) error {
+		for _, key := range []string{kvKey("b", "2"), kvKey("a", "2"), kvKey("a", "1"), kvKey("ab", "1")} {
+			if err := txn.Put(kvTableSyncState, key, []byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	var keys []string
+	err = kv.View(func(txn kvTxn) error {
+		return txn.Scan(kvTableSyncState, "a"+kvSep, func(key string, _ []byte) error {
+			keys = append(keys, key)
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	want := []string{kvKey("a", "1"), kvKey("a", "2")}
+	if strings.Join(keys, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected keys %q, got %q", want, keys)
+	}
+}
+
+func TestKVUpdateRollback(t *testing.T) {
+	kv := newMemoryKV()
+
+	err := kv.Update(func(txn kvTxn) error {
+		if err := txn.Put(kvTableAggregates, "event", []byte("{}")); err != nil {
+			return err
+		}
+		return fmt.Errorf("abort")
+	})
+	if err == nil {
+		t.Fatal("Expected Update to return the callback error")
+	}
+
+	err = kv.View(func(txn kvTxn) error {
+		_, err := txn.Get(kvTableAggregates, "event")
+		return err
+	})
+	if err != errKVNotFound {
+		t.Errorf("Expected write to be rolled back, got err=%v", err)
+	}
+}
+
+func TestKVEachEventPages(t *testing.T) {
+	s := setupMemoryKVStorage(t)
+	ctx := context.Background()
+
+	defer func(page int) { kvEventPage = page }(kvEventPage)
+	kvEventPage = 3
+
+	// Seven events share a second, more than a page holds
+	for i := 0; i < 12; i++ {
+		createdAt := nostr.Timestamp(1000 + i)
+		if i < 7 {
+			createdAt = 1000
+		}
+		event := &nostr.Event{
+			ID:        fmt.Sprintf("%064x", i),
+			PubKey:    strings.Repeat("f", 64),
+			CreatedAt: createdAt,
+			Kind:      1,
+			Tags:      nostr.Tags{},
+			Sig:       strings.Repeat("c", 128),
+		}
+		if err := s.StoreEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to store event: %v", err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	last := nostr.Timestamp(math.MaxInt64)
+	err := s.kvEachEvent(ctx, nostr.Filter{}, func(event *nostr.Event) error {
+		if seen[event.ID] {
+			t.Errorf("Event %s visited twice", event.ID)
+		}
+		if event.CreatedAt > last {
+			t.Errorf("Events out of order: %d after %d", event.CreatedAt, last)
+		}
+		seen[event.ID] = true
+		last = event.CreatedAt
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("kvEachEvent failed: %v", err)
+	}
+
+	if len(seen) != 12 {
+		t.Errorf("Expected 12 events, got %d", len(seen))
+	}
+}
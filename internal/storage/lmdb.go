@@ -1,13 +1,226 @@
+//go:build lmdb
+
 package storage
 
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+	eventstorelmdb "github.com/fiatjaf/eventstore/lmdb"
+	"github.com/fiatjaf/khatru"
 )
 
-// initLMDB initializes the LMDB backend with Khatru
+// LMDBAvailable reports whether this binary was built with LMDB support
+const LMDBAvailable = true
+
+// initLMDB initializes the LMDB backend with Khatru.
+//
+// The lmdb_path directory holds two environments:
+//
+//	events/  the eventstore LMDB adapter used by the Khatru relay
+//	tables/  nophr's custom tables (aggregates, sync_state, relay_hints,
+//	         graph_nodes, retention_metadata, relay_capabilities)
 func (s *Storage) initLMDB(ctx context.Context) error {
-	// LMDB support is optional and not implemented in Phase 2
-	// This is a placeholder for future implementation
-	return fmt.Errorf("LMDB support not yet implemented - please use SQLite")
+	basePath := s.config.LMDBPath
+	if basePath == "" {
+		return fmt.Errorf("lmdb_path is required for the lmdb driver")
+	}
+
+	mapSize := int64(s.config.LMDBMaxSizeMB) * 1024 * 1024
+
+	// Initialize LMDB eventstore for Khatru
+	db := &eventstorelmdb.LMDBBackend{
+		Path:    filepath.Join(basePath, "events"),
+		MapSize: mapSize,
+		// Whole-store scans page through kvEachEvent instead of relying on
+		// one unbounded query
+		MaxLimit: kvMaxQueryLimit,
+	}
+
+	if err := db.Init(); err != nil {
+		return fmt.Errorf("failed to initialize LMDB eventstore: %w", err)
+	}
+
+	// Create Khatru relay instance
+	relay := khatru.NewRelay()
+	relay.StoreEvent = append(relay.StoreEvent, db.SaveEvent)
+	relay.QueryEvents = append(relay.QueryEvents, db.QueryEvents)
+	relay.DeleteEvent = append(relay.DeleteEvent, db.DeleteEvent)
+
+	s.relay = relay
+	s.query = db.QueryEvents
+
+	// Open a separate environment for custom tables
+	kv, err := openLMDBKV(filepath.Join(basePath, "tables"), mapSize)
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("failed to open LMDB custom tables: %w", err)
+	}
+	kv.events = db
+
+	s.kv = kv
+	return nil
+}
+
+// lmdbKV implements kvStore with one named LMDB database per custom table
+type lmdbKV struct {
+	env    *lmdb.Env
+	dbis   map[string]lmdb.DBI
+	events *eventstorelmdb.LMDBBackend
+}
+
+func openLMDBKV(path string, mapSize int64) (*lmdbKV, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	env, err := lmdb.NewEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := env.SetMaxDBs(len(kvTables)); err != nil {
+		env.Close()
+		return nil, err
+	}
+	if mapSize > 0 {
+		if err := env.SetMapSize(mapSize); err != nil {
+			env.Close()
+			return nil, err
+		}
+	}
+
+	if err := env.Open(path, lmdb.NoTLS, 0644); err != nil {
+		env.Close()
+		return nil, err
+	}
+
+	kv := &lmdbKV{
+		env:  env,
+		dbis: make(map[string]lmdb.DBI, len(kvTables)),
+	}
+
+	if err := env.Update(func(txn *lmdb.Txn) error {
+		for _, table := range kvTables {
+			dbi, err := txn.OpenDBI(table, lmdb.Create)
+			if err != nil {
+				return fmt.Errorf("failed to open table %s: %w", table, err)
+			}
+			kv.dbis[table] = dbi
+		}
+		return nil
+	}); err != nil {
+		env.Close()
+		return nil, err
+	}
+
+	return kv, nil
+}
+
+func (k *lmdbKV) View(fn func(txn kvTxn) error) error {
+	return k.env.View(func(txn *lmdb.Txn) error {
+		return fn(&lmdbTxn{kv: k, txn: txn})
+	})
+}
+
+func (k *lmdbKV) Update(fn func(txn kvTxn) error) error {
+	return k.env.Update(func(txn *lmdb.Txn) error {
+		return fn(&lmdbTxn{kv: k, txn: txn})
+	})
+}
+
+func (k *lmdbKV) Close() error {
+	if k.events != nil {
+		k.events.Close()
+	}
+	return k.env.Close()
+}
+
+type lmdbTxn struct {
+	kv  *lmdbKV
+	txn *lmdb.Txn
+}
+
+func (t *lmdbTxn) dbi(table string) (lmdb.DBI, error) {
+	dbi, ok := t.kv.dbis[table]
+	if !ok {
+		return 0, fmt.Errorf("unknown table: %s", table)
+	}
+	return dbi, nil
+}
+
+func (t *lmdbTxn) Get(table, key string) ([]byte, error) {
+	dbi, err := t.dbi(table)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := t.txn.Get(dbi, []byte(key))
+	if lmdb.IsNotFound(err) {
+		return nil, errKVNotFound
+	}
+	return value, err
+}
+
+func (t *lmdbTxn) Put(table, key string, value []byte) error {
+	dbi, err := t.dbi(table)
+	if err != nil {
+		return err
+	}
+	return t.txn.Put(dbi, []byte(key), value, 0)
+}
+
+func (t *lmdbTxn) Delete(table, key string) error {
+	dbi, err := t.dbi(table)
+	if err != nil {
+		return err
+	}
+
+	err = t.txn.Del(dbi, []byte(key), nil)
+	if lmdb.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (t *lmdbTxn) Scan(table, prefix string, fn func(key string, value []byte) error) error {
+	dbi, err := t.dbi(table)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := t.txn.OpenCursor(dbi)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	var k, v []byte
+	if prefix == "" {
+		k, v, err = cursor.Get(nil, nil, lmdb.First)
+	} else {
+		k, v, err = cursor.Get([]byte(prefix), nil, lmdb.SetRange)
+	}
+
+	for ; err == nil; k, v, err = cursor.Get(nil, nil, lmdb.Next) {
+		key := string(k)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		if err := fn(key, v); err != nil {
+			if err == errKVStop {
+				return nil
+			}
+			return err
+		}
+	}
+
+	if lmdb.IsNotFound(err) {
+		return nil
+	}
+	return err
 }
@@ -0,0 +1,17 @@
+//go:build !lmdb
+
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// LMDBAvailable reports whether this binary was built with LMDB support
+const LMDBAvailable = false
+
+// initLMDB reports that LMDB support was not compiled in. LMDB needs cgo and
+// the lmdb-go bindings, so it is only built with `-tags lmdb`.
+func (s *Storage) initLMDB(ctx context.Context) error {
+	return fmt.Errorf("this binary was built without LMDB support - rebuild with `-tags lmdb` or use SQLite")
+}
@@ -0,0 +1,35 @@
+//go:build lmdb
+
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/sandwichfarm/nophr/internal/config"
+)
+
+func init() {
+	testDrivers = append(testDrivers, testDriver{
+		name:  "lmdb",
+		setup: setupLMDBStorage,
+	})
+}
+
+func setupLMDBStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	cfg := &config.Storage{
+		Driver:        "lmdb",
+		LMDBPath:      filepath.Join(t.TempDir(), "test.lmdb"),
+		LMDBMaxSizeMB: 64,
+	}
+
+	s, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Failed to create LMDB storage: %v", err)
+	}
+
+	return s
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sandwichfarm/nophr/internal/config"
+)
+
+// ErrLocked is returned by Lock when another process holds the database
+var ErrLocked = errors.New("database is in use by another nophr process")
+
+// lockPath returns the lock file guarding the configured database, or ""
+// when there is nothing on disk to guard
+func lockPath(cfg *config.Storage) string {
+	switch cfg.Driver {
+	case "sqlite":
+		if cfg.SQLitePath == "" || cfg.SQLitePath == ":memory:" || strings.HasPrefix(cfg.SQLitePath, "file::memory:") {
+			return ""
+		}
+		return cfg.SQLitePath + ".lock"
+	case "lmdb":
+		if cfg.LMDBPath == "" {
+			return ""
+		}
+		return filepath.Join(cfg.LMDBPath, "nophr.lock")
+	default:
+		return ""
+	}
+}
+
+// Lock takes an exclusive lock on the configured database so that the server
+// and operator commands never use it at the same time. It fails with
+// ErrLocked, naming the holder's pid, if another process has it. The lock is
+// held until release is called or the process exits.
+func Lock(cfg *config.Storage) (release func() error, err error) {
+	path := lockPath(cfg)
+	if path == "" {
+		return func() error { return nil }, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := lockFile(f); err != nil {
+		defer f.Close()
+		if errors.Is(err, ErrLocked) {
+			holder, _ := io.ReadAll(f)
+			if pid := strings.TrimSpace(string(holder)); pid != "" {
+				return nil, fmt.Errorf("%w (pid %s, %s)", ErrLocked, pid, path)
+			}
+			return nil, fmt.Errorf("%w (%s)", ErrLocked, path)
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	// Record the holder for the error message of the next process
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	return func() error {
+		unlockFile(f)
+		return f.Close()
+	}, nil
+}
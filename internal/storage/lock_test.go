@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sandwichfarm/nophr/internal/config"
+)
+
+func TestLock(t *testing.T) {
+	cfg := &config.Storage{
+		Driver:     "sqlite",
+		SQLitePath: filepath.Join(t.TempDir(), "data", "nophr.db"),
+	}
+
+	release, err := Lock(cfg)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	_, err = Lock(cfg)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked while the database is held, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "pid "+strconv.Itoa(os.Getpid())) {
+		t.Errorf("expected the holder's pid in %q", err)
+	}
+
+	if err := release(); err != nil {
+		t.Fatalf("release() error = %v", err)
+	}
+
+	release, err = Lock(cfg)
+	if err != nil {
+		t.Fatalf("expected the lock to be free after release, got %v", err)
+	}
+	release()
+
+	// In-memory databases have nothing to guard
+	memory := &config.Storage{Driver: "sqlite", SQLitePath: ":memory:"}
+	for i := 0; i < 2; i++ {
+		release, err := Lock(memory)
+		if err != nil {
+			t.Fatalf("Lock(:memory:) error = %v", err)
+		}
+		defer release()
+	}
+}
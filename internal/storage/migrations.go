@@ -7,84 +7,21 @@ import (
 
 // runMigrations creates the custom tables for nophr
 func (s *Storage) runMigrations(ctx context.Context) error {
+	// Key-value drivers create their tables when the environment is opened
+	if s.kv != nil {
+		return nil
+	}
+
 	if s.db == nil {
 		return fmt.Errorf("database not initialized")
 	}
 
-	migrations := []string{
-		// relay_hints: Track which relays to use for each author (from NIP-65)
-		`CREATE TABLE IF NOT EXISTS relay_hints (
-			pubkey TEXT NOT NULL,
-			relay TEXT NOT NULL,
-			can_read INTEGER NOT NULL DEFAULT 1,
-			can_write INTEGER NOT NULL DEFAULT 1,
-			freshness INTEGER NOT NULL,
-			last_seen_event_id TEXT NOT NULL,
-			PRIMARY KEY (pubkey, relay)
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_relay_hints_pubkey_freshness
-		 ON relay_hints(pubkey, freshness DESC)`,
-
-		// graph_nodes: Owner-centric social graph cache
-		`CREATE TABLE IF NOT EXISTS graph_nodes (
-			root_pubkey TEXT NOT NULL,
-			pubkey TEXT NOT NULL,
-			depth INTEGER NOT NULL,
-			mutual INTEGER NOT NULL DEFAULT 0,
-			last_seen INTEGER NOT NULL,
-			PRIMARY KEY (root_pubkey, pubkey)
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_graph_nodes_root_depth_mutual
-		 ON graph_nodes(root_pubkey, depth, mutual)`,
-
-		// sync_state: Cursor tracking per relay/kind
-		`CREATE TABLE IF NOT EXISTS sync_state (
-			relay TEXT NOT NULL,
-			kind INTEGER NOT NULL,
-			since INTEGER NOT NULL,
-			updated_at INTEGER NOT NULL,
-			PRIMARY KEY (relay, kind)
-		)`,
-
-		// aggregates: Interaction rollups (reply counts, reactions, zaps)
-		`CREATE TABLE IF NOT EXISTS aggregates (
-			event_id TEXT PRIMARY KEY,
-			reply_count INTEGER NOT NULL DEFAULT 0,
-			reaction_total INTEGER NOT NULL DEFAULT 0,
-			reaction_counts_json TEXT,
-			zap_sats_total INTEGER NOT NULL DEFAULT 0,
-			last_interaction_at INTEGER NOT NULL
-		)`,
-
-		// retention_metadata: Advanced retention metadata (Phase 20)
-		`CREATE TABLE IF NOT EXISTS retention_metadata (
-			event_id TEXT PRIMARY KEY,
-			rule_name TEXT NOT NULL,
-			rule_priority INTEGER NOT NULL,
-			retain_until INTEGER,
-			last_evaluated_at INTEGER NOT NULL,
-			score INTEGER,
-			protected BOOLEAN DEFAULT 0,
-			FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_retention_metadata_retain_until
-		 ON retention_metadata(retain_until)`,
-		`CREATE INDEX IF NOT EXISTS idx_retention_metadata_score
-		 ON retention_metadata(score)`,
-		`CREATE INDEX IF NOT EXISTS idx_retention_metadata_protected
-		 ON retention_metadata(protected)`,
-
-		// relay_capabilities: Track relay feature support (NIP-77, etc.)
-		`CREATE TABLE IF NOT EXISTS relay_capabilities (
-			url TEXT PRIMARY KEY,
-			supports_negentropy INTEGER NOT NULL DEFAULT 0,
-			nip11_software TEXT,
-			nip11_version TEXT,
-			last_checked INTEGER NOT NULL,
-			check_expiry INTEGER NOT NULL
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_relay_capabilities_expiry
-		 ON relay_capabilities(check_expiry)`,
+	var version int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version > SchemaVersion() {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d)", version, SchemaVersion())
 	}
 
 	for i, migration := range migrations {
@@ -93,5 +30,136 @@ func (s *Storage) runMigrations(ctx context.Context) error {
 		}
 	}
 
-	return nil
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", SchemaVersion())); err != nil {
+		return fmt.Errorf("failed to set schema version: %w", err)
+	}
+
+	// event_search: FTS5 full-text index (see search_index.go)
+	return s.migrateSearchIndex(ctx)
+}
+
+// SchemaVersion is the version runMigrations brings a SQLite database to,
+// recorded in its user_version pragma. Databases from before versioning
+// report 0.
+func SchemaVersion() int {
+	return len(migrations)
+}
+
+// migrations create the custom tables. They are applied in order on every
+// start and must stay idempotent; append new ones at the end, as the schema
+// version is their count.
+var migrations = []string{
+	// relay_hints: Track which relays to use for each author (from NIP-65)
+	`CREATE TABLE IF NOT EXISTS relay_hints (
+		pubkey TEXT NOT NULL,
+		relay TEXT NOT NULL,
+		can_read INTEGER NOT NULL DEFAULT 1,
+		can_write INTEGER NOT NULL DEFAULT 1,
+		freshness INTEGER NOT NULL,
+		last_seen_event_id TEXT NOT NULL,
+		PRIMARY KEY (pubkey, relay)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_relay_hints_pubkey_freshness
+	 ON relay_hints(pubkey, freshness DESC)`,
+
+	// graph_nodes: Owner-centric social graph cache
+	`CREATE TABLE IF NOT EXISTS graph_nodes (
+		root_pubkey TEXT NOT NULL,
+		pubkey TEXT NOT NULL,
+		depth INTEGER NOT NULL,
+		mutual INTEGER NOT NULL DEFAULT 0,
+		last_seen INTEGER NOT NULL,
+		PRIMARY KEY (root_pubkey, pubkey)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_graph_nodes_root_depth_mutual
+	 ON graph_nodes(root_pubkey, depth, mutual)`,
+
+	// sync_state: Cursor tracking per relay/kind
+	`CREATE TABLE IF NOT EXISTS sync_state (
+		relay TEXT NOT NULL,
+		kind INTEGER NOT NULL,
+		since INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL,
+		PRIMARY KEY (relay, kind)
+	)`,
+
+	// aggregates: Interaction rollups (reply counts, reactions, zaps)
+	`CREATE TABLE IF NOT EXISTS aggregates (
+		event_id TEXT PRIMARY KEY,
+		reply_count INTEGER NOT NULL DEFAULT 0,
+		reaction_total INTEGER NOT NULL DEFAULT 0,
+		reaction_counts_json TEXT,
+		zap_sats_total INTEGER NOT NULL DEFAULT 0,
+		last_interaction_at INTEGER NOT NULL
+	)`,
+
+	// retention_metadata: Advanced retention metadata (Phase 20)
+	`CREATE TABLE IF NOT EXISTS retention_metadata (
+		event_id TEXT PRIMARY KEY,
+		rule_name TEXT NOT NULL,
+		rule_priority INTEGER NOT NULL,
+		retain_until INTEGER,
+		last_evaluated_at INTEGER NOT NULL,
+		score INTEGER,
+		protected BOOLEAN DEFAULT 0,
+		FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_retention_metadata_retain_until
+	 ON retention_metadata(retain_until)`,
+	`CREATE INDEX IF NOT EXISTS idx_retention_metadata_score
+	 ON retention_metadata(score)`,
+	`CREATE INDEX IF NOT EXISTS idx_retention_metadata_protected
+	 ON retention_metadata(protected)`,
+
+	// relay_capabilities: Track relay feature support (NIP-77, etc.)
+	`CREATE TABLE IF NOT EXISTS relay_capabilities (
+		url TEXT PRIMARY KEY,
+		supports_negentropy INTEGER NOT NULL DEFAULT 0,
+		nip11_software TEXT,
+		nip11_version TEXT,
+		last_checked INTEGER NOT NULL,
+		check_expiry INTEGER NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_relay_capabilities_expiry
+	 ON relay_capabilities(check_expiry)`,
+
+	// deletions: NIP-09 deletion requests by target ID or address
+	`CREATE TABLE IF NOT EXISTS deletions (
+		target TEXT NOT NULL,
+		pubkey TEXT NOT NULL,
+		deletion_id TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		PRIMARY KEY (target, pubkey)
+	)`,
+
+	// zaps: Validated NIP-57 zap receipts per sender (event_id is '' for profile zaps)
+	`CREATE TABLE IF NOT EXISTS zaps (
+		receipt_id TEXT PRIMARY KEY,
+		event_id TEXT NOT NULL DEFAULT '',
+		recipient TEXT NOT NULL,
+		sender TEXT NOT NULL,
+		amount_sats INTEGER NOT NULL,
+		comment TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_zaps_event
+	 ON zaps(event_id, created_at DESC)`,
+	`CREATE INDEX IF NOT EXISTS idx_zaps_recipient
+	 ON zaps(recipient, event_id)`,
+
+	// contact_lists: The kind 3 event each author's stored follows come from
+	`CREATE TABLE IF NOT EXISTS contact_lists (
+		pubkey TEXT PRIMARY KEY,
+		event_id TEXT NOT NULL,
+		created_at INTEGER NOT NULL
+	)`,
+
+	// follows: Follow edges from each author's latest contact list
+	`CREATE TABLE IF NOT EXISTS follows (
+		follower TEXT NOT NULL,
+		followee TEXT NOT NULL,
+		PRIMARY KEY (follower, followee)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_follows_followee
+	 ON follows(followee, follower)`,
 }
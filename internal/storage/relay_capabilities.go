@@ -19,6 +19,10 @@ type RelayCapabilities struct {
 
 // GetRelayCapabilities retrieves cached capability information for a relay
 func (s *Storage) GetRelayCapabilities(ctx context.Context, url string) (*RelayCapabilities, error) {
+	if s.kv != nil {
+		return s.kvGetRelayCapabilities(url)
+	}
+
 	row := s.db.QueryRowContext(ctx, `
 		SELECT url, supports_negentropy, nip11_software, nip11_version,
 		       last_checked, check_expiry
@@ -53,6 +57,10 @@ func (s *Storage) GetRelayCapabilities(ctx context.Context, url string) (*RelayC
 
 // SaveRelayCapabilities stores capability information for a relay
 func (s *Storage) SaveRelayCapabilities(ctx context.Context, caps *RelayCapabilities) error {
+	if s.kv != nil {
+		return s.kvSaveRelayCapabilities(caps)
+	}
+
 	_, err := s.db.ExecContext(ctx, `
 		INSERT INTO relay_capabilities (
 			url, supports_negentropy, nip11_software, nip11_version,
@@ -17,6 +17,10 @@ type RelayHint struct {
 
 // SaveRelayHint stores or updates a relay hint
 func (s *Storage) SaveRelayHint(ctx context.Context, hint *RelayHint) error {
+	if s.kv != nil {
+		return s.kvSaveRelayHint(hint)
+	}
+
 	query := `
 		INSERT INTO relay_hints (pubkey, relay, can_read, can_write, freshness, last_seen_event_id)
 		VALUES (?, ?, ?, ?, ?, ?)
@@ -48,6 +52,10 @@ func (s *Storage) SaveRelayHint(ctx context.Context, hint *RelayHint) error {
 
 // GetRelayHints retrieves relay hints for a given pubkey
 func (s *Storage) GetRelayHints(ctx context.Context, pubkey string) ([]*RelayHint, error) {
+	if s.kv != nil {
+		return s.kvGetRelayHints(pubkey)
+	}
+
 	query := `
 		SELECT pubkey, relay, can_read, can_write, freshness, last_seen_event_id
 		FROM relay_hints
@@ -85,8 +93,54 @@ func (s *Storage) GetRelayHints(ctx context.Context, pubkey string) ([]*RelayHin
 	return hints, nil
 }
 
+// GetAllRelayHints retrieves the relay hints of every pubkey
+func (s *Storage) GetAllRelayHints(ctx context.Context) ([]*RelayHint, error) {
+	if s.kv != nil {
+		return s.kvAllRelayHints()
+	}
+
+	query := `
+		SELECT pubkey, relay, can_read, can_write, freshness, last_seen_event_id
+		FROM relay_hints
+		ORDER BY pubkey, relay
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query relay hints: %w", err)
+	}
+	defer rows.Close()
+
+	var hints []*RelayHint
+	for rows.Next() {
+		var hint RelayHint
+		var canRead, canWrite int
+
+		if err := rows.Scan(
+			&hint.Pubkey, &hint.Relay, &canRead, &canWrite,
+			&hint.Freshness, &hint.LastSeenEventID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan relay hint: %w", err)
+		}
+
+		hint.CanRead = canRead == 1
+		hint.CanWrite = canWrite == 1
+		hints = append(hints, &hint)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return hints, nil
+}
+
 // GetWriteRelays returns the write relays for a given pubkey
 func (s *Storage) GetWriteRelays(ctx context.Context, pubkey string) ([]string, error) {
+	if s.kv != nil {
+		return s.kvGetRelays(pubkey, true)
+	}
+
 	query := `
 		SELECT relay
 		FROM relay_hints
@@ -118,6 +172,10 @@ func (s *Storage) GetWriteRelays(ctx context.Context, pubkey string) ([]string,
 
 // GetReadRelays returns the read relays for a given pubkey
 func (s *Storage) GetReadRelays(ctx context.Context, pubkey string) ([]string, error) {
+	if s.kv != nil {
+		return s.kvGetRelays(pubkey, false)
+	}
+
 	query := `
 		SELECT relay
 		FROM relay_hints
@@ -149,6 +207,10 @@ func (s *Storage) GetReadRelays(ctx context.Context, pubkey string) ([]string, e
 
 // DeleteRelayHints removes all relay hints for a given pubkey
 func (s *Storage) DeleteRelayHints(ctx context.Context, pubkey string) error {
+	if s.kv != nil {
+		return s.kvDeleteRelayHints(pubkey)
+	}
+
 	query := `DELETE FROM relay_hints WHERE pubkey = ?`
 	_, err := s.db.ExecContext(ctx, query, pubkey)
 	if err != nil {
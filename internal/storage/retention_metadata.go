@@ -20,6 +20,10 @@ type RetentionMetadata struct {
 
 // StoreRetentionMetadata stores or updates retention metadata for an event
 func (s *Storage) StoreRetentionMetadata(ctx context.Context, meta *RetentionMetadata) error {
+	if s.kv != nil {
+		return s.kvStoreRetentionMetadata(meta)
+	}
+
 	var retainUntil *int64
 	if meta.RetainUntil != nil {
 		ts := meta.RetainUntil.Unix()
@@ -50,6 +54,10 @@ func (s *Storage) StoreRetentionMetadata(ctx context.Context, meta *RetentionMet
 
 // GetRetentionMetadata retrieves retention metadata for an event
 func (s *Storage) GetRetentionMetadata(ctx context.Context, eventID string) (*RetentionMetadata, error) {
+	if s.kv != nil {
+		return s.kvGetRetentionMetadata(eventID)
+	}
+
 	query := `
 		SELECT event_id, rule_name, rule_priority, retain_until, last_evaluated_at, score, protected
 		FROM retention_metadata
@@ -85,8 +93,61 @@ func (s *Storage) GetRetentionMetadata(ctx context.Context, eventID string) (*Re
 	return &meta, nil
 }
 
+// GetAllRetentionMetadata retrieves the retention metadata of every event
+func (s *Storage) GetAllRetentionMetadata(ctx context.Context) ([]*RetentionMetadata, error) {
+	if s.kv != nil {
+		return s.kvAllRetentionMetadata()
+	}
+
+	query := `
+		SELECT event_id, rule_name, rule_priority, retain_until, last_evaluated_at, score, protected
+		FROM retention_metadata
+		ORDER BY event_id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query retention metadata: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*RetentionMetadata
+	for rows.Next() {
+		var meta RetentionMetadata
+		var retainUntil *int64
+		var lastEvaluatedAt int64
+
+		err := rows.Scan(
+			&meta.EventID,
+			&meta.RuleName,
+			&meta.RulePriority,
+			&retainUntil,
+			&lastEvaluatedAt,
+			&meta.Score,
+			&meta.Protected,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan retention metadata: %w", err)
+		}
+
+		if retainUntil != nil {
+			t := time.Unix(*retainUntil, 0)
+			meta.RetainUntil = &t
+		}
+		meta.LastEvaluatedAt = time.Unix(lastEvaluatedAt, 0)
+
+		results = append(results, &meta)
+	}
+
+	return results, rows.Err()
+}
+
 // GetExpiredEvents returns event IDs that have passed their retain_until date
 func (s *Storage) GetExpiredEvents(ctx context.Context, limit int) ([]string, error) {
+	if s.kv != nil {
+		return s.kvGetExpiredEvents(limit)
+	}
+
 	now := time.Now().Unix()
 	query := `
 		SELECT event_id
@@ -119,6 +180,10 @@ func (s *Storage) GetExpiredEvents(ctx context.Context, limit int) ([]string, er
 // GetEventsByScore returns events sorted by score (ascending - lowest priority first)
 // Used for cap enforcement
 func (s *Storage) GetEventsByScore(ctx context.Context, limit int) ([]*RetentionMetadata, error) {
+	if s.kv != nil {
+		return s.kvGetEventsByScore(limit)
+	}
+
 	query := `
 		SELECT event_id, rule_name, rule_priority, retain_until, last_evaluated_at, score, protected
 		FROM retention_metadata
@@ -166,6 +231,10 @@ func (s *Storage) GetEventsByScore(ctx context.Context, limit int) ([]*Retention
 
 // GetEventsNeedingEvaluation returns event IDs that don't have retention metadata yet
 func (s *Storage) GetEventsNeedingEvaluation(ctx context.Context, limit int) ([]string, error) {
+	if s.kv != nil {
+		return s.kvGetEventsNeedingEvaluation(ctx, limit)
+	}
+
 	query := `
 		SELECT e.id
 		FROM events e
@@ -195,6 +264,10 @@ func (s *Storage) GetEventsNeedingEvaluation(ctx context.Context, limit int) ([]
 
 // GetEventsForReEvaluation returns events that need re-evaluation (older than interval)
 func (s *Storage) GetEventsForReEvaluation(ctx context.Context, olderThan time.Time, limit int) ([]string, error) {
+	if s.kv != nil {
+		return s.kvGetEventsForReEvaluation(olderThan, limit)
+	}
+
 	query := `
 		SELECT event_id
 		FROM retention_metadata
@@ -223,6 +296,10 @@ func (s *Storage) GetEventsForReEvaluation(ctx context.Context, olderThan time.T
 
 // CountRetentionStats returns retention statistics
 func (s *Storage) CountRetentionStats(ctx context.Context) (map[string]interface{}, error) {
+	if s.kv != nil {
+		return s.kvCountRetentionStats()
+	}
+
 	stats := make(map[string]interface{})
 
 	// Total events with retention metadata
@@ -276,6 +353,10 @@ func (s *Storage) CountRetentionStats(ctx context.Context) (map[string]interface
 
 // DeleteRetentionMetadata removes retention metadata for an event
 func (s *Storage) DeleteRetentionMetadata(ctx context.Context, eventID string) error {
+	if s.kv != nil {
+		return s.kvDeleteRetentionMetadata(eventID)
+	}
+
 	_, err := s.db.ExecContext(ctx, "DELETE FROM retention_metadata WHERE event_id = ?", eventID)
 	if err != nil {
 		return fmt.Errorf("failed to delete retention metadata: %w", err)
@@ -285,6 +366,16 @@ func (s *Storage) DeleteRetentionMetadata(ctx context.Context, eventID string) e
 
 // CountRetentionMetadata returns the total number of events with retention metadata
 func (s *Storage) CountRetentionMetadata(ctx context.Context) (int64, error) {
+	if s.kv != nil {
+		var count int64
+		err := s.kv.View(func(txn kvTxn) error {
+			var err error
+			count, err = kvCount(txn, kvTableRetentionMetadata, "")
+			return err
+		})
+		return count, err
+	}
+
 	query := `SELECT COUNT(*) FROM retention_metadata`
 	var count int64
 	if err := s.db.QueryRowContext(ctx, query).Scan(&count); err != nil {
@@ -295,6 +386,20 @@ func (s *Storage) CountRetentionMetadata(ctx context.Context) (int64, error) {
 
 // CountRetentionProtected returns the number of protected events
 func (s *Storage) CountRetentionProtected(ctx context.Context) (int64, error) {
+	if s.kv != nil {
+		metas, err := s.kvAllRetentionMetadata()
+		if err != nil {
+			return 0, err
+		}
+		var count int64
+		for _, meta := range metas {
+			if meta.Protected {
+				count++
+			}
+		}
+		return count, nil
+	}
+
 	query := `SELECT COUNT(*) FROM retention_metadata WHERE protected = 1`
 	var count int64
 	if err := s.db.QueryRowContext(ctx, query).Scan(&count); err != nil {
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// ScanEvents calls fn for every stored event matching the filter's kinds,
+// authors, since and until, newest first. Unlike QueryEvents it is not
+// capped by the eventstore's query limit: SQLite streams the rows and the
+// key-value drivers page through the eventstore, so at most one page of
+// events is held in memory and it suits exporting a whole archive. Other
+// filter fields are ignored. A non-nil error from fn stops the scan and is
+// returned.
+func (s *Storage) ScanEvents(ctx context.Context, filter nostr.Filter, fn func(*nostr.Event) error) error {
+	if s.kv != nil {
+		return s.kvScanEvents(ctx, filter, fn)
+	}
+
+	var conditions []string
+	var params []any
+
+	if len(filter.Kinds) > 0 {
+		conditions = append(conditions, "kind IN ("+placeholders(len(filter.Kinds))+")")
+		for _, kind := range filter.Kinds {
+			params = append(params, kind)
+		}
+	}
+	if len(filter.Authors) > 0 {
+		conditions = append(conditions, "pubkey IN ("+placeholders(len(filter.Authors))+")")
+		for _, author := range filter.Authors {
+			params = append(params, author)
+		}
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "created_at >= ?")
+		params = append(params, int64(*filter.Since))
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "created_at <= ?")
+		params = append(params, int64(*filter.Until))
+	}
+
+	query := "SELECT id, pubkey, created_at, kind, tags, content, sig FROM event"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := s.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event nostr.Event
+		var createdAt int64
+		if err := rows.Scan(&event.ID, &event.PubKey, &createdAt,
+			&event.Kind, &event.Tags, &event.Content, &event.Sig); err != nil {
+			return fmt.Errorf("failed to scan event: %w", err)
+		}
+		event.CreatedAt = nostr.Timestamp(createdAt)
+
+		if err := fn(&event); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return nil
+}
@@ -5,113 +5,115 @@ import (
 	"strings"
 
 	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/search"
 )
 
 // QueryEventsWithSearch performs a NIP-50 compliant search when the Search field is present
 // Falls back to regular QueryEvents if no search term is provided
+//
+// The search string may use the kind:, author:, since: and until: extensions
+// and "quoted phrases" (see search.ParseQuery). Results are ranked by BM25
+// when the full-text index is available, otherwise matched by a linear scan
+// of recent events.
 func (s *Storage) QueryEventsWithSearch(ctx context.Context, filter nostr.Filter) ([]*nostr.Event, error) {
 	// If no search term, use regular query
 	if filter.Search == "" {
 		return s.QueryEvents(ctx, filter)
 	}
 
-	// Get all matching events (without search filter first)
-	// Store the search term and clear it temporarily
-	searchTerm := filter.Search
+	query := search.ParseQuery(filter.Search)
 	filter.Search = ""
 
-	events, err := s.QueryEvents(ctx, filter)
-	if err != nil {
-		return nil, err
+	filter, ok := query.Restrict(filter)
+	if !ok {
+		return nil, nil
 	}
 
-	// Filter results by search term
-	searchLower := strings.ToLower(searchTerm)
-	var results []*nostr.Event
-
-	for _, event := range events {
-		if matchesSearch(event, searchLower) {
-			results = append(results, event)
-		}
+	// Only extensions (e.g. "kind:1 author:npub1..."), nothing to rank
+	if !query.HasText() {
+		return s.QueryEvents(ctx, filter)
 	}
 
-	// Rank by relevance (simple relevance scoring)
-	rankByRelevance(results, searchLower)
-
-	// Apply limit after relevance sorting (per NIP-50)
-	if filter.Limit > 0 && len(results) > filter.Limit {
-		results = results[:filter.Limit]
+	if s.searchIndex {
+		return s.searchIndexed(ctx, filter, query)
 	}
-
-	return results, nil
+	return s.searchScan(ctx, filter, query)
 }
 
-// matchesSearch checks if an event matches the search term
-func matchesSearch(event *nostr.Event, searchLower string) bool {
-	// Search in content (primary field per NIP-50)
-	if strings.Contains(strings.ToLower(event.Content), searchLower) {
-		return true
-	}
+// searchScanBatch is how many events searchScan loads per query, and
+// searchScanMax how many it looks at before giving up
+const (
+	searchScanBatch = maxSearchResults
+	searchScanMax   = maxSearchResults * 50
+)
 
-	// For profiles (kind 0), also search in parsed metadata
-	// This provides better UX for profile searches
-	if event.Kind == 0 {
-		// Simple check in raw JSON - could be enhanced with proper parsing
-		return strings.Contains(strings.ToLower(event.Content), searchLower)
+// searchScan matches events in Go when no full-text index is available
+// (LMDB, or a binary built without FTS5). It scans newest first in batches
+// and stops once it has enough matches or has looked at searchScanMax events,
+// so only recent events are searched on large stores.
+func (s *Storage) searchScan(ctx context.Context, filter nostr.Filter, query search.Query) ([]*nostr.Event, error) {
+	limit := filter.Limit
+	if limit <= 0 || limit > maxSearchResults {
+		limit = maxSearchResults
 	}
 
-	return false
-}
-
-// rankByRelevance sorts events by search relevance
-// Higher score = more relevant, appears first
-func rankByRelevance(events []*nostr.Event, searchLower string) {
-	// Calculate scores
-	scores := make([]int, len(events))
-	for i, event := range events {
-		scores[i] = calculateRelevance(event, searchLower)
+	needles := make([]string, 0, len(query.Terms)+len(query.Phrases))
+	for _, term := range query.Terms {
+		needles = append(needles, strings.ToLower(term))
+	}
+	for _, phrase := range query.Phrases {
+		needles = append(needles, strings.ToLower(phrase))
 	}
 
-	// Bubble sort by score (descending)
-	for i := 0; i < len(events); i++ {
-		for j := i + 1; j < len(events); j++ {
-			if scores[j] > scores[i] {
-				events[i], events[j] = events[j], events[i]
-				scores[i], scores[j] = scores[j], scores[i]
-			}
+	var results []*nostr.Event
+	seen := make(map[string]bool) // batches overlap at the cursor's second
+	for scanned := 0; scanned < searchScanMax; {
+		filter.Limit = searchScanBatch
+		events, err := s.QueryEvents(ctx, filter)
+		if err != nil {
+			return nil, err
 		}
-	}
-}
 
-// calculateRelevance scores an event's relevance to search term
-func calculateRelevance(event *nostr.Event, searchLower string) int {
-	score := 0
-	contentLower := strings.ToLower(event.Content)
+		fresh := 0
+		for _, event := range events {
+			if seen[event.ID] {
+				continue
+			}
+			seen[event.ID] = true
+			fresh++
+
+			if matchesSearch(event, needles) {
+				results = append(results, event)
+				if len(results) == limit {
+					return results, nil
+				}
+			}
+		}
+		scanned += fresh
 
-	// Exact phrase match = highest score
-	if contentLower == searchLower {
-		score += 100
-	} else if strings.Contains(contentLower, searchLower) {
-		score += 50
-	}
+		if len(events) < searchScanBatch || fresh == 0 {
+			break
+		}
 
-	// Count word matches
-	searchWords := strings.Fields(searchLower)
-	for _, word := range searchWords {
-		if strings.Contains(contentLower, word) {
-			score += 10
+		cursor := events[len(events)-1].CreatedAt
+		for _, event := range events {
+			cursor = min(cursor, event.CreatedAt)
 		}
+		filter.Until = &cursor
 	}
 
-	// Bonus for shorter content (more focused)
-	if len(event.Content) < 500 {
-		score += 5
-	}
+	return results, nil
+}
 
-	// Bonus for profiles (kind 0)
-	if event.Kind == 0 {
-		score += 10
-	}
+// matchesSearch reports whether an event's searchable text contains every needle
+func matchesSearch(event *nostr.Event, needles []string) bool {
+	title, summary, content := searchFields(event)
+	text := strings.ToLower(title + "\n" + summary + "\n" + content)
 
-	return score
+	for _, needle := range needles {
+		if !strings.Contains(text, needle) {
+			return false
+		}
+	}
+	return true
 }
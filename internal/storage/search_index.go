@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/search"
+)
+
+// maxSearchResults caps a ranked search. The eventstore returns at most 100
+// events per query, so larger limits would only be truncated later.
+const maxSearchResults = 100
+
+// BM25 column weights: a match in an article title or profile name counts
+// more than one in a summary, which counts more than one in the body
+const (
+	titleWeight   = 10.0
+	summaryWeight = 5.0
+	contentWeight = 1.0
+)
+
+// migrateSearchIndex creates the FTS5 full-text index, backfills it from the
+// event table on first run and keeps it in sync through the relay's store
+// and delete handlers. Binaries built without the sqlite_fts5 tag have no
+// FTS5 module; search then falls back to a linear scan.
+func (s *Storage) migrateSearchIndex(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `CREATE VIRTUAL TABLE IF NOT EXISTS event_search USING fts5(
+		event_id UNINDEXED,
+		pubkey UNINDEXED,
+		kind UNINDEXED,
+		created_at UNINDEXED,
+		title,
+		summary,
+		content,
+		tokenize = 'unicode61 remove_diacritics 2'
+	)`)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			return nil
+		}
+		return fmt.Errorf("failed to create search index: %w", err)
+	}
+
+	if err := s.backfillSearchIndex(ctx); err != nil {
+		return fmt.Errorf("failed to backfill search index: %w", err)
+	}
+
+	s.relay.StoreEvent = append(s.relay.StoreEvent, s.indexEvent)
+	s.relay.DeleteEvent = append(s.relay.DeleteEvent, s.unindexEvent)
+	s.searchIndex = true
+
+	return nil
+}
+
+// backfillSearchIndex indexes existing events when the index is empty
+func (s *Storage) backfillSearchIndex(ctx context.Context) error {
+	var exists int
+	err := s.db.QueryRowContext(ctx, `SELECT 1 FROM event_search LIMIT 1`).Scan(&exists)
+	if err == nil {
+		return nil // already populated
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	// The event table belongs to the eventstore; it is not visible from this
+	// connection for in-memory databases
+	err = s.db.QueryRowContext(ctx,
+		`SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'event'`).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, pubkey, created_at, kind, tags, content FROM event`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event nostr.Event
+		var tags string
+		if err := rows.Scan(&event.ID, &event.PubKey, &event.CreatedAt, &event.Kind, &tags, &event.Content); err != nil {
+			return err
+		}
+		if err := json.Unmarshal([]byte(tags), &event.Tags); err != nil {
+			continue // skip rows with malformed tags
+		}
+		if err := insertSearchRow(ctx, tx, &event); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// execer is satisfied by *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// indexEvent adds an event to the search index (relay StoreEvent handler)
+func (s *Storage) indexEvent(ctx context.Context, event *nostr.Event) error {
+	if err := insertSearchRow(ctx, s.db, event); err != nil {
+		return fmt.Errorf("failed to index event: %w", err)
+	}
+	return nil
+}
+
+// unindexEvent removes an event from the search index (relay DeleteEvent handler)
+func (s *Storage) unindexEvent(ctx context.Context, event *nostr.Event) error {
+	if _, err := s.db.ExecContext(ctx,
+		`DELETE FROM event_search WHERE rowid = ?`, searchRowID(event.ID)); err != nil {
+		return fmt.Errorf("failed to unindex event: %w", err)
+	}
+	return nil
+}
+
+func insertSearchRow(ctx context.Context, db execer, event *nostr.Event) error {
+	title, summary, content := searchFields(event)
+	if title == "" && summary == "" && content == "" {
+		return nil
+	}
+
+	_, err := db.ExecContext(ctx, `INSERT OR REPLACE INTO event_search
+		(rowid, event_id, pubkey, kind, created_at, title, summary, content)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		searchRowID(event.ID), event.ID, event.PubKey, event.Kind, int64(event.CreatedAt),
+		title, summary, content)
+	return err
+}
+
+// searchRowID derives a stable FTS rowid from the event ID, so re-indexing
+// replaces the row and deletes need no ID lookup
+func searchRowID(eventID string) int64 {
+	sum := sha256.Sum256([]byte(eventID))
+	return int64(binary.BigEndian.Uint64(sum[:8]))
+}
+
+// searchFields extracts the indexed text of an event. Profiles index their
+// names and about text rather than the raw JSON; articles add their title and
+// summary tags.
+func searchFields(event *nostr.Event) (title, summary, content string) {
+	if event.Kind == 0 {
+		var profile struct {
+			Name        string `json:"name"`
+			DisplayName string `json:"display_name"`
+			About       string `json:"about"`
+			NIP05       string `json:"nip05"`
+		}
+		if err := json.Unmarshal([]byte(event.Content), &profile); err != nil {
+			return "", "", event.Content
+		}
+		return strings.TrimSpace(profile.Name + " " + profile.DisplayName), profile.NIP05, profile.About
+	}
+
+	if tag := event.Tags.Find("title"); len(tag) >= 2 {
+		title = tag[1]
+	}
+	if tag := event.Tags.Find("summary"); len(tag) >= 2 {
+		summary = tag[1]
+	}
+	return title, summary, event.Content
+}
+
+// searchIndexed ranks matching events with BM25 and loads them in rank order
+func (s *Storage) searchIndexed(ctx context.Context, filter nostr.Filter, query search.Query) ([]*nostr.Event, error) {
+	limit := filter.Limit
+	if limit <= 0 || limit > maxSearchResults {
+		limit = maxSearchResults
+	}
+
+	// Tag filters are applied when loading the events, so over-fetch
+	candidates := limit
+	if len(filter.Tags) > 0 {
+		candidates = limit * 5
+	}
+
+	where := []string{"event_search MATCH ?"}
+	args := []any{matchExpression(query)}
+
+	if len(filter.IDs) > 0 {
+		where = append(where, "event_id IN ("+placeholders(len(filter.IDs))+")")
+		for _, id := range filter.IDs {
+			args = append(args, id)
+		}
+	}
+	if len(filter.Kinds) > 0 {
+		where = append(where, "kind IN ("+placeholders(len(filter.Kinds))+")")
+		for _, kind := range filter.Kinds {
+			args = append(args, kind)
+		}
+	}
+	if len(filter.Authors) > 0 {
+		where = append(where, "pubkey IN ("+placeholders(len(filter.Authors))+")")
+		for _, author := range filter.Authors {
+			args = append(args, author)
+		}
+	}
+	if filter.Since != nil {
+		where = append(where, "created_at >= ?")
+		args = append(args, int64(*filter.Since))
+	}
+	if filter.Until != nil {
+		where = append(where, "created_at <= ?")
+		args = append(args, int64(*filter.Until))
+	}
+	args = append(args, candidates)
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT event_id FROM event_search
+		WHERE %s
+		ORDER BY bm25(event_search, 0, 0, 0, 0, %g, %g, %g)
+		LIMIT ?`, strings.Join(where, " AND "), titleWeight, summaryWeight, contentWeight), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search index: %w", err)
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read search results: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// Load the events, re-applying the filter for tag constraints
+	filter.IDs = ids
+	filter.Limit = len(ids)
+	found, err := s.QueryEvents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*nostr.Event, len(found))
+	for _, event := range found {
+		byID[event.ID] = event
+	}
+
+	results := make([]*nostr.Event, 0, len(found))
+	for _, id := range ids {
+		if event, ok := byID[id]; ok {
+			results = append(results, event)
+			if len(results) == limit {
+				break
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// matchExpression builds an FTS5 query that requires every term and phrase.
+// Each is quoted so FTS5 operators in user input are matched literally.
+func matchExpression(query search.Query) string {
+	parts := make([]string, 0, len(query.Terms)+len(query.Phrases))
+	for _, text := range append(append([]string{}, query.Terms...), query.Phrases...) {
+		parts = append(parts, `"`+strings.ReplaceAll(text, `"`, `""`)+`"`)
+	}
+	return strings.Join(parts, " ")
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
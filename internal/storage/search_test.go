@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/search"
+)
+
+// searchFixture stores a small corpus and returns the events by name
+func searchFixture(t *testing.T, s *Storage) map[string]*nostr.Event {
+	t.Helper()
+
+	alice := strings.Repeat("a", 64)
+	bob := strings.Repeat("b", 64)
+
+	events := map[string]*nostr.Event{
+		"note":    {PubKey: alice, CreatedAt: 1000, Kind: 1, Content: "bitcoin fixes this"},
+		"mention": {PubKey: bob, CreatedAt: 2000, Kind: 1, Content: "I like nostr and bitcoin, mostly nostr"},
+		"old":     {PubKey: bob, CreatedAt: 10, Kind: 1, Content: "early bitcoin thoughts"},
+		"article": {
+			PubKey: alice, CreatedAt: 3000, Kind: 30023,
+			Tags:    nostr.Tags{{"d", "sound-money"}, {"title", "Bitcoin"}, {"summary", "On sound money"}},
+			Content: "A long read about money.",
+		},
+		"profile": {PubKey: alice, CreatedAt: 500, Kind: 0, Content: `{"name":"satoshi","about":"Writes on bitcoin"}`},
+	}
+
+	ctx := context.Background()
+	i := 0
+	for _, event := range events {
+		i++
+		event.ID = fmt.Sprintf("%064x", i)
+		event.Sig = strings.Repeat("c", 128)
+		if event.Tags == nil {
+			event.Tags = nostr.Tags{}
+		}
+		if err := s.StoreEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to store event: %v", err)
+		}
+	}
+
+	return events
+}
+
+func TestQueryEventsWithSearch(t *testing.T) {
+	npub, _ := nip19.EncodePublicKey(strings.Repeat("a", 64))
+
+	forEachDriver(t, func(t *testing.T, s *Storage) {
+		events := searchFixture(t, s)
+
+		tests := []struct {
+			name   string
+			filter nostr.Filter
+			want   []string
+		}{
+			{"term", nostr.Filter{Search: "bitcoin", Kinds: []int{1}}, []string{"note", "mention", "old"}},
+			{"all terms required", nostr.Filter{Search: "bitcoin nostr"}, []string{"mention"}},
+			{"phrase", nostr.Filter{Search: `"fixes this"`}, []string{"note"}},
+			{"phrase order matters", nostr.Filter{Search: `"this fixes"`}, nil},
+			{"article title", nostr.Filter{Search: "sound money"}, []string{"article"}},
+			{"profile fields not raw json", nostr.Filter{Search: "satoshi"}, []string{"profile"}},
+			{"profile json keys", nostr.Filter{Search: "about"}, []string{"article"}},
+			{"kind extension", nostr.Filter{Search: "bitcoin kind:30023"}, []string{"article"}},
+			{"kind intersects filter", nostr.Filter{Search: "bitcoin kind:30023", Kinds: []int{1}}, nil},
+			{"author extension", nostr.Filter{Search: "bitcoin author:" + npub, Kinds: []int{1}}, []string{"note"}},
+			{"since extension", nostr.Filter{Search: "bitcoin since:100", Kinds: []int{1}}, []string{"note", "mention"}},
+			{"until extension", nostr.Filter{Search: "bitcoin until:100"}, []string{"old"}},
+			{"only extensions", nostr.Filter{Search: "kind:0"}, []string{"profile"}},
+			{"unsupported extension ignored", nostr.Filter{Search: "fixes include:spam"}, []string{"note"}},
+			{"fts operators are literal", nostr.Filter{Search: "bitcoin OR"}, nil},
+			{"limit", nostr.Filter{Search: "bitcoin", Kinds: []int{1}, Limit: 2}, nil},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				results, err := s.QueryEventsWithSearch(context.Background(), tt.filter)
+				if err != nil {
+					t.Fatalf("QueryEventsWithSearch() error = %v", err)
+				}
+
+				if tt.filter.Limit > 0 {
+					if len(results) != tt.filter.Limit {
+						t.Errorf("Expected %d results, got %d", tt.filter.Limit, len(results))
+					}
+					return
+				}
+
+				got := make(map[string]bool)
+				for _, event := range results {
+					got[event.ID] = true
+				}
+				if len(results) != len(tt.want) {
+					t.Errorf("Expected %d results, got %d", len(tt.want), len(results))
+				}
+				for _, name := range tt.want {
+					if !got[events[name].ID] {
+						t.Errorf("Expected %q in results", name)
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestSearchScanBatches(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, s *Storage) {
+		ctx := context.Background()
+
+		// The only match sits behind a few batches of newer events
+		for i := 0; i < searchScanBatch*3; i++ {
+			content := "unrelated"
+			if i == 0 {
+				content = "needle"
+			}
+			event := &nostr.Event{
+				ID:        fmt.Sprintf("%064x", i+1),
+				PubKey:    strings.Repeat("a", 64),
+				CreatedAt: nostr.Timestamp(1000 + i/2),
+				Kind:      1,
+				Tags:      nostr.Tags{},
+				Content:   content,
+				Sig:       strings.Repeat("c", 128),
+			}
+			if err := s.StoreEvent(ctx, event); err != nil {
+				t.Fatalf("Failed to store event: %v", err)
+			}
+		}
+
+		results, err := s.searchScan(ctx, nostr.Filter{}, search.ParseQuery("needle"))
+		if err != nil {
+			t.Fatalf("searchScan() error = %v", err)
+		}
+		if len(results) != 1 || results[0].Content != "needle" {
+			t.Errorf("Expected the old match, got %v", results)
+		}
+
+		results, err = s.searchScan(ctx, nostr.Filter{Limit: 5}, search.ParseQuery("unrelated"))
+		if err != nil {
+			t.Fatalf("searchScan() error = %v", err)
+		}
+		if len(results) != 5 {
+			t.Errorf("Expected 5 results, got %d", len(results))
+		}
+	})
+}
+
+func TestSearchIndex(t *testing.T) {
+	s, _ := setupTestStorage(t)
+	defer s.Close()
+	if !s.searchIndex {
+		t.Skip("SQLite built without FTS5 (use -tags sqlite_fts5)")
+	}
+
+	ctx := context.Background()
+	events := searchFixture(t, s)
+
+	// A title match outranks body matches
+	results, err := s.QueryEventsWithSearch(ctx, nostr.Filter{Search: "bitcoin"})
+	if err != nil {
+		t.Fatalf("QueryEventsWithSearch() error = %v", err)
+	}
+	if len(results) == 0 || results[0].ID != events["article"].ID {
+		t.Errorf("Expected the article to rank first, got %v", results)
+	}
+
+	// Deleting an event removes it from the index
+	if err := s.DeleteEvent(ctx, events["note"].ID); err != nil {
+		t.Fatalf("Failed to delete event: %v", err)
+	}
+	var count int
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT count(*) FROM event_search WHERE event_search MATCH 'fixes'`).Scan(&count); err != nil {
+		t.Fatalf("Failed to query index: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected deleted event to be unindexed, found %d rows", count)
+	}
+}
+
+func TestSearchIndexBackfill(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	cfg := &config.Storage{Driver: "sqlite", SQLitePath: dbPath}
+	ctx := context.Background()
+
+	s, err := New(ctx, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	if !s.searchIndex {
+		s.Close()
+		t.Skip("SQLite built without FTS5 (use -tags sqlite_fts5)")
+	}
+
+	events := searchFixture(t, s)
+
+	// Simulate a database created before the index existed
+	if _, err := s.db.ExecContext(ctx, `DROP TABLE event_search`); err != nil {
+		t.Fatalf("Failed to drop index: %v", err)
+	}
+	s.Close()
+
+	s, err = New(ctx, cfg)
+	if err != nil {
+		t.Fatalf("Failed to reopen storage: %v", err)
+	}
+	defer s.Close()
+
+	results, err := s.QueryEventsWithSearch(ctx, nostr.Filter{Search: `"fixes this"`})
+	if err != nil {
+		t.Fatalf("QueryEventsWithSearch() error = %v", err)
+	}
+	if len(results) != 1 || results[0].ID != events["note"].ID {
+		t.Errorf("Expected backfilled index to find the note, got %v", results)
+	}
+}
@@ -36,6 +36,7 @@ func (s *Storage) initSQLite(ctx context.Context) error {
 	relay.DeleteEvent = append(relay.DeleteEvent, db.DeleteEvent)
 
 	s.relay = relay
+	s.query = db.QueryEvents
 
 	// Open a separate connection for custom tables
 	sqlDB, err := sql.Open("sqlite3", dbPath)
@@ -17,6 +17,10 @@ func (s *Storage) Driver() string {
 
 // CountEvents returns the total number of events in storage
 func (s *Storage) CountEvents(ctx context.Context) (int64, error) {
+	if s.kv != nil {
+		return s.kvCountEvents(ctx, nostr.Filter{})
+	}
+
 	var count int64
 	query := "SELECT COUNT(*) FROM event"
 
@@ -30,6 +34,10 @@ func (s *Storage) CountEvents(ctx context.Context) (int64, error) {
 
 // CountEventsByKind returns event counts grouped by kind
 func (s *Storage) CountEventsByKind(ctx context.Context) (map[int]int64, error) {
+	if s.kv != nil {
+		return s.kvCountEventsByKind(ctx)
+	}
+
 	counts := make(map[int]int64)
 
 	query := "SELECT kind, COUNT(*) FROM event GROUP BY kind"
@@ -63,7 +71,11 @@ func (s *Storage) DatabaseSize(ctx context.Context) (float64, error) {
 	case "sqlite":
 		path = s.config.SQLitePath
 	case "lmdb":
-		path = s.config.LMDBPath
+		size, err := kvDirSize(s.config.LMDBPath)
+		if err != nil {
+			return 0, fmt.Errorf("failed to stat database directory: %w", err)
+		}
+		return float64(size) / 1024 / 1024, nil
 	default:
 		return 0, fmt.Errorf("unsupported driver: %s", s.config.Driver)
 	}
@@ -79,6 +91,10 @@ func (s *Storage) DatabaseSize(ctx context.Context) (float64, error) {
 
 // EventTimeRange returns the oldest and newest event timestamps
 func (s *Storage) EventTimeRange(ctx context.Context) (*time.Time, *time.Time, error) {
+	if s.kv != nil {
+		return s.kvEventTimeRange(ctx)
+	}
+
 	var oldestUnix, newestUnix sql.NullInt64
 
 	query := "SELECT MIN(created_at), MAX(created_at) FROM event"
@@ -125,6 +141,22 @@ type CursorInfo struct {
 func (s *Storage) GetAllCursors(ctx context.Context) ([]CursorInfo, error) {
 	var cursors []CursorInfo
 
+	if s.kv != nil {
+		states, err := s.kvGetAllSyncStates()
+		if err != nil {
+			return nil, fmt.Errorf("failed to query cursors: %w", err)
+		}
+		for _, state := range states {
+			cursors = append(cursors, CursorInfo{
+				Relay:    state.Relay,
+				Kind:     state.Kind,
+				Position: state.Since,
+				Updated:  time.Unix(state.UpdatedAt, 0),
+			})
+		}
+		return cursors, nil
+	}
+
 	query := `
 		SELECT relay, kind, cursor, updated_at
 		FROM sync_state
@@ -158,6 +190,19 @@ func (s *Storage) GetAllCursors(ctx context.Context) ([]CursorInfo, error) {
 
 // CountAggregates returns the total number of aggregates
 func (s *Storage) CountAggregates(ctx context.Context) (int64, error) {
+	if s.kv != nil {
+		var count int64
+		err := s.kv.View(func(txn kvTxn) error {
+			var err error
+			count, err = kvCount(txn, kvTableAggregates, "")
+			return err
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to count aggregates: %w", err)
+		}
+		return count, nil
+	}
+
 	var count int64
 	query := "SELECT COUNT(*) FROM aggregates"
 
@@ -171,6 +216,11 @@ func (s *Storage) CountAggregates(ctx context.Context) (int64, error) {
 
 // CountAggregatesByKind returns aggregate counts grouped by kind
 func (s *Storage) CountAggregatesByKind(ctx context.Context) (map[int]int64, error) {
+	if s.kv != nil {
+		// Aggregate records do not carry the target kind
+		return make(map[int]int64), nil
+	}
+
 	counts := make(map[int]int64)
 
 	query := "SELECT kind, COUNT(*) FROM aggregates GROUP BY kind"
@@ -198,6 +248,11 @@ func (s *Storage) CountAggregatesByKind(ctx context.Context) (map[int]int64, err
 
 // LastReconcileTime returns the last time aggregates were reconciled
 func (s *Storage) LastReconcileTime(ctx context.Context) (*time.Time, error) {
+	if s.kv != nil {
+		// Reconcile runs are not recorded in the key-value tables
+		return nil, nil
+	}
+
 	var lastReconcileUnix sql.NullInt64
 
 	query := "SELECT MAX(last_reconciled_at) FROM aggregates"
@@ -252,8 +307,32 @@ func (s *Storage) GetEventsByKind(ctx context.Context, kind int, limit int) ([]*
 	return s.QueryEvents(ctx, filter)
 }
 
+// CountEventsBefore returns the number of events created before the given
+// timestamp, the events DeleteEventsBefore would delete
+func (s *Storage) CountEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	if s.kv != nil {
+		until := nostr.Timestamp(before.Unix() - 1)
+		return s.kvCountEvents(ctx, nostr.Filter{Until: &until})
+	}
+
+	var count int64
+	err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM event WHERE created_at < ?",
+		before.Unix()).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count events: %w", err)
+	}
+
+	return count, nil
+}
+
 // DeleteEventsBefore deletes events created before the given timestamp
 func (s *Storage) DeleteEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	if s.kv != nil {
+		until := nostr.Timestamp(before.Unix() - 1)
+		return s.kvDeleteEvents(ctx, nostr.Filter{Until: &until})
+	}
+
 	result, err := s.db.ExecContext(ctx,
 		"DELETE FROM event WHERE created_at < ?",
 		before.Unix())
@@ -271,6 +350,10 @@ func (s *Storage) DeleteEventsBefore(ctx context.Context, before time.Time) (int
 
 // DeleteEventsByKind deletes all events of a specific kind
 func (s *Storage) DeleteEventsByKind(ctx context.Context, kind int) (int64, error) {
+	if s.kv != nil {
+		return s.kvDeleteEvents(ctx, nostr.Filter{Kinds: []int{kind}})
+	}
+
 	result, err := s.db.ExecContext(ctx,
 		"DELETE FROM event WHERE kind = ?",
 		kind)
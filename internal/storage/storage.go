@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/fiatjaf/khatru"
 	"github.com/nbd-wtf/go-nostr"
@@ -13,8 +15,15 @@ import (
 // Storage provides the main storage interface for nophr
 type Storage struct {
 	relay  *khatru.Relay
+	query  func(ctx context.Context, filter nostr.Filter) (chan *nostr.Event, error) // eventstore query, independent of relay hooks
 	db     *sql.DB
+	kv     kvStore // custom tables for key-value drivers (LMDB); nil for SQLite
 	config *config.Storage
+
+	searchIndex bool // FTS5 index available (SQLite built with sqlite_fts5)
+
+	deletionsMu     sync.Mutex
+	deletionAuthors map[string]bool // pubkeys with deletion requests, nil until loaded
 }
 
 // New creates a new Storage instance with the given configuration
@@ -50,7 +59,8 @@ func (s *Storage) Relay() *khatru.Relay {
 	return s.relay
 }
 
-// DB returns the underlying database connection (for custom tables)
+// DB returns the underlying database connection (for custom tables).
+// It is nil for drivers that keep custom tables in a key-value store.
 func (s *Storage) DB() *sql.DB {
 	return s.db
 }
@@ -81,6 +91,18 @@ func (s *Storage) StoreEventBatch(ctx context.Context, events []*nostr.Event) er
 		return nil
 	}
 
+	// Key-value drivers commit each SaveEvent in their own write transaction
+	if s.kv != nil {
+		for _, event := range events {
+			for _, handler := range s.relay.StoreEvent {
+				if err := handler(ctx, event); err != nil {
+					return fmt.Errorf("failed to store event in batch: %w", err)
+				}
+			}
+		}
+		return nil
+	}
+
 	// Start transaction for batch insert
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -159,12 +181,13 @@ func (s *Storage) QueryEvents(ctx context.Context, filter nostr.Filter) ([]*nost
 		return nil, fmt.Errorf("relay not initialized")
 	}
 
-	// Use the first QueryEvents handler (eventstore)
-	if len(s.relay.QueryEvents) == 0 {
+	// Query the eventstore directly, so hooks added to the served relay
+	// (see Relay) do not affect internal reads
+	if s.query == nil {
 		return nil, fmt.Errorf("no query handlers configured")
 	}
 
-	ch, err := s.relay.QueryEvents[0](ctx, filter)
+	ch, err := s.query(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query events: %w", err)
 	}
@@ -178,6 +201,66 @@ func (s *Storage) QueryEvents(ctx context.Context, filter nostr.Filter) ([]*nost
 	return events, nil
 }
 
+// QueryEventsFiltered queries events and drops those keep rejects before
+// the limit is applied: while a full batch loses events to keep, storage is
+// queried further back until filter.Limit events are kept or the history
+// runs out. Events are returned newest first.
+func (s *Storage) QueryEventsFiltered(ctx context.Context, filter nostr.Filter, keep func([]*nostr.Event) []*nostr.Event) ([]*nostr.Event, error) {
+	if filter.Limit <= 0 || len(filter.IDs) > 0 {
+		events, err := s.QueryEvents(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		return keep(events), nil
+	}
+
+	// Until is inclusive, so consecutive batches overlap on one second
+	seen := make(map[string]bool)
+	var kept []*nostr.Event
+	for {
+		batch, err := s.QueryEvents(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		oldest := batch[0].CreatedAt
+		var fresh []*nostr.Event
+		for _, event := range batch {
+			if event.CreatedAt < oldest {
+				oldest = event.CreatedAt
+			}
+			if !seen[event.ID] {
+				seen[event.ID] = true
+				fresh = append(fresh, event)
+			}
+		}
+		kept = append(kept, keep(fresh)...)
+
+		if len(kept) >= filter.Limit || len(batch) < filter.Limit {
+			break
+		}
+		if len(fresh) == 0 {
+			// The whole batch shares one second; move past it
+			oldest--
+		}
+		if filter.Since != nil && oldest < *filter.Since {
+			break
+		}
+		filter.Until = &oldest
+	}
+
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].CreatedAt > kept[j].CreatedAt
+	})
+	if len(kept) > filter.Limit {
+		kept = kept[:filter.Limit]
+	}
+	return kept, nil
+}
+
 // QuerySync is a synchronous query adapter (implements search.Relay interface)
 func (s *Storage) QuerySync(ctx context.Context, filter nostr.Filter) ([]*nostr.Event, error) {
 	// Use QueryEventsWithSearch to support NIP-50
@@ -186,6 +269,11 @@ func (s *Storage) QuerySync(ctx context.Context, filter nostr.Filter) ([]*nostr.
 
 // Close closes the storage connections
 func (s *Storage) Close() error {
+	if s.kv != nil {
+		if err := s.kv.Close(); err != nil {
+			return fmt.Errorf("failed to close database: %w", err)
+		}
+	}
 	if s.db != nil {
 		if err := s.db.Close(); err != nil {
 			return fmt.Errorf("failed to close database: %w", err)
@@ -2,9 +2,13 @@ package storage
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/sandwichfarm/nophr/internal/config"
@@ -36,6 +40,42 @@ func setupTestStorage(t *testing.T) (*Storage, func()) {
 	return storage, cleanup
 }
 
+// testDriver builds a Storage for one backend under test
+type testDriver struct {
+	name  string
+	setup func(t *testing.T) *Storage
+}
+
+// testDrivers lists the backends the storage suite runs against. "kv" drives
+// the LMDB code paths with in-memory tables; lmdb_test.go adds the real LMDB
+// driver when built with -tags lmdb.
+var testDrivers = []testDriver{
+	{
+		name: "sqlite",
+		setup: func(t *testing.T) *Storage {
+			s, _ := setupTestStorage(t)
+			return s
+		},
+	},
+	{
+		name:  "kv",
+		setup: setupMemoryKVStorage,
+	},
+}
+
+// forEachDriver runs fn as a subtest against every driver in testDrivers
+func forEachDriver(t *testing.T, fn func(t *testing.T, s *Storage)) {
+	t.Helper()
+
+	for _, driver := range testDrivers {
+		t.Run(driver.name, func(t *testing.T) {
+			s := driver.setup(t)
+			defer s.Close()
+			fn(t, s)
+		})
+	}
+}
+
 func TestNew(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -58,12 +98,13 @@ func TestNew(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name: "lmdb not implemented",
+			name: "lmdb",
 			cfg: &config.Storage{
-				Driver:   "lmdb",
-				LMDBPath: "/tmp/test.lmdb",
+				Driver:        "lmdb",
+				LMDBPath:      filepath.Join(t.TempDir(), "test.lmdb"),
+				LMDBMaxSizeMB: 64,
 			},
-			wantErr: true,
+			wantErr: !LMDBAvailable,
 		},
 	}
 
@@ -83,344 +124,762 @@ func TestNew(t *testing.T) {
 }
 
 func TestStoreAndQueryEvents(t *testing.T) {
-	s, cleanup := setupTestStorage(t)
-	defer cleanup()
-
-	ctx := context.Background()
-
-	// Create a test event
-	event := &nostr.Event{
-		ID:        "test-event-id",
-		PubKey:    "test-pubkey",
-		CreatedAt: nostr.Now(),
-		Kind:      1,
-		Tags:      nostr.Tags{},
-		Content:   "Hello, Nostr!",
-		Sig:       "test-signature",
-	}
-
-	// Store the event
-	if err := s.StoreEvent(ctx, event); err != nil {
-		t.Fatalf("Failed to store event: %v", err)
-	}
-
-	// Query the event
-	filter := nostr.Filter{
-		IDs: []string{event.ID},
-	}
+	forEachDriver(t, func(t *testing.T, s *Storage) {
+		ctx := context.Background()
+
+		// Create a test event
+		event := &nostr.Event{
+			ID:        strings.Repeat("a", 64),
+			PubKey:    strings.Repeat("b", 64),
+			CreatedAt: nostr.Now(),
+			Kind:      1,
+			Tags:      nostr.Tags{},
+			Content:   "Hello, Nostr!",
+			Sig:       strings.Repeat("c", 128),
+		}
+
+		// Store the event
+		if err := s.StoreEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to store event: %v", err)
+		}
+
+		// Query the event
+		filter := nostr.Filter{
+			IDs: []string{event.ID},
+		}
+
+		events, err := s.QueryEvents(ctx, filter)
+		if err != nil {
+			t.Fatalf("Failed to query events: %v", err)
+		}
+
+		if len(events) != 1 {
+			t.Errorf("Expected 1 event, got %d", len(events))
+		}
+
+		if events[0].ID != event.ID {
+			t.Errorf("Expected event ID %s, got %s", event.ID, events[0].ID)
+		}
+	})
+}
 
-	events, err := s.QueryEvents(ctx, filter)
-	if err != nil {
-		t.Fatalf("Failed to query events: %v", err)
-	}
+func TestQueryEventsFiltered(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, s *Storage) {
+		ctx := context.Background()
+		muted := strings.Repeat("d", 64)
+		author := strings.Repeat("b", 64)
+
+		// The 30 newest events are from a pubkey the filter drops
+		base := nostr.Now() - 1000
+		for i := 0; i < 45; i++ {
+			pubkey := author
+			if i >= 15 {
+				pubkey = muted
+			}
+			event := &nostr.Event{
+				ID:        fmt.Sprintf("%064x", i+1),
+				PubKey:    pubkey,
+				CreatedAt: base + nostr.Timestamp(i),
+				Kind:      1,
+				Tags:      nostr.Tags{},
+				Content:   "note",
+				Sig:       strings.Repeat("c", 128),
+			}
+			if err := s.StoreEvent(ctx, event); err != nil {
+				t.Fatalf("Failed to store event: %v", err)
+			}
+		}
+
+		keep := func(events []*nostr.Event) []*nostr.Event {
+			var kept []*nostr.Event
+			for _, event := range events {
+				if event.PubKey != muted {
+					kept = append(kept, event)
+				}
+			}
+			return kept
+		}
+
+		events, err := s.QueryEventsFiltered(ctx, nostr.Filter{Kinds: []int{1}, Limit: 10}, keep)
+		if err != nil {
+			t.Fatalf("Failed to query events: %v", err)
+		}
+		if len(events) != 10 {
+			t.Fatalf("Expected a full page of 10 events, got %d", len(events))
+		}
+		for i, event := range events {
+			if event.PubKey != author || event.CreatedAt != base+nostr.Timestamp(14-i) {
+				t.Errorf("Unexpected event %d: pubkey %s at %d", i, event.PubKey[:8], event.CreatedAt-base)
+			}
+		}
+	})
+}
 
-	if len(events) != 1 {
-		t.Errorf("Expected 1 event, got %d", len(events))
-	}
+func TestIsDeleted(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, s *Storage) {
+		ctx := context.Background()
+		author := strings.Repeat("b", 64)
+		note := &nostr.Event{ID: strings.Repeat("1", 64), PubKey: author, CreatedAt: 100, Kind: 1}
+		profile := &nostr.Event{ID: strings.Repeat("2", 64), PubKey: author, CreatedAt: 100, Kind: 0}
+
+		if deleted, err := s.IsDeleted(ctx, note); err != nil || deleted {
+			t.Fatalf("Expected no deletion before any request, got %v, %v", deleted, err)
+		}
+
+		for _, d := range []*Deletion{
+			{Target: note.ID, Pubkey: author, DeletionID: strings.Repeat("3", 64), CreatedAt: 200},
+			{Target: DeletionAddress(profile), Pubkey: author, DeletionID: strings.Repeat("3", 64), CreatedAt: 200},
+		} {
+			if err := s.SaveDeletion(ctx, d); err != nil {
+				t.Fatalf("Failed to save deletion: %v", err)
+			}
+		}
 
-	if events[0].ID != event.ID {
-		t.Errorf("Expected event ID %s, got %s", event.ID, events[0].ID)
-	}
+		check := func(event *nostr.Event, want bool) {
+			t.Helper()
+			deleted, err := s.IsDeleted(ctx, event)
+			if err != nil {
+				t.Fatalf("IsDeleted failed: %v", err)
+			}
+			if deleted != want {
+				t.Errorf("IsDeleted(%s) = %v, want %v", event.ID[:8], deleted, want)
+			}
+		}
+		check(note, true)
+		check(profile, true)
+		check(&nostr.Event{ID: note.ID, PubKey: strings.Repeat("c", 64), Kind: 1}, false)
+		check(&nostr.Event{ID: strings.Repeat("4", 64), PubKey: author, CreatedAt: 300, Kind: 0}, false)
+
+		// Authors with deletions are read back from storage on first use
+		s.deletionAuthors = nil
+		check(note, true)
+	})
 }
 
 func TestRelayHints(t *testing.T) {
-	s, cleanup := setupTestStorage(t)
-	defer cleanup()
-
-	ctx := context.Background()
-
-	hint := &RelayHint{
-		Pubkey:          "test-pubkey",
-		Relay:           "wss://relay.test",
-		CanRead:         true,
-		CanWrite:        true,
-		Freshness:       12345,
-		LastSeenEventID: "event-123",
-	}
-
-	// Save relay hint
-	if err := s.SaveRelayHint(ctx, hint); err != nil {
-		t.Fatalf("Failed to save relay hint: %v", err)
-	}
-
-	// Get relay hints
-	hints, err := s.GetRelayHints(ctx, hint.Pubkey)
-	if err != nil {
-		t.Fatalf("Failed to get relay hints: %v", err)
-	}
-
-	if len(hints) != 1 {
-		t.Errorf("Expected 1 hint, got %d", len(hints))
-	}
-
-	if hints[0].Relay != hint.Relay {
-		t.Errorf("Expected relay %s, got %s", hint.Relay, hints[0].Relay)
-	}
-
-	// Get write relays
-	writeRelays, err := s.GetWriteRelays(ctx, hint.Pubkey)
-	if err != nil {
-		t.Fatalf("Failed to get write relays: %v", err)
-	}
-
-	if len(writeRelays) != 1 {
-		t.Errorf("Expected 1 write relay, got %d", len(writeRelays))
-	}
-
-	// Get read relays
-	readRelays, err := s.GetReadRelays(ctx, hint.Pubkey)
-	if err != nil {
-		t.Fatalf("Failed to get read relays: %v", err)
-	}
-
-	if len(readRelays) != 1 {
-		t.Errorf("Expected 1 read relay, got %d", len(readRelays))
-	}
-
-	// Delete relay hints
-	if err := s.DeleteRelayHints(ctx, hint.Pubkey); err != nil {
-		t.Fatalf("Failed to delete relay hints: %v", err)
-	}
-
-	hints, err = s.GetRelayHints(ctx, hint.Pubkey)
-	if err != nil {
-		t.Fatalf("Failed to get relay hints after delete: %v", err)
-	}
-
-	if len(hints) != 0 {
-		t.Errorf("Expected 0 hints after delete, got %d", len(hints))
-	}
+	forEachDriver(t, func(t *testing.T, s *Storage) {
+		ctx := context.Background()
+
+		hint := &RelayHint{
+			Pubkey:          "test-pubkey",
+			Relay:           "wss://relay.test",
+			CanRead:         true,
+			CanWrite:        true,
+			Freshness:       12345,
+			LastSeenEventID: "event-123",
+		}
+
+		// Save relay hint
+		if err := s.SaveRelayHint(ctx, hint); err != nil {
+			t.Fatalf("Failed to save relay hint: %v", err)
+		}
+
+		// Get relay hints
+		hints, err := s.GetRelayHints(ctx, hint.Pubkey)
+		if err != nil {
+			t.Fatalf("Failed to get relay hints: %v", err)
+		}
+
+		if len(hints) != 1 {
+			t.Errorf("Expected 1 hint, got %d", len(hints))
+		}
+
+		if hints[0].Relay != hint.Relay {
+			t.Errorf("Expected relay %s, got %s", hint.Relay, hints[0].Relay)
+		}
+
+		other := &RelayHint{Pubkey: "another-pubkey", Relay: "wss://relay.test", CanRead: true, Freshness: 1}
+		if err := s.SaveRelayHint(ctx, other); err != nil {
+			t.Fatalf("Failed to save relay hint: %v", err)
+		}
+
+		all, err := s.GetAllRelayHints(ctx)
+		if err != nil {
+			t.Fatalf("Failed to get all relay hints: %v", err)
+		}
+
+		if len(all) != 2 || all[0].Pubkey != other.Pubkey || all[1].Pubkey != hint.Pubkey {
+			t.Errorf("Expected hints of both pubkeys ordered by pubkey, got %d", len(all))
+		}
+
+		// Get write relays
+		writeRelays, err := s.GetWriteRelays(ctx, hint.Pubkey)
+		if err != nil {
+			t.Fatalf("Failed to get write relays: %v", err)
+		}
+
+		if len(writeRelays) != 1 {
+			t.Errorf("Expected 1 write relay, got %d", len(writeRelays))
+		}
+
+		// Get read relays
+		readRelays, err := s.GetReadRelays(ctx, hint.Pubkey)
+		if err != nil {
+			t.Fatalf("Failed to get read relays: %v", err)
+		}
+
+		if len(readRelays) != 1 {
+			t.Errorf("Expected 1 read relay, got %d", len(readRelays))
+		}
+
+		// Delete relay hints
+		if err := s.DeleteRelayHints(ctx, hint.Pubkey); err != nil {
+			t.Fatalf("Failed to delete relay hints: %v", err)
+		}
+
+		hints, err = s.GetRelayHints(ctx, hint.Pubkey)
+		if err != nil {
+			t.Fatalf("Failed to get relay hints after delete: %v", err)
+		}
+
+		if len(hints) != 0 {
+			t.Errorf("Expected 0 hints after delete, got %d", len(hints))
+		}
+	})
 }
 
 func TestGraphNodes(t *testing.T) {
-	s, cleanup := setupTestStorage(t)
-	defer cleanup()
-
-	ctx := context.Background()
-
-	node := &GraphNode{
-		RootPubkey: "root-pubkey",
-		Pubkey:     "follower-pubkey",
-		Depth:      1,
-		Mutual:     true,
-		LastSeen:   12345,
-	}
-
-	// Save graph node
-	if err := s.SaveGraphNode(ctx, node); err != nil {
-		t.Fatalf("Failed to save graph node: %v", err)
-	}
-
-	// Get graph nodes
-	nodes, err := s.GetGraphNodes(ctx, node.RootPubkey, 2)
-	if err != nil {
-		t.Fatalf("Failed to get graph nodes: %v", err)
-	}
-
-	if len(nodes) != 1 {
-		t.Errorf("Expected 1 node, got %d", len(nodes))
-	}
-
-	if nodes[0].Pubkey != node.Pubkey {
-		t.Errorf("Expected pubkey %s, got %s", node.Pubkey, nodes[0].Pubkey)
-	}
-
-	// Get following pubkeys
-	following, err := s.GetFollowingPubkeys(ctx, node.RootPubkey)
-	if err != nil {
-		t.Fatalf("Failed to get following pubkeys: %v", err)
-	}
-
-	if len(following) != 1 {
-		t.Errorf("Expected 1 following, got %d", len(following))
-	}
-
-	// Get mutual pubkeys
-	mutuals, err := s.GetMutualPubkeys(ctx, node.RootPubkey)
-	if err != nil {
-		t.Fatalf("Failed to get mutual pubkeys: %v", err)
-	}
-
-	if len(mutuals) != 1 {
-		t.Errorf("Expected 1 mutual, got %d", len(mutuals))
-	}
-
-	// Delete graph nodes
-	if err := s.DeleteGraphNodes(ctx, node.RootPubkey); err != nil {
-		t.Fatalf("Failed to delete graph nodes: %v", err)
-	}
+	forEachDriver(t, func(t *testing.T, s *Storage) {
+		ctx := context.Background()
+
+		node := &GraphNode{
+			RootPubkey: "root-pubkey",
+			Pubkey:     "follower-pubkey",
+			Depth:      1,
+			Mutual:     true,
+			LastSeen:   12345,
+		}
+
+		// Save graph node
+		if err := s.SaveGraphNode(ctx, node); err != nil {
+			t.Fatalf("Failed to save graph node: %v", err)
+		}
+
+		// Get graph nodes
+		nodes, err := s.GetGraphNodes(ctx, node.RootPubkey, 2)
+		if err != nil {
+			t.Fatalf("Failed to get graph nodes: %v", err)
+		}
+
+		if len(nodes) != 1 {
+			t.Errorf("Expected 1 node, got %d", len(nodes))
+		}
+
+		if nodes[0].Pubkey != node.Pubkey {
+			t.Errorf("Expected pubkey %s, got %s", node.Pubkey, nodes[0].Pubkey)
+		}
+
+		other := &GraphNode{RootPubkey: "another-root", Pubkey: "follower-pubkey", Depth: 2}
+		if err := s.SaveGraphNode(ctx, other); err != nil {
+			t.Fatalf("Failed to save graph node: %v", err)
+		}
+
+		all, err := s.GetAllGraphNodes(ctx)
+		if err != nil {
+			t.Fatalf("Failed to get all graph nodes: %v", err)
+		}
+
+		if len(all) != 2 || all[0].RootPubkey != other.RootPubkey || all[1].RootPubkey != node.RootPubkey {
+			t.Errorf("Expected nodes of both roots ordered by root, got %d", len(all))
+		}
+
+		if err := s.DeleteGraphNodes(ctx, other.RootPubkey); err != nil {
+			t.Fatalf("Failed to delete graph nodes: %v", err)
+		}
+
+		// Get following pubkeys
+		following, err := s.GetFollowingPubkeys(ctx, node.RootPubkey)
+		if err != nil {
+			t.Fatalf("Failed to get following pubkeys: %v", err)
+		}
+
+		if len(following) != 1 {
+			t.Errorf("Expected 1 following, got %d", len(following))
+		}
+
+		// Get mutual pubkeys
+		mutuals, err := s.GetMutualPubkeys(ctx, node.RootPubkey)
+		if err != nil {
+			t.Fatalf("Failed to get mutual pubkeys: %v", err)
+		}
+
+		if len(mutuals) != 1 {
+			t.Errorf("Expected 1 mutual, got %d", len(mutuals))
+		}
+
+		// Delete graph nodes
+		if err := s.DeleteGraphNodes(ctx, node.RootPubkey); err != nil {
+			t.Fatalf("Failed to delete graph nodes: %v", err)
+		}
+
+		nodes, err = s.GetGraphNodes(ctx, node.RootPubkey, 2)
+		if err != nil {
+			t.Fatalf("Failed to get graph nodes after delete: %v", err)
+		}
+
+		if len(nodes) != 0 {
+			t.Errorf("Expected 0 nodes after delete, got %d", len(nodes))
+		}
+	})
+}
 
-	nodes, err = s.GetGraphNodes(ctx, node.RootPubkey, 2)
-	if err != nil {
-		t.Fatalf("Failed to get graph nodes after delete: %v", err)
-	}
+func TestUpdateGraphNodes(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, s *Storage) {
+		ctx := context.Background()
+		root := "root-pubkey"
+
+		save := []*GraphNode{
+			{RootPubkey: root, Pubkey: "alice", Depth: 1, Mutual: true, LastSeen: 100},
+			{RootPubkey: root, Pubkey: "bob", Depth: 2, LastSeen: 100},
+		}
+		if err := s.UpdateGraphNodes(ctx, root, save, nil); err != nil {
+			t.Fatalf("Failed to update graph nodes: %v", err)
+		}
+
+		moved := []*GraphNode{{RootPubkey: root, Pubkey: "bob", Depth: 1, LastSeen: 200}}
+		if err := s.UpdateGraphNodes(ctx, root, moved, []string{"alice"}); err != nil {
+			t.Fatalf("Failed to update graph nodes: %v", err)
+		}
+
+		nodes, err := s.GetGraphNodes(ctx, root, 2)
+		if err != nil {
+			t.Fatalf("Failed to get graph nodes: %v", err)
+		}
+		if len(nodes) != 1 || nodes[0].Pubkey != "bob" || nodes[0].Depth != 1 || nodes[0].LastSeen != 200 {
+			t.Errorf("Expected bob alone at depth 1, got %+v", nodes)
+		}
+	})
+}
 
-	if len(nodes) != 0 {
-		t.Errorf("Expected 0 nodes after delete, got %d", len(nodes))
-	}
+func TestContactLists(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, s *Storage) {
+		ctx := context.Background()
+
+		if _, err := s.GetContactList(ctx, "alice"); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows for a missing contact list, got %v", err)
+		}
+
+		list := &ContactList{Pubkey: "alice", EventID: "event1", CreatedAt: 100}
+		if err := s.SaveContactList(ctx, list, []string{"bob", "carol"}, nil); err != nil {
+			t.Fatalf("Failed to save contact list: %v", err)
+		}
+		if err := s.SaveContactList(ctx, &ContactList{Pubkey: "bob", EventID: "event2", CreatedAt: 100}, []string{"carol"}, nil); err != nil {
+			t.Fatalf("Failed to save contact list: %v", err)
+		}
+
+		// A newer list drops bob and adds dave
+		list = &ContactList{Pubkey: "alice", EventID: "event3", CreatedAt: 200}
+		if err := s.SaveContactList(ctx, list, []string{"dave"}, []string{"bob"}); err != nil {
+			t.Fatalf("Failed to save contact list: %v", err)
+		}
+
+		got, err := s.GetContactList(ctx, "alice")
+		if err != nil {
+			t.Fatalf("Failed to get contact list: %v", err)
+		}
+		if *got != *list {
+			t.Errorf("Expected %+v, got %+v", list, got)
+		}
+
+		follows, err := s.GetFollows(ctx, "alice")
+		if err != nil {
+			t.Fatalf("Failed to get follows: %v", err)
+		}
+		if strings.Join(follows, ",") != "carol,dave" {
+			t.Errorf("Expected alice to follow carol and dave, got %v", follows)
+		}
+
+		followers, err := s.GetFollowers(ctx, "carol")
+		if err != nil {
+			t.Fatalf("Failed to get followers: %v", err)
+		}
+		if strings.Join(followers, ",") != "alice,bob" {
+			t.Errorf("Expected carol to be followed by alice and bob, got %v", followers)
+		}
+
+		following, err := s.IsFollowing(ctx, "alice", "bob")
+		if err != nil || following {
+			t.Errorf("Expected alice to no longer follow bob, got %v (%v)", following, err)
+		}
+
+		all, err := s.GetAllFollows(ctx)
+		if err != nil {
+			t.Fatalf("Failed to get all follows: %v", err)
+		}
+		if len(all) != 2 || len(all["alice"]) != 2 || len(all["bob"]) != 1 {
+			t.Errorf("Expected 3 follows from 2 authors, got %v", all)
+		}
+
+		count, err := s.CountContactLists(ctx)
+		if err != nil || count != 2 {
+			t.Errorf("Expected 2 contact lists, got %d (%v)", count, err)
+		}
+	})
 }
 
 func TestSyncState(t *testing.T) {
-	s, cleanup := setupTestStorage(t)
-	defer cleanup()
-
-	ctx := context.Background()
-
-	state := &SyncState{
-		Relay:     "wss://relay.test",
-		Kind:      1,
-		Since:     12345,
-		UpdatedAt: 67890,
-	}
-
-	// Save sync state
-	if err := s.SaveSyncState(ctx, state); err != nil {
-		t.Fatalf("Failed to save sync state: %v", err)
-	}
-
-	// Get sync state
-	retrieved, err := s.GetSyncState(ctx, state.Relay, state.Kind)
-	if err != nil {
-		t.Fatalf("Failed to get sync state: %v", err)
-	}
-
-	if retrieved.Since != state.Since {
-		t.Errorf("Expected since %d, got %d", state.Since, retrieved.Since)
-	}
-
-	// Update sync cursor
-	newSince := int64(99999)
-	if err := s.UpdateSyncCursor(ctx, state.Relay, state.Kind, newSince); err != nil {
-		t.Fatalf("Failed to update sync cursor: %v", err)
-	}
-
-	retrieved, err = s.GetSyncState(ctx, state.Relay, state.Kind)
-	if err != nil {
-		t.Fatalf("Failed to get sync state after update: %v", err)
-	}
-
-	if retrieved.Since != newSince {
-		t.Errorf("Expected since %d after update, got %d", newSince, retrieved.Since)
-	}
-
-	// Get all sync states
-	states, err := s.GetAllSyncStates(ctx)
-	if err != nil {
-		t.Fatalf("Failed to get all sync states: %v", err)
-	}
-
-	if len(states) != 1 {
-		t.Errorf("Expected 1 sync state, got %d", len(states))
-	}
-
-	// Delete sync state
-	if err := s.DeleteSyncState(ctx, state.Relay, state.Kind); err != nil {
-		t.Fatalf("Failed to delete sync state: %v", err)
-	}
-
-	states, err = s.GetAllSyncStates(ctx)
-	if err != nil {
-		t.Fatalf("Failed to get all sync states after delete: %v", err)
-	}
-
-	if len(states) != 0 {
-		t.Errorf("Expected 0 sync states after delete, got %d", len(states))
-	}
+	forEachDriver(t, func(t *testing.T, s *Storage) {
+		ctx := context.Background()
+
+		state := &SyncState{
+			Relay:     "wss://relay.test",
+			Kind:      1,
+			Since:     12345,
+			UpdatedAt: 67890,
+		}
+
+		// Save sync state
+		if err := s.SaveSyncState(ctx, state); err != nil {
+			t.Fatalf("Failed to save sync state: %v", err)
+		}
+
+		// Get sync state
+		retrieved, err := s.GetSyncState(ctx, state.Relay, state.Kind)
+		if err != nil {
+			t.Fatalf("Failed to get sync state: %v", err)
+		}
+
+		if retrieved.Since != state.Since {
+			t.Errorf("Expected since %d, got %d", state.Since, retrieved.Since)
+		}
+
+		// Update sync cursor
+		newSince := int64(99999)
+		if err := s.UpdateSyncCursor(ctx, state.Relay, state.Kind, newSince); err != nil {
+			t.Fatalf("Failed to update sync cursor: %v", err)
+		}
+
+		retrieved, err = s.GetSyncState(ctx, state.Relay, state.Kind)
+		if err != nil {
+			t.Fatalf("Failed to get sync state after update: %v", err)
+		}
+
+		if retrieved.Since != newSince {
+			t.Errorf("Expected since %d after update, got %d", newSince, retrieved.Since)
+		}
+
+		// Get all sync states
+		states, err := s.GetAllSyncStates(ctx)
+		if err != nil {
+			t.Fatalf("Failed to get all sync states: %v", err)
+		}
+
+		if len(states) != 1 {
+			t.Errorf("Expected 1 sync state, got %d", len(states))
+		}
+
+		// Delete sync state
+		if err := s.DeleteSyncState(ctx, state.Relay, state.Kind); err != nil {
+			t.Fatalf("Failed to delete sync state: %v", err)
+		}
+
+		states, err = s.GetAllSyncStates(ctx)
+		if err != nil {
+			t.Fatalf("Failed to get all sync states after delete: %v", err)
+		}
+
+		if len(states) != 0 {
+			t.Errorf("Expected 0 sync states after delete, got %d", len(states))
+		}
+	})
 }
 
 func TestAggregates(t *testing.T) {
-	s, cleanup := setupTestStorage(t)
-	defer cleanup()
-
-	ctx := context.Background()
-
-	agg := &Aggregate{
-		EventID:           "event-123",
-		ReplyCount:        5,
-		ReactionTotal:     10,
-		ReactionCounts:    map[string]int{"+": 8, "❤️": 2},
-		ZapSatsTotal:      1000,
-		LastInteractionAt: 12345,
-	}
-
-	// Save aggregate
-	if err := s.SaveAggregate(ctx, agg); err != nil {
-		t.Fatalf("Failed to save aggregate: %v", err)
-	}
-
-	// Get aggregate
-	retrieved, err := s.GetAggregate(ctx, agg.EventID)
-	if err != nil {
-		t.Fatalf("Failed to get aggregate: %v", err)
-	}
-
-	if retrieved.ReplyCount != agg.ReplyCount {
-		t.Errorf("Expected reply count %d, got %d", agg.ReplyCount, retrieved.ReplyCount)
-	}
-
-	if retrieved.ReactionCounts["+"] != 8 {
-		t.Errorf("Expected + reaction count 8, got %d", retrieved.ReactionCounts["+"])
-	}
-
-	// Increment reply count
-	if err := s.IncrementReplyCount(ctx, agg.EventID, 12346); err != nil {
-		t.Fatalf("Failed to increment reply count: %v", err)
-	}
-
-	retrieved, err = s.GetAggregate(ctx, agg.EventID)
-	if err != nil {
-		t.Fatalf("Failed to get aggregate after increment: %v", err)
-	}
-
-	if retrieved.ReplyCount != 6 {
-		t.Errorf("Expected reply count 6 after increment, got %d", retrieved.ReplyCount)
-	}
-
-	// Increment reaction
-	if err := s.IncrementReaction(ctx, agg.EventID, "🔥", 12347); err != nil {
-		t.Fatalf("Failed to increment reaction: %v", err)
-	}
-
-	retrieved, err = s.GetAggregate(ctx, agg.EventID)
-	if err != nil {
-		t.Fatalf("Failed to get aggregate after reaction: %v", err)
-	}
-
-	if retrieved.ReactionTotal != 11 {
-		t.Errorf("Expected reaction total 11, got %d", retrieved.ReactionTotal)
-	}
-
-	// Add zap amount
-	if err := s.AddZapAmount(ctx, agg.EventID, 500, 12348); err != nil {
-		t.Fatalf("Failed to add zap amount: %v", err)
-	}
+	forEachDriver(t, func(t *testing.T, s *Storage) {
+		ctx := context.Background()
+
+		agg := &Aggregate{
+			EventID:           "event-123",
+			ReplyCount:        5,
+			ReactionTotal:     10,
+			ReactionCounts:    map[string]int{"+": 8, "❤️": 2},
+			ZapSatsTotal:      1000,
+			LastInteractionAt: 12345,
+		}
+
+		// Save aggregate
+		if err := s.SaveAggregate(ctx, agg); err != nil {
+			t.Fatalf("Failed to save aggregate: %v", err)
+		}
+
+		// Get aggregate
+		retrieved, err := s.GetAggregate(ctx, agg.EventID)
+		if err != nil {
+			t.Fatalf("Failed to get aggregate: %v", err)
+		}
+
+		if retrieved.ReplyCount != agg.ReplyCount {
+			t.Errorf("Expected reply count %d, got %d", agg.ReplyCount, retrieved.ReplyCount)
+		}
+
+		if retrieved.ReactionCounts["+"] != 8 {
+			t.Errorf("Expected + reaction count 8, got %d", retrieved.ReactionCounts["+"])
+		}
+
+		// Increment reply count
+		if err := s.IncrementReplyCount(ctx, agg.EventID, 12346); err != nil {
+			t.Fatalf("Failed to increment reply count: %v", err)
+		}
+
+		retrieved, err = s.GetAggregate(ctx, agg.EventID)
+		if err != nil {
+			t.Fatalf("Failed to get aggregate after increment: %v", err)
+		}
+
+		if retrieved.ReplyCount != 6 {
+			t.Errorf("Expected reply count 6 after increment, got %d", retrieved.ReplyCount)
+		}
+
+		// Increment reaction
+		if err := s.IncrementReaction(ctx, agg.EventID, "🔥", 12347); err != nil {
+			t.Fatalf("Failed to increment reaction: %v", err)
+		}
+
+		retrieved, err = s.GetAggregate(ctx, agg.EventID)
+		if err != nil {
+			t.Fatalf("Failed to get aggregate after reaction: %v", err)
+		}
+
+		if retrieved.ReactionTotal != 11 {
+			t.Errorf("Expected reaction total 11, got %d", retrieved.ReactionTotal)
+		}
+
+		// Add zap amount
+		if err := s.AddZapAmount(ctx, agg.EventID, 500, 12348); err != nil {
+			t.Fatalf("Failed to add zap amount: %v", err)
+		}
+
+		retrieved, err = s.GetAggregate(ctx, agg.EventID)
+		if err != nil {
+			t.Fatalf("Failed to get aggregate after zap: %v", err)
+		}
+
+		if retrieved.ZapSatsTotal != 1500 {
+			t.Errorf("Expected zap sats total 1500, got %d", retrieved.ZapSatsTotal)
+		}
+
+		// Get aggregates (batch)
+		aggregates, err := s.GetAggregates(ctx, []string{agg.EventID, "nonexistent"})
+		if err != nil {
+			t.Fatalf("Failed to get aggregates: %v", err)
+		}
+
+		if len(aggregates) != 1 {
+			t.Errorf("Expected 1 aggregate, got %d", len(aggregates))
+		}
+
+		// Delete aggregate
+		if err := s.DeleteAggregate(ctx, agg.EventID); err != nil {
+			t.Fatalf("Failed to delete aggregate: %v", err)
+		}
+
+		_, err = s.GetAggregate(ctx, agg.EventID)
+		if err == nil {
+			t.Error("Expected error when getting deleted aggregate, got nil")
+		}
+	})
+}
 
-	retrieved, err = s.GetAggregate(ctx, agg.EventID)
-	if err != nil {
-		t.Fatalf("Failed to get aggregate after zap: %v", err)
-	}
+func TestZaps(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, s *Storage) {
+		ctx := context.Background()
+
+		zaps := []*Zap{
+			{ReceiptID: "receipt-1", EventID: "event-123", Recipient: "alice", Sender: "bob", AmountSats: 21, Comment: "nice", CreatedAt: 100},
+			{ReceiptID: "receipt-2", EventID: "event-123", Recipient: "alice", Sender: "carol", AmountSats: 1000, CreatedAt: 200},
+			{ReceiptID: "receipt-3", Recipient: "alice", Sender: "bob", AmountSats: 500, CreatedAt: 300},
+		}
+		for _, z := range zaps {
+			inserted, err := s.SaveZap(ctx, z)
+			if err != nil {
+				t.Fatalf("Failed to save zap: %v", err)
+			}
+			if !inserted {
+				t.Errorf("Expected %s to be inserted", z.ReceiptID)
+			}
+		}
+
+		// The same receipt again is not recorded twice
+		inserted, err := s.SaveZap(ctx, zaps[0])
+		if err != nil {
+			t.Fatalf("Failed to save zap: %v", err)
+		}
+		if inserted {
+			t.Error("Expected duplicate receipt to be ignored")
+		}
+
+		eventZaps, err := s.GetZaps(ctx, "event-123")
+		if err != nil {
+			t.Fatalf("Failed to get zaps: %v", err)
+		}
+		if len(eventZaps) != 2 || eventZaps[0].ReceiptID != "receipt-2" || eventZaps[1].Comment != "nice" {
+			t.Errorf("Unexpected event zaps: %+v", eventZaps)
+		}
+
+		profileZaps, err := s.GetProfileZaps(ctx, "alice")
+		if err != nil {
+			t.Fatalf("Failed to get profile zaps: %v", err)
+		}
+		if len(profileZaps) != 1 || profileZaps[0].ReceiptID != "receipt-3" {
+			t.Errorf("Unexpected profile zaps: %+v", profileZaps)
+		}
+	})
+}
 
-	if retrieved.ZapSatsTotal != 1500 {
-		t.Errorf("Expected zap sats total 1500, got %d", retrieved.ZapSatsTotal)
-	}
+func TestEventStats(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, s *Storage) {
+		ctx := context.Background()
+
+		for i, kind := range []int{1, 1, 7} {
+			event := &nostr.Event{
+				ID:        strings.Repeat(string(rune('a'+i)), 64),
+				PubKey:    strings.Repeat("f", 64),
+				CreatedAt: nostr.Timestamp(1000 + i),
+				Kind:      kind,
+				Tags:      nostr.Tags{},
+				Sig:       strings.Repeat("c", 128),
+			}
+			if err := s.StoreEvent(ctx, event); err != nil {
+				t.Fatalf("Failed to store event: %v", err)
+			}
+		}
+
+		byKind, err := s.CountEventsByKind(ctx)
+		if err != nil {
+			t.Fatalf("Failed to count events by kind: %v", err)
+		}
+
+		if byKind[1] != 2 || byKind[7] != 1 {
+			t.Errorf("Expected counts {1:2 7:1}, got %v", byKind)
+		}
+
+		before, err := s.CountEventsBefore(ctx, time.Unix(1002, 0))
+		if err != nil {
+			t.Fatalf("Failed to count events before: %v", err)
+		}
+
+		if before != 2 {
+			t.Errorf("Expected 2 events before 1002, got %d", before)
+		}
+
+		deleted, err := s.DeleteEventsByKind(ctx, 7)
+		if err != nil {
+			t.Fatalf("Failed to delete events by kind: %v", err)
+		}
+
+		if deleted != 1 {
+			t.Errorf("Expected 1 deleted event, got %d", deleted)
+		}
+
+		count, err := s.CountEvents(ctx)
+		if err != nil {
+			t.Fatalf("Failed to count events: %v", err)
+		}
+
+		if count != 2 {
+			t.Errorf("Expected 2 events after delete, got %d", count)
+		}
+	})
+}
 
-	// Get aggregates (batch)
-	aggregates, err := s.GetAggregates(ctx, []string{agg.EventID, "nonexistent"})
-	if err != nil {
-		t.Fatalf("Failed to get aggregates: %v", err)
-	}
+func TestScanEvents(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, s *Storage) {
+		ctx := context.Background()
 
-	if len(aggregates) != 1 {
-		t.Errorf("Expected 1 aggregate, got %d", len(aggregates))
-	}
+		// More events than the eventstore returns from one query, and than
+		// one page of a key-value scan
+		defer func(page int) { kvEventPage = page }(kvEventPage)
+		kvEventPage = 40
 
-	// Delete aggregate
-	if err := s.DeleteAggregate(ctx, agg.EventID); err != nil {
-		t.Fatalf("Failed to delete aggregate: %v", err)
-	}
+		const total = 150
+		for i := 0; i < total; i++ {
+			kind := 1
+			if i%3 == 0 {
+				kind = 7
+			}
+			event := &nostr.Event{
+				ID:        fmt.Sprintf("%064x", i),
+				PubKey:    strings.Repeat("f", 64),
+				CreatedAt: nostr.Timestamp(2000 - i),
+				Kind:      kind,
+				Tags:      nostr.Tags{{"t", "test"}},
+				Sig:       strings.Repeat("c", 128),
+			}
+			if err := s.StoreEvent(ctx, event); err != nil {
+				t.Fatalf("Failed to store event: %v", err)
+			}
+		}
+
+		var scanned []*nostr.Event
+		if err := s.ScanEvents(ctx, nostr.Filter{}, func(event *nostr.Event) error {
+			scanned = append(scanned, event)
+			return nil
+		}); err != nil {
+			t.Fatalf("Failed to scan events: %v", err)
+		}
+
+		if len(scanned) != total {
+			t.Fatalf("Expected %d events, got %d", total, len(scanned))
+		}
+		for i := 1; i < len(scanned); i++ {
+			if scanned[i].CreatedAt > scanned[i-1].CreatedAt {
+				t.Fatalf("Expected events newest first, got %d after %d", scanned[i].CreatedAt, scanned[i-1].CreatedAt)
+			}
+		}
+		if len(scanned[0].Tags) != 1 || scanned[0].Tags[0][1] != "test" {
+			t.Errorf("Expected tags to be read back, got %v", scanned[0].Tags)
+		}
+
+		since := nostr.Timestamp(1900)
+		var reactions int
+		if err := s.ScanEvents(ctx, nostr.Filter{Kinds: []int{7}, Since: &since}, func(event *nostr.Event) error {
+			if event.Kind != 7 || event.CreatedAt < since {
+				t.Errorf("Event %s does not match the filter", event.ID)
+			}
+			reactions++
+			return nil
+		}); err != nil {
+			t.Fatalf("Failed to scan events: %v", err)
+		}
+
+		if reactions != 34 {
+			t.Errorf("Expected 34 recent reactions, got %d", reactions)
+		}
+	})
+}
 
-	_, err = s.GetAggregate(ctx, agg.EventID)
-	if err == nil {
-		t.Error("Expected error when getting deleted aggregate, got nil")
-	}
+func TestCountInteractionsWith(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, s *Storage) {
+		ctx := context.Background()
+		owner := strings.Repeat("a", 64)
+		alice := strings.Repeat("b", 64)
+		bob := strings.Repeat("c", 64)
+
+		events := []*nostr.Event{
+			{Kind: 1, PubKey: alice, CreatedAt: 1000, Tags: nostr.Tags{{"e", "x"}, {"p", owner}}},
+			{Kind: 7, PubKey: alice, CreatedAt: 1001, Tags: nostr.Tags{{"p", owner}}},
+			{Kind: 7, PubKey: alice, CreatedAt: 10, Tags: nostr.Tags{{"p", owner}}},   // too old
+			{Kind: 1, PubKey: bob, CreatedAt: 1002, Tags: nostr.Tags{{"p", alice}}},   // someone else
+			{Kind: 1, PubKey: owner, CreatedAt: 1003, Tags: nostr.Tags{{"p", owner}}}, // the owner
+		}
+		for i, event := range events {
+			event.ID = fmt.Sprintf("%064x", i+1)
+			event.Sig = strings.Repeat("c", 128)
+			if err := s.StoreEvent(ctx, event); err != nil {
+				t.Fatalf("Failed to store event: %v", err)
+			}
+		}
+		if _, err := s.SaveZap(ctx, &Zap{ReceiptID: "r1", Recipient: owner, Sender: bob, AmountSats: 21, CreatedAt: 1004}); err != nil {
+			t.Fatalf("Failed to save zap: %v", err)
+		}
+
+		counts, err := s.CountInteractionsWith(ctx, owner, 500)
+		if err != nil {
+			t.Fatalf("Failed to count interactions: %v", err)
+		}
+
+		if len(counts) != 2 || counts[alice] != 2 || counts[bob] != 1 {
+			t.Errorf("Expected {alice: 2, bob: 1}, got %v", counts)
+		}
+	})
 }
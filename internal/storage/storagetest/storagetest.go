@@ -0,0 +1,50 @@
+// Package storagetest provides event fixtures for tests that render stored
+// events.
+package storagetest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+// StoreSigned signs an event with priv and stores it, returning the author's
+// pubkey
+func StoreSigned(t testing.TB, st *storage.Storage, priv string, kind int, createdAt time.Time, tags nostr.Tags, content string) string {
+	t.Helper()
+
+	pub, _ := nostr.GetPublicKey(priv)
+	event := nostr.Event{Kind: kind, PubKey: pub, CreatedAt: nostr.Timestamp(createdAt.Unix()), Tags: tags, Content: content}
+	if err := event.Sign(priv); err != nil {
+		t.Fatalf("Failed to sign event: %v", err)
+	}
+	if err := st.StoreEvent(context.Background(), &event); err != nil {
+		t.Fatalf("Failed to store event: %v", err)
+	}
+	return pub
+}
+
+// SeedSectionGroups stores profiles, notes and an article from two authors
+// across October 2025, for section grouping tests, and returns their
+// pubkeys. Fixed keys and timestamps keep event IDs, and so rendered output,
+// stable.
+func SeedSectionGroups(t testing.TB, st *storage.Storage) (alice, bob string) {
+	t.Helper()
+
+	alicePriv := strings.Repeat("1", 64)
+	bobPriv := strings.Repeat("2", 64)
+	day := func(d, h int) time.Time { return time.Date(2025, time.October, d, h, 0, 0, 0, time.UTC) }
+
+	alice = StoreSigned(t, st, alicePriv, 0, day(1, 0), nostr.Tags{}, `{"name":"alice"}`)
+	bob = StoreSigned(t, st, bobPriv, 0, day(1, 0), nostr.Tags{}, `{"display_name":"Bob"}`)
+	StoreSigned(t, st, alicePriv, 1, day(24, 9), nostr.Tags{}, "first light")
+	StoreSigned(t, st, bobPriv, 1, day(24, 8), nostr.Tags{}, "coffee")
+	StoreSigned(t, st, alicePriv, 1, day(23, 22), nostr.Tags{}, "late post")
+	StoreSigned(t, st, bobPriv, 30023, day(20, 12), nostr.Tags{{"d", "gophers"}, {"title", "On Gophers"}}, "Long form")
+	StoreSigned(t, st, alicePriv, 1, day(13, 7), nostr.Tags{}, "last week")
+	return alice, bob
+}
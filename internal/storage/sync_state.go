@@ -16,6 +16,10 @@ type SyncState struct {
 
 // SaveSyncState stores or updates a sync state cursor
 func (s *Storage) SaveSyncState(ctx context.Context, state *SyncState) error {
+	if s.kv != nil {
+		return s.kvSaveSyncState(state)
+	}
+
 	query := `
 		INSERT INTO sync_state (relay, kind, since, updated_at)
 		VALUES (?, ?, ?, ?)
@@ -35,6 +39,10 @@ func (s *Storage) SaveSyncState(ctx context.Context, state *SyncState) error {
 
 // GetSyncState retrieves the sync state for a relay/kind pair
 func (s *Storage) GetSyncState(ctx context.Context, relay string, kind int) (*SyncState, error) {
+	if s.kv != nil {
+		return s.kvGetSyncState(relay, kind)
+	}
+
 	query := `
 		SELECT relay, kind, since, updated_at
 		FROM sync_state
@@ -65,6 +73,10 @@ func (s *Storage) UpdateSyncCursor(ctx context.Context, relay string, kind int,
 
 // GetAllSyncStates retrieves all sync states
 func (s *Storage) GetAllSyncStates(ctx context.Context) ([]*SyncState, error) {
+	if s.kv != nil {
+		return s.kvGetAllSyncStates()
+	}
+
 	query := `
 		SELECT relay, kind, since, updated_at
 		FROM sync_state
@@ -97,6 +109,10 @@ func (s *Storage) GetAllSyncStates(ctx context.Context) ([]*SyncState, error) {
 
 // DeleteSyncState removes sync state for a relay/kind pair
 func (s *Storage) DeleteSyncState(ctx context.Context, relay string, kind int) error {
+	if s.kv != nil {
+		return s.kvDeleteSyncState(relay, kind)
+	}
+
 	query := `DELETE FROM sync_state WHERE relay = ? AND kind = ?`
 	_, err := s.db.ExecContext(ctx, query, relay, kind)
 	if err != nil {
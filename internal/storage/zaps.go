@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// Zap is a validated NIP-57 zap receipt, kept per sender so pages can show
+// who zapped and what they said. Profile zaps have no EventID.
+type Zap struct {
+	ReceiptID  string // kind 9735 receipt ID
+	EventID    string // zapped event, "" for a profile zap
+	Recipient  string // zapped pubkey (the receipt's "p" tag)
+	Sender     string // pubkey of the zap request
+	AmountSats int64
+	Comment    string // zap request content
+	CreatedAt  int64  // created_at of the receipt
+}
+
+// SaveZap records a zap. It reports false if the receipt was already
+// recorded, so callers add each receipt to totals only once.
+func (s *Storage) SaveZap(ctx context.Context, z *Zap) (bool, error) {
+	if s.kv != nil {
+		return s.kvSaveZap(z)
+	}
+
+	query := `
+		INSERT INTO zaps (receipt_id, event_id, recipient, sender, amount_sats, comment, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(receipt_id) DO NOTHING
+	`
+
+	result, err := s.db.ExecContext(ctx, query,
+		z.ReceiptID, z.EventID, z.Recipient, z.Sender, z.AmountSats, z.Comment, z.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to save zap: %w", err)
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to save zap: %w", err)
+	}
+
+	return inserted > 0, nil
+}
+
+// GetZaps returns the zaps of an event, newest first
+func (s *Storage) GetZaps(ctx context.Context, eventID string) ([]*Zap, error) {
+	if s.kv != nil {
+		return s.kvGetZaps(zapEventPrefix(eventID))
+	}
+
+	return s.queryZaps(ctx, `WHERE event_id = ?`, eventID)
+}
+
+// GetProfileZaps returns the zaps of a pubkey's profile (not of its
+// events), newest first
+func (s *Storage) GetProfileZaps(ctx context.Context, pubkey string) ([]*Zap, error) {
+	if s.kv != nil {
+		return s.kvGetZaps(zapProfilePrefix(pubkey))
+	}
+
+	return s.queryZaps(ctx, `WHERE event_id = '' AND recipient = ?`, pubkey)
+}
+
+// queryZaps selects zaps matching a WHERE clause
+func (s *Storage) queryZaps(ctx context.Context, where string, args ...interface{}) ([]*Zap, error) {
+	query := `
+		SELECT receipt_id, event_id, recipient, sender, amount_sats, comment, created_at
+		FROM zaps
+	` + where + `
+		ORDER BY created_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query zaps: %w", err)
+	}
+	defer rows.Close()
+
+	var zaps []*Zap
+	for rows.Next() {
+		var z Zap
+		if err := rows.Scan(&z.ReceiptID, &z.EventID, &z.Recipient, &z.Sender,
+			&z.AmountSats, &z.Comment, &z.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan zap: %w", err)
+		}
+		zaps = append(zaps, &z)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return zaps, nil
+}
+
+// zapEventPrefix is the key-value prefix of an event's zaps
+func zapEventPrefix(eventID string) string {
+	return kvKey("e", eventID, "")
+}
+
+// zapProfilePrefix is the key-value prefix of a profile's zaps
+func zapProfilePrefix(pubkey string) string {
+	return kvKey("p", pubkey, "")
+}
@@ -0,0 +1,133 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/aggregates"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+// DeletionHandler is notified after a NIP-09 deletion request has removed
+// events from storage. targets holds the removed events.
+type DeletionHandler func(ctx context.Context, deletion *nostr.Event, targets []*nostr.Event)
+
+// AddDeletionHandler registers a handler for applied deletions
+func (e *Engine) AddDeletionHandler(handler DeletionHandler) {
+	if handler == nil {
+		return
+	}
+	e.deletionHandlers = append(e.deletionHandlers, handler)
+}
+
+// applyDeletion applies a kind 5 deletion request (NIP-09). Every "e" and "a"
+// target is recorded so late-arriving copies are rejected, and targets already
+// stored are removed when the deletion's author is also the target's author.
+// Interaction counts of the events the removed targets referenced (e.g. the
+// note a deleted reply or reaction pointed at) are recomputed.
+func (e *Engine) applyDeletion(ctx context.Context, deletion *nostr.Event) error {
+	var targets []*nostr.Event
+
+	for _, tag := range deletion.Tags {
+		if len(tag) < 2 || (tag[0] != "e" && tag[0] != "a") {
+			continue
+		}
+
+		var filter nostr.Filter
+		switch tag[0] {
+		case "e":
+			filter = nostr.Filter{IDs: []string{tag[1]}, Authors: []string{deletion.PubKey}}
+		case "a":
+			f, ok := addressFilter(tag[1], deletion)
+			if !ok {
+				continue
+			}
+			filter = f
+		}
+
+		if err := e.storage.SaveDeletion(ctx, &storage.Deletion{
+			Target:     tag[1],
+			Pubkey:     deletion.PubKey,
+			DeletionID: deletion.ID,
+			CreatedAt:  int64(deletion.CreatedAt),
+		}); err != nil {
+			return err
+		}
+
+		events, err := e.storage.QueryEvents(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("failed to query deletion targets: %w", err)
+		}
+		for _, target := range events {
+			// Deleting a deletion request has no effect
+			if target.Kind != 5 {
+				targets = append(targets, target)
+			}
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil
+	}
+
+	referenced := make(map[string]bool)
+	for _, target := range targets {
+		if err := e.storage.DeleteEvent(ctx, target.ID); err != nil {
+			return err
+		}
+		if err := e.storage.DeleteAggregate(ctx, target.ID); err != nil {
+			return fmt.Errorf("failed to delete aggregate: %w", err)
+		}
+
+		for _, tag := range target.Tags {
+			if len(tag) >= 2 && tag[0] == "e" {
+				referenced[tag[1]] = true
+			}
+		}
+	}
+
+	reconciler := aggregates.NewReconciler(e.storage, nil)
+	for eventID := range referenced {
+		if err := reconciler.ReconcileEvent(ctx, eventID); err != nil {
+			fmt.Printf("[SYNC]   ⚠ Failed to recompute aggregates for %s: %v\n", eventID, err)
+		}
+	}
+
+	fmt.Printf("[SYNC]   ✓ Deletion %s removed %d event(s)\n", deletion.ID[:16]+"...", len(targets))
+
+	for _, handler := range e.deletionHandlers {
+		handler(ctx, deletion, targets)
+	}
+
+	return nil
+}
+
+// addressFilter builds the filter for an "a" tag target ("kind:pubkey:d").
+// Only the deletion's author may delete an address, and only versions created
+// up to the deletion request.
+func addressFilter(address string, deletion *nostr.Event) (nostr.Filter, bool) {
+	parts := strings.SplitN(address, ":", 3)
+	if len(parts) != 3 || parts[1] != deletion.PubKey {
+		return nostr.Filter{}, false
+	}
+
+	kind, err := strconv.Atoi(parts[0])
+	if err != nil || !(nostr.IsReplaceableKind(kind) || nostr.IsAddressableKind(kind)) {
+		return nostr.Filter{}, false
+	}
+
+	until := deletion.CreatedAt
+	filter := nostr.Filter{
+		Kinds:   []int{kind},
+		Authors: []string{deletion.PubKey},
+		Until:   &until,
+	}
+	if nostr.IsAddressableKind(kind) {
+		filter.Tags = nostr.TagMap{"d": []string{parts[2]}}
+	}
+
+	return filter, true
+}
@@ -0,0 +1,134 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+func signedEvent(t *testing.T, sk string, kind int, createdAt nostr.Timestamp, tags nostr.Tags, content string) *nostr.Event {
+	t.Helper()
+
+	event := &nostr.Event{Kind: kind, CreatedAt: createdAt, Tags: tags, Content: content}
+	if event.Tags == nil {
+		event.Tags = nostr.Tags{}
+	}
+	if err := event.Sign(sk); err != nil {
+		t.Fatalf("Failed to sign event: %v", err)
+	}
+	return event
+}
+
+func TestApplyDeletion(t *testing.T) {
+	_, st, cleanup := setupTestGraph(t)
+	defer cleanup()
+
+	engine := NewEngine(st, config.Default())
+	defer engine.cancel()
+
+	var deleted []*nostr.Event
+	engine.AddDeletionHandler(func(_ context.Context, _ *nostr.Event, targets []*nostr.Event) {
+		deleted = append(deleted, targets...)
+	})
+
+	ctx := context.Background()
+	alice := nostr.GeneratePrivateKey()
+	alicePub, _ := nostr.GetPublicKey(alice)
+	bob := nostr.GeneratePrivateKey()
+
+	note := signedEvent(t, alice, 1, 100, nil, "hello")
+	reply := signedEvent(t, alice, 1, 110, nostr.Tags{{"e", note.ID, "", "reply"}}, "follow-up")
+	for _, event := range []*nostr.Event{note, reply} {
+		if err := engine.processEvent(event); err != nil {
+			t.Fatalf("processEvent() error = %v", err)
+		}
+	}
+	if err := st.SaveAggregate(ctx, &storage.Aggregate{EventID: note.ID, ReplyCount: 1, LastInteractionAt: 110}); err != nil {
+		t.Fatalf("Failed to save aggregate: %v", err)
+	}
+
+	exists := func(id string) bool {
+		ok, err := st.EventExists(ctx, id)
+		if err != nil {
+			t.Fatalf("EventExists() error = %v", err)
+		}
+		return ok
+	}
+
+	// Only the author may delete an event
+	if err := engine.processEvent(signedEvent(t, bob, 5, 120, nostr.Tags{{"e", note.ID}}, "")); err != nil {
+		t.Fatalf("processEvent() error = %v", err)
+	}
+	if !exists(note.ID) {
+		t.Error("Deletion by another pubkey should not remove the note")
+	}
+
+	// The author's deletion removes the reply and recomputes the parent's counts
+	if err := engine.processEvent(signedEvent(t, alice, 5, 130, nostr.Tags{{"e", reply.ID}}, "")); err != nil {
+		t.Fatalf("processEvent() error = %v", err)
+	}
+	if exists(reply.ID) {
+		t.Error("Expected the reply to be deleted")
+	}
+	if len(deleted) != 1 || deleted[0].ID != reply.ID {
+		t.Errorf("Expected deletion handler to receive the reply, got %v", deleted)
+	}
+	agg, err := st.GetAggregate(ctx, note.ID)
+	if err != nil {
+		t.Fatalf("GetAggregate() error = %v", err)
+	}
+	if agg.ReplyCount != 0 {
+		t.Errorf("Expected reply count 0 after deletion, got %d", agg.ReplyCount)
+	}
+
+	// A deleted event arriving later is rejected
+	if err := engine.processEvent(reply); err != nil {
+		t.Fatalf("processEvent() error = %v", err)
+	}
+	if exists(reply.ID) {
+		t.Error("Expected late copy of a deleted event to be rejected")
+	}
+
+	// Deletion before the target arrives
+	late := signedEvent(t, alice, 1, 140, nil, "never shown")
+	if err := engine.processEvent(signedEvent(t, alice, 5, 150, nostr.Tags{{"e", late.ID}}, "")); err != nil {
+		t.Fatalf("processEvent() error = %v", err)
+	}
+	if err := engine.processEvent(late); err != nil {
+		t.Fatalf("processEvent() error = %v", err)
+	}
+	if exists(late.ID) {
+		t.Error("Expected target of an earlier deletion to be rejected")
+	}
+
+	// Addressable events are deleted by address up to the deletion's created_at
+	address := fmt.Sprintf("30023:%s:post", alicePub)
+	article := signedEvent(t, alice, 30023, 200, nostr.Tags{{"d", "post"}}, "v1")
+	if err := engine.processEvent(article); err != nil {
+		t.Fatalf("processEvent() error = %v", err)
+	}
+	if err := engine.processEvent(signedEvent(t, alice, 5, 300, nostr.Tags{{"a", address}}, "")); err != nil {
+		t.Fatalf("processEvent() error = %v", err)
+	}
+	if exists(article.ID) {
+		t.Error("Expected the article to be deleted by address")
+	}
+
+	older := signedEvent(t, alice, 30023, 250, nostr.Tags{{"d", "post"}}, "v2")
+	newer := signedEvent(t, alice, 30023, 350, nostr.Tags{{"d", "post"}}, "v3")
+	for _, event := range []*nostr.Event{older, newer} {
+		if err := engine.processEvent(event); err != nil {
+			t.Fatalf("processEvent() error = %v", err)
+		}
+	}
+	if exists(older.ID) {
+		t.Error("Expected a version older than the deletion to be rejected")
+	}
+	if !exists(newer.ID) {
+		t.Error("Expected a version newer than the deletion to be stored")
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/sandwichfarm/nophr/internal/aggregates"
 	"github.com/sandwichfarm/nophr/internal/config"
 	internalnostr "github.com/sandwichfarm/nophr/internal/nostr"
 	"github.com/sandwichfarm/nophr/internal/storage"
@@ -19,8 +20,8 @@ type Engine struct {
 	storage       *storage.Storage
 	nostrClient   *internalnostr.Client
 	discovery     *internalnostr.Discovery
-	filterBuilder *FilterBuilder
-	graph         *Graph
+	scopeMu       sync.RWMutex
+	scope         *syncScope
 	cursors       *CursorManager
 
 	ctx    context.Context
@@ -35,22 +36,45 @@ type Engine struct {
 
 	// Performance optimizations (Balanced Plan - Tier 2)
 	aggregateChan chan *AggregateUpdate // Async aggregate processing
+	zaps          *aggregates.ZapQueue  // Async zap validation (LNURL lookups)
+	zapsOnce      sync.Once
+	mutes         *aggregates.MuteFilter
 
 	// Phase 20: Optional retention evaluation callback
 	evaluateRetention func(context.Context, *nostr.Event) error
 
-	eventHandlers []EventHandler
+	eventHandlers    []EventHandler
+	deletionHandlers []DeletionHandler
+
+	policies []IngestPolicy // checks run before an event is stored
+	ingest   ingestCounters
 }
 
 // AggregateUpdate represents a pending aggregate update
 type AggregateUpdate struct {
-	Type          string // "reply", "reaction", "zap"
+	Type          string // "reply", "reaction"
 	EventID       string
 	Reaction      string // For reactions
-	Sats          int64  // For zaps
 	InteractionAt int64
 }
 
+// syncScope bundles the settings that decide which events are synced, so a
+// reload swaps them together
+type syncScope struct {
+	config  *config.Sync
+	filters *FilterBuilder
+	graph   *Graph
+}
+
+// newSyncScope builds the filter builder and social graph for a sync config
+func newSyncScope(st *storage.Storage, cfg *config.Sync) *syncScope {
+	return &syncScope{
+		config:  cfg,
+		filters: NewFilterBuilder(cfg),
+		graph:   NewGraph(st, &cfg.Scope),
+	}
+}
+
 // EventHandler is notified for each stored event.
 type EventHandler func(context.Context, *nostr.Event)
 
@@ -59,8 +83,6 @@ func New(ctx context.Context, cfg *config.Config, st *storage.Storage, client *i
 	engineCtx, cancel := context.WithCancel(ctx)
 
 	discovery := internalnostr.NewDiscovery(client, st)
-	filterBuilder := NewFilterBuilder(&cfg.Sync)
-	graph := NewGraph(st, &cfg.Sync.Scope)
 	cursors := NewCursorManager(st)
 
 	return &Engine{
@@ -68,14 +90,16 @@ func New(ctx context.Context, cfg *config.Config, st *storage.Storage, client *i
 		storage:       st,
 		nostrClient:   client,
 		discovery:     discovery,
-		filterBuilder: filterBuilder,
-		graph:         graph,
+		scope:         newSyncScope(st, &cfg.Sync),
 		cursors:       cursors,
 		ctx:           engineCtx,
 		cancel:        cancel,
 		eventChan:     make(chan *nostr.Event, 5000),     // Tier 2: Larger buffer for burst handling
 		eventCache:    NewEventCache(5000),               // Tier 1: Cache last 5000 event IDs
 		aggregateChan: make(chan *AggregateUpdate, 1000), // Tier 2: Async aggregate queue
+		zaps:          aggregates.NewZapQueue(aggregates.NewZapProcessor(st, &cfg.Inbox)),
+		mutes:         aggregates.NewMuteFilter(st, cfg),
+		policies:      newIngestPolicies(cfg),
 	}
 }
 
@@ -88,8 +112,6 @@ func NewEngine(st *storage.Storage, cfg *config.Config) *Engine {
 	nostrClient := internalnostr.New(ctx, &cfg.Relays)
 
 	discovery := internalnostr.NewDiscovery(nostrClient, st)
-	filterBuilder := NewFilterBuilder(&cfg.Sync)
-	graph := NewGraph(st, &cfg.Sync.Scope)
 	cursors := NewCursorManager(st)
 
 	return &Engine{
@@ -97,14 +119,16 @@ func NewEngine(st *storage.Storage, cfg *config.Config) *Engine {
 		storage:       st,
 		nostrClient:   nostrClient,
 		discovery:     discovery,
-		filterBuilder: filterBuilder,
-		graph:         graph,
+		scope:         newSyncScope(st, &cfg.Sync),
 		cursors:       cursors,
 		ctx:           engineCtx,
 		cancel:        cancel,
 		eventChan:     make(chan *nostr.Event, 5000),     // Tier 2: Larger buffer for burst handling
 		eventCache:    NewEventCache(5000),               // Tier 1: Cache last 5000 event IDs
 		aggregateChan: make(chan *AggregateUpdate, 1000), // Tier 2: Async aggregate queue
+		zaps:          aggregates.NewZapQueue(aggregates.NewZapProcessor(st, &cfg.Inbox)),
+		mutes:         aggregates.NewMuteFilter(st, cfg),
+		policies:      newIngestPolicies(cfg),
 	}
 }
 
@@ -130,6 +154,8 @@ func (e *Engine) Start() error {
 	e.wg.Add(1)
 	go e.processAggregates()
 
+	e.startZaps()
+
 	// Start continuous sync
 	e.wg.Add(1)
 	go e.continuousSync()
@@ -157,6 +183,23 @@ func (e *Engine) AddEventHandler(handler EventHandler) {
 	e.eventHandlers = append(e.eventHandlers, handler)
 }
 
+// SetScope applies new sync kinds and scope from the next sync iteration on.
+// Workers, negentropy and ingest policies keep their startup settings.
+func (e *Engine) SetScope(cfg *config.Sync) {
+	scope := newSyncScope(e.storage, cfg)
+
+	e.scopeMu.Lock()
+	defer e.scopeMu.Unlock()
+	e.scope = scope
+}
+
+// currentScope returns the sync scope in effect
+func (e *Engine) currentScope() *syncScope {
+	e.scopeMu.RLock()
+	defer e.scopeMu.RUnlock()
+	return e.scope
+}
+
 // SetRetentionEvaluator sets the retention evaluation callback (Phase 20)
 func (e *Engine) SetRetentionEvaluator(fn func(context.Context, *nostr.Event) error) {
 	e.evaluateRetention = fn
@@ -206,10 +249,23 @@ func (e *Engine) bootstrap() error {
 	}
 	fmt.Printf("[SYNC] Fetched %d contact list events\n", len(events))
 
+	// Databases from before the graph kept follows get them from the stored
+	// contact lists once
+	graph := e.currentScope().graph
+	if rebuilt, err := graph.Backfill(e.ctx, ownerPubkey); err != nil {
+		return fmt.Errorf("failed to backfill social graph: %w", err)
+	} else if rebuilt {
+		// Those versions also filed live contact lists under the npub
+		if err := e.storage.DeleteGraphNodes(e.ctx, e.config.Identity.Npub); err != nil {
+			return fmt.Errorf("failed to remove stale graph nodes: %w", err)
+		}
+		fmt.Printf("[SYNC] ✓ Social graph rebuilt from stored contact lists\n")
+	}
+
 	if len(events) > 0 {
 		// Process the contact list to build the graph
 		fmt.Printf("[SYNC] Processing contact list (event ID: %s)\n", events[0].ID)
-		if err := e.graph.ProcessContactList(e.ctx, events[0], ownerPubkey); err != nil {
+		if err := graph.ProcessContactList(e.ctx, events[0], ownerPubkey); err != nil {
 			return fmt.Errorf("failed to process contact list: %w", err)
 		}
 		fmt.Printf("[SYNC] ✓ Contact list processed\n")
@@ -217,9 +273,12 @@ func (e *Engine) bootstrap() error {
 		fmt.Printf("[SYNC] ⚠ No contact list found - will sync owner events only\n")
 	}
 
+	// Fetch the owner's mute list before computing the scope
+	e.syncMuteList(ownerPubkey)
+
 	// Step 3: Get authors in scope
 	fmt.Printf("[SYNC] Step 3: Getting authors in scope...\n")
-	authors, err := e.graph.GetAuthorsInScope(e.ctx, ownerPubkey)
+	authors, err := e.authorsInScope(ownerPubkey)
 	if err != nil {
 		return fmt.Errorf("failed to get authors in scope: %w", err)
 	}
@@ -314,8 +373,10 @@ func (e *Engine) syncOnce() error {
 		return err
 	}
 
+	scope := e.currentScope()
+
 	// Get authors in scope
-	authors, err := e.graph.GetAuthorsInScope(e.ctx, ownerPubkey)
+	authors, err := e.authorsInScope(ownerPubkey)
 	if err != nil {
 		return fmt.Errorf("failed to get authors: %w", err)
 	}
@@ -330,7 +391,7 @@ func (e *Engine) syncOnce() error {
 	fmt.Printf("[SYNC] Active relays: %d\n", len(relays))
 
 	// Build filters with cursors
-	kinds := e.filterBuilder.GetConfiguredKinds()
+	kinds := scope.filters.GetConfiguredKinds()
 	fmt.Printf("[SYNC] Configured event kinds: %v\n", kinds)
 
 	// STEP 1: Sync authors' posts from their OUTBOX (write relays)
@@ -350,15 +411,18 @@ func (e *Engine) syncOnce() error {
 		}
 
 		// Build filters for authors' posts (outbox)
-		filters := e.filterBuilder.BuildFilters(authors, since)
+		filters := scope.filters.BuildFilters(authors, since)
 		fmt.Printf("[SYNC]   Built %d filters for outbox\n", len(filters))
 
 		// Try negentropy sync first, fall back to REQ if unsupported
 		go e.syncRelayWithFallback(relay, filters)
 	}
 
+	// Keep the owner's mute list current
+	go e.syncMuteList(ownerPubkey)
+
 	// STEP 2: Sync interactions TO US from OUR INBOX (read relays)
-	if e.config.Sync.Scope.IncludeDirectMentions {
+	if scope.config.Scope.IncludeDirectMentions {
 		if err := e.syncOwnerInbox(ownerPubkey, kinds); err != nil {
 			fmt.Printf("[SYNC] ⚠ Inbox sync failed: %v\n", err)
 			// Don't fail the whole sync if inbox fails
@@ -374,6 +438,7 @@ func (e *Engine) syncRelayWithFallback(relay string, filters []nostr.Filter) {
 	// Check if negentropy is enabled
 	if !e.config.Sync.Performance.UseNegentropy {
 		// Negentropy disabled, use traditional REQ
+		e.ingest.countSync(SyncMethodREQ)
 		e.subscribeRelay(relay, filters)
 		return
 	}
@@ -420,15 +485,65 @@ func (e *Engine) syncRelayWithFallback(relay string, filters []nostr.Filter) {
 	} else if success {
 		// Negentropy succeeded - we're done!
 		fmt.Printf("[SYNC] ✓ Negentropy sync complete for %s\n", relay)
+		e.ingest.countSync(SyncMethodNegentropy)
 		return
 	}
 
 	// Fall back to traditional REQ-based sync (always enabled for reliability)
 	// REQ uses cursor-based incremental sync (efficient for traditional subscriptions)
 	fmt.Printf("[SYNC] Using traditional REQ for %s\n", relay)
+	e.ingest.countSync(SyncMethodREQFallback)
 	e.subscribeRelay(relay, filters)
 }
 
+// syncMuteList fetches the owner's NIP-51 mute list from their outbox relays
+func (e *Engine) syncMuteList(ownerPubkey string) {
+	filter, ok := e.currentScope().filters.BuildMuteListFilter(ownerPubkey)
+	if !ok {
+		return
+	}
+
+	relays, err := e.discovery.GetOutboxRelays(e.ctx, ownerPubkey)
+	if err != nil || len(relays) == 0 {
+		relays = e.nostrClient.GetSeedRelays()
+	}
+
+	events, err := e.nostrClient.FetchEvents(e.ctx, relays, filter)
+	if err != nil {
+		fmt.Printf("[SYNC] ⚠ Failed to fetch mute list: %v\n", err)
+		return
+	}
+
+	for _, event := range events {
+		if err := e.processEvent(event); err != nil {
+			fmt.Printf("[SYNC] ⚠ Failed to process mute list: %v\n", err)
+		}
+	}
+}
+
+// authorsInScope returns the authors to sync, leaving out pubkeys on the
+// owner's mute list when sync.scope.exclude_muted is set
+func (e *Engine) authorsInScope(ownerPubkey string) ([]string, error) {
+	scope := e.currentScope()
+	authors, err := scope.graph.GetAuthorsInScope(e.ctx, ownerPubkey)
+	if err != nil || !scope.config.Scope.ExcludeMuted {
+		return authors, err
+	}
+
+	list := e.mutes.MuteList(e.ctx)
+	if list == nil {
+		return authors, nil
+	}
+
+	kept := make([]string, 0, len(authors))
+	for _, author := range authors {
+		if author == ownerPubkey || !list.Pubkeys[author] {
+			kept = append(kept, author)
+		}
+	}
+	return kept, nil
+}
+
 // syncOwnerInbox syncs interactions directed at the owner from their INBOX (read relays)
 // This queries for mentions, replies, reactions, and zaps TO the owner
 func (e *Engine) syncOwnerInbox(ownerPubkey string, kinds []int) error {
@@ -460,7 +575,7 @@ func (e *Engine) syncOwnerInbox(ownerPubkey string, kinds []int) error {
 	}
 
 	// Build inbox filter (mentions, replies, reactions, zaps TO owner)
-	inboxFilter := e.filterBuilder.BuildInboxFilter(ownerPubkey, int64(since))
+	inboxFilter := e.currentScope().filters.BuildInboxFilter(ownerPubkey, int64(since))
 	if len(inboxFilter.Kinds) == 0 {
 		fmt.Printf("[SYNC] No interaction kinds enabled for inbox, skipping\n")
 		return nil
@@ -491,6 +606,7 @@ func (e *Engine) subscribeRelay(relay string, filters []nostr.Filter) {
 	eventCount := 0
 	for event := range eventChan {
 		eventCount++
+		e.ingest.countEvent(relay)
 		if eventCount == 1 {
 			fmt.Printf("[SYNC] ✓ Receiving events from %s\n", relay)
 		}
@@ -542,27 +658,93 @@ func (e *Engine) processEvent(event *nostr.Event) error {
 		}
 	}
 
+	_, err := e.ingestEvent(event, true)
+	return err
+}
+
+// ImportEvent stores an event read from an archive, with the same checks and
+// bookkeeping as a synced event: ingest policies, deletions, the social
+// graph, relay hints, zaps, aggregates and retention. It reports false for
+// events that are already stored or were turned away. Aggregates are updated
+// before it returns rather than through the batching queue, which only runs
+// while the engine syncs; zap receipts are validated by a worker, so call
+// WaitForZaps once the last event is imported.
+func (e *Engine) ImportEvent(event *nostr.Event) (bool, error) {
+	e.startZaps()
+
+	exists, err := e.storage.EventExists(e.ctx, event.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check event: %w", err)
+	}
+	if exists {
+		return false, nil
+	}
+
+	return e.ingestEvent(event, false)
+}
+
+// ingestEvent admits, stores and indexes an event, reporting whether it was
+// stored. Live events come from relays: they are logged one by one and their
+// aggregate updates are queued. Imported events are neither.
+func (e *Engine) ingestEvent(event *nostr.Event, live bool) (bool, error) {
+	// Drop events that fail the sync.ingest policies
+	if !e.admit(event) {
+		return false, nil
+	}
+
+	// Reject events whose author already requested their deletion (NIP-09)
+	deleted, err := e.storage.IsDeleted(e.ctx, event)
+	if err != nil {
+		return false, fmt.Errorf("failed to check deletions: %w", err)
+	}
+	if deleted {
+		fmt.Printf("[SYNC]   ✗ Skipped deleted event %s\n", event.ID[:16]+"...")
+		return false, nil
+	}
+
 	// Store event in Khatru
 	if err := e.storage.StoreEvent(e.ctx, event); err != nil {
-		return fmt.Errorf("failed to store event: %w", err)
+		return false, fmt.Errorf("failed to store event: %w", err)
 	}
 
 	// Add to cache after successful storage
 	e.eventCache.Add(event.ID)
 
-	fmt.Printf("[SYNC]   ✓ Stored event %s (kind %d)\n", event.ID[:16]+"...", event.Kind)
+	if live {
+		fmt.Printf("[SYNC]   ✓ Stored event %s (kind %d)\n", event.ID[:16]+"...", event.Kind)
+	}
+
+	return true, e.processStored(event, live)
+}
+
+// HandleStored applies the bookkeeping of a synced event to an event stored
+// another way, such as one the owner published to the local relay:
+// deletions, the social graph, relay hints, zaps, aggregates and retention,
+// then the event and deletion handlers. As for imports, aggregates are
+// updated before it returns. The engine's context is used, since the
+// publishing connection may close first.
+func (e *Engine) HandleStored(_ context.Context, event *nostr.Event) {
+	e.startZaps()
+	e.eventCache.Add(event.ID)
+
+	if err := e.processStored(event, false); err != nil {
+		fmt.Printf("[SYNC] ⚠ Failed to process stored event %s: %v\n", event.ID[:16]+"...", err)
+	}
+}
 
+// processStored handles what follows storing an event: kind-specific
+// indexes, retention and the event handlers
+func (e *Engine) processStored(event *nostr.Event, live bool) error {
 	// Handle special event kinds
 	switch event.Kind {
 	case 3:
-		// Contact list - update graph
-		if err := e.graph.ProcessContactList(e.ctx, event, e.config.Identity.Npub); err != nil {
-			return fmt.Errorf("failed to process contact list: %w", err)
+		// Contact list - apply follow changes to the graph
+		ownerPubkey, err := e.getOwnerPubkey()
+		if err != nil {
+			return err
 		}
-
-		// Recompute mutuals
-		if err := e.graph.ComputeMutuals(e.ctx, e.config.Identity.Npub); err != nil {
-			return fmt.Errorf("failed to compute mutuals: %w", err)
+		if err := e.currentScope().graph.ProcessContactList(e.ctx, event, ownerPubkey); err != nil {
+			return fmt.Errorf("failed to process contact list: %w", err)
 		}
 
 	case 10002:
@@ -580,15 +762,39 @@ func (e *Engine) processEvent(event *nostr.Event) error {
 
 	case 7:
 		// Tier 2 Optimization: Queue reaction aggregate update (async, non-blocking)
-		e.queueReactionUpdate(event)
+		if live {
+			e.queueReactionUpdate(event)
+		} else if err := e.applyAggregateUpdate(reactionUpdate(event)); err != nil {
+			return err
+		}
 
 	case 1:
 		// Tier 2 Optimization: Queue reply aggregate update (async, non-blocking)
-		e.queueReplyUpdate(event)
+		if live {
+			e.queueReplyUpdate(event)
+		} else if err := e.applyAggregateUpdate(replyUpdate(event)); err != nil {
+			return err
+		}
+
+	case 0:
+		// Profile - retry zap receipts that waited for its lightning address
+		e.zaps.ProfileUpdated(event.PubKey)
 
 	case 9735:
-		// Tier 2 Optimization: Queue zap aggregate update (async, non-blocking)
-		e.queueZapUpdate(event)
+		// Zap receipt - queued for validation, only valid receipts count
+		if live {
+			if !e.zaps.Enqueue(event) {
+				fmt.Printf("[SYNC] ⚠ Zap queue full, dropped zap receipt %s\n", event.ID[:16]+"...")
+			}
+		} else if err := e.zaps.EnqueueWait(e.ctx, event); err != nil {
+			return fmt.Errorf("failed to queue zap receipt: %w", err)
+		}
+
+	case 5:
+		// Deletion request (NIP-09)
+		if err := e.applyDeletion(e.ctx, event); err != nil {
+			return fmt.Errorf("failed to apply deletion: %w", err)
+		}
 	}
 
 	// Phase 20: Evaluate retention if enabled
@@ -604,6 +810,25 @@ func (e *Engine) processEvent(event *nostr.Event) error {
 	return nil
 }
 
+// startZaps starts the zap validation worker, once
+func (e *Engine) startZaps() {
+	e.zapsOnce.Do(func() {
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+			e.zaps.Run(e.ctx)
+		}()
+	})
+}
+
+// WaitForZaps blocks until the zap receipts queued so far have been
+// validated, and returns how many are held until their recipient's profile
+// is stored
+func (e *Engine) WaitForZaps() int {
+	e.zaps.Wait()
+	return e.zaps.Held()
+}
+
 func (e *Engine) notifyEventHandlers(event *nostr.Event) {
 	if len(e.eventHandlers) == 0 {
 		return
@@ -641,7 +866,7 @@ func (e *Engine) refreshReplaceables() error {
 	}
 
 	// Get authors in scope
-	authors, err := e.graph.GetAuthorsInScope(e.ctx, ownerPubkey)
+	authors, err := e.authorsInScope(ownerPubkey)
 	if err != nil {
 		return err
 	}
@@ -653,7 +878,7 @@ func (e *Engine) refreshReplaceables() error {
 	}
 
 	// Build replaceable filter (no since cursor)
-	filter := e.filterBuilder.BuildReplaceableFilter(authors)
+	filter := e.currentScope().filters.BuildReplaceableFilter(authors)
 
 	// Fetch events
 	events, err := e.nostrClient.FetchEvents(e.ctx, relays, filter)
@@ -713,17 +938,40 @@ func (e *Engine) getActiveRelays(authors []string) []string {
 
 // Tier 2: Async aggregate queueing methods (non-blocking)
 func (e *Engine) queueReactionUpdate(event *nostr.Event) {
-	// Find the event being reacted to
-	var targetEventID string
-	for _, tag := range event.Tags {
-		if len(tag) >= 2 && tag[0] == "e" {
-			targetEventID = tag[1]
-			break
-		}
+	update := reactionUpdate(event)
+	if update == nil {
+		return // No target event
+	}
+
+	// Queue update (non-blocking)
+	select {
+	case e.aggregateChan <- update:
+	default:
+		// Channel full, log and drop (graceful degradation)
+		fmt.Printf("[SYNC] ⚠ Aggregate queue full, dropped reaction update\n")
+	}
+}
+
+func (e *Engine) queueReplyUpdate(event *nostr.Event) {
+	update := replyUpdate(event)
+	if update == nil {
+		return // Not a reply
 	}
 
+	// Queue update (non-blocking)
+	select {
+	case e.aggregateChan <- update:
+	default:
+		fmt.Printf("[SYNC] ⚠ Aggregate queue full, dropped reply update\n")
+	}
+}
+
+// reactionUpdate returns the aggregate update for a reaction, or nil when it
+// does not name the event it reacts to
+func reactionUpdate(event *nostr.Event) *AggregateUpdate {
+	targetEventID := firstEventTag(event)
 	if targetEventID == "" {
-		return // No target event
+		return nil
 	}
 
 	// Reaction content is the emoji
@@ -732,74 +980,59 @@ func (e *Engine) queueReactionUpdate(event *nostr.Event) {
 		reaction = "+" // Default like
 	}
 
-	// Queue update (non-blocking)
-	select {
-	case e.aggregateChan <- &AggregateUpdate{
+	return &AggregateUpdate{
 		Type:          "reaction",
 		EventID:       targetEventID,
 		Reaction:      reaction,
 		InteractionAt: int64(event.CreatedAt),
-	}:
-	default:
-		// Channel full, log and drop (graceful degradation)
-		fmt.Printf("[SYNC] ⚠ Aggregate queue full, dropped reaction update\n")
 	}
 }
 
-func (e *Engine) queueReplyUpdate(event *nostr.Event) {
-	// Check if this is a reply (has e tags)
-	var targetEventID string
-	for _, tag := range event.Tags {
-		if len(tag) >= 2 && tag[0] == "e" {
-			targetEventID = tag[1]
-			break
-		}
-	}
-
+// replyUpdate returns the aggregate update for a note, or nil when it is not
+// a reply
+func replyUpdate(event *nostr.Event) *AggregateUpdate {
+	targetEventID := firstEventTag(event)
 	if targetEventID == "" {
-		return // Not a reply
+		return nil
 	}
 
-	// Queue update (non-blocking)
-	select {
-	case e.aggregateChan <- &AggregateUpdate{
+	return &AggregateUpdate{
 		Type:          "reply",
 		EventID:       targetEventID,
 		InteractionAt: int64(event.CreatedAt),
-	}:
-	default:
-		fmt.Printf("[SYNC] ⚠ Aggregate queue full, dropped reply update\n")
 	}
 }
 
-func (e *Engine) queueZapUpdate(event *nostr.Event) {
-	// Parse zap amount from bolt11 invoice
-	// This is simplified - real implementation needs to parse the invoice
-	var targetEventID string
-	var amount int64 = 1000 // Placeholder
-
+// firstEventTag returns the first event an event's e tags point to
+func firstEventTag(event *nostr.Event) string {
 	for _, tag := range event.Tags {
 		if len(tag) >= 2 && tag[0] == "e" {
-			targetEventID = tag[1]
-			break
+			return tag[1]
 		}
 	}
+	return ""
+}
 
-	if targetEventID == "" {
-		return
+// applyAggregateUpdate writes a single aggregate update straight to storage.
+// Batches count each target once, so this is how updates that must all count,
+// like a stream of imported events, are applied.
+func (e *Engine) applyAggregateUpdate(update *AggregateUpdate) error {
+	if update == nil {
+		return nil
 	}
 
-	// Queue update (non-blocking)
-	select {
-	case e.aggregateChan <- &AggregateUpdate{
-		Type:          "zap",
-		EventID:       targetEventID,
-		Sats:          amount,
-		InteractionAt: int64(event.CreatedAt),
-	}:
-	default:
-		fmt.Printf("[SYNC] ⚠ Aggregate queue full, dropped zap update\n")
+	switch update.Type {
+	case "reply":
+		if err := e.storage.BatchIncrementReplies(e.ctx, map[string]int64{update.EventID: update.InteractionAt}); err != nil {
+			return fmt.Errorf("failed to update replies: %w", err)
+		}
+	case "reaction":
+		reactions := map[string]map[string]int64{update.EventID: {update.Reaction: update.InteractionAt}}
+		if err := e.storage.BatchIncrementReactions(e.ctx, reactions); err != nil {
+			return fmt.Errorf("failed to update reactions: %w", err)
+		}
 	}
+	return nil
 }
 
 // processAggregates processes aggregate updates in batches (Tier 2 optimization)
@@ -812,10 +1045,6 @@ func (e *Engine) processAggregates() {
 
 	replies := make(map[string]int64)
 	reactions := make(map[string]map[string]int64)
-	zaps := make(map[string]struct {
-		Sats          int64
-		InteractionAt int64
-	})
 
 	flush := func() {
 		// Process batched replies
@@ -833,17 +1062,6 @@ func (e *Engine) processAggregates() {
 			}
 			reactions = make(map[string]map[string]int64)
 		}
-
-		// Process batched zaps
-		if len(zaps) > 0 {
-			if err := e.storage.BatchAddZaps(e.ctx, zaps); err != nil {
-				fmt.Printf("[SYNC] ⚠ Failed to batch update zaps: %v\n", err)
-			}
-			zaps = make(map[string]struct {
-				Sats          int64
-				InteractionAt int64
-			})
-		}
 	}
 
 	for {
@@ -868,12 +1086,6 @@ func (e *Engine) processAggregates() {
 					reactions[update.EventID] = make(map[string]int64)
 				}
 				reactions[update.EventID][update.Reaction] = update.InteractionAt
-
-			case "zap":
-				zaps[update.EventID] = struct {
-					Sats          int64
-					InteractionAt int64
-				}{Sats: update.Sats, InteractionAt: update.InteractionAt}
 			}
 
 		case <-ticker.C:
@@ -0,0 +1,65 @@
+package sync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/config"
+)
+
+func TestImportEvent(t *testing.T) {
+	_, st, cleanup := setupTestGraph(t)
+	defer cleanup()
+
+	engine := NewEngine(st, config.Default())
+	defer engine.cancel()
+
+	ctx := context.Background()
+	alice := nostr.GeneratePrivateKey()
+	alicePub, _ := nostr.GetPublicKey(alice)
+	bob := nostr.GeneratePrivateKey()
+
+	note := signedEvent(t, alice, 1, 100, nil, "hello")
+	events := []*nostr.Event{
+		note,
+		signedEvent(t, bob, 1, 110, nostr.Tags{{"e", note.ID, "", "reply"}}, "first"),
+		signedEvent(t, bob, 1, 120, nostr.Tags{{"e", note.ID, "", "reply"}}, "second"),
+		signedEvent(t, bob, 7, 130, nostr.Tags{{"e", note.ID}}, "+"),
+		signedEvent(t, alice, 10002, 140, nostr.Tags{{"r", "wss://relay.example.com"}}, ""),
+	}
+	for _, event := range events {
+		stored, err := engine.ImportEvent(event)
+		if err != nil {
+			t.Fatalf("ImportEvent() error = %v", err)
+		}
+		if !stored {
+			t.Errorf("expected event of kind %d to be stored", event.Kind)
+		}
+	}
+
+	// Every reply counts, even two to the same note in quick succession
+	agg, err := st.GetAggregate(ctx, note.ID)
+	if err != nil {
+		t.Fatalf("GetAggregate() error = %v", err)
+	}
+	if agg.ReplyCount != 2 || agg.ReactionTotal != 1 || agg.LastInteractionAt != 130 {
+		t.Errorf("aggregate = %+v, want 2 replies, 1 reaction, last interaction 130", agg)
+	}
+
+	relays, err := st.GetWriteRelays(ctx, alicePub)
+	if err != nil {
+		t.Fatalf("GetWriteRelays() error = %v", err)
+	}
+	if len(relays) != 1 {
+		t.Errorf("expected the relay list to produce a hint, got %v", relays)
+	}
+
+	stored, err := engine.ImportEvent(note)
+	if err != nil {
+		t.Fatalf("ImportEvent() error = %v", err)
+	}
+	if stored {
+		t.Error("expected an already stored event to be skipped")
+	}
+}
@@ -23,11 +23,7 @@ func (fb *FilterBuilder) BuildFilters(authors []string, since int64) []nostr.Fil
 		return nil
 	}
 
-	kinds := fb.config.Kinds.ToIntSlice()
-	if len(kinds) == 0 {
-		// Default kinds per sync_scope.md
-		kinds = []int{0, 1, 3, 6, 7, 9735, 30023, 10002}
-	}
+	kinds := fb.authorKinds()
 
 	// Apply max authors limit if configured
 	if fb.config.Scope.MaxAuthors > 0 && len(authors) > fb.config.Scope.MaxAuthors {
@@ -72,12 +68,45 @@ func (fb *FilterBuilder) BuildFilters(authors []string, since int64) []nostr.Fil
 	return filters
 }
 
+// ownerOnlyKinds are synced for the owner alone, never for every author in scope
+var ownerOnlyKinds = map[int]bool{
+	10000: true, // NIP-51 mute list
+}
+
+// authorKinds returns the configured kinds to request from every author in scope
+func (fb *FilterBuilder) authorKinds() []int {
+	configured := fb.config.Kinds.ToIntSlice()
+	if len(configured) == 0 {
+		// Default kinds per sync_scope.md
+		return []int{0, 1, 3, 5, 6, 7, 9735, 30023, 10002}
+	}
+
+	kinds := make([]int, 0, len(configured))
+	for _, kind := range configured {
+		if !ownerOnlyKinds[kind] {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// BuildMuteListFilter creates a filter for the owner's NIP-51 mute list, or
+// reports false if mute lists are not synced
+func (fb *FilterBuilder) BuildMuteListFilter(ownerPubkey string) (nostr.Filter, bool) {
+	if !fb.config.Kinds.MuteList {
+		return nostr.Filter{}, false
+	}
+
+	return nostr.Filter{
+		Kinds:   []int{10000},
+		Authors: []string{ownerPubkey},
+		Limit:   1,
+	}, true
+}
+
 // BuildMentionFilter creates a filter for events that mention the owner
 func (fb *FilterBuilder) BuildMentionFilter(ownerPubkey string, since int64) nostr.Filter {
-	kinds := fb.config.Kinds.ToIntSlice()
-	if len(kinds) == 0 {
-		kinds = []int{0, 1, 3, 6, 7, 9735, 30023, 10002}
-	}
+	kinds := fb.authorKinds()
 
 	filter := nostr.Filter{
 		Kinds: kinds,
@@ -158,7 +187,7 @@ func (fb *FilterBuilder) GetConfiguredKinds() []int {
 		return kinds
 	}
 	// Default kinds
-	return []int{0, 1, 3, 6, 7, 9735, 30023, 10002}
+	return []int{0, 1, 3, 5, 6, 7, 9735, 30023, 10002}
 }
 
 // BuildNegentropyFilter creates an optimized filter for negentropy sync
@@ -171,10 +200,7 @@ func (fb *FilterBuilder) BuildNegentropyFilter(authors []string) nostr.Filter {
 		return nostr.Filter{}
 	}
 
-	kinds := fb.config.Kinds.ToIntSlice()
-	if len(kinds) == 0 {
-		kinds = []int{0, 1, 3, 6, 7, 9735, 30023, 10002}
-	}
+	kinds := fb.authorKinds()
 
 	filter := nostr.Filter{
 		Authors: authors,
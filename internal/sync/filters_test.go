@@ -53,7 +53,7 @@ func TestBuildFilters(t *testing.T) {
 			authors:         []string{"pubkey1"},
 			since:           0,
 			expectedFilters: 2, // replaceable + regular split
-			expectedKinds:   8, // Empty SyncKinds falls back to 8 default kinds
+			expectedKinds:   9, // Empty SyncKinds falls back to 9 default kinds
 		},
 		{
 			name:            "empty authors",
@@ -233,7 +233,7 @@ func TestGetConfiguredKinds(t *testing.T) {
 		{
 			name:     "empty kinds (defaults)",
 			cfg:      &config.Sync{Kinds: config.SyncKinds{}},
-			expected: 8, // Falls back to 8 default kinds
+			expected: 9, // Falls back to 9 default kinds
 		},
 	}
 
@@ -248,3 +248,64 @@ func TestGetConfiguredKinds(t *testing.T) {
 		})
 	}
 }
+
+func TestMuteListOwnerOnly(t *testing.T) {
+	cfg := &config.Sync{
+		Kinds: config.SyncKinds{
+			Notes:    true,
+			MuteList: true,
+		},
+	}
+	fb := NewFilterBuilder(cfg)
+
+	for _, filter := range fb.BuildFilters([]string{"owner", "friend"}, 0) {
+		for _, kind := range filter.Kinds {
+			if kind == 10000 {
+				t.Error("Mute lists should not be requested from every author")
+			}
+		}
+	}
+
+	filter, ok := fb.BuildMuteListFilter("owner")
+	if !ok {
+		t.Fatal("Expected a mute list filter when mute_list is enabled")
+	}
+	if len(filter.Authors) != 1 || filter.Authors[0] != "owner" || len(filter.Kinds) != 1 || filter.Kinds[0] != 10000 {
+		t.Errorf("Unexpected mute list filter: %+v", filter)
+	}
+
+	cfg.Kinds.MuteList = false
+	if _, ok := fb.BuildMuteListFilter("owner"); ok {
+		t.Error("Expected no mute list filter when mute_list is disabled")
+	}
+}
+
+func TestEngineSetScope(t *testing.T) {
+	_, st, cleanup := setupTestGraph(t)
+	defer cleanup()
+
+	cfg := config.Default()
+	cfg.Sync.Kinds = config.SyncKinds{Notes: true, ContactList: true, Reactions: true}
+	engine := NewEngine(st, cfg)
+	defer engine.cancel()
+
+	before := engine.currentScope()
+
+	next := cfg.Sync
+	next.Kinds = config.SyncKinds{Notes: true}
+	next.Scope.Mode = "self"
+	engine.SetScope(&next)
+
+	after := engine.currentScope()
+	if kinds := after.filters.GetConfiguredKinds(); len(kinds) != 1 || kinds[0] != 1 {
+		t.Errorf("Expected only kind 1 after SetScope, got %v", kinds)
+	}
+	if after.graph.config.Mode != "self" {
+		t.Errorf("Expected graph mode self, got %q", after.graph.config.Mode)
+	}
+
+	// An iteration already running keeps the scope it started with
+	if kinds := before.filters.GetConfiguredKinds(); len(kinds) != 3 {
+		t.Errorf("Expected the previous scope to keep 3 kinds, got %d", len(kinds))
+	}
+}
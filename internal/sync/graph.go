@@ -2,17 +2,28 @@ package sync
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/sandwichfarm/nophr/internal/config"
 	"github.com/sandwichfarm/nophr/internal/storage"
 )
 
+// maxGraphDepth reaches every node of the graph
+const maxGraphDepth = 999
+
 // Graph handles social graph computation from kind 3 events
 type Graph struct {
 	storage *storage.Storage
 	config  *config.SyncScope
+
+	mu        sync.RWMutex
+	selection *AuthorSelection // last outcome of GetAuthorsInScope
 }
 
 // NewGraph creates a new graph processor
@@ -23,128 +34,180 @@ func NewGraph(st *storage.Storage, cfg *config.SyncScope) *Graph {
 	}
 }
 
-// ProcessContactList processes a kind 3 event (contact list) and updates the social graph
+// ProcessContactList applies an author's kind 3 event (contact list) to the
+// social graph. Only the follows added or removed since the author's previous
+// list are stored, and only the depths and mutual flags they affect are
+// recomputed. A list older than the one already applied is ignored.
 func (g *Graph) ProcessContactList(ctx context.Context, event *nostr.Event, rootPubkey string) error {
 	if event.Kind != 3 {
 		return fmt.Errorf("expected kind 3, got %d", event.Kind)
 	}
 
-	// Extract followed pubkeys from p tags
-	following := make([]string, 0)
-	for _, tag := range event.Tags {
-		if len(tag) >= 2 && tag[0] == "p" {
-			following = append(following, tag[1])
-		}
+	added, removed, stored, err := g.storeContactList(ctx, event)
+	if err != nil || !stored {
+		return err
 	}
 
-	// Determine depth for these contacts
-	depth := 1
-	if event.PubKey == rootPubkey {
-		depth = 1 // Direct follows
-	} else {
-		// This is a follow list from someone in the graph
-		// We need to check their current depth
-		nodes, err := g.storage.GetGraphNodes(ctx, rootPubkey, 999)
-		if err != nil {
-			return fmt.Errorf("failed to get graph nodes: %w", err)
-		}
+	update := newGraphUpdate(g.storage, rootPubkey, int64(event.CreatedAt))
+	if err := update.apply(ctx, event.PubKey, added, removed); err != nil {
+		return err
+	}
+	return update.commit(ctx)
+}
 
-		// Find the depth of the event author
-		for _, node := range nodes {
-			if node.Pubkey == event.PubKey {
-				depth = node.Depth + 1
-				break
-			}
+// storeContactList records event as its author's contact list and returns the
+// follows it added and removed. Stale lists are not stored.
+func (g *Graph) storeContactList(ctx context.Context, event *nostr.Event) (added, removed []string, stored bool, err error) {
+	previous, err := g.storage.GetContactList(ctx, event.PubKey)
+	switch {
+	case err == nil:
+		if !supersedes(event, previous) {
+			return nil, nil, false, nil
 		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, nil, false, fmt.Errorf("failed to get contact list: %w", err)
 	}
 
-	// Save each followed pubkey as a graph node
-	for _, followedPubkey := range following {
-		node := &storage.GraphNode{
-			RootPubkey: rootPubkey,
-			Pubkey:     followedPubkey,
-			Depth:      depth,
-			Mutual:     false, // We'll calculate this separately
-			LastSeen:   int64(event.CreatedAt),
+	// Extract followed pubkeys from p tags
+	following := make(map[string]bool)
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == "p" && tag[1] != event.PubKey {
+			following[tag[1]] = true
 		}
+	}
 
-		if err := g.storage.SaveGraphNode(ctx, node); err != nil {
-			return fmt.Errorf("failed to save graph node: %w", err)
+	follows, err := g.storage.GetFollows(ctx, event.PubKey)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	for _, pubkey := range follows {
+		if following[pubkey] {
+			delete(following, pubkey)
+		} else {
+			removed = append(removed, pubkey)
 		}
 	}
+	for pubkey := range following {
+		added = append(added, pubkey)
+	}
+	sort.Strings(added)
+
+	list := &storage.ContactList{
+		Pubkey:    event.PubKey,
+		EventID:   event.ID,
+		CreatedAt: int64(event.CreatedAt),
+	}
+	if err := g.storage.SaveContactList(ctx, list, added, removed); err != nil {
+		return nil, nil, false, err
+	}
+
+	return added, removed, true, nil
+}
 
-	return nil
+// supersedes reports whether event replaces the recorded contact list: it is
+// newer, or as old with the lower ID (NIP-01)
+func supersedes(event *nostr.Event, list *storage.ContactList) bool {
+	if int64(event.CreatedAt) != list.CreatedAt {
+		return int64(event.CreatedAt) > list.CreatedAt
+	}
+	return event.ID < list.EventID
 }
 
-// ComputeMutuals identifies mutual follows and updates the graph
-func (g *Graph) ComputeMutuals(ctx context.Context, rootPubkey string) error {
-	// Get all direct follows (depth 1)
-	allNodes, err := g.storage.GetGraphNodes(ctx, rootPubkey, 1)
+// Rebuild recomputes the whole graph under rootPubkey from the stored
+// follows: depths breadth first from the root, and mutual flags for the
+// root's direct follows
+func (g *Graph) Rebuild(ctx context.Context, rootPubkey string) error {
+	follows, err := g.storage.GetAllFollows(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get graph nodes: %w", err)
+		return err
 	}
 
-	directFollows := make(map[string]bool)
-	for _, node := range allNodes {
-		if node.Depth == 1 {
-			directFollows[node.Pubkey] = true
-		}
+	lists, err := g.storage.GetAllContactLists(ctx)
+	if err != nil {
+		return err
+	}
+	listedAt := make(map[string]int64, len(lists))
+	for _, list := range lists {
+		listedAt[list.Pubkey] = list.CreatedAt
 	}
 
-	// For each direct follow, check if they follow us back
-	for followedPubkey := range directFollows {
-		// Get their contact list from storage
-		filter := nostr.Filter{
-			Kinds:   []int{3},
-			Authors: []string{followedPubkey},
-			Limit:   1,
+	followsRoot := make(map[string]bool)
+	for follower, followees := range follows {
+		for _, followee := range followees {
+			if followee == rootPubkey {
+				followsRoot[follower] = true
+			}
 		}
+	}
 
-		events, err := g.storage.QueryEvents(ctx, filter)
-		if err != nil {
-			continue // Skip if we can't query
+	depths := map[string]int{rootPubkey: 0}
+	var nodes []*storage.GraphNode
+	queue := []string{rootPubkey}
+	for i := 0; i < len(queue); i++ {
+		parent := queue[i]
+		for _, pubkey := range follows[parent] {
+			if _, ok := depths[pubkey]; ok {
+				continue
+			}
+			depths[pubkey] = depths[parent] + 1
+			nodes = append(nodes, &storage.GraphNode{
+				RootPubkey: rootPubkey,
+				Pubkey:     pubkey,
+				Depth:      depths[pubkey],
+				Mutual:     depths[pubkey] == 1 && followsRoot[pubkey],
+				LastSeen:   listedAt[parent],
+			})
+			queue = append(queue, pubkey)
 		}
+	}
 
-		if len(events) == 0 {
-			continue // No contact list yet
+	existing, err := g.storage.GetGraphNodes(ctx, rootPubkey, maxGraphDepth)
+	if err != nil {
+		return err
+	}
+	var remove []string
+	for _, node := range existing {
+		if _, ok := depths[node.Pubkey]; !ok || node.Pubkey == rootPubkey {
+			remove = append(remove, node.Pubkey)
 		}
+	}
 
-		// Check if they follow root back
-		isMutual := false
-		for _, tag := range events[0].Tags {
-			if len(tag) >= 2 && tag[0] == "p" && tag[1] == rootPubkey {
-				isMutual = true
-				break
-			}
-		}
+	return g.storage.UpdateGraphNodes(ctx, rootPubkey, nodes, remove)
+}
 
-		// Update the node if it's mutual
-		if isMutual {
-			// Fetch and update the node
-			nodes, err := g.storage.GetGraphNodes(ctx, rootPubkey, 1)
-			if err != nil {
-				continue
-			}
+// Backfill records the follows of every stored contact list and rebuilds the
+// graph under rootPubkey from them, reporting whether it did. It only runs
+// while no contact list is recorded, as on databases from before the graph
+// kept follows.
+func (g *Graph) Backfill(ctx context.Context, rootPubkey string) (bool, error) {
+	count, err := g.storage.CountContactLists(ctx)
+	if err != nil || count > 0 {
+		return false, err
+	}
 
-			for _, node := range nodes {
-				if node.Pubkey == followedPubkey {
-					node.Mutual = true
-					if err := g.storage.SaveGraphNode(ctx, node); err != nil {
-						return fmt.Errorf("failed to update mutual status: %w", err)
-					}
-					break
-				}
-			}
+	stored := 0
+	err = g.storage.ScanEvents(ctx, nostr.Filter{Kinds: []int{3}}, func(event *nostr.Event) error {
+		_, _, ok, err := g.storeContactList(ctx, event)
+		if ok {
+			stored++
 		}
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to backfill contact lists: %w", err)
+	}
+	if stored == 0 {
+		return false, nil
 	}
 
-	return nil
+	return true, g.Rebuild(ctx, rootPubkey)
 }
 
 // GetAuthorsInScope returns the list of authors to sync based on scope configuration
 func (g *Graph) GetAuthorsInScope(ctx context.Context, rootPubkey string) ([]string, error) {
 	switch g.config.Mode {
 	case "self":
+		g.setSelection(&AuthorSelection{At: time.Now(), Candidates: 1, MaxAuthors: g.config.MaxAuthors})
 		return []string{rootPubkey}, nil
 
 	case "following":
@@ -154,7 +217,7 @@ func (g *Graph) GetAuthorsInScope(ctx context.Context, rootPubkey string) ([]str
 		}
 		authors := []string{rootPubkey}
 		authors = append(authors, following...)
-		return g.applyLimits(authors), nil
+		return g.applyLimits(ctx, rootPubkey, authors)
 
 	case "mutual":
 		mutuals, err := g.storage.GetMutualPubkeys(ctx, rootPubkey)
@@ -163,7 +226,7 @@ func (g *Graph) GetAuthorsInScope(ctx context.Context, rootPubkey string) ([]str
 		}
 		authors := []string{rootPubkey}
 		authors = append(authors, mutuals...)
-		return g.applyLimits(authors), nil
+		return g.applyLimits(ctx, rootPubkey, authors)
 
 	case "foaf":
 		// Get all nodes up to configured depth
@@ -189,15 +252,17 @@ func (g *Graph) GetAuthorsInScope(ctx context.Context, rootPubkey string) ([]str
 			authors = append(authors, pubkey)
 		}
 
-		return g.applyLimits(authors), nil
+		return g.applyLimits(ctx, rootPubkey, authors)
 
 	default:
 		return []string{rootPubkey}, nil
 	}
 }
 
-// applyLimits applies allowlist, denylist, and max_authors limits
-func (g *Graph) applyLimits(authors []string) []string {
+// applyLimits applies allowlist, denylist, and max_authors limits. When more
+// authors remain than max_authors allows, the owner is kept and the rest are
+// ranked so the same graph always yields the same selection.
+func (g *Graph) applyLimits(ctx context.Context, rootPubkey string, authors []string) ([]string, error) {
 	filtered := make([]string, 0, len(authors))
 
 	for _, author := range authors {
@@ -230,10 +295,39 @@ func (g *Graph) applyLimits(authors []string) []string {
 		filtered = append(filtered, author)
 	}
 
+	selection := &AuthorSelection{
+		At:         time.Now(),
+		Candidates: len(filtered),
+		MaxAuthors: g.config.MaxAuthors,
+	}
+
 	// Apply max authors cap
 	if g.config.MaxAuthors > 0 && len(filtered) > g.config.MaxAuthors {
-		filtered = filtered[:g.config.MaxAuthors]
+		selected := make([]string, 0, g.config.MaxAuthors)
+		candidates := make([]string, 0, len(filtered))
+		for _, author := range filtered {
+			if author == rootPubkey {
+				selected = append(selected, author)
+			} else {
+				candidates = append(candidates, author)
+			}
+		}
+
+		ranked, err := g.rankAuthors(ctx, rootPubkey, candidates)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rank authors: %w", err)
+		}
+		if keep := g.config.MaxAuthors - len(selected); keep < len(ranked) {
+			ranked = ranked[:max(keep, 0)]
+		}
+		for _, score := range ranked {
+			selected = append(selected, score.Pubkey)
+		}
+
+		selection.Ranked = ranked
+		filtered = selected
 	}
 
-	return filtered
+	g.setSelection(selection)
+	return filtered, nil
 }
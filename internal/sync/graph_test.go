@@ -2,7 +2,10 @@ package sync
 
 import (
 	"context"
+	"fmt"
+	"math/rand"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/nbd-wtf/go-nostr"
@@ -244,7 +247,10 @@ func TestApplyLimits(t *testing.T) {
 			defer st.Close()
 
 			graph := NewGraph(st, tt.config)
-			filtered := graph.applyLimits(tt.authors)
+			filtered, err := graph.applyLimits(ctx, "", tt.authors)
+			if err != nil {
+				t.Fatalf("applyLimits() error = %v", err)
+			}
 
 			if len(filtered) != tt.expected {
 				t.Errorf("Expected %d authors, got %d", tt.expected, len(filtered))
@@ -252,3 +258,237 @@ func TestApplyLimits(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyLimits_Ranking(t *testing.T) {
+	graph, st, cleanup := setupTestGraph(t)
+	defer cleanup()
+
+	graph.config.MaxAuthors = 3
+	ctx := context.Background()
+	rootPubkey := "root-pubkey"
+
+	alice := nostr.GeneratePrivateKey()
+	alicePub, _ := nostr.GetPublicKey(alice)
+	bob := nostr.GeneratePrivateKey()
+	bobPub, _ := nostr.GetPublicKey(bob)
+
+	for _, node := range []*storage.GraphNode{
+		{RootPubkey: rootPubkey, Pubkey: alicePub, Depth: 1},
+		{RootPubkey: rootPubkey, Pubkey: bobPub, Depth: 1},
+		{RootPubkey: rootPubkey, Pubkey: "carol", Depth: 1, Mutual: true},
+		{RootPubkey: rootPubkey, Pubkey: "dave", Depth: 1},
+		{RootPubkey: rootPubkey, Pubkey: "erin", Depth: 2},
+	} {
+		if err := st.SaveGraphNode(ctx, node); err != nil {
+			t.Fatalf("SaveGraphNode() error = %v", err)
+		}
+	}
+
+	// Alice follows dave, and bob replied to the owner
+	now := nostr.Now()
+	list := &storage.ContactList{Pubkey: alicePub, EventID: "alice-list", CreatedAt: int64(now)}
+	if err := st.SaveContactList(ctx, list, []string{"dave"}, nil); err != nil {
+		t.Fatalf("SaveContactList() error = %v", err)
+	}
+	if err := st.StoreEvent(ctx, signedEvent(t, bob, 1, now, nostr.Tags{{"p", rootPubkey}}, "hi")); err != nil {
+		t.Fatalf("StoreEvent() error = %v", err)
+	}
+
+	authors := []string{"erin", "dave", "carol", bobPub, alicePub, rootPubkey}
+	selected, err := graph.applyLimits(ctx, rootPubkey, authors)
+	if err != nil {
+		t.Fatalf("applyLimits() error = %v", err)
+	}
+
+	// The owner stays; carol (mutual) and bob (interaction) outrank dave
+	// (followed by alice), alice and erin (depth 2)
+	want := []string{rootPubkey, "carol", bobPub}
+	if len(selected) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, selected)
+	}
+	for i := range want {
+		if selected[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, selected)
+			break
+		}
+	}
+
+	selection := graph.Selection()
+	if selection == nil || selection.Candidates != 6 || len(selection.Ranked) != 2 {
+		t.Fatalf("Expected a selection of 2 ranked out of 6 candidates, got %+v", selection)
+	}
+	if bob := selection.Ranked[1]; bob.Interactions != 1 || bob.Depth != 1 {
+		t.Errorf("Expected bob's signals to be recorded, got %+v", bob)
+	}
+
+	scores, err := graph.rankAuthors(ctx, rootPubkey, []string{"dave", "erin"})
+	if err != nil {
+		t.Fatalf("rankAuthors() error = %v", err)
+	}
+	if scores[0].Pubkey != "dave" || scores[0].FollowedBy != 1 {
+		t.Errorf("Expected dave to be followed by alice, got %+v", scores[0])
+	}
+
+	// Reordering the input does not change the outcome
+	reversed := []string{rootPubkey, alicePub, bobPub, "carol", "dave", "erin"}
+	again, err := graph.applyLimits(ctx, rootPubkey, reversed)
+	if err != nil {
+		t.Fatalf("applyLimits() error = %v", err)
+	}
+	for i := range want {
+		if again[i] != want[i] {
+			t.Errorf("Expected a stable selection %v, got %v", want, again)
+			break
+		}
+	}
+}
+
+func contactList(pubkey string, createdAt nostr.Timestamp, follows ...string) *nostr.Event {
+	event := &nostr.Event{Kind: 3, PubKey: pubkey, CreatedAt: createdAt, Tags: nostr.Tags{}}
+	for _, follow := range follows {
+		event.Tags = append(event.Tags, nostr.Tag{"p", follow})
+	}
+	event.ID = event.GetID()
+	return event
+}
+
+// graphDepths returns the depth of every node under root, with mutual nodes
+// marked by a trailing "*"
+func graphDepths(t *testing.T, st *storage.Storage, root string) map[string]string {
+	t.Helper()
+
+	nodes, err := st.GetGraphNodes(context.Background(), root, maxGraphDepth)
+	if err != nil {
+		t.Fatalf("GetGraphNodes() error = %v", err)
+	}
+	depths := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		depths[node.Pubkey] = fmt.Sprint(node.Depth)
+		if node.Mutual {
+			depths[node.Pubkey] += "*"
+		}
+	}
+	return depths
+}
+
+func TestProcessContactList_Incremental(t *testing.T) {
+	graph, st, cleanup := setupTestGraph(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	root := "root"
+	apply := func(event *nostr.Event) {
+		t.Helper()
+		if err := graph.ProcessContactList(ctx, event, root); err != nil {
+			t.Fatalf("ProcessContactList() error = %v", err)
+		}
+	}
+	expect := func(want map[string]string) {
+		t.Helper()
+		if got := graphDepths(t, st, root); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected graph %v, got %v", want, got)
+		}
+	}
+
+	apply(contactList(root, 100, "alice", "bob"))
+	apply(contactList("alice", 100, "carol", root))
+	apply(contactList("bob", 100, "carol"))
+	apply(contactList("carol", 100, "dave"))
+	expect(map[string]string{"alice": "1*", "bob": "1", "carol": "2", "dave": "3"})
+
+	// Carol stays at depth 2 through bob after alice is unfollowed
+	apply(contactList(root, 200, "bob"))
+	expect(map[string]string{"bob": "1", "carol": "2", "dave": "3"})
+
+	// Following dave directly brings him closer
+	apply(contactList(root, 300, "bob", "dave"))
+	expect(map[string]string{"bob": "1", "carol": "2", "dave": "1"})
+
+	// Without bob's follow carol is only reachable through alice, who is out
+	apply(contactList("bob", 200))
+	expect(map[string]string{"bob": "1", "dave": "1"})
+
+	// An older list from the owner is ignored
+	apply(contactList(root, 250, "alice"))
+	expect(map[string]string{"bob": "1", "dave": "1"})
+
+	// Following alice again restores her branch, and bob following back
+	// makes him mutual
+	apply(contactList(root, 400, "alice", "bob", "dave"))
+	apply(contactList("bob", 300, root))
+	expect(map[string]string{"alice": "1*", "bob": "1*", "carol": "2", "dave": "1"})
+}
+
+func TestProcessContactList_MatchesRebuild(t *testing.T) {
+	graph, st, cleanup := setupTestGraph(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(1))
+
+	pubkeys := []string{"root"}
+	for i := 0; i < 30; i++ {
+		pubkeys = append(pubkeys, fmt.Sprintf("author%02d", i))
+	}
+
+	for step := 0; step < 200; step++ {
+		author := pubkeys[rng.Intn(len(pubkeys))]
+		var follows []string
+		for _, pubkey := range pubkeys {
+			if rng.Intn(12) == 0 {
+				follows = append(follows, pubkey)
+			}
+		}
+
+		// Some lists arrive out of order and must be ignored
+		createdAt := nostr.Timestamp(1000 + step - rng.Intn(3)*10)
+		if err := graph.ProcessContactList(ctx, contactList(author, createdAt, follows...), "root"); err != nil {
+			t.Fatalf("ProcessContactList() error = %v", err)
+		}
+
+		incremental := graphDepths(t, st, "root")
+		if err := graph.Rebuild(ctx, "root"); err != nil {
+			t.Fatalf("Rebuild() error = %v", err)
+		}
+		if rebuilt := graphDepths(t, st, "root"); !reflect.DeepEqual(incremental, rebuilt) {
+			t.Fatalf("step %d: incremental graph %v differs from rebuilt %v", step, incremental, rebuilt)
+		}
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	graph, st, cleanup := setupTestGraph(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	owner := nostr.GeneratePrivateKey()
+	ownerPub, _ := nostr.GetPublicKey(owner)
+	alice := nostr.GeneratePrivateKey()
+	alicePub, _ := nostr.GetPublicKey(alice)
+
+	for _, event := range []*nostr.Event{
+		signedEvent(t, owner, 3, 100, nostr.Tags{{"p", alicePub}}, ""),
+		signedEvent(t, alice, 3, 100, nostr.Tags{{"p", ownerPub}, {"p", "carol"}}, ""),
+	} {
+		if err := st.StoreEvent(ctx, event); err != nil {
+			t.Fatalf("StoreEvent() error = %v", err)
+		}
+	}
+
+	rebuilt, err := graph.Backfill(ctx, ownerPub)
+	if err != nil {
+		t.Fatalf("Backfill() error = %v", err)
+	}
+	if !rebuilt {
+		t.Fatal("Expected the graph to be rebuilt from stored contact lists")
+	}
+	want := map[string]string{alicePub: "1*", "carol": "2"}
+	if got := graphDepths(t, st, ownerPub); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected graph %v, got %v", want, got)
+	}
+
+	// Once follows are recorded there is nothing to backfill
+	if rebuilt, err := graph.Backfill(ctx, ownerPub); err != nil || rebuilt {
+		t.Errorf("Expected a second Backfill() to do nothing, got %v (%v)", rebuilt, err)
+	}
+}
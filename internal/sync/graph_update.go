@@ -0,0 +1,384 @@
+package sync
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+// unreachable is the depth of pubkeys outside the graph
+const unreachable = math.MaxInt32
+
+// graphUpdate applies one author's follow changes to the nodes under a root.
+// Nodes and edges are loaded as the change reaches them, so the work grows
+// with the part of the graph affected rather than with the whole graph, and
+// changed nodes are written together by commit.
+type graphUpdate struct {
+	storage   *storage.Storage
+	root      string
+	seen      int64                         // LastSeen for nodes whose depth changes
+	nodes     map[string]*storage.GraphNode // nil: not in the graph
+	follows   map[string][]string
+	followers map[string][]string
+	changed   map[string]bool
+}
+
+func newGraphUpdate(st *storage.Storage, rootPubkey string, seen int64) *graphUpdate {
+	return &graphUpdate{
+		storage:   st,
+		root:      rootPubkey,
+		seen:      seen,
+		nodes:     make(map[string]*storage.GraphNode),
+		follows:   make(map[string][]string),
+		followers: make(map[string][]string),
+		changed:   make(map[string]bool),
+	}
+}
+
+// apply updates the graph after author added and removed follows, which must
+// already be stored
+func (u *graphUpdate) apply(ctx context.Context, author string, added, removed []string) error {
+	// A direct follow following the root back, or no longer, flips mutual
+	if author != u.root && (containsPubkey(added, u.root) || containsPubkey(removed, u.root)) {
+		node, err := u.node(ctx, author)
+		if err != nil {
+			return err
+		}
+		if node != nil && node.Depth == 1 {
+			flipped := *node
+			flipped.Mutual = containsPubkey(added, u.root)
+			u.nodes[author] = &flipped
+			u.changed[author] = true
+		}
+	}
+
+	// Follows of authors outside the graph do not affect it
+	depth, err := u.depth(ctx, author)
+	if err != nil || depth == unreachable {
+		return err
+	}
+
+	// Additions go first: unlink only ever moves nodes further away, so once
+	// it has placed the detached nodes, using any new edges, nothing below
+	// them can get closer. Neither step changes the author's own depth.
+	if err := u.link(ctx, depth+1, added); err != nil {
+		return err
+	}
+	return u.unlink(ctx, depth+1, removed)
+}
+
+// link brings pubkeys up to depth where that is closer than before and
+// carries the change to their follows, breadth first
+func (u *graphUpdate) link(ctx context.Context, depth int, pubkeys []string) error {
+	var queue []string
+	for _, pubkey := range pubkeys {
+		moved, err := u.moveCloser(ctx, pubkey, depth)
+		if err != nil {
+			return err
+		}
+		if moved {
+			queue = append(queue, pubkey)
+		}
+	}
+
+	for i := 0; i < len(queue); i++ {
+		parent := queue[i]
+		depth, err := u.depth(ctx, parent)
+		if err != nil {
+			return err
+		}
+		follows, err := u.followsOf(ctx, parent)
+		if err != nil {
+			return err
+		}
+		for _, pubkey := range follows {
+			moved, err := u.moveCloser(ctx, pubkey, depth+1)
+			if err != nil {
+				return err
+			}
+			if moved {
+				queue = append(queue, pubkey)
+			}
+		}
+	}
+
+	return nil
+}
+
+// moveCloser sets pubkey to depth if it is currently further away
+func (u *graphUpdate) moveCloser(ctx context.Context, pubkey string, depth int) (bool, error) {
+	if pubkey == u.root {
+		return false, nil
+	}
+	current, err := u.depth(ctx, pubkey)
+	if err != nil || current <= depth {
+		return false, err
+	}
+	return true, u.setDepth(ctx, pubkey, depth)
+}
+
+// unlink handles pubkeys no longer followed from depth-1. Those left without
+// a parent one hop closer to the root are detached together with the nodes
+// below them that depended on them, then each detached node takes the
+// shortest depth still reachable, or leaves the graph.
+func (u *graphUpdate) unlink(ctx context.Context, depth int, pubkeys []string) error {
+	detached := make(map[string]bool)
+	var queue []string
+	for _, pubkey := range pubkeys {
+		orphaned, err := u.orphaned(ctx, pubkey, depth, detached)
+		if err != nil {
+			return err
+		}
+		if orphaned {
+			detached[pubkey] = true
+			queue = append(queue, pubkey)
+		}
+	}
+	if len(queue) == 0 {
+		return nil
+	}
+
+	// Breadth first, every detached node at a depth is known before the
+	// nodes one hop further are checked against it
+	for i := 0; i < len(queue); i++ {
+		parent := queue[i]
+		depth, err := u.depth(ctx, parent)
+		if err != nil {
+			return err
+		}
+		follows, err := u.followsOf(ctx, parent)
+		if err != nil {
+			return err
+		}
+		for _, pubkey := range follows {
+			if detached[pubkey] {
+				continue
+			}
+			orphaned, err := u.orphaned(ctx, pubkey, depth+1, detached)
+			if err != nil {
+				return err
+			}
+			if orphaned {
+				detached[pubkey] = true
+				queue = append(queue, pubkey)
+			}
+		}
+	}
+
+	// Each detached node starts one hop below its closest attached follower
+	distances := make(map[string]int, len(queue))
+	buckets := make(map[int][]string)
+	closest, furthest := unreachable, 0
+	for _, pubkey := range queue {
+		followers, err := u.followersOf(ctx, pubkey)
+		if err != nil {
+			return err
+		}
+		best := unreachable
+		for _, follower := range followers {
+			if detached[follower] {
+				continue
+			}
+			depth, err := u.depth(ctx, follower)
+			if err != nil {
+				return err
+			}
+			if depth != unreachable && depth+1 < best {
+				best = depth + 1
+			}
+		}
+		if best != unreachable {
+			distances[pubkey] = best
+			buckets[best] = append(buckets[best], pubkey)
+			closest = min(closest, best)
+			furthest = max(furthest, best)
+		}
+	}
+
+	// and improves through other detached nodes, closest first
+	done := make(map[string]bool, len(queue))
+	for depth := closest; depth <= furthest; depth++ {
+		for _, pubkey := range buckets[depth] {
+			if done[pubkey] || distances[pubkey] != depth {
+				continue
+			}
+			done[pubkey] = true
+
+			follows, err := u.followsOf(ctx, pubkey)
+			if err != nil {
+				return err
+			}
+			for _, follow := range follows {
+				if !detached[follow] || done[follow] {
+					continue
+				}
+				if current, ok := distances[follow]; !ok || depth+1 < current {
+					distances[follow] = depth + 1
+					buckets[depth+1] = append(buckets[depth+1], follow)
+					furthest = max(furthest, depth+1)
+				}
+			}
+		}
+	}
+
+	for _, pubkey := range queue {
+		depth, ok := distances[pubkey]
+		if !ok {
+			depth = unreachable
+		}
+		if err := u.setDepth(ctx, pubkey, depth); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// orphaned reports whether pubkey sits at depth and has no attached follower
+// one hop closer to the root
+func (u *graphUpdate) orphaned(ctx context.Context, pubkey string, depth int, detached map[string]bool) (bool, error) {
+	if pubkey == u.root {
+		return false, nil
+	}
+	current, err := u.depth(ctx, pubkey)
+	if err != nil || current != depth {
+		return false, err
+	}
+
+	followers, err := u.followersOf(ctx, pubkey)
+	if err != nil {
+		return false, err
+	}
+	for _, follower := range followers {
+		if detached[follower] {
+			continue
+		}
+		parentDepth, err := u.depth(ctx, follower)
+		if err != nil {
+			return false, err
+		}
+		if parentDepth == depth-1 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// setDepth moves pubkey to depth, or out of the graph when unreachable. Only
+// direct follows can be mutual.
+func (u *graphUpdate) setDepth(ctx context.Context, pubkey string, depth int) error {
+	u.changed[pubkey] = true
+	if depth == unreachable {
+		u.nodes[pubkey] = nil
+		return nil
+	}
+
+	mutual := false
+	if depth == 1 {
+		var err error
+		if mutual, err = u.storage.IsFollowing(ctx, pubkey, u.root); err != nil {
+			return err
+		}
+	}
+
+	u.nodes[pubkey] = &storage.GraphNode{
+		RootPubkey: u.root,
+		Pubkey:     pubkey,
+		Depth:      depth,
+		Mutual:     mutual,
+		LastSeen:   u.seen,
+	}
+	return nil
+}
+
+// depth returns how many hops pubkey is from the root
+func (u *graphUpdate) depth(ctx context.Context, pubkey string) (int, error) {
+	if pubkey == u.root {
+		return 0, nil
+	}
+	node, err := u.node(ctx, pubkey)
+	if err != nil || node == nil {
+		return unreachable, err
+	}
+	return node.Depth, nil
+}
+
+// node returns the graph node for pubkey, or nil if it is not in the graph
+func (u *graphUpdate) node(ctx context.Context, pubkey string) (*storage.GraphNode, error) {
+	if node, ok := u.nodes[pubkey]; ok {
+		return node, nil
+	}
+
+	node, err := u.storage.GetGraphNode(ctx, u.root, pubkey)
+	if errors.Is(err, sql.ErrNoRows) {
+		node, err = nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get graph node: %w", err)
+	}
+
+	u.nodes[pubkey] = node
+	return node, nil
+}
+
+func (u *graphUpdate) followsOf(ctx context.Context, pubkey string) ([]string, error) {
+	if follows, ok := u.follows[pubkey]; ok {
+		return follows, nil
+	}
+	follows, err := u.storage.GetFollows(ctx, pubkey)
+	if err != nil {
+		return nil, err
+	}
+	u.follows[pubkey] = follows
+	return follows, nil
+}
+
+func (u *graphUpdate) followersOf(ctx context.Context, pubkey string) ([]string, error) {
+	if followers, ok := u.followers[pubkey]; ok {
+		return followers, nil
+	}
+	followers, err := u.storage.GetFollowers(ctx, pubkey)
+	if err != nil {
+		return nil, err
+	}
+	u.followers[pubkey] = followers
+	return followers, nil
+}
+
+// commit writes the changed nodes
+func (u *graphUpdate) commit(ctx context.Context) error {
+	pubkeys := make([]string, 0, len(u.changed))
+	for pubkey := range u.changed {
+		pubkeys = append(pubkeys, pubkey)
+	}
+	sort.Strings(pubkeys)
+
+	var save []*storage.GraphNode
+	var remove []string
+	for _, pubkey := range pubkeys {
+		if node := u.nodes[pubkey]; node != nil {
+			save = append(save, node)
+		} else {
+			remove = append(remove, pubkey)
+		}
+	}
+
+	if err := u.storage.UpdateGraphNodes(ctx, u.root, save, remove); err != nil {
+		return fmt.Errorf("failed to update graph nodes: %w", err)
+	}
+	return nil
+}
+
+func containsPubkey(pubkeys []string, pubkey string) bool {
+	for _, p := range pubkeys {
+		if p == pubkey {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package sync
+
+import "sync"
+
+// Sync methods counted in IngestStats.Syncs
+const (
+	SyncMethodNegentropy  = "negentropy"   // NIP-77 reconciliation completed
+	SyncMethodREQFallback = "req_fallback" // negentropy unavailable or failed, fell back to REQ
+	SyncMethodREQ         = "req"          // REQ without trying negentropy
+)
+
+// IngestStats counts what the engine has received since it started
+type IngestStats struct {
+	EventsByRelay map[string]int64 // events received per relay
+	Syncs         map[string]int64 // relay syncs per method
+	Rejected      map[string]int64 // events dropped per rejection reason (see RejectInvalidID)
+}
+
+// ingestCounters accumulates IngestStats; the zero value is ready to use
+type ingestCounters struct {
+	mu       sync.Mutex
+	events   map[string]int64
+	syncs    map[string]int64
+	rejected map[string]int64
+}
+
+func (c *ingestCounters) countEvent(relay string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.events == nil {
+		c.events = make(map[string]int64)
+	}
+	c.events[relay]++
+}
+
+func (c *ingestCounters) countSync(method string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.syncs == nil {
+		c.syncs = make(map[string]int64)
+	}
+	c.syncs[method]++
+}
+
+func (c *ingestCounters) countRejection(reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rejected == nil {
+		c.rejected = make(map[string]int64)
+	}
+	c.rejected[reason]++
+}
+
+func (c *ingestCounters) snapshot() IngestStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := IngestStats{
+		EventsByRelay: make(map[string]int64, len(c.events)),
+		Syncs:         make(map[string]int64, len(c.syncs)),
+		Rejected:      make(map[string]int64, len(c.rejected)),
+	}
+	for relay, n := range c.events {
+		stats.EventsByRelay[relay] = n
+	}
+	for method, n := range c.syncs {
+		stats.Syncs[method] = n
+	}
+	for reason, n := range c.rejected {
+		stats.Rejected[reason] = n
+	}
+	return stats
+}
+
+// IngestStats returns per-relay event counts, sync method counts and
+// rejection counts since the engine started
+func (e *Engine) IngestStats() IngestStats {
+	return e.ingest.snapshot()
+}
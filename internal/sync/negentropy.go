@@ -16,6 +16,8 @@ import (
 type NegentropyStore struct {
 	storage *storage.Storage
 	ctx     context.Context
+	onSave  func(*nostr.Event)      // called for each event received, may be nil
+	admit   func(*nostr.Event) bool // reports whether an event may be stored, may be nil
 }
 
 // NewNegentropyStore creates a new adapter wrapping nophr storage
@@ -64,6 +66,12 @@ func (s *NegentropyStore) QueryEvents(ctx context.Context, filter nostr.Filter)
 
 // SaveEvent implements eventstore.Store interface
 func (s *NegentropyStore) SaveEvent(ctx context.Context, event *nostr.Event) error {
+	if s.onSave != nil {
+		s.onSave(event)
+	}
+	if s.admit != nil && !s.admit(event) {
+		return nil
+	}
 	return s.storage.StoreEvent(ctx, event)
 }
 
@@ -102,6 +110,8 @@ func (e *Engine) NegentropySync(ctx context.Context, relayURL string, filter nos
 
 	// Create negentropy store adapter
 	store := NewNegentropyStore(e.storage, ctx)
+	store.onSave = func(*nostr.Event) { e.ingest.countEvent(relayURL) }
+	store.admit = e.admit
 	relayWrapper := &eventstore.RelayWrapper{Store: store}
 
 	// Attempt negentropy sync (DOWN direction = fetch missing events from relay)
@@ -0,0 +1,189 @@
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip13"
+	"github.com/nbd-wtf/go-nostr/nip40"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/nostr/helpers"
+	"github.com/sandwichfarm/nophr/internal/security"
+)
+
+// Rejection reasons counted in IngestStats.Rejected
+const (
+	RejectInvalidID        = "invalid_id"
+	RejectInvalidSignature = "invalid_signature"
+	RejectContentTooLarge  = "content_too_large"
+	RejectTooManyTags      = "too_many_tags"
+	RejectTagsTooLarge     = "tags_too_large"
+	RejectFuture           = "future_created_at"
+	RejectPoW              = "insufficient_pow"
+	RejectExpired          = "expired"
+	RejectDenylist         = "denylist"
+	RejectBannedWords      = "banned_words"
+)
+
+// Rejection is returned by an IngestPolicy for an event that must not be stored
+type Rejection struct {
+	Reason string // counted in IngestStats.Rejected
+	Detail string
+}
+
+func (r *Rejection) Error() string {
+	if r.Detail == "" {
+		return r.Reason
+	}
+	return fmt.Sprintf("%s: %s", r.Reason, r.Detail)
+}
+
+// IngestPolicy checks an event received from a relay before it is stored. It
+// returns nil to pass the event on to the next policy.
+type IngestPolicy func(event *nostr.Event) *Rejection
+
+// newIngestPolicies builds the policy chain configured in sync.ingest
+func newIngestPolicies(cfg *config.Config) []IngestPolicy {
+	ingest := &cfg.Sync.Ingest
+	owner, _ := helpers.NormalizePubkey(cfg.Identity.Npub)
+
+	policies := make([]IngestPolicy, 0, len(ingest.Policies))
+	for _, name := range ingest.Policies {
+		switch name {
+		case config.IngestSignature:
+			policies = append(policies, checkSignature)
+		case config.IngestSize:
+			policies = append(policies, checkSize(ingest.MaxContentBytes, ingest.MaxTags, ingest.MaxTagBytes))
+		case config.IngestFuture:
+			policies = append(policies, checkFuture(time.Duration(ingest.MaxFutureSeconds)*time.Second, time.Now))
+		case config.IngestPoW:
+			policies = append(policies, checkPoW(ingest, owner))
+		case config.IngestExpiration:
+			policies = append(policies, checkExpiration(time.Now))
+		case config.IngestSecurity:
+			policies = append(policies, checkSecurity(security.NewEnforcerFromConfig(&cfg.Security)))
+		}
+	}
+
+	return policies
+}
+
+// checkSignature rejects events whose ID is not the hash of their content or
+// whose signature does not verify
+func checkSignature(event *nostr.Event) *Rejection {
+	if !event.CheckID() {
+		return &Rejection{Reason: RejectInvalidID}
+	}
+	if ok, err := event.CheckSignature(); !ok {
+		rejection := &Rejection{Reason: RejectInvalidSignature}
+		if err != nil {
+			rejection.Detail = err.Error()
+		}
+		return rejection
+	}
+	return nil
+}
+
+// checkSize rejects events with oversized content or tags
+func checkSize(maxContent, maxTags, maxTagBytes int) IngestPolicy {
+	return func(event *nostr.Event) *Rejection {
+		if len(event.Content) > maxContent {
+			return &Rejection{Reason: RejectContentTooLarge, Detail: fmt.Sprintf("%d > %d bytes", len(event.Content), maxContent)}
+		}
+		if len(event.Tags) > maxTags {
+			return &Rejection{Reason: RejectTooManyTags, Detail: fmt.Sprintf("%d > %d", len(event.Tags), maxTags)}
+		}
+
+		size := 0
+		for _, tag := range event.Tags {
+			for _, value := range tag {
+				size += len(value)
+			}
+		}
+		if size > maxTagBytes {
+			return &Rejection{Reason: RejectTagsTooLarge, Detail: fmt.Sprintf("%d > %d bytes", size, maxTagBytes)}
+		}
+		return nil
+	}
+}
+
+// checkFuture rejects events dated further ahead than the allowed clock skew
+func checkFuture(skew time.Duration, now func() time.Time) IngestPolicy {
+	return func(event *nostr.Event) *Rejection {
+		limit := now().Add(skew)
+		if event.CreatedAt.Time().After(limit) {
+			return &Rejection{Reason: RejectFuture, Detail: event.CreatedAt.Time().UTC().Format(time.RFC3339)}
+		}
+		return nil
+	}
+}
+
+// checkPoW rejects events without the NIP-13 difficulty required for their
+// kind. Only committed work (the nonce tag's target) counts. The owner's
+// events are exempt.
+func checkPoW(ingest *config.Ingest, owner string) IngestPolicy {
+	return func(event *nostr.Event) *Rejection {
+		required := ingest.RequiredPoW(event.Kind)
+		if required == 0 || event.PubKey == owner {
+			return nil
+		}
+		if work := nip13.CommittedDifficulty(event); work < required {
+			return &Rejection{Reason: RejectPoW, Detail: fmt.Sprintf("difficulty %d < %d", work, required)}
+		}
+		return nil
+	}
+}
+
+// checkExpiration rejects events whose NIP-40 expiration has passed
+func checkExpiration(now func() time.Time) IngestPolicy {
+	return func(event *nostr.Event) *Rejection {
+		expiration := nip40.GetExpiration(event.Tags)
+		if expiration >= 0 && expiration.Time().Before(now()) {
+			return &Rejection{Reason: RejectExpired, Detail: expiration.Time().UTC().Format(time.RFC3339)}
+		}
+		return nil
+	}
+}
+
+// checkSecurity rejects events from denied pubkeys or containing banned words
+func checkSecurity(enforcer *security.Enforcer) IngestPolicy {
+	return func(event *nostr.Event) *Rejection {
+		if enforcer.GetDenyList().IsEventDenied(event) {
+			return &Rejection{Reason: RejectDenylist, Detail: event.PubKey}
+		}
+		if enforcer.GetContentFilter().IsEventFiltered(event) {
+			return &Rejection{Reason: RejectBannedWords}
+		}
+		return nil
+	}
+}
+
+// AddIngestPolicy appends a policy to the chain run before each event is
+// stored. Call it before Start.
+func (e *Engine) AddIngestPolicy(policy IngestPolicy) {
+	if policy == nil {
+		return
+	}
+	e.policies = append(e.policies, policy)
+}
+
+// admit runs the ingest policies and counts the reason an event is rejected
+func (e *Engine) admit(event *nostr.Event) bool {
+	for _, policy := range e.policies {
+		if rejection := policy(event); rejection != nil {
+			e.ingest.countRejection(rejection.Reason)
+			fmt.Printf("[SYNC]   ✗ Rejected event %s: %v\n", shortID(event.ID), rejection)
+			return false
+		}
+	}
+	return true
+}
+
+// shortID abbreviates an event ID for logs; rejected IDs may be malformed
+func shortID(id string) string {
+	if len(id) > 16 {
+		return id[:16] + "..."
+	}
+	return id
+}
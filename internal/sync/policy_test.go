@@ -0,0 +1,114 @@
+package sync
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip13"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/sandwichfarm/nophr/internal/config"
+)
+
+func TestIngestPolicies(t *testing.T) {
+	_, st, cleanup := setupTestGraph(t)
+	defer cleanup()
+
+	owner := nostr.GeneratePrivateKey()
+	ownerPub, _ := nostr.GetPublicKey(owner)
+	npub, _ := nip19.EncodePublicKey(ownerPub)
+	spammer := nostr.GeneratePrivateKey()
+	spammerPub, _ := nostr.GetPublicKey(spammer)
+	alice := nostr.GeneratePrivateKey()
+
+	cfg := config.Default()
+	cfg.Identity.Npub = npub
+	cfg.Sync.Ingest.MaxContentBytes = 100
+	cfg.Sync.Ingest.MinPoWByKind = map[int]int{1: 4}
+	cfg.Security.DenylistPubkeys = []string{spammerPub}
+	cfg.Security.BannedWords = []string{"casino"}
+
+	engine := NewEngine(st, cfg)
+	defer engine.cancel()
+
+	now := nostr.Now()
+	mined := func(sk string, kind int, tags nostr.Tags, content string) *nostr.Event {
+		t.Helper()
+		event := signedEvent(t, sk, kind, now, tags, content)
+		pk, _ := nostr.GetPublicKey(sk)
+		event.PubKey = pk
+		nonce, err := nip13.DoWork(context.Background(), *event, 4)
+		if err != nil {
+			t.Fatalf("DoWork() error = %v", err)
+		}
+		return signedEvent(t, sk, kind, now, append(tags, nonce), content)
+	}
+
+	forged := signedEvent(t, alice, 7, now, nil, "🤙")
+	forged.Content = "-"
+	badSig := signedEvent(t, alice, 7, now, nil, "❤️")
+	badSig.Sig = strings.Repeat("0", 128)
+
+	tests := []struct {
+		name   string
+		event  *nostr.Event
+		reason string // "" when the event is stored
+	}{
+		{"valid reaction", signedEvent(t, alice, 7, now, nil, "+"), ""},
+		{"note with work", mined(alice, 1, nostr.Tags{}, "hello"), ""},
+		{"owner note without work", signedEvent(t, owner, 1, now, nil, "mine"), ""},
+		{"tampered content", forged, RejectInvalidID},
+		{"bad signature", badSig, RejectInvalidSignature},
+		{"oversized content", signedEvent(t, alice, 7, now, nil, strings.Repeat("x", 101)), RejectContentTooLarge},
+		{"far future", signedEvent(t, alice, 7, now+3600, nil, "+"), RejectFuture},
+		{"note without work", signedEvent(t, alice, 1, now, nil, "cheap"), RejectPoW},
+		{"expired", signedEvent(t, alice, 7, now, nostr.Tags{{"expiration", strconv.FormatInt(int64(now)-60, 10)}}, "+"), RejectExpired},
+		{"denied pubkey", signedEvent(t, spammer, 7, now, nil, "+"), RejectDenylist},
+		{"banned word", mined(alice, 1, nostr.Tags{}, "win at the casino"), RejectBannedWords},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := engine.IngestStats().Rejected[tt.reason]
+			if err := engine.processEvent(tt.event); err != nil {
+				t.Fatalf("processEvent() error = %v", err)
+			}
+
+			stored, err := st.EventExists(context.Background(), tt.event.ID)
+			if err != nil {
+				t.Fatalf("EventExists() error = %v", err)
+			}
+			if stored != (tt.reason == "") {
+				t.Errorf("stored = %v, want %v", stored, tt.reason == "")
+			}
+			if tt.reason != "" && engine.IngestStats().Rejected[tt.reason] != before+1 {
+				t.Errorf("Rejected[%s] = %d, want %d", tt.reason, engine.IngestStats().Rejected[tt.reason], before+1)
+			}
+		})
+	}
+}
+
+func TestIngestPoliciesConfigured(t *testing.T) {
+	cfg := config.Default()
+	cfg.Sync.Ingest.Policies = []string{config.IngestFuture}
+	policies := newIngestPolicies(cfg)
+	if len(policies) != 1 {
+		t.Fatalf("Expected 1 policy, got %d", len(policies))
+	}
+
+	event := &nostr.Event{ID: "not-an-id", CreatedAt: nostr.Now(), Content: "unsigned"}
+	if rejection := policies[0](event); rejection != nil {
+		t.Errorf("Only the future policy should run, got %v", rejection)
+	}
+
+	check := checkFuture(time.Minute, func() time.Time { return time.Unix(1000, 0) })
+	if check(&nostr.Event{CreatedAt: 1060}) != nil {
+		t.Error("Events within the allowed skew should pass")
+	}
+	if rejection := check(&nostr.Event{CreatedAt: 1061}); rejection == nil || rejection.Reason != RejectFuture {
+		t.Errorf("Expected %s, got %v", RejectFuture, rejection)
+	}
+}
@@ -0,0 +1,157 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sandwichfarm/nophr/internal/config"
+)
+
+// AuthorScore is how a candidate author ranked for max_authors, with the
+// signals its score was built from
+type AuthorScore struct {
+	Pubkey       string
+	Score        float64
+	Depth        int   // hops from the owner in the social graph
+	FollowedBy   int   // how many of the owner's follows follow them
+	Mutual       bool  // they follow the owner back
+	Interactions int64 // replies, reactions and zaps to the owner in the window
+	RelayHints   bool  // their relay list is known
+}
+
+// AuthorSelection is the outcome of the last scope computation
+type AuthorSelection struct {
+	At         time.Time
+	Candidates int           // authors in scope after the allow and deny lists, owner included
+	MaxAuthors int           // 0 = no cap
+	Ranked     []AuthorScore // the selected authors besides the owner, best first; nil when all candidates fit
+}
+
+// rankAuthors scores candidates, which must not include the owner, and
+// returns them best first
+func (g *Graph) rankAuthors(ctx context.Context, rootPubkey string, candidates []string) ([]AuthorScore, error) {
+	weights := g.config.Ranking
+	if weights == nil {
+		weights = config.DefaultAuthorRanking()
+	}
+
+	nodes, err := g.storage.GetGraphNodes(ctx, rootPubkey, maxGraphDepth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get graph nodes: %w", err)
+	}
+
+	scores := make([]AuthorScore, len(candidates))
+	index := make(map[string]int, len(candidates))
+	for i, pubkey := range candidates {
+		scores[i] = AuthorScore{Pubkey: pubkey}
+		index[pubkey] = i
+	}
+
+	// Depth and mutual status come from the graph; authors outside it (such
+	// as allowlisted ones) count as the furthest away
+	var follows []string
+	furthest := 1
+	for _, node := range nodes {
+		if node.Depth == 1 {
+			follows = append(follows, node.Pubkey)
+		}
+		if i, ok := index[node.Pubkey]; ok {
+			scores[i].Depth = node.Depth
+			scores[i].Mutual = node.Mutual
+			if node.Depth > furthest {
+				furthest = node.Depth
+			}
+		}
+	}
+	for i := range scores {
+		if scores[i].Depth == 0 {
+			scores[i].Depth = furthest
+		}
+	}
+
+	if weights.FollowedBy > 0 {
+		if err := g.countFollowedBy(ctx, follows, index, scores); err != nil {
+			return nil, err
+		}
+	}
+
+	if weights.Interactions > 0 {
+		since := time.Now().AddDate(0, 0, -weights.InteractionDays).Unix()
+		counts, err := g.storage.CountInteractionsWith(ctx, rootPubkey, since)
+		if err != nil {
+			return nil, err
+		}
+		for pubkey, count := range counts {
+			if i, ok := index[pubkey]; ok {
+				scores[i].Interactions = count
+			}
+		}
+	}
+
+	if weights.RelayHints > 0 {
+		hints, err := g.storage.GetAllRelayHints(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, hint := range hints {
+			if i, ok := index[hint.Pubkey]; ok {
+				scores[i].RelayHints = true
+			}
+		}
+	}
+
+	for i := range scores {
+		s := &scores[i]
+		s.Score = weights.Depth*float64(furthest+1-s.Depth) +
+			weights.FollowedBy*float64(s.FollowedBy) +
+			weights.Interactions*float64(s.Interactions)
+		if s.Mutual {
+			s.Score += weights.Mutual
+		}
+		if s.RelayHints {
+			s.Score += weights.RelayHints
+		}
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].Pubkey < scores[j].Pubkey
+	})
+	return scores, nil
+}
+
+// countFollowedBy counts, for each candidate, the owner's follows whose
+// latest contact list includes them
+func (g *Graph) countFollowedBy(ctx context.Context, follows []string, index map[string]int, scores []AuthorScore) error {
+	for _, follow := range follows {
+		followees, err := g.storage.GetFollows(ctx, follow)
+		if err != nil {
+			return fmt.Errorf("failed to get follows: %w", err)
+		}
+		for _, followee := range followees {
+			if i, ok := index[followee]; ok && followee != follow {
+				scores[i].FollowedBy++
+			}
+		}
+	}
+	return nil
+}
+
+// Selection returns the outcome of the last GetAuthorsInScope, or nil before
+// the first one
+func (g *Graph) Selection() *AuthorSelection {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.selection
+}
+
+// setSelection records the outcome of a scope computation
+func (g *Graph) setSelection(selection *AuthorSelection) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.selection = selection
+}
@@ -75,3 +75,9 @@ func (e *Engine) LastSyncTime(ctx context.Context) (*time.Time, error) {
 
 	return newest, nil
 }
+
+// AuthorSelection returns how the last sync chose its authors, or nil before
+// the first sync
+func (e *Engine) AuthorSelection() *AuthorSelection {
+	return e.currentScope().graph.Selection()
+}
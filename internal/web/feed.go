@@ -0,0 +1,80 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/sandwichfarm/nophr/internal/feeds"
+	nostrclient "github.com/sandwichfarm/nophr/internal/nostr"
+)
+
+// handleSectionFeed renders the first page of a public section as an Atom
+// feed at /feeds/<section>.atom. Sensitive entries carry their content
+// warning as the summary and no content.
+func (r *Router) handleSectionFeed(ctx context.Context, name string) *response {
+	section, err := r.server.GetSectionManager().GetSection(name)
+	if err != nil {
+		return r.renderer.Error(http.StatusNotFound, fmt.Sprintf("Unknown section: %s", name))
+	}
+
+	page, err := r.server.GetSectionManager().GetPage(ctx, section.Name, 1)
+	if err != nil {
+		return r.renderer.Error(http.StatusInternalServerError, fmt.Sprintf("Error loading section: %v", err))
+	}
+
+	self := r.absoluteURL(sectionFeedPath(section))
+	link := r.absoluteURL("/")
+	if section.Path != "" {
+		link = r.absoluteURL(section.Path)
+	}
+
+	feed := &feeds.Feed{
+		ID:       self,
+		Title:    fmt.Sprintf("%s - %s", r.server.settings().Site.Title, sectionLabel(section)),
+		Subtitle: section.Description,
+		Link:     link,
+		Self:     self,
+		Author:   r.server.settings().Site.Operator,
+	}
+	for _, event := range page.Events {
+		feed.Entries = append(feed.Entries, r.feedEntry(ctx, event))
+	}
+
+	data, err := feed.Atom()
+	if err != nil {
+		return r.renderer.Error(http.StatusInternalServerError, fmt.Sprintf("Error encoding feed: %v", err))
+	}
+	return &response{status: http.StatusOK, contentType: feeds.AtomContentType + "; charset=utf-8", body: data}
+}
+
+// feedEntry builds the feed entry of an event
+func (r *Router) feedEntry(ctx context.Context, event *nostr.Event) *feeds.Entry {
+	item := r.renderer.EventItem(ctx, event, nil)
+
+	entry := &feeds.Entry{
+		ID:        "nostr:" + event.ID,
+		Title:     item.Title,
+		Link:      r.absoluteURL(item.Href),
+		Author:    item.Author,
+		Published: item.Time,
+		Updated:   event.CreatedAt.Time(),
+	}
+	if note, err := nip19.EncodeNote(event.ID); err == nil {
+		entry.ID = "nostr:" + note
+	}
+
+	if label, ok := nostrclient.ContentWarningLabel(event); ok {
+		entry.Summary = label
+		return entry
+	}
+
+	if article := nostrclient.ParseArticle(event); article != nil {
+		entry.Summary = article.Summary
+	}
+	entry.Content = string(r.renderer.RenderContent(ctx, event))
+
+	return entry
+}
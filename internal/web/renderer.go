@@ -0,0 +1,568 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"html"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/sandwichfarm/nophr/internal/aggregates"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/entities"
+	"github.com/sandwichfarm/nophr/internal/markdown"
+	nostrclient "github.com/sandwichfarm/nophr/internal/nostr"
+	"github.com/sandwichfarm/nophr/internal/presentation"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// pageNames are the templates rendered inside templates/layout.html
+var pageNames = []string{"home", "list", "sections", "note", "thread", "profile", "search", "error"}
+
+// templateFuncs are available to every template
+var templateFuncs = template.FuncMap{
+	"datetime": func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+	"date":     func(t time.Time) string { return t.UTC().Format("2 Jan 2006 15:04 UTC") },
+}
+
+// pageTemplates holds one template set per page, each sharing the layout
+var pageTemplates = parseTemplates()
+
+func parseTemplates() map[string]*template.Template {
+	base := template.Must(template.New("layout.html").Funcs(templateFuncs).ParseFS(templateFS, "templates/layout.html", "templates/partials.html"))
+
+	pages := make(map[string]*template.Template, len(pageNames))
+	for _, name := range pageNames {
+		pages[name] = template.Must(template.Must(base.Clone()).ParseFS(templateFS, "templates/"+name+".html"))
+	}
+	return pages
+}
+
+// Renderer renders Nostr events as HTML pages
+type Renderer struct {
+	parser   *markdown.Parser
+	mu       sync.RWMutex
+	config   *config.Config
+	loader   *presentation.Loader
+	resolver *entities.Resolver
+	storage  *storage.Storage
+}
+
+// NewRenderer creates a new event renderer
+func NewRenderer(cfg *config.Config, st *storage.Storage) *Renderer {
+	return &Renderer{
+		parser:   markdown.NewParser(),
+		config:   cfg,
+		loader:   presentation.NewLoader(cfg),
+		resolver: entities.NewResolver(st),
+		storage:  st,
+	}
+}
+
+// SetConfig switches rendering to a new configuration, including the headers
+// and footers it defines
+func (r *Renderer) SetConfig(cfg *config.Config) {
+	r.mu.Lock()
+	r.config = cfg
+	r.mu.Unlock()
+	r.loader.SetConfig(cfg)
+}
+
+// settings returns the configuration pages are currently rendered with
+func (r *Renderer) settings() *config.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config
+}
+
+// layoutData is passed to templates/layout.html
+type layoutData struct {
+	SiteTitle       string
+	SiteDescription string
+	Title           string     // document title, before the site title
+	Nav             string     // highlighted navigation entry
+	Header          string     // configured page header text
+	Footer          string     // configured page footer text
+	Feeds           []linkView // Atom feeds advertised in <head>
+	Content         any
+}
+
+// linkView is a link with its text
+type linkView struct {
+	Title string
+	Href  string
+}
+
+// eventItem is an event in a list
+type eventItem struct {
+	Href       string
+	Title      string
+	Kind       string // "Note", "Article" or "Profile"
+	Author     string
+	AuthorHref string
+	Time       time.Time
+	Sensitive  bool // Title is the content warning
+	Stats      string
+}
+
+// pageOptions are the layout fields of a page that vary per route
+type pageOptions struct {
+	title string     // document title
+	nav   string     // navigation entry to highlight
+	page  string     // page name for configured headers and footers
+	feeds []linkView // Atom feeds to advertise
+}
+
+// Page renders a page template inside the layout
+func (r *Renderer) Page(name string, opts pageOptions, content any) *response {
+	data := &layoutData{
+		SiteTitle:       r.settings().Site.Title,
+		SiteDescription: r.settings().Site.Description,
+		Title:           opts.title,
+		Nav:             opts.nav,
+		Feeds:           opts.feeds,
+		Content:         content,
+	}
+	if opts.page != "" {
+		if header, err := r.loader.GetHeader(opts.page); err == nil {
+			data.Header = header
+		}
+		if footer, err := r.loader.GetFooter(opts.page); err == nil {
+			data.Footer = footer
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pageTemplates[name].ExecuteTemplate(&buf, "layout.html", data); err != nil {
+		fmt.Printf("HTTP template error (%s): %v\n", name, err)
+		return textResponse(http.StatusInternalServerError, "Error rendering page")
+	}
+	return htmlResponse(http.StatusOK, buf.Bytes())
+}
+
+// Error renders an error page with a status code
+func (r *Renderer) Error(status int, message string) *response {
+	resp := r.Page("error", pageOptions{title: http.StatusText(status)}, struct {
+		Status  string
+		Message string
+	}{http.StatusText(status), message})
+	if resp.status == http.StatusOK {
+		resp.status = status
+	}
+	return resp
+}
+
+// EventItem builds the list entry of an event
+func (r *Renderer) EventItem(ctx context.Context, event *nostr.Event, agg *aggregates.EventAggregates) eventItem {
+	item := eventItem{
+		Href:       "/note/" + event.ID,
+		Title:      r.eventTitle(event),
+		Kind:       "Note",
+		Author:     r.resolver.DisplayName(ctx, event.PubKey),
+		AuthorHref: "/profile/" + event.PubKey,
+		Time:       event.CreatedAt.Time(),
+		Sensitive:  nostrclient.IsSensitive(event),
+	}
+	if article := nostrclient.ParseArticle(event); article != nil {
+		item.Kind = "Article"
+		item.Time = article.PublishedAt
+	}
+	if agg != nil && agg.HasInteractions() && r.settings().Display.Feed.ShowInteractions {
+		feed := r.settings().Display.Feed
+		item.Stats = r.stats(agg, feed.ShowReplies, feed.ShowReactions, feed.ShowZaps)
+	}
+	return item
+}
+
+// EventItems builds the list entries of enriched events
+func (r *Renderer) EventItems(ctx context.Context, events []*aggregates.EnrichedEvent) []eventItem {
+	items := make([]eventItem, 0, len(events))
+	for _, event := range events {
+		items = append(items, r.EventItem(ctx, event.Event, event.Aggregates))
+	}
+	return items
+}
+
+// PlainEventItems builds the list entries of events without aggregates
+func (r *Renderer) PlainEventItems(ctx context.Context, events []*nostr.Event) []eventItem {
+	items := make([]eventItem, 0, len(events))
+	for _, event := range events {
+		items = append(items, r.EventItem(ctx, event, nil))
+	}
+	return items
+}
+
+// articleView holds the NIP-23 metadata shown above an article
+type articleView struct {
+	Title       string
+	Summary     string
+	Image       string
+	PublishedAt time.Time
+	Hashtags    []string
+}
+
+// zapperView is a zapper with their total and latest comment
+type zapperView struct {
+	Name    string
+	Href    string
+	Sats    string
+	Count   int
+	Comment string
+}
+
+// noteView is the content of a note page
+type noteView struct {
+	Item       eventItem
+	Article    *articleView
+	Warning    string // content warning shown above the revealed content
+	Body       template.HTML
+	Stats      string
+	Zappers    []zapperView
+	ThreadHref string
+	Thread     *threadNode
+	Portals    []linkView
+}
+
+// NoteView builds the note page of an event, with its thread if configured
+func (r *Renderer) NoteView(ctx context.Context, event *nostr.Event, agg *aggregates.EventAggregates, thread *aggregates.ThreadView) *noteView {
+	view := &noteView{
+		Item:       r.EventItem(ctx, event, nil),
+		Warning:    nostrclient.RevealedWarning(event),
+		ThreadHref: "/thread/" + event.ID,
+		Portals:    r.portalLinks(event),
+	}
+
+	article := nostrclient.ParseArticle(event)
+	if article != nil {
+		view.Article = &articleView{
+			Title:       article.Title,
+			Summary:     article.Summary,
+			Image:       article.Image,
+			PublishedAt: article.PublishedAt,
+			Hashtags:    article.Hashtags,
+		}
+		if view.Article.Title == "" {
+			view.Article.Title = "Untitled article"
+		}
+	}
+	view.Body = r.RenderContent(ctx, event)
+
+	if agg != nil && agg.HasInteractions() {
+		detail := r.settings().Display.Detail
+		view.Stats = r.stats(agg, detail.ShowReplies, detail.ShowReactions, detail.ShowZaps)
+		if detail.ShowZaps {
+			view.Zappers = r.zapperViews(ctx, agg.Zappers)
+		}
+	}
+
+	if thread != nil && r.settings().Display.Detail.ShowThread {
+		view.Thread = r.ThreadTree(ctx, thread)
+	}
+
+	return view
+}
+
+// RenderContent renders an event's content as HTML: NIP-19 entities become
+// links to their pages, then the markdown is rendered. Notes keep their line
+// breaks; articles are rendered as written.
+func (r *Renderer) RenderContent(ctx context.Context, event *nostr.Event) template.HTML {
+	content := r.resolver.ReplaceEntities(ctx, event.Content, entities.MarkdownFormatter)
+
+	rendered, err := r.parser.RenderHTML([]byte(content), event.Kind != nostrclient.KindArticle)
+	if err != nil {
+		return template.HTML("<p>" + html.EscapeString(event.Content) + "</p>")
+	}
+	// RenderHTML omits raw HTML and drops dangerous link schemes
+	return template.HTML(rendered)
+}
+
+// threadNode is an event in a thread tree
+type threadNode struct {
+	ID       string
+	Href     string
+	Summary  string
+	Author   string
+	Time     time.Time
+	Root     bool
+	Focus    bool
+	Children []*threadNode
+	Hidden   bool // replies beyond the maximum depth were left out
+}
+
+// threadView is the content of a thread page
+type threadView struct {
+	FocusHref string
+	Root      *threadNode
+}
+
+// ThreadTree builds the tree of a thread, down to the configured depth
+func (r *Renderer) ThreadTree(ctx context.Context, thread *aggregates.ThreadView) *threadNode {
+	if thread == nil || thread.Root == nil {
+		return nil
+	}
+
+	maxDepth := r.settings().Display.Limits.MaxThreadDepth
+	if maxDepth <= 0 {
+		maxDepth = 10
+	}
+	return r.threadNode(ctx, thread.Root, 0, thread.FocusID, maxDepth)
+}
+
+func (r *Renderer) threadNode(ctx context.Context, node *aggregates.ThreadNode, depth int, focusID string, maxDepth int) *threadNode {
+	view := &threadNode{
+		ID:      node.Event.ID,
+		Href:    "/note/" + node.Event.ID,
+		Summary: r.threadSummary(node.Event),
+		Author:  r.resolver.DisplayName(ctx, node.Event.PubKey),
+		Time:    node.Event.CreatedAt.Time(),
+		Root:    depth == 0,
+		Focus:   node.Event.ID == focusID,
+	}
+
+	if len(node.Children) > 0 && depth+1 >= maxDepth {
+		view.Hidden = true
+		return view
+	}
+	for _, child := range node.Children {
+		view.Children = append(view.Children, r.threadNode(ctx, child, depth+1, focusID, maxDepth))
+	}
+	return view
+}
+
+// profileView is the content of a profile page
+type profileView struct {
+	Name      string
+	Pubkey    string
+	Npub      string
+	Username  string
+	About     template.HTML
+	Website   string
+	NIP05     string
+	Lightning string
+	Picture   string
+	Banner    string
+	ZapTotal  string
+	ZapCount  int
+	Zappers   []zapperView
+	Portals   []linkView
+}
+
+// ProfileView builds a profile page with the profile's zaps, if any
+func (r *Renderer) ProfileView(ctx context.Context, profileEvent *nostr.Event, zaps *aggregates.ZapSummary) *profileView {
+	view := &profileView{
+		Name:   truncatePubkey(profileEvent.PubKey),
+		Pubkey: profileEvent.PubKey,
+	}
+	if npub, err := nip19.EncodePublicKey(profileEvent.PubKey); err == nil {
+		view.Npub = npub
+		for _, base := range r.portalBases() {
+			view.Portals = append(view.Portals, linkView{Title: portalName(base), Href: base + "/" + npub})
+		}
+	}
+
+	if profile := nostrclient.ParseProfile(profileEvent); profile != nil {
+		if name := profile.GetDisplayName(); name != "" {
+			view.Name = name
+		}
+		if profile.Name != "" && profile.Name != view.Name {
+			view.Username = profile.Name
+		}
+
+		// Escape the bio, then link its NIP-19 entities
+		about := html.EscapeString(profile.About)
+		about = r.resolver.ReplaceEntities(ctx, about, entities.HTMLFormatter)
+		view.About = template.HTML(about)
+
+		view.Website = safeURL(profile.Website)
+		view.NIP05 = profile.NIP05
+		view.Lightning = profile.GetLightningAddress()
+		view.Picture = safeURL(profile.Picture)
+		view.Banner = safeURL(profile.Banner)
+	}
+
+	if zaps != nil && zaps.Count > 0 {
+		view.ZapTotal = aggregates.FormatSats(zaps.TotalSats)
+		view.ZapCount = zaps.Count
+		view.Zappers = r.zapperViews(ctx, zaps.Top(aggregates.TopZappers))
+	}
+
+	return view
+}
+
+// zapperViews resolves the names of zappers
+func (r *Renderer) zapperViews(ctx context.Context, zappers []*aggregates.Zapper) []zapperView {
+	views := make([]zapperView, 0, len(zappers))
+	for _, zapper := range zappers {
+		views = append(views, zapperView{
+			Name:    r.resolver.DisplayName(ctx, zapper.Pubkey),
+			Href:    "/profile/" + zapper.Pubkey,
+			Sats:    aggregates.FormatSats(zapper.Sats),
+			Count:   zapper.Count,
+			Comment: zapper.Comment,
+		})
+	}
+	return views
+}
+
+// stats summarizes an event's interactions
+func (r *Renderer) stats(agg *aggregates.EventAggregates, showReplies, showReactions, showZaps bool) string {
+	var parts []string
+
+	if showReplies && agg.ReplyCount > 0 {
+		parts = append(parts, plural(agg.ReplyCount, "reply", "replies"))
+	}
+
+	if showReactions && agg.ReactionTotal > 0 {
+		part := plural(agg.ReactionTotal, "reaction", "reactions")
+		if len(agg.ReactionCounts) > 0 {
+			emojis := make([]string, 0, len(agg.ReactionCounts))
+			for emoji := range agg.ReactionCounts {
+				emojis = append(emojis, emoji)
+			}
+			sort.Strings(emojis)
+
+			counts := make([]string, 0, len(emojis))
+			for _, emoji := range emojis {
+				counts = append(counts, fmt.Sprintf("%s %d", emoji, agg.ReactionCounts[emoji]))
+			}
+			part += " (" + strings.Join(counts, ", ") + ")"
+		}
+		parts = append(parts, part)
+	}
+
+	if showZaps && agg.ZapSatsTotal > 0 {
+		parts = append(parts, fmt.Sprintf("%s zapped", aggregates.FormatSats(agg.ZapSatsTotal)))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// eventTitle returns the text of an event's link in lists
+func (r *Renderer) eventTitle(event *nostr.Event) string {
+	// Collapse sensitive content to its warning; the note page reveals it
+	if label, ok := nostrclient.ContentWarningLabel(event); ok {
+		return label
+	}
+
+	if article := nostrclient.ParseArticle(event); article != nil && article.Title != "" {
+		return article.Title
+	}
+
+	content := strings.TrimSpace(event.Content)
+	if content == "" {
+		if len(event.ID) > 8 {
+			return fmt.Sprintf("Event %s...", event.ID[:8])
+		}
+		return fmt.Sprintf("Event %s", event.ID)
+	}
+
+	firstLine := strings.Split(content, "\n")[0]
+	if len(firstLine) > 120 {
+		return firstLine[:117] + "..."
+	}
+	return firstLine
+}
+
+// threadSummary returns one line of an event's content for thread trees, or
+// its content warning in place of sensitive content
+func (r *Renderer) threadSummary(event *nostr.Event) string {
+	if label, ok := nostrclient.ContentWarningLabel(event); ok {
+		return label
+	}
+
+	limit := r.settings().Display.Limits.SummaryLength
+	if limit <= 0 {
+		limit = 100
+	}
+
+	plain := strings.TrimSpace(strings.ReplaceAll(event.Content, "\n", " "))
+	if len(plain) <= limit {
+		return plain
+	}
+
+	indicator := r.settings().Display.Limits.TruncateIndicator
+	if indicator == "" {
+		indicator = "..."
+	}
+	return plain[:limit-len(indicator)] + indicator
+}
+
+// portalLinks links an event on the configured web portals
+func (r *Renderer) portalLinks(event *nostr.Event) []linkView {
+	code, ok := r.nostrPointer(event)
+	if !ok {
+		return nil
+	}
+
+	portals := r.portalBases()
+	links := make([]linkView, 0, len(portals))
+	for _, base := range portals {
+		links = append(links, linkView{Title: portalName(base), Href: base + "/" + code})
+	}
+	return links
+}
+
+// nostrPointer encodes an event as an naddr (articles) or nevent
+func (r *Renderer) nostrPointer(event *nostr.Event) (string, bool) {
+	relays, _ := r.storage.GetReadRelays(context.Background(), event.PubKey)
+
+	if article := nostrclient.ParseArticle(event); article != nil && article.Identifier != "" {
+		if code, err := nip19.EncodeEntity(event.PubKey, event.Kind, article.Identifier, relays); err == nil {
+			return code, true
+		}
+	}
+
+	if code, err := nip19.EncodeEvent(event.ID, relays, event.PubKey); err == nil {
+		return code, true
+	}
+
+	return "", false
+}
+
+func (r *Renderer) portalBases() []string {
+	if len(r.settings().Rendering.Portals) > 0 {
+		return r.settings().Rendering.Portals
+	}
+	return []string{"https://njump.me", "https://nostr.at", "https://nostr.eu"}
+}
+
+// portalName returns the host of a portal base URL
+func portalName(base string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(base, "https://"), "http://")
+	return strings.TrimSuffix(name, "/")
+}
+
+// safeURL returns a profile URL if it is http(s), "" otherwise
+func safeURL(u string) string {
+	if strings.HasPrefix(u, "https://") || strings.HasPrefix(u, "http://") {
+		return u
+	}
+	return ""
+}
+
+// plural formats a count with the singular or plural noun
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return fmt.Sprintf("%d %s", n, pluralForm)
+}
+
+// truncatePubkey truncates a pubkey for display
+func truncatePubkey(pubkey string) string {
+	if len(pubkey) <= 16 {
+		return pubkey
+	}
+	return pubkey[:8] + "..." + pubkey[len(pubkey)-8:]
+}
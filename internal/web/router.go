@@ -0,0 +1,447 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/aggregates"
+	"github.com/sandwichfarm/nophr/internal/sections"
+)
+
+// response is a rendered page
+type response struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+func htmlResponse(status int, body []byte) *response {
+	return &response{status: status, contentType: "text/html; charset=utf-8", body: body}
+}
+
+func textResponse(status int, body string) *response {
+	return &response{status: status, contentType: "text/plain; charset=utf-8", body: []byte(body)}
+}
+
+// listLimit is the number of events on the notes, articles, replies,
+// mentions and search pages
+const listLimit = 50
+
+// Router handles URL routing for HTTP requests
+type Router struct {
+	server   *Server
+	renderer *Renderer
+}
+
+// NewRouter creates a new router
+func NewRouter(server *Server) *Router {
+	return &Router{
+		server:   server,
+		renderer: NewRenderer(server.fullConfig, server.storage),
+	}
+}
+
+// Route routes a URL to the appropriate handler
+func (r *Router) Route(ctx context.Context, u *url.URL) *response {
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	// Check if sections are registered for this path (sections override defaults)
+	base, page := splitPagePath(path)
+	sectionsList := r.server.GetSectionManager().GetSectionsByPath(base)
+	if len(sectionsList) == 1 || (len(sectionsList) > 1 && page == 1) {
+		return r.handleSections(ctx, sectionsList, base, page)
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) == 0 || parts[0] == "" {
+		return r.handleHome()
+	}
+
+	switch parts[0] {
+	case "notes":
+		if len(parts) >= 2 && parts[1] != "" {
+			return r.handleNote(ctx, parts[1])
+		}
+		return r.handleList(ctx, "notes", "Notes", r.server.GetQueryHelper().GetNotes)
+
+	case "articles":
+		return r.handleList(ctx, "articles", "Articles", r.server.GetQueryHelper().GetArticles)
+
+	case "replies":
+		return r.handleList(ctx, "replies", "Replies", r.server.GetQueryHelper().GetReplies)
+
+	case "mentions":
+		return r.handleList(ctx, "mentions", "Mentions", r.server.GetQueryHelper().GetMentions)
+
+	case "note":
+		if len(parts) >= 2 {
+			return r.handleNote(ctx, parts[1])
+		}
+		return r.renderer.Error(http.StatusNotFound, "Missing note ID")
+
+	case "thread":
+		if len(parts) >= 2 {
+			return r.handleThread(ctx, parts[1])
+		}
+		return r.renderer.Error(http.StatusNotFound, "Missing thread ID")
+
+	case "profile":
+		if len(parts) >= 2 {
+			return r.handleProfile(ctx, parts[1])
+		}
+		return r.renderer.Error(http.StatusNotFound, "Missing pubkey")
+
+	case "search":
+		return r.handleSearch(ctx, u.Query().Get("q"))
+
+	case "feeds":
+		if len(parts) == 2 && strings.HasSuffix(parts[1], ".atom") {
+			return r.handleSectionFeed(ctx, strings.TrimSuffix(parts[1], ".atom"))
+		}
+	}
+
+	return r.renderer.Error(http.StatusNotFound, fmt.Sprintf("Unknown path: %s", path))
+}
+
+// handleHome renders the home page with links to the lists and section feeds
+func (r *Router) handleHome() *response {
+	feeds := r.sectionFeeds(r.server.GetSectionManager().PublicSections())
+	content := struct {
+		Description string
+		Feeds       []linkView
+	}{r.server.settings().Site.Description, feeds}
+
+	return r.renderer.Page("home", pageOptions{nav: "home", page: "home", feeds: feeds}, content)
+}
+
+// handleList renders one of the notes, articles, replies or mentions lists
+func (r *Router) handleList(ctx context.Context, name, title string, query func(context.Context, int) ([]*aggregates.EnrichedEvent, error)) *response {
+	events, err := query(ctx, listLimit)
+	if err != nil {
+		return r.renderer.Error(http.StatusInternalServerError, fmt.Sprintf("Error loading %s: %v", name, err))
+	}
+
+	content := struct {
+		Heading string
+		Items   []eventItem
+	}{title, r.renderer.EventItems(ctx, events)}
+
+	return r.renderer.Page("list", pageOptions{title: title, nav: name, page: name}, content)
+}
+
+// handleNote renders a note or article with its interactions and thread
+func (r *Router) handleNote(ctx context.Context, noteID string) *response {
+	events, err := r.server.queryHelper.QueryEvents(ctx, nostr.Filter{
+		IDs: []string{noteID},
+	})
+	if err != nil || len(events) == 0 {
+		return r.renderer.Error(http.StatusNotFound, fmt.Sprintf("Note not found: %s", noteID))
+	}
+
+	note := events[0]
+
+	// Get aggregates from storage
+	aggData, err := r.server.GetStorage().GetAggregate(ctx, noteID)
+	var agg *aggregates.EventAggregates
+	if err == nil && aggData != nil {
+		agg = &aggregates.EventAggregates{
+			EventID:         aggData.EventID,
+			ReplyCount:      aggData.ReplyCount,
+			ReactionTotal:   aggData.ReactionTotal,
+			ReactionCounts:  aggData.ReactionCounts,
+			ZapSatsTotal:    aggData.ZapSatsTotal,
+			LastInteraction: aggData.LastInteractionAt,
+		}
+
+		// Top zappers with their comments
+		if summary, err := r.server.GetQueryHelper().GetZapSummary(ctx, noteID); err == nil {
+			agg.Zappers = summary.Top(aggregates.TopZappers)
+		}
+	}
+
+	threadView, err := r.server.GetQueryHelper().GetThreadByEvent(ctx, noteID)
+	if err != nil {
+		threadView = nil
+	}
+
+	view := r.renderer.NoteView(ctx, note, agg, threadView)
+	title := view.Item.Title
+	if view.Article != nil {
+		title = view.Article.Title
+	}
+
+	return r.renderer.Page("note", pageOptions{title: title}, view)
+}
+
+// handleThread renders the thread around an event
+func (r *Router) handleThread(ctx context.Context, eventID string) *response {
+	thread, err := r.server.GetQueryHelper().GetThreadByEvent(ctx, eventID)
+	if err != nil || thread == nil || thread.Root == nil {
+		return r.renderer.Error(http.StatusNotFound, fmt.Sprintf("Thread not found: %s", eventID))
+	}
+
+	view := &threadView{
+		FocusHref: "/note/" + thread.FocusID,
+		Root:      r.renderer.ThreadTree(ctx, thread),
+	}
+	return r.renderer.Page("thread", pageOptions{title: "Thread"}, view)
+}
+
+// handleProfile renders a profile (kind 0)
+func (r *Router) handleProfile(ctx context.Context, pubkey string) *response {
+	events, err := r.server.queryHelper.QueryEvents(ctx, nostr.Filter{
+		Kinds:   []int{0},
+		Authors: []string{pubkey},
+		Limit:   1,
+	})
+	if err != nil || len(events) == 0 {
+		return r.renderer.Error(http.StatusNotFound, fmt.Sprintf("Profile not found: %s", pubkey))
+	}
+
+	// Profile zaps (errors only hide the zaps section)
+	zaps, _ := r.server.GetQueryHelper().GetProfileZapSummary(ctx, pubkey)
+
+	view := r.renderer.ProfileView(ctx, events[0], zaps)
+	return r.renderer.Page("profile", pageOptions{title: view.Name}, view)
+}
+
+// handleSearch renders the search form and, given a query, its NIP-50 results
+func (r *Router) handleSearch(ctx context.Context, query string) *response {
+	content := struct {
+		Query   string
+		Error   string
+		Results []eventItem
+	}{Query: query}
+
+	title := "Search"
+	if query != "" {
+		title = fmt.Sprintf("Search: %s", query)
+
+		events, err := r.server.GetStorage().QueryEventsWithSearch(ctx, nostr.Filter{
+			Search: query,
+			Kinds:  []int{0, 1, 30023}, // Profiles, notes, articles
+			Limit:  listLimit,
+		})
+		if err != nil {
+			content.Error = err.Error()
+		}
+
+		for _, event := range r.server.events.Filter(events) {
+			if event.Kind == 0 {
+				content.Results = append(content.Results, eventItem{
+					Href:  "/profile/" + event.PubKey,
+					Title: r.renderer.resolver.DisplayName(ctx, event.PubKey),
+					Kind:  "Profile",
+				})
+				continue
+			}
+			content.Results = append(content.Results, r.renderer.EventItem(ctx, event, nil))
+		}
+	}
+
+	return r.renderer.Page("search", pageOptions{title: title, nav: "search"}, content)
+}
+
+// sectionView is a section on a sections page
+type sectionView struct {
+	Title       string
+	Description string
+	Feed        string
+	Items       []eventItem
+	Groups      []groupView
+	Error       string
+	Prev        string
+	Next        string
+	Page        int
+	More        *linkView
+}
+
+// groupView is a group of a section's events
+type groupView struct {
+	Title string
+	Items []eventItem
+}
+
+// handleSections renders the sections registered on a path (e.g., a homepage
+// with multiple filtered views). A path with a single section is paginated
+// with /page/N.
+func (r *Router) handleSections(ctx context.Context, sectionsList []*sections.Section, path string, page int) *response {
+	manager := r.server.GetSectionManager()
+	views := make([]*sectionView, 0, len(sectionsList))
+
+	for _, section := range sectionsList {
+		view := &sectionView{
+			Title:       sectionLabel(section),
+			Description: section.Description,
+			Feed:        sectionFeedPath(section),
+		}
+		views = append(views, view)
+
+		sectionPage, err := manager.GetPage(ctx, section.Name, page)
+		if err != nil {
+			view.Error = err.Error()
+			continue
+		}
+
+		// Events under group headings if configured
+		if len(sectionPage.Groups) > 0 {
+			for _, group := range sectionPage.Groups {
+				view.Groups = append(view.Groups, groupView{
+					Title: r.groupTitle(ctx, section, group),
+					Items: r.sectionItems(ctx, section, group.Events),
+				})
+			}
+		} else {
+			view.Items = r.sectionItems(ctx, section, sectionPage.Events)
+		}
+
+		if len(sectionsList) == 1 {
+			view.Page = sectionPage.PageNumber
+			if sectionPage.HasPrev {
+				view.Prev = sectionPagePath(path, sectionPage.PageNumber-1)
+			}
+			if sectionPage.HasNext {
+				view.Next = sectionPagePath(path, sectionPage.PageNumber+1)
+			}
+		}
+
+		// Add "more" link if configured
+		if section.MoreLink != nil {
+			target, err := manager.GetSection(section.MoreLink.SectionRef)
+			if err == nil && target.Path != "" {
+				view.More = &linkView{Title: section.MoreLink.Text, Href: target.Path}
+			}
+		}
+	}
+
+	opts := pageOptions{feeds: r.sectionFeeds(sectionsList)}
+	content := struct {
+		Heading  string
+		Single   bool
+		Sections []*sectionView
+	}{r.server.settings().Site.Title, len(views) == 1, views}
+
+	if len(sectionsList) == 1 {
+		content.Heading = sectionLabel(sectionsList[0])
+		opts.title = content.Heading
+		opts.page = sectionsList[0].Name
+		if page > 1 {
+			opts.title = fmt.Sprintf("%s - Page %d", content.Heading, page)
+		}
+	}
+	if path == "/" {
+		opts.nav = "home"
+		opts.page = "home"
+	}
+
+	return r.renderer.Page("sections", opts, content)
+}
+
+// sectionItems builds a section's list entries, without the authors or dates
+// the section does not show
+func (r *Router) sectionItems(ctx context.Context, section *sections.Section, events []*nostr.Event) []eventItem {
+	items := r.renderer.PlainEventItems(ctx, events)
+	for i := range items {
+		if !section.ShowAuthors {
+			items[i].Author = ""
+		}
+		if !section.ShowDates {
+			items[i].Time = time.Time{}
+		}
+	}
+	return items
+}
+
+// groupTitle returns the heading of an event group: the group's date or
+// kind name, or the author's profile name
+func (r *Router) groupTitle(ctx context.Context, section *sections.Section, group *sections.EventGroup) string {
+	title := group.Title
+	if section.GroupBy == sections.GroupByAuthor {
+		title = r.renderer.resolver.DisplayName(ctx, group.Key)
+	}
+	if group.Continued {
+		title += " (continued)"
+	}
+	return title
+}
+
+// sectionFeeds returns the Atom feed links of sections
+func (r *Router) sectionFeeds(sectionsList []*sections.Section) []linkView {
+	feeds := make([]linkView, 0, len(sectionsList))
+	for _, section := range sectionsList {
+		feeds = append(feeds, linkView{Title: sectionLabel(section), Href: sectionFeedPath(section)})
+	}
+	return feeds
+}
+
+// baseURL returns the public base URL of the server, without a trailing slash
+func (r *Router) baseURL() string {
+	cfg := r.server.GetConfig()
+	if cfg.URL != "" {
+		return strings.TrimSuffix(cfg.URL, "/")
+	}
+
+	host := cfg.Host
+	if host == "" {
+		host = "localhost"
+	}
+	if cfg.Port == 80 {
+		return "http://" + host
+	}
+	return fmt.Sprintf("http://%s:%d", host, cfg.Port)
+}
+
+// absoluteURL returns the public URL of a path
+func (r *Router) absoluteURL(path string) string {
+	return r.baseURL() + path
+}
+
+// sectionFeedPath returns the path of a section's Atom feed
+func sectionFeedPath(section *sections.Section) string {
+	return "/feeds/" + url.PathEscape(section.Name) + ".atom"
+}
+
+// sectionLabel returns a section's title, falling back to its name
+func sectionLabel(section *sections.Section) string {
+	if section.Title != "" {
+		return section.Title
+	}
+	return section.Name
+}
+
+// splitPagePath splits a trailing /page/N off a path, returning the base
+// path and page number (1 when there is none)
+func splitPagePath(path string) (string, int) {
+	idx := strings.LastIndex(path, "/page/")
+	if idx < 0 {
+		return path, 1
+	}
+
+	page, err := strconv.Atoi(path[idx+len("/page/"):])
+	if err != nil || page < 1 {
+		return path, 1
+	}
+
+	base := path[:idx]
+	if base == "" {
+		base = "/"
+	}
+	return base, page
+}
+
+// sectionPagePath returns the path of a page of a section path
+func sectionPagePath(path string, page int) string {
+	if page <= 1 {
+		return path
+	}
+	return fmt.Sprintf("%s/page/%d", strings.TrimSuffix(path, "/"), page)
+}
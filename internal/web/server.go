@@ -0,0 +1,306 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sandwichfarm/nophr/internal/aggregates"
+	"github.com/sandwichfarm/nophr/internal/cache"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/ops"
+	"github.com/sandwichfarm/nophr/internal/sections"
+	"github.com/sandwichfarm/nophr/internal/security"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+// Server serves the gateway's routes as HTML pages, with an Atom feed per
+// section
+type Server struct {
+	config         *config.HTTPProtocol
+	reloadMu       sync.RWMutex // held for reading while a request is served
+	mu             sync.RWMutex
+	fullConfig     *config.Config
+	storage        *storage.Storage
+	router         *Router
+	queryHelper    *aggregates.QueryHelper
+	sectionManager *sections.Manager
+	guard          *security.Guard
+	events         *aggregates.EventFilter
+	cache          cache.Cache // nil = rendered responses are not cached
+	cacheTTLs      *cache.RenderTTLs
+	metrics        *ops.Metrics // nil = requests are not counted
+
+	httpServer *http.Server
+	listener   net.Listener
+	wg         sync.WaitGroup
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+// New creates a new HTTP server
+func New(cfg *config.HTTPProtocol, fullCfg *config.Config, st *storage.Storage, aggMgr *aggregates.Manager) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &Server{
+		config:      cfg,
+		fullConfig:  fullCfg,
+		storage:     st,
+		ctx:         ctx,
+		cancel:      cancel,
+		queryHelper: aggregates.NewQueryHelper(st, fullCfg, aggMgr),
+		guard:       security.NewGuard(&fullCfg.Security),
+	}
+
+	// Hide events from denied pubkeys, with banned content or muted by the
+	// owner everywhere
+	s.events = aggregates.NewEventFilter(s.guard, aggregates.NewMuteFilter(st, fullCfg))
+	s.queryHelper.SetEventFilter(s.events.Filter)
+
+	// Initialize sections manager (opt-in for custom filtered views)
+	s.sectionManager = sections.NewManager(st, fullCfg.Identity.Npub)
+	s.sectionManager.SetEventFilter(s.queryHelper.FilterFeedEvents)
+
+	s.router = NewRouter(s)
+
+	return s
+}
+
+// Start starts the HTTP server
+func (s *Server) Start() error {
+	// Use Bind field for listening, fallback to Host if Bind not set
+	bindAddr := s.config.Bind
+	if bindAddr == "" {
+		bindAddr = s.config.Host
+	}
+	addr := fmt.Sprintf("%s:%d", bindAddr, s.config.Port)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to start HTTP server: %w", err)
+	}
+
+	s.listener = listener
+	s.httpServer = &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+	fmt.Printf("HTTP server listening on %s\n", addr)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("HTTP server error: %v\n", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the HTTP server
+func (s *Server) Stop() error {
+	s.cancel()
+
+	if s.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.httpServer.Shutdown(ctx); err != nil {
+			s.httpServer.Close()
+		}
+	}
+
+	s.wg.Wait()
+	s.guard.Close()
+	return nil
+}
+
+// ServeHTTP applies the security guard, then serves a rendered page
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+
+	clientIP := remoteIP(req.RemoteAddr)
+	if err := s.guard.AcquireConnection(clientIP); err != nil {
+		fmt.Printf("HTTP request refused for %s: %v\n", clientIP, err)
+		status := http.StatusTooManyRequests
+		if err == security.ErrTooManyConnections {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(connectionRetrySeconds))
+		http.Error(w, "Too many connections, try again later", status)
+		s.metrics.ObserveRequest(security.ProtocolHTTP, strconv.Itoa(status), time.Since(start))
+		return
+	}
+	defer s.guard.ReleaseConnection(clientIP)
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		s.metrics.ObserveRequest(security.ProtocolHTTP, strconv.Itoa(http.StatusMethodNotAllowed), time.Since(start))
+		return
+	}
+
+	if ok, retryAfter := s.guard.AllowRequest(security.ProtocolHTTP, clientIP); !ok {
+		fmt.Printf("HTTP request rate limited for %s\n", clientIP)
+		w.Header().Set("Retry-After", strconv.Itoa(security.RetrySeconds(retryAfter)))
+		http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+		s.metrics.ObserveRequest(security.ProtocolHTTP, strconv.Itoa(http.StatusTooManyRequests), time.Since(start))
+		return
+	}
+
+	resp := s.route(req)
+	s.metrics.ObserveRequest(security.ProtocolHTTP, strconv.Itoa(resp.status), time.Since(start))
+
+	w.Header().Set("Content-Type", resp.contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+}
+
+// route renders a request, serving and storing it in the response cache when
+// one is configured
+func (s *Server) route(req *http.Request) *response {
+	s.reloadMu.RLock()
+	defer s.reloadMu.RUnlock()
+
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	if s.cache == nil {
+		return s.router.Route(s.ctx, req.URL)
+	}
+
+	key := cache.HTTPKey(path, req.URL.RawQuery)
+
+	if data, hit, err := s.cache.Get(s.ctx, key); err == nil && hit {
+		if resp, ok := decodeCached(data); ok {
+			return resp
+		}
+	}
+
+	resp := s.router.Route(s.ctx, req.URL)
+
+	// Only successful responses are cached; failures may be transient
+	if ttl := s.cacheTTLs.HTTP(path); ttl > 0 && resp.status == http.StatusOK {
+		if err := s.cache.Set(s.ctx, key, encodeCached(resp), ttl); err != nil {
+			fmt.Printf("Cache set error: %v\n", err)
+		}
+	}
+
+	return resp
+}
+
+// encodeCached stores a response as its content type, a newline and the body
+func encodeCached(resp *response) []byte {
+	data := make([]byte, 0, len(resp.contentType)+1+len(resp.body))
+	data = append(data, resp.contentType...)
+	data = append(data, '\n')
+	return append(data, resp.body...)
+}
+
+// decodeCached reverses encodeCached
+func decodeCached(data []byte) (*response, bool) {
+	contentType, body, ok := bytes.Cut(data, []byte("\n"))
+	if !ok {
+		return nil, false
+	}
+	return &response{status: http.StatusOK, contentType: string(contentType), body: body}, true
+}
+
+// connectionRetrySeconds is the Retry-After sent when a client IP holds too
+// many concurrent requests
+const connectionRetrySeconds = 5
+
+// remoteIP extracts the IP address of an http.Request's RemoteAddr
+func remoteIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// GetStorage returns the storage instance
+func (s *Server) GetStorage() *storage.Storage {
+	return s.storage
+}
+
+// GetConfig returns the config
+func (s *Server) GetConfig() *config.HTTPProtocol {
+	return s.config
+}
+
+// GetQueryHelper returns the query helper instance
+func (s *Server) GetQueryHelper() *aggregates.QueryHelper {
+	return s.queryHelper
+}
+
+// SetCache enables response caching with TTLs from the caching config
+func (s *Server) SetCache(c cache.Cache) {
+	s.cache = c
+	s.cacheTTLs = cache.NewRenderTTLs(&s.settings().Caching)
+}
+
+// SetMetrics enables request counters and latency histograms
+func (s *Server) SetMetrics(m *ops.Metrics) {
+	s.metrics = m
+}
+
+// GetCache returns the response cache, or nil if caching is disabled
+func (s *Server) GetCache() cache.Cache {
+	return s.cache
+}
+
+// SetGuard replaces the server's own guard with one shared by every
+// server, so connection caps count connections to all of them together
+func (s *Server) SetGuard(g *security.Guard) {
+	s.guard.Close()
+	s.guard = g
+	s.events.SetGuard(g)
+}
+
+// GetSectionManager returns the section manager instance
+func (s *Server) GetSectionManager() *sections.Manager {
+	return s.sectionManager
+}
+
+// PrepareReload converts the sections of a new configuration and returns a
+// function that switches the server to it: sections, display, presentation
+// and rendering settings. Listener and security settings keep their startup
+// values. The switch cannot fail and waits for requests in flight, so every
+// request is served entirely with the old settings or the new ones.
+func (s *Server) PrepareReload(cfg *config.Config) (func(), error) {
+	list, err := sections.FromConfig(cfg.Sections)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		s.reloadMu.Lock()
+		defer s.reloadMu.Unlock()
+
+		s.sectionManager.ReplaceSections(list)
+		s.router.renderer.SetConfig(cfg)
+		s.queryHelper.SetConfig(cfg)
+
+		s.mu.Lock()
+		s.fullConfig = cfg
+		s.mu.Unlock()
+	}, nil
+}
+
+// settings returns the configuration the server currently follows
+func (s *Server) settings() *config.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.fullConfig
+}
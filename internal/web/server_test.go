@@ -0,0 +1,322 @@
+package web
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/sandwichfarm/nophr/internal/aggregates"
+	"github.com/sandwichfarm/nophr/internal/cache"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/sections"
+	"github.com/sandwichfarm/nophr/internal/storage"
+)
+
+// testServer creates a server over a fresh database with the owner's profile,
+// a note mentioning the owner, a content-warned note and an article
+func testServer(t *testing.T, configure func(cfg *config.Config)) (*Server, map[string]*nostr.Event) {
+	t.Helper()
+
+	owner := nostr.GeneratePrivateKey()
+	ownerPub, _ := nostr.GetPublicKey(owner)
+	npub, _ := nip19.EncodePublicKey(ownerPub)
+
+	cfg := config.Default()
+	cfg.Identity.Npub = npub
+	cfg.Site.Title = "Test Site"
+	cfg.Storage = config.Storage{Driver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "test.db")}
+	cfg.Protocols.HTTP = config.HTTPProtocol{Enabled: true, Host: "localhost", Port: 8080, URL: "https://example.com/"}
+	if configure != nil {
+		configure(cfg)
+	}
+
+	ctx := context.Background()
+	st, err := storage.New(ctx, &cfg.Storage)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	events := make(map[string]*nostr.Event)
+	store := func(name string, kind int, tags nostr.Tags, content string) {
+		event := &nostr.Event{Kind: kind, CreatedAt: nostr.Now(), Tags: tags, Content: content}
+		if err := event.Sign(owner); err != nil {
+			t.Fatalf("Failed to sign event: %v", err)
+		}
+		if err := st.StoreEvent(ctx, event); err != nil {
+			t.Fatalf("Failed to store event: %v", err)
+		}
+		events[name] = event
+	}
+
+	store("profile", 0, nostr.Tags{}, `{"name":"alice","about":"Hi <b>there</b>"}`)
+	store("note", 1, nostr.Tags{}, "Hello **web**\nsecond line <script>alert(1)</script> nostr:"+npub)
+	store("sensitive", 1, nostr.Tags{{"content-warning", "spoilers"}}, "the butler did it")
+	store("article", 30023, nostr.Tags{
+		{"d", "first"},
+		{"title", "First Article"},
+		{"summary", "An introduction"},
+		{"image", "https://example.com/cover.png"},
+	}, "## Chapter one\n\nIt was a dark night.")
+
+	server := New(&cfg.Protocols.HTTP, cfg, st, aggregates.NewManager(st, cfg))
+	t.Cleanup(func() { server.Stop() })
+
+	if err := server.GetSectionManager().RegisterSection(&sections.Section{
+		Name:        "journal",
+		Path:        "/journal",
+		Title:       "Journal",
+		Description: "Everything I post",
+		ShowDates:   true,
+		Filters:     sections.FilterSet{Kinds: []int{1, 30023}, Authors: []string{ownerPub}},
+		SortBy:      sections.SortByCreatedAt,
+		SortOrder:   sections.SortDesc,
+	}); err != nil {
+		t.Fatalf("Failed to register section: %v", err)
+	}
+
+	return server, events
+}
+
+// get serves a GET request
+func get(server *Server, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	server.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHTTPRoutes(t *testing.T) {
+	server, events := testServer(t, nil)
+	note, sensitive, article := events["note"], events["sensitive"], events["article"]
+
+	tests := []struct {
+		name     string
+		path     string
+		status   int
+		contains []string
+		excludes []string
+	}{
+		{"home", "/", http.StatusOK, []string{
+			`<html lang="en">`,
+			`<a class="skip" href="#main">Skip to content</a>`,
+			`<a href="/" aria-current="page">Home</a>`,
+			`<link rel="alternate" type="application/atom+xml" title="Journal" href="/feeds/journal.atom">`,
+		}, nil},
+		{"notes", "/notes", http.StatusOK, []string{
+			`<a href="/notes" aria-current="page">Notes</a>`,
+			`<a href="/note/` + note.ID + `">Hello **web**</a>`,
+			`⚠ CW: spoilers</a>`,
+		}, []string{"the butler did it"}},
+		{"articles", "/articles", http.StatusOK, []string{"First Article", "Article by"}, nil},
+		{"note", "/note/" + note.ID, http.StatusOK, []string{
+			"<strong>web</strong><br>",
+			`<a href="/profile/` + note.PubKey + `">alice</a>`,
+			"<!-- raw HTML omitted -->alert(1)",
+			`<a href="/thread/` + note.ID + `">View thread</a>`,
+			"https://njump.me/nevent1",
+		}, []string{"<script>alert"}},
+		{"sensitive note", "/note/" + sensitive.ID, http.StatusOK, []string{
+			`<p class="warning" role="note">⚠ CW: spoilers</p>`,
+			"the butler did it",
+		}, nil},
+		{"article", "/note/" + article.ID, http.StatusOK, []string{
+			"<title>First Article - Test Site</title>",
+			"<h1>First Article</h1>",
+			`<img src="https://example.com/cover.png" alt="">`,
+			"<p><em>An introduction</em></p>",
+			`<h2 id="chapter-one">Chapter one</h2>`,
+			"https://njump.me/naddr1",
+		}, nil},
+		{"thread", "/thread/" + note.ID, http.StatusOK, []string{`<li aria-current="true">`, "[root]"}, nil},
+		{"profile", "/profile/" + note.PubKey, http.StatusOK, []string{"<h1>alice</h1>", "Hi &lt;b&gt;there&lt;/b&gt;"}, nil},
+		{"search form", "/search", http.StatusOK, []string{`<form action="/search" method="get" role="search">`}, nil},
+		{"section", "/journal", http.StatusOK, []string{
+			"<h1>Journal</h1>",
+			"<p>Everything I post</p>",
+			`<a href="/feeds/journal.atom" type="application/atom+xml">Atom feed</a>`,
+		}, nil},
+		{"missing note", "/note/" + strings.Repeat("0", 64), http.StatusNotFound, []string{"Note not found"}, nil},
+		{"unknown path", "/nowhere", http.StatusNotFound, []string{"Unknown path: /nowhere"}, nil},
+		{"unknown feed", "/feeds/nothing.atom", http.StatusNotFound, []string{"Unknown section: nothing"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(server, tt.path)
+			if rec.Code != tt.status {
+				t.Errorf("GET %s: status %d, want %d", tt.path, rec.Code, tt.status)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+				t.Errorf("GET %s: Content-Type %q", tt.path, ct)
+			}
+			body := rec.Body.String()
+			for _, want := range tt.contains {
+				if !strings.Contains(body, want) {
+					t.Errorf("GET %s: expected %q in body:\n%s", tt.path, want, body)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(body, unwanted) {
+					t.Errorf("GET %s: unexpected %q in body", tt.path, unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPSectionFeed(t *testing.T) {
+	server, events := testServer(t, nil)
+
+	rec := get(server, "/feeds/journal.atom")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/atom+xml; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	var feed struct {
+		Title   string `xml:"title"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Title   string `xml:"title"`
+			Summary string `xml:"summary"`
+			Content string `xml:"content"`
+			Link    struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("invalid Atom feed: %v", err)
+	}
+	if feed.Title != "Test Site - Journal" {
+		t.Errorf("feed title = %q", feed.Title)
+	}
+	if len(feed.Entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(feed.Entries))
+	}
+
+	for _, entry := range feed.Entries {
+		if !strings.HasPrefix(entry.ID, "nostr:note1") || !strings.HasPrefix(entry.Link.Href, "https://example.com/note/") {
+			t.Errorf("entry %q has id %q and link %q", entry.Title, entry.ID, entry.Link.Href)
+		}
+		switch entry.Title {
+		case "CW: spoilers":
+			if entry.Content != "" || entry.Summary != "CW: spoilers" {
+				t.Errorf("sensitive entry should only carry its warning, got content %q", entry.Content)
+			}
+		case "First Article":
+			if entry.Summary != "An introduction" || !strings.Contains(entry.Content, "<h2") {
+				t.Errorf("article entry summary %q, content %q", entry.Summary, entry.Content)
+			}
+		}
+	}
+	if !strings.Contains(rec.Body.String(), events["article"].PubKey) {
+		t.Error("entry content should link resolved entities")
+	}
+}
+
+func TestHTTPCachingAndLimits(t *testing.T) {
+	server, _ := testServer(t, func(cfg *config.Config) {
+		cfg.Security.RateLimits.HTTP = 3
+	})
+	c := cache.NewMemoryCache(cache.DefaultConfig())
+	defer c.Close()
+	server.SetCache(c)
+
+	ctx := context.Background()
+	if rec := get(server, "/notes"); rec.Code != http.StatusOK {
+		t.Fatalf("GET /notes: status %d", rec.Code)
+	}
+	if hit, _ := c.Has(ctx, cache.HTTPKey("/notes", "")); !hit {
+		t.Error("successful pages should be cached")
+	}
+	if rec := get(server, "/notes"); rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("cached GET /notes: status %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	if rec := get(server, "/nowhere"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /nowhere: status %d", rec.Code)
+	}
+	if hit, _ := c.Has(ctx, cache.HTTPKey("/nowhere", "")); hit {
+		t.Error("error pages should not be cached")
+	}
+
+	rec := get(server, "/notes")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 429 with Retry-After after the limit, got %d", rec.Code)
+	}
+
+	post := httptest.NewRecorder()
+	server.ServeHTTP(post, httptest.NewRequest(http.MethodPost, "/notes", nil))
+	if post.Code != http.StatusMethodNotAllowed || post.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("POST: status %d, Allow %q", post.Code, post.Header().Get("Allow"))
+	}
+}
+
+func TestHTTPServerStartStop(t *testing.T) {
+	server, _ := testServer(t, func(cfg *config.Config) {
+		cfg.Protocols.HTTP.Bind = "127.0.0.1"
+		cfg.Protocols.HTTP.Port = 18080
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get("http://127.0.0.1:18080/")
+	if err != nil {
+		t.Fatalf("GET / error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /: status %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPReload(t *testing.T) {
+	server, _ := testServer(t, nil)
+
+	next := *server.settings()
+	next.Site.Title = "Renamed Site"
+	next.Sections = []config.SectionConfig{
+		{Name: "diary", Path: "/diary", Title: "Diary", Filters: config.SectionFilterConfig{Kinds: []int{1}}},
+	}
+	apply, err := server.PrepareReload(&next)
+	if err != nil {
+		t.Fatalf("PrepareReload() error = %v", err)
+	}
+	if rec := get(server, "/diary"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /diary: status %d, nothing should change before the reload is applied", rec.Code)
+	}
+	apply()
+
+	if rec := get(server, "/diary"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<h1>Diary</h1>") {
+		t.Errorf("GET /diary: status %d, expected the reloaded section", rec.Code)
+	}
+	if rec := get(server, "/journal"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /journal: status %d, sections missing from the new config should be gone", rec.Code)
+	}
+	if body := get(server, "/notes").Body.String(); !strings.Contains(body, "Renamed Site") {
+		t.Error("pages should be rendered with the reloaded site title")
+	}
+
+	broken := next
+	broken.Sections = []config.SectionConfig{{Name: "broken", Filters: config.SectionFilterConfig{Since: "someday"}}}
+	if _, err := server.PrepareReload(&broken); err == nil {
+		t.Fatal("expected an invalid section to fail the reload")
+	}
+	if rec := get(server, "/diary"); rec.Code != http.StatusOK {
+		t.Errorf("GET /diary: status %d, a failed reload should keep the previous sections", rec.Code)
+	}
+}
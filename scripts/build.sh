@@ -15,7 +15,7 @@ echo "  Date:    $DATE"
 echo "  Built by: $BUILT_BY"
 echo ""
 
-go build \
+go build -tags sqlite_fts5 \
     -ldflags "-X main.version=$VERSION -X main.commit=$COMMIT -X main.date=$DATE -X main.builtBy=$BUILT_BY" \
     -o nophr \
     ./cmd/nophr
@@ -15,4 +15,4 @@ echo "Starting nophr in development mode..."
 echo "Config: $CONFIG"
 echo ""
 
-go run ./cmd/nophr --config "$CONFIG"
+go run -tags sqlite_fts5 ./cmd/nophr --config "$CONFIG"
@@ -4,7 +4,7 @@
 set -e
 
 echo "Running tests..."
-go test ./... -v -cover -coverprofile=coverage.out
+go test -tags sqlite_fts5 ./... -v -cover -coverprofile=coverage.out
 
 echo ""
 echo "Coverage summary:"
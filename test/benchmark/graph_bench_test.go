@@ -0,0 +1,144 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/sandwichfarm/nophr/internal/config"
+	"github.com/sandwichfarm/nophr/internal/storage"
+	"github.com/sandwichfarm/nophr/internal/sync"
+)
+
+const graphRoot = "root"
+
+// graphFixture is a FOAF graph: the owner follows a number of authors, and
+// each of them follows 100 pubkeys from a shared pool
+type graphFixture struct {
+	st    *storage.Storage
+	graph *sync.Graph
+	lists map[string][]string // latest follows per author
+	pool  []string
+	rng   *rand.Rand
+	now   nostr.Timestamp
+}
+
+func newGraphFixture(b *testing.B, follows int) *graphFixture {
+	b.Helper()
+
+	ctx := context.Background()
+	cfg := &config.Storage{
+		Driver:     "sqlite",
+		SQLitePath: filepath.Join(b.TempDir(), "bench.db"),
+	}
+
+	st, err := storage.New(ctx, cfg)
+	if err != nil {
+		b.Fatalf("Failed to create storage: %v", err)
+	}
+	b.Cleanup(func() { st.Close() })
+
+	f := &graphFixture{
+		st:    st,
+		graph: sync.NewGraph(st, &config.SyncScope{Mode: "foaf", Depth: 2}),
+		lists: make(map[string][]string),
+		rng:   rand.New(rand.NewSource(1)),
+		now:   1000,
+	}
+	for i := 0; i < follows*10; i++ {
+		f.pool = append(f.pool, fmt.Sprintf("pubkey%058d", i))
+	}
+
+	f.lists[graphRoot] = f.pool[:follows]
+	for _, author := range f.pool[:follows] {
+		f.lists[author] = f.sample(100)
+	}
+
+	// Seed storage directly and build the graph once
+	for author, list := range f.lists {
+		record := &storage.ContactList{Pubkey: author, EventID: author, CreatedAt: int64(f.now)}
+		if err := st.SaveContactList(ctx, record, list, nil); err != nil {
+			b.Fatalf("Failed to save contact list: %v", err)
+		}
+	}
+	if err := f.graph.Rebuild(ctx, graphRoot); err != nil {
+		b.Fatalf("Failed to build graph: %v", err)
+	}
+
+	return f
+}
+
+func (f *graphFixture) sample(n int) []string {
+	picked := make([]string, 0, n)
+	for _, i := range f.rng.Perm(len(f.pool))[:n] {
+		picked = append(picked, f.pool[i])
+	}
+	return picked
+}
+
+// nextList returns a newer contact list for a random direct follow that
+// swaps one of their follows for another, as most kind 3 updates do, along
+// with the follow added and the one removed
+func (f *graphFixture) nextList() (event *nostr.Event, added, removed string) {
+	direct := f.lists[graphRoot]
+	author := direct[f.rng.Intn(len(direct))]
+
+	list := append([]string(nil), f.lists[author]...)
+	i := f.rng.Intn(len(list))
+	removed, added = list[i], f.pool[f.rng.Intn(len(f.pool))]
+	list[i] = added
+	f.lists[author] = list
+
+	f.now++
+	event = &nostr.Event{Kind: 3, PubKey: author, CreatedAt: f.now, Tags: nostr.Tags{}}
+	for _, pubkey := range list {
+		event.Tags = append(event.Tags, nostr.Tag{"p", pubkey})
+	}
+	event.ID = event.GetID()
+	return event, added, removed
+}
+
+// BenchmarkGraphIncremental benchmarks applying a contact list update as a
+// diff against the author's previous list
+func BenchmarkGraphIncremental(b *testing.B) {
+	for _, follows := range []int{100, 500} {
+		b.Run(fmt.Sprintf("follows=%d", follows), func(b *testing.B) {
+			f := newGraphFixture(b, follows)
+			ctx := context.Background()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				event, _, _ := f.nextList()
+				if err := f.graph.ProcessContactList(ctx, event, graphRoot); err != nil {
+					b.Fatalf("Failed to process contact list: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkGraphFullRecompute benchmarks the same updates when the whole
+// graph is recomputed after each one
+func BenchmarkGraphFullRecompute(b *testing.B) {
+	for _, follows := range []int{100, 500} {
+		b.Run(fmt.Sprintf("follows=%d", follows), func(b *testing.B) {
+			f := newGraphFixture(b, follows)
+			ctx := context.Background()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				event, added, removed := f.nextList()
+				record := &storage.ContactList{Pubkey: event.PubKey, EventID: event.ID, CreatedAt: int64(event.CreatedAt)}
+				if err := f.st.SaveContactList(ctx, record, []string{added}, []string{removed}); err != nil {
+					b.Fatalf("Failed to save contact list: %v", err)
+				}
+				if err := f.graph.Rebuild(ctx, graphRoot); err != nil {
+					b.Fatalf("Failed to rebuild graph: %v", err)
+				}
+			}
+		})
+	}
+}